@@ -0,0 +1,105 @@
+// Package accounts 博客用户与学生、员工记录的关联及账号开通.
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"github.com/alexwang789/Base1_golang_task3/student"
+	"github.com/jmoiron/sqlx"
+	"gorm.io/gorm"
+)
+
+// ErrNotLinked 用户没有关联对应的学生/员工记录
+var ErrNotLinked = errors.New("账号未关联")
+
+// StudentLink 博客用户与学生记录的一对一关联
+type StudentLink struct {
+	UserID    uint `gorm:"primaryKey"`
+	StudentID uint `gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time
+}
+
+// EmployeeLink 博客用户与员工记录的一对一关联. 员工在人事库, 无法建立外键
+type EmployeeLink struct {
+	UserID     uint `gorm:"primaryKey"`
+	EmployeeID int  `gorm:"not null;uniqueIndex"`
+	CreatedAt  time.Time
+}
+
+// Migrate 创建关联表, 与博客的表在同一个库
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&StudentLink{}, &EmployeeLink{}); err != nil {
+		return fmt.Errorf("创建关联表失败: %w", err)
+	}
+	return nil
+}
+
+// 关联只能由后台流程 (如 ProvisionEmployee) 建立, 不提供用户自助关联的接口,
+// 否则任何人都能把自己关联到别人的学生/员工记录上
+
+// LinkStudent 把用户关联到学生记录, 用户已有关联时替换
+func LinkStudent(ctx context.Context, db *gorm.DB, userID, studentID uint) error {
+	if _, err := student.NewGormStore(db).GetByID(ctx, studentID); err != nil {
+		return err
+	}
+
+	link := StudentLink{UserID: userID, StudentID: studentID}
+	if err := db.WithContext(ctx).Save(&link).Error; err != nil {
+		return fmt.Errorf("关联学生失败: %w", err)
+	}
+	return nil
+}
+
+// LinkEmployee 把用户关联到员工记录, 用户已有关联时替换
+func LinkEmployee(ctx context.Context, db *gorm.DB, hr sqlx.QueryerContext, userID uint, employeeID int) error {
+	if _, err := employee.GetByID(ctx, hr, employeeID); err != nil {
+		return err
+	}
+
+	link := EmployeeLink{UserID: userID, EmployeeID: employeeID}
+	if err := db.WithContext(ctx).Save(&link).Error; err != nil {
+		return fmt.Errorf("关联员工失败: %w", err)
+	}
+	return nil
+}
+
+// LinkedStudent 返回用户关联的学生记录
+func LinkedStudent(ctx context.Context, db *gorm.DB, userID uint) (*student.Student, error) {
+	var link StudentLink
+	err := db.WithContext(ctx).Scopes(scopes.ByUser(userID)).First(&link).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotLinked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询学生关联失败: %w", err)
+	}
+
+	s, err := student.NewGormStore(db).GetByID(ctx, link.StudentID)
+	if errors.Is(err, student.ErrNotFound) {
+		return nil, ErrNotLinked // 学生记录已被删除
+	}
+	return s, err
+}
+
+// LinkedEmployee 返回用户关联的员工记录
+func LinkedEmployee(ctx context.Context, db *gorm.DB, hr sqlx.QueryerContext, userID uint) (*employee.Employee, error) {
+	var link EmployeeLink
+	err := db.WithContext(ctx).Scopes(scopes.ByUser(userID)).First(&link).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotLinked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询员工关联失败: %w", err)
+	}
+
+	emp, err := employee.GetByID(ctx, hr, link.EmployeeID)
+	if errors.Is(err, employee.ErrNotFound) {
+		return nil, ErrNotLinked // 员工记录已被删除
+	}
+	return emp, err
+}
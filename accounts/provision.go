@@ -0,0 +1,57 @@
+package accounts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	"github.com/alexwang789/Base1_golang_task3/saga"
+	"github.com/jmoiron/sqlx"
+	"gorm.io/gorm"
+)
+
+// ProvisionEmployee 为新员工开通博客账号: 先在人事库写入员工, 再在博客库创建用户并关联到员工记录.
+// 两个库不在同一事务中, 创建用户失败时由 saga 删除刚插入的员工记录.
+func ProvisionEmployee(ctx context.Context, hr *sqlx.DB, blogDB *gorm.DB, emp *employee.Employee, user *blog.User) error {
+	// 先校验两边的数据, 避免写入员工后才因用户数据无效而回滚
+	if err := emp.Validate(); err != nil {
+		return err
+	}
+	if err := user.Validate(); err != nil {
+		return err
+	}
+
+	s := saga.New("provision_employee_account")
+
+	s.Step("insert_employee",
+		func(ctx context.Context) error {
+			return employee.InTx(ctx, hr, func(tx *sqlx.Tx) error {
+				return employee.Insert(ctx, tx, emp)
+			})
+		},
+		func(ctx context.Context) error {
+			return employee.InTx(ctx, hr, func(tx *sqlx.Tx) error {
+				return employee.Delete(ctx, tx, emp.ID)
+			})
+		})
+
+	s.Step("create_blog_user",
+		func(ctx context.Context) error {
+			if err := blogDB.WithContext(ctx).Create(user).Error; err != nil {
+				return fmt.Errorf("创建博客用户失败: %w", err)
+			}
+			return nil
+		},
+		func(ctx context.Context) error {
+			return blogDB.WithContext(ctx).Delete(&blog.User{}, user.ID).Error
+		})
+
+	s.Step("link_accounts",
+		func(ctx context.Context) error {
+			return LinkEmployee(ctx, blogDB, hr, user.ID, emp.ID)
+		},
+		nil)
+
+	return s.Run(ctx)
+}
@@ -0,0 +1,131 @@
+// Package anonymize 生产数据脱敏: 生成逼真的假姓名和邮箱, 以及把整个库复制一份再脱敏.
+//
+// 各模块按主键改写自己的个人信息 (blog.AnonymizeUsers、employee.AnonymizeNames), 主键和外键不变, 关联关系保持完整.
+// 同一 seed 下同一主键总是得到同一个假身份, 重复执行结果相同.
+package anonymize
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var surnames = []struct{ han, pinyin string }{
+	{"王", "wang"}, {"李", "li"}, {"张", "zhang"}, {"刘", "liu"}, {"陈", "chen"}, {"杨", "yang"}, {"黄", "huang"}, {"赵", "zhao"},
+	{"吴", "wu"}, {"周", "zhou"}, {"徐", "xu"}, {"孙", "sun"}, {"马", "ma"}, {"朱", "zhu"}, {"胡", "hu"}, {"郭", "guo"},
+	{"何", "he"}, {"高", "gao"}, {"林", "lin"}, {"罗", "luo"}, {"郑", "zheng"}, {"梁", "liang"}, {"谢", "xie"}, {"宋", "song"},
+	{"唐", "tang"}, {"许", "xu"}, {"韩", "han"}, {"冯", "feng"}, {"邓", "deng"}, {"曹", "cao"}, {"彭", "peng"}, {"曾", "zeng"},
+}
+
+var givenNames = []struct{ han, pinyin string }{
+	{"伟", "wei"}, {"芳", "fang"}, {"娜", "na"}, {"敏", "min"}, {"静", "jing"}, {"丽", "li"}, {"强", "qiang"}, {"磊", "lei"},
+	{"军", "jun"}, {"洋", "yang"}, {"勇", "yong"}, {"艳", "yan"}, {"杰", "jie"}, {"娟", "juan"}, {"涛", "tao"}, {"明", "ming"},
+	{"超", "chao"}, {"秀", "xiu"}, {"霞", "xia"}, {"平", "ping"}, {"刚", "gang"}, {"桂", "gui"}, {"华", "hua"}, {"建", "jian"},
+	{"文", "wen"}, {"玲", "ling"}, {"斌", "bin"}, {"宇", "yu"}, {"浩", "hao"}, {"凯", "kai"}, {"欣", "xin"}, {"婷", "ting"},
+	{"鹏", "peng"}, {"晨", "chen"}, {"琳", "lin"}, {"雪", "xue"}, {"慧", "hui"}, {"颖", "ying"}, {"博", "bo"}, {"思", "si"},
+}
+
+// Person 一个假身份
+type Person struct {
+	Name   string // 中文姓名, 如 "张思颖"
+	Pinyin string // 姓名的拼音, 如 "zhangsiying", 用于生成邮箱
+}
+
+// Faker 按主键生成假身份, 同一 seed 下结果固定
+type Faker struct {
+	seed uint64
+}
+
+// New 创建 Faker
+func New(seed int64) *Faker {
+	return &Faker{seed: uint64(seed)}
+}
+
+// Person 返回主键 id 对应的假身份, 姓名为单名或双名. 不同主键可能得到同名的身份
+func (f *Faker) Person(id uint64) Person {
+	h := splitmix64(f.seed ^ splitmix64(id))
+	s := surnames[h%uint64(len(surnames))]
+	h /= uint64(len(surnames))
+	p := Person{Name: s.han, Pinyin: s.pinyin}
+	n := 1 + h%2
+	h /= 2
+	for range n {
+		g := givenNames[h%uint64(len(givenNames))]
+		h /= uint64(len(givenNames))
+		p.Name += g.han
+		p.Pinyin += g.pinyin
+	}
+	return p
+}
+
+// UniqueName 带主键后缀的姓名, 如 "张伟42", 用于需要唯一的用户名
+func (p Person) UniqueName(id uint64) string {
+	return p.Name + strconv.FormatUint(id, 10)
+}
+
+// Email 带主键的邮箱, 如 "zhangwei.42@example.com", 不同主键的邮箱不会相同. example.com 为保留域名, 不会误发邮件
+func (p Person) Email(id uint64) string {
+	return p.Pinyin + "." + strconv.FormatUint(id, 10) + "@example.com"
+}
+
+// splitmix64 把连续的输入打散为均匀分布的 64 位值
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// CopyDatabase 在同一台服务器上把库 src 的全部表和数据复制到新库 dst, dst 已存在时返回错误 (不覆盖已有的库).
+// 表结构按 CREATE TABLE ... LIKE 复制, 包括索引但不包括外键约束, 因此各表的复制顺序无关
+func CopyDatabase(ctx context.Context, db *sql.DB, src, dst string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "CREATE DATABASE "+quote(dst)); err != nil {
+		return fmt.Errorf("创建库 %s 失败: %w", dst, err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT TABLE_NAME FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME
+	`, src)
+	if err != nil {
+		return fmt.Errorf("查询库 %s 的表失败: %w", src, err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("读取表名失败: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("读取表名失败: %w", err)
+	}
+
+	for _, t := range tables {
+		from, to := quote(src)+"."+quote(t), quote(dst)+"."+quote(t)
+		if _, err := conn.ExecContext(ctx, "CREATE TABLE "+to+" LIKE "+from); err != nil {
+			return fmt.Errorf("复制表 %s 的结构失败: %w", t, err)
+		}
+		if _, err := conn.ExecContext(ctx, "INSERT INTO "+to+" SELECT * FROM "+from); err != nil {
+			return fmt.Errorf("复制表 %s 的数据失败: %w", t, err)
+		}
+	}
+	return nil
+}
+
+// quote 以反引号引用库名或表名
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,1057 @@
+// Package api 博客 REST API.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/accounts"
+	"github.com/alexwang789/Base1_golang_task3/asyncwork"
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/cdc"
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/dbbreaker"
+	"github.com/alexwang789/Base1_golang_task3/i18n"
+	"github.com/alexwang789/Base1_golang_task3/idcodec"
+	"github.com/alexwang789/Base1_golang_task3/idempotency"
+	"github.com/alexwang789/Base1_golang_task3/jobs"
+	"github.com/alexwang789/Base1_golang_task3/middleware"
+	"github.com/alexwang789/Base1_golang_task3/nplusone"
+	"github.com/alexwang789/Base1_golang_task3/outbox"
+	"github.com/alexwang789/Base1_golang_task3/ratelimit"
+	"github.com/alexwang789/Base1_golang_task3/redact"
+	"github.com/alexwang789/Base1_golang_task3/search"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"github.com/alexwang789/Base1_golang_task3/validate"
+	"github.com/alexwang789/Base1_golang_task3/webhook"
+	"github.com/jmoiron/sqlx"
+	"gorm.io/gorm"
+)
+
+// Server 博客 REST API. 对外的 ID 一律经 idcodec 编码, 不暴露自增主键
+type Server struct {
+	db    *gorm.DB
+	hr    *sqlx.DB // 人事库, 为 nil 时员工自助接口不可用
+	ids   *idcodec.Codec
+	users *blog.UserRepository
+	posts *blog.PostRepository
+
+	progress *blog.ProgressBuffer
+	views    *blog.ViewCounter
+	comments ratelimit.Limiter // 发表评论的频率限制
+	logins   ratelimit.Limiter // 登录失败次数的限制
+
+	redis         *ratelimit.RedisStore        // 为 nil 时限流计数保存在进程内
+	trustProxy    bool                         // 客户端 IP 取 X-Forwarded-For
+	routeLimiters map[string]ratelimit.Limiter // 路由级限流策略名 -> 限流器
+
+	idempotency *idempotency.Store // x-idempotent 操作的幂等键
+
+	search *search.Client // 文章检索的搜索引擎, 为 nil 时只用数据库检索
+
+	validateResponses bool // 按 OpenAPI 文档校验响应并记录不符合的响应
+}
+
+// 阅读进度的批量写入间隔
+const progressFlushInterval = 5 * time.Second
+
+// 钩子副作用任务池的 worker 数、队列长度和退出时的最长等待时间
+const (
+	hookWorkers      = 4
+	hookQueueSize    = 1000
+	hookDrainTimeout = 30 * time.Second
+)
+
+// 收到退出信号后等待进行中的请求完成的最长时间
+const shutdownTimeout = 30 * time.Second
+
+// New 创建 API 服务, hr 为 nil 时员工自助接口返回 503
+func New(db *gorm.DB, hr *sqlx.DB, ids *idcodec.Codec) *Server {
+	viewFlushInterval, viewFlushThreshold := config.LoadViewFlush()
+	s := &Server{
+		db:       db,
+		hr:       hr,
+		ids:      ids,
+		users:    blog.NewUserRepository(db),
+		posts:    blog.NewPostRepository(db),
+		progress: blog.NewProgressBuffer(db, progressFlushInterval),
+		views:    blog.NewViewCounter(db, viewFlushInterval, viewFlushThreshold),
+
+		trustProxy:        config.LoadTrustProxy(),
+		routeLimiters:     map[string]ratelimit.Limiter{},
+		idempotency:       idempotency.NewStore(db, config.LoadIdempotencyTTL()),
+		validateResponses: config.LoadValidateResponses(),
+	}
+	if cfg := config.LoadSearch(); cfg.Enabled() {
+		s.search = search.NewClient(cfg.URL, cfg.Index, cfg.Timeout)
+	}
+	if cfg := config.LoadRedis(); cfg.Addr != "" {
+		s.redis = ratelimit.NewRedisStore(ratelimit.RedisOptions{Addr: cfg.Addr, Password: cfg.Password, DB: cfg.DB, PoolSize: 16})
+	}
+	s.comments = s.newLimiter("comment", config.RateLimit{N: config.LoadCommentRateLimit(), Per: time.Minute})
+	s.logins = s.newLimiter("login", config.LoadRateLimit("login", loginRateLimit))
+	return s
+}
+
+// Routes 返回 API 的路由. 路由须在 openapi.yaml 中定义, 认证要求和请求校验按文档执行,
+// 文档中的操作没有全部注册时 panic
+func (s *Server) Routes() http.Handler {
+	mux := http.NewServeMux()
+	s.handle(mux, "GET /openapi.yaml", serveOpenAPI)
+	s.handle(mux, "GET /users/{id}", s.getUser)
+	s.handle(mux, "GET /users/{id}/posts", s.listUserPosts)
+	s.handle(mux, "GET /posts", s.searchPosts)
+	s.handle(mux, "GET /posts/{id}", s.getPost)
+	s.handle(mux, "GET /posts/discover", s.discoverPost)
+	s.handle(mux, "GET /posts/most-viewed", s.mostViewedPosts)
+	s.handle(mux, "GET /posts/trending", s.trendingPosts)
+	s.handle(mux, "GET /posts/search", s.fullTextSearchPosts)
+	s.handle(mux, "GET /posts/{id}/attachments", s.listAttachments)
+	s.handle(mux, "GET /attachments/{id}", s.downloadAttachment)
+	s.handle(mux, "POST /verify-email", s.verifyEmail)
+
+	// 自助接口, 只返回当前登录用户关联的记录
+	s.handle(mux, "GET /me/grades", s.myGrades)
+	s.handle(mux, "GET /me/payslip", s.myPayslip)
+	s.handle(mux, "GET /me/profile", s.myProfile)
+	s.handle(mux, "PUT /me/profile", s.updateMyProfile)
+	s.handle(mux, "POST /me/verification-email", s.resendVerificationEmail)
+	s.handle(mux, "GET /me/feed", s.myFeed)
+	s.handle(mux, "GET /me/reading-progress", s.listReadingProgress)
+	s.handle(mux, "GET /me/reading-progress/{post}", s.getReadingProgress)
+	s.handle(mux, "PUT /me/reading-progress/{post}", s.putReadingProgress)
+	s.handle(mux, "GET /me/posts/scheduled", s.myScheduledPosts)
+	s.handle(mux, "GET /me/posts/archived", s.myArchivedPosts)
+	s.handle(mux, "GET /me/notifications", s.myNotifications)
+	s.handle(mux, "GET /me/notifications/unread-count", s.myUnreadNotificationCount)
+	s.handle(mux, "POST /me/notifications/read", s.readMyNotifications)
+	s.handle(mux, "GET /me/data-export", s.myDataExport)
+	s.handle(mux, "POST /me/erasure", s.requestMyErasure)
+	s.handle(mux, "DELETE /me/erasure", s.cancelMyErasure)
+
+	// 以当前登录用户的身份发表评论
+	s.handle(mux, "POST /posts", s.createPost)
+	s.handle(mux, "PUT /posts/{id}", s.updatePost)
+	s.handle(mux, "POST /posts/{id}/archive", s.archivePost)
+	s.handle(mux, "POST /posts/{id}/restore", s.restorePost)
+	s.handle(mux, "POST /posts/{id}/comments", s.createComment)
+	s.handle(mux, "PUT /posts/{id}/like", s.likePost)
+	s.handle(mux, "DELETE /posts/{id}/like", s.unlikePost)
+	s.handle(mux, "POST /posts/{id}/attachments", s.uploadAttachment)
+	s.handle(mux, "DELETE /attachments/{id}", s.deleteAttachment)
+	s.handle(mux, "PUT /users/{id}/follow", s.followUser)
+	s.handle(mux, "DELETE /users/{id}/follow", s.unfollowUser)
+
+	// 评论审核, 仅管理员可用
+	s.handle(mux, "POST /comments/{id}/approve", s.moderateComment(blog.CommentApproved))
+	s.handle(mux, "POST /comments/{id}/reject", s.moderateComment(blog.CommentRejected))
+
+	// 审计日志、员工查询和统计, 仅管理员可用
+	s.handle(mux, "GET /audit-logs", s.auditLogs)
+	s.handle(mux, "GET /employees", s.listEmployees)
+	s.handle(mux, "GET /admin/stats/posts-per-day", s.postsPerDayStats)
+	s.handle(mux, "GET /admin/stats/new-users-per-week", s.newUsersPerWeekStats)
+	s.handle(mux, "GET /admin/stats/comments-per-post", s.commentsPerPostStats)
+	s.handle(mux, "GET /admin/stats/top-authors", s.topAuthorsStats)
+
+	if missing := apiSpec.unregistered(); len(missing) > 0 {
+		panic(fmt.Sprintf("OpenAPI 文档中的操作没有注册路由: %v", missing))
+	}
+	return mux
+}
+
+// Handler 返回加上中间件的 API: 请求 ID、N+1 查询统计 (仅在开发环境注册了检测插件时生效)、访问日志、panic 恢复、
+// CORS、按客户端 IP 的全局限流和按域名确定租户
+func (s *Server) Handler() http.Handler {
+	global := s.newLimiter("global", config.LoadRateLimit("global", globalRateLimit))
+	// DEFAULT_LOCALE 不是支持的语言时按源语言
+	locale, ok := i18n.Parse(config.LoadDefaultLocale())
+	if !ok {
+		locale = i18n.Source
+	}
+	return middleware.Chain(s.Routes(),
+		middleware.RequestID,
+		i18n.Middleware(locale),
+		nplusone.Middleware,
+		middleware.AccessLog(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+			ReplaceAttr: redact.New(config.LoadRedactFields()).ReplaceAttr,
+		}))),
+		middleware.Recover,
+		middleware.CORS(config.LoadCORS()),
+		middleware.RateLimit(global, func(r *http.Request) string { return s.clientIP(r) }),
+		middleware.Tenant(tenant.HostResolver(config.LoadTenantHosts())),
+	)
+}
+
+// Serve 在 addr 上提供 API, 同时定期刷新阅读进度和推荐权重、执行 jobs 中的定期任务, 并投递发件箱中的事件和 webhook,
+// 阻塞直到服务退出. 收到 SIGINT 或 SIGTERM 时停止接收新请求, 等进行中的请求完成后写入缓冲的计数并等待钩子任务执行完
+func Serve(addr string, db *gorm.DB, hr *sqlx.DB, ids *idcodec.Codec) error {
+	s := New(db, hr, ids)
+
+	hooks := asyncwork.NewPool(hookWorkers, hookQueueSize)
+	if err := blog.EnableAsyncHooks(db, hooks); err != nil {
+		return err
+	}
+
+	// 后台的刷新和任务处理全部租户
+	ctx, cancel := context.WithCancel(tenant.All(context.Background()))
+	go s.progress.Run(ctx)
+	go s.views.Run(ctx)
+	go blog.RefreshDiscoverWeightsLoop(ctx, db)
+	if err := runJobs(ctx, db); err != nil {
+		cancel()
+		return err
+	}
+	var changes outbox.MultiSink
+	if cfg := config.LoadCDC(); cfg.Enabled() {
+		changes = append(changes, cdc.NewSink(cdc.NewRESTProducer(cfg.KafkaRESTURL, cfg.Timeout), cfg.TopicPrefix))
+	}
+	if s.search != nil {
+		if err := s.search.EnsureIndex(ctx); err != nil {
+			log.Printf("创建文章索引失败, 检索暂时改用数据库: %v", err)
+		}
+		changes = append(changes, blog.NewSearchIndexer(db, s.search))
+	}
+	var sink outbox.Sink = outbox.MultiSink{outbox.LogSink{}, webhook.NewSink(db)}
+	if len(changes) > 0 {
+		// 行变更只发布到 Kafka 和同步搜索索引, 不打印日志也不推送给 webhook
+		sink = append(outbox.MultiSink{cdc.Exclude(sink)}, changes...)
+	}
+	go outbox.NewRelay(db, sink, outbox.Options{}).Run(ctx)
+	go webhook.NewWorker(db, webhook.Options{}).Run(ctx)
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	log.Printf("API 监听 %s", addr)
+
+	var err error
+	select {
+	case err = <-serveErr:
+	case <-signals.Done():
+		log.Print("收到退出信号, 等待进行中的请求完成")
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+		err = srv.Shutdown(shutdownCtx)
+		cancelShutdown()
+	}
+
+	// 退出前写入缓冲中的阅读进度和浏览数
+	cancel()
+	if flushErr := s.progress.Flush(context.Background()); flushErr != nil {
+		log.Print(flushErr)
+	}
+	if flushErr := s.views.Flush(context.Background()); flushErr != nil {
+		log.Print(flushErr)
+	}
+	// 等待已提交事务的通知等副作用执行完
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), hookDrainTimeout)
+	defer cancelDrain()
+	if drainErr := hooks.Shutdown(drainCtx); drainErr != nil {
+		log.Printf("钩子任务未全部完成: %v", drainErr)
+	}
+	return err
+}
+
+// runJobs 在后台执行博客模块的定期任务, 多个实例之间通过 MySQL 命名锁避免重复执行
+func runJobs(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	scheduler := jobs.New(jobs.NewMySQLLocker(sqlDB))
+	if err := jobs.RegisterBlogJobs(scheduler, db); err != nil {
+		return err
+	}
+	go scheduler.Run(ctx)
+	return nil
+}
+
+// 响应结构
+
+type userResponse struct {
+	ID           string           `json:"id"`
+	UUID         string           `json:"uuid,omitempty"` // 开启 UUID 键时才有
+	Name         string           `json:"name"`
+	ArticleCount int              `json:"article_count"`
+	CreatedAt    time.Time        `json:"created_at"`
+	Profile      *profileResponse `json:"profile,omitempty"`
+}
+
+type profileResponse struct {
+	Bio       string `json:"bio"`
+	AvatarURL string `json:"avatar_url"`
+	Website   string `json:"website"`
+	Location  string `json:"location"`
+	Version   uint   `json:"version"` // 修订号, 修改时的 If-Match 为 "profile-v<version>", 请求中忽略
+}
+
+// authorResponse 文章列表中内嵌的作者摘要
+type authorResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type postResponse struct {
+	ID                string     `json:"id"`
+	UUID              string     `json:"uuid,omitempty"` // 开启 UUID 键时才有
+	Title             string     `json:"title"`
+	Content           string     `json:"content,omitempty"`   // 列表指定 view=summary 时省略
+	CommentStatus     string     `json:"comment_status"`      // 代码, 见 blog.PostNoComments
+	CommentStatusText string     `json:"comment_status_text"` // 评论状态按请求语言的展示文字
+	ViewCount         uint64     `json:"view_count"`
+	Status            string     `json:"status"`
+	PublishAt         *time.Time `json:"publish_at,omitempty"` // 定时发布的时间
+	Version           uint       `json:"version"`              // 修订号, 修改时的 If-Match 为 "post-v<version>"
+	AuthorID          string     `json:"author_id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	Author *authorResponse `json:"author,omitempty"` // 加载了作者 (blog.LoadAuthors) 时才有
+}
+
+type mostViewedPostResponse struct {
+	postResponse
+	WindowViews uint64 `json:"window_views"` // 统计窗口内的浏览数
+}
+
+type commentResponse struct {
+	ID        string    `json:"id"`
+	UUID      string    `json:"uuid,omitempty"` // 开启 UUID 键时才有
+	PostID    string    `json:"post_id"`
+	AuthorID  string    `json:"author_id"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	Content   string    `json:"content"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type notificationResponse struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	PostID    string    `json:"post_id"`
+	CommentID string    `json:"comment_id"`
+	ActorID   string    `json:"actor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type readingProgressResponse struct {
+	PostID    string    `json:"post_id"`
+	Percent   uint8     `json:"percent"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type gradesResponse struct {
+	Name  string `json:"name"`
+	Age   uint8  `json:"age"`
+	Grade string `json:"grade"`
+}
+
+type payslipResponse struct {
+	Name       string `json:"name"`
+	Department string `json:"department"`
+	Salary     int    `json:"salary"`
+}
+
+func (s *Server) toUserResponse(u *blog.User) userResponse {
+	resp := userResponse{
+		ID:           s.ids.Encode(u.ID),
+		UUID:         uuidKey(u.UUID),
+		Name:         u.Name,
+		ArticleCount: u.ArticleCount,
+		CreatedAt:    u.CreatedAt,
+	}
+	if u.Profile != nil {
+		profile := toProfileResponse(u.Profile)
+		resp.Profile = &profile
+	}
+	return resp
+}
+
+func toProfileResponse(p *blog.Profile) profileResponse {
+	return profileResponse{
+		Bio:       p.Bio,
+		AvatarURL: p.AvatarURL,
+		Website:   p.Website,
+		Location:  p.Location,
+		Version:   p.Version,
+	}
+}
+
+func (s *Server) toPostResponse(ctx context.Context, p *blog.Post) postResponse {
+	summary := p.Summary()
+	resp := s.toPostSummaryResponse(ctx, &summary)
+	resp.Content = p.Content
+	return resp
+}
+
+// toPostSummaryResponse 不含正文的文章, 评论状态的展示文字按 ctx 的语言给出
+func (s *Server) toPostSummaryResponse(ctx context.Context, p *blog.PostSummary) postResponse {
+	resp := postResponse{
+		ID:                s.ids.Encode(p.ID),
+		UUID:              uuidKey(p.UUID),
+		Title:             p.Title,
+		CommentStatus:     string(p.CommentStatus),
+		CommentStatusText: i18n.T(ctx, "comment_status."+string(p.CommentStatus)),
+		ViewCount:         p.ViewCount,
+		Status:            string(p.Status),
+		PublishAt:         p.PublishAt,
+		Version:           p.Version,
+		AuthorID:          s.ids.Encode(p.UserID),
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
+	}
+	if p.User.ID != 0 {
+		resp.Author = &authorResponse{ID: s.ids.Encode(p.User.ID), Name: p.User.Name}
+		if p.User.Profile != nil {
+			resp.Author.AvatarURL = p.User.Profile.AvatarURL
+		}
+	}
+	return resp
+}
+
+func (s *Server) toCommentResponse(c *blog.Comment) commentResponse {
+	var parentID string
+	if c.ParentID != nil {
+		parentID = s.ids.Encode(*c.ParentID)
+	}
+	return commentResponse{
+		ID:        s.ids.Encode(c.ID),
+		UUID:      uuidKey(c.UUID),
+		PostID:    s.ids.Encode(c.PostID),
+		AuthorID:  s.ids.Encode(c.UserID),
+		ParentID:  parentID,
+		Content:   c.Content,
+		Status:    string(c.Status),
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+func (s *Server) toNotificationResponse(n *blog.Notification) notificationResponse {
+	return notificationResponse{
+		ID:        s.ids.Encode(n.ID),
+		Kind:      n.Kind,
+		PostID:    s.ids.Encode(n.PostID),
+		CommentID: s.ids.Encode(n.CommentID),
+		ActorID:   s.ids.Encode(n.ActorID),
+		CreatedAt: n.CreatedAt,
+	}
+}
+
+func (s *Server) toReadingProgressResponse(p blog.ReadingProgress) readingProgressResponse {
+	return readingProgressResponse{
+		PostID:    s.ids.Encode(p.PostID),
+		Percent:   p.Percent,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
+
+// 处理函数
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := s.users.GetByIDCached(r.Context(), id)
+	if errors.Is(err, blog.ErrUserNotFound) {
+		writeError(w, http.StatusNotFound, "用户不存在")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	profile, err := blog.GetProfile(r.Context(), s.db, id)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	// 用户和资料任一修改时 ETag 都要变化, 取两者中较晚的修改时间
+	updated := user.UpdatedAt
+	if profile.UpdatedAt.After(updated) {
+		updated = profile.UpdatedAt
+	}
+	if notModified(w, r, etag("user", updated)) {
+		return
+	}
+
+	resp := s.toUserResponse(user)
+	profileResp := toProfileResponse(profile)
+	resp.Profile = &profileResp
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) listUserPosts(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	sel, ok := parsePostSelection(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	paged := q.Has("page") || q.Has("size")
+	page, _ := strconv.Atoi(q.Get("page"))
+	size, _ := strconv.Atoi(q.Get("size"))
+	if summaryView(r) {
+		var (
+			posts []blog.PostSummary
+			err   error
+		)
+		if paged {
+			posts, err = s.posts.PageSummariesByUser(r.Context(), id, page, size)
+		} else {
+			posts, err = s.posts.ListSummariesByUser(r.Context(), id)
+		}
+		s.writePostSummaries(w, r, posts, err)
+		return
+	}
+
+	var (
+		posts []blog.Post
+		err   error
+	)
+	if paged {
+		posts, err = s.posts.PageByUser(r.Context(), id, page, size, sel.Scope("id"))
+	} else {
+		posts, err = s.posts.ListByUser(r.Context(), id, 0, 0, sel.Scope("id"))
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	s.writeShapedPosts(w, r, sel, posts)
+}
+
+// summaryView 列表请求是否指定了 view=summary: 只查询和返回文章摘要, 不含正文. 指定了 fields 或 embed 时以它们为准, 忽略 view
+func summaryView(r *http.Request) bool {
+	q := r.URL.Query()
+	return q.Get("view") == "summary" && !q.Has("fields") && !q.Has("embed")
+}
+
+// writePostSummaries 加载作者后返回文章摘要列表, err 为查询文章的错误
+func (s *Server) writePostSummaries(w http.ResponseWriter, r *http.Request, posts []blog.PostSummary, err error) {
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if err := blog.LoadSummaryAuthors(r.Context(), s.db, posts); err != nil {
+		s.internalError(w, err)
+		return
+	}
+	resp := make([]postResponse, len(posts))
+	for i := range posts {
+		resp[i] = s.toPostSummaryResponse(r.Context(), &posts[i])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// getPost 返回对外可见的文章, fields 和 embed 参数 (见 fieldsel) 选择返回的字段和内嵌的评论
+func (s *Server) getPost(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+	sel, ok := parsePostSelection(w, r)
+	if !ok {
+		return
+	}
+
+	// 判断是否公开和生成 ETag 的列无论是否选择都要查询
+	post, err := s.posts.Get(r.Context(), id, sel.Scope("id", "status", "updated_at"))
+	// 定时发布和已归档的文章不公开, 作者从 /me/posts/scheduled 和 /me/posts/archived 查看
+	if errors.Is(err, blog.ErrPostNotFound) || err == nil && !post.Public() {
+		writeError(w, http.StatusNotFound, "文章不存在")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	// 客户端用缓存的内容展示也算一次浏览, 先记录再检查 If-None-Match.
+	// 评论变化时文章的修改时间不变, 内嵌评论的响应不生成 ETag
+	s.views.Record(r.Context(), post.ID)
+	if _, embed := sel.Embed("comments"); !embed && notModified(w, r, etag("post", post.UpdatedAt)) {
+		return
+	}
+	resp, err := s.shapePosts(r.Context(), sel, []blog.Post{*post})
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	s.writeShaped(w, sel, resp[0])
+}
+
+// discoverPost "随便看看": 按新鲜度和互动量加权随机返回一篇文章
+func (s *Server) discoverPost(w http.ResponseWriter, r *http.Request) {
+	post, err := blog.DiscoverPost(r.Context(), s.db)
+	if errors.Is(err, blog.ErrNoDiscoverablePost) {
+		writeError(w, http.StatusNotFound, "暂无文章")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	s.writePostWithAuthor(w, r, post)
+}
+
+// mostViewedPosts 最近 window (默认 24h) 内浏览最多的文章
+func (s *Server) mostViewedPosts(w http.ResponseWriter, r *http.Request) {
+	window := 24 * time.Hour
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > blog.MaxViewWindow {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("window 应为不超过 %s 的时长, 如 24h", blog.MaxViewWindow))
+			return
+		}
+		window = d
+	}
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, _ = strconv.Atoi(v)
+	}
+
+	viewed, err := blog.MostViewedPosts(r.Context(), s.db, window, limit)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	posts := make([]blog.Post, len(viewed))
+	for i := range viewed {
+		posts[i] = viewed[i].Post
+	}
+	if err := blog.LoadAuthors(r.Context(), s.db, posts); err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	resp := make([]mostViewedPostResponse, len(posts))
+	for i := range posts {
+		resp[i] = mostViewedPostResponse{postResponse: s.toPostResponse(r.Context(), &posts[i]), WindowViews: viewed[i].WindowViews}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// trendingPosts 首页的热门文章, 按浏览、点赞和评论随时间衰减的热度排序
+func (s *Server) trendingPosts(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, _ = strconv.Atoi(v)
+	}
+
+	trending, err := blog.GetTrending(r.Context(), s.db, limit)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	posts := make([]blog.Post, len(trending))
+	for i := range trending {
+		posts[i] = trending[i].Post
+	}
+	if err := blog.LoadAuthors(r.Context(), s.db, posts); err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	resp := make([]postResponse, len(posts))
+	for i := range posts {
+		resp[i] = s.toPostResponse(r.Context(), &posts[i])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// writePostWithAuthor 加载作者摘要后返回单篇文章
+func (s *Server) writePostWithAuthor(w http.ResponseWriter, r *http.Request, post *blog.Post) {
+	posts := []blog.Post{*post}
+	if err := blog.LoadAuthors(r.Context(), s.db, posts); err != nil {
+		s.internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.toPostResponse(r.Context(), &posts[0]))
+}
+
+func (s *Server) myGrades(w http.ResponseWriter, r *http.Request) {
+	student, err := accounts.LinkedStudent(r.Context(), s.db, currentUser(r).ID)
+	if errors.Is(err, accounts.ErrNotLinked) {
+		writeError(w, http.StatusNotFound, "账号未关联学生")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, gradesResponse{Name: student.Name, Age: student.Age, Grade: student.Grade})
+}
+
+func (s *Server) myPayslip(w http.ResponseWriter, r *http.Request) {
+	if s.hr == nil {
+		writeError(w, http.StatusServiceUnavailable, "人事系统不可用")
+		return
+	}
+
+	employee, err := accounts.LinkedEmployee(r.Context(), s.db, s.hr, currentUser(r).ID)
+	if errors.Is(err, accounts.ErrNotLinked) {
+		writeError(w, http.StatusNotFound, "账号未关联员工")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, payslipResponse{
+		Name:       employee.Name,
+		Department: employee.Department,
+		Salary:     employee.Salary,
+	})
+}
+
+// 未读通知一次最多返回的条数
+const maxUnreadNotifications = 100
+
+func (s *Server) myNotifications(w http.ResponseWriter, r *http.Request) {
+	list, err := blog.UnreadNotifications(r.Context(), s.db, currentUser(r).ID, maxUnreadNotifications)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	resp := make([]notificationResponse, len(list))
+	for i := range list {
+		resp[i] = s.toNotificationResponse(&list[i])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) myUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	counts, err := blog.UnreadNotificationCounts(r.Context(), s.db, currentUser(r).ID)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"total": total, "by_kind": counts})
+}
+
+// readMyNotifications 把请求体 {"ids": [...]} 中的通知标记为已读, 没有请求体或 ids 为空时标记全部
+func (s *Server) readMyNotifications(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "请求体应为 {\"ids\": [...]}")
+		return
+	}
+
+	ids := make([]uint, 0, len(req.IDs))
+	for _, encoded := range req.IDs {
+		id, err := s.ids.Decode(encoded)
+		if err != nil {
+			continue // 无效 ID 不可能属于当前用户
+		}
+		ids = append(ids, id)
+	}
+	if len(req.IDs) > 0 && len(ids) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := blog.MarkNotificationsRead(r.Context(), s.db, currentUser(r).ID, ids...); err != nil {
+		s.internalError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listReadingProgress 当前用户的阅读进度. 阅读记录属于个人数据, 只能查看和上报自己的
+func (s *Server) listReadingProgress(w http.ResponseWriter, r *http.Request) {
+	list, err := s.progress.List(r.Context(), currentUser(r).ID)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	resp := make([]readingProgressResponse, len(list))
+	for i, p := range list {
+		resp[i] = s.toReadingProgressResponse(p)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) getReadingProgress(w http.ResponseWriter, r *http.Request) {
+	postID, ok := s.pathValueID(w, r, "post")
+	if !ok {
+		return
+	}
+
+	p, found, err := s.progress.Get(r.Context(), currentUser(r).ID, postID)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "没有阅读进度")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.toReadingProgressResponse(p))
+}
+
+// putReadingProgress 上报当前用户的阅读进度. 写入是异步合并的, 成功返回 202
+func (s *Server) putReadingProgress(w http.ResponseWriter, r *http.Request) {
+	postID, ok := s.pathValueID(w, r, "post")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Percent *int `json:"percent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Percent == nil {
+		writeError(w, http.StatusBadRequest, "请求体应为 {\"percent\": 0~100}")
+		return
+	}
+	if *req.Percent < 0 || *req.Percent > 100 {
+		writeError(w, http.StatusBadRequest, "percent 必须在 0~100 之间")
+		return
+	}
+
+	// 批量写入时才会触发外键错误, 这里先确认文章存在
+	var n int64
+	err := s.db.WithContext(r.Context()).Model(&blog.Post{}).Scopes(blog.PublicOnly()).Where("id = ?", postID).Count(&n).Error
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if n == 0 {
+		writeError(w, http.StatusNotFound, "文章不存在")
+		return
+	}
+	p := s.progress.Set(r.Context(), currentUser(r).ID, postID, uint8(*req.Percent))
+	writeJSON(w, http.StatusAccepted, s.toReadingProgressResponse(p))
+}
+
+// createComment 发表评论, 评论审核通过后才对外展示. 超出频率限制时返回 429 和 Retry-After
+func (s *Server) createComment(w http.ResponseWriter, r *http.Request) {
+	postID, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Content  string `json:"content"`
+		ParentID string `json:"parent_id"` // 回复时为被回复评论的 ID
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "请求体应为 {\"content\": \"...\", \"parent_id\": \"...\"}")
+		return
+	}
+
+	var n int64
+	if err := s.db.WithContext(r.Context()).Model(&blog.Post{}).Scopes(blog.PublicOnly()).Where("id = ?", postID).Count(&n).Error; err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if n == 0 {
+		writeError(w, http.StatusNotFound, "文章不存在")
+		return
+	}
+
+	comment := blog.Comment{Content: req.Content, PostID: postID, UserID: currentUser(r).ID}
+	if req.ParentID != "" {
+		parentID, err := s.ids.Decode(req.ParentID)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{
+				"error":  "数据校验失败",
+				"fields": validate.Errors{"parent_id": "回复的评论不存在"},
+			})
+			return
+		}
+		comment.ParentID = &parentID
+	}
+	err := blog.CreateComment(r.Context(), s.db, s.comments, &comment)
+	var verrs validate.Errors
+	switch {
+	case errors.As(err, &verrs):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "数据校验失败", "fields": verrs})
+	case errors.Is(err, ratelimit.ErrRateLimited):
+		middleware.WriteRateLimited(w, err)
+	case errors.Is(err, blog.ErrEmailNotVerified):
+		writeError(w, http.StatusForbidden, "邮箱尚未验证")
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		writeJSON(w, http.StatusCreated, s.toCommentResponse(&comment))
+	}
+}
+
+func (s *Server) likePost(w http.ResponseWriter, r *http.Request) {
+	s.setLike(w, r, blog.LikePost)
+}
+
+func (s *Server) unlikePost(w http.ResponseWriter, r *http.Request) {
+	s.setLike(w, r, blog.UnlikePost)
+}
+
+// setLike 以当前用户身份点赞或取消点赞路径中的文章, 重复操作同样返回 204
+func (s *Server) setLike(w http.ResponseWriter, r *http.Request, op func(context.Context, *gorm.DB, uint, uint) error) {
+	postID, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var n int64
+	if err := s.db.WithContext(r.Context()).Model(&blog.Post{}).Scopes(blog.PublicOnly()).Where("id = ?", postID).Count(&n).Error; err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if n == 0 {
+		writeError(w, http.StatusNotFound, "文章不存在")
+		return
+	}
+
+	if err := op(r.Context(), s.db, currentUser(r).ID, postID); err != nil {
+		s.internalError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// moderateComment 把路径中的评论改为 status, 成功返回 204
+func (s *Server) moderateComment(status blog.CommentStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := s.pathID(w, r)
+		if !ok {
+			return
+		}
+
+		err := blog.ModerateComment(r.Context(), s.db, currentUser(r), id, status)
+		switch {
+		case errors.Is(err, blog.ErrForbidden):
+			writeError(w, http.StatusForbidden, "需要管理员权限")
+		case errors.Is(err, blog.ErrCommentNotFound):
+			writeError(w, http.StatusNotFound, "评论不存在")
+		case err != nil:
+			s.internalError(w, err)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+// fullTextSearchPosts 按关键词检索对外可见的文章, 按相关度排序. 配置了搜索引擎时在其中检索 (匹配标题、正文和作者名),
+// 集群不可用时改用数据库的全文索引
+func (s *Server) fullTextSearchPosts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	size, _ := strconv.Atoi(q.Get("size"))
+	posts, err := blog.SearchPostsES(r.Context(), s.db, s.search, strings.TrimSpace(q.Get("q")), page, size)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if err := blog.LoadAuthors(r.Context(), s.db, posts); err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	resp := make([]postResponse, len(posts))
+	for i := range posts {
+		resp[i] = s.toPostResponse(r.Context(), &posts[i])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// 辅助函数
+
+// pathID 解码路径中的 {id}, 失败时直接写 404 响应 —— 无效 ID 与不存在的资源不做区分
+func (s *Server) pathID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	return s.pathValueID(w, r, "id")
+}
+
+// pathValueID 同 pathID, 解码路径中名为 name 的参数
+func (s *Server) pathValueID(w http.ResponseWriter, r *http.Request, name string) (uint, bool) {
+	value := r.PathValue(name)
+	if model := uuidModel(r, name); model != nil && blog.IsUUID(value) {
+		id, err := blog.FindIDByUUID(r.Context(), s.db, model, value)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			writeError(w, http.StatusNotFound, "资源不存在")
+		case err != nil:
+			s.internalError(w, err)
+		default:
+			return id, true
+		}
+		return 0, false
+	}
+	id, err := s.ids.Decode(value)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "资源不存在")
+		return 0, false
+	}
+	return id, true
+}
+
+// uuidModels 开启 UUID 键时, 路由的第一段为这些资源时路径中的 {id} 可以是 UUID
+var uuidModels = map[string]any{"users": &blog.User{}, "posts": &blog.Post{}, "comments": &blog.Comment{}}
+
+// uuidModel 路径参数 name 可以是 UUID 时返回对应的模型, 否则返回 nil
+func uuidModel(r *http.Request, name string) any {
+	if !blog.UUIDKeysEnabled() || name != "id" {
+		return nil
+	}
+	// r.Pattern 形如 "GET /posts/{id}"
+	_, path, _ := strings.Cut(r.Pattern, " ")
+	resource, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return uuidModels[resource]
+}
+
+// uuidKey 未开启 UUID 键时为空
+func uuidKey(key *string) string {
+	if key == nil {
+		return ""
+	}
+	return *key
+}
+
+// internalError 未预期的错误返回 500; 数据库熔断期间 (dbbreaker.ErrDBUnavailable) 返回 503, 客户端可稍后重试
+func (s *Server) internalError(w http.ResponseWriter, err error) {
+	if errors.Is(err, dbbreaker.ErrDBUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, "数据库暂时不可用")
+		return
+	}
+	log.Printf("API 内部错误: %v", err)
+	writeError(w, http.StatusInternalServerError, "服务器内部错误")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
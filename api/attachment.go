@@ -0,0 +1,164 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+)
+
+type attachmentResponse struct {
+	ID          string    `json:"id"`
+	PostID      string    `json:"post_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	URL         string    `json:"url"` // 下载地址
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (s *Server) toAttachmentResponse(a *blog.Attachment) attachmentResponse {
+	id := s.ids.Encode(a.ID)
+	return attachmentResponse{
+		ID:          id,
+		PostID:      s.ids.Encode(a.PostID),
+		FileName:    a.FileName,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		URL:         "/attachments/" + id,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+// uploadAttachment 上传文章附件, 请求体为 multipart/form-data, 文件在 file 字段中.
+// 文件内容直接写入存储, 不在内存或临时目录中缓存整个请求
+func (s *Server) uploadAttachment(w http.ResponseWriter, r *http.Request) {
+	postID, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "请求体应为 multipart/form-data, 文件放在 file 字段")
+		return
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "请求体应为 multipart/form-data, 文件放在 file 字段")
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		a := blog.Attachment{PostID: postID, UserID: currentUser(r).ID, FileName: part.FileName()}
+		err = blog.UploadAttachment(r.Context(), s.db, &a, part)
+		switch {
+		case errors.Is(err, blog.ErrAttachmentsDisabled):
+			writeError(w, http.StatusServiceUnavailable, "附件存储未启用")
+		case errors.Is(err, blog.ErrPostNotFound):
+			writeError(w, http.StatusNotFound, "文章不存在")
+		case errors.Is(err, blog.ErrForbidden):
+			writeError(w, http.StatusForbidden, "只有文章作者可以上传附件")
+		case errors.Is(err, blog.ErrAttachmentTooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		case errors.Is(err, blog.ErrAttachmentType):
+			writeError(w, http.StatusUnsupportedMediaType, err.Error())
+		case err != nil:
+			s.internalError(w, err)
+		default:
+			writeJSON(w, http.StatusCreated, s.toAttachmentResponse(&a))
+		}
+		return
+	}
+}
+
+func (s *Server) listAttachments(w http.ResponseWriter, r *http.Request) {
+	postID, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	attachments, err := blog.ListAttachments(r.Context(), s.db, postID)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	resp := make([]attachmentResponse, len(attachments))
+	for i := range attachments {
+		resp[i] = s.toAttachmentResponse(&attachments[i])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// downloadAttachment 返回附件内容. 图片在浏览器中直接显示, 其他类型作为下载;
+// 类型固定为上传时识别的结果, 禁止浏览器再次猜测
+func (s *Server) downloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	a, err := blog.GetAttachment(r.Context(), s.db, id)
+	if errors.Is(err, blog.ErrAttachmentNotFound) {
+		writeError(w, http.StatusNotFound, "附件不存在")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	f, err := blog.OpenAttachment(r.Context(), a)
+	switch {
+	case errors.Is(err, blog.ErrAttachmentsDisabled):
+		writeError(w, http.StatusServiceUnavailable, "附件存储未启用")
+		return
+	case errors.Is(err, blog.ErrAttachmentNotFound):
+		writeError(w, http.StatusNotFound, "附件不存在")
+		return
+	case err != nil:
+		s.internalError(w, err)
+		return
+	}
+	defer f.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(a.ContentType, "image/") {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, f); err != nil {
+		log.Printf("发送附件 %d 失败: %v", a.ID, err)
+	}
+}
+
+func (s *Server) deleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	err := blog.DeleteAttachment(r.Context(), s.db, id, currentUser(r).ID)
+	switch {
+	case errors.Is(err, blog.ErrAttachmentNotFound):
+		writeError(w, http.StatusNotFound, "附件不存在")
+	case errors.Is(err, blog.ErrForbidden):
+		writeError(w, http.StatusForbidden, "只有上传者可以删除附件")
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
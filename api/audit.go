@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/audit"
+	"github.com/jmoiron/sqlx"
+)
+
+type auditLogResponse struct {
+	ID        uint64          `json:"id"`
+	ActorID   string          `json:"actor_id,omitempty"` // 系统操作时为空
+	Entity    string          `json:"entity"`
+	EntityID  string          `json:"entity_id"`
+	Action    string          `json:"action"`
+	OldValues json.RawMessage `json:"old_values,omitempty"`
+	NewValues json.RawMessage `json:"new_values,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func (s *Server) toAuditLogResponse(l *audit.Log) auditLogResponse {
+	resp := auditLogResponse{
+		ID:        l.ID,
+		Entity:    l.Entity,
+		EntityID:  s.ids.Encode(uint(l.EntityID)),
+		Action:    l.Action,
+		CreatedAt: l.CreatedAt,
+	}
+	if l.ActorID != 0 {
+		resp.ActorID = s.ids.Encode(l.ActorID)
+	}
+	if l.OldValues != nil {
+		resp.OldValues = json.RawMessage(*l.OldValues)
+	}
+	if l.NewValues != nil {
+		resp.NewValues = json.RawMessage(*l.NewValues)
+	}
+	return resp
+}
+
+// auditLogs 按条件查询审计日志, 仅管理员可用. 查询参数 (均可选):
+//
+//	entity=posts  entity_id=<ID>  actor_id=<ID>  from=2024-01-01T00:00:00Z  to=...  limit=100
+//
+// entity=employees 查询人事库, 其余查询博客库
+func (s *Server) auditLogs(w http.ResponseWriter, r *http.Request) {
+	if !currentUser(r).IsAdmin {
+		writeError(w, http.StatusForbidden, "需要管理员权限")
+		return
+	}
+
+	filter, err := s.parseAuditFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var db *sqlx.DB
+	if filter.Entity == "employees" {
+		if s.hr == nil {
+			writeError(w, http.StatusServiceUnavailable, "人事系统不可用")
+			return
+		}
+		db = s.hr
+	} else {
+		sqlDB, err := s.db.DB()
+		if err != nil {
+			s.internalError(w, err)
+			return
+		}
+		db = sqlx.NewDb(sqlDB, "mysql")
+	}
+
+	logs, err := audit.Query(r.Context(), db, filter)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	resp := make([]auditLogResponse, len(logs))
+	for i := range logs {
+		resp[i] = s.toAuditLogResponse(&logs[i])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	q := r.URL.Query()
+	f := audit.Filter{Entity: q.Get("entity")}
+	if v := q.Get("entity_id"); v != "" {
+		id, err := s.ids.Decode(v)
+		if err != nil {
+			return f, fmt.Errorf("无效的 entity_id %q", v)
+		}
+		f.EntityID = uint64(id)
+	}
+	if v := q.Get("actor_id"); v != "" {
+		id, err := s.ids.Decode(v)
+		if err != nil {
+			return f, fmt.Errorf("无效的 actor_id %q", v)
+		}
+		f.ActorID = id
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("%s 应为 RFC 3339 格式的时间", p.name)
+			}
+			*p.dst = t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, fmt.Errorf("无效的 limit %q", v)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/alexwang789/Base1_golang_task3/apikey"
+	"github.com/alexwang789/Base1_golang_task3/audit"
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/middleware"
+	"github.com/alexwang789/Base1_golang_task3/validate"
+	"gorm.io/gorm"
+)
+
+type currentUserKey struct{}
+
+// requireUser 要求请求携带 HTTP Basic 认证 (邮箱 + 密码) 或 API Key (Authorization: Bearer, 见 apikey),
+// 认证通过后把用户放入请求 context, 并作为审计日志的操作者. 同一邮箱或 IP 认证失败过多时在一段时间内返回 429
+func (s *Server) requireUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user *blog.User
+			ok   bool
+		)
+		if token, isKey := bearerToken(r); isKey {
+			user, ok = s.authenticateKey(w, r, token)
+		} else {
+			user, ok = s.authenticateBasic(w, r)
+		}
+		if !ok {
+			return
+		}
+
+		// 请求中的写入在审计日志中记为该用户所做
+		ctx := audit.WithActor(context.WithValue(r.Context(), currentUserKey{}, user), user.ID)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// authenticateBasic 按 HTTP Basic 认证查找用户, 失败时写入响应并返回 false
+func (s *Server) authenticateBasic(w http.ResponseWriter, r *http.Request) (*blog.User, bool) {
+	email, password, ok := r.BasicAuth()
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="blog", charset="UTF-8"`)
+		writeError(w, http.StatusUnauthorized, "需要登录")
+		return nil, false
+	}
+
+	if err := s.checkLogin(r, email); err != nil {
+		middleware.WriteRateLimited(w, err)
+		return nil, false
+	}
+
+	var user blog.User
+	err := s.db.WithContext(r.Context()).Scopes(blog.ByEmail(email)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = *absentUser()
+	} else if err != nil {
+		s.internalError(w, err)
+		return nil, false
+	}
+	// 用户不存在与密码错误返回相同的响应, 也同样计算一次哈希, 耗时上无法区分
+	if !user.CheckPassword(password) || err != nil {
+		s.recordLoginFailure(r, email)
+		writeError(w, http.StatusUnauthorized, "邮箱或密码错误")
+		return nil, false
+	}
+	return &user, true
+}
+
+// absentUser 邮箱不存在时用来比对密码的用户, 密码是随机字符串的哈希, 任何密码都不匹配
+var absentUser = sync.OnceValue(func() *blog.User {
+	hash, err := blog.HashPassword(rand.Text())
+	if err != nil {
+		panic(err)
+	}
+	return &blog.User{Password: hash}
+})
+
+// authenticateKey 按 API Key 查找它代表的用户, 失败时写入响应并返回 false.
+// 只读的 key 只能调用 GET 和 HEAD, 其余方法返回 403
+func (s *Server) authenticateKey(w http.ResponseWriter, r *http.Request, token string) (*blog.User, bool) {
+	if err := s.checkLogin(r, ""); err != nil {
+		middleware.WriteRateLimited(w, err)
+		return nil, false
+	}
+
+	key, err := apikey.Authenticate(r.Context(), s.db, token)
+	if errors.Is(err, apikey.ErrInvalid) || errors.Is(err, apikey.ErrExpired) {
+		s.recordLoginFailure(r, "")
+		writeError(w, http.StatusUnauthorized, "API Key 无效或已过期")
+		return nil, false
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return nil, false
+	}
+	scope := apikey.ScopeWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		scope = apikey.ScopeRead
+	}
+	if !key.Has(scope) {
+		writeError(w, http.StatusForbidden, "API Key 没有 "+scope+" 权限")
+		return nil, false
+	}
+
+	user, err := s.users.GetByIDCached(r.Context(), key.UserID)
+	if errors.Is(err, blog.ErrUserNotFound) {
+		writeError(w, http.StatusUnauthorized, "API Key 无效或已过期")
+		return nil, false
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return nil, false
+	}
+	return user, true
+}
+
+// bearerToken 返回 Authorization: Bearer 携带的 API Key
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// currentUser 返回 requireUser 认证的用户
+func currentUser(r *http.Request) *blog.User {
+	user, _ := r.Context().Value(currentUserKey{}).(*blog.User)
+	return user
+}
+
+// resendVerificationEmail 重新发送当前用户的验证邮件, 邮箱已验证时返回 409
+func (s *Server) resendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	err := blog.ResendVerification(r.Context(), s.db, currentUser(r))
+	switch {
+	case errors.Is(err, blog.ErrEmailAlreadyVerified):
+		writeError(w, http.StatusConflict, "邮箱已验证")
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// verifyEmail 用验证邮件中的令牌验证邮箱, 不需要登录
+func (s *Server) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "请求体应为 {\"token\": \"...\"}")
+		return
+	}
+
+	err := blog.VerifyEmail(r.Context(), s.db, req.Token)
+	switch {
+	case errors.Is(err, blog.ErrVerificationToken):
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":  "数据校验失败",
+			"fields": validate.Errors{"token": "验证链接无效或已过期"},
+		})
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
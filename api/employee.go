@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	"github.com/alexwang789/Base1_golang_task3/filterdsl"
+)
+
+// listEmployees 按 filter 和 sort 参数 (见 filterdsl) 分页查询员工, 字段见 employee.FilterFields. 仅管理员可用
+func (s *Server) listEmployees(w http.ResponseWriter, r *http.Request) {
+	if !currentUser(r).IsAdmin {
+		writeError(w, http.StatusForbidden, "需要管理员权限")
+		return
+	}
+	if s.hr == nil {
+		writeError(w, http.StatusServiceUnavailable, "人事系统不可用")
+		return
+	}
+
+	q := r.URL.Query()
+	query, err := filterdsl.Parse(q.Get("filter"), q.Get("sort"), employee.FilterFields)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	page := employee.Page{Sort: query.Sort}
+	page.Number, _ = strconv.Atoi(q.Get("page"))
+	page.Size, _ = strconv.Atoi(q.Get("size"))
+
+	result, err := employee.Search(r.Context(), s.hr, employee.Filter{Conds: query.Conds}, page)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
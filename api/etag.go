@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// etag 由资源类型和最后修改时间生成弱 ETag, 如 W/"user-1718000000123".
+// 修改时间取到毫秒 (与库中 datetime(3) 一致, MySQL 写入时四舍五入), 刚写入返回的 ETag 与之后读出的相同.
+// 弱 ETag 只表示语义上相同: 浏览数、作者摘要等派生字段变化时不变, 只用于 If-None-Match
+func etag(kind string, updated time.Time) string {
+	var ms int64
+	if !updated.IsZero() {
+		ms = updated.Round(time.Millisecond).UnixMilli()
+	}
+	return `W/"` + kind + "-" + strconv.FormatInt(ms, 10) + `"`
+}
+
+// versionETag 由资源类型和修订号生成 If-Match 比较的强 ETag, 如 "post-v3". 响应中的 ETag 是弱的,
+// 不能用于 If-Match (RFC 9110 13.1.1 要求强比较), 客户端用响应中的 version 字段构造
+func versionETag(kind string, version uint) string {
+	return `"` + kind + "-v" + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// notModified 设置 ETag 响应头, If-None-Match 与之匹配时写 304 响应并返回 true
+func notModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+	w.Header().Set("ETag", tag)
+	if !etagMatch(r.Header.Get("If-None-Match"), tag, false) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// ifMatch 检查 If-Match: 没有该请求头时不限制, 否则须为 * 或按强比较包含 tag (见 versionETag),
+// 列表中的弱 ETag 一律不匹配. 不满足时应返回 412
+func ifMatch(r *http.Request, tag string) bool {
+	header := r.Header.Get("If-Match")
+	return header == "" || etagMatch(header, tag, true)
+}
+
+// etagMatch 判断逗号分隔的 ETag 列表是否包含 tag, * 匹配任意 ETag. 弱比较忽略 W/ 前缀,
+// 强比较要求两者都不是弱 ETag 且完全相同
+func etagMatch(header, tag string, strong bool) bool {
+	if header == "" || strong && strings.HasPrefix(tag, "W/") {
+		return false
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak := strings.HasPrefix(candidate, "W/"); weak && strong {
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// writePreconditionFailed 客户端持有的版本已过期, 应重新读取后再修改
+func writePreconditionFailed(w http.ResponseWriter) {
+	writeError(w, http.StatusPreconditionFailed, "资源已被修改, 请重新获取后再提交")
+}
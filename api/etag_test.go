@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestETagFormat(t *testing.T) {
+	updated := time.UnixMilli(1718000000123)
+	if got := etag("user", updated); got != `W/"user-1718000000123"` {
+		t.Errorf("etag() = %s", got)
+	}
+	if got := versionETag("post", 3); got != `"post-v3"` {
+		t.Errorf("versionETag() = %s", got)
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tag := versionETag("post", 3)
+	tests := []struct {
+		name   string
+		header string
+		tag    string
+		want   bool
+	}{
+		{"没有请求头", "", tag, true},
+		{"相同的修订号", `"post-v3"`, tag, true},
+		{"列表中包含", `"post-v1", "post-v3"`, tag, true},
+		{"星号", "*", tag, true},
+		{"不同的修订号", `"post-v2"`, tag, false},
+		{"弱 ETag", `W/"post-v3"`, tag, false},
+		{"列表中只有弱 ETag 相同", `"post-v1", W/"post-v3"`, tag, false},
+		{"响应中的弱 ETag", `W/"post-1718000000123"`, etag("post", time.UnixMilli(1718000000123)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/posts/1", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			if got := ifMatch(r, tt.tag); got != tt.want {
+				t.Errorf("ifMatch(%s, %s) = %v, 期望 %v", tt.header, tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotModifiedUsesWeakComparison(t *testing.T) {
+	tag := etag("post", time.UnixMilli(1718000000123))
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"没有请求头", "", false},
+		{"强 ETag", `"post-1718000000123"`, true},
+		{"弱 ETag", `W/"post-1718000000123"`, true},
+		{"星号", "*", true},
+		{"不同的 ETag", `W/"post-1"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/posts/1", nil)
+			if tt.header != "" {
+				r.Header.Set("If-None-Match", tt.header)
+			}
+			w := httptest.NewRecorder()
+			if got := notModified(w, r, tag); got != tt.want {
+				t.Errorf("notModified(%s) = %v, 期望 %v", tt.header, got, tt.want)
+			}
+			if w.Header().Get("ETag") != tag {
+				t.Errorf("ETag = %s, 期望 %s", w.Header().Get("ETag"), tag)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/fieldsel"
+)
+
+// postFields 文章响应中可以通过 fields 选择的字段及其需要查询的列
+var postFields = fieldsel.Fields{
+	"id":                  {Columns: []string{"id"}},
+	"uuid":                {Columns: []string{"uuid"}},
+	"title":               {Columns: []string{"title"}},
+	"content":             {Columns: []string{"content"}},
+	"comment_status":      {Columns: []string{"comment_status"}},
+	"comment_status_text": {Columns: []string{"comment_status"}},
+	"view_count":          {Columns: []string{"view_count"}},
+	"status":              {Columns: []string{"status"}},
+	"publish_at":          {Columns: []string{"publish_at"}},
+	"version":             {Columns: []string{"version"}},
+	"author_id":           {Columns: []string{"user_id"}},
+	"created_at":          {Columns: []string{"created_at"}},
+	"updated_at":          {Columns: []string{"updated_at"}},
+	"author":              {Columns: []string{"user_id"}, Nested: []string{"id", "name", "avatar_url"}},
+}
+
+// postEmbeds 文章响应中可以通过 embed 内嵌的关联
+var postEmbeds = fieldsel.Embeds{
+	"comments": {DefaultLimit: 5, MaxLimit: 20}, // 最新的已通过审核的评论
+}
+
+// postWithCommentsResponse 指定 embed=comments 时的文章
+type postWithCommentsResponse struct {
+	postResponse
+	Comments []commentResponse `json:"comments"` // 新评论在前
+}
+
+// parsePostSelection 解析 fields 和 embed 参数, 不合法时写 400 响应
+func parsePostSelection(w http.ResponseWriter, r *http.Request) (*fieldsel.Selection, bool) {
+	q := r.URL.Query()
+	sel, err := fieldsel.Parse(q.Get("fields"), q.Get("embed"), postFields, postEmbeds)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return sel, true
+}
+
+// shapePosts 按 sel 加载作者和内嵌的评论并转换为响应, 未选择 author 时不查询作者. 结果仍需 sel.Apply 裁剪字段
+func (s *Server) shapePosts(ctx context.Context, sel *fieldsel.Selection, posts []blog.Post) ([]any, error) {
+	if sel.Has("author") {
+		if err := blog.LoadAuthors(ctx, s.db, posts); err != nil {
+			return nil, err
+		}
+	}
+	limit, embed := sel.Embed("comments")
+	if embed {
+		if err := blog.LoadLatestComments(ctx, s.db, posts, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := make([]any, len(posts))
+	for i := range posts {
+		post := s.toPostResponse(ctx, &posts[i])
+		if !embed {
+			resp[i] = post
+			continue
+		}
+		comments := make([]commentResponse, len(posts[i].Comments))
+		for j := range posts[i].Comments {
+			comments[j] = s.toCommentResponse(&posts[i].Comments[j])
+		}
+		resp[i] = postWithCommentsResponse{postResponse: post, Comments: comments}
+	}
+	return resp, nil
+}
+
+// writeShapedPosts 按 sel 返回文章列表
+func (s *Server) writeShapedPosts(w http.ResponseWriter, r *http.Request, sel *fieldsel.Selection, posts []blog.Post) {
+	resp, err := s.shapePosts(r.Context(), sel, posts)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	s.writeShaped(w, sel, resp)
+}
+
+// writeShaped 按 sel 裁剪后返回 200 响应
+func (s *Server) writeShaped(w http.ResponseWriter, sel *fieldsel.Selection, resp any) {
+	shaped, err := sel.Apply(resp)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, shaped)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"gorm.io/gorm"
+)
+
+type feedResponse struct {
+	Posts      []postResponse `json:"posts"`
+	NextBefore string         `json:"next_before,omitempty"` // 下一页的 before 参数, 没有更多时省略
+}
+
+func (s *Server) followUser(w http.ResponseWriter, r *http.Request) {
+	s.setFollow(w, r, blog.FollowUser)
+}
+
+func (s *Server) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	s.setFollow(w, r, blog.UnfollowUser)
+}
+
+// setFollow 以当前用户身份关注或取消关注路径中的用户, 重复操作同样返回 204
+func (s *Server) setFollow(w http.ResponseWriter, r *http.Request, op func(context.Context, *gorm.DB, uint, uint) error) {
+	id, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	err := op(r.Context(), s.db, currentUser(r).ID, id)
+	switch {
+	case errors.Is(err, blog.ErrFollowSelf):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, blog.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, "用户不存在")
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// myFeed 当前用户关注的作者的文章, 新的在前, 用 before 翻页
+func (s *Server) myFeed(w http.ResponseWriter, r *http.Request) {
+	var page blog.FeedPage
+	if before := r.URL.Query().Get("before"); before != "" {
+		id, err := s.ids.Decode(before)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "before 无效")
+			return
+		}
+		page.Before = id
+	}
+	page.Size, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+
+	posts, err := blog.Feed(r.Context(), s.db, currentUser(r).ID, page)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if err := blog.LoadAuthors(r.Context(), s.db, posts); err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	resp := feedResponse{Posts: make([]postResponse, len(posts))}
+	for i := range posts {
+		resp.Posts[i] = s.toPostResponse(r.Context(), &posts[i])
+	}
+	size := page.Size
+	if size <= 0 {
+		size = blog.DefaultFeedSize
+	}
+	if len(posts) == min(size, blog.MaxFeedSize) {
+		resp.NextBefore = s.ids.Encode(posts[len(posts)-1].ID)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,521 @@
+package api
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alexwang789/Base1_golang_task3/validate"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIDocument 接口定义, 路由、认证要求和请求校验都以它为准
+//
+//go:embed openapi.yaml
+var openAPIDocument []byte
+
+// apiSpec 解析后的接口定义. 文档随二进制嵌入, 解析失败属于编程错误, 启动时 panic
+var apiSpec = mustLoadSpec(openAPIDocument)
+
+// 校验时请求体最多读取的字节数, 幂等键中间件同样按它限制
+const maxRequestBody = 1 << 20
+
+// errRequestTooLarge 请求体超过 maxRequestBody, 返回 413
+var errRequestTooLarge = fmt.Errorf("请求体超过 %d 字节", maxRequestBody)
+
+// openAPI 只解析路由、参数、请求体和响应的 schema, 其余字段 (描述、示例等) 忽略
+type openAPI struct {
+	Paths      map[string]map[string]*operation `yaml:"paths"`
+	Components struct {
+		Parameters map[string]*parameter `yaml:"parameters"`
+		Responses  map[string]*response  `yaml:"responses"`
+		Schemas    map[string]*schema    `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+type operation struct {
+	OperationID string                `yaml:"operationId"`
+	Security    []map[string][]string `yaml:"security"`
+	Parameters  []*parameter          `yaml:"parameters"`
+	RequestBody *requestBody          `yaml:"requestBody"`
+	Responses   map[string]*response  `yaml:"responses"`
+	RateLimit   *rateLimitPolicy      `yaml:"x-rate-limit"`
+	Idempotent  bool                  `yaml:"x-idempotent"` // 接受 Idempotency-Key, 见 idempotency
+
+	registered bool
+}
+
+type parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *schema `yaml:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `yaml:"required"`
+	Content  map[string]mediaType `yaml:"content"`
+}
+
+type response struct {
+	Ref     string               `yaml:"$ref"`
+	Content map[string]mediaType `yaml:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `yaml:"schema"`
+}
+
+// schema 支持的 JSON Schema 子集: 类型、必填、枚举、长度和取值范围、date-time 和 date 格式
+type schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 string             `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Nullable             bool               `yaml:"nullable"`
+	Enum                 []any              `yaml:"enum"`
+	Properties           map[string]*schema `yaml:"properties"`
+	Required             []string           `yaml:"required"`
+	AdditionalProperties *bool              `yaml:"additionalProperties"` // false 时不允许未定义的字段
+	Items                *schema            `yaml:"items"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	MinItems             *int               `yaml:"minItems"`
+	MaxItems             *int               `yaml:"maxItems"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+}
+
+func mustLoadSpec(doc []byte) *openAPI {
+	spec, err := loadSpec(doc)
+	if err != nil {
+		panic(err)
+	}
+	return spec
+}
+
+// loadSpec 解析文档并把 $ref 替换为引用的组件
+func loadSpec(doc []byte) (*openAPI, error) {
+	var spec openAPI
+	if err := yaml.Unmarshal(doc, &spec); err != nil {
+		return nil, fmt.Errorf("解析 OpenAPI 文档失败: %w", err)
+	}
+
+	for _, s := range spec.Components.Schemas {
+		if err := spec.resolveChildren(s); err != nil {
+			return nil, err
+		}
+	}
+	for path, ops := range spec.Paths {
+		for method, op := range ops {
+			if err := spec.resolveOperation(op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+		}
+	}
+	return &spec, nil
+}
+
+func (spec *openAPI) resolveOperation(op *operation) error {
+	for i, p := range op.Parameters {
+		if p.Ref != "" {
+			target := spec.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+			if target == nil {
+				return fmt.Errorf("未定义的参数 %s", p.Ref)
+			}
+			op.Parameters[i], p = target, target
+		}
+		var err error
+		if p.Schema, err = spec.resolve(p.Schema); err != nil {
+			return err
+		}
+	}
+	if op.RequestBody != nil {
+		if err := spec.resolveContent(op.RequestBody.Content); err != nil {
+			return err
+		}
+	}
+	for code, r := range op.Responses {
+		if r.Ref != "" {
+			target := spec.Components.Responses[strings.TrimPrefix(r.Ref, "#/components/responses/")]
+			if target == nil {
+				return fmt.Errorf("未定义的响应 %s", r.Ref)
+			}
+			op.Responses[code], r = target, target
+		}
+		if err := spec.resolveContent(r.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (spec *openAPI) resolveContent(content map[string]mediaType) error {
+	for typ, m := range content {
+		var err error
+		if m.Schema, err = spec.resolve(m.Schema); err != nil {
+			return err
+		}
+		content[typ] = m
+	}
+	return nil
+}
+
+// resolve 返回 s 引用的组件 schema, s 不是引用时解析它的子 schema 并返回 s 本身
+func (spec *openAPI) resolve(s *schema) (*schema, error) {
+	if s == nil {
+		return nil, nil
+	}
+	if s.Ref != "" {
+		target := spec.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		if target == nil {
+			return nil, fmt.Errorf("未定义的 schema %s", s.Ref)
+		}
+		return target, nil
+	}
+	return s, spec.resolveChildren(s)
+}
+
+func (spec *openAPI) resolveChildren(s *schema) error {
+	for name, p := range s.Properties {
+		resolved, err := spec.resolve(p)
+		if err != nil {
+			return err
+		}
+		s.Properties[name] = resolved
+	}
+	var err error
+	s.Items, err = spec.resolve(s.Items)
+	return err
+}
+
+// operation 返回路由模式 (如 "GET /posts/{id}") 对应的操作
+func (spec *openAPI) operation(pattern string) (*operation, error) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		return nil, fmt.Errorf("路由 %q 缺少请求方法", pattern)
+	}
+	op := spec.Paths[path][strings.ToLower(method)]
+	if op == nil {
+		return nil, fmt.Errorf("OpenAPI 文档中没有定义 %s", pattern)
+	}
+	return op, nil
+}
+
+// unregistered 返回文档中定义了但没有注册路由的操作
+func (spec *openAPI) unregistered() []string {
+	var missing []string
+	for path, ops := range spec.Paths {
+		for method, op := range ops {
+			if !op.registered {
+				missing = append(missing, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// requiresUser 操作是否要求 HTTP Basic 登录
+func (op *operation) requiresUser() bool {
+	return slices.ContainsFunc(op.Security, func(req map[string][]string) bool {
+		_, ok := req["basicAuth"]
+		return ok
+	})
+}
+
+// handle 按文档注册路由: 要求登录的操作先经 requireUser 认证, x-idempotent 的操作按 Idempotency-Key 去重
+// (重试的请求直接返回第一次的响应, 不计入限流), 再按 x-rate-limit 限流, 最后校验查询参数和请求体.
+// 路由在文档中没有定义时 panic, 路由与文档不会悄悄偏离
+func (s *Server) handle(mux *http.ServeMux, pattern string, h http.HandlerFunc) {
+	op, err := apiSpec.operation(pattern)
+	if err != nil {
+		panic(err)
+	}
+	op.registered = true
+
+	h = s.validated(op, h)
+	if op.RateLimit != nil {
+		if h, err = s.rateLimited(op, h); err != nil {
+			panic(err)
+		}
+	}
+	if op.Idempotent {
+		if !op.requiresUser() {
+			panic(fmt.Sprintf("%s 不要求登录, 不能使用 x-idempotent", op.OperationID))
+		}
+		h = s.idempotency.Middleware(func(r *http.Request) uint { return currentUser(r).ID }, maxRequestBody)(h).ServeHTTP
+	}
+	if op.requiresUser() {
+		h = s.requireUser(h)
+	}
+	mux.HandleFunc(pattern, h)
+}
+
+// validated 请求不符合文档时返回 400, 请求体过大时返回 413, 启用了响应校验时记录不符合文档的响应
+func (s *Server) validated(op *operation, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		errs, err := op.validateRequest(r)
+		if errors.Is(err, errRequestTooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(errs) > 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "请求不符合接口定义", "fields": errs})
+			return
+		}
+		// 指定 fields 时响应只有选择的字段, 不按文档中的必填字段校验
+		if !s.validateResponses || r.URL.Query().Has("fields") {
+			next(w, r)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		if errs := op.validateResponse(rec.status, rec.body.Bytes()); len(errs) > 0 {
+			log.Printf("%s %s 的 %d 响应不符合接口定义: %v", r.Method, r.URL.Path, rec.status, errs)
+		}
+	}
+}
+
+// validateRequest 校验查询参数和 JSON 请求体, 请求体读出后重新放回 r.Body 供处理函数读取
+func (op *operation) validateRequest(r *http.Request) (validate.Errors, error) {
+	errs := validate.Errors{}
+
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		if p.In != "query" {
+			continue
+		}
+		if !query.Has(p.Name) {
+			errs.Check(!p.Required, p.Name, "缺少参数")
+			continue
+		}
+		p.Schema.check(p.Name, queryValue(p.Schema, query.Get(p.Name)), errs)
+	}
+
+	if op.RequestBody == nil {
+		return errs, nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		// 其他类型的请求体 (如文件上传) 不缓存, 由处理函数流式读取
+		return errs, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取请求体失败: %w", err)
+	}
+	if len(data) > maxRequestBody {
+		return nil, errRequestTooLarge
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		errs.Check(!op.RequestBody.Required, "body", "缺少请求体")
+		return errs, nil
+	}
+	if media.Schema == nil {
+		return errs, nil
+	}
+	body, err := decodeJSON(data)
+	if err != nil {
+		errs.Check(false, "body", "请求体不是合法的 JSON")
+		return errs, nil
+	}
+	media.Schema.check("", body, errs)
+	return errs, nil
+}
+
+// validateResponse 校验 JSON 响应体, 文档中没有定义该状态码或没有 schema 时不校验
+func (op *operation) validateResponse(status int, body []byte) validate.Errors {
+	r := op.Responses[strconv.Itoa(status)]
+	if r == nil {
+		return nil
+	}
+	media, ok := r.Content["application/json"]
+	if !ok || media.Schema == nil {
+		return nil
+	}
+	errs := validate.Errors{}
+	v, err := decodeJSON(body)
+	if err != nil {
+		errs.Check(false, "body", "响应体不是合法的 JSON")
+		return errs
+	}
+	media.Schema.check("", v, errs)
+	return errs
+}
+
+// decodeJSON 解码任意 JSON, 数字保留为 json.Number 以区分整数
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// queryValue 把查询参数转换为与 JSON 解码结果相同的类型再按 schema 校验
+func queryValue(s *schema, v string) any {
+	if s == nil {
+		return v
+	}
+	switch s.Type {
+	case "integer", "number":
+		return json.Number(v)
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// check 按 schema 校验 v, 错误以字段路径 (如 ids[0]) 为键记入 errs, path 为空表示整个请求体
+func (s *schema) check(path string, v any, errs validate.Errors) {
+	if s == nil {
+		return
+	}
+	field := path
+	if field == "" {
+		field = "body"
+	}
+	if v == nil {
+		errs.Check(s.Nullable, field, "不能为 null")
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		m, ok := v.(map[string]any)
+		if !ok {
+			errs.Check(false, field, "应为对象")
+			return
+		}
+		for _, name := range s.Required {
+			_, present := m[name]
+			errs.Check(present, joinPath(path, name), "缺少该字段")
+		}
+		for name, value := range m {
+			if ps, ok := s.Properties[name]; ok {
+				ps.check(joinPath(path, name), value, errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				errs.Check(false, joinPath(path, name), "未定义的字段")
+			}
+		}
+	case "array":
+		a, ok := v.([]any)
+		if !ok {
+			errs.Check(false, field, "应为数组")
+			return
+		}
+		errs.Check(s.MinItems == nil || len(a) >= *s.MinItems, field, fmt.Sprintf("至少 %d 项", deref(s.MinItems)))
+		errs.Check(s.MaxItems == nil || len(a) <= *s.MaxItems, field, fmt.Sprintf("最多 %d 项", deref(s.MaxItems)))
+		for i, item := range a {
+			s.Items.check(fmt.Sprintf("%s[%d]", field, i), item, errs)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			errs.Check(false, field, "应为字符串")
+			return
+		}
+		n := utf8.RuneCountInString(str)
+		errs.Check(s.MinLength == nil || n >= *s.MinLength, field, fmt.Sprintf("至少 %d 个字符", deref(s.MinLength)))
+		errs.Check(s.MaxLength == nil || n <= *s.MaxLength, field, fmt.Sprintf("不能超过 %d 个字符", deref(s.MaxLength)))
+		switch s.Format {
+		case "date-time":
+			_, err := time.Parse(time.RFC3339, str)
+			errs.Check(err == nil, field, "应为 RFC 3339 格式的时间")
+		case "date":
+			_, err := time.Parse(time.DateOnly, str)
+			errs.Check(err == nil, field, "应为 YYYY-MM-DD 格式的日期")
+		}
+	case "integer", "number":
+		num, ok := v.(json.Number)
+		if !ok {
+			errs.Check(false, field, map[string]string{"integer": "应为整数", "number": "应为数字"}[s.Type])
+			return
+		}
+		f, err := num.Float64()
+		if s.Type == "integer" {
+			_, intErr := num.Int64()
+			errs.Check(intErr == nil, field, "应为整数")
+		}
+		if err != nil {
+			errs.Check(false, field, "应为数字")
+			return
+		}
+		errs.Check(s.Minimum == nil || f >= *s.Minimum, field, fmt.Sprintf("不能小于 %v", derefFloat(s.Minimum)))
+		errs.Check(s.Maximum == nil || f <= *s.Maximum, field, fmt.Sprintf("不能大于 %v", derefFloat(s.Maximum)))
+	case "boolean":
+		_, ok := v.(bool)
+		errs.Check(ok, field, "应为布尔值")
+	}
+
+	if len(s.Enum) > 0 {
+		errs.Check(slices.ContainsFunc(s.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(v) }),
+			field, fmt.Sprintf("应为 %v 之一", s.Enum))
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func deref(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+func derefFloat(p *float64) float64 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+// responseRecorder 在写出响应的同时保留状态码和响应体, 供响应校验使用
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// serveOpenAPI 返回接口文档
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.Write(openAPIDocument)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/alexwang789/Base1_golang_task3/ratelimit"
+)
+
+// newRouteTestServer 只用于注册路由的 Server, 不连接数据库
+func newRouteTestServer(t *testing.T) *Server {
+	t.Helper()
+	// 每个测试使用新解析的文档, 注册标记互不影响
+	saved := apiSpec
+	apiSpec = mustLoadSpec(openAPIDocument)
+	t.Cleanup(func() { apiSpec = saved })
+	return &Server{routeLimiters: map[string]ratelimit.Limiter{}}
+}
+
+// 注册的每个路由都要在 openapi.yaml 中定义, 文档中的每个操作也都要注册了路由
+func TestRoutesMatchSpec(t *testing.T) {
+	s := newRouteTestServer(t)
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("路由与 OpenAPI 文档不一致: %v", r)
+		}
+	}()
+	s.Routes()
+
+	if missing := apiSpec.unregistered(); len(missing) > 0 {
+		t.Errorf("文档中的操作没有注册路由: %v", missing)
+	}
+}
+
+func TestSpecOperationIDsUnique(t *testing.T) {
+	spec := mustLoadSpec(openAPIDocument)
+	seen := map[string]string{}
+	for path, ops := range spec.Paths {
+		for method, op := range ops {
+			where := method + " " + path
+			if op.OperationID == "" {
+				t.Errorf("%s 缺少 operationId", where)
+				continue
+			}
+			if prev, ok := seen[op.OperationID]; ok {
+				t.Errorf("operationId %s 重复: %s 和 %s", op.OperationID, prev, where)
+			}
+			seen[op.OperationID] = where
+		}
+	}
+}
+
+func TestSpecOperationLookup(t *testing.T) {
+	spec := mustLoadSpec(openAPIDocument)
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{"GET /posts/{id}", false},
+		{"GET /me/reading-progress", false},
+		{"DELETE /posts/{id}/comments", true},
+		{"/posts", true},
+	}
+	for _, tt := range tests {
+		_, err := spec.operation(tt.pattern)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("operation(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/filterdsl"
+	"github.com/alexwang789/Base1_golang_task3/validate"
+	"gorm.io/gorm"
+)
+
+// createPost 以当前用户身份发表文章, 邮箱未验证时返回 403. publish_at 晚于当前时间时定时发布, 到时间前只有作者可见
+func (s *Server) createPost(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Title     string     `json:"title"`
+		Content   string     `json:"content"`
+		PublishAt *time.Time `json:"publish_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "请求体应为 {\"title\": \"...\", \"content\": \"...\", \"publish_at\": \"RFC 3339 时间\"}")
+		return
+	}
+
+	post := blog.Post{Title: req.Title, Content: req.Content, PublishAt: req.PublishAt, UserID: currentUser(r).ID}
+	err := s.posts.Create(r.Context(), &post)
+	var verrs validate.Errors
+	switch {
+	case errors.As(err, &verrs):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "数据校验失败", "fields": verrs})
+	case errors.Is(err, blog.ErrEmailNotVerified):
+		writeError(w, http.StatusForbidden, "邮箱尚未验证")
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		writeJSON(w, http.StatusCreated, s.toPostResponse(r.Context(), &post))
+	}
+}
+
+// updatePost 作者修改文章的标题和内容. 带 If-Match ("post-v<version>") 时只在文章未被修改过时保存, 否则返回 412;
+// 并发的修改由 blog.EditPost 的乐观锁拦截, 同样返回 412
+func (s *Server) updatePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Title   string `json:"title"`
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "请求体应为 {\"title\": \"...\", \"content\": \"...\"}")
+		return
+	}
+
+	post := blog.Post{ID: id, Title: req.Title, Content: req.Content}
+	err := blog.EditPost(r.Context(), s.db, currentUser(r), &post, func(current *blog.Post) bool {
+		return ifMatch(r, versionETag("post", current.Version))
+	})
+	var verrs validate.Errors
+	switch {
+	case errors.As(err, &verrs):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "数据校验失败", "fields": verrs})
+	case errors.Is(err, blog.ErrPostNotFound):
+		writeError(w, http.StatusNotFound, "文章不存在")
+	case errors.Is(err, blog.ErrForbidden):
+		writeError(w, http.StatusForbidden, "只有作者可以修改文章")
+	case errors.Is(err, blog.ErrModified):
+		writePreconditionFailed(w)
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		w.Header().Set("ETag", etag("post", post.UpdatedAt))
+		writeJSON(w, http.StatusOK, s.toPostResponse(r.Context(), &post))
+	}
+}
+
+// myScheduledPosts 当前用户尚未发布的定时文章, 按计划发布时间排序
+func (s *Server) myScheduledPosts(w http.ResponseWriter, r *http.Request) {
+	s.writeMyPosts(w, r, blog.ListScheduledPosts)
+}
+
+// myArchivedPosts 当前用户已归档的文章, 最近归档的在前
+func (s *Server) myArchivedPosts(w http.ResponseWriter, r *http.Request) {
+	s.writeMyPosts(w, r, blog.ListArchivedPosts)
+}
+
+func (s *Server) writeMyPosts(w http.ResponseWriter, r *http.Request, list func(context.Context, *gorm.DB, uint) ([]blog.Post, error)) {
+	posts, err := list(r.Context(), s.db, currentUser(r).ID)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	resp := make([]postResponse, len(posts))
+	for i := range posts {
+		resp[i] = s.toPostResponse(r.Context(), &posts[i])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) archivePost(w http.ResponseWriter, r *http.Request) {
+	s.setArchived(w, r, blog.ArchivePost)
+}
+
+func (s *Server) restorePost(w http.ResponseWriter, r *http.Request) {
+	s.setArchived(w, r, blog.RestorePost)
+}
+
+// setArchived 归档或恢复路径中的文章, 作者和管理员可以操作
+func (s *Server) setArchived(w http.ResponseWriter, r *http.Request, op func(context.Context, *gorm.DB, *blog.User, uint) error) {
+	id, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	err := op(r.Context(), s.db, currentUser(r), id)
+	switch {
+	case errors.Is(err, blog.ErrPostNotFound):
+		writeError(w, http.StatusNotFound, "文章不存在")
+	case errors.Is(err, blog.ErrForbidden):
+		writeError(w, http.StatusForbidden, "只有作者和管理员可以归档或恢复文章")
+	case errors.Is(err, blog.ErrPostStatus):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// searchPosts 按 filter 和 sort 参数 (见 filterdsl) 分页查询对外可见的文章, 字段见 blog.PostFilterFields.
+// fields 和 embed 参数 (见 fieldsel) 选择返回的字段和内嵌的评论
+func (s *Server) searchPosts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query, err := filterdsl.Parse(q.Get("filter"), q.Get("sort"), blog.PostFilterFields)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sel, ok := parsePostSelection(w, r)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(q.Get("page"))
+	size, _ := strconv.Atoi(q.Get("size"))
+	if summaryView(r) {
+		posts, err := s.posts.SearchSummaries(r.Context(), query, page, size)
+		s.writePostSummaries(w, r, posts, err)
+		return
+	}
+
+	posts, err := s.posts.Search(r.Context(), query, page, size, sel.Scope("id"))
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	s.writeShapedPosts(w, r, sel, posts)
+}
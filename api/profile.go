@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/validate"
+)
+
+// myProfile 返回当前用户的资料, 没有保存过时各字段为空
+func (s *Server) myProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := blog.GetProfile(r.Context(), s.db, currentUser(r).ID)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if notModified(w, r, etag("profile", profile.UpdatedAt)) {
+		return
+	}
+	writeJSON(w, http.StatusOK, toProfileResponse(profile))
+}
+
+// updateMyProfile 整体替换当前用户的资料, 请求中省略的字段被清空. 带 If-Match ("profile-v<version>") 时只在资料未被修改过时保存, 否则返回 412
+func (s *Server) updateMyProfile(w http.ResponseWriter, r *http.Request) {
+	var req profileResponse
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "请求体应为 {\"bio\": \"...\", \"avatar_url\": \"...\", \"website\": \"...\", \"location\": \"...\"}")
+		return
+	}
+
+	profile := blog.Profile{
+		UserID:    currentUser(r).ID,
+		Bio:       req.Bio,
+		AvatarURL: req.AvatarURL,
+		Website:   req.Website,
+		Location:  req.Location,
+	}
+	err := blog.SaveProfile(r.Context(), s.db, &profile, func(current *blog.Profile) bool {
+		return ifMatch(r, versionETag("profile", current.Version))
+	})
+	var verrs validate.Errors
+	switch {
+	case errors.As(err, &verrs):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "数据校验失败", "fields": verrs})
+	case errors.Is(err, blog.ErrModified):
+		writePreconditionFailed(w)
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		w.Header().Set("ETag", etag("profile", profile.UpdatedAt))
+		writeJSON(w, http.StatusOK, toProfileResponse(&profile))
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/middleware"
+	"github.com/alexwang789/Base1_golang_task3/ratelimit"
+	"github.com/alexwang789/Base1_golang_task3/requestid"
+)
+
+// 默认的限流策略, 可用 RATE_LIMIT_<NAME> 覆盖. 路由级的策略在 openapi.yaml 的 x-rate-limit 中定义
+var (
+	// 每个客户端 IP 的全部请求
+	globalRateLimit = config.RateLimit{N: 600, Per: time.Minute}
+	// 每个邮箱和每个客户端 IP 的登录 (HTTP Basic 认证) 失败次数, 超出后在窗口内拒绝认证
+	loginRateLimit = config.RateLimit{N: 10, Per: 10 * time.Minute}
+)
+
+// rateLimitPolicy 操作的限流策略, 对应 openapi.yaml 中的 x-rate-limit 扩展
+type rateLimitPolicy struct {
+	Name  string `yaml:"name"`  // 同名的操作共享计数, RATE_LIMIT_<NAME> 覆盖默认值
+	Limit string `yaml:"limit"` // 默认值, 如 "30/1m"
+	Key   string `yaml:"key"`   // user: 按登录用户计数, 操作须要求登录; ip: 按客户端 IP 计数
+}
+
+// newLimiter 按策略创建限流器: 配置了 Redis 时多个实例共享计数, 否则为进程内令牌桶
+func (s *Server) newLimiter(name string, rl config.RateLimit) ratelimit.Limiter {
+	if s.redis != nil {
+		return s.redis.Limiter(name, rl.N, rl.Per)
+	}
+	return ratelimit.NewTokenBucket(rl.N, rl.Per)
+}
+
+// clientIP 返回请求的客户端 IP, 部署在反向代理之后时见 config.LoadTrustProxy
+func (s *Server) clientIP(r *http.Request) string {
+	return middleware.ClientIP(r, s.trustProxy)
+}
+
+// rateLimited 按操作的 x-rate-limit 策略限流, 同名策略只创建一个限流器
+func (s *Server) rateLimited(op *operation, next http.HandlerFunc) (http.HandlerFunc, error) {
+	p := op.RateLimit
+	def, err := config.ParseRateLimit(p.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s 的 x-rate-limit: %w", op.OperationID, err)
+	}
+
+	var key func(*http.Request) string
+	switch p.Key {
+	case "user":
+		if !op.requiresUser() {
+			return nil, fmt.Errorf("%s 不要求登录, x-rate-limit 不能按 user 计数", op.OperationID)
+		}
+		key = func(r *http.Request) string { return fmt.Sprintf("user:%d", currentUser(r).ID) }
+	case "ip":
+		key = func(r *http.Request) string { return "ip:" + s.clientIP(r) }
+	default:
+		return nil, fmt.Errorf("%s 的 x-rate-limit 计数维度 %q 无效, 应为 user 或 ip", op.OperationID, p.Key)
+	}
+
+	l, ok := s.routeLimiters[p.Name]
+	if !ok {
+		l = s.newLimiter("route:"+p.Name, config.LoadRateLimit(p.Name, def))
+		s.routeLimiters[p.Name] = l
+	}
+	return middleware.RateLimit(l, key)(next).ServeHTTP, nil
+}
+
+// loginKeys 登录失败按邮箱 (防止针对单个账号猜密码) 和客户端 IP (防止撞库) 分别计数, API Key 认证没有邮箱, 只按 IP 计数
+func (s *Server) loginKeys(r *http.Request, email string) []string {
+	if email == "" {
+		return []string{"ip:" + s.clientIP(r)}
+	}
+	return []string{"email:" + strings.ToLower(email), "ip:" + s.clientIP(r)}
+}
+
+// checkLogin 登录失败次数已超出限制时返回 *ratelimit.LimitError. 限流存储不可用时放行
+func (s *Server) checkLogin(r *http.Request, email string) error {
+	for _, key := range s.loginKeys(r, email) {
+		err := s.logins.Check(r.Context(), key)
+		if errors.Is(err, ratelimit.ErrRateLimited) {
+			return err
+		}
+		if err != nil {
+			log.Printf("[%s] 登录限流失败, 已放行: %v", requestid.From(r.Context()), err)
+		}
+	}
+	return nil
+}
+
+// recordLoginFailure 记一次登录失败
+func (s *Server) recordLoginFailure(r *http.Request, email string) {
+	for _, key := range s.loginKeys(r, email) {
+		err := s.logins.Allow(r.Context(), key)
+		if err != nil && !errors.Is(err, ratelimit.ErrRateLimited) {
+			log.Printf("[%s] 记录登录失败次数失败: %v", requestid.From(r.Context()), err)
+		}
+	}
+}
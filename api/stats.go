@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"gorm.io/gorm"
+)
+
+// 统计接口的默认时间范围和上限
+const (
+	defaultStatDays  = 30
+	maxStatDays      = 366
+	defaultStatWeeks = 12
+	maxStatWeeks     = 104
+	defaultTopAuthor = 10
+)
+
+type statPointResponse struct {
+	Start string `json:"start"` // 该段的开始日期, YYYY-MM-DD
+	Count int64  `json:"count"`
+}
+
+type commentBucketResponse struct {
+	Min   int64  `json:"min"`
+	Max   *int64 `json:"max,omitempty"` // 最后一个区间不设上限
+	Posts int64  `json:"posts"`
+}
+
+type authorStatResponse struct {
+	UserID   string `json:"user_id"`
+	Name     string `json:"name"`
+	Posts    int64  `json:"posts"`
+	Comments int64  `json:"comments"`
+}
+
+// postsPerDayStats 每天发布的文章数, 默认最近 30 天, 仅管理员可用
+func (s *Server) postsPerDayStats(w http.ResponseWriter, r *http.Request) {
+	s.writeSeries(w, r, blog.PostsPerDay, defaultStatDays*24*time.Hour, maxStatDays*24*time.Hour)
+}
+
+// newUsersPerWeekStats 每周的新用户数, 默认最近 12 周, 仅管理员可用
+func (s *Server) newUsersPerWeekStats(w http.ResponseWriter, r *http.Request) {
+	s.writeSeries(w, r, blog.NewUsersPerWeek, defaultStatWeeks*7*24*time.Hour, maxStatWeeks*7*24*time.Hour)
+}
+
+// writeSeries 按查询参数 from、to (YYYY-MM-DD, to 当天包含在内) 查询时间序列, 没有数据的段为 0.
+// 未指定 from 时取 to 之前的 def, 范围超过 maxRange 时返回 400
+func (s *Server) writeSeries(w http.ResponseWriter, r *http.Request, series func(context.Context, *gorm.DB, time.Time, time.Time) ([]blog.StatPoint, error), def, maxRange time.Duration) {
+	if !currentUser(r).IsAdmin {
+		writeError(w, http.StatusForbidden, "需要管理员权限")
+		return
+	}
+	from, to, ok := statRange(w, r, def)
+	if !ok {
+		return
+	}
+	if to.Sub(from) > maxRange {
+		writeError(w, http.StatusBadRequest, "时间范围过大")
+		return
+	}
+
+	points, err := series(r.Context(), s.db, from, to)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	resp := make([]statPointResponse, len(points))
+	for i, p := range points {
+		resp[i] = statPointResponse{Start: p.Start.Format(time.DateOnly), Count: p.Count}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// statRange 解析查询参数 from 和 to 为 [from, to 的次日) 的本地时间, to 默认今天, from 默认 to 之前的 def.
+// 格式已由 openapi.yaml 校验
+func statRange(w http.ResponseWriter, r *http.Request, def time.Duration) (from, to time.Time, ok bool) {
+	q := r.URL.Query()
+	now := time.Now()
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if v := q.Get("to"); v != "" {
+		to, _ = time.ParseInLocation(time.DateOnly, v, time.Local)
+	}
+	to = to.AddDate(0, 0, 1)
+	from = to.Add(-def)
+	if v := q.Get("from"); v != "" {
+		from, _ = time.ParseInLocation(time.DateOnly, v, time.Local)
+	}
+	if !from.Before(to) {
+		writeError(w, http.StatusBadRequest, "from 不能晚于 to")
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// commentsPerPostStats 对外可见的文章的评论数分布, 仅管理员可用
+func (s *Server) commentsPerPostStats(w http.ResponseWriter, r *http.Request) {
+	if !currentUser(r).IsAdmin {
+		writeError(w, http.StatusForbidden, "需要管理员权限")
+		return
+	}
+	buckets, err := blog.CommentsPerPost(r.Context(), s.db)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	resp := make([]commentBucketResponse, len(buckets))
+	for i, b := range buckets {
+		resp[i] = commentBucketResponse{Min: b.Min, Posts: b.Posts}
+		if b.Max >= 0 {
+			upper := b.Max
+			resp[i].Max = &upper
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// topAuthorsStats 时间范围内发表文章最多的作者, 默认最近 30 天的前 10 名, 仅管理员可用
+func (s *Server) topAuthorsStats(w http.ResponseWriter, r *http.Request) {
+	if !currentUser(r).IsAdmin {
+		writeError(w, http.StatusForbidden, "需要管理员权限")
+		return
+	}
+	from, to, ok := statRange(w, r, defaultStatDays*24*time.Hour)
+	if !ok {
+		return
+	}
+	limit := defaultTopAuthor
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, _ = strconv.Atoi(v)
+	}
+
+	authors, err := blog.TopAuthors(r.Context(), s.db, from, to, limit)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	resp := make([]authorStatResponse, len(authors))
+	for i, a := range authors {
+		resp[i] = authorStatResponse{UserID: s.ids.Encode(a.UserID), Name: a.Name, Posts: a.PostCount, Comments: a.CommentCount}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
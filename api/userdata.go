@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+)
+
+// myDataExport 以附件返回当前用户的全部数据 (资料、文章、评论、点赞和待执行的注销请求)
+func (s *Server) myDataExport(w http.ResponseWriter, r *http.Request) {
+	data, err := blog.ExportUserData(r.Context(), s.db, currentUser(r).ID)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	name := fmt.Sprintf("user-%d-%s.json", data.User.ID, data.ExportedAt.Format("20060102"))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	writeJSON(w, http.StatusOK, data)
+}
+
+type erasureResponse struct {
+	Mode    blog.ErasureMode `json:"mode"`
+	EraseAt time.Time        `json:"erase_at"`
+}
+
+// requestMyErasure 申请注销当前用户, 宽限期后执行; 宽限期内可以撤销
+func (s *Server) requestMyErasure(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Mode blog.ErasureMode `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Mode.Valid() {
+		writeError(w, http.StatusBadRequest, "请求体应为 {\"mode\": \"anonymize\" 或 \"delete\"}")
+		return
+	}
+	e, err := blog.RequestErasure(r.Context(), s.db, currentUser(r).ID, req.Mode)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, erasureResponse{Mode: e.Mode, EraseAt: e.EraseAt})
+}
+
+// cancelMyErasure 撤销当前用户待执行的注销请求
+func (s *Server) cancelMyErasure(w http.ResponseWriter, r *http.Request) {
+	err := blog.CancelErasure(r.Context(), s.db, currentUser(r).ID)
+	switch {
+	case errors.Is(err, blog.ErrNoErasure):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,142 @@
+// Package apikey 服务间调用的 API Key. 机器客户端以 "Authorization: Bearer <key>" 调用 REST API,
+// 每个 key 代表一个用户 (通常是专门的服务账号), 请求以该用户的身份和权限执行, 并按 scope 限制为只读或可写.
+//
+// 库中只保存 key 的 SHA-256, 明文只在 Issue 时返回一次. key 属于创建时 ctx 的租户, 只能在该租户的站点使用.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// key 的权限范围
+const (
+	ScopeRead  = "read"  // 只读操作
+	ScopeWrite = "write" // 写操作
+)
+
+// Scopes 全部权限范围
+var Scopes = []string{ScopeRead, ScopeWrite}
+
+// 明文 key 的前缀, 便于在日志和代码仓库中识别泄漏的 key
+const prefix = "bk_"
+
+// 最近使用时间的更新间隔, 避免每个请求都写一次
+const touchInterval = time.Minute
+
+var (
+	// ErrInvalid key 不存在或格式错误
+	ErrInvalid = errors.New("API Key 无效")
+	// ErrExpired key 已过期
+	ErrExpired = errors.New("API Key 已过期")
+)
+
+// Key 一个 API Key
+type Key struct {
+	ID         uint       `gorm:"primaryKey"`
+	TenantID   uint       `gorm:"not null;default:1;index"`     // 所属租户
+	UserID     uint       `gorm:"not null;index"`               // key 代表的用户
+	Name       string     `gorm:"size:100;not null"`            // 用途, 如 "搜索索引同步"
+	Hash       string     `gorm:"size:64;not null;uniqueIndex"` // 明文 key 的 SHA-256
+	Scopes     string     `gorm:"size:100;not null"`            // 逗号分隔的权限范围, 如 "read,write"
+	ExpiresAt  *time.Time // 为 nil 时不过期
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// TableName 指定表名
+func (Key) TableName() string {
+	return "api_keys"
+}
+
+// Has key 是否有权限范围 scope
+func (k *Key) Has(scope string) bool {
+	return slices.Contains(strings.Split(k.Scopes, ","), scope)
+}
+
+// Issue 为用户 userID 创建 key, 返回明文 key (只此一次) 和记录. ttl 为 0 时不过期
+func Issue(ctx context.Context, db *gorm.DB, userID uint, name string, scopes []string, ttl time.Duration) (string, *Key, error) {
+	if len(scopes) == 0 {
+		return "", nil, errors.New("至少需要一个权限范围")
+	}
+	for _, s := range scopes {
+		if !slices.Contains(Scopes, s) {
+			return "", nil, fmt.Errorf("未知的权限范围 %q, 可选 %v", s, Scopes)
+		}
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, fmt.Errorf("生成 API Key 失败: %w", err)
+	}
+	plain := prefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	key := &Key{UserID: userID, Name: name, Hash: hash(plain), Scopes: strings.Join(scopes, ",")}
+	if ttl > 0 {
+		expires := time.Now().Add(ttl)
+		key.ExpiresAt = &expires
+	}
+	if err := db.WithContext(ctx).Create(key).Error; err != nil {
+		return "", nil, fmt.Errorf("保存 API Key 失败: %w", err)
+	}
+	return plain, key, nil
+}
+
+// Authenticate 按明文 key 查找 key, 不存在时返回 ErrInvalid, 已过期时返回 ErrExpired. 同时记下最近使用时间
+func Authenticate(ctx context.Context, db *gorm.DB, plain string) (*Key, error) {
+	if !strings.HasPrefix(plain, prefix) {
+		return nil, ErrInvalid
+	}
+	var key Key
+	err := db.WithContext(ctx).Where("hash = ?", hash(plain)).Take(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询 API Key 失败: %w", err)
+	}
+	now := time.Now()
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval {
+		// 只是统计信息, 失败不影响认证
+		db.WithContext(ctx).Model(&key).UpdateColumn("last_used_at", now)
+	}
+	return &key, nil
+}
+
+// List 返回 ctx 的租户的全部 key, 新的在前
+func List(ctx context.Context, db *gorm.DB) ([]Key, error) {
+	var keys []Key
+	if err := db.WithContext(ctx).Order("id DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("查询 API Key 失败: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke 删除 key, 之后使用它的请求认证失败. key 不存在时返回 ErrInvalid
+func Revoke(ctx context.Context, db *gorm.DB, id uint) error {
+	result := db.WithContext(ctx).Delete(&Key{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("删除 API Key 失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
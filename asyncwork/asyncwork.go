@@ -0,0 +1,158 @@
+// Package asyncwork 把 GORM 钩子中的非关键副作用 (通知、缓存失效等) 移出请求路径.
+//
+// 钩子调用 Defer 把任务挂到当前事务上; 事务提交后任务交给有界的 Pool 异步执行, 回滚时丢弃.
+// 事务由 GORM 的默认事务 (单条 Create/Update/Delete) 开启时, Plugin 自动在提交后提交任务;
+// 显式事务需要用 WithPending 包装并在结束时调用 Finish. 不在这两种事务中时 Defer 返回 false,
+// 调用方应同步执行任务.
+//
+// 队列已满时任务在提交它的 goroutine 中同步执行, 以此对请求施加背压而不是丢弃.
+package asyncwork
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrClosed Pool 已关闭
+var ErrClosed = errors.New("asyncwork: 任务池已关闭")
+
+// Task 一个异步任务. 任务在事务提交后执行, 需要自行读取最新状态, 不能假设钩子执行时的数据仍然有效
+type Task struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// 单个任务的执行时限
+const taskTimeout = 30 * time.Second
+
+// Pool 固定数量的 worker 和有界队列
+type Pool struct {
+	tasks chan Task
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewPool 启动 workers 个 worker, 队列最多缓存 queueSize 个任务
+func NewPool(workers, queueSize int) *Pool {
+	p := &Pool{tasks: make(chan Task, queueSize)}
+	p.wg.Add(workers)
+	for range workers {
+		go p.work()
+	}
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		run(task)
+	}
+}
+
+// run 执行任务并记录错误, panic 不会让 worker 退出
+func run(task Task) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("异步任务 %s panic: %v", task.Name, r)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), taskTimeout)
+	defer cancel()
+	if err := task.Run(ctx); err != nil {
+		log.Printf("异步任务 %s 失败: %v", task.Name, err)
+	}
+}
+
+// Submit 把任务放入队列; 队列已满或 Pool 已关闭时在当前 goroutine 中同步执行
+func (p *Pool) Submit(task Task) {
+	p.mu.RLock()
+	if !p.closed {
+		select {
+		case p.tasks <- task:
+			p.mu.RUnlock()
+			return
+		default:
+		}
+	}
+	p.mu.RUnlock()
+	run(task)
+}
+
+// Shutdown 停止接收新任务, 等待队列中的任务执行完成. ctx 到期时返回 ctx.Err(), 剩余任务在后台继续执行
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.closed = true
+	close(p.tasks)
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type pendingKey struct{}
+
+// Pending 一个事务中挂起的任务
+type Pending struct {
+	pool *Pool
+
+	mu    sync.Mutex
+	tasks []Task
+}
+
+// WithPending 返回挂载了任务列表的 ctx, 在该 ctx 下执行的事务中 Defer 的任务会加入列表.
+// 已挂载时 (外层事务) 返回原 ctx 和 nil, 任务归外层事务
+func WithPending(ctx context.Context, pool *Pool) (context.Context, *Pending) {
+	if _, ok := ctx.Value(pendingKey{}).(*Pending); ok {
+		return ctx, nil
+	}
+	p := &Pending{pool: pool}
+	return context.WithValue(ctx, pendingKey{}, p), p
+}
+
+// Finish 事务结束时调用: committed 为 true 时提交全部任务, 否则丢弃. p 为 nil 时不做任何事
+func (p *Pending) Finish(committed bool) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	tasks := p.tasks
+	p.tasks = nil
+	p.mu.Unlock()
+
+	if !committed {
+		return
+	}
+	for _, task := range tasks {
+		p.pool.Submit(task)
+	}
+}
+
+// Defer 把任务挂到 ctx 所在的事务上, ctx 没有挂载任务列表时返回 false
+func Defer(ctx context.Context, task Task) bool {
+	p, ok := ctx.Value(pendingKey{}).(*Pending)
+	if !ok {
+		return false
+	}
+	p.mu.Lock()
+	p.tasks = append(p.tasks, task)
+	p.mu.Unlock()
+	return true
+}
@@ -0,0 +1,59 @@
+package asyncwork
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Plugin 为 GORM 默认事务 (单条 Create/Update/Delete 自动开启的事务) 挂载任务列表,
+// 提交后把钩子中 Defer 的任务交给 Pool
+type Plugin struct {
+	pool *Pool
+}
+
+// NewPlugin 创建插件
+func NewPlugin(pool *Pool) *Plugin {
+	return &Plugin{pool: pool}
+}
+
+// Name 实现 gorm.Plugin
+func (p *Plugin) Name() string {
+	return "asyncwork"
+}
+
+const pendingSetting = "asyncwork:pending"
+
+// Initialize 实现 gorm.Plugin, 在开启和结束默认事务的回调之后注册
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	errs := []error{
+		cb.Create().After("gorm:begin_transaction").Register("asyncwork:begin", p.begin),
+		cb.Create().After("gorm:commit_or_rollback_transaction").Register("asyncwork:finish", finish),
+		cb.Update().After("gorm:begin_transaction").Register("asyncwork:begin", p.begin),
+		cb.Update().After("gorm:commit_or_rollback_transaction").Register("asyncwork:finish", finish),
+		cb.Delete().After("gorm:begin_transaction").Register("asyncwork:begin", p.begin),
+		cb.Delete().After("gorm:commit_or_rollback_transaction").Register("asyncwork:finish", finish),
+	}
+	return errors.Join(errs...)
+}
+
+// begin 本条语句开启了事务时挂载任务列表, 之后执行的钩子从 Statement.Context 中取得它
+func (p *Plugin) begin(db *gorm.DB) {
+	if _, started := db.InstanceGet("gorm:started_transaction"); !started {
+		return
+	}
+	ctx, pending := WithPending(db.Statement.Context, p.pool)
+	if pending == nil {
+		return
+	}
+	db.Statement.Context = ctx
+	db.InstanceSet(pendingSetting, pending)
+}
+
+// finish 事务提交成功 (db.Error 包含提交错误) 时提交任务
+func finish(db *gorm.DB) {
+	if v, ok := db.InstanceGet(pendingSetting); ok {
+		v.(*Pending).Finish(db.Error == nil)
+	}
+}
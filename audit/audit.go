@@ -0,0 +1,263 @@
+// Package audit 把用户、文章、评论和员工的每次写入记录到 audit_logs 表: 操作者、实体、动作和变化字段的前后取值.
+//
+// 博客库的写入由 GORM 插件 Plugin 在写入所在的事务中自动记录; 员工模块 (sqlx) 在各写入函数中调用 Write.
+// 操作者从 ctx 中读取 (见 WithActor), 未设置时记为 0, 表示系统或命令行操作.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 审计动作
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Log 一条审计记录
+type Log struct {
+	ID        uint64    `gorm:"primaryKey" db:"id" json:"id"`
+	ActorID   uint      `db:"actor_id" json:"actor_id"` // 0 表示系统
+	Entity    string    `db:"entity" json:"entity"`     // 表名
+	EntityID  uint64    `db:"entity_id" json:"entity_id"`
+	Action    string    `db:"action" json:"action"`
+	OldValues *string   `db:"old_values" json:"-"` // 变化字段的旧值 (JSON 对象), 创建时为 NULL
+	NewValues *string   `db:"new_values" json:"-"` // 变化字段的新值 (JSON 对象), 删除时为 NULL
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Log) TableName() string {
+	return "audit_logs"
+}
+
+// Migrate 创建 audit_logs 表, 博客库和人事库各有一张, 可重复执行
+func Migrate(ctx context.Context, db sqlx.ExecerContext) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS audit_logs (
+			id         BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			actor_id   BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			entity     VARCHAR(64) NOT NULL,
+			entity_id  BIGINT UNSIGNED NOT NULL,
+			action     VARCHAR(10) NOT NULL,
+			old_values JSON NULL,
+			new_values JSON NULL,
+			created_at DATETIME(3) NOT NULL,
+			KEY idx_audit_logs_entity (entity, entity_id, created_at),
+			KEY idx_audit_logs_created (created_at)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建审计日志表失败: %w", err)
+	}
+	return nil
+}
+
+type actorKey struct{}
+
+// WithActor 返回携带操作者 ID 的 ctx, 在该 ctx 下的写入记为 actorID 所做
+func WithActor(ctx context.Context, actorID uint) context.Context {
+	return context.WithValue(ctx, actorKey{}, actorID)
+}
+
+// ActorFrom 返回 ctx 中的操作者, 未设置时为 0
+func ActorFrom(ctx context.Context) uint {
+	id, _ := ctx.Value(actorKey{}).(uint)
+	return id
+}
+
+// 只记录发生了变化、不记录取值的字段
+var redacted = map[string]bool{"password": true}
+
+// 更新时不参与比较的字段, 只有这些字段变化不产生记录
+var ignored = map[string]bool{"updated_at": true}
+
+// NewLog 比较实体写入前后的字段 (列名 -> 值), 创建时 before 为 nil, 删除时 after 为 nil.
+// 更新只保留变化的字段, 没有字段变化时返回 false
+func NewLog(ctx context.Context, entity string, entityID uint64, before, after map[string]any) (Log, bool, error) {
+	log := Log{
+		ActorID:   ActorFrom(ctx),
+		Entity:    entity,
+		EntityID:  entityID,
+		CreatedAt: time.Now(),
+	}
+	switch {
+	case before == nil:
+		log.Action = ActionCreate
+	case after == nil:
+		log.Action = ActionDelete
+	default:
+		log.Action = ActionUpdate
+	}
+
+	oldValues, newValues := map[string]any{}, map[string]any{}
+	for _, column := range columns(before, after) {
+		b, inBefore := before[column]
+		a, inAfter := after[column]
+		if log.Action == ActionUpdate {
+			if ignored[column] {
+				continue
+			}
+			same, err := equal(b, a)
+			if err != nil {
+				return Log{}, false, err
+			}
+			if inBefore && inAfter && same {
+				continue
+			}
+		}
+		if redacted[column] {
+			b, a = "***", "***"
+		}
+		if inBefore {
+			oldValues[column] = b
+		}
+		if inAfter {
+			newValues[column] = a
+		}
+	}
+	if log.Action == ActionUpdate && len(oldValues) == 0 && len(newValues) == 0 {
+		return Log{}, false, nil
+	}
+
+	var err error
+	if before != nil {
+		if log.OldValues, err = marshal(oldValues); err != nil {
+			return Log{}, false, err
+		}
+	}
+	if after != nil {
+		if log.NewValues, err = marshal(newValues); err != nil {
+			return Log{}, false, err
+		}
+	}
+	return log, true, nil
+}
+
+// Fields 把结构体按 JSON 标签转换为 列名 -> 值, 用于字段名与列名一致的模型 (如 employee.Employee)
+func Fields(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("序列化审计字段失败: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("序列化审计字段失败: %w", err)
+	}
+	return fields, nil
+}
+
+// Write 写入审计记录
+func Write(ctx context.Context, db sqlx.ExtContext, logs ...Log) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	_, err := sqlx.NamedExecContext(ctx, db, `
+		INSERT INTO audit_logs (actor_id, entity, entity_id, action, old_values, new_values, created_at)
+		VALUES (:actor_id, :entity, :entity_id, :action, :old_values, :new_values, :created_at)
+	`, logs)
+	if err != nil {
+		return fmt.Errorf("写入审计日志失败: %w", err)
+	}
+	return nil
+}
+
+// columns 返回两侧出现过的全部列
+func columns(before, after map[string]any) []string {
+	seen := make(map[string]bool, len(before)+len(after))
+	var names []string
+	for _, m := range []map[string]any{before, after} {
+		for name := range m {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// equal 按 JSON 表示比较, 时间等类型的内部表示 (时区、单调时钟) 不同但取值相同时视为相等
+func equal(a, b any) (bool, error) {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false, fmt.Errorf("序列化审计字段失败: %w", err)
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false, fmt.Errorf("序列化审计字段失败: %w", err)
+	}
+	return string(ja) == string(jb), nil
+}
+
+func marshal(values map[string]any) (*string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("序列化审计字段失败: %w", err)
+	}
+	s := string(data)
+	return &s, nil
+}
+
+// Filter 审计记录的查询条件, 零值的条件不生效
+type Filter struct {
+	Entity   string
+	EntityID uint64
+	ActorID  uint
+	From     time.Time // 含
+	To       time.Time // 不含
+	Limit    int       // 默认 100, 最多 1000
+}
+
+// Query 按时间倒序返回符合条件的审计记录
+func Query(ctx context.Context, db sqlx.QueryerContext, f Filter) ([]Log, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	if f.Entity != "" {
+		conds, args = append(conds, "entity = ?"), append(args, f.Entity)
+	}
+	if f.EntityID != 0 {
+		conds, args = append(conds, "entity_id = ?"), append(args, f.EntityID)
+	}
+	if f.ActorID != 0 {
+		conds, args = append(conds, "actor_id = ?"), append(args, f.ActorID)
+	}
+	if !f.From.IsZero() {
+		conds, args = append(conds, "created_at >= ?"), append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		conds, args = append(conds, "created_at < ?"), append(args, f.To)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	limit = min(limit, 1000)
+
+	var logs []Log
+	err := sqlx.SelectContext(ctx, db, &logs, `
+		SELECT id, actor_id, entity, entity_id, action, old_values, new_values, created_at
+		FROM audit_logs
+		`+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("查询审计日志失败: %w", err)
+	}
+	return logs, nil
+}
@@ -0,0 +1,220 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Plugin 记录指定表上经 GORM Create/Update/Delete 的写入. 更新和删除前先按同样的条件加锁读出受影响的行,
+// 写入后再按主键读出新值比较, 审计记录与业务数据在同一事务中提交.
+// 原生 SQL (db.Exec) 不经过这些回调, 不会被记录
+type Plugin struct {
+	tables map[string]bool
+}
+
+// NewPlugin 创建插件, tables 为需要审计的表名, 表须有单列主键
+func NewPlugin(tables ...string) *Plugin {
+	p := &Plugin{tables: map[string]bool{}}
+	for _, t := range tables {
+		p.tables[t] = true
+	}
+	return p
+}
+
+// Name 实现 gorm.Plugin
+func (p *Plugin) Name() string {
+	return "audit"
+}
+
+const beforeSetting = "audit:before"
+
+// Initialize 实现 gorm.Plugin
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	errs := []error{
+		cb.Create().After("gorm:create").Register("audit:create", p.afterCreate),
+		cb.Update().Before("gorm:update").Register("audit:before_update", p.snapshot),
+		cb.Update().After("gorm:update").Register("audit:update", p.afterUpdate),
+		cb.Delete().Before("gorm:delete").Register("audit:before_delete", p.snapshot),
+		cb.Delete().After("gorm:delete").Register("audit:delete", p.afterDelete),
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Plugin) audited(db *gorm.DB) bool {
+	stmt := db.Statement
+	return db.Error == nil && stmt.Schema != nil && stmt.Schema.PrioritizedPrimaryField != nil && p.tables[stmt.Table]
+}
+
+func (p *Plugin) afterCreate(db *gorm.DB) {
+	if !p.audited(db) || db.RowsAffected == 0 {
+		return
+	}
+	after, err := load(db, primaryKeys(db))
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	var logs []Log
+	for id, row := range after {
+		log, _, err := NewLog(db.Statement.Context, db.Statement.Table, id, nil, row)
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		logs = append(logs, log)
+	}
+	write(db, logs)
+}
+
+// snapshot 在更新或删除前读出将受影响的行
+func (p *Plugin) snapshot(db *gorm.DB) {
+	if !p.audited(db) {
+		return
+	}
+	stmt := db.Statement
+	q := db.Session(&gorm.Session{NewDB: true}).Table(stmt.Table)
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			q = q.Clauses(where)
+		}
+	}
+	// 主键非零的模型 (如 Model(&user).Update) 在 gorm:update/gorm:delete 中才追加主键条件
+	if ids := primaryKeys(db); len(ids) > 0 {
+		q = q.Where(clause.IN{Column: clause.Column{Name: stmt.Schema.PrioritizedPrimaryField.DBName}, Values: ids})
+	}
+	if _, inTx := stmt.ConnPool.(gorm.TxCommitter); inTx {
+		q = q.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var rows []map[string]any
+	if err := q.Find(&rows).Error; err != nil {
+		db.AddError(fmt.Errorf("读取审计前的数据失败: %w", err))
+		return
+	}
+	before, err := byID(db, rows)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(beforeSetting, before)
+}
+
+func (p *Plugin) afterUpdate(db *gorm.DB) {
+	before := snapshotOf(db)
+	if !p.audited(db) || len(before) == 0 {
+		return
+	}
+	ids := make([]any, 0, len(before))
+	for id := range before {
+		ids = append(ids, id)
+	}
+	after, err := load(db, ids)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	var logs []Log
+	for id, row := range before {
+		if after[id] == nil {
+			continue
+		}
+		log, changed, err := NewLog(db.Statement.Context, db.Statement.Table, id, row, after[id])
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		if changed {
+			logs = append(logs, log)
+		}
+	}
+	write(db, logs)
+}
+
+func (p *Plugin) afterDelete(db *gorm.DB) {
+	before := snapshotOf(db)
+	if !p.audited(db) || db.RowsAffected == 0 {
+		return
+	}
+	var logs []Log
+	for id, row := range before {
+		log, _, err := NewLog(db.Statement.Context, db.Statement.Table, id, row, nil)
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		logs = append(logs, log)
+	}
+	write(db, logs)
+}
+
+func snapshotOf(db *gorm.DB) map[uint64]map[string]any {
+	v, _ := db.InstanceGet(beforeSetting)
+	before, _ := v.(map[uint64]map[string]any)
+	return before
+}
+
+// primaryKeys 返回语句模型 (单个或切片) 中非零的主键值
+func primaryKeys(db *gorm.DB) []any {
+	stmt := db.Statement
+	field := stmt.Schema.PrioritizedPrimaryField
+	rv := reflect.Indirect(stmt.ReflectValue)
+
+	var ids []any
+	switch rv.Kind() {
+	case reflect.Struct:
+		if v, zero := field.ValueOf(stmt.Context, rv); !zero {
+			ids = append(ids, v)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range rv.Len() {
+			if v, zero := field.ValueOf(stmt.Context, reflect.Indirect(rv.Index(i))); !zero {
+				ids = append(ids, v)
+			}
+		}
+	}
+	return ids
+}
+
+// load 在当前事务中按主键读出行的全部列
+func load(db *gorm.DB, ids []any) (map[uint64]map[string]any, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	stmt := db.Statement
+	var rows []map[string]any
+	err := db.Session(&gorm.Session{NewDB: true}).Table(stmt.Table).
+		Where(clause.IN{Column: clause.Column{Name: stmt.Schema.PrioritizedPrimaryField.DBName}, Values: ids}).
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("读取审计后的数据失败: %w", err)
+	}
+	return byID(db, rows)
+}
+
+func byID(db *gorm.DB, rows []map[string]any) (map[uint64]map[string]any, error) {
+	pk := db.Statement.Schema.PrioritizedPrimaryField.DBName
+	m := make(map[uint64]map[string]any, len(rows))
+	for _, row := range rows {
+		id, err := strconv.ParseUint(fmt.Sprint(row[pk]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s 的主键 %v 不是整数", db.Statement.Table, row[pk])
+		}
+		m[id] = row
+	}
+	return m, nil
+}
+
+func write(db *gorm.DB, logs []Log) {
+	if len(logs) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Create(&logs).Error; err != nil {
+		db.AddError(fmt.Errorf("写入审计日志失败: %w", err))
+	}
+}
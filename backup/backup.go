@@ -0,0 +1,310 @@
+// Package backup 把库中的表导出为 SQL 或 JSONL 文本, 以及把导出的文件导回库中.
+//
+// 导出用普通查询逐行读取 (不调用 mysqldump), 在一个一致性快照事务中完成, 各表的数据属于同一时刻.
+// 每张表先写表结构 (SHOW CREATE TABLE) 再写数据, 导入时先删除同名表再按表结构重建.
+// 压缩由调用方负责, 见 cmd/task3 的 backup 和 restore 命令.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Formats 支持的格式
+var Formats = []string{"sql", "jsonl"}
+
+// insertBatchSize SQL 格式每条 INSERT 的行数, 也是导入 JSONL 时每条 INSERT 的行数
+const insertBatchSize = 200
+
+// Filter 按表名筛选要导出或导入的表, 支持 path.Match 的通配符 (如 webhook_*)
+type Filter struct {
+	Include []string // 为空表示全部表
+	Exclude []string // 优先于 Include
+}
+
+// Match 表 table 是否通过筛选
+func (f Filter) Match(table string) bool {
+	if slices.ContainsFunc(f.Exclude, func(p string) bool { return matchTable(p, table) }) {
+		return false
+	}
+	return len(f.Include) == 0 || slices.ContainsFunc(f.Include, func(p string) bool { return matchTable(p, table) })
+}
+
+// Validate 检查通配符的语法
+func (f Filter) Validate() error {
+	for _, p := range slices.Concat(f.Include, f.Exclude) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("表名模式 %q 不合法: %w", p, err)
+		}
+	}
+	return nil
+}
+
+func matchTable(pattern, table string) bool {
+	ok, _ := path.Match(pattern, table)
+	return ok
+}
+
+// TableStats 一张表导出或导入的行数
+type TableStats struct {
+	Table string
+	Rows  int64
+}
+
+// header JSONL 格式中每张表的第一行, 之后每行是该表的一行数据
+type header struct {
+	Table   string   `json:"table"`
+	Create  string   `json:"create"`
+	Columns []string `json:"columns"`
+	Binary  []string `json:"binary,omitempty"` // 二进制列, 值以 base64 编码
+}
+
+// record JSONL 格式中的一行数据, 值按 header.Columns 的顺序排列
+type record struct {
+	Values []any `json:"values"`
+}
+
+// listTables 返回当前库中通过筛选的表, 按表名排序
+func listTables(ctx context.Context, conn *sql.Conn, filter Filter) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT TABLE_NAME FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询表失败: %w", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("读取表名失败: %w", err)
+		}
+		if filter.Match(name) {
+			tables = append(tables, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取表名失败: %w", err)
+	}
+	return tables, nil
+}
+
+// Dump 把当前库中通过筛选的表按 format 写入 w, 返回各表的行数.
+// 数据逐行流式读取, 不会把整张表读入内存. db 的连接串不应设置 parseTime, 时间列按 MySQL 的文本格式原样导出
+func Dump(ctx context.Context, db *sql.DB, w io.Writer, format string, filter Filter) ([]TableStats, error) {
+	if !slices.Contains(Formats, format) {
+		return nil, fmt.Errorf("不支持的格式 %q, 可选 %s", format, strings.Join(Formats, ", "))
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+		return nil, fmt.Errorf("设置隔离级别失败: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY"); err != nil {
+		return nil, fmt.Errorf("开启快照事务失败: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+
+	tables, err := listTables(ctx, conn, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	bw := bufio.NewWriter(w)
+	d := &dumper{conn: conn, w: bw, format: format}
+	if format == "sql" {
+		fmt.Fprint(bw, "SET NAMES utf8mb4;\nSET FOREIGN_KEY_CHECKS = 0;\n")
+	}
+	stats := make([]TableStats, 0, len(tables))
+	for _, t := range tables {
+		n, err := d.table(ctx, t)
+		if err != nil {
+			return stats, err
+		}
+		stats = append(stats, TableStats{Table: t, Rows: n})
+	}
+	if format == "sql" {
+		fmt.Fprint(bw, "SET FOREIGN_KEY_CHECKS = 1;\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return stats, fmt.Errorf("写入备份失败: %w", err)
+	}
+	return stats, nil
+}
+
+type dumper struct {
+	conn   *sql.Conn
+	w      *bufio.Writer
+	format string
+}
+
+// column 导出时对一列的处理方式
+type column struct {
+	name    string
+	numeric bool // 数值列, 不加引号
+	binary  bool // 二进制列, SQL 中以十六进制、JSONL 中以 base64 表示
+}
+
+func (d *dumper) table(ctx context.Context, table string) (int64, error) {
+	var name, create string
+	err := d.conn.QueryRowContext(ctx, "SHOW CREATE TABLE "+quoteName(table)).Scan(&name, &create)
+	if err != nil {
+		return 0, fmt.Errorf("查询表 %s 的结构失败: %w", table, err)
+	}
+
+	rows, err := d.conn.QueryContext(ctx, "SELECT * FROM "+quoteName(table))
+	if err != nil {
+		return 0, fmt.Errorf("查询表 %s 失败: %w", table, err)
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, fmt.Errorf("查询表 %s 的列失败: %w", table, err)
+	}
+	cols := make([]column, len(types))
+	for i, t := range types {
+		cols[i] = classify(t.Name(), t.DatabaseTypeName())
+	}
+
+	if err := d.begin(table, create, cols); err != nil {
+		return 0, err
+	}
+	values := make([]sql.RawBytes, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("读取表 %s 失败: %w", table, err)
+		}
+		if err := d.row(table, cols, values, n); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, fmt.Errorf("读取表 %s 失败: %w", table, err)
+	}
+	if d.format == "sql" && n > 0 {
+		d.w.WriteString(";\n")
+	}
+	return n, nil
+}
+
+// begin 写入表的开头: SQL 格式为删表和建表语句, JSONL 格式为 header
+func (d *dumper) begin(table, create string, cols []column) error {
+	if d.format == "sql" {
+		fmt.Fprintf(d.w, "\n-- table: %s\nDROP TABLE IF EXISTS %s;\n%s;\n", table, quoteName(table), create)
+		return nil
+	}
+	h := header{Table: table, Create: create}
+	for _, c := range cols {
+		h.Columns = append(h.Columns, c.name)
+		if c.binary {
+			h.Binary = append(h.Binary, c.name)
+		}
+	}
+	return d.writeJSON(h)
+}
+
+// row 写入第 n 行 (从 0 开始). SQL 格式每 insertBatchSize 行合并为一条 INSERT
+func (d *dumper) row(table string, cols []column, values []sql.RawBytes, n int64) error {
+	if d.format == "jsonl" {
+		rec := record{Values: make([]any, len(values))}
+		for i, v := range values {
+			switch {
+			case v == nil:
+			case cols[i].binary:
+				rec.Values[i] = base64.StdEncoding.EncodeToString(v)
+			case cols[i].numeric:
+				rec.Values[i] = json.Number(v)
+			default:
+				rec.Values[i] = string(v)
+			}
+		}
+		return d.writeJSON(rec)
+	}
+
+	if n%insertBatchSize == 0 {
+		if n > 0 {
+			d.w.WriteString(";\n")
+		}
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = quoteName(c.name)
+		}
+		fmt.Fprintf(d.w, "INSERT INTO %s (%s) VALUES\n", quoteName(table), strings.Join(names, ", "))
+	} else {
+		d.w.WriteString(",\n")
+	}
+	d.w.WriteByte('(')
+	for i, v := range values {
+		if i > 0 {
+			d.w.WriteString(", ")
+		}
+		switch {
+		case v == nil:
+			d.w.WriteString("NULL")
+		case cols[i].binary:
+			d.w.WriteString("X'" + hex.EncodeToString(v) + "'")
+		case cols[i].numeric:
+			d.w.Write(v)
+		default:
+			d.w.WriteString(quoteString(string(v)))
+		}
+	}
+	d.w.WriteByte(')')
+	return nil
+}
+
+func (d *dumper) writeJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("编码备份失败: %w", err)
+	}
+	d.w.Write(b)
+	return d.w.WriteByte('\n')
+}
+
+// classify 按驱动报告的列类型决定值的写法
+func classify(name, typ string) column {
+	c := column{name: name}
+	switch typ {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "DECIMAL", "FLOAT", "DOUBLE", "YEAR",
+		"UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT", "UNSIGNED BIGINT":
+		c.numeric = true
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+		c.binary = true
+	}
+	return c
+}
+
+// quoteName 以反引号引用表名或列名
+func quoteName(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// stringEscaper 转义字符串字面量中的特殊字符, 换行也被转义, 因此每条语句中只有建表语句会跨行
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\x00", `\0`, "\x1a", `\Z`)
+
+// quoteString 把字符串写成 SQL 字符串字面量, 要求导入时未启用 NO_BACKSLASH_ESCAPES
+func quoteString(s string) string {
+	return "'" + stringEscaper.Replace(s) + "'"
+}
@@ -0,0 +1,232 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Restore 把 Dump 写出的备份导入当前库, 返回各表导入的行数. 只有通过筛选的表被导入.
+// 备份中的每张表先被删除再按备份的表结构重建. 建表语句会隐式提交, 中途失败时已导入的表保留
+func Restore(ctx context.Context, db *sql.DB, r io.Reader, format string, filter Filter) ([]TableStats, error) {
+	if !slices.Contains(Formats, format) {
+		return nil, fmt.Errorf("不支持的格式 %q, 可选 %s", format, strings.Join(Formats, ", "))
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	// 备份中的表按表名排序而不是按外键依赖排序, 导入期间关闭外键检查
+	for _, stmt := range []string{
+		"SET NAMES utf8mb4",
+		"SET FOREIGN_KEY_CHECKS = 0",
+		"SET SESSION sql_mode = REPLACE(@@SESSION.sql_mode, 'NO_BACKSLASH_ESCAPES', '')",
+	} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("设置会话失败: %w", err)
+		}
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SET FOREIGN_KEY_CHECKS = 1")
+
+	br := bufio.NewReaderSize(r, 1<<20)
+	if format == "sql" {
+		return restoreSQL(ctx, conn, br, filter)
+	}
+	return restoreJSONL(ctx, conn, br, filter)
+}
+
+// restoreSQL 逐条执行 SQL 备份中的语句. Dump 写出的语句都以 ";" 结尾并换行, 字符串中的换行已转义,
+// 因此以 ";" 结尾的行就是语句的结尾. 每张表的语句前有 "-- table: <表名>" 注释, 用于按表筛选
+func restoreSQL(ctx context.Context, conn *sql.Conn, r *bufio.Reader, filter Filter) ([]TableStats, error) {
+	var (
+		stats   []TableStats
+		current *TableStats
+		skip    bool
+		stmt    strings.Builder
+	)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return stats, fmt.Errorf("读取备份失败: %w", err)
+		}
+		text := strings.TrimRight(line, "\r\n")
+
+		switch {
+		case stmt.Len() == 0 && strings.HasPrefix(text, "-- table: "):
+			table := strings.TrimPrefix(text, "-- table: ")
+			skip = !filter.Match(table)
+			current = nil
+			if !skip {
+				stats = append(stats, TableStats{Table: table})
+				current = &stats[len(stats)-1]
+			}
+		case stmt.Len() == 0 && (text == "" || strings.HasPrefix(text, "--")):
+		default:
+			stmt.WriteString(line)
+			if !strings.HasSuffix(text, ";") {
+				break
+			}
+			query := stmt.String()
+			stmt.Reset()
+			if skip {
+				break
+			}
+			res, err := conn.ExecContext(ctx, query)
+			if err != nil {
+				return stats, fmt.Errorf("执行备份中的语句失败: %w", err)
+			}
+			if current != nil && strings.HasPrefix(query, "INSERT ") {
+				n, _ := res.RowsAffected()
+				current.Rows += n
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	if strings.TrimSpace(stmt.String()) != "" {
+		return stats, errors.New("备份不完整: 最后一条语句没有结尾")
+	}
+	return stats, nil
+}
+
+// restoreJSONL 按 JSONL 备份中的表结构建表, 再把数据按 insertBatchSize 行一批插入
+func restoreJSONL(ctx context.Context, conn *sql.Conn, r *bufio.Reader, filter Filter) ([]TableStats, error) {
+	var (
+		stats  []TableStats
+		table  *tableLoader
+		lineNo int
+	)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return stats, fmt.Errorf("读取备份失败: %w", err)
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			lineNo++
+			var entry struct {
+				header
+				record
+			}
+			dec := json.NewDecoder(bytes.NewReader(line))
+			dec.UseNumber()
+			if err := dec.Decode(&entry); err != nil {
+				return stats, fmt.Errorf("备份第 %d 行: 解析失败: %w", lineNo, err)
+			}
+
+			if entry.Table != "" {
+				if err := table.flush(ctx); err != nil {
+					return stats, err
+				}
+				if table != nil && !table.skip {
+					stats = append(stats, TableStats{Table: table.Table, Rows: table.rows})
+				}
+				table = &tableLoader{header: entry.header, conn: conn, skip: !filter.Match(entry.Table)}
+				if err := table.create(ctx); err != nil {
+					return stats, err
+				}
+			} else {
+				if table == nil {
+					return stats, fmt.Errorf("备份第 %d 行: 数据行之前没有表结构", lineNo)
+				}
+				if err := table.add(ctx, entry.Values); err != nil {
+					return stats, fmt.Errorf("备份第 %d 行: %w", lineNo, err)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	if err := table.flush(ctx); err != nil {
+		return stats, err
+	}
+	if table != nil && !table.skip {
+		stats = append(stats, TableStats{Table: table.Table, Rows: table.rows})
+	}
+	return stats, nil
+}
+
+// tableLoader 导入 JSONL 备份中的一张表, 攒够一批数据后插入
+type tableLoader struct {
+	header
+	conn    *sql.Conn
+	skip    bool
+	pending [][]any
+	rows    int64
+}
+
+func (t *tableLoader) create(ctx context.Context) error {
+	if t.skip {
+		return nil
+	}
+	if _, err := t.conn.ExecContext(ctx, "DROP TABLE IF EXISTS "+quoteName(t.Table)); err != nil {
+		return fmt.Errorf("删除表 %s 失败: %w", t.Table, err)
+	}
+	if _, err := t.conn.ExecContext(ctx, t.Create); err != nil {
+		return fmt.Errorf("创建表 %s 失败: %w", t.Table, err)
+	}
+	return nil
+}
+
+func (t *tableLoader) add(ctx context.Context, values []any) error {
+	if t.skip {
+		return nil
+	}
+	if len(values) != len(t.Columns) {
+		return fmt.Errorf("表 %s 有 %d 列, 数据有 %d 个值", t.Table, len(t.Columns), len(values))
+	}
+	for i, v := range values {
+		switch v := v.(type) {
+		case json.Number:
+			values[i] = v.String()
+		case string:
+			if slices.Contains(t.Binary, t.Columns[i]) {
+				b, err := base64.StdEncoding.DecodeString(v)
+				if err != nil {
+					return fmt.Errorf("列 %s 的二进制值不合法: %w", t.Columns[i], err)
+				}
+				values[i] = b
+			}
+		}
+	}
+	t.pending = append(t.pending, values)
+	if len(t.pending) >= insertBatchSize {
+		return t.flush(ctx)
+	}
+	return nil
+}
+
+func (t *tableLoader) flush(ctx context.Context) error {
+	if t == nil || len(t.pending) == 0 {
+		return nil
+	}
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = quoteName(c)
+	}
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(t.Columns)), ", ") + ")"
+	query := "INSERT INTO " + quoteName(t.Table) + " (" + strings.Join(names, ", ") + ") VALUES " +
+		strings.TrimSuffix(strings.Repeat(row+", ", len(t.pending)), ", ")
+	args := make([]any, 0, len(t.pending)*len(t.Columns))
+	for _, values := range t.pending {
+		args = append(args, values...)
+	}
+	if _, err := t.conn.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("导入表 %s 失败: %w", t.Table, err)
+	}
+	t.rows += int64(len(t.pending))
+	t.pending = t.pending[:0]
+	return nil
+}
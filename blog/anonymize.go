@@ -0,0 +1,53 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/anonymize"
+	"github.com/alexwang789/Base1_golang_task3/fieldcrypt"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"gorm.io/gorm"
+)
+
+// AnonymizeUsers 把全部租户的用户名、邮箱和密码改写为假数据, 并清空资料中的网站和所在地. 用于在本地调试时使用生产数据的副本.
+// 用户名为假姓名加主键 (租户内仍唯一), 邮箱为 example.com 下的地址, 密码统一改为 password. 主键不变, 文章、评论等关联数据不受影响.
+// 直接改写列值, 不经过钩子, 不产生事件和审计日志. 返回改写的用户数
+func AnonymizeUsers(ctx context.Context, db *gorm.DB, faker *anonymize.Faker, password string) (int, error) {
+	ctx = tenant.All(ctx)
+	keyring := fieldcrypt.Current()
+	updated := 0
+	var rows []struct{ ID uint }
+	err := db.WithContext(ctx).Table("users").Select("id").
+		FindInBatches(&rows, DefaultBatchSize, func(*gorm.DB, int) error {
+			for _, r := range rows {
+				id := uint64(r.ID)
+				p := faker.Person(id)
+				email := p.Email(id)
+				sealed, err := keyring.Encrypt(email)
+				if err != nil {
+					return err
+				}
+				err = db.WithContext(ctx).Table("users").Where("id = ?", r.ID).UpdateColumns(map[string]any{
+					"name":       p.UniqueName(id),
+					"email":      sealed,
+					"email_hash": emailHash(email),
+					"password":   password,
+				}).Error
+				if err != nil {
+					return fmt.Errorf("改写用户 %d 失败: %w", r.ID, err)
+				}
+				updated++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return updated, fmt.Errorf("改写用户失败: %w", err)
+	}
+
+	err = db.WithContext(ctx).Exec("UPDATE profiles SET website = '', location = ''").Error
+	if err != nil {
+		return updated, fmt.Errorf("清空用户资料失败: %w", err)
+	}
+	return updated, nil
+}
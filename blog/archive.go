@@ -0,0 +1,107 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"gorm.io/gorm"
+)
+
+// PostArchived 已归档: 文章和它的评论不再对外展示, 作者可以恢复
+const PostArchived PostStatus = "archived"
+
+// ErrPostStatus 文章当前的发布状态不允许该操作, 如归档尚未发布的定时文章
+var ErrPostStatus = errors.New("文章状态不允许该操作")
+
+// PublicOnly 只保留对外可见的文章: 已发布且未归档 (scopes.PublishedOnly). 所有对外的文章查询都通过它过滤,
+// 原生 SQL 中等价的条件为 posts.status = PostPublished
+func PublicOnly() scopes.Scope {
+	return scopes.PublishedOnly
+}
+
+// PublicComments 只保留对外可见的评论: 已通过审核, 且所属文章对外可见 (见 PublicOnly).
+// 文章归档后它的评论随之隐藏, 恢复后重新可见, 评论本身的审核状态不变
+func PublicComments() scopes.Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Scopes(approvedComments).
+			Where("EXISTS (SELECT 1 FROM posts WHERE posts.id = comments.post_id AND posts.status = ?)", PostPublished)
+	}
+}
+
+// Public 文章是否对外可见, 与 PublicOnly 一致
+func (p *Post) Public() bool {
+	return p.Status == PostPublished
+}
+
+// ArchivePost 归档文章, 文章和评论在同一事务中对外隐藏, 作者的文章数减一. 作者和管理员可以操作
+func ArchivePost(ctx context.Context, db *gorm.DB, operator *User, postID uint) error {
+	return setPostArchived(ctx, db, operator, postID, true)
+}
+
+// RestorePost 恢复已归档的文章, 作者和管理员可以操作
+func RestorePost(ctx context.Context, db *gorm.DB, operator *User, postID uint) error {
+	return setPostArchived(ctx, db, operator, postID, false)
+}
+
+func setPostArchived(ctx context.Context, db *gorm.DB, operator *User, postID uint, archive bool) error {
+	from, to, delta := PostPublished, PostArchived, -1
+	if !archive {
+		from, to, delta = PostArchived, PostPublished, 1
+	}
+
+	return transaction(ctx, db, func(tx *gorm.DB) error {
+		var post Post
+		if err := WithRowLock(tx, &post, postID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("文章 %d: %w", postID, ErrPostNotFound)
+			}
+			return err
+		}
+		if operator == nil || operator.ID != post.UserID && !operator.IsAdmin {
+			return ErrForbidden
+		}
+		if post.Status != from {
+			return fmt.Errorf("文章 %d 为 %s: %w", postID, post.Status, ErrPostStatus)
+		}
+
+		updates := map[string]any{"status": to, "archived_at": nil}
+		if archive {
+			updates["archived_at"] = time.Now()
+		}
+		if err := tx.Model(&post).Updates(updates).Error; err != nil {
+			return fmt.Errorf("更新文章状态失败: %w", err)
+		}
+
+		if err := WithRowLock(tx, &User{}, post.UserID); err != nil {
+			return err
+		}
+		err := tx.Model(&User{}).Where("id = ?", post.UserID).
+			Update("article_count", gorm.Expr("article_count + ?", delta)).Error
+		if err != nil {
+			return fmt.Errorf("更新作者文章数失败: %w", err)
+		}
+		if archive {
+			err = addPostArchived(tx, &post)
+		} else {
+			err = addPostRestored(tx, &post)
+		}
+		if err != nil {
+			return err
+		}
+		return invalidateUserCacheAfterCommit(tx, post.UserID)
+	})
+}
+
+// ListArchivedPosts 按归档时间倒序返回用户已归档的文章
+func ListArchivedPosts(ctx context.Context, db *gorm.DB, userID uint) ([]Post, error) {
+	var posts []Post
+	err := db.WithContext(ctx).Scopes(scopes.ByUser(userID)).Where("status = ?", PostArchived).
+		Order("archived_at DESC").Order("id DESC").Find(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询已归档的文章失败: %w", err)
+	}
+	return posts, nil
+}
@@ -0,0 +1,54 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/asyncwork"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"gorm.io/gorm"
+)
+
+// 钩子副作用的任务池和执行任务用的连接, 由 EnableAsyncHooks 设置; 为 nil 时副作用在钩子中同步执行
+var (
+	asyncPool *asyncwork.Pool
+	asyncDB   *gorm.DB
+)
+
+// EnableAsyncHooks 把通知、缓存失效等非关键的钩子副作用移到 pool 中, 在事务提交后执行.
+// 计数器、文章统计和发件箱事件 (webhook 由它异步投递) 仍在事务中同步写入, 与业务数据一起提交或回滚
+func EnableAsyncHooks(db *gorm.DB, pool *asyncwork.Pool) error {
+	if err := db.Use(asyncwork.NewPlugin(pool)); err != nil {
+		return fmt.Errorf("注册 asyncwork 插件失败: %w", err)
+	}
+	asyncDB, asyncPool = db, pool
+	return nil
+}
+
+// afterCommit 执行非关键副作用 fn. 启用了异步钩子且 tx 处于可追踪的事务中时,
+// fn 在事务提交后由任务池用新连接执行, 沿用 tx 的租户; 否则立即在 tx 中同步执行
+func afterCommit(tx *gorm.DB, name string, fn func(db *gorm.DB) error) error {
+	if asyncPool != nil {
+		from := tx.Statement.Context
+		deferred := asyncwork.Defer(from, asyncwork.Task{
+			Name: name,
+			Run:  func(ctx context.Context) error { return fn(asyncDB.WithContext(tenant.Inherit(ctx, from))) },
+		})
+		if deferred {
+			return nil
+		}
+	}
+	return fn(tx)
+}
+
+// transaction 在事务中执行 fn, 其中 afterCommit 挂起的副作用在提交后执行.
+// db 本身已处于事务中时无法得知最终是否提交, 副作用改为同步执行
+func transaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); asyncPool == nil || inTx {
+		return db.WithContext(ctx).Transaction(fn)
+	}
+	ctx, pending := asyncwork.WithPending(ctx, asyncPool)
+	err := db.WithContext(ctx).Transaction(fn)
+	pending.Finish(err == nil)
+	return err
+}
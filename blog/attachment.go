@@ -0,0 +1,230 @@
+package blog
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alexwang789/Base1_golang_task3/storage"
+	"gorm.io/gorm"
+)
+
+// Attachment 文章的附件, 文件内容保存在存储中, 表中只记录元数据
+type Attachment struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	PostID      uint   `gorm:"not null;index"`
+	UserID      uint   `gorm:"not null"`                      // 上传者, 即文章作者
+	StorageKey  string `gorm:"size:100;not null;uniqueIndex"` // 存储中的对象 key
+	FileName    string `gorm:"size:255;not null"`             // 上传时的文件名, 下载时原样返回
+	ContentType string `gorm:"size:100;not null"`             // 按文件内容识别的 MIME 类型
+	Size        int64  `gorm:"not null"`
+	CreatedAt   time.Time
+}
+
+var (
+	// ErrAttachmentsDisabled 没有调用 EnableAttachments 配置存储
+	ErrAttachmentsDisabled = errors.New("附件存储未启用")
+	// ErrAttachmentNotFound 附件不存在
+	ErrAttachmentNotFound = errors.New("附件不存在")
+	// ErrAttachmentTooLarge 附件超过 AttachmentLimits.MaxSize
+	ErrAttachmentTooLarge = errors.New("附件过大")
+	// ErrAttachmentType 附件的类型不在 AttachmentLimits.AllowedTypes 中
+	ErrAttachmentType = errors.New("不支持的附件类型")
+)
+
+// AttachmentLimits 附件的校验规则
+type AttachmentLimits struct {
+	MaxSize      int64    // 单个附件的最大字节数
+	AllowedTypes []string // 允许的 MIME 类型, 不含参数, 如 "image/png"
+}
+
+// 附件的存储和校验规则, 由 EnableAttachments 设置; 存储为 nil 时上传和下载返回 ErrAttachmentsDisabled
+var (
+	attachmentStore  storage.Store
+	attachmentLimits AttachmentLimits
+)
+
+// EnableAttachments 设置附件的存储和校验规则. 删除文章时其附件的文件也从 store 中删除
+func EnableAttachments(store storage.Store, limits AttachmentLimits) {
+	attachmentStore, attachmentLimits = store, limits
+}
+
+// 识别类型需要的文件头长度, 见 http.DetectContentType
+const sniffLen = 512
+
+// UploadAttachment 把 body 保存为文章 a.PostID 的附件, a 需要填写 PostID、UserID 和 FileName.
+// 类型按文件内容识别, 不信任客户端声明的类型; 只有文章作者可以上传.
+// 先写入存储再插入记录, 插入失败时删除已写入的文件
+func UploadAttachment(ctx context.Context, db *gorm.DB, a *Attachment, body io.Reader) error {
+	if attachmentStore == nil {
+		return ErrAttachmentsDisabled
+	}
+	a.FileName = attachmentFileName(a.FileName)
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("读取附件失败: %w", err)
+	}
+	head = head[:n]
+	a.ContentType, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+	if !slices.Contains(attachmentLimits.AllowedTypes, a.ContentType) {
+		return fmt.Errorf("%w: %s", ErrAttachmentType, a.ContentType)
+	}
+
+	a.StorageKey = fmt.Sprintf("posts/%d/%s", a.PostID, strings.ToLower(rand.Text()))
+	sized := &sizeLimitReader{r: io.MultiReader(bytes.NewReader(head), body), limit: attachmentLimits.MaxSize}
+	if err := attachmentStore.Put(ctx, a.StorageKey, sized); err != nil {
+		if errors.Is(err, ErrAttachmentTooLarge) {
+			return fmt.Errorf("%w: 超过 %d 字节", ErrAttachmentTooLarge, attachmentLimits.MaxSize)
+		}
+		return fmt.Errorf("保存附件失败: %w", err)
+	}
+	a.Size = sized.n
+
+	err = transaction(ctx, db, func(tx *gorm.DB) error {
+		var post Post
+		if err := WithRowLock(tx, &post, a.PostID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("文章 %d: %w", a.PostID, ErrPostNotFound)
+			}
+			return err
+		}
+		if post.UserID != a.UserID {
+			return ErrForbidden
+		}
+		return tx.Create(a).Error
+	})
+	if err != nil {
+		if derr := attachmentStore.Delete(context.WithoutCancel(ctx), a.StorageKey); derr != nil {
+			log.Printf("删除未入库的附件文件失败: %v", derr)
+		}
+		if errors.Is(err, ErrPostNotFound) || errors.Is(err, ErrForbidden) {
+			return err
+		}
+		return fmt.Errorf("保存附件记录失败: %w", err)
+	}
+	return nil
+}
+
+// attachmentFileName 只保留文件名的最后一段, 截断到 255 个字符
+func attachmentFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		name = "attachment"
+	}
+	if utf8.RuneCountInString(name) > 255 {
+		name = string([]rune(name)[:255])
+	}
+	return name
+}
+
+// sizeLimitReader 读取超过 limit 字节时返回 ErrAttachmentTooLarge, 使存储放弃写入
+type sizeLimitReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+func (r *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if r.n > r.limit {
+		return n, ErrAttachmentTooLarge
+	}
+	return n, err
+}
+
+// ListAttachments 按上传顺序返回文章的附件
+func ListAttachments(ctx context.Context, db *gorm.DB, postID uint) ([]Attachment, error) {
+	var attachments []Attachment
+	if err := db.WithContext(ctx).Where("post_id = ?", postID).Order("id").Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("查询文章附件失败: %w", err)
+	}
+	return attachments, nil
+}
+
+// GetAttachment 按主键查询附件
+func GetAttachment(ctx context.Context, db *gorm.DB, id uint) (*Attachment, error) {
+	var a Attachment
+	if err := db.WithContext(ctx).First(&a, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("附件 %d: %w", id, ErrAttachmentNotFound)
+		}
+		return nil, fmt.Errorf("查询附件失败: %w", err)
+	}
+	return &a, nil
+}
+
+// OpenAttachment 读取附件的文件内容, 调用方负责关闭
+func OpenAttachment(ctx context.Context, a *Attachment) (io.ReadCloser, error) {
+	if attachmentStore == nil {
+		return nil, ErrAttachmentsDisabled
+	}
+	f, err := attachmentStore.Open(ctx, a.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("附件 %d 的文件: %w", a.ID, ErrAttachmentNotFound)
+	}
+	return f, err
+}
+
+// DeleteAttachment 删除附件, 只有上传者可以删除; 文件在事务提交后删除
+func DeleteAttachment(ctx context.Context, db *gorm.DB, id, userID uint) error {
+	return transaction(ctx, db, func(tx *gorm.DB) error {
+		var a Attachment
+		if err := WithRowLock(tx, &a, id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("附件 %d: %w", id, ErrAttachmentNotFound)
+			}
+			return err
+		}
+		if a.UserID != userID {
+			return ErrForbidden
+		}
+		if err := tx.Delete(&a).Error; err != nil {
+			return fmt.Errorf("删除附件失败: %w", err)
+		}
+		return deleteAttachmentFilesAfterCommit(tx, []string{a.StorageKey})
+	})
+}
+
+// removePostAttachments 删除文章的附件记录, 由 Post.BeforeDelete 以钩子的 tx 调用 (见其中的 NewDB)
+func removePostAttachments(tx *gorm.DB, postID uint) error {
+	var keys []string
+	if err := tx.Model(&Attachment{}).Where("post_id = ?", postID).Pluck("storage_key", &keys).Error; err != nil {
+		return fmt.Errorf("查询文章 %d 的附件失败: %w", postID, err)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Where("post_id = ?", postID).Delete(&Attachment{}).Error; err != nil {
+		return fmt.Errorf("删除文章 %d 的附件失败: %w", postID, err)
+	}
+	return deleteAttachmentFilesAfterCommit(tx, keys)
+}
+
+// deleteAttachmentFilesAfterCommit 事务提交后删除附件文件. 未启用异步钩子时在事务中同步删除,
+// 事务随后回滚的话记录仍在而文件已删除, 下载时返回 ErrAttachmentNotFound
+func deleteAttachmentFilesAfterCommit(tx *gorm.DB, keys []string) error {
+	if attachmentStore == nil {
+		return nil
+	}
+	return afterCommit(tx, "删除附件文件", func(db *gorm.DB) error {
+		var errs []error
+		for _, key := range keys {
+			errs = append(errs, attachmentStore.Delete(db.Statement.Context, key))
+		}
+		return errors.Join(errs...)
+	})
+}
@@ -0,0 +1,509 @@
+// Package blog 博客模块: 用户、文章、评论模型及其钩子、仓储、阅读进度、推荐和导出.
+package blog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/apikey"
+	"github.com/alexwang789/Base1_golang_task3/audit"
+	"github.com/alexwang789/Base1_golang_task3/cdc"
+	"github.com/alexwang789/Base1_golang_task3/chaos"
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/dbbreaker"
+	"github.com/alexwang789/Base1_golang_task3/dbpool"
+	"github.com/alexwang789/Base1_golang_task3/emailqueue"
+	"github.com/alexwang789/Base1_golang_task3/fieldcrypt"
+	"github.com/alexwang789/Base1_golang_task3/idempotency"
+	"github.com/alexwang789/Base1_golang_task3/nplusone"
+	"github.com/alexwang789/Base1_golang_task3/outbox"
+	"github.com/alexwang789/Base1_golang_task3/querystats"
+	"github.com/alexwang789/Base1_golang_task3/querytimeout"
+	"github.com/alexwang789/Base1_golang_task3/redact"
+	"github.com/alexwang789/Base1_golang_task3/replica"
+	"github.com/alexwang789/Base1_golang_task3/sharding"
+	"github.com/alexwang789/Base1_golang_task3/snowflake"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"github.com/alexwang789/Base1_golang_task3/tenantdb"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// 1. 模型定义
+
+// User 用户模型
+type User struct {
+	ID            uint    `gorm:"primaryKey;autoIncrement"`
+	TenantID      uint    `gorm:"not null;default:1;uniqueIndex:idx_users_tenant_name,priority:1;uniqueIndex:idx_users_tenant_email_hash,priority:1"` // 所属租户, 用户名和邮箱在租户内唯一
+	UUID          *string `gorm:"type:char(36);uniqueIndex"`                                                                                          // UUID 键 (见 UUIDKeysEnabled), 未开启时为 NULL
+	Name          string  `gorm:"size:100;not null;uniqueIndex:idx_users_tenant_name,priority:2"`
+	Email         string  `gorm:"size:255;not null;serializer:encrypted"`                     // 加密存储 (见 fieldcrypt), 按邮箱查找用 ByEmail
+	EmailHash     *string `gorm:"size:64;uniqueIndex:idx_users_tenant_email_hash,priority:2"` // 邮箱的盲索引, 由 BeforeSave 维护; 加密上线前的用户为 NULL, 执行 rekey 后补全
+	Password      string  `gorm:"size:255;not null"`
+	ArticleCount  int     `gorm:"default:0"`              // 文章数量统计
+	IsAdmin       bool    `gorm:"not null;default:false"` // 管理员可审核评论
+	EmailVerified bool    `gorm:"not null;default:false"` // 邮箱验证前只能浏览, 不能发表文章和评论, 见 VerifyEmail
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Posts         []Post   // 一对多关系: 用户 -> 文章
+	Profile       *Profile `gorm:"foreignKey:UserID"` // 一对一关系: 用户 -> 资料, 没有保存过资料时为 nil
+}
+
+// Post 文章模型
+type Post struct {
+	ID            uint              `gorm:"primaryKey;autoIncrement"`
+	TenantID      uint              `gorm:"not null;default:1;index"`  // 所属租户, 与作者相同
+	UUID          *string           `gorm:"type:char(36);uniqueIndex"` // UUID 键 (见 UUIDKeysEnabled), 未开启时为 NULL
+	Title         string            `gorm:"size:200;not null"`
+	Content       string            `gorm:"type:text;not null"`
+	CommentStatus PostCommentStatus `gorm:"size:20;default:'none';check:chk_posts_comment_status,comment_status IN ('none','has_comments')"`
+	ViewCount     uint64            `gorm:"not null;default:0"`                                                                                                                                      // 浏览数, 由 ViewCounter 批量累加
+	Status        PostStatus        `gorm:"size:20;not null;default:'published';index:idx_posts_status_publish_at,priority:1;check:chk_posts_status,status IN ('published','scheduled','archived')"` // 发布状态, 见 PostStatuses, 由 BeforeCreate 按 PublishAt 确定
+	PublishAt     *time.Time        `gorm:"index:idx_posts_status_publish_at,priority:2"`                                                                                                            // 定时发布的时间, 立即发布的文章为 NULL
+	ArchivedAt    *time.Time        // 归档时间, 未归档时为 NULL
+	Version       uint              `gorm:"not null;default:1"`                               // 修订号, 从 1 开始, 作者每次修改标题或内容 (EditPost) 时加 1
+	CreatedAt     time.Time         `gorm:"not null;index:idx_posts_user_created,priority:2"` // 按月分区 (见 PartitionTable) 时是主键的一部分
+	UpdatedAt     time.Time
+	UserID        uint      `gorm:"index:idx_posts_user_id;index:idx_posts_user_created,priority:1"` // 外键. 二级索引隐含主键, 即 (user_id, id), 按作者倒序翻页和 Feed 依赖它; (user_id, created_at) 供按作者和时间筛选
+	User          User      `gorm:"foreignKey:UserID"`                                               // 多对一关系: 文章 -> 用户
+	Comments      []Comment // 一对多关系: 文章 -> 评论
+}
+
+// Comment 评论模型
+type Comment struct {
+	ID        uint          `gorm:"primaryKey;autoIncrement"`
+	TenantID  uint          `gorm:"not null;default:1;index"`  // 所属租户, 与文章相同
+	UUID      *string       `gorm:"type:char(36);uniqueIndex"` // UUID 键 (见 UUIDKeysEnabled), 未开启时为 NULL
+	Content   string        `gorm:"type:text;not null"`
+	Status    CommentStatus `gorm:"size:20;not null;default:'pending';index;index:idx_comments_status_created,priority:1;check:chk_comments_status,status IN ('pending','approved','rejected','spam')"` // 审核状态, 见 CommentStatuses
+	CreatedAt time.Time     `gorm:"not null;index:idx_comments_status_created,priority:2;index:idx_comments_post_created,priority:2"`                                                                   // 热度计算按时间范围读取已通过的评论; 按月分区时是主键的一部分
+	UpdatedAt time.Time
+	PostID    uint `gorm:"index:idx_comments_post_created,priority:1"` // 外键, 文章的评论按时间读取
+	Post      Post `gorm:"foreignKey:PostID"`                          // 多对一关系: 评论 -> 文章
+	UserID    uint // 外键
+	User      User `gorm:"foreignKey:UserID"` // 多对一关系: 评论 -> 用户
+
+	ParentID *uint `gorm:"index"` // 回复的评论, 顶层评论为 NULL
+}
+
+// QueryStats 进程启动以来经 Open 打开的连接执行过的 SQL 统计
+var QueryStats = querystats.NewAggregator()
+
+// DSNParams 博客库连接串的参数
+const DSNParams = "charset=utf8mb4&parseTime=True&loc=Local"
+
+// Open 按 blog_db 配置连接数据库 (有独立库的租户时经 tenantdb.Router), 注册只读副本、tenant、tenantdb、querystats、querytimeout、audit、dbbreaker 和 chaos 插件及连接池监控
+func Open() (*gorm.DB, error) {
+	return open(nil)
+}
+
+// OpenConn 与 Open 相同, 但在已有的连接池 conn 上打开, 使 GORM 与 sqlx 共用一个连接池 (见 shareddb).
+// conn 需以 DSNParams 连接 blog_db 配置的库. 故障注入和熔断由 conn 自身负责 (见 employee.OpenSqlx), 不再注册 chaos 和 dbbreaker 插件.
+// 共用连接池时不支持独立库的租户
+func OpenConn(conn *sql.DB) (*gorm.DB, error) {
+	return open(conn)
+}
+
+func open(conn *sql.DB) (*gorm.DB, error) {
+	// 从环境变量获取数据库配置
+	cfg := config.LoadDatabase("blog_db")
+	params := DSNParams
+
+	// 连接池配置, 环境变量未设置的项使用默认值
+	poolCfg := cfg.Pool.WithDefaults(config.Pool{
+		MaxOpen:     100,
+		MaxIdle:     10,
+		MaxLifetime: time.Hour,
+	})
+
+	// 构建 DSN, 已有连接池时直接使用
+	dialector := mysql.Open(cfg.DSN(params))
+	var router *tenantdb.Router
+	if conn != nil {
+		dialector = mysql.New(mysql.Config{Conn: conn})
+	} else if tenants := config.LoadTenantDatabases(); len(tenants.DSNs) > 0 {
+		// 有独立库的租户时由 Router 按租户选择连接池
+		def, err := sql.Open("mysql", cfg.DSN(params))
+		if err != nil {
+			return nil, fmt.Errorf("数据库连接失败: %w", err)
+		}
+		for id, dsn := range tenants.DSNs {
+			if !strings.Contains(dsn, "?") {
+				tenants.DSNs[id] = dsn + "?" + params
+			}
+		}
+		router = tenantdb.New(def, tenants.DSNs, tenantdb.Options{Name: "blog", Pool: poolCfg, IdleTimeout: tenants.IdleTimeout})
+		dialector = mysql.New(mysql.Config{Conn: router})
+	}
+
+	// 配置GORM日志, 日志带上请求 ID, 遮盖敏感参数
+	gormLogger := requestLogger{logger.New(
+		log.New(os.Stdout, "\r\n", log.LstdFlags),
+		logger.Config{
+			SlowThreshold: time.Second,
+			LogLevel:      logger.Info,
+			Colorful:      true,
+		},
+	), redact.New(config.LoadRedactFields())}
+
+	// 创建数据库连接
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: gormLogger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("数据库连接失败: %w", err)
+	}
+
+	// 读写分离: 配置了只读副本时读操作走副本
+	policy, err := replica.ParsePolicy(cfg.ReplicaPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if err := replica.Register(db, cfg.ReplicaDSNs(params), policy); err != nil {
+		return nil, fmt.Errorf("注册只读副本失败: %w", err)
+	}
+
+	// 敏感字段加密的密钥
+	if err := fieldcrypt.Configure(config.LoadFieldEncryption()); err != nil {
+		return nil, fmt.Errorf("加载字段加密密钥失败: %w", err)
+	}
+
+	// 用户、文章和评论创建时是否生成 UUID 键, 主键是否由应用生成
+	uuidKeys = config.LoadUUIDKeys()
+	erasureGrace = config.LoadErasureGracePeriod()
+	if cfg := config.LoadIDGenerator(); cfg.Snowflake {
+		gen, err := snowflake.New(cfg.NodeID)
+		if err != nil {
+			return nil, err
+		}
+		idGenerator = gen
+	}
+
+	// 评论分表: 分表各自的自增主键会重复, 须由应用生成主键
+	if commentTable.Shards = config.LoadCommentShards(); commentTable.Sharded() {
+		if idGenerator == nil {
+			return nil, fmt.Errorf("评论分表 (COMMENT_SHARDS=%d) 需要 ID_GENERATOR=snowflake", commentTable.Shards)
+		}
+		if err := db.Use(sharding.NewPlugin(commentTable)); err != nil {
+			return nil, fmt.Errorf("注册 sharding 插件失败: %w", err)
+		}
+	}
+
+	// 用户、文章和评论按 ctx 的租户隔离
+	if err := db.Use(tenant.NewPlugin()); err != nil {
+		return nil, fmt.Errorf("注册 tenant 插件失败: %w", err)
+	}
+
+	// 独立库的租户的读操作不走默认库的只读副本
+	if router != nil {
+		if err := db.Use(tenantdb.NewPlugin(router)); err != nil {
+			return nil, fmt.Errorf("注册 tenantdb 插件失败: %w", err)
+		}
+	}
+
+	// 按语句形状统计执行次数和耗时
+	if err := db.Use(querystats.NewPlugin(QueryStats)); err != nil {
+		return nil, fmt.Errorf("注册 querystats 插件失败: %w", err)
+	}
+
+	// 开发环境检测同一请求中反复执行的单行查询 (N+1)
+	if cfg := config.LoadNPlusOne(); cfg.Enabled {
+		if err := db.Use(nplusone.NewPlugin(cfg.Threshold, nil)); err != nil {
+			return nil, fmt.Errorf("注册 nplusone 插件失败: %w", err)
+		}
+	}
+
+	// 语句按操作类型限时, 失控的查询不会长时间占用连接
+	if err := db.Use(querytimeout.NewPlugin(config.LoadQueryTimeouts())); err != nil {
+		return nil, fmt.Errorf("注册 querytimeout 插件失败: %w", err)
+	}
+
+	// 用户、资料、文章和评论的写入记录到审计日志
+	if err := db.Use(audit.NewPlugin("users", "profiles", "posts", "comments")); err != nil {
+		return nil, fmt.Errorf("注册 audit 插件失败: %w", err)
+	}
+
+	// 配置了 CDC 或搜索引擎时用户、文章和评论的行变更写入发件箱, 由 relay 发布到 Kafka 和同步搜索索引
+	if config.LoadCDC().Enabled() || config.LoadSearch().Enabled() {
+		if err := db.Use(cdc.NewPlugin(CDCTables...)); err != nil {
+			return nil, fmt.Errorf("注册 cdc 插件失败: %w", err)
+		}
+	}
+
+	// 数据库不可用时熔断, 快速失败而不是每个请求各自等待超时; 共用连接池时由 conn 自身负责
+	if conn == nil {
+		if err := db.Use(dbbreaker.NewPlugin(dbbreaker.New("blog", config.LoadBreaker()))); err != nil {
+			return nil, fmt.Errorf("注册 dbbreaker 插件失败: %w", err)
+		}
+	}
+
+	// 测试/预发环境按配置注入延迟和错误
+	if cfg := chaos.ConfigFromEnv(); cfg.Enabled && conn == nil {
+		if err := db.Use(chaos.NewPlugin(chaos.New(cfg))); err != nil {
+			return nil, fmt.Errorf("注册 chaos 插件失败: %w", err)
+		}
+		fmt.Println("⚠️ 已启用数据库故障注入")
+	}
+
+	// 获取通用数据库对象 sql.DB
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+
+	// 配置连接池
+	dbpool.Default.Register("blog", sqlDB, poolCfg)
+
+	fmt.Println("🚀 数据库连接成功")
+	return db, nil
+}
+
+// Close 关闭数据库连接, 包括独立库的租户的连接
+func Close(db *gorm.DB) {
+	if router := tenantdb.Of(db); router != nil {
+		router.Close()
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Printf("获取数据库连接失败: %v", err)
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		log.Printf("关闭数据库连接失败: %v", err)
+	}
+}
+
+// Migrate 创建博客模块的表, 可重复执行. 迁移处理全部租户的数据, 之前的数据属于 tenant.Default;
+// 独立库的租户的库分别迁移
+func Migrate(db *gorm.DB) error {
+	return tenantdb.Of(db).ForEach(tenant.All(context.Background()), func(ctx context.Context) error {
+		return migrate(db.WithContext(ctx))
+	})
+}
+
+func migrate(db *gorm.DB) error {
+	ctx := db.Statement.Context
+
+	// 审核上线前的评论都已公开展示, 新增审核状态列时直接标记为已通过
+	addingStatus := db.Migrator().HasTable(&Comment{}) && !db.Migrator().HasColumn(&Comment{}, "Status")
+	// 邮箱验证上线前注册的用户视为已验证
+	addingVerified := db.Migrator().HasTable(&User{}) && !db.Migrator().HasColumn(&User{}, "EmailVerified")
+
+	// 评论状态从中文文字改为代码, 展示文字由 i18n 按语言给出. 须在 AutoMigrate 添加 CHECK 约束之前转换
+	if db.Migrator().HasColumn(&Post{}, "CommentStatus") {
+		for text, code := range map[string]PostCommentStatus{"无评论": PostNoComments, "有评论": PostHasComments} {
+			if err := db.Model(&Post{}).Where("comment_status = ?", text).UpdateColumn("comment_status", code).Error; err != nil {
+				return fmt.Errorf("转换文章评论状态失败: %w", err)
+			}
+		}
+	}
+
+	// 分区表不能有外键, 也不能被外键引用: 文章或评论已按月分区时迁移不再创建外键
+	if err := disableForeignKeysIfPartitioned(db); err != nil {
+		return err
+	}
+
+	err := db.AutoMigrate(&User{}, &Profile{}, &Follow{}, &Post{}, &Comment{}, &PostStat{}, &PostLike{}, &Notification{}, &ReadingProgress{}, &PostDiscoverWeight{}, &PostViewBucket{}, &TrendingScore{}, &Attachment{}, &EmailVerification{}, &UserErasure{}, &apikey.Key{}, &emailqueue.Email{}, &outbox.Event{}, &idempotency.Record{})
+	if err != nil {
+		return fmt.Errorf("表创建失败: %w", err)
+	}
+	// 评论的分表以 comments 为模板创建
+	if err := commentTable.Create(db); err != nil {
+		return err
+	}
+	// 搜索引擎不可用时的数据库检索
+	if err := ensurePostFullTextIndex(db); err != nil {
+		return err
+	}
+	// 经 ConnPool 执行, 独立库的租户的审计日志表建在自己的库中
+	if err := audit.Migrate(ctx, db.Statement.ConnPool); err != nil {
+		return err
+	}
+	// 通知的去重键从 (接收者, 评论) 扩展为整个事件
+	if db.Migrator().HasIndex(&Notification{}, "idx_notifications_comment") {
+		if err := db.Migrator().DropIndex(&Notification{}, "idx_notifications_comment"); err != nil {
+			return fmt.Errorf("删除旧的通知索引失败: %w", err)
+		}
+	}
+	// 用户名和邮箱从全局唯一改为租户内唯一, 邮箱加密后改由盲索引保证唯一, 推荐权重的索引加上租户
+	for _, idx := range []struct {
+		model any
+		name  string
+	}{
+		{&User{}, "idx_users_name"},
+		{&User{}, "idx_users_email"},
+		{&User{}, "idx_users_tenant_email"},
+		{&PostDiscoverWeight{}, "idx_post_discover_weights_cum_weight"},
+	} {
+		if db.Migrator().HasIndex(idx.model, idx.name) {
+			if err := db.Migrator().DropIndex(idx.model, idx.name); err != nil {
+				return fmt.Errorf("删除旧索引 %s 失败: %w", idx.name, err)
+			}
+		}
+	}
+	if addingStatus {
+		if err := db.Model(&Comment{}).Where("1 = 1").Update("status", CommentApproved).Error; err != nil {
+			return fmt.Errorf("初始化评论审核状态失败: %w", err)
+		}
+	}
+	if addingVerified {
+		if err := db.Model(&User{}).Where("1 = 1").Update("email_verified", true).Error; err != nil {
+			return fmt.Errorf("初始化邮箱验证状态失败: %w", err)
+		}
+	}
+	if err := backfillUUIDs(db); err != nil {
+		return err
+	}
+	if err := hashPlaintextPasswords(db); err != nil {
+		return err
+	}
+	// 新建的统计表从已有评论初始化
+	return RebuildPostStats(ctx, db)
+}
+
+// 3.1 Post 钩子函数 - 创建文章后更新用户文章数量. 定时发布的文章在 PublishDuePosts 发布时才执行
+func (p *Post) AfterCreate(tx *gorm.DB) error {
+	if p.Status == PostScheduled {
+		return nil
+	}
+	return onPostPublished(tx, p)
+}
+
+// onPostPublished 文章发布的副作用: 累加作者的文章数, 创建统计行并写入发布事件
+func onPostPublished(tx *gorm.DB, p *Post) error {
+	// 先锁定作者行, 并发发文时对同一用户的计数更新依次执行
+	if err := WithRowLock(tx, &User{}, p.UserID); err != nil {
+		return err
+	}
+
+	// 更新用户的文章数量
+	result := tx.Model(&User{}).Where("id = ?", p.UserID).
+		Update("article_count", gorm.Expr("article_count + ?", 1))
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	// 统计行从创建起就存在, 没有评论的文章也出现在排行中
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&PostStat{PostID: p.ID}).Error; err != nil {
+		return fmt.Errorf("创建文章统计失败: %w", err)
+	}
+
+	if err := addPostCreated(tx, p); err != nil {
+		return err
+	}
+
+	if err := invalidateUserCacheAfterCommit(tx, p.UserID); err != nil {
+		return err
+	}
+	fmt.Printf("✅ 用户 %d 的文章数量已更新\n", p.UserID)
+	return nil
+}
+
+// Post 钩子函数 - 删除文章前加锁读出文章, 并删除它的评论、点赞、通知等从属数据.
+// 评论批量删除, 不逐条触发评论的钩子
+func (p *Post) BeforeDelete(tx *gorm.DB) error {
+	if p.ID == 0 {
+		return errors.New("只能按主键删除文章")
+	}
+	if err := WithRowLock(tx, p, p.ID); err != nil {
+		return err
+	}
+	// 钩子的 tx 共享外层 DELETE 的语句, 不加 NewDB 会带上 posts 表和它的条件
+	batch := tx.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+	for _, model := range []any{&Comment{}, &PostLike{}, &Notification{}, &PostStat{}, &ReadingProgress{}, &PostDiscoverWeight{}, &PostViewBucket{}, &TrendingScore{}} {
+		if err := batch.Where("post_id = ?", p.ID).Delete(model).Error; err != nil {
+			return fmt.Errorf("删除文章 %d 的从属数据失败: %w", p.ID, err)
+		}
+	}
+	return removePostAttachments(tx, p.ID)
+}
+
+// Post 钩子函数 - 删除文章后更新用户文章数量. 尚未发布的定时文章没有计入, 也没有发布事件;
+// 已归档的文章在归档时已从文章数中减去
+func (p *Post) AfterDelete(tx *gorm.DB) error {
+	if p.Status == PostScheduled {
+		return nil
+	}
+	if p.Status == PostPublished {
+		if err := WithRowLock(tx, &User{}, p.UserID); err != nil {
+			return err
+		}
+		err := tx.Model(&User{}).Where("id = ?", p.UserID).
+			Update("article_count", gorm.Expr("article_count - ?", 1)).Error
+		if err != nil {
+			return err
+		}
+	}
+	if err := addPostDeleted(tx, p); err != nil {
+		return err
+	}
+	return invalidateUserCacheAfterCommit(tx, p.UserID)
+}
+
+// User 钩子函数 - 保存前更新邮箱的盲索引并哈希明文密码 (见 password.go), 创建时同样执行
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	if u.Email != "" {
+		h := emailHash(u.Email)
+		u.EmailHash = &h
+	}
+	return u.hashPassword()
+}
+
+// User 钩子函数 - 创建前生成主键 (见 idGenerator) 和 UUID 键.
+// 不能放在 BeforeSave 中: Model(&User{}).Update 同样执行 BeforeSave, 为空模型生成的 ID 会被 GORM 加入更新条件
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	assignID(&u.ID)
+	assignUUID(&u.UUID)
+	return nil
+}
+
+// User 钩子函数 - 创建用户后写入注册事件
+func (u *User) AfterCreate(tx *gorm.DB) error {
+	return addUserRegistered(tx, u)
+}
+
+// User 钩子函数 - 用户更新或删除后使缓存失效
+func (u *User) AfterUpdate(tx *gorm.DB) error {
+	return invalidateUserCacheAfterCommit(tx, u.ID)
+}
+
+func (u *User) AfterDelete(tx *gorm.DB) error {
+	return invalidateUserCacheAfterCommit(tx, u.ID)
+}
+
+// Comment 钩子函数 - 创建已通过审核的评论后把文章标记为有评论, 累加文章统计并发出评论通知.
+// 待审核的评论在 ModerateComment 审核通过时才计入
+func (c *Comment) AfterCreate(tx *gorm.DB) error {
+	if c.Status != CommentApproved {
+		return nil
+	}
+	err := tx.Model(&Post{}).Where("id = ? AND comment_status <> ?", c.PostID, PostHasComments).
+		Update("comment_status", PostHasComments).Error
+	if err != nil {
+		return err
+	}
+	if err := incrementPostStats(tx, c); err != nil {
+		return err
+	}
+	return notifyCommentPublished(tx, c)
+}
+
+// 3.2 Comment 钩子函数 - 删除评论后检查文章评论状态
+func (c *Comment) AfterDelete(tx *gorm.DB) error {
+	if err := removeCommentNotifications(tx, c.ID); err != nil {
+		return err
+	}
+	if err := addCommentDeleted(tx, c); err != nil {
+		return err
+	}
+	return refreshPostComments(tx, c.PostID)
+}
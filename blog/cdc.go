@@ -0,0 +1,11 @@
+package blog
+
+import "github.com/alexwang789/Base1_golang_task3/cdc"
+
+// CDCTables 开启 CDC (见 config.LoadCDC) 时发布行变更的表和列. 不发布密码和加密的邮箱;
+// 浏览数由原生 SQL 累加, 不产生变更, 也不发布. 删除、改名或改变列的类型时增加 Version
+var CDCTables = []cdc.Table{
+	{Name: "users", Version: 1, Columns: []string{"id", "tenant_id", "uuid", "name", "article_count", "is_admin", "email_verified", "created_at", "updated_at"}},
+	{Name: "posts", Version: 1, Columns: []string{"id", "tenant_id", "uuid", "user_id", "title", "content", "status", "comment_status", "publish_at", "archived_at", "created_at", "updated_at"}},
+	{Name: "comments", Version: 1, Columns: []string{"id", "tenant_id", "uuid", "post_id", "user_id", "parent_id", "content", "status", "created_at", "updated_at"}},
+}
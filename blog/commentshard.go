@@ -0,0 +1,67 @@
+package blog
+
+import (
+	"context"
+
+	"github.com/alexwang789/Base1_golang_task3/sharding"
+	"gorm.io/gorm"
+)
+
+// commentTable 评论的分表布局. 评论数量远多于其他表, 可按 post_id 的哈希拆分到 COMMENT_SHARDS 张表
+// (见 config.LoadCommentShards), 由 Open 设置. 同一文章的评论 (含回复) 在同一张分表中, 按文章读取评论只访问一张分表;
+// 原生 SQL 经 commentTable.From 取得表引用
+var commentTable = sharding.Table{Name: "comments", Key: "post_id", Shards: 1}
+
+// 搬迁评论时每批读取的行数
+const reshardBatch = 1000
+
+// createComments 分批创建评论. 分表后一次批量创建只能写入一张分表, 按所在分表分组后在同一事务中分别创建,
+// 生成的 ID 等写回 comments
+func createComments(ctx context.Context, db *gorm.DB, comments []Comment, batchSize int) error {
+	if !commentTable.Sharded() {
+		return createInBatches(ctx, db, &comments, len(comments), batchSize)
+	}
+	groups := make(map[int][]int)
+	for i, c := range comments {
+		s := commentTable.Shard(uint64(c.PostID))
+		groups[s] = append(groups[s], i)
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, group := range groups {
+			rows := make([]Comment, len(group))
+			for i, j := range group {
+				rows[i] = comments[j]
+			}
+			if err := createInBatches(ctx, tx, &rows, len(rows), batchSize); err != nil {
+				return err
+			}
+			for i, j := range group {
+				comments[j] = rows[i]
+			}
+		}
+		return nil
+	})
+}
+
+// lockComment 锁定评论并加载进 comment. 分表后加锁读取必须带上分片键, 先按 ID 查出评论所属的文章 (评论不会换文章)
+func lockComment(tx *gorm.DB, comment *Comment, id uint) error {
+	if commentTable.Sharded() {
+		var postIDs []uint
+		if err := tx.Model(&Comment{}).Where("id = ?", id).Limit(1).Pluck("post_id", &postIDs).Error; err != nil {
+			return err
+		}
+		if len(postIDs) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		tx = tx.Where("post_id = ?", postIDs[0])
+	}
+	return WithRowLock(tx, comment, id)
+}
+
+// ReshardComments 把评论从 from 张分表搬迁到 to 张分表 (1 表示不分表), 返回搬迁的评论数, 见 sharding.Reshard.
+// 全部租户的评论一起搬迁
+func ReshardComments(ctx context.Context, db *gorm.DB, from, to int) (int64, error) {
+	src, dst := commentTable, commentTable
+	src.Shards, dst.Shards = from, to
+	return sharding.Reshard(ctx, db, src, dst, reshardBatch)
+}
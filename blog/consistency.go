@@ -0,0 +1,125 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"gorm.io/gorm"
+)
+
+// CounterMismatch 冗余计数与实际数据不一致的一条记录
+type CounterMismatch struct {
+	Table    string // users、posts 或 post_stats
+	ID       uint
+	Column   string // article_count、comment_status 等, 压测核对 (见 Stress) 还有 comments 和 comment_count
+	Stored   string
+	Expected string
+}
+
+func (m CounterMismatch) String() string {
+	return fmt.Sprintf("%s %d: %s = %s, 应为 %s", m.Table, m.ID, m.Column, m.Stored, m.Expected)
+}
+
+// CheckCounters 校验钩子维护的冗余字段: users.article_count 等于用户对外可见的文章数,
+// posts.comment_status 与文章是否有已通过审核的评论一致. 返回全部不一致的记录, 全部一致时返回空切片
+func CheckCounters(ctx context.Context, db *gorm.DB) ([]CounterMismatch, error) {
+	var users []struct {
+		ID           uint
+		ArticleCount int
+		Actual       int
+	}
+	err := db.WithContext(ctx).Raw(`
+		SELECT u.id, u.article_count, COUNT(p.id) AS actual
+		FROM users u
+		LEFT JOIN posts p ON p.user_id = u.id AND p.status = ?
+		GROUP BY u.id, u.article_count
+		HAVING u.article_count <> COUNT(p.id)
+		ORDER BY u.id
+	`, PostPublished).Scan(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("校验文章数失败: %w", err)
+	}
+
+	var posts []struct {
+		ID            uint
+		CommentStatus string
+		Comments      int
+	}
+	err = db.WithContext(ctx).Raw(`
+		SELECT p.id, p.comment_status, COUNT(c.id) AS comments
+		FROM posts p
+		LEFT JOIN `+commentTable.From("c")+` ON c.post_id = p.id AND c.status = ?
+		GROUP BY p.id, p.comment_status
+		HAVING (COUNT(c.id) = 0) <> (p.comment_status = ?)
+		ORDER BY p.id
+	`, CommentApproved, PostNoComments).Scan(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("校验评论状态失败: %w", err)
+	}
+
+	mismatches := []CounterMismatch{}
+	for _, u := range users {
+		mismatches = append(mismatches, CounterMismatch{
+			Table:    "users",
+			ID:       u.ID,
+			Column:   "article_count",
+			Stored:   fmt.Sprint(u.ArticleCount),
+			Expected: fmt.Sprint(u.Actual),
+		})
+	}
+	for _, p := range posts {
+		expected := postCommentStatus(int64(p.Comments))
+		mismatches = append(mismatches, CounterMismatch{
+			Table:    "posts",
+			ID:       p.ID,
+			Column:   "comment_status",
+			Stored:   p.CommentStatus,
+			Expected: string(expected),
+		})
+	}
+	return mismatches, nil
+}
+
+// WriteCounterReport 以文本形式输出 CheckCounters 的结果
+func WriteCounterReport(w io.Writer, mismatches []CounterMismatch) {
+	if len(mismatches) == 0 {
+		fmt.Fprintln(w, "✅ 计数字段全部一致")
+		return
+	}
+	fmt.Fprintf(w, "❌ %d 条记录的计数字段不一致:\n", len(mismatches))
+	for _, m := range mismatches {
+		fmt.Fprintf(w, "  %s\n", m)
+	}
+}
+
+// ReconcileCounters 校验冗余字段并按实际数据修正, 返回修正前不一致的记录.
+// 每条记录在各自的事务中加锁重新计算, 不会覆盖校验之后由钩子写入的新值
+func ReconcileCounters(ctx context.Context, db *gorm.DB) ([]CounterMismatch, error) {
+	mismatches, err := CheckCounters(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mismatches {
+		err := transaction(ctx, db, func(tx *gorm.DB) error {
+			if m.Table == "posts" {
+				return refreshPostComments(tx, m.ID)
+			}
+			if err := WithRowLock(tx, &User{}, m.ID); err != nil {
+				return err
+			}
+			var count int64
+			if err := tx.Model(&Post{}).Scopes(PublicOnly(), scopes.ByUser(m.ID)).Count(&count).Error; err != nil {
+				return err
+			}
+			return tx.Model(&User{ID: m.ID}).Update("article_count", count).Error
+		})
+		// 校验之后被删除的记录无需修正
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("修正 %s 失败: %w", m, err)
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,49 @@
+package blog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeletePostRemovesDependents(t *testing.T) {
+	db := newTestDB(t)
+	author := newTestUser(t, db, "alice")
+	reader := newTestUser(t, db, "bob")
+	posts := NewPostRepository(db)
+	ctx := context.Background()
+
+	post := Post{UserID: author.ID, Title: "标题", Content: "内容"}
+	if err := posts.Create(ctx, &post); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Comment{PostID: post.ID, UserID: reader.ID, Content: "评论", Status: CommentApproved}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&PostLike{PostID: post.ID, UserID: reader.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	attachment := Attachment{PostID: post.ID, UserID: author.ID, StorageKey: "k1", FileName: "a.png", ContentType: "image/png", Size: 1}
+	if err := db.Create(&attachment).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := posts.Delete(ctx, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range []any{&Post{}, &Comment{}, &PostLike{}, &PostStat{}, &Notification{}, &Attachment{}} {
+		var n int64
+		if err := db.Model(model).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("删除文章后还有 %d 行 %T", n, model)
+		}
+	}
+	var user User
+	if err := db.Take(&user, author.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.ArticleCount != 0 {
+		t.Errorf("article_count = %d, 期望 0", user.ArticleCount)
+	}
+}
@@ -0,0 +1,207 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/alexwang789/Base1_golang_task3/collate"
+	"github.com/alexwang789/Base1_golang_task3/fixtures"
+	"github.com/alexwang789/Base1_golang_task3/i18n"
+	"github.com/alexwang789/Base1_golang_task3/txmanager"
+	"gorm.io/gorm"
+)
+
+// Seed 初始化数据: 设置了 FIXTURES 环境变量 (逗号分隔的 YAML 文件) 时从 fixture 加载, 否则使用内置测试数据
+func Seed(db *gorm.DB) error {
+	paths := os.Getenv("FIXTURES")
+	if paths == "" {
+		return createTestData(db)
+	}
+
+	set, err := fixtures.Load(strings.Split(paths, ",")...)
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	if _, err := set.Insert(context.Background(), sqlDB); err != nil {
+		return err
+	}
+	// 直接写表不经过评论钩子, 统计需要重建
+	if err := RebuildPostStats(context.Background(), db); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ 已从 %s 加载测试数据\n", paths)
+	return nil
+}
+
+// 创建测试数据
+func createTestData(db *gorm.DB) error {
+	ctx := context.Background()
+
+	// 创建用户
+	users := []User{
+		{Name: "张三", Email: "zhangsan@example.com", Password: "pass123", EmailVerified: true},
+		{Name: "李四", Email: "lisi@example.com", Password: "pass456", EmailVerified: true},
+	}
+
+	if err := NewUserRepository(db).CreateBatch(ctx, users, DefaultBatchSize); err != nil {
+		return err
+	}
+
+	// 创建文章
+	posts := []Post{
+		{Title: "Go语言入门", Content: "Go语言基础教程...", UserID: users[0].ID},
+		{Title: "GORM使用指南", Content: "GORM高级技巧...", UserID: users[0].ID},
+		{Title: "Web开发实践", Content: "使用Go开发Web应用...", UserID: users[1].ID},
+	}
+
+	if err := NewPostRepository(db).CreateBatch(ctx, posts, DefaultBatchSize); err != nil {
+		return err
+	}
+
+	// 创建评论, 内置数据直接标记为已通过审核
+	comments := []Comment{
+		{Content: "好文章！", Status: CommentApproved, PostID: posts[0].ID, UserID: users[1].ID},
+		{Content: "学到了很多", Status: CommentApproved, PostID: posts[0].ID, UserID: users[0].ID},
+		{Content: "期待更多内容", Status: CommentApproved, PostID: posts[1].ID, UserID: users[1].ID},
+	}
+
+	if err := NewCommentRepository(db).CreateBatch(ctx, comments, DefaultBatchSize); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ 测试数据创建成功")
+	return nil
+}
+
+// RunDemo 依次演示关联查询和钩子函数, 需要先调用 Seed 写入测试数据.
+// 各步骤的错误只记录日志, 不中断后续步骤
+func RunDemo(ctx context.Context, db *gorm.DB) {
+	// 2. 关联查询
+	// 查询用户1的所有文章及其评论
+	fmt.Println("\n查询用户1的所有文章及其评论:")
+	if err := queryUserPostsWithComments(db, 1); err != nil {
+		log.Printf("查询失败: %v", err)
+	}
+
+	// 查询评论数量最多的文章
+	fmt.Println("\n评论数量最多的 3 篇文章:")
+	if err := queryMostCommentedPosts(db, 3); err != nil {
+		log.Printf("查询失败: %v", err)
+	}
+
+	// 3. 钩子函数测试
+	// 钩子与触发它的写操作在同一事务中执行, 死锁时由 txmanager 整体重试
+	txm := txmanager.New(db)
+
+	// 创建新文章测试钩子
+	fmt.Println("\n创建新文章测试钩子:")
+	newPost := Post{
+		Title:   "钩子函数测试文章",
+		Content: "测试创建文章时自动更新用户文章数量",
+		UserID:  1,
+	}
+	err := txm.RunInTx(ctx, func(ctx context.Context) error {
+		return txm.DB(ctx).Create(&newPost).Error
+	})
+	if err != nil {
+		log.Printf("创建文章失败: %v", err)
+	} else {
+		fmt.Println("✅ 文章创建成功")
+	}
+
+	// 删除评论测试钩子
+	fmt.Println("\n删除评论测试钩子:")
+	err = txm.RunInTx(ctx, func(ctx context.Context) error {
+		var comment Comment
+		if err := txm.DB(ctx).First(&comment).Error; err != nil {
+			return fmt.Errorf("获取评论失败: %w", err)
+		}
+		return txm.DB(ctx).Delete(&comment).Error
+	})
+	if err != nil {
+		log.Printf("删除评论失败: %v", err)
+	} else {
+		fmt.Println("✅ 评论删除成功")
+	}
+
+	// 显示最终用户和文章状态
+	fmt.Println("\n最终用户和文章状态:")
+	if err := showFinalStatus(db); err != nil {
+		log.Printf("查询失败: %v", err)
+	}
+}
+
+// 2.1 查询用户的所有文章及其评论
+func queryUserPostsWithComments(db *gorm.DB, userID uint) error {
+	var user User
+
+	// 预加载文章和文章已通过审核的评论
+	err := db.Preload("Posts.Comments", approvedComments).First(&user, userID).Error
+	if err != nil {
+		return fmt.Errorf("查询用户失败: %w", err)
+	}
+
+	fmt.Printf("用户 %s 的文章:\n", user.Name)
+	for i, post := range user.Posts {
+		fmt.Printf("  %d. %s (评论数: %d)\n", i+1, post.Title, len(post.Comments))
+		for j, comment := range post.Comments {
+			// 评论作者走缓存, 同一用户的多条评论只查询一次
+			author, err := NewUserRepository(db).GetByIDCached(context.Background(), comment.UserID)
+			if err != nil {
+				return fmt.Errorf("查询评论作者失败: %w", err)
+			}
+			fmt.Printf("    - %d. %s (%s)\n", j+1, comment.Content, author.Name)
+		}
+	}
+
+	return nil
+}
+
+// 2.2 查询评论数量最多的文章
+func queryMostCommentedPosts(db *gorm.DB, n int) error {
+	posts, err := NewPostRepository(db).MostCommented(context.Background(), nil, n)
+	if err != nil {
+		return err
+	}
+
+	for i, post := range posts {
+		fmt.Printf("%d. %s (ID: %d, 评论数: %d)\n", i+1, post.Title, post.ID, post.CommentCount)
+	}
+	return nil
+}
+
+// 显示最终状态
+func showFinalStatus(db *gorm.DB) error {
+	// 查询所有用户, 按姓名排序
+	users, err := NewUserRepository(db).ListByName(context.Background(), collate.LocaleFromEnv())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("用户文章数量统计:")
+	for _, user := range users {
+		fmt.Printf("- %s: %d 篇文章\n", user.Name, user.ArticleCount)
+	}
+
+	// 查询所有文章
+	var posts []Post
+	if err := db.Find(&posts).Error; err != nil {
+		return err
+	}
+
+	fmt.Println("\n文章评论状态:")
+	for _, post := range posts {
+		fmt.Printf("- %s: %s\n", post.Title, i18n.Translate(i18n.Source, "comment_status."+string(post.CommentStatus)))
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"github.com/alexwang789/Base1_golang_task3/tenantdb"
+	"gorm.io/gorm"
+)
+
+// ErrNoDiscoverablePost 权重表为空, 没有可推荐的文章
+var ErrNoDiscoverablePost = errors.New("没有可推荐的文章")
+
+// PostDiscoverWeight 预先计算的文章推荐权重. CumWeight 是租户内按 post_id 顺序的累计权重,
+// 随机取一个 [0, 总权重) 的数后按索引找第一个 CumWeight 大于它的行, 避免 ORDER BY RAND() 全表扫描
+type PostDiscoverWeight struct {
+	PostID    uint    `gorm:"primaryKey"`
+	TenantID  uint    `gorm:"not null;default:1;index:idx_post_discover_weights_tenant_cum,priority:1"`
+	Weight    float64 `gorm:"not null"`
+	CumWeight float64 `gorm:"not null;index:idx_post_discover_weights_tenant_cum,priority:2"`
+	UpdatedAt time.Time
+}
+
+// 推荐权重参数: 新鲜度按半衰期衰减, 互动量按评论数的对数增长
+const (
+	discoverHalfLife     = 7 * 24 * time.Hour
+	discoverRefreshEvery = 10 * time.Minute
+)
+
+// 按累计权重选取文章, 执行计划检查 (queryplans.go) 也引用该语句
+const sqlDiscoverPost = `
+	SELECT posts.*
+	FROM post_discover_weights AS w
+	JOIN posts ON posts.id = w.post_id
+	WHERE w.tenant_id = ? AND w.cum_weight > ? AND posts.status = ?
+	ORDER BY w.cum_weight
+	LIMIT 1
+`
+
+// 计算单篇文章的权重
+func discoverWeight(age time.Duration, comments int64) float64 {
+	freshness := math.Exp2(-max(age, 0).Hours() / discoverHalfLife.Hours())
+	engagement := 1 + math.Log1p(float64(comments))
+	return freshness * engagement
+}
+
+// RefreshDiscoverWeights 重新计算全部租户的文章的推荐权重, 整表在一个事务中替换
+func RefreshDiscoverWeights(ctx context.Context, db *gorm.DB) error {
+	ctx = tenant.All(ctx)
+	var stats []struct {
+		ID           uint
+		TenantID     uint
+		CreatedAt    time.Time
+		CommentCount int64
+	}
+	err := db.WithContext(ctx).Model(&Post{}).
+		Select("posts.id, posts.tenant_id, posts.created_at, COALESCE(post_stats.comment_count, 0) AS comment_count").
+		Joins("LEFT JOIN post_stats ON post_stats.post_id = posts.id").
+		Scopes(PublicOnly()).
+		Order("posts.tenant_id, posts.id").
+		Scan(&stats).Error
+	if err != nil {
+		return fmt.Errorf("统计文章互动量失败: %w", err)
+	}
+
+	now := time.Now()
+	weights := make([]PostDiscoverWeight, len(stats))
+	var cum float64
+	for i, s := range stats {
+		if i > 0 && s.TenantID != stats[i-1].TenantID {
+			cum = 0
+		}
+		w := discoverWeight(now.Sub(s.CreatedAt), s.CommentCount)
+		cum += w
+		weights[i] = PostDiscoverWeight{PostID: s.ID, TenantID: s.TenantID, Weight: w, CumWeight: cum, UpdatedAt: now}
+	}
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&PostDiscoverWeight{}).Error; err != nil {
+			return err
+		}
+		if len(weights) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&weights, DefaultBatchSize).Error
+	})
+	if err != nil {
+		return fmt.Errorf("写入推荐权重失败: %w", err)
+	}
+	return nil
+}
+
+// DiscoverPost 按权重随机返回 ctx 所属租户的一篇文章, 越新、评论越多的文章被选中的概率越大.
+// 权重刷新后被删除的文章会顺延到下一篇
+func DiscoverPost(ctx context.Context, db *gorm.DB) (*Post, error) {
+	var total float64
+	err := db.WithContext(ctx).Model(&PostDiscoverWeight{}).
+		Select("COALESCE(MAX(cum_weight), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询推荐权重失败: %w", err)
+	}
+	if total <= 0 {
+		return nil, ErrNoDiscoverablePost
+	}
+
+	// 选中的位置之后的文章都已被删除时, 从头再取一次
+	for _, threshold := range []float64{rand.Float64() * total, 0} {
+		var posts []Post
+		if err := db.WithContext(ctx).Raw(sqlDiscoverPost, tenant.ID(ctx), threshold, PostPublished).Scan(&posts).Error; err != nil {
+			return nil, fmt.Errorf("查询推荐文章失败: %w", err)
+		}
+		if len(posts) > 0 {
+			return &posts[0], nil
+		}
+	}
+	return nil, ErrNoDiscoverablePost
+}
+
+// RefreshDiscoverWeightsLoop 定期刷新默认库和各独立库的推荐权重直到 ctx 取消
+func RefreshDiscoverWeightsLoop(ctx context.Context, db *gorm.DB) {
+	ticker := time.NewTicker(discoverRefreshEvery)
+	defer ticker.Stop()
+	for {
+		err := tenantdb.Of(db).ForEach(ctx, func(ctx context.Context) error {
+			return RefreshDiscoverWeights(ctx, db)
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Print(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alexwang789/Base1_golang_task3/validate"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// MySQL 唯一键冲突错误码
+const errDuplicateEntry = 1062
+
+// DuplicateFieldError 唯一字段的值已被其他记录使用
+type DuplicateFieldError struct {
+	Field string // 字段名, 如 "email"、"name"
+}
+
+func (e *DuplicateFieldError) Error() string {
+	return fmt.Sprintf("%s 已被使用", e.Field)
+}
+
+// users 表唯一索引对应的字段. 姓名和邮箱在租户内唯一, 邮箱由盲索引保证
+var userUniqueFields = map[string]string{
+	"idx_users_tenant_email_hash": "email",
+	"idx_users_tenant_name":       "name",
+}
+
+// 把 users 表的唯一键冲突转换为 DuplicateFieldError, 其他错误原样返回.
+// MySQL 8 的错误信息形如 "Duplicate entry '1-张三' for key 'users.idx_users_tenant_name'"
+func translateUserDuplicate(err error) error {
+	var myErr *mysqldriver.MySQLError
+	if !errors.As(err, &myErr) || myErr.Number != errDuplicateEntry {
+		return err
+	}
+	i := strings.LastIndex(myErr.Message, "for key '")
+	if i < 0 {
+		return err
+	}
+	key := strings.TrimSuffix(myErr.Message[i+len("for key '"):], "'")
+	key = key[strings.LastIndex(key, ".")+1:] // MySQL 5.7 不带表名前缀
+	if field, ok := userUniqueFields[key]; ok {
+		return &DuplicateFieldError{Field: field}
+	}
+	return err
+}
+
+// CheckAvailability 检查姓名和邮箱是否可以注册, 用于表单提交前的快速校验.
+// 被占用的字段以 validate.Errors 形式返回; 检查与注册之间仍可能被抢注, RegisterUser 会返回 DuplicateFieldError
+func CheckAvailability(ctx context.Context, db *gorm.DB, name, email string) error {
+	// 读出完整的 User, 邮箱经解密后比较
+	var taken []User
+	err := db.WithContext(ctx).
+		Select("name, email").
+		Where("name = ? OR ?", name, emailMatch(email)).
+		Limit(2).
+		Find(&taken).Error
+	if err != nil {
+		return fmt.Errorf("查询用户失败: %w", err)
+	}
+
+	errs := validate.Errors{}
+	for _, u := range taken {
+		errs.Check(!strings.EqualFold(u.Email, email), "email", "邮箱已被注册")
+		errs.Check(!strings.EqualFold(u.Name, name), "name", "姓名已被使用")
+	}
+	return errs.Err()
+}
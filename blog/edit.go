@@ -0,0 +1,48 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrModified 记录在客户端读取之后已被修改, 客户端持有的修订号 (如 HTTP 的 If-Match) 已过期
+var ErrModified = errors.New("记录已被修改")
+
+// EditPost 以 editor 的身份修改文章的标题和内容, 只有作者可以修改, 否则返回 ErrForbidden; 数据不合法时返回 validate.Errors.
+// precondition 不为 nil 时用读取到的文章检查客户端持有的修订号, 不满足时返回 ErrModified. 更新带上读取时的修订号作为条件 (乐观锁)
+// 并把它加 1, 检查之后被并发修改时同样返回 ErrModified. 成功后 post 为更新后的文章
+func EditPost(ctx context.Context, db *gorm.DB, editor *User, post *Post, precondition func(current *Post) bool) error {
+	var current Post
+	err := db.WithContext(ctx).Take(&current, post.ID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPostNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("查询文章失败: %w", err)
+	}
+	if editor == nil || current.UserID != editor.ID {
+		return ErrForbidden
+	}
+	if precondition != nil && !precondition(&current) {
+		return ErrModified
+	}
+
+	version := current.Version
+	current.Title, current.Content = post.Title, post.Content
+	if err := current.Validate(); err != nil {
+		return err
+	}
+	current.Version = version + 1
+	result := db.WithContext(ctx).Model(&current).Where("version = ?", version).Select("title", "content", "version").Updates(&current)
+	if result.Error != nil {
+		return fmt.Errorf("更新文章失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrModified
+	}
+	*post = current
+	return nil
+}
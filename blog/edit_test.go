@@ -0,0 +1,71 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEditPostVersion(t *testing.T) {
+	db := newTestDB(t)
+	author := newTestUser(t, db, "alice")
+	ctx := context.Background()
+
+	post := Post{UserID: author.ID, Title: "标题", Content: "内容"}
+	if err := NewPostRepository(db).Create(ctx, &post); err != nil {
+		t.Fatal(err)
+	}
+	if post.Version != 1 {
+		t.Fatalf("新文章的修订号 = %d, 期望 1", post.Version)
+	}
+
+	tests := []struct {
+		name    string
+		version uint // 客户端持有的修订号
+		wantErr error
+		want    uint // 之后库中的修订号
+	}{
+		{"修订号一致", 1, nil, 2},
+		{"修订号已过期", 1, ErrModified, 2},
+		{"使用新的修订号", 2, nil, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edit := Post{ID: post.ID, Title: "新标题 " + tt.name, Content: "新内容"}
+			err := EditPost(ctx, db, author, &edit, func(current *Post) bool { return current.Version == tt.version })
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EditPost() = %v, 期望 %v", err, tt.wantErr)
+			}
+			if err == nil && edit.Version != tt.want {
+				t.Errorf("返回的修订号 = %d, 期望 %d", edit.Version, tt.want)
+			}
+			var stored Post
+			if err := db.Take(&stored, post.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.Version != tt.want {
+				t.Errorf("库中的修订号 = %d, 期望 %d", stored.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveProfileVersion(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "alice")
+	ctx := context.Background()
+
+	for want := uint(1); want <= 2; want++ {
+		profile := Profile{UserID: user.ID, Bio: "简介"}
+		if err := SaveProfile(ctx, db, &profile, func(current *Profile) bool { return current.Version == want-1 }); err != nil {
+			t.Fatal(err)
+		}
+		if profile.Version != want {
+			t.Errorf("第 %d 次保存后的修订号 = %d", want, profile.Version)
+		}
+	}
+	err := SaveProfile(ctx, db, &Profile{UserID: user.ID}, func(current *Profile) bool { return current.Version == 1 })
+	if !errors.Is(err, ErrModified) {
+		t.Errorf("修订号已过期时 SaveProfile() = %v, 期望 ErrModified", err)
+	}
+}
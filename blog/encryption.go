@@ -0,0 +1,74 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alexwang789/Base1_golang_task3/fieldcrypt"
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// emailHash 邮箱的盲索引, 不区分大小写, 与加密前 email 列的排序规则一致
+func emailHash(email string) string {
+	return fieldcrypt.Hash(strings.ToLower(strings.TrimSpace(email)))
+}
+
+// ByEmail 按邮箱查找用户. 邮箱加密存储, 按盲索引匹配; 盲索引尚未补全的用户 (加密上线前的明文) 按原值匹配
+func ByEmail(email string) scopes.Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(emailMatch(email))
+	}
+}
+
+// emailMatch ByEmail 的条件, 用于与其他条件组合
+func emailMatch(email string) clause.Expr {
+	return gorm.Expr("email_hash = ? OR (email_hash IS NULL AND email = ?)", emailHash(email), email)
+}
+
+// userEmailRow 重新加密时读取的原始列, 不经过解密
+type userEmailRow struct {
+	ID        uint
+	Email     string
+	EmailHash *string
+}
+
+// RekeyUsers 用当前密钥重新加密用户的邮箱, 包括加密上线前的明文和旧密钥加密的密文, 并补全缺失的盲索引.
+// 处理全部租户, 按主键分批读取, 可重复执行, 中途失败后重新执行即可继续. 返回更新的用户数
+func RekeyUsers(ctx context.Context, db *gorm.DB) (int, error) {
+	ctx = tenant.All(ctx)
+	keyring := fieldcrypt.Current()
+	updated := 0
+	var rows []userEmailRow
+	err := db.WithContext(ctx).Table("users").Select("id, email, email_hash").
+		FindInBatches(&rows, DefaultBatchSize, func(*gorm.DB, int) error {
+			for _, r := range rows {
+				if !keyring.NeedsRekey(r.Email) && r.EmailHash != nil {
+					continue
+				}
+				plain, err := keyring.Decrypt(r.Email)
+				if err != nil {
+					return fmt.Errorf("用户 %d: %w", r.ID, err)
+				}
+				sealed, err := keyring.Encrypt(plain)
+				if err != nil {
+					return err
+				}
+				// 直接写入列值, 不经过序列化器和钩子
+				err = db.WithContext(ctx).Table("users").Where("id = ?", r.ID).
+					UpdateColumns(map[string]any{"email": sealed, "email_hash": emailHash(plain)}).Error
+				if err != nil {
+					return fmt.Errorf("更新用户 %d 失败: %w", r.ID, err)
+				}
+				updated++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return updated, fmt.Errorf("重新加密用户邮箱失败: %w", err)
+	}
+	return updated, nil
+}
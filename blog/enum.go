@@ -0,0 +1,78 @@
+package blog
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"slices"
+)
+
+// 状态字段的枚举类型: 存储为字符串代码, 读写数据库时都校验取值, 非法的值既写不进去也不会被当作合法状态读出.
+// 表上另有 CHECK 约束 (见各模型的 check 标签), 原生 SQL 和其他服务的写入同样受约束
+
+// PostStatus 文章发布状态, 取值见 PostStatuses
+type PostStatus string
+
+// PostCommentStatus 文章的评论状态, 取值见 PostCommentStatuses
+type PostCommentStatus string
+
+// CommentStatus 评论审核状态, 取值见 CommentStatuses
+type CommentStatus string
+
+// Valid 是否为 PostStatuses 之一
+func (s PostStatus) Valid() bool { return slices.Contains(PostStatuses, s) }
+
+// Value 实现 driver.Valuer, 非法的值返回错误
+func (s PostStatus) Value() (driver.Value, error) { return enumValue(s, "文章发布状态") }
+
+// Scan 实现 sql.Scanner
+func (s *PostStatus) Scan(src any) error { return scanEnum(s, src, "文章发布状态") }
+
+// Valid 是否为 PostCommentStatuses 之一
+func (s PostCommentStatus) Valid() bool { return slices.Contains(PostCommentStatuses, s) }
+
+// Value 实现 driver.Valuer, 非法的值返回错误
+func (s PostCommentStatus) Value() (driver.Value, error) { return enumValue(s, "文章评论状态") }
+
+// Scan 实现 sql.Scanner
+func (s *PostCommentStatus) Scan(src any) error { return scanEnum(s, src, "文章评论状态") }
+
+// Valid 是否为 CommentStatuses 之一
+func (s CommentStatus) Valid() bool { return slices.Contains(CommentStatuses, s) }
+
+// Value 实现 driver.Valuer, 非法的值返回错误
+func (s CommentStatus) Value() (driver.Value, error) { return enumValue(s, "评论审核状态") }
+
+// Scan 实现 sql.Scanner
+func (s *CommentStatus) Scan(src any) error { return scanEnum(s, src, "评论审核状态") }
+
+type enum interface {
+	~string
+	Valid() bool
+}
+
+func enumValue[T enum](v T, kind string) (driver.Value, error) {
+	if !v.Valid() {
+		return nil, fmt.Errorf("不支持的%s %q", kind, string(v))
+	}
+	return string(v), nil
+}
+
+func scanEnum[T enum](dst *T, src any, kind string) error {
+	var v T
+	switch src := src.(type) {
+	case nil:
+		*dst = v // 外连接等读到 NULL 时为零值
+		return nil
+	case string:
+		v = T(src)
+	case []byte:
+		v = T(src)
+	default:
+		return fmt.Errorf("读取%s失败: 不支持的类型 %T", kind, src)
+	}
+	if !v.Valid() {
+		return fmt.Errorf("读取%s失败: 不支持的值 %q", kind, string(v))
+	}
+	*dst = v
+	return nil
+}
@@ -0,0 +1,107 @@
+package blog
+
+import (
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/outbox"
+	"gorm.io/gorm"
+)
+
+// 写入发件箱的领域事件类型, 由模型钩子在同一事务中产生
+const (
+	EventUserRegistered   = "blog.user_registered"
+	EventPostCreated      = "blog.post_created"
+	EventPostDeleted      = "blog.post_deleted"
+	EventPostArchived     = "blog.post_archived"
+	EventPostRestored     = "blog.post_restored"
+	EventCommentPublished = "blog.comment_published"
+	EventCommentDeleted   = "blog.comment_deleted"
+)
+
+// UserRegistered 新用户注册. 不包含密码
+type UserRegistered struct {
+	UserID uint   `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+}
+
+// PostCreated 新文章发布
+type PostCreated struct {
+	PostID uint   `json:"post_id"`
+	UserID uint   `json:"user_id"`
+	Title  string `json:"title"`
+}
+
+// PostDeleted 文章被删除, 它的评论随之删除, 不再单独产生 CommentDeleted
+type PostDeleted struct {
+	PostID uint `json:"post_id"`
+	UserID uint `json:"user_id"`
+}
+
+// PostArchival 文章被归档, 它和它的评论不再对外展示
+type PostArchival struct {
+	PostID uint `json:"post_id"`
+	UserID uint `json:"user_id"`
+}
+
+// PostRestoration 已归档的文章被恢复
+type PostRestoration struct {
+	PostID uint `json:"post_id"`
+	UserID uint `json:"user_id"`
+}
+
+// CommentPublished 评论对外可见: 创建时已通过审核, 或之后审核通过
+type CommentPublished struct {
+	CommentID uint   `json:"comment_id"`
+	PostID    uint   `json:"post_id"`
+	UserID    uint   `json:"user_id"`
+	ParentID  *uint  `json:"parent_id,omitempty"`
+	Content   string `json:"content"`
+}
+
+// CommentDeleted 评论被删除
+type CommentDeleted struct {
+	CommentID uint `json:"comment_id"`
+	PostID    uint `json:"post_id"`
+	UserID    uint `json:"user_id"`
+}
+
+func userKey(id uint) string { return fmt.Sprintf("user:%d", id) }
+func postKey(id uint) string { return fmt.Sprintf("post:%d", id) }
+
+// 各钩子写入事件的辅助函数
+
+func addUserRegistered(tx *gorm.DB, u *User) error {
+	return outbox.Add(tx, EventUserRegistered, userKey(u.ID), UserRegistered{UserID: u.ID, Name: u.Name, Email: u.Email})
+}
+
+func addPostCreated(tx *gorm.DB, p *Post) error {
+	return outbox.Add(tx, EventPostCreated, postKey(p.ID), PostCreated{PostID: p.ID, UserID: p.UserID, Title: p.Title})
+}
+
+func addPostDeleted(tx *gorm.DB, p *Post) error {
+	return outbox.Add(tx, EventPostDeleted, postKey(p.ID), PostDeleted{PostID: p.ID, UserID: p.UserID})
+}
+
+func addPostArchived(tx *gorm.DB, p *Post) error {
+	return outbox.Add(tx, EventPostArchived, postKey(p.ID), PostArchival{PostID: p.ID, UserID: p.UserID})
+}
+
+func addPostRestored(tx *gorm.DB, p *Post) error {
+	return outbox.Add(tx, EventPostRestored, postKey(p.ID), PostRestoration{PostID: p.ID, UserID: p.UserID})
+}
+
+// 评论事件以文章为键, 同一文章的事件进入同一分区, 保持顺序
+func addCommentPublished(tx *gorm.DB, c *Comment) error {
+	return outbox.Add(tx, EventCommentPublished, postKey(c.PostID), CommentPublished{
+		CommentID: c.ID,
+		PostID:    c.PostID,
+		UserID:    c.UserID,
+		ParentID:  c.ParentID,
+		Content:   c.Content,
+	})
+}
+
+func addCommentDeleted(tx *gorm.DB, c *Comment) error {
+	return outbox.Add(tx, EventCommentDeleted, postKey(c.PostID), CommentDeleted{CommentID: c.ID, PostID: c.PostID, UserID: c.UserID})
+}
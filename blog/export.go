@@ -0,0 +1,187 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/exportsink"
+	"github.com/alexwang789/Base1_golang_task3/querytimeout"
+	"gorm.io/gorm"
+)
+
+// 导出时每次读取的行数
+const exportChunkSize = 500
+
+type userRecord struct {
+	ID           uint      `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	ArticleCount int       `json:"article_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (userRecord) CSVHeader() []string {
+	return []string{"id", "name", "email", "article_count", "created_at"}
+}
+
+func (r userRecord) CSVRow() []string {
+	return []string{formatUint(r.ID), r.Name, r.Email, strconv.Itoa(r.ArticleCount), r.CreatedAt.Format(time.RFC3339)}
+}
+
+type postRecord struct {
+	ID            uint            `json:"id"`
+	Title         string          `json:"title"`
+	Content       string          `json:"content"`
+	CommentStatus string          `json:"comment_status"`
+	UserID        uint            `json:"user_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	Comments      []commentRecord `json:"comments"`
+}
+
+// CSV 无法嵌套, 只输出评论数
+func (postRecord) CSVHeader() []string {
+	return []string{"id", "title", "content", "comment_status", "user_id", "created_at", "updated_at", "comment_count"}
+}
+
+func (r postRecord) CSVRow() []string {
+	return []string{
+		formatUint(r.ID), r.Title, r.Content, r.CommentStatus, formatUint(r.UserID),
+		r.CreatedAt.Format(time.RFC3339), r.UpdatedAt.Format(time.RFC3339), strconv.Itoa(len(r.Comments)),
+	}
+}
+
+type commentRecord struct {
+	ID        uint      `json:"id"`
+	PostID    uint      `json:"post_id"`
+	UserID    uint      `json:"user_id"`
+	ParentID  *uint     `json:"parent_id"`
+	Content   string    `json:"content"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (commentRecord) CSVHeader() []string {
+	return []string{"id", "post_id", "user_id", "parent_id", "content", "status", "created_at", "updated_at"}
+}
+
+func (r commentRecord) CSVRow() []string {
+	parentID := ""
+	if r.ParentID != nil {
+		parentID = formatUint(*r.ParentID)
+	}
+	return []string{
+		formatUint(r.ID), formatUint(r.PostID), formatUint(r.UserID), parentID, r.Content, r.Status,
+		r.CreatedAt.Format(time.RFC3339), r.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toCommentRecord(c *Comment) commentRecord {
+	return commentRecord{
+		ID:        c.ID,
+		PostID:    c.PostID,
+		UserID:    c.UserID,
+		ParentID:  c.ParentID,
+		Content:   c.Content,
+		Status:    string(c.Status),
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+// Tables 可导出的博客数据
+var Tables = []string{"users", "posts", "comments"}
+
+// Export 按主键顺序把 table (Tables 之一) 的数据写入 out, 续传时从 out.After() 之后开始.
+// 用户不导出密码, 文章附带其全部评论 (含未通过审核的, 以 status 区分)
+func Export(ctx context.Context, db *gorm.DB, table string, out *exportsink.Output) error {
+	switch table {
+	case "users":
+		return exportGorm(ctx, db, out, func(u *User) (exportsink.Record, uint) {
+			return userRecord{
+				ID:           u.ID,
+				Name:         u.Name,
+				Email:        u.Email,
+				ArticleCount: u.ArticleCount,
+				CreatedAt:    u.CreatedAt,
+			}, u.ID
+		})
+	case "posts":
+		return exportPosts(ctx, db, out)
+	case "comments":
+		return exportGorm(ctx, db, out, func(c *Comment) (exportsink.Record, uint) {
+			return toCommentRecord(c), c.ID
+		})
+	default:
+		return fmt.Errorf("不支持导出表 %q", table)
+	}
+}
+
+// 按主键顺序分批读取并逐行写出. 每批查询按报表限时, 超时中断后可以从已写出的位置继续
+func exportGorm[T any](ctx context.Context, db *gorm.DB, out *exportsink.Output, record func(*T) (exportsink.Record, uint)) error {
+	tx := db.WithContext(querytimeout.Report(ctx)).Where("id > ?", out.After())
+	err := forEachRow(tx, func(row *T) error {
+		rec, id := record(row)
+		return out.Write(rec, uint64(id))
+	})
+	if err != nil {
+		return fmt.Errorf("导出数据失败: %w", err)
+	}
+	return nil
+}
+
+// 导出文章及其评论: 按主键分批读取文章, 每批用一条查询加载这批文章的全部评论
+func exportPosts(ctx context.Context, db *gorm.DB, out *exportsink.Output) error {
+	db = db.WithContext(ctx)
+	after := out.After()
+	for {
+		var posts []Post
+		if err := db.Where("id > ?", after).Order("id").Limit(exportChunkSize).Find(&posts).Error; err != nil {
+			return fmt.Errorf("查询导出数据失败: %w", err)
+		}
+		if len(posts) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(posts))
+		for i, p := range posts {
+			ids[i] = p.ID
+		}
+		var comments []Comment
+		if err := db.Where("post_id IN ?", ids).Order("id").Find(&comments).Error; err != nil {
+			return fmt.Errorf("查询导出数据失败: %w", err)
+		}
+		byPost := make(map[uint][]commentRecord, len(posts))
+		for i := range comments {
+			byPost[comments[i].PostID] = append(byPost[comments[i].PostID], toCommentRecord(&comments[i]))
+		}
+
+		for _, p := range posts {
+			comments := byPost[p.ID]
+			if comments == nil {
+				comments = []commentRecord{}
+			}
+			rec := postRecord{
+				ID:            p.ID,
+				Title:         p.Title,
+				Content:       p.Content,
+				CommentStatus: string(p.CommentStatus),
+				UserID:        p.UserID,
+				CreatedAt:     p.CreatedAt,
+				UpdatedAt:     p.UpdatedAt,
+				Comments:      comments,
+			}
+			if err := out.Write(rec, uint64(p.ID)); err != nil {
+				return err
+			}
+		}
+		after = uint64(posts[len(posts)-1].ID)
+	}
+}
+
+func formatUint(n uint) string {
+	return strconv.FormatUint(uint64(n), 10)
+}
@@ -0,0 +1,102 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrFollowSelf 不能关注自己
+var ErrFollowSelf = errors.New("不能关注自己")
+
+// Follow 关注关系: FollowerID 关注了 FolloweeID.
+// 主键 (follower_id, followee_id) 用于 Feed 查出 "我关注的人", idx_follows_followee 用于查 "谁关注了我"
+type Follow struct {
+	FollowerID uint `gorm:"primaryKey;index:idx_follows_followee,priority:2"`
+	FolloweeID uint `gorm:"primaryKey;index:idx_follows_followee,priority:1"`
+	CreatedAt  time.Time
+}
+
+// FollowUser 关注用户, 已关注时不做任何事. 被关注的用户不存在时返回 ErrUserNotFound
+func FollowUser(ctx context.Context, db *gorm.DB, followerID, followeeID uint) error {
+	if followerID == followeeID {
+		return ErrFollowSelf
+	}
+	return transaction(ctx, db, func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&User{}).Where("id = ?", followeeID).Count(&n).Error; err != nil {
+			return fmt.Errorf("查询用户失败: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("用户 %d: %w", followeeID, ErrUserNotFound)
+		}
+		follow := Follow{FollowerID: followerID, FolloweeID: followeeID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error; err != nil {
+			return fmt.Errorf("关注失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// UnfollowUser 取消关注, 没有关注时不做任何事
+func UnfollowUser(ctx context.Context, db *gorm.DB, followerID, followeeID uint) error {
+	err := db.WithContext(ctx).Delete(&Follow{FollowerID: followerID, FolloweeID: followeeID}).Error
+	if err != nil {
+		return fmt.Errorf("取消关注失败: %w", err)
+	}
+	return nil
+}
+
+// FeedPage Feed 的一页: Before 不为 0 时返回 ID 小于它的文章 (keyset 翻页, 传上一页最后一篇的 ID), Size 为页大小
+type FeedPage struct {
+	Before uint
+	Size   int
+}
+
+// 默认和最大的页大小
+const (
+	DefaultFeedSize = 20
+	MaxFeedSize     = 100
+)
+
+// sqlFeed 关注的人最近的文章. 对每个关注的作者用 LATERAL 子查询沿 posts 的 idx_posts_user_id (user_id, id)
+// 倒序最多取一页, 再合并取最新的一页: 代价与 关注数 x 页大小 成正比, 与作者的文章总数和全站文章数无关.
+// 直接 JOIN 后按 posts.id 排序则要么扫描关注者全部文章再排序, 要么沿主键倒序扫描全站文章逐篇判断作者
+const sqlFeed = `
+	SELECT p.*
+	FROM follows AS f
+	JOIN LATERAL (
+		SELECT posts.*
+		FROM posts
+		WHERE posts.user_id = f.followee_id AND posts.id < ? AND posts.status = ?
+		ORDER BY posts.id DESC
+		LIMIT ?
+	) AS p ON TRUE
+	WHERE f.follower_id = ?
+	ORDER BY p.id DESC
+	LIMIT ?
+`
+
+// Feed 按发布时间倒序返回 userID 关注的作者已发布的文章
+func Feed(ctx context.Context, db *gorm.DB, userID uint, page FeedPage) ([]Post, error) {
+	size := page.Size
+	if size <= 0 {
+		size = DefaultFeedSize
+	}
+	size = min(size, MaxFeedSize)
+	before := uint64(math.MaxInt64)
+	if page.Before != 0 {
+		before = uint64(page.Before)
+	}
+
+	var posts []Post
+	if err := db.WithContext(ctx).Raw(sqlFeed, before, PostPublished, size, userID, size).Scan(&posts).Error; err != nil {
+		return nil, fmt.Errorf("查询关注动态失败: %w", err)
+	}
+	return posts, nil
+}
@@ -0,0 +1,314 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/anonymize"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"gorm.io/gorm"
+)
+
+// 每个事务写入的行数, 事务内再按 BatchSize 拆成多条 INSERT
+const generateChunkSize = 5000
+
+// GenerateOptions 压测数据的规模和分布
+type GenerateOptions struct {
+	Users     int
+	Posts     int
+	Comments  int
+	Days      int   // 文章分布在最近多少天内, <= 0 时为 365
+	Seed      int64 // 随机种子, 同一种子下生成的数据相同
+	BatchSize int   // 每条 INSERT 语句的行数, <= 0 时使用 DefaultBatchSize
+
+	// Progress 每写完一个事务调用一次, 可以为 nil
+	Progress func(table string, done, total int)
+}
+
+// GenerateResult 实际写入的行数
+type GenerateResult struct {
+	Users    int
+	Posts    int
+	Comments int
+}
+
+// 拼接文章标题和正文的素材
+var (
+	genTopics  = []string{"Go", "MySQL", "Redis", "Kubernetes", "微服务", "分布式事务", "索引优化", "消息队列", "缓存", "性能测试", "GORM", "并发编程"}
+	genAngles  = []string{"入门", "实践", "踩坑记录", "原理解析", "最佳实践", "性能调优", "源码阅读", "常见问题", "设计取舍", "线上复盘"}
+	genPhrases = []string{
+		"这个问题在数据量小的时候并不明显.", "上线一周后慢查询日志里开始出现它.", "我们先用 EXPLAIN 看了执行计划.",
+		"瓶颈最后定位在一次没有走索引的排序上.", "改成批量查询后接口耗时降了一个数量级.", "连接池的配置也需要一起调整.",
+		"压测时要关注 P99 而不只是平均值.", "缓存失效的时机比缓存本身更难设计.", "事务要尽量短, 不要在事务里调用外部服务.",
+		"这里的取舍取决于读写比例.", "监控先行, 否则优化无从验证.", "下面是完整的复现步骤.",
+	}
+)
+
+// GenerateLoadData 生成压测数据: 用户、文章和评论按接近真实的分布写入 ctx 所属的租户.
+//   - 文章的作者和评论者服从 Zipf 分布, 少数活跃用户贡献大部分内容
+//   - 评论集中在少数热门文章上 (同样服从 Zipf 分布), 约 20% 是对同一文章中已有评论的回复
+//   - 文章时间均匀分布在最近 Days 天内, 按时间顺序写入; 评论时间在文章之后, 间隔服从指数分布
+//   - 约 90% 的评论已通过审核, 其余为待审核或已拒绝
+//
+// 为了速度, 写入时跳过钩子 (不产生事件、通知和审计日志), 之后统一回填用户文章数、文章评论状态并重建文章统计.
+// 用户名和邮箱由 anonymize.Faker 按序号生成, 密码统一为 "genload"
+func GenerateLoadData(ctx context.Context, db *gorm.DB, opts GenerateOptions) (GenerateResult, error) {
+	var result GenerateResult
+	if opts.Users <= 0 && (opts.Posts > 0 || opts.Comments > 0) {
+		return result, fmt.Errorf("生成文章和评论至少需要 1 个用户")
+	}
+	if opts.Posts <= 0 && opts.Comments > 0 {
+		return result, fmt.Errorf("生成评论至少需要 1 篇文章")
+	}
+	if opts.Days <= 0 {
+		opts.Days = 365
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	g := &generator{
+		db:    db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}),
+		opts:  opts,
+		rng:   rand.New(rand.NewPCG(uint64(opts.Seed), 0)),
+		faker: anonymize.New(opts.Seed),
+		now:   time.Now(),
+	}
+	g.start = g.now.AddDate(0, 0, -opts.Days)
+
+	if err := g.users(ctx); err != nil {
+		return result, err
+	}
+	result.Users = len(g.userIDs)
+	if err := g.posts(); err != nil {
+		return result, err
+	}
+	result.Posts = len(g.postIDs)
+	n, err := g.comments()
+	result.Comments = n
+	if err != nil {
+		return result, err
+	}
+	return result, g.backfill(ctx)
+}
+
+type generator struct {
+	db    *gorm.DB
+	opts  GenerateOptions
+	rng   *rand.Rand
+	faker *anonymize.Faker
+	now   time.Time
+	start time.Time
+
+	userIDs   []uint
+	postIDs   []uint
+	postTimes []time.Time
+}
+
+// insert 在一个事务中分批写入一块数据
+func (g *generator) insert(table string, rows any, done, total int) error {
+	err := g.db.Transaction(func(tx *gorm.DB) error {
+		if comments, ok := rows.(*[]Comment); ok {
+			return createComments(tx.Statement.Context, tx, *comments, g.opts.BatchSize)
+		}
+		return tx.CreateInBatches(rows, g.opts.BatchSize).Error
+	})
+	if err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", table, err)
+	}
+	if g.opts.Progress != nil {
+		g.opts.Progress(table, done, total)
+	}
+	return nil
+}
+
+// zipf 返回 [0, n) 上的 Zipf 分布, 下标经过打乱, 热门的不总是 ID 最小的
+func (g *generator) zipf(n int, s float64) func() int {
+	z := rand.NewZipf(g.rng, s, 1, uint64(n-1))
+	perm := g.rng.Perm(n)
+	return func() int { return perm[z.Uint64()] }
+}
+
+func (g *generator) users(ctx context.Context) error {
+	// 用户名和邮箱按序号生成, 从当前最大的 ID 之后开始编号, 与已有用户不重复
+	var base uint64
+	err := g.db.WithContext(tenant.All(ctx)).Model(&User{}).Select("COALESCE(MAX(id), 0)").Scan(&base).Error
+	if err != nil {
+		return fmt.Errorf("查询最大用户 ID 失败: %w", err)
+	}
+	// 跳过了钩子, 密码在这里哈希, 全部用户共用
+	password, err := HashPassword("genload1")
+	if err != nil {
+		return err
+	}
+	for done := 0; done < g.opts.Users; {
+		chunk := make([]User, min(generateChunkSize, g.opts.Users-done))
+		for i := range chunk {
+			seq := base + uint64(done+i) + 1
+			p := g.faker.Person(seq)
+			email := p.Email(seq)
+			hash := emailHash(email)
+			// 注册时间在第一篇文章之前的 30 天内
+			created := g.start.Add(-time.Duration(g.rng.Int64N(int64(30 * 24 * time.Hour))))
+			chunk[i] = User{
+				Name:          p.UniqueName(seq),
+				Email:         email,
+				EmailHash:     &hash,
+				Password:      password,
+				EmailVerified: true,
+				CreatedAt:     created,
+				UpdatedAt:     created,
+			}
+			// 主键和 UUID 键同样在这里生成
+			assignID(&chunk[i].ID)
+			assignUUID(&chunk[i].UUID)
+		}
+		done += len(chunk)
+		if err := g.insert("users", &chunk, done, g.opts.Users); err != nil {
+			return err
+		}
+		for _, u := range chunk {
+			g.userIDs = append(g.userIDs, u.ID)
+		}
+	}
+	return nil
+}
+
+func (g *generator) posts() error {
+	if g.opts.Posts <= 0 {
+		return nil
+	}
+	// 先生成全部发布时间并排序, 文章 ID 与时间同序
+	span := int64(g.now.Sub(g.start))
+	g.postTimes = make([]time.Time, g.opts.Posts)
+	for i := range g.postTimes {
+		g.postTimes[i] = g.start.Add(time.Duration(g.rng.Int64N(span)))
+	}
+	slices.SortFunc(g.postTimes, func(a, b time.Time) int { return a.Compare(b) })
+
+	author := g.zipf(len(g.userIDs), 1.2)
+	for done := 0; done < g.opts.Posts; {
+		chunk := make([]Post, min(generateChunkSize, g.opts.Posts-done))
+		for i := range chunk {
+			t := g.postTimes[done+i]
+			chunk[i] = Post{
+				Title:         g.title(done + i + 1),
+				Content:       g.paragraphs(),
+				CommentStatus: PostNoComments,
+				Status:        PostPublished,
+				CreatedAt:     t,
+				UpdatedAt:     t,
+				UserID:        g.userIDs[author()],
+			}
+			assignID(&chunk[i].ID)
+			assignUUID(&chunk[i].UUID)
+		}
+		done += len(chunk)
+		if err := g.insert("posts", &chunk, done, g.opts.Posts); err != nil {
+			return err
+		}
+		for _, p := range chunk {
+			g.postIDs = append(g.postIDs, p.ID)
+		}
+	}
+	return nil
+}
+
+func (g *generator) comments() (int, error) {
+	if g.opts.Comments <= 0 {
+		return 0, nil
+	}
+	post := g.zipf(len(g.postIDs), 1.1)
+	commenter := g.zipf(len(g.userIDs), 1.05)
+	// 各文章最近写入的评论, 用于生成回复; 只能回复之前的事务中已有 ID 的评论
+	last := make([]uint, len(g.postIDs))
+	for done := 0; done < g.opts.Comments; {
+		chunk := make([]Comment, min(generateChunkSize, g.opts.Comments-done))
+		index := make([]int, len(chunk))
+		for i := range chunk {
+			pi := post()
+			index[i] = pi
+			// 评论大多在发布后的几天内, 间隔平均 2 天
+			t := g.postTimes[pi].Add(time.Duration(g.rng.ExpFloat64() * float64(48*time.Hour)))
+			if t.After(g.now) {
+				t = g.now
+			}
+			c := Comment{
+				Content:   genPhrases[g.rng.IntN(len(genPhrases))],
+				Status:    g.commentStatus(),
+				CreatedAt: t,
+				UpdatedAt: t,
+				PostID:    g.postIDs[pi],
+				UserID:    g.userIDs[commenter()],
+			}
+			if last[pi] != 0 && g.rng.Float64() < 0.2 {
+				parent := last[pi]
+				c.ParentID = &parent
+			}
+			assignID(&c.ID)
+			assignUUID(&c.UUID)
+			chunk[i] = c
+		}
+		done += len(chunk)
+		if err := g.insert("comments", &chunk, done, g.opts.Comments); err != nil {
+			return done - len(chunk), err
+		}
+		for i, c := range chunk {
+			last[index[i]] = c.ID
+		}
+	}
+	return g.opts.Comments, nil
+}
+
+func (g *generator) commentStatus() CommentStatus {
+	switch x := g.rng.Float64(); {
+	case x < 0.9:
+		return CommentApproved
+	case x < 0.97:
+		return CommentPending
+	default:
+		return CommentRejected
+	}
+}
+
+func (g *generator) title(n int) string {
+	return genTopics[g.rng.IntN(len(genTopics))] + " " + genAngles[g.rng.IntN(len(genAngles))] + " #" + strconv.Itoa(n)
+}
+
+// paragraphs 正文为 1 到 8 段, 每段 3 到 10 句
+func (g *generator) paragraphs() string {
+	var b strings.Builder
+	for p := range 1 + g.rng.IntN(8) {
+		if p > 0 {
+			b.WriteString("\n\n")
+		}
+		for range 3 + g.rng.IntN(8) {
+			b.WriteString(genPhrases[g.rng.IntN(len(genPhrases))])
+		}
+	}
+	return b.String()
+}
+
+// backfill 回填跳过钩子而未维护的派生数据. 生成的用户和文章 ID 递增 (自增或本节点的 snowflake), 按 ID 范围更新
+func (g *generator) backfill(ctx context.Context) error {
+	if len(g.userIDs) > 0 && len(g.postIDs) > 0 {
+		err := g.db.Model(&User{}).Where("id BETWEEN ? AND ?", g.userIDs[0], g.userIDs[len(g.userIDs)-1]).
+			Update("article_count", gorm.Expr("(SELECT COUNT(*) FROM posts WHERE posts.user_id = users.id AND posts.status <> ?)", PostScheduled)).Error
+		if err != nil {
+			return fmt.Errorf("回填用户文章数失败: %w", err)
+		}
+	}
+	if len(g.postIDs) > 0 && g.opts.Comments > 0 {
+		err := g.db.Model(&Post{}).Where("id BETWEEN ? AND ?", g.postIDs[0], g.postIDs[len(g.postIDs)-1]).
+			Where("EXISTS (SELECT 1 FROM "+commentTable.From("")+" WHERE comments.post_id = posts.id AND comments.status = ?)", CommentApproved).
+			Update("comment_status", PostHasComments).Error
+		if err != nil {
+			return fmt.Errorf("回填文章评论状态失败: %w", err)
+		}
+	}
+	return RebuildPostStats(ctx, g.db)
+}
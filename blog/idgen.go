@@ -0,0 +1,14 @@
+package blog
+
+import "github.com/alexwang789/Base1_golang_task3/snowflake"
+
+// idGenerator 用户、文章和评论的主键生成器, 由 Open 按 ID_GENERATOR 设置; 为 nil 时使用数据库自增.
+// 两种方式可以先后使用: 显式写入的 ID 会推高自增计数器, 切回自增后也不会与已生成的 ID 冲突
+var idGenerator *snowflake.Generator
+
+// assignID 配置了生成器时为尚未设置的主键生成 ID
+func assignID(id *uint) {
+	if idGenerator != nil && *id == 0 {
+		*id = uint(idGenerator.Next())
+	}
+}
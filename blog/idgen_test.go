@@ -0,0 +1,52 @@
+package blog
+
+import (
+	"testing"
+
+	"github.com/alexwang789/Base1_golang_task3/snowflake"
+	"gorm.io/gorm"
+)
+
+// withSnowflake 在测试期间使用雪花 ID 生成器
+func withSnowflake(t *testing.T) {
+	t.Helper()
+	gen, err := snowflake.New(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idGenerator = gen
+	t.Cleanup(func() { idGenerator = nil })
+}
+
+func TestUserCreateAssignsSnowflakeID(t *testing.T) {
+	withSnowflake(t)
+	db := newTestDB(t)
+
+	user := newTestUser(t, db, "alice")
+	if _, node, _ := snowflake.Parts(uint64(user.ID)); node != 1 {
+		t.Errorf("用户 ID %d 不是节点 1 生成的雪花 ID", user.ID)
+	}
+}
+
+// 对空模型的 Update 不能生成主键, 否则生成的 ID 会成为更新条件, 更新不到任何行
+func TestUpdateOnEmptyUserModelWithSnowflake(t *testing.T) {
+	withSnowflake(t)
+	db := newTestDB(t)
+	user := newTestUser(t, db, "alice")
+
+	result := db.Model(&User{}).Where("id = ?", user.ID).Update("article_count", gorm.Expr("article_count + ?", 1))
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if result.RowsAffected != 1 {
+		t.Fatalf("RowsAffected = %d, want 1", result.RowsAffected)
+	}
+
+	var got User
+	if err := db.First(&got, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.ArticleCount != 1 {
+		t.Errorf("ArticleCount = %d, want 1", got.ArticleCount)
+	}
+}
@@ -0,0 +1,139 @@
+//go:build integration
+
+package blog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexwang789/Base1_golang_task3/testmysql"
+)
+
+// 在真实的 MySQL 上执行文章和评论钩子的各个场景, 每步之后核对冗余计数. 运行: go test -tags=integration ./blog
+func TestHooksOnMySQL(t *testing.T) {
+	testmysql.Start(t, "blog_db", DSNParams)
+	db, err := Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Close(db) })
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	author := newTestUser(t, db, "alice")
+	reader := newTestUser(t, db, "bob")
+	admin := newTestUser(t, db, "admin")
+	if err := db.Model(admin).Update("is_admin", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	posts := NewPostRepository(db)
+	comments := NewCommentRepository(db)
+
+	first := &Post{UserID: author.ID, Title: "第一篇", Content: "内容"}
+	second := &Post{UserID: author.ID, Title: "第二篇", Content: "内容"}
+	var approved, pending Comment
+
+	t.Run("创建文章", func(t *testing.T) {
+		for _, p := range []*Post{first, second} {
+			if err := posts.Create(ctx, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		assertArticleCount(t, db, author.ID, 2)
+		assertCommentStatus(t, db, first.ID, PostNoComments)
+		assertPostStats(t, db, first.ID, 0)
+	})
+
+	t.Run("创建评论", func(t *testing.T) {
+		// 已确定状态的评论直接计入, 待审核的评论审核通过时才计入
+		approved = Comment{PostID: first.ID, UserID: reader.ID, Content: "不错", Status: CommentApproved}
+		if err := db.Create(&approved).Error; err != nil {
+			t.Fatal(err)
+		}
+		assertCommentStatus(t, db, first.ID, PostHasComments)
+		assertPostStats(t, db, first.ID, 1)
+
+		pending = Comment{PostID: first.ID, UserID: reader.ID, Content: "再来一条"}
+		if err := CreateComment(ctx, db, nil, &pending); err != nil {
+			t.Fatal(err)
+		}
+		assertPostStats(t, db, first.ID, 1)
+		if err := ApproveComment(ctx, db, admin, pending.ID); err != nil {
+			t.Fatal(err)
+		}
+		assertPostStats(t, db, first.ID, 2)
+	})
+
+	t.Run("删除评论", func(t *testing.T) {
+		if err := comments.Delete(ctx, approved.ID); err != nil {
+			t.Fatal(err)
+		}
+		assertCommentStatus(t, db, first.ID, PostHasComments)
+		assertPostStats(t, db, first.ID, 1)
+
+		if err := comments.Delete(ctx, pending.ID); err != nil {
+			t.Fatal(err)
+		}
+		assertCommentStatus(t, db, first.ID, PostNoComments)
+		assertPostStats(t, db, first.ID, 0)
+	})
+
+	// 文章没有软删除列, 归档是对外隐藏、可以恢复的软删除
+	t.Run("归档和恢复文章", func(t *testing.T) {
+		if err := ArchivePost(ctx, db, author, second.ID); err != nil {
+			t.Fatal(err)
+		}
+		assertArticleCount(t, db, author.ID, 1)
+		if err := RestorePost(ctx, db, author, second.ID); err != nil {
+			t.Fatal(err)
+		}
+		assertArticleCount(t, db, author.ID, 2)
+	})
+
+	t.Run("删除文章", func(t *testing.T) {
+		comment := Comment{PostID: second.ID, UserID: reader.ID, Content: "评论会随文章删除", Status: CommentApproved}
+		if err := db.Create(&comment).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := posts.Delete(ctx, second.ID); err != nil {
+			t.Fatal(err)
+		}
+		assertArticleCount(t, db, author.ID, 1)
+
+		for _, model := range []any{&Comment{}, &PostStat{}} {
+			var n int64
+			if err := db.Model(model).Where("post_id = ?", second.ID).Count(&n).Error; err != nil {
+				t.Fatal(err)
+			}
+			if n != 0 {
+				t.Errorf("删除文章后还有 %d 行 %T", n, model)
+			}
+		}
+	})
+
+	// 多个连接并发写入, 钩子靠 WithRowLock 串行化计数的读取和写回
+	t.Run("并发创建文章和评论", func(t *testing.T) {
+		const n = 10
+		created := make([]Post, n)
+		run(t, n, func(i int) error {
+			created[i] = Post{UserID: author.ID, Title: "并发", Content: "内容"}
+			return posts.Create(ctx, &created[i])
+		})
+		assertArticleCount(t, db, author.ID, 1+n)
+
+		run(t, n, func(i int) error {
+			return db.Create(&Comment{PostID: created[0].ID, UserID: reader.ID, Content: "并发评论", Status: CommentApproved}).Error
+		})
+		assertPostStats(t, db, created[0].ID, n)
+	})
+
+	mismatches, err := CheckCounters(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range mismatches {
+		t.Errorf("计数不一致: %s", m)
+	}
+}
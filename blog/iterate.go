@@ -0,0 +1,65 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/filterdsl"
+	"gorm.io/gorm"
+)
+
+// iterateBatchSize ForEachPost 等逐行处理时每次读取的行数
+const iterateBatchSize = 500
+
+// ErrStopIteration fn 返回它时提前结束遍历, ForEachPost 等返回 nil
+var ErrStopIteration = errors.New("停止遍历")
+
+// ForEachPost 按主键顺序对符合 filter 的每篇文章调用 fn, filter 为 nil 时包含全部文章 (含已归档和定时发布的).
+// 按主键分批读取 (keyset 翻页), 每批 iterateBatchSize 篇, 同一时刻内存中只有一批, 适合导出和批处理任务.
+// filter 中的排序被忽略. fn 返回 ErrStopIteration 时提前结束, 返回其他错误时中止并返回该错误.
+// 不预加载关联, fn 中不要保留 *Post, 下一批会复用同一块内存
+func ForEachPost(ctx context.Context, db *gorm.DB, filter *filterdsl.Query, fn func(*Post) error) error {
+	tx := db.WithContext(ctx)
+	if filter != nil {
+		tx = tx.Scopes((&filterdsl.Query{Conds: filter.Conds}).Scope())
+	}
+	if err := forEachRow(tx, fn); err != nil {
+		return fmt.Errorf("遍历文章失败: %w", err)
+	}
+	return nil
+}
+
+// ForEachUser 按主键顺序对每个用户调用 fn, 分批方式和 fn 的约定同 ForEachPost
+func ForEachUser(ctx context.Context, db *gorm.DB, fn func(*User) error) error {
+	if err := forEachRow(db.WithContext(ctx), fn); err != nil {
+		return fmt.Errorf("遍历用户失败: %w", err)
+	}
+	return nil
+}
+
+// ForEachComment 按主键顺序对每条评论 (含未通过审核的) 调用 fn, 分批方式和 fn 的约定同 ForEachPost
+func ForEachComment(ctx context.Context, db *gorm.DB, fn func(*Comment) error) error {
+	if err := forEachRow(db.WithContext(ctx), fn); err != nil {
+		return fmt.Errorf("遍历评论失败: %w", err)
+	}
+	return nil
+}
+
+// forEachRow 用 FindInBatches 按主键分批读取 tx 查询到的 T, 逐行调用 fn. 批与批之间不占用连接,
+// fn 中可以用同一个 db 读写. fn 返回 ErrStopIteration 时提前结束并返回 nil
+func forEachRow[T any](tx *gorm.DB, fn func(*T) error) error {
+	var batch []T
+	err := tx.FindInBatches(&batch, iterateBatchSize, func(*gorm.DB, int) error {
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+	return err
+}
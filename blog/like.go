@@ -0,0 +1,53 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostLike 用户对文章的点赞, 每个用户对每篇文章最多一条
+type PostLike struct {
+	UserID    uint      `gorm:"primaryKey"`
+	PostID    uint      `gorm:"primaryKey;index"`
+	CreatedAt time.Time `gorm:"index"` // 热度计算按时间范围读取
+}
+
+// LikePost 点赞文章并通知作者, 已赞过时不做任何事
+func LikePost(ctx context.Context, db *gorm.DB, userID, postID uint) error {
+	return transaction(ctx, db, func(tx *gorm.DB) error {
+		like := PostLike{UserID: userID, PostID: postID}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&like)
+		if result.Error != nil {
+			return fmt.Errorf("点赞失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := addPostLikes(tx, postID, 1); err != nil {
+			return err
+		}
+		return notifyPostLiked(tx, &like)
+	})
+}
+
+// UnlikePost 取消点赞并撤回点赞通知, 没有赞过时不做任何事
+func UnlikePost(ctx context.Context, db *gorm.DB, userID, postID uint) error {
+	return transaction(ctx, db, func(tx *gorm.DB) error {
+		like := PostLike{UserID: userID, PostID: postID}
+		result := tx.Delete(&like)
+		if result.Error != nil {
+			return fmt.Errorf("取消点赞失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := addPostLikes(tx, postID, -1); err != nil {
+			return err
+		}
+		return removeLikeNotification(tx, &like)
+	})
+}
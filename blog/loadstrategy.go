@@ -0,0 +1,250 @@
+package blog
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// LoadStrategy 加载用户文章及其评论的方式
+type LoadStrategy string
+
+const (
+	// LoadPreload GORM Preload: 一条查文章, 一条按文章 ID 列表查评论 (共 2 条)
+	LoadPreload LoadStrategy = "preload"
+	// LoadJoin 一条 LEFT JOIN, 在内存中按文章分组; 文章字段随评论数重复传输
+	LoadJoin LoadStrategy = "join"
+	// LoadInQueries 手写的两条查询, 与 Preload 相同但不经过 GORM 的关联反射
+	LoadInQueries LoadStrategy = "in"
+	// LoadNPlusOne 每篇文章单独查询评论 (共 1 + 文章数 条), 仅作对照
+	LoadNPlusOne LoadStrategy = "n+1"
+)
+
+// LoadStrategies 全部加载方式
+var LoadStrategies = []LoadStrategy{LoadPreload, LoadJoin, LoadInQueries, LoadNPlusOne}
+
+// LoadUserPosts 按 strategy 加载用户的全部文章及评论, 文章和评论均按 id 排序.
+// 各方式的结果相同, 只在查询次数和传输量上有区别: N+1 的查询数随文章数线性增长,
+// JOIN 的传输量随评论数成倍增长 (文章内容重复), 一般情况下 Preload 或 IN 查询更稳定.
+// 具体数据规模下的取舍用 RunLoadBenchmark (task3 blog bench) 实测
+func LoadUserPosts(ctx context.Context, db *gorm.DB, userID uint, strategy LoadStrategy) ([]Post, error) {
+	db = db.WithContext(ctx)
+	var (
+		posts []Post
+		err   error
+	)
+	switch strategy {
+	case LoadPreload:
+		err = db.Preload("Comments", func(tx *gorm.DB) *gorm.DB { return approvedComments(tx).Order("id") }).
+			Scopes(scopes.ByUser(userID)).Order("id").Find(&posts).Error
+	case LoadJoin:
+		posts, err = loadUserPostsJoin(db, userID)
+	case LoadInQueries:
+		posts, err = loadUserPostsIn(db, userID)
+	case LoadNPlusOne:
+		posts, err = loadUserPostsNPlusOne(db, userID)
+	default:
+		return nil, fmt.Errorf("不支持的加载方式 %q", strategy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("加载用户文章失败 (%s): %w", strategy, err)
+	}
+	return posts, nil
+}
+
+func loadUserPostsJoin(db *gorm.DB, userID uint) ([]Post, error) {
+	var rows []struct {
+		Post
+		CommentID        sql.NullInt64
+		CommentContent   sql.NullString
+		CommentUserID    sql.NullInt64
+		CommentCreatedAt sql.NullTime
+		CommentUpdatedAt sql.NullTime
+	}
+	err := db.Raw(`
+		SELECT p.*,
+			c.id AS comment_id, c.content AS comment_content, c.user_id AS comment_user_id,
+			c.created_at AS comment_created_at, c.updated_at AS comment_updated_at
+		FROM posts p
+		LEFT JOIN `+commentTable.From("c")+` ON c.post_id = p.id AND c.status = ?
+		WHERE p.user_id = ?
+		ORDER BY p.id, c.id
+	`, CommentApproved, userID).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []Post
+	for _, row := range rows {
+		if len(posts) == 0 || posts[len(posts)-1].ID != row.ID {
+			post := row.Post
+			post.Comments = []Comment{}
+			posts = append(posts, post)
+		}
+		if !row.CommentID.Valid {
+			continue // 没有评论的文章
+		}
+		last := &posts[len(posts)-1]
+		last.Comments = append(last.Comments, Comment{
+			ID:        uint(row.CommentID.Int64),
+			Content:   row.CommentContent.String,
+			PostID:    row.ID,
+			UserID:    uint(row.CommentUserID.Int64),
+			CreatedAt: row.CommentCreatedAt.Time,
+			UpdatedAt: row.CommentUpdatedAt.Time,
+		})
+	}
+	return posts, nil
+}
+
+func loadUserPostsIn(db *gorm.DB, userID uint) ([]Post, error) {
+	var posts []Post
+	if err := db.Scopes(scopes.ByUser(userID)).Order("id").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return posts, nil
+	}
+
+	ids := make([]uint, len(posts))
+	index := make(map[uint]int, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+		index[posts[i].ID] = i
+		posts[i].Comments = []Comment{}
+	}
+	var comments []Comment
+	if err := db.Scopes(approvedComments).Where("post_id IN ?", ids).Order("id").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range comments {
+		p := &posts[index[c.PostID]]
+		p.Comments = append(p.Comments, c)
+	}
+	return posts, nil
+}
+
+func loadUserPostsNPlusOne(db *gorm.DB, userID uint) ([]Post, error) {
+	var posts []Post
+	if err := db.Scopes(scopes.ByUser(userID)).Order("id").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		posts[i].Comments = []Comment{}
+		if err := db.Scopes(approvedComments).Where("post_id = ?", posts[i].ID).Order("id").Find(&posts[i].Comments).Error; err != nil {
+			return nil, err
+		}
+	}
+	return posts, nil
+}
+
+// LoadBenchmark 对比各加载方式的参数, 零值字段使用默认值
+type LoadBenchmark struct {
+	Users           int // 生成的用户数, 默认 20
+	PostsPerUser    int // 每个用户的文章数, 默认 20
+	CommentsPerPost int // 每篇文章的评论数, 默认 10
+	Rounds          int // 每种方式加载全部用户的轮数, 默认 5
+}
+
+// LoadBenchmarkResult 一种加载方式的耗时
+type LoadBenchmarkResult struct {
+	Strategy LoadStrategy
+	Total    time.Duration
+	PerLoad  time.Duration // 加载一个用户的平均耗时
+}
+
+// RunLoadBenchmark 在事务中生成数据集, 用每种方式依次加载全部生成用户的文章, 结束后回滚事务, 不留下数据
+func RunLoadBenchmark(ctx context.Context, db *gorm.DB, b LoadBenchmark) ([]LoadBenchmarkResult, error) {
+	b.Users = cmp.Or(b.Users, 20)
+	b.PostsPerUser = cmp.Or(b.PostsPerUser, 20)
+	b.CommentsPerPost = cmp.Or(b.CommentsPerPost, 10)
+	b.Rounds = cmp.Or(b.Rounds, 5)
+
+	var results []LoadBenchmarkResult
+	errRollback := errors.New("回滚基准数据")
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 关闭 SQL 日志; 生成数据时跳过钩子, 加载不依赖文章数和评论状态
+		quiet := tx.Session(&gorm.Session{Logger: tx.Logger.LogMode(logger.Silent)})
+		userIDs, err := generateLoadDataset(ctx, quiet.Session(&gorm.Session{SkipHooks: true}), b)
+		if err != nil {
+			return err
+		}
+
+		for _, strategy := range LoadStrategies {
+			start := time.Now()
+			for range b.Rounds {
+				for _, id := range userIDs {
+					if _, err := LoadUserPosts(ctx, quiet, id, strategy); err != nil {
+						return err
+					}
+				}
+			}
+			total := time.Since(start)
+			results = append(results, LoadBenchmarkResult{
+				Strategy: strategy,
+				Total:    total,
+				PerLoad:  total / time.Duration(b.Rounds*len(userIDs)),
+			})
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		return nil, fmt.Errorf("加载方式基准测试失败: %w", err)
+	}
+	return results, nil
+}
+
+// 生成 b 描述的用户、文章和评论, 返回用户 ID
+func generateLoadDataset(ctx context.Context, tx *gorm.DB, b LoadBenchmark) ([]uint, error) {
+	suffix := time.Now().UnixNano() // 避免与已有用户的姓名、邮箱冲突
+	// 调用方可能跳过了钩子, 密码预先哈希, 全部用户共用
+	password, err := HashPassword("bench123")
+	if err != nil {
+		return nil, err
+	}
+	users := make([]User, b.Users)
+	for i := range users {
+		users[i] = User{
+			Name:     fmt.Sprintf("bench-%d-%d", suffix, i),
+			Email:    fmt.Sprintf("bench-%d-%d@example.com", suffix, i),
+			Password: password,
+		}
+	}
+	if err := NewUserRepository(tx).CreateBatch(ctx, users, DefaultBatchSize); err != nil {
+		return nil, err
+	}
+
+	posts := make([]Post, 0, b.Users*b.PostsPerUser)
+	for _, u := range users {
+		for j := range b.PostsPerUser {
+			posts = append(posts, Post{Title: fmt.Sprintf("文章 %d", j+1), Content: "基准测试数据", UserID: u.ID})
+		}
+	}
+	if err := NewPostRepository(tx).CreateBatch(ctx, posts, DefaultBatchSize); err != nil {
+		return nil, err
+	}
+
+	comments := make([]Comment, 0, len(posts)*b.CommentsPerPost)
+	for i, p := range posts {
+		for j := range b.CommentsPerPost {
+			author := users[(i+j)%len(users)].ID
+			comments = append(comments, Comment{Content: fmt.Sprintf("评论 %d", j+1), Status: CommentApproved, PostID: p.ID, UserID: author})
+		}
+	}
+	if err := NewCommentRepository(tx).CreateBatch(ctx, comments, DefaultBatchSize); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids, nil
+}
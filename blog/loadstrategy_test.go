@@ -0,0 +1,90 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// newLoadDataset 在 db 上生成 b 描述的数据集, 与 RunLoadBenchmark 一样跳过钩子
+func newLoadDataset(tb testing.TB, db *gorm.DB, b LoadBenchmark) []uint {
+	tb.Helper()
+	ids, err := generateLoadDataset(context.Background(), db.Session(&gorm.Session{SkipHooks: true}), b)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return ids
+}
+
+// postIDs 把文章和评论展开为 "文章ID:评论ID,评论ID" 便于比较
+func postIDs(posts []Post) []string {
+	out := make([]string, len(posts))
+	for i, p := range posts {
+		out[i] = fmt.Sprint(p.ID, ":")
+		for j, c := range p.Comments {
+			if j > 0 {
+				out[i] += ","
+			}
+			out[i] += fmt.Sprint(c.ID)
+		}
+	}
+	return out
+}
+
+func TestLoadStrategiesAgree(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	ids := newLoadDataset(t, db, LoadBenchmark{Users: 3, PostsPerUser: 4, CommentsPerPost: 3})
+	// 没有评论的文章和未审核的评论
+	user := ids[0]
+	empty := &Post{Title: "没有评论", Content: "内容", UserID: user}
+	if err := db.Create(empty).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Comment{Content: "待审核", Status: CommentPending, PostID: empty.ID, UserID: ids[1]}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := LoadUserPosts(ctx, db, user, LoadPreload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 5 || len(want[0].Comments) != 3 || len(want[4].Comments) != 0 {
+		t.Fatalf("Preload 结果 = %v", postIDs(want))
+	}
+	for _, strategy := range LoadStrategies[1:] {
+		got, err := LoadUserPosts(ctx, db, user, strategy)
+		if err != nil {
+			t.Fatalf("%s: %v", strategy, err)
+		}
+		if !slices.Equal(postIDs(got), postIDs(want)) {
+			t.Errorf("%s 结果 = %v, 期望与 Preload 相同 %v", strategy, postIDs(got), postIDs(want))
+		}
+	}
+
+	if _, err := LoadUserPosts(ctx, db, user, "eager"); err == nil {
+		t.Error("不支持的加载方式没有返回错误")
+	}
+}
+
+// 各加载方式在不同文章数下的对比. 运行: go test -run=^$ -bench=LoadUserPosts ./blog
+// SQLite 没有网络往返, N+1 的劣势在 MySQL 上会更明显, 需要时用 task3 blog bench 在 MySQL 上实测
+func BenchmarkLoadUserPosts(b *testing.B) {
+	ctx := context.Background()
+	for _, posts := range []int{10, 100} {
+		db := newTestDB(b)
+		ids := newLoadDataset(b, db, LoadBenchmark{Users: 2, PostsPerUser: posts, CommentsPerPost: 10})
+		for _, strategy := range LoadStrategies {
+			b.Run(fmt.Sprintf("%s/文章%d", strategy, posts), func(b *testing.B) {
+				for b.Loop() {
+					if _, err := LoadUserPosts(ctx, db, ids[0], strategy); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package blog
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WithRowLock 用 SELECT ... FOR UPDATE 锁定 model 对应表中主键为 id 的行, 并把当前值加载进 model.
+// 必须在事务内调用 (GORM 钩子天然运行在创建/删除的事务中), 锁在事务提交或回滚时释放,
+// 同一行上的其他加锁读取和更新会排队等待, 保证 "读取 - 计算 - 写回" 不被并发打断.
+func WithRowLock(tx *gorm.DB, model any, id any) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(model, id).Error; err != nil {
+		return fmt.Errorf("锁定记录 %v 失败: %w", id, err)
+	}
+	return nil
+}
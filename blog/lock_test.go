@@ -0,0 +1,72 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// 多个 goroutine 同时发表文章和评论, 钩子维护的文章数、评论状态和评论统计正确.
+// SQLite 测试库只有一个连接, 事务实际按顺序执行, 不会出现行锁竞争 (WithRowLock 在这里不起作用),
+// 只检查顺序执行时的计数, 以及在 go test -race 下检查钩子、缓存等进程内状态的并发访问.
+// 并发事务下的行锁见集成测试 (integration_test.go 的 "并发创建文章和评论")
+func TestSerializedHooksKeepCounters(t *testing.T) {
+	db := newTestDB(t)
+	withUserCache(t, db)
+	author := newTestUser(t, db, "alice")
+	reader := newTestUser(t, db, "bob")
+	posts := NewPostRepository(db)
+	ctx := context.Background()
+
+	const n = 20
+	created := make([]Post, n)
+	run(t, n, func(i int) error {
+		created[i] = Post{UserID: author.ID, Title: fmt.Sprintf("文章 %d", i), Content: "内容"}
+		return posts.Create(ctx, &created[i])
+	})
+	assertArticleCount(t, db, author.ID, n)
+
+	target := created[0].ID
+	run(t, n, func(i int) error {
+		if _, err := NewUserRepository(db).GetByIDCached(ctx, author.ID); err != nil {
+			return err
+		}
+		return db.Create(&Comment{PostID: target, UserID: reader.ID, Content: fmt.Sprintf("评论 %d", i), Status: CommentApproved}).Error
+	})
+	assertCommentStatus(t, db, target, PostHasComments)
+	assertPostStats(t, db, target, n)
+
+	// 并发删除一半文章, 同时在另一半上评论
+	run(t, n, func(i int) error {
+		if i%2 == 0 {
+			return posts.Delete(ctx, created[i].ID)
+		}
+		return db.Create(&Comment{PostID: created[i].ID, UserID: reader.ID, Content: "评论", Status: CommentApproved}).Error
+	})
+	assertArticleCount(t, db, author.ID, n/2)
+
+	mismatches, err := CheckCounters(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range mismatches {
+		t.Errorf("计数不一致: %s", m)
+	}
+}
+
+// run 并发执行 fn(0) ... fn(n-1), 等待全部完成, 报告每个出错的调用
+func run(t *testing.T, n int, fn func(i int) error) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range n {
+		wg.Go(func() { errs[i] = fn(i) })
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("第 %d 个调用失败: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package blog
+
+import (
+	"context"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/redact"
+	"github.com/alexwang789/Base1_golang_task3/requestid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// requestLogger 在 GORM 日志前加上 ctx 中的请求 ID, SQL 日志以注释形式带上 request_id,
+// 同一 HTTP 请求执行的 SQL 可以与访问日志对应起来. SQL 日志中敏感列的参数由 redactor 遮盖
+type requestLogger struct {
+	logger.Interface
+	redactor *redact.Redactor
+}
+
+func (l requestLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return requestLogger{l.Interface.LogMode(level), l.redactor}
+}
+
+// ParamsFilter 实现 gorm.ParamsFilter, 在 SQL 写入日志前遮盖密码、邮箱等参数
+func (l requestLogger) ParamsFilter(ctx context.Context, sql string, params ...any) (string, []any) {
+	if f, ok := l.Interface.(gorm.ParamsFilter); ok {
+		sql, params = f.ParamsFilter(ctx, sql, params...)
+	}
+	return sql, l.redactor.Params(sql, params)
+}
+
+func (l requestLogger) Info(ctx context.Context, msg string, data ...any) {
+	l.Interface.Info(ctx, withRequestID(ctx, msg), data...)
+}
+
+func (l requestLogger) Warn(ctx context.Context, msg string, data ...any) {
+	l.Interface.Warn(ctx, withRequestID(ctx, msg), data...)
+}
+
+func (l requestLogger) Error(ctx context.Context, msg string, data ...any) {
+	l.Interface.Error(ctx, withRequestID(ctx, msg), data...)
+}
+
+func (l requestLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	id := requestid.From(ctx)
+	if id == "" {
+		l.Interface.Trace(ctx, begin, fc, err)
+		return
+	}
+	l.Interface.Trace(ctx, begin, func() (string, int64) {
+		sql, rows := fc()
+		return "/* request_id=" + id + " */ " + sql, rows
+	}, err)
+}
+
+func withRequestID(ctx context.Context, msg string) string {
+	if id := requestid.From(ctx); id != "" {
+		return "[" + id + "] " + msg
+	}
+	return msg
+}
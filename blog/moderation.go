@@ -0,0 +1,139 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// 评论审核状态. 新评论默认待审核, 只有通过审核的评论对外展示并计入文章的评论状态和统计
+const (
+	CommentPending  CommentStatus = "pending"
+	CommentApproved CommentStatus = "approved"
+	CommentRejected CommentStatus = "rejected"
+	CommentSpam     CommentStatus = "spam"
+)
+
+// CommentStatuses 全部审核状态
+var CommentStatuses = []CommentStatus{CommentPending, CommentApproved, CommentRejected, CommentSpam}
+
+// 文章的评论状态 (Post.CommentStatus), 存储为代码, 展示文字见 i18n 目录中的 comment_status.*
+const (
+	PostNoComments  PostCommentStatus = "none"
+	PostHasComments PostCommentStatus = "has_comments"
+)
+
+// PostCommentStatuses 全部评论状态
+var PostCommentStatuses = []PostCommentStatus{PostNoComments, PostHasComments}
+
+// postCommentStatus 有 approved 条已通过审核的评论的文章应有的评论状态
+func postCommentStatus(approved int64) PostCommentStatus {
+	if approved == 0 {
+		return PostNoComments
+	}
+	return PostHasComments
+}
+
+var (
+	// ErrForbidden 操作者没有管理员权限
+	ErrForbidden = errors.New("没有权限")
+	// ErrCommentNotFound 评论不存在
+	ErrCommentNotFound = errors.New("评论不存在")
+)
+
+// approvedComments 只保留已通过审核的评论, 用于评论计数和统计; 对外的评论查询还需排除归档文章的评论, 见 PublicComments
+func approvedComments(tx *gorm.DB) *gorm.DB {
+	return tx.Where("comments.status = ?", CommentApproved)
+}
+
+// ApproveComment 审核通过评论, 仅管理员可操作
+func ApproveComment(ctx context.Context, db *gorm.DB, moderator *User, commentID uint) error {
+	return ModerateComment(ctx, db, moderator, commentID, CommentApproved)
+}
+
+// RejectComment 驳回评论, 仅管理员可操作
+func RejectComment(ctx context.Context, db *gorm.DB, moderator *User, commentID uint) error {
+	return ModerateComment(ctx, db, moderator, commentID, CommentRejected)
+}
+
+// ModerateComment 把评论改为 status (CommentStatuses 之一), 仅管理员可操作.
+// 评论进入或离开 "已通过" 时同步更新文章的评论状态和统计, 并发出或撤回评论通知
+func ModerateComment(ctx context.Context, db *gorm.DB, moderator *User, commentID uint, status CommentStatus) error {
+	if moderator == nil || !moderator.IsAdmin {
+		return ErrForbidden
+	}
+	if !status.Valid() {
+		return fmt.Errorf("不支持的审核状态 %q", status)
+	}
+
+	return transaction(ctx, db, func(tx *gorm.DB) error {
+		var comment Comment
+		err := lockComment(tx, &comment, commentID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCommentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if comment.Status == status {
+			return nil
+		}
+
+		previous := comment.Status // Update 会把新状态写回 comment
+		if err := tx.Model(&comment).Update("status", status).Error; err != nil {
+			return fmt.Errorf("更新评论审核状态失败: %w", err)
+		}
+		switch {
+		case status == CommentApproved:
+			if err := notifyCommentPublished(tx, &comment); err != nil {
+				return err
+			}
+		case previous == CommentApproved:
+			if err := removeCommentNotifications(tx, comment.ID); err != nil {
+				return err
+			}
+		default:
+			return nil // 对外可见的评论没有变化
+		}
+		return refreshPostComments(tx, comment.PostID)
+	})
+}
+
+// PendingComments 按创建顺序返回待审核的评论, 仅管理员可查看
+func PendingComments(ctx context.Context, db *gorm.DB, moderator *User, limit int) ([]Comment, error) {
+	if moderator == nil || !moderator.IsAdmin {
+		return nil, ErrForbidden
+	}
+	var comments []Comment
+	err := db.WithContext(ctx).Where("status = ?", CommentPending).Order("id").Limit(limit).Find(&comments).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询待审核评论失败: %w", err)
+	}
+	return comments, nil
+}
+
+// refreshPostComments 按已通过审核的评论重新计算文章的评论状态和统计
+func refreshPostComments(tx *gorm.DB, postID uint) error {
+	// 锁定文章行, 避免并发审核/删除评论时基于过期的评论数写入错误状态
+	if err := WithRowLock(tx, &Post{}, postID); err != nil {
+		return err
+	}
+
+	var commentCount int64
+	if err := tx.Model(&Comment{}).Scopes(approvedComments).Where("post_id = ?", postID).Count(&commentCount).Error; err != nil {
+		return err
+	}
+
+	newStatus := postCommentStatus(commentCount)
+	if err := tx.Model(&Post{}).Where("id = ?", postID).Update("comment_status", newStatus).Error; err != nil {
+		return err
+	}
+	if err := resetPostStats(tx, postID, commentCount); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ 文章 %d 的评论状态已更新为: %s\n", postID, newStatus)
+	return nil
+}
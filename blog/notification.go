@@ -0,0 +1,224 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 通知类型
+const (
+	NotificationComment = "comment" // 有人评论了你的文章
+	NotificationReply   = "reply"   // 有人回复了你的评论
+	NotificationMention = "mention" // 有人在评论中 @ 了你
+	NotificationLike    = "like"    // 有人赞了你的文章
+)
+
+// Notification 站内通知. 同一事件对同一接收者只产生一条通知
+type Notification struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `gorm:"not null;uniqueIndex:idx_notifications_event,priority:1;index:idx_notifications_unread,priority:1"` // 接收者
+	Kind      string     `gorm:"size:20;not null;uniqueIndex:idx_notifications_event,priority:2"`
+	PostID    uint       `gorm:"not null;uniqueIndex:idx_notifications_event,priority:3"`
+	CommentID uint       `gorm:"not null;default:0;uniqueIndex:idx_notifications_event,priority:4;index"` // 点赞通知为 0
+	ActorID   uint       `gorm:"not null;uniqueIndex:idx_notifications_event,priority:5"`                 // 触发通知的用户
+	ReadAt    *time.Time `gorm:"index:idx_notifications_unread,priority:2"`                               // 未读时为 NULL
+	CreatedAt time.Time
+}
+
+// 匹配 @用户名: @ 前不能紧跟字母、数字或点 (排除邮箱), 用户名到空白或标点为止
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.])@([\p{L}\p{N}_]{1,100})`)
+
+// parseMentions 按出现顺序返回评论中提及的用户名, 已去重
+func parseMentions(content string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// dispatcher 收集一个事件要发出的通知: 每个接收者只保留最先加入的 (最具体的) 一条,
+// 触发者自己不会收到通知
+type dispatcher struct {
+	actorID       uint
+	postID        uint
+	commentID     uint
+	notifications []Notification
+	notified      map[uint]bool
+}
+
+func newDispatcher(actorID, postID, commentID uint) *dispatcher {
+	return &dispatcher{actorID: actorID, postID: postID, commentID: commentID, notified: map[uint]bool{}}
+}
+
+func (d *dispatcher) add(kind string, userIDs ...uint) {
+	for _, id := range userIDs {
+		if id == 0 || id == d.actorID || d.notified[id] {
+			continue
+		}
+		d.notified[id] = true
+		d.notifications = append(d.notifications, Notification{
+			UserID:    id,
+			Kind:      kind,
+			PostID:    d.postID,
+			CommentID: d.commentID,
+			ActorID:   d.actorID,
+		})
+	}
+}
+
+// send 写入通知, 已存在的 (如评论被重新审核通过) 忽略
+func (d *dispatcher) send(tx *gorm.DB) error {
+	if len(d.notifications) == 0 {
+		return nil
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&d.notifications).Error; err != nil {
+		return fmt.Errorf("写入通知失败: %w", err)
+	}
+	return nil
+}
+
+// notifyCommentPublished 评论对外可见时写入发件箱事件, 并在提交后通知被回复的评论作者、被提及的用户和文章作者.
+// 同一用户同时符合多项时按 回复 > 提及 > 评论 只通知一次
+func notifyCommentPublished(tx *gorm.DB, c *Comment) error {
+	if err := addCommentPublished(tx, c); err != nil {
+		return err
+	}
+	comment := *c
+	return afterCommit(tx, "评论通知", func(db *gorm.DB) error {
+		return dispatchCommentNotifications(db, &comment)
+	})
+}
+
+func dispatchCommentNotifications(tx *gorm.DB, c *Comment) error {
+	// 异步执行时评论可能已被驳回或删除
+	var n int64
+	if err := tx.Model(&Comment{}).Scopes(approvedComments).Where("id = ?", c.ID).Count(&n).Error; err != nil {
+		return fmt.Errorf("查询评论失败: %w", err)
+	}
+	if n == 0 {
+		return nil
+	}
+
+	d := newDispatcher(c.UserID, c.PostID, c.ID)
+
+	if c.ParentID != nil {
+		var parentAuthor uint
+		if err := tx.Model(&Comment{}).Where("id = ?", *c.ParentID).Pluck("user_id", &parentAuthor).Error; err != nil {
+			return fmt.Errorf("查询被回复的评论失败: %w", err)
+		}
+		d.add(NotificationReply, parentAuthor)
+	}
+
+	if names := parseMentions(c.Content); len(names) > 0 {
+		var userIDs []uint
+		if err := tx.Model(&User{}).Where("name IN ?", names).Pluck("id", &userIDs).Error; err != nil {
+			return fmt.Errorf("查询被提及的用户失败: %w", err)
+		}
+		d.add(NotificationMention, userIDs...)
+	}
+
+	var postAuthor uint
+	if err := tx.Model(&Post{}).Where("id = ?", c.PostID).Pluck("user_id", &postAuthor).Error; err != nil {
+		return fmt.Errorf("查询文章作者失败: %w", err)
+	}
+	d.add(NotificationComment, postAuthor)
+	return d.send(tx)
+}
+
+// removeCommentNotifications 评论不再对外可见 (被驳回或删除) 时撤回它产生的通知
+func removeCommentNotifications(tx *gorm.DB, commentID uint) error {
+	return afterCommit(tx, "撤回评论通知", func(db *gorm.DB) error {
+		if err := db.Where("comment_id = ?", commentID).Delete(&Notification{}).Error; err != nil {
+			return fmt.Errorf("撤回评论通知失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// notifyPostLiked 通知文章作者文章被赞
+func notifyPostLiked(tx *gorm.DB, like *PostLike) error {
+	userID, postID := like.UserID, like.PostID
+	return afterCommit(tx, "点赞通知", func(db *gorm.DB) error {
+		// 异步执行时可能已取消点赞
+		var postAuthor uint
+		err := db.Model(&Post{}).
+			Joins("JOIN post_likes ON post_likes.post_id = posts.id AND post_likes.user_id = ?", userID).
+			Where("posts.id = ?", postID).
+			Pluck("posts.user_id", &postAuthor).Error
+		if err != nil {
+			return fmt.Errorf("查询文章作者失败: %w", err)
+		}
+		d := newDispatcher(userID, postID, 0)
+		d.add(NotificationLike, postAuthor)
+		return d.send(db)
+	})
+}
+
+// removeLikeNotification 取消点赞时撤回点赞通知
+func removeLikeNotification(tx *gorm.DB, like *PostLike) error {
+	userID, postID := like.UserID, like.PostID
+	return afterCommit(tx, "撤回点赞通知", func(db *gorm.DB) error {
+		err := db.Where("kind = ? AND post_id = ? AND actor_id = ?", NotificationLike, postID, userID).
+			Delete(&Notification{}).Error
+		if err != nil {
+			return fmt.Errorf("撤回点赞通知失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// UnreadNotifications 按时间倒序返回用户的未读通知, 最多 limit 条
+func UnreadNotifications(ctx context.Context, db *gorm.DB, userID uint, limit int) ([]Notification, error) {
+	var notifications []Notification
+	err := db.WithContext(ctx).Scopes(scopes.ByUser(userID)).Where("read_at IS NULL").
+		Order("id DESC").Limit(limit).Find(&notifications).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询未读通知失败: %w", err)
+	}
+	return notifications, nil
+}
+
+// UnreadNotificationCounts 返回用户各类型未读通知的数量, 没有未读的类型不出现在结果中
+func UnreadNotificationCounts(ctx context.Context, db *gorm.DB, userID uint) (map[string]int64, error) {
+	var rows []struct {
+		Kind  string
+		Count int64
+	}
+	err := db.WithContext(ctx).Model(&Notification{}).
+		Select("kind, COUNT(*) AS count").
+		Scopes(scopes.ByUser(userID)).Where("read_at IS NULL").
+		Group("kind").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计未读通知失败: %w", err)
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		counts[r.Kind] = r.Count
+	}
+	return counts, nil
+}
+
+// MarkNotificationsRead 把用户的通知标记为已读, ids 为空时标记全部未读通知
+func MarkNotificationsRead(ctx context.Context, db *gorm.DB, userID uint, ids ...uint) error {
+	q := db.WithContext(ctx).Model(&Notification{}).Scopes(scopes.ByUser(userID)).Where("read_at IS NULL")
+	if len(ids) > 0 {
+		q = q.Where("id IN ?", ids)
+	}
+	if err := q.Update("read_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("标记通知已读失败: %w", err)
+	}
+	return nil
+}
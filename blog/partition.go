@@ -0,0 +1,106 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/partition"
+	"gorm.io/gorm"
+)
+
+// PartitionedTables 可以按 created_at 每月一个分区的表 (见 partition 包), 数据量随时间增长且按时间查询和清理.
+// 分区是可选的, 由 PartitionTable 显式开启; 评论分表后逐张分表分区
+var PartitionedTables = []string{"posts", "comments", "audit_logs"}
+
+// 分区列
+const partitionColumn = "created_at"
+
+// partitionTargets 逻辑表 table 对应的物理表
+func partitionTargets(table string) ([]string, error) {
+	if !slices.Contains(PartitionedTables, table) {
+		return nil, fmt.Errorf("%s 不支持按月分区, 可选: %v", table, PartitionedTables)
+	}
+	if table == commentTable.Name {
+		return commentTable.Names(), nil
+	}
+	return []string{table}, nil
+}
+
+// PartitionTable 把 table (PartitionedTables 之一) 改为按月分区, 并建好之后 ahead 个月的分区; 已经分区时什么都不做.
+// 文章和评论分区后不再有外键, 之后的迁移也不再创建外键. 分区表不支持 FULLTEXT 索引, 文章分区前删除它,
+// 之后数据库检索改用 LIKE (见 SearchPostsFullText)
+func PartitionTable(ctx context.Context, db *gorm.DB, table string, ahead int) error {
+	targets, err := partitionTargets(table)
+	if err != nil {
+		return err
+	}
+	if table == "posts" {
+		if err := dropPostFullTextIndex(db.WithContext(ctx)); err != nil {
+			return err
+		}
+	}
+	for _, t := range targets {
+		if err := partition.Enable(ctx, db, t, partitionColumn, time.Now(), ahead); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListPartitions 各个已分区的表的分区, 未分区的表不列出
+func ListPartitions(ctx context.Context, db *gorm.DB) (map[string][]partition.Partition, error) {
+	result := make(map[string][]partition.Partition)
+	for _, table := range PartitionedTables {
+		targets, _ := partitionTargets(table)
+		for _, t := range targets {
+			parts, err := partition.List(ctx, db, t)
+			if err != nil {
+				return nil, err
+			}
+			if len(parts) > 0 {
+				result[t] = parts
+			}
+		}
+	}
+	return result, nil
+}
+
+// MaintainPartitions 为已分区的表建好之后 cfg.Ahead 个月的分区, 并按 cfg.Retention 删除过期的分区, 见 partition.Maintain
+func MaintainPartitions(ctx context.Context, db *gorm.DB, cfg config.Partitioning) error {
+	now := time.Now()
+	for _, table := range PartitionedTables {
+		targets, _ := partitionTargets(table)
+		for _, t := range targets {
+			added, dropped, err := partition.Maintain(ctx, db, t, now, cfg.Ahead, cfg.Retention[table])
+			if len(added) > 0 {
+				log.Printf("%s 新建分区 %v", t, added)
+			}
+			if len(dropped) > 0 {
+				log.Printf("%s 删除过期分区 %v", t, dropped)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// disableForeignKeysIfPartitioned 文章或评论已分区时关闭迁移创建外键. 只影响迁移, 对同一连接之后的迁移都生效
+func disableForeignKeysIfPartitioned(db *gorm.DB) error {
+	for _, t := range append([]string{"posts"}, commentTable.Names()...) {
+		parts, err := partition.List(db.Statement.Context, db, t)
+		if err != nil {
+			return err
+		}
+		if len(parts) > 0 {
+			db.Config.DisableForeignKeyConstraintWhenMigrating = true
+			return nil
+		}
+	}
+	return nil
+}
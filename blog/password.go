@@ -0,0 +1,83 @@
+package blog
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 密码以 bcrypt 哈希保存: User.BeforeSave 在创建和保存时哈希尚未哈希的密码, 登录时用 CheckPassword 比对.
+// 哈希上线前保存的明文密码由 Migrate 转换 (见 hashPlaintextPasswords)
+
+// 密码的最大长度, bcrypt 只接受 72 字节以内的输入
+const maxPasswordBytes = 72
+
+// passwordHashBatch 迁移时每批哈希的用户数
+const passwordHashBatch = 100
+
+// HashPassword 返回密码的 bcrypt 哈希. 批量生成用户时可以预先哈希一次供全部用户使用,
+// 已是哈希的密码保存时不会再次哈希
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("哈希密码失败: %w", err)
+	}
+	return string(hash), nil
+}
+
+// isPasswordHash s 是否为 bcrypt 哈希
+func isPasswordHash(s string) bool {
+	_, err := bcrypt.Cost([]byte(s))
+	return err == nil
+}
+
+// CheckPassword 比对明文密码与用户保存的哈希, 保存的不是哈希 (如 Migrate 尚未转换的明文) 时总是失败.
+// 注销的用户 (见 EraseUser) 保存的是随机密码的哈希, 随机密码不保留, 无法再登录
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
+}
+
+// hashPassword 保存用户前哈希明文密码, 空密码 (如只更新部分列的空模型) 和已哈希的密码不变
+func (u *User) hashPassword() error {
+	if u.Password == "" || isPasswordHash(u.Password) {
+		return nil
+	}
+	hash, err := HashPassword(u.Password)
+	if err != nil {
+		return err
+	}
+	u.Password = hash
+	return nil
+}
+
+// hashPlaintextPasswords 把哈希上线前保存的明文密码替换为哈希, 可重复执行. 逐个用户计算哈希, 用户多时较慢
+func hashPlaintextPasswords(db *gorm.DB) error {
+	var lastID uint
+	for {
+		var users []User
+		err := db.Select("id", "password").Where("id > ? AND password NOT LIKE ?", lastID, "$2_$%").
+			Order("id").Limit(passwordHashBatch).Find(&users).Error
+		if err != nil {
+			return fmt.Errorf("查询明文密码失败: %w", err)
+		}
+		if len(users) == 0 {
+			return nil
+		}
+		for _, u := range users {
+			lastID = u.ID
+			if isPasswordHash(u.Password) {
+				continue
+			}
+			hash, err := HashPassword(u.Password)
+			if err != nil {
+				return fmt.Errorf("用户 %d: %w", u.ID, err)
+			}
+			// 只改密码列, 不执行钩子也不修改 updated_at
+			err = db.Model(&User{}).Where("id = ? AND password = ?", u.ID, u.Password).UpdateColumn("password", hash).Error
+			if err != nil {
+				return fmt.Errorf("哈希用户 %d 的密码失败: %w", u.ID, err)
+			}
+		}
+	}
+}
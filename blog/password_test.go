@@ -0,0 +1,146 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alexwang789/Base1_golang_task3/fixtures"
+	"github.com/alexwang789/Base1_golang_task3/validate"
+	"gorm.io/gorm"
+)
+
+func TestPasswordHashedOnSave(t *testing.T) {
+	db := newTestDB(t)
+	users := NewUserRepository(db)
+	ctx := context.Background()
+
+	user := User{Name: "alice", Email: "alice@example.com", Password: "Passw0rd!"}
+	if err := users.Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	var stored User
+	if err := db.Take(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !isPasswordHash(stored.Password) {
+		t.Fatalf("保存的密码不是 bcrypt 哈希: %q", stored.Password)
+	}
+
+	// 已哈希的密码再次保存时不变
+	hash := stored.Password
+	stored.Name = "alice2"
+	if err := db.Save(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Take(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password != hash {
+		t.Errorf("保存已哈希的密码时再次哈希: %q -> %q", hash, stored.Password)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"正确的密码", "Passw0rd!", true},
+		{"错误的密码", "Passw0rd?", false},
+		{"空密码", "", false},
+		{"哈希本身", hash, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stored.CheckPassword(tt.password); got != tt.want {
+				t.Errorf("CheckPassword(%q) = %v, 期望 %v", tt.password, got, tt.want)
+			}
+		})
+	}
+
+	// 库中的密码不是哈希时不能用它登录
+	if (&User{Password: "plain123"}).CheckPassword("plain123") {
+		t.Error("没有哈希的密码通过了比对")
+	}
+}
+
+func TestPasswordTooLong(t *testing.T) {
+	user := User{Name: "alice", Email: "alice@example.com", Password: strings.Repeat("a1", maxPasswordBytes/2+1)}
+	var errs validate.Errors
+	if err := user.Validate(); !errors.As(err, &errs) || errs["password"] == "" {
+		t.Errorf("Validate() = %v, 期望密码过长的错误", err)
+	}
+}
+
+func TestHashPlaintextPasswords(t *testing.T) {
+	db := newTestDB(t)
+	raw := db.Session(&gorm.Session{SkipHooks: true})
+	// 哈希上线前的用户
+	legacy := []User{
+		{ID: 1, Name: "alice", Email: "alice@example.com", Password: "alice123"},
+		{ID: 2, Name: "bob", Email: "bob@example.com", Password: "bob123"},
+	}
+	if err := raw.Create(&legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+	hashed := newTestUser(t, db, "carol")
+
+	for range 2 { // 可重复执行
+		if err := hashPlaintextPasswords(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, u := range legacy {
+		var stored User
+		if err := db.Take(&stored, u.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !stored.CheckPassword(u.Password) {
+			t.Errorf("%s 的密码 %q 迁移后无法通过比对: %q", u.Name, u.Password, stored.Password)
+		}
+	}
+	var stored User
+	if err := db.Take(&stored, hashed.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !stored.CheckPassword("Passw0rd!") {
+		t.Error("已哈希的密码被再次哈希")
+	}
+}
+
+// fixture 直接写表不经过钩子, 示例用户的密码须在文件中以哈希给出才能登录
+func TestFixtureUsersCanLogIn(t *testing.T) {
+	db := newTestDB(t)
+	set, err := fixtures.Load("../fixtures/data/blog.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.Insert(context.Background(), sqlDB); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+	}{
+		{"张三", "pass123"},
+		{"李四", "pass456"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var user User
+			if err := db.Where("name = ?", tt.name).Take(&user).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !user.CheckPassword(tt.password) {
+				t.Errorf("%s 无法用密码 %q 登录: %q", tt.name, tt.password, user.Password)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostStat 文章的互动统计, 由评论钩子和点赞增量维护, 并由 jobs 定期从 comments、post_likes 表全量重建以纠正偏差
+// (如直接写库的 fixture、删除文章后残留的统计). 排行查询只读这张表, 不再对评论表 GROUP BY
+type PostStat struct {
+	PostID          uint       `gorm:"primaryKey;index:idx_post_stats_ranking,priority:2"`
+	CommentCount    int64      `gorm:"not null;default:0;index:idx_post_stats_ranking,priority:1"`
+	LikeCount       int64      `gorm:"not null;default:0"`
+	LastCommentedAt *time.Time // 没有评论时为 NULL
+	UpdatedAt       time.Time
+}
+
+// 新评论写入后累加评论数, 统计行不存在时创建
+func incrementPostStats(tx *gorm.DB, c *Comment) error {
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "post_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"comment_count":     gorm.Expr("comment_count + 1"),
+			"last_commented_at": c.CreatedAt,
+			"updated_at":        time.Now(),
+		}),
+	}).Create(&PostStat{PostID: c.PostID, CommentCount: 1, LastCommentedAt: &c.CreatedAt}).Error
+	if err != nil {
+		return fmt.Errorf("更新文章统计失败: %w", err)
+	}
+	return nil
+}
+
+// 点赞或取消点赞后调整点赞数, 统计行不存在时创建
+func addPostLikes(tx *gorm.DB, postID uint, delta int64) error {
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "post_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"like_count": gorm.Expr("GREATEST(like_count + ?, 0)", delta),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&PostStat{PostID: postID, LikeCount: max(delta, 0)}).Error
+	if err != nil {
+		return fmt.Errorf("更新文章统计失败: %w", err)
+	}
+	return nil
+}
+
+// 评论删除或审核状态变化后按已通过审核的评论写入评论数和最后评论时间
+func resetPostStats(tx *gorm.DB, postID uint, commentCount int64) error {
+	var last *time.Time
+	if commentCount > 0 {
+		if err := tx.Model(&Comment{}).Scopes(approvedComments).Where("post_id = ?", postID).Select("MAX(created_at)").Scan(&last).Error; err != nil {
+			return fmt.Errorf("查询最后评论时间失败: %w", err)
+		}
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"comment_count", "last_commented_at", "updated_at"}),
+	}).Create(&PostStat{PostID: postID, CommentCount: commentCount, LastCommentedAt: last}).Error
+	if err != nil {
+		return fmt.Errorf("更新文章统计失败: %w", err)
+	}
+	return nil
+}
+
+// RebuildPostStats 从 comments 表中已通过审核的评论和 post_likes 表重新计算全部发布过的文章 (含已归档) 的统计,
+// 并删除已不存在或尚未发布的文章的统计. 已归档的文章保留统计, 恢复后排行照旧, 排行查询按 PublicOnly 过滤
+func RebuildPostStats(ctx context.Context, db *gorm.DB) error {
+	likes := db.Model(&PostLike{}).Select("COUNT(*)").Where("post_likes.post_id = posts.id")
+	var stats []PostStat
+	err := db.WithContext(ctx).Model(&Post{}).
+		Select("posts.id AS post_id, COUNT(comments.id) AS comment_count, MAX(comments.created_at) AS last_commented_at, (?) AS like_count", likes).
+		Joins("LEFT JOIN "+commentTable.From("")+" ON comments.post_id = posts.id AND comments.status = ?", CommentApproved).
+		Where("posts.status <> ?", PostScheduled).
+		Group("posts.id").
+		Scan(&stats).Error
+	if err != nil {
+		return fmt.Errorf("统计文章评论失败: %w", err)
+	}
+
+	now := time.Now()
+	for i := range stats {
+		stats[i].UpdatedAt = now
+	}
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id NOT IN (?)", tx.Model(&Post{}).Select("id").Where("status <> ?", PostScheduled)).Delete(&PostStat{}).Error; err != nil {
+			return err
+		}
+		if len(stats) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "post_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"comment_count", "like_count", "last_commented_at", "updated_at"}),
+		}).CreateInBatches(&stats, DefaultBatchSize).Error
+	})
+	if err != nil {
+		return fmt.Errorf("写入文章统计失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"github.com/alexwang789/Base1_golang_task3/validate"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Profile 用户资料, 与 User 一对一. 用户第一次保存资料时创建, 没有资料的用户各字段视为空
+type Profile struct {
+	UserID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	Bio       string `gorm:"size:500;not null;default:''"` // 个人简介
+	AvatarURL string `gorm:"size:500;not null;default:''"`
+	Website   string `gorm:"size:255;not null;default:''"`
+	Location  string `gorm:"size:100;not null;default:''"`
+	Version   uint   `gorm:"not null;default:1"` // 修订号, 从 1 开始, 每次保存加 1
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Validate 校验资料各字段的长度和链接格式, 失败时返回 validate.Errors
+func (p *Profile) Validate() error {
+	errs := validate.Errors{}
+	errs.Check(validate.MaxLen(p.Bio, 500), "bio", "简介不能超过 500 个字符")
+	errs.Check(p.AvatarURL == "" || validate.HTTPURL(p.AvatarURL), "avatar_url", "头像地址应为 http 或 https 链接")
+	errs.Check(validate.MaxLen(p.AvatarURL, 500), "avatar_url", "头像地址不能超过 500 个字符")
+	errs.Check(p.Website == "" || validate.HTTPURL(p.Website), "website", "个人网站应为 http 或 https 链接")
+	errs.Check(validate.MaxLen(p.Website, 255), "website", "个人网站不能超过 255 个字符")
+	errs.Check(validate.MaxLen(p.Location, 100), "location", "所在地不能超过 100 个字符")
+	return errs.Err()
+}
+
+// Profile 钩子函数 - 保存资料后使用户缓存失效, 缓存的用户带有资料
+func (p *Profile) AfterSave(tx *gorm.DB) error {
+	return invalidateUserCacheAfterCommit(tx, p.UserID)
+}
+
+// GetProfile 查询用户资料, 用户还没有保存过资料时返回只有 UserID 的空资料
+func GetProfile(ctx context.Context, db *gorm.DB, userID uint) (*Profile, error) {
+	var profiles []Profile
+	if err := db.WithContext(ctx).Scopes(scopes.ByUser(userID)).Limit(1).Find(&profiles).Error; err != nil {
+		return nil, fmt.Errorf("查询用户资料失败: %w", err)
+	}
+	if len(profiles) == 0 {
+		return &Profile{UserID: userID}, nil
+	}
+	return &profiles[0], nil
+}
+
+// SaveProfile 校验并整体替换用户资料, 不存在时创建. 数据不合法时返回 validate.Errors.
+// precondition 不为 nil 时在锁定当前资料后检查客户端读取的修订号 (如 HTTP 的 If-Match), 不满足时返回 ErrModified;
+// 还没有保存过资料时以只有 UserID 的空资料 (修订号为 0) 检查
+func SaveProfile(ctx context.Context, db *gorm.DB, profile *Profile, precondition func(current *Profile) bool) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current Profile
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(scopes.ByUser(profile.UserID)).Take(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if precondition != nil && !precondition(&Profile{UserID: profile.UserID}) {
+				return ErrModified
+			}
+			profile.Version = 1
+			if err := tx.Create(profile).Error; err != nil {
+				return fmt.Errorf("创建用户资料失败: %w", err)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("查询用户资料失败: %w", err)
+		}
+		if precondition != nil && !precondition(&current) {
+			return ErrModified
+		}
+
+		// 显式选择列, 清空字段 (零值) 也会写入
+		profile.Version = current.Version + 1
+		err = tx.Model(&current).Select("bio", "avatar_url", "website", "location", "version").Updates(profile).Error
+		if err != nil {
+			return fmt.Errorf("更新用户资料失败: %w", err)
+		}
+		if err := tx.Scopes(scopes.ByUser(profile.UserID)).Take(profile).Error; err != nil {
+			return fmt.Errorf("查询用户资料失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// LoadAuthors 为文章批量加载作者及其资料 (post.User 和 post.User.Profile), 列表中同一作者只查询一次
+func LoadAuthors(ctx context.Context, db *gorm.DB, posts []Post) error {
+	return loadAuthors(ctx, db, posts, func(p *Post) (uint, *User) { return p.UserID, &p.User })
+}
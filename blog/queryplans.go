@@ -0,0 +1,55 @@
+package blog
+
+import (
+	"math"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/queryplan"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"gorm.io/gorm"
+)
+
+// Indexes 博客库的查询依赖的复合索引, 由模型的 index 标签在 Migrate 时创建, 启动时检查是否缺失
+var Indexes = []queryplan.Index{
+	{Table: "comments", Name: "idx_comments_post_created", Columns: []string{"post_id", "created_at"}},
+	{Table: "posts", Name: "idx_posts_user_created", Columns: []string{"user_id", "created_at"}},
+}
+
+// QueryPlans 博客库中受执行计划检查保护的查询, 由查询构造器生成的语句用 db 渲染为 SQL
+func QueryPlans(db *gorm.DB) []queryplan.Query {
+	mostCommented := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return mostCommentedQuery(tx, nil, 10).Scan(&[]PostCommentCount{})
+	})
+	trendingScores := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Raw(trendingScoresSQL(), trendingScoresArgs(time.Now())).Scan(&[]TrendingScore{})
+	})
+	return []queryplan.Query{
+		{
+			Name: "most_commented_posts",
+			SQL:  mostCommented,
+			// 按 idx_post_stats_ranking 顺序读取, 不再扫描评论表
+		},
+		{Name: "discover_post", SQL: sqlDiscoverPost, Args: []any{tenant.Default, 0.5, PostPublished}},
+		{
+			Name: "feed",
+			SQL:  sqlFeed,
+			Args: []any{uint64(math.MaxInt64), PostPublished, DefaultFeedSize, 1, DefaultFeedSize},
+			// LATERAL 子查询的结果按关注的作者逐个物化, 每个最多一页
+			AllowFullScan: []string{"<derived2>"},
+		},
+		{
+			Name: "most_viewed_posts",
+			SQL:  sqlMostViewedPosts,
+			Args: []any{tenant.Default, time.Now().Add(-24 * time.Hour).Truncate(time.Hour), 10, PostPublished},
+			// 窗口内前 n 篇的聚合结果, 至多 n 行
+			AllowFullScan: []string{"<derived2>"},
+		},
+		{
+			Name: "trending_scores",
+			SQL:  trendingScores,
+			// UNION 的结果在内存中聚合, 各分支按时间范围走索引
+			AllowFullScan: []string{"<derived2>"},
+		},
+		{Name: "trending", SQL: sqlTrending, Args: []any{tenant.Default, PostPublished, 20}},
+	}
+}
@@ -0,0 +1,165 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"github.com/alexwang789/Base1_golang_task3/tenantdb"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReadingProgress 用户在某篇文章上的阅读进度, 用于跨设备 "继续阅读"
+type ReadingProgress struct {
+	UserID    uint      `gorm:"primaryKey"`
+	PostID    uint      `gorm:"primaryKey;index"`
+	Percent   uint8     `gorm:"not null"` // 0 ~ 100
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+type progressKey struct {
+	db             uint // 写入的库, 见 tenantdb.Target
+	userID, postID uint
+}
+
+// ProgressBuffer 对阅读进度做写合并: 客户端滚动时会频繁上报, 同一篇文章只保留最新值,
+// 每隔 interval 批量 upsert 一次. 读取时先查缓冲区, 保证用户能读到自己刚上报的进度.
+type ProgressBuffer struct {
+	db       *gorm.DB
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[progressKey]ReadingProgress
+}
+
+// NewProgressBuffer 创建阅读进度缓冲区, 需要调用 Run 定期写入
+func NewProgressBuffer(db *gorm.DB, interval time.Duration) *ProgressBuffer {
+	return &ProgressBuffer{
+		db:       db,
+		interval: interval,
+		pending:  make(map[progressKey]ReadingProgress),
+	}
+}
+
+// Set 记录最新进度, 等待下次刷新写入 ctx 对应的库
+func (b *ProgressBuffer) Set(ctx context.Context, userID, postID uint, percent uint8) ReadingProgress {
+	p := ReadingProgress{UserID: userID, PostID: postID, Percent: percent, UpdatedAt: time.Now()}
+	b.mu.Lock()
+	b.pending[progressKey{tenantdb.Target(ctx), userID, postID}] = p
+	b.mu.Unlock()
+	return p
+}
+
+// Get 查询进度, 缓冲区中的值优先
+func (b *ProgressBuffer) Get(ctx context.Context, userID, postID uint) (ReadingProgress, bool, error) {
+	b.mu.Lock()
+	p, ok := b.pending[progressKey{tenantdb.Target(ctx), userID, postID}]
+	b.mu.Unlock()
+	if ok {
+		return p, true, nil
+	}
+
+	var rows []ReadingProgress
+	err := b.db.WithContext(ctx).Scopes(scopes.ByUser(userID)).Where("post_id = ?", postID).Limit(1).Find(&rows).Error
+	if err != nil {
+		return ReadingProgress{}, false, fmt.Errorf("查询阅读进度失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return ReadingProgress{}, false, nil
+	}
+	return rows[0], true, nil
+}
+
+// List 查询用户的全部进度, 最近阅读的在前
+func (b *ProgressBuffer) List(ctx context.Context, userID uint) ([]ReadingProgress, error) {
+	var rows []ReadingProgress
+	if err := b.db.WithContext(ctx).Scopes(scopes.ByUser(userID)).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("查询阅读进度失败: %w", err)
+	}
+
+	// 用缓冲区中尚未写入的值覆盖
+	merged := make(map[uint]ReadingProgress, len(rows))
+	for _, p := range rows {
+		merged[p.PostID] = p
+	}
+	target := tenantdb.Target(ctx)
+	b.mu.Lock()
+	for key, p := range b.pending {
+		if key.db == target && key.userID == userID {
+			merged[key.postID] = p
+		}
+	}
+	b.mu.Unlock()
+
+	list := make([]ReadingProgress, 0, len(merged))
+	for _, p := range merged {
+		list = append(list, p)
+	}
+	slices.SortFunc(list, func(a, b ReadingProgress) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return list, nil
+}
+
+// Flush 把缓冲区中的进度按库批量写入, 失败的放回缓冲区等待下次重试
+func (b *ProgressBuffer) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	if len(b.pending) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	batches := make(map[uint][]ReadingProgress)
+	for key, p := range b.pending {
+		batches[key.db] = append(batches[key.db], p)
+	}
+	b.pending = make(map[progressKey]ReadingProgress)
+	b.mu.Unlock()
+
+	var errs []error
+	for target, batch := range batches {
+		err := b.db.WithContext(tenantdb.Route(ctx, target)).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "post_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"percent", "updated_at"}),
+		}).CreateInBatches(&batch, DefaultBatchSize).Error
+		if err == nil {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("写入阅读进度失败: %w", err))
+
+		// 放回时不覆盖刷新期间收到的更新值
+		b.mu.Lock()
+		for _, p := range batch {
+			key := progressKey{target, p.UserID, p.PostID}
+			if _, newer := b.pending[key]; !newer {
+				b.pending[key] = p
+			}
+		}
+		b.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Run 定期刷新直到 ctx 取消, 退出前做最后一次刷新
+func (b *ProgressBuffer) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := b.Flush(context.WithoutCancel(ctx)); err != nil {
+				log.Print(err)
+			}
+			return
+		case <-ticker.C:
+			if err := b.Flush(ctx); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
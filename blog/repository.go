@@ -0,0 +1,339 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/collate"
+	"github.com/alexwang789/Base1_golang_task3/filterdsl"
+	"github.com/alexwang789/Base1_golang_task3/repository"
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"github.com/alexwang789/Base1_golang_task3/usercache"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUserNotFound 用户不存在
+var ErrUserNotFound = errors.New("用户不存在")
+
+// UserRepository 用户数据访问, 通用的增删改查见 repository.Repository
+type UserRepository struct {
+	*repository.Repository[User]
+}
+
+// NewUserRepository 创建用户仓储, 不存在时返回 ErrUserNotFound
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{newRepository[User](db, "用户", ErrUserNotFound)}
+}
+
+// newRepository 创建 blog 模型的通用仓储, 删除在 transaction 中执行, 钩子挂起的副作用在提交后执行
+func newRepository[T any](db *gorm.DB, name string, notFound error) *repository.Repository[T] {
+	return repository.New[T](db, repository.Options{Name: name, NotFound: notFound, Transaction: transaction})
+}
+
+// userCache 用户查询缓存, 由 EnableUserCache 初始化; 为 nil 时钩子跳过失效处理
+var userCache *usercache.Cache[userCacheKey, *User]
+
+// userCacheKey 用户缓存的键. 独立库的租户各自自增主键, 不同租户的用户 ID 可能相同
+type userCacheKey struct {
+	tenant uint
+	id     uint
+}
+
+func userCacheKeyOf(ctx context.Context, id uint) userCacheKey {
+	return userCacheKey{tenant: tenant.ID(ctx), id: id}
+}
+
+// EnableUserCache 启用用户查询缓存, 之后 GetByIDCached、LoadAuthors 等读取走缓存, 修改用户或资料的钩子使缓存失效.
+// 缓存的用户带有资料 (User.Profile)
+func EnableUserCache(db *gorm.DB) {
+	users := NewUserRepository(db)
+	userCache = usercache.New(func(ctx context.Context, key userCacheKey) (*User, error) {
+		return users.Get(ctx, key.id, preloadProfile)
+	}, usercache.Options{
+		Size: 1000,
+		TTL:  5 * time.Minute,
+	})
+}
+
+func preloadProfile(db *gorm.DB) *gorm.DB {
+	return db.Preload("Profile")
+}
+
+// GetByIDCached 同 GetByID, 启用了缓存 (见 EnableUserCache) 时走缓存, 结果可能滞后于数据库最多一个 TTL,
+// 需要读取最新数据 (如随后要修改用户) 时用 GetByID. 返回的是副本, 调用方可以修改顶层字段, 但不要修改 Profile 和 Posts
+func (r *UserRepository) GetByIDCached(ctx context.Context, id uint) (*User, error) {
+	if userCache == nil || tenant.IsAll(ctx) {
+		return r.GetByID(ctx, id)
+	}
+	user, err := userCache.Get(ctx, userCacheKeyOf(ctx, id))
+	if err != nil {
+		return nil, err
+	}
+	clone := *user
+	return &clone, nil
+}
+
+// cachedUsers 批量查询用户及其资料, 启用了缓存时只查询未命中的用户. 不存在的用户不出现在结果中
+func cachedUsers(ctx context.Context, db *gorm.DB, ids []uint) (map[uint]*User, error) {
+	load := func(ctx context.Context, ids []uint) (map[uint]*User, error) {
+		var users []User
+		if err := db.WithContext(ctx).Scopes(preloadProfile).Where("id IN ?", ids).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		byID := make(map[uint]*User, len(users))
+		for i := range users {
+			byID[users[i].ID] = &users[i]
+		}
+		return byID, nil
+	}
+	if userCache == nil || tenant.IsAll(ctx) {
+		return load(ctx, ids)
+	}
+
+	keys := make([]userCacheKey, len(ids))
+	for i, id := range ids {
+		keys[i] = userCacheKeyOf(ctx, id)
+	}
+	found, err := userCache.GetMany(ctx, keys, func(ctx context.Context, missing []userCacheKey) (map[userCacheKey]*User, error) {
+		missingIDs := make([]uint, len(missing))
+		for i, key := range missing {
+			missingIDs[i] = key.id
+		}
+		loaded, err := load(ctx, missingIDs)
+		if err != nil {
+			return nil, err
+		}
+		byKey := make(map[userCacheKey]*User, len(loaded))
+		for id, user := range loaded {
+			byKey[userCacheKeyOf(ctx, id)] = user
+		}
+		return byKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*User, len(found))
+	for key, user := range found {
+		byID[key.id] = user
+	}
+	return byID, nil
+}
+
+// 事务提交后使指定用户的缓存失效, 由修改用户数据的钩子调用
+func invalidateUserCacheAfterCommit(tx *gorm.DB, id uint) error {
+	return afterCommit(tx, "用户缓存失效", func(*gorm.DB) error {
+		invalidateUserCache(id)
+		return nil
+	})
+}
+
+// 使指定用户的缓存失效. 钩子不一定知道用户所属的租户 (如跨租户的迁移), 移除全部租户中该 ID 的条目
+func invalidateUserCache(id uint) {
+	if userCache != nil && id != 0 {
+		userCache.InvalidateFunc(func(key userCacheKey) bool { return key.id == id })
+	}
+}
+
+// DefaultBatchSize 批量插入时每条 INSERT 语句包含的默认行数
+const DefaultBatchSize = 500
+
+// CreateBatch 分批插入用户, batchSize <= 0 时使用 DefaultBatchSize; 插入后各元素的 ID 会被回填
+func (r *UserRepository) CreateBatch(ctx context.Context, users []User, batchSize int) error {
+	if err := validateAll(users); err != nil {
+		return err
+	}
+	if err := createInBatches(ctx, r.DB(), &users, len(users), batchSize); err != nil {
+		return fmt.Errorf("批量创建用户失败: %w", translateUserDuplicate(err))
+	}
+	return nil
+}
+
+// PostRepository 文章数据访问, 通用的增删改查见 repository.Repository. Delete 同时删除评论、点赞等从属数据 (见 Post.BeforeDelete)
+type PostRepository struct {
+	*repository.Repository[Post]
+}
+
+// NewPostRepository 创建文章仓储, 不存在时返回 ErrPostNotFound
+func NewPostRepository(db *gorm.DB) *PostRepository {
+	return &PostRepository{newRepository[Post](db, "文章", ErrPostNotFound)}
+}
+
+// ErrPostNotFound 文章不存在
+var ErrPostNotFound = errors.New("文章不存在")
+
+// newestFirst 新文章在前
+func newestFirst(tx *gorm.DB) *gorm.DB {
+	return tx.Order(clause.OrderByColumn{Column: clause.PrimaryColumn, Desc: true})
+}
+
+// ListByUser 按发布时间倒序返回用户对外可见的文章. afterID 不为 0 时从该文章之后继续 (keyset 翻页), n <= 0 时返回全部.
+// ss 为追加的查询条件, 如只查询部分列 (见 fieldsel)
+func (r *PostRepository) ListByUser(ctx context.Context, userID, afterID uint, n int, ss ...scopes.Scope) ([]Post, error) {
+	q := r.DB().WithContext(ctx).Scopes(append([]scopes.Scope{PublicOnly(), scopes.ByUser(userID)}, ss...)...).Order("id DESC")
+	if afterID != 0 {
+		q = q.Where("id < ?", afterID)
+	}
+	if n > 0 {
+		q = q.Limit(n)
+	}
+	var posts []Post
+	if err := q.Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("查询用户文章失败: %w", err)
+	}
+	return posts, nil
+}
+
+// PageByUser 按发布时间倒序返回用户对外可见的文章的第 page 页 (从 1 开始), 页大小见 scopes.Paginate. ss 同 ListByUser
+func (r *PostRepository) PageByUser(ctx context.Context, userID uint, page, size int, ss ...scopes.Scope) ([]Post, error) {
+	return r.Page(ctx, page, size, append([]scopes.Scope{PublicOnly(), scopes.ByUser(userID), newestFirst}, ss...)...)
+}
+
+// PostFilterFields 可以通过 filterdsl 过滤和排序的文章字段, 如 ?filter=title~Go,view_count>=100&sort=-created_at
+var PostFilterFields = filterdsl.Fields{
+	"title":      {Column: "title", Kind: filterdsl.String},
+	"view_count": {Column: "view_count", Kind: filterdsl.Int},
+	"created_at": {Column: "created_at", Kind: filterdsl.Time},
+}
+
+// Search 按 filterdsl 的条件和排序返回对外可见的文章的第 page 页, 排序值相同时按 id 倒序; 没有排序时新文章在前. ss 同 ListByUser
+func (r *PostRepository) Search(ctx context.Context, q *filterdsl.Query, page, size int, ss ...scopes.Scope) ([]Post, error) {
+	return r.Page(ctx, page, size, append([]scopes.Scope{PublicOnly(), q.Scope(), newestFirst}, ss...)...)
+}
+
+// Create 以 post.UserID 的身份发表文章, 作者的邮箱未验证时返回 ErrEmailNotVerified
+func (r *PostRepository) Create(ctx context.Context, post *Post) error {
+	if err := requireVerified(ctx, r.DB(), post.UserID); err != nil {
+		return err
+	}
+	return r.Repository.Create(ctx, post)
+}
+
+// Update 更新文章的标题和内容, 成功后 post 为更新后的文章. 数据不合法时返回 validate.Errors
+func (r *PostRepository) Update(ctx context.Context, post *Post) error {
+	current, err := r.GetByID(ctx, post.ID)
+	if err != nil {
+		return err
+	}
+	current.Title, current.Content = post.Title, post.Content
+	if err := r.Repository.Update(ctx, current, "title", "content"); err != nil {
+		return err
+	}
+	*post = *current
+	return nil
+}
+
+// CreateBatch 分批插入文章. 每篇文章仍会触发 AfterCreate 钩子, 作者的文章数与逐条插入时一致
+func (r *PostRepository) CreateBatch(ctx context.Context, posts []Post, batchSize int) error {
+	if err := validateAll(posts); err != nil {
+		return err
+	}
+	if err := createInBatches(ctx, r.DB(), &posts, len(posts), batchSize); err != nil {
+		return fmt.Errorf("批量创建文章失败: %w", err)
+	}
+	return nil
+}
+
+// PostCommentCount 文章及其评论数
+type PostCommentCount struct {
+	Post
+	CommentCount int64
+}
+
+// CommentRankCursor 评论数排行的翻页位置: 上一页最后一篇文章的评论数和 ID
+type CommentRankCursor struct {
+	CommentCount int64
+	PostID       uint
+}
+
+// Cursor 返回从该文章之后继续翻页的位置
+func (p *PostCommentCount) Cursor() *CommentRankCursor {
+	return &CommentRankCursor{CommentCount: p.CommentCount, PostID: p.ID}
+}
+
+// MostCommented 返回评论数最多的 n 篇文章, 评论数相同时新文章 (id 大的) 在前.
+// after 为 nil 时从第一名开始, 否则返回排在 after 之后的文章 (keyset 翻页, 翻页期间有新评论也不会重复或遗漏整页).
+// 评论数读自 post_stats, 只使用 GORM 的查询构造器, 不依赖特定数据库的 SQL 写法
+func (r *PostRepository) MostCommented(ctx context.Context, after *CommentRankCursor, n int) ([]PostCommentCount, error) {
+	var posts []PostCommentCount
+	if err := mostCommentedQuery(r.DB().WithContext(ctx), after, n).Scan(&posts).Error; err != nil {
+		return nil, fmt.Errorf("查询评论最多的文章失败: %w", err)
+	}
+	return posts, nil
+}
+
+// 评论最多的文章, 执行计划检查 (queryplans.go) 也使用该查询
+func mostCommentedQuery(db *gorm.DB, after *CommentRankCursor, n int) *gorm.DB {
+	q := db.Model(&Post{}).
+		Select("posts.*, post_stats.comment_count").
+		Joins("JOIN post_stats ON post_stats.post_id = posts.id").
+		Scopes(PublicOnly())
+	if after != nil {
+		q = q.Where("post_stats.comment_count < ? OR (post_stats.comment_count = ? AND post_stats.post_id < ?)",
+			after.CommentCount, after.CommentCount, after.PostID)
+	}
+	// 两列同为降序, 可以反向扫描 idx_post_stats_ranking (comment_count, post_id) 而无需排序
+	return q.Order("post_stats.comment_count DESC").
+		Order("post_stats.post_id DESC").
+		Limit(n)
+}
+
+// CommentRepository 评论数据访问, 通用的增删改查见 repository.Repository
+type CommentRepository struct {
+	*repository.Repository[Comment]
+}
+
+// NewCommentRepository 创建评论仓储, 不存在时返回 ErrCommentNotFound
+func NewCommentRepository(db *gorm.DB) *CommentRepository {
+	return &CommentRepository{newRepository[Comment](db, "评论", ErrCommentNotFound)}
+}
+
+// CreateBatch 分批插入评论, 分表后按所在分表分组插入
+func (r *CommentRepository) CreateBatch(ctx context.Context, comments []Comment, batchSize int) error {
+	if err := validateAll(comments); err != nil {
+		return err
+	}
+	if err := createComments(ctx, r.DB(), comments, batchSize); err != nil {
+		return fmt.Errorf("批量创建评论失败: %w", err)
+	}
+	return nil
+}
+
+// 插入前逐条校验, 返回第一条不合法数据的错误
+func validateAll[T any, P interface {
+	*T
+	Validate() error
+}](rows []T) error {
+	for i := range rows {
+		if err := P(&rows[i]).Validate(); err != nil {
+			return fmt.Errorf("第 %d 条: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// 所有批次在同一事务中执行, 任一批失败则全部回滚
+func createInBatches(ctx context.Context, db *gorm.DB, rows any, n, batchSize int) error {
+	if n == 0 {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(rows, batchSize).Error
+	})
+}
+
+// ListByName 按姓名排序返回全部用户的摘要, locale 决定中文姓名的排序方式
+func (r *UserRepository) ListByName(ctx context.Context, locale collate.Locale) ([]UserSummary, error) {
+	var users []UserSummary
+	err := r.DB().WithContext(ctx).Model(&User{}).Order(collate.OrderBy("name", locale, false)).Find(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询用户列表失败: %w", err)
+	}
+	return users, nil
+}
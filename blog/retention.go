@@ -0,0 +1,45 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/retention"
+	"gorm.io/gorm"
+)
+
+// RetentionPolicies 博客库的保留策略: 带 deleted_at 列的表中软删除超过保留期的记录, 以及过期的审计日志和通知.
+// 软删除的表从库结构中查找, 之后加上软删除的模型无需修改这里
+func RetentionPolicies(ctx context.Context, db *gorm.DB, cfg config.Retention) ([]retention.Policy, error) {
+	var tables []string
+	err := db.WithContext(ctx).Raw(`
+		SELECT TABLE_NAME
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND COLUMN_NAME IN ('deleted_at', 'id')
+		GROUP BY TABLE_NAME
+		HAVING COUNT(*) = 2
+		ORDER BY TABLE_NAME
+	`).Scan(&tables).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询软删除的表失败: %w", err)
+	}
+	policies := make([]retention.Policy, 0, len(tables)+2)
+	for _, t := range tables {
+		policies = append(policies, retention.Policy{Name: "soft_deleted", Table: t, Where: "deleted_at IS NOT NULL AND deleted_at < ?", Keep: cfg.SoftDeleted})
+	}
+	return append(policies,
+		retention.Policy{Name: "audit_logs", Table: "audit_logs", Where: "created_at < ?", Keep: cfg.AuditLogs},
+		retention.Policy{Name: "notifications", Table: "notifications", Where: "created_at < ?", Keep: cfg.Notifications},
+	), nil
+}
+
+// PurgeExpired 按 cfg 的保留期分批删除过期数据, 返回每条生效的策略删除的行数; dryRun 时只统计. 见 retention 包
+func PurgeExpired(ctx context.Context, db *gorm.DB, cfg config.Retention, dryRun bool) ([]retention.Result, error) {
+	policies, err := RetentionPolicies(ctx, db, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return retention.Run(ctx, db, policies, time.Now(), retention.Options{BatchSize: cfg.BatchSize, Pause: cfg.Pause, DryRun: dryRun})
+}
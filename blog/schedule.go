@@ -0,0 +1,164 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"gorm.io/gorm"
+)
+
+// 文章发布状态. PublishAt 晚于创建时间的文章为定时发布, 到时间后由 PublishDuePosts 发布;
+// 发布前只有作者可见, 不计入作者的文章数, 也不产生发布事件
+const (
+	PostPublished PostStatus = scopes.Published
+	PostScheduled PostStatus = "scheduled"
+)
+
+// PostStatuses 全部发布状态
+var PostStatuses = []PostStatus{PostPublished, PostScheduled, PostArchived}
+
+// Post 钩子函数 - 创建文章前生成主键和 UUID 键, 修订号从 1 开始, 并按 PublishAt 确定发布状态, PublishAt 为空或已过去时立即发布
+func (p *Post) BeforeCreate(tx *gorm.DB) error {
+	assignID(&p.ID)
+	assignUUID(&p.UUID)
+	p.Version = 1
+	p.Status = PostPublished
+	if p.PublishAt != nil && p.PublishAt.After(time.Now()) {
+		p.Status = PostScheduled
+	}
+	return nil
+}
+
+// 每次发布的最大文章数, PublishDuePosts 分批处理直到没有到期的文章
+const publishBatchSize = 100
+
+// PublishDuePosts 发布 PublishAt 已到的定时文章, 触发与直接发布相同的副作用 (见 onPostPublished).
+// 每篇文章在各自的事务中加锁发布, 并发执行时不会重复发布. 返回发布的篇数
+func PublishDuePosts(ctx context.Context, db *gorm.DB) (int, error) {
+	published := 0
+	var errs []error
+	for {
+		var ids []uint
+		err := db.WithContext(ctx).Model(&Post{}).
+			Where("status = ? AND publish_at <= ?", PostScheduled, time.Now()).
+			Order("publish_at").Limit(publishBatchSize).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return published, fmt.Errorf("查询到期的定时文章失败: %w", err)
+		}
+
+		failed := 0
+		for _, id := range ids {
+			ok, err := publishScheduledPost(ctx, db, id)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("发布文章 %d 失败: %w", id, err))
+				failed++
+			}
+			if ok {
+				published++
+			}
+		}
+		// 有失败时停止, 失败的文章留到下次运行重试, 避免本次反复查询到它们
+		if len(ids) < publishBatchSize || failed > 0 {
+			return published, errors.Join(errs...)
+		}
+	}
+}
+
+// publishScheduledPost 发布一篇定时文章, 已被删除或已发布时返回 false
+func publishScheduledPost(ctx context.Context, db *gorm.DB, id uint) (bool, error) {
+	published := false
+	err := transaction(ctx, db, func(tx *gorm.DB) error {
+		var post Post
+		if err := WithRowLock(tx, &post, id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if post.Status != PostScheduled {
+			return nil
+		}
+		if err := tx.Model(&post).Update("status", PostPublished).Error; err != nil {
+			return err
+		}
+		published = true
+		return onPostPublished(tx, &post)
+	})
+	return published, err
+}
+
+// ListScheduledPosts 按计划发布时间返回用户尚未发布的定时文章
+func ListScheduledPosts(ctx context.Context, db *gorm.DB, userID uint) ([]Post, error) {
+	var posts []Post
+	err := db.WithContext(ctx).Scopes(scopes.ByUser(userID)).Where("status = ?", PostScheduled).
+		Order("publish_at").Order("id").Find(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询定时文章失败: %w", err)
+	}
+	return posts, nil
+}
+
+// PurgeStaleScheduledPosts 删除计划发布时间和最后修改时间都早于 maxAge 之前、却仍未发布的定时文章.
+// PublishDuePosts 每分钟发布到期的文章, 这些文章是发布一直失败又被作者遗忘的, 计划在将来发布的文章不删除.
+// 与直接删除文章一样执行 Post 的删除钩子, 一并删除附件等从属数据. maxAge <= 0 时不删除. 返回删除的篇数
+func PurgeStaleScheduledPosts(ctx context.Context, db *gorm.DB, maxAge time.Duration) (int, error) {
+	if maxAge <= 0 {
+		return 0, nil
+	}
+	purged := 0
+	var errs []error
+	for {
+		cutoff := time.Now().Add(-maxAge)
+		var ids []uint
+		err := db.WithContext(ctx).Model(&Post{}).
+			Where("status = ? AND publish_at < ? AND updated_at < ?", PostScheduled, cutoff, cutoff).
+			Order("id").Limit(publishBatchSize).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return purged, fmt.Errorf("查询过期的定时文章失败: %w", err)
+		}
+
+		failed := 0
+		for _, id := range ids {
+			ok, err := purgeStaleScheduledPost(ctx, db, id, cutoff)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("删除定时文章 %d 失败: %w", id, err))
+				failed++
+			}
+			if ok {
+				purged++
+			}
+		}
+		// 与 PublishDuePosts 相同, 有失败时停止, 留到下次运行重试
+		if len(ids) < publishBatchSize || failed > 0 {
+			return purged, errors.Join(errs...)
+		}
+	}
+}
+
+// purgeStaleScheduledPost 加锁后再次检查并删除一篇定时文章, 期间已发布、被修改、改期或已删除时返回 false
+func purgeStaleScheduledPost(ctx context.Context, db *gorm.DB, id uint, cutoff time.Time) (bool, error) {
+	purged := false
+	err := transaction(ctx, db, func(tx *gorm.DB) error {
+		var post Post
+		if err := WithRowLock(tx, &post, id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if post.Status != PostScheduled || post.PublishAt == nil || !post.PublishAt.Before(cutoff) || !post.UpdatedAt.Before(cutoff) {
+			return nil
+		}
+		if err := tx.Delete(&post).Error; err != nil {
+			return err
+		}
+		purged = true
+		return nil
+	})
+	return purged, err
+}
@@ -0,0 +1,66 @@
+package blog
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPurgeStaleScheduledPosts(t *testing.T) {
+	db := newTestDB(t)
+	author := newTestUser(t, db, "alice")
+	posts := NewPostRepository(db)
+	ctx := context.Background()
+	future := time.Now().Add(24 * time.Hour)
+	longAgo := time.Now().AddDate(0, -7, 0)
+
+	tests := []struct {
+		name      string
+		publishAt *time.Time
+		updatedAt time.Time
+		wantGone  bool
+	}{
+		{"早已过了计划时间的定时文章", &longAgo, longAgo, true},
+		{"长期未修改但计划在明天发布的定时文章", &future, longAgo, false},
+		{"早已过了计划时间但最近修改过的定时文章", &longAgo, time.Now(), false},
+		{"长期未修改的已发布文章", nil, longAgo, false},
+	}
+	ids := make([]uint, len(tests))
+	for i, tt := range tests {
+		post := Post{UserID: author.ID, Title: tt.name, Content: "内容"}
+		if tt.publishAt != nil {
+			post.PublishAt = &future // 计划时间在将来才会创建为定时文章, 之后再改为要测试的时间
+		}
+		if err := posts.Create(ctx, &post); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&Attachment{PostID: post.ID, UserID: author.ID, StorageKey: tt.name, FileName: "a.png", ContentType: "image/png", Size: 1}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Model(&Post{}).Where("id = ?", post.ID).UpdateColumns(map[string]any{"publish_at": tt.publishAt, "updated_at": tt.updatedAt}).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = post.ID
+	}
+
+	if n, err := PurgeStaleScheduledPosts(ctx, db, 0); err != nil || n != 0 {
+		t.Fatalf("保留期为 0 时删除了 %d 篇, err = %v", n, err)
+	}
+	n, err := PurgeStaleScheduledPosts(ctx, db, 180*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("删除了 %d 篇, 期望 1 篇", n)
+	}
+
+	for i, tt := range tests {
+		var posts, attachments int64
+		db.Model(&Post{}).Where("id = ?", ids[i]).Count(&posts)
+		db.Model(&Attachment{}).Where("post_id = ?", ids[i]).Count(&attachments)
+		if gone := posts == 0 && attachments == 0; gone != tt.wantGone {
+			t.Errorf("%s: 文章 %d 篇, 附件 %d 个, 期望删除 %v", tt.name, posts, attachments, tt.wantGone)
+		}
+	}
+	assertArticleCount(t, db, author.ID, 1)
+}
@@ -0,0 +1,195 @@
+package blog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/alexwang789/Base1_golang_task3/cdc"
+	"github.com/alexwang789/Base1_golang_task3/outbox"
+	"github.com/alexwang789/Base1_golang_task3/partition"
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"github.com/alexwang789/Base1_golang_task3/search"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 文章检索: 配置了搜索引擎 (见 config.LoadSearch) 时在其中检索, 集群不可用时改用 MySQL 的 FULLTEXT 索引.
+// 索引只包含对外可见的文章, 由 SearchIndexer 按文章和用户的行变更 (见 cdc 包, 经发件箱投递) 同步;
+// 首次启用或索引丢失时用 ReindexPosts 重建.
+
+// 文章标题和正文的 FULLTEXT 索引
+const postFullTextIndex = "idx_posts_fulltext"
+
+// ensurePostFullTextIndex 创建文章标题和正文的 FULLTEXT 索引, 按 ngram 分词以支持中文. MySQL 的分区表不支持
+// FULLTEXT 索引, 文章表已按月分区时不创建, 数据库检索改用 LIKE
+func ensurePostFullTextIndex(db *gorm.DB) error {
+	parts, err := partition.List(db.Statement.Context, db, "posts")
+	if err != nil || len(parts) > 0 || db.Migrator().HasIndex(&Post{}, postFullTextIndex) {
+		return err
+	}
+	if err := db.Exec("CREATE FULLTEXT INDEX " + postFullTextIndex + " ON posts (title, content) WITH PARSER ngram").Error; err != nil {
+		return fmt.Errorf("创建文章的全文索引失败: %w", err)
+	}
+	return nil
+}
+
+// dropPostFullTextIndex 分区前删除文章的 FULLTEXT 索引
+func dropPostFullTextIndex(db *gorm.DB) error {
+	if !db.Migrator().HasIndex(&Post{}, postFullTextIndex) {
+		return nil
+	}
+	if err := db.Migrator().DropIndex(&Post{}, postFullTextIndex); err != nil {
+		return fmt.Errorf("删除文章的全文索引失败: %w", err)
+	}
+	return nil
+}
+
+// SearchPostsFullText 在数据库中检索对外可见的文章的第 page 页 (页大小见 scopes.Paginate): 有 FULLTEXT 索引时
+// 按标题和正文的相关度排序, 否则按 LIKE 匹配标题和正文、新的在前. 不匹配作者名
+func SearchPostsFullText(ctx context.Context, db *gorm.DB, query string, page, size int) ([]Post, error) {
+	db = db.WithContext(ctx)
+	q := db.Scopes(PublicOnly(), scopes.Paginate(page, size))
+	if db.Migrator().HasIndex(&Post{}, postFullTextIndex) {
+		match := "MATCH (title, content) AGAINST (? IN NATURAL LANGUAGE MODE)"
+		q = q.Where(match, query).
+			Order(clause.OrderBy{Expression: clause.Expr{SQL: match + " DESC, id DESC", Vars: []any{query}}})
+	} else {
+		like := "%" + escapeLike(query) + "%"
+		q = q.Where("title LIKE ? OR content LIKE ?", like, like).Order("id DESC")
+	}
+	var posts []Post
+	if err := q.Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("检索文章失败: %w", err)
+	}
+	return posts, nil
+}
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// SearchPostsES 在搜索引擎中检索 ctx 所属租户对外可见的文章的第 page 页, 匹配标题、正文和作者名, 按相关度排序.
+// client 为 nil 或检索失败 (如集群不可用) 时改用 SearchPostsFullText
+func SearchPostsES(ctx context.Context, db *gorm.DB, client *search.Client, query string, page, size int) ([]Post, error) {
+	if client == nil || !client.Available() {
+		return SearchPostsFullText(ctx, db, query, page, size)
+	}
+	if size <= 0 {
+		size = scopes.DefaultPageSize
+	}
+	size = min(size, scopes.MaxPageSize)
+	page = max(page, 1)
+	ids, err := client.Search(ctx, tenant.ID(ctx), query, (page-1)*size, size)
+	if err != nil {
+		log.Printf("搜索引擎检索失败, 改用数据库检索: %v", err)
+		return SearchPostsFullText(ctx, db, query, page, size)
+	}
+	if len(ids) == 0 {
+		return []Post{}, nil
+	}
+
+	// 索引可能落后于数据库: 已不可见的文章不返回
+	var posts []Post
+	if err := db.WithContext(ctx).Scopes(PublicOnly()).Where("id IN ?", ids).Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("查询检索到的文章失败: %w", err)
+	}
+	slices.SortFunc(posts, func(a, b Post) int {
+		return slices.Index(ids, a.ID) - slices.Index(ids, b.ID)
+	})
+	return posts, nil
+}
+
+// 重建索引时每批的文章数
+const reindexBatch = 500
+
+// ReindexPosts 创建索引 (不存在时) 并写入全部租户对外可见的文章, 返回写入的数量. 不删除索引中已不可见的文章
+func ReindexPosts(ctx context.Context, db *gorm.DB, client *search.Client) (int, error) {
+	if err := client.EnsureIndex(ctx); err != nil {
+		return 0, err
+	}
+	ctx = tenant.All(ctx)
+	indexed := 0
+	var posts []Post
+	err := db.WithContext(ctx).Scopes(PublicOnly()).FindInBatches(&posts, reindexBatch, func(tx *gorm.DB, _ int) error {
+		if err := LoadAuthors(ctx, db, posts); err != nil {
+			return err
+		}
+		docs := make([]search.Doc, len(posts))
+		for i := range posts {
+			docs[i] = postDoc(&posts[i])
+		}
+		if err := client.Bulk(ctx, docs); err != nil {
+			return err
+		}
+		indexed += len(docs)
+		return nil
+	}).Error
+	if err != nil {
+		return indexed, fmt.Errorf("重建文章索引失败: %w", err)
+	}
+	return indexed, nil
+}
+
+func postDoc(p *Post) search.Doc {
+	return search.Doc{
+		ID:        p.ID,
+		TenantID:  p.TenantID,
+		Title:     p.Title,
+		Content:   p.Content,
+		AuthorID:  p.UserID,
+		Author:    p.User.Name,
+		CreatedAt: p.CreatedAt,
+	}
+}
+
+// SearchIndexer 作为 outbox.Relay 的投递目标, 按文章和用户的行变更同步搜索索引, 其他事件忽略.
+// 文章变更时按数据库中的当前状态写入或删除, 重复或乱序投递的结果相同; 用户改名时更新其全部文章的作者名
+type SearchIndexer struct {
+	db     *gorm.DB
+	client *search.Client
+}
+
+// NewSearchIndexer 创建投递目标
+func NewSearchIndexer(db *gorm.DB, client *search.Client) *SearchIndexer {
+	return &SearchIndexer{db: db, client: client}
+}
+
+// Publish 实现 outbox.Sink
+func (s *SearchIndexer) Publish(ctx context.Context, event outbox.Event) error {
+	if event.Type != cdc.EventType("posts") && event.Type != cdc.EventType("users") {
+		return nil
+	}
+	var change cdc.Change
+	if err := json.Unmarshal([]byte(event.Payload), &change); err != nil {
+		return fmt.Errorf("解析变更事件 %d 失败: %w", event.ID, err)
+	}
+	if change.Schema == "users" {
+		if change.Op != cdc.OpUpdate || change.Before["name"] == change.After["name"] {
+			return nil
+		}
+		name, _ := change.After["name"].(string)
+		return s.client.UpdateAuthor(ctx, uint(change.ID), name)
+	}
+
+	ctx = tenant.All(ctx)
+	var post Post
+	err := s.db.WithContext(ctx).Scopes(PublicOnly()).First(&post, change.ID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.client.Delete(ctx, uint(change.ID))
+	}
+	if err != nil {
+		return fmt.Errorf("查询文章 %d 失败: %w", change.ID, err)
+	}
+	posts := []Post{post}
+	if err := LoadAuthors(ctx, s.db, posts); err != nil {
+		return err
+	}
+	return s.client.Index(ctx, postDoc(&posts[0]))
+}
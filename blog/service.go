@@ -0,0 +1,57 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/ratelimit"
+	"github.com/alexwang789/Base1_golang_task3/validate"
+	"gorm.io/gorm"
+)
+
+// RegisterUser 注册用户并在同一事务中写入带验证令牌的欢迎邮件, 邮件由 emailqueue.Worker 异步发送.
+// 邮箱验证前用户只能浏览 (见 VerifyEmail). 姓名或邮箱已被使用时返回 *DuplicateFieldError
+func RegisterUser(ctx context.Context, db *gorm.DB, user *User) error {
+	if err := user.Validate(); err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return fmt.Errorf("创建用户失败: %w", translateUserDuplicate(err))
+		}
+		return sendVerification(tx, user, "欢迎加入", "你的账号已创建成功. ")
+	})
+}
+
+// CreateComment 发表评论或回复 (ParentID 非空), 评论进入待审核状态. limiter 按作者限制发表频率 (为 nil 时不限流),
+// 超出频率时返回 *ratelimit.LimitError (errors.Is(err, ratelimit.ErrRateLimited)); 作者的邮箱未验证时返回 ErrEmailNotVerified
+func CreateComment(ctx context.Context, db *gorm.DB, limiter ratelimit.Limiter, comment *Comment) error {
+	comment.Status = CommentPending
+	if err := comment.Validate(); err != nil {
+		return err
+	}
+	if err := requireVerified(ctx, db, comment.UserID); err != nil {
+		return err
+	}
+	if comment.ParentID != nil {
+		// 只能回复同一文章下对外可见的评论
+		var n int64
+		err := db.WithContext(ctx).Model(&Comment{}).Scopes(PublicComments()).
+			Where("id = ? AND post_id = ?", *comment.ParentID, comment.PostID).Count(&n).Error
+		if err != nil {
+			return fmt.Errorf("查询被回复的评论失败: %w", err)
+		}
+		if n == 0 {
+			return validate.Errors{"parent_id": "回复的评论不存在"}
+		}
+	}
+	if limiter != nil {
+		if err := limiter.Allow(ctx, fmt.Sprintf("comment:%d", comment.UserID)); err != nil {
+			return err
+		}
+	}
+	if err := db.WithContext(ctx).Create(comment).Error; err != nil {
+		return fmt.Errorf("创建评论失败: %w", err)
+	}
+	return nil
+}
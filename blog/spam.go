@@ -0,0 +1,94 @@
+package blog
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"gorm.io/gorm"
+)
+
+// SpamChecker 判断待审核的评论是否为垃圾评论. 在创建评论的事务中调用, tx 可用于查询历史评论
+type SpamChecker interface {
+	// Check 返回非空的 reason 表示是垃圾评论
+	Check(tx *gorm.DB, c *Comment) (reason string, err error)
+}
+
+// spamChecker 由 EnableSpamCheck 设置; 为 nil 时不做检查
+var spamChecker SpamChecker
+
+// EnableSpamCheck 设置创建评论时使用的检查器, 被判定为垃圾的评论直接标记为 CommentSpam,
+// 不进入待审核队列. checker 为 nil 时关闭检查
+func EnableSpamCheck(checker SpamChecker) {
+	spamChecker = checker
+}
+
+// BeforeCreate 钩子 - 生成主键和 UUID 键, 并对待审核的评论做垃圾检查. 已确定状态的评论 (如内置数据、导入) 不检查
+func (c *Comment) BeforeCreate(tx *gorm.DB) error {
+	assignID(&c.ID)
+	assignUUID(&c.UUID)
+	if spamChecker == nil || (c.Status != "" && c.Status != CommentPending) {
+		return nil
+	}
+	reason, err := spamChecker.Check(tx, c)
+	if err != nil {
+		return fmt.Errorf("垃圾评论检查失败: %w", err)
+	}
+	if reason != "" {
+		c.Status = CommentSpam
+		log.Printf("用户 %d 在文章 %d 的评论被标记为垃圾评论: %s", c.UserID, c.PostID, reason)
+	}
+	return nil
+}
+
+// 匹配评论中的链接
+var linkPattern = regexp.MustCompile(`(?i)https?://|www\.`)
+
+// HeuristicSpamChecker 按规则判断垃圾评论: 链接过多、包含屏蔽词、同一用户短时间内重复发表相同内容
+type HeuristicSpamChecker struct {
+	MaxLinks        int           // 允许的最多链接数, 超过即为垃圾评论
+	BannedWords     []string      // 屏蔽词, 不区分大小写
+	DuplicateWindow time.Duration // 重复内容的检查窗口, <= 0 时不检查
+}
+
+// NewHeuristicSpamChecker 创建规则检查器, 最多 2 个链接, 24 小时内不得重复发表相同内容
+func NewHeuristicSpamChecker(bannedWords []string) *HeuristicSpamChecker {
+	return &HeuristicSpamChecker{
+		MaxLinks:        2,
+		BannedWords:     bannedWords,
+		DuplicateWindow: 24 * time.Hour,
+	}
+}
+
+// Check 实现 SpamChecker
+func (h *HeuristicSpamChecker) Check(tx *gorm.DB, c *Comment) (string, error) {
+	if n := len(linkPattern.FindAllStringIndex(c.Content, -1)); n > h.MaxLinks {
+		return fmt.Sprintf("包含 %d 个链接", n), nil
+	}
+
+	content := strings.ToLower(c.Content)
+	for _, word := range h.BannedWords {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" && strings.Contains(content, word) {
+			return fmt.Sprintf("包含屏蔽词 %q", word), nil
+		}
+	}
+
+	if h.DuplicateWindow > 0 {
+		var n int64
+		err := tx.Model(&Comment{}).
+			Scopes(scopes.ByUser(c.UserID), scopes.CreatedBetween(time.Now().Add(-h.DuplicateWindow), time.Time{})).
+			Where("content = ?", c.Content).
+			Count(&n).Error
+		if err != nil {
+			return "", err
+		}
+		if n > 0 {
+			return "重复发表相同内容", nil
+		}
+	}
+	return "", nil
+}
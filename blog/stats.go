@@ -0,0 +1,151 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 管理后台的统计. 时间按 DSNParams 中的 loc=Local 存储, 按本地时区的日期和周 (周一开始) 分组.
+// 统计只使用 GORM 的查询构造器, tenant 插件按 posts / users 的 tenant_id 过滤
+
+// StatPoint 时间序列中的一个点, Start 为该段 (天或周) 的开始, 没有数据的段 Count 为 0
+type StatPoint struct {
+	Start time.Time
+	Count int64
+}
+
+// dateBucket 按日期分组的行, 日期由 MySQL 格式化为 YYYY-MM-DD
+type dateBucket struct {
+	Day   string
+	Count int64
+}
+
+// PostsPerDay 返回 [from, to) 内每天发布的文章数, from 和 to 按本地日期取整. 定时文章按计划发布的时间计,
+// 尚未发布的不计入, 已归档的仍按当初发布的日期计入
+func PostsPerDay(ctx context.Context, db *gorm.DB, from, to time.Time) ([]StatPoint, error) {
+	from, to = startOfDay(from), startOfDay(to)
+	var rows []dateBucket
+	err := db.WithContext(ctx).Model(&Post{}).
+		Select("DATE_FORMAT(COALESCE(publish_at, created_at), '%Y-%m-%d') AS day, COUNT(*) AS count").
+		Where("status <> ?", PostScheduled).
+		Where("COALESCE(publish_at, created_at) >= ? AND COALESCE(publish_at, created_at) < ?", from, to).
+		Group("day").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计每天的文章数失败: %w", err)
+	}
+	return fillSeries(rows, from, to, func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }), nil
+}
+
+// NewUsersPerWeek 返回 [from, to) 内每周 (周一开始) 注册的用户数, from 和 to 按本地时区取整到周一
+func NewUsersPerWeek(ctx context.Context, db *gorm.DB, from, to time.Time) ([]StatPoint, error) {
+	from, to = startOfWeek(from), startOfWeek(to)
+	var rows []dateBucket
+	err := db.WithContext(ctx).Model(&User{}).
+		Select("DATE_FORMAT(DATE_SUB(DATE(created_at), INTERVAL WEEKDAY(created_at) DAY), '%Y-%m-%d') AS day, COUNT(*) AS count").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("day").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计每周的新用户数失败: %w", err)
+	}
+	return fillSeries(rows, from, to, func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }), nil
+}
+
+// fillSeries 把按日期分组的行展开为 [from, to) 内连续的时间序列, 没有数据的段补 0
+func fillSeries(rows []dateBucket, from, to time.Time, next func(time.Time) time.Time) []StatPoint {
+	counts := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		counts[r.Day] = r.Count
+	}
+	var points []StatPoint
+	for t := from; t.Before(to); t = next(t) {
+		points = append(points, StatPoint{Start: t, Count: counts[t.Format(time.DateOnly)]})
+	}
+	return points
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.In(time.Local).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+func startOfWeek(t time.Time) time.Time {
+	day := startOfDay(t)
+	// time.Weekday 周日为 0, 与 MySQL 的 WEEKDAY 一致改为周一为 0
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// CommentBucket 评论数分布的一个区间: 评论数在 [Min, Max] 内的文章有 Posts 篇, 最后一个区间的 Max 为 -1 (不设上限)
+type CommentBucket struct {
+	Min   int64
+	Max   int64
+	Posts int64
+}
+
+// 评论数分布的区间下限
+var commentBucketBounds = []int64{0, 1, 2, 6, 11, 21, 51, 101}
+
+// CommentsPerPost 返回对外可见的文章的评论数分布, 评论数读自 post_stats (只计已通过审核的评论)
+func CommentsPerPost(ctx context.Context, db *gorm.DB) ([]CommentBucket, error) {
+	var rows []struct {
+		Comments int64
+		Posts    int64
+	}
+	err := db.WithContext(ctx).Model(&Post{}).
+		Select("COALESCE(post_stats.comment_count, 0) AS comments, COUNT(*) AS posts").
+		Joins("LEFT JOIN post_stats ON post_stats.post_id = posts.id").
+		Scopes(PublicOnly()).
+		Group("comments").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计评论数分布失败: %w", err)
+	}
+
+	buckets := make([]CommentBucket, len(commentBucketBounds))
+	for i, lo := range commentBucketBounds {
+		buckets[i] = CommentBucket{Min: lo, Max: -1}
+		if i+1 < len(commentBucketBounds) {
+			buckets[i].Max = commentBucketBounds[i+1] - 1
+		}
+	}
+	for _, r := range rows {
+		for i := len(buckets) - 1; i >= 0; i-- {
+			if r.Comments >= buckets[i].Min {
+				buckets[i].Posts += r.Posts
+				break
+			}
+		}
+	}
+	return buckets, nil
+}
+
+// AuthorStat 作者在统计时间范围内发表的文章数和这些文章收到的评论数
+type AuthorStat struct {
+	UserID       uint
+	Name         string
+	PostCount    int64
+	CommentCount int64
+}
+
+// TopAuthors 返回 [from, to) 内发表对外可见的文章最多的 n 位作者, 文章数相同时评论多的在前
+func TopAuthors(ctx context.Context, db *gorm.DB, from, to time.Time, n int) ([]AuthorStat, error) {
+	var authors []AuthorStat
+	err := db.WithContext(ctx).Model(&Post{}).
+		Select("posts.user_id, users.name, COUNT(*) AS post_count, COALESCE(SUM(post_stats.comment_count), 0) AS comment_count").
+		Joins("JOIN users ON users.id = posts.user_id").
+		Joins("LEFT JOIN post_stats ON post_stats.post_id = posts.id").
+		Scopes(PublicOnly()).
+		Where("COALESCE(posts.publish_at, posts.created_at) >= ? AND COALESCE(posts.publish_at, posts.created_at) < ?", from, to).
+		Group("posts.user_id, users.name").
+		Order("post_count DESC, comment_count DESC, posts.user_id").
+		Limit(n).
+		Scan(&authors).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计作者排行失败: %w", err)
+	}
+	return authors, nil
+}
@@ -0,0 +1,236 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StressOptions 并发写入压测的参数
+type StressOptions struct {
+	Workers    int // 并发的 goroutine 数
+	Iterations int // 每个 goroutine 的操作次数, 一半发文章, 一半发评论
+	Users      int // 参与的作者数, 越少则同一作者行上的竞争越激烈
+	Posts      int // 评论的目标文章数, 越少则同一文章统计行上的竞争越激烈
+}
+
+// StressResult 压测结果. Mismatches 为空且 Errors 为 0 时钩子和加锁的设计经受住了并发
+type StressResult struct {
+	Posts      int64             // 成功发表的文章数
+	Comments   int64             // 成功发表并通过审核的评论数
+	Errors     map[string]int    // 失败的操作按错误信息计数
+	Mismatches []CounterMismatch // 计数字段与成功的操作数不一致的记录
+	Elapsed    time.Duration
+}
+
+// Stress 用 Workers 个 goroutine 并发发表文章 (PostRepository.Create) 和评论 (CreateComment 后 ModerateComment 通过),
+// 结束后逐一核对本次创建的作者的 article_count 和目标文章的 comment_status、post_stats.comment_count
+// 是否与成功的操作数完全一致. 压测数据属于一批新建的用户, 不清理, 应在测试库上执行
+func Stress(ctx context.Context, db *gorm.DB, opts StressOptions) (*StressResult, error) {
+	opts.Workers, opts.Iterations = max(opts.Workers, 1), max(opts.Iterations, 1)
+	opts.Users, opts.Posts = max(opts.Users, 1), max(opts.Posts, 1)
+
+	// 作者和管理员的用户名带上本次的时间戳, 可以在同一个库上反复执行
+	run := time.Now().UnixNano()
+	password, err := HashPassword("stress123") // 全部用户共用一个哈希, 不逐个计算
+	if err != nil {
+		return nil, err
+	}
+	users := make([]User, opts.Users+1)
+	for i := range users {
+		users[i] = User{
+			Name:          fmt.Sprintf("stress-%d-%d", run, i),
+			Email:         fmt.Sprintf("stress-%d-%d@example.com", run, i),
+			Password:      password,
+			EmailVerified: true,
+		}
+	}
+	users[0].IsAdmin = true
+	if err := NewUserRepository(db).CreateBatch(ctx, users, DefaultBatchSize); err != nil {
+		return nil, fmt.Errorf("创建压测用户失败: %w", err)
+	}
+	moderator, authors := &users[0], users[1:]
+
+	posts := make([]Post, opts.Posts)
+	for i := range posts {
+		posts[i] = Post{Title: fmt.Sprintf("压测文章 %d", i+1), Content: "并发写入压测的目标文章", UserID: authors[i%len(authors)].ID}
+	}
+	if err := NewPostRepository(db).CreateBatch(ctx, posts, DefaultBatchSize); err != nil {
+		return nil, fmt.Errorf("创建压测文章失败: %w", err)
+	}
+
+	// 期望值: 各作者的文章数和各目标文章已通过的评论数, 只在操作成功后累加
+	articles := make([]atomic.Int64, len(authors))
+	for i := range posts {
+		articles[i%len(authors)].Add(1)
+	}
+	comments := make([]atomic.Int64, len(posts))
+
+	result := &StressResult{Errors: map[string]int{}}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	fail := func(err error) {
+		mu.Lock()
+		result.Errors[err.Error()]++
+		mu.Unlock()
+	}
+	started := time.Now()
+	for w := range opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(uint64(run), uint64(w)))
+			repo := NewPostRepository(db)
+			for i := range opts.Iterations {
+				if ctx.Err() != nil {
+					return
+				}
+				a := rng.IntN(len(authors))
+				if i%2 == 0 {
+					post := &Post{Title: fmt.Sprintf("压测 %d-%d", w, i), Content: "并发发表的文章", UserID: authors[a].ID}
+					if err := repo.Create(ctx, post); err != nil {
+						fail(err)
+						continue
+					}
+					articles[a].Add(1)
+					atomic.AddInt64(&result.Posts, 1)
+					continue
+				}
+				p := rng.IntN(len(posts))
+				comment := &Comment{Content: fmt.Sprintf("压测评论 %d-%d", w, i), PostID: posts[p].ID, UserID: authors[a].ID}
+				if err := CreateComment(ctx, db, nil, comment); err != nil {
+					fail(err)
+					continue
+				}
+				if err := ModerateComment(ctx, db, moderator, comment.ID, CommentApproved); err != nil {
+					fail(err)
+					continue
+				}
+				comments[p].Add(1)
+				atomic.AddInt64(&result.Comments, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	result.Elapsed = time.Since(started)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := checkStress(ctx, db, result, authors, articles, posts, comments); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkStress 把数据库中的计数字段与期望值逐一核对, 不一致的记录写入 result.Mismatches
+func checkStress(ctx context.Context, db *gorm.DB, result *StressResult, authors []User, articles []atomic.Int64, posts []Post, comments []atomic.Int64) error {
+	ids := make([]uint, len(authors))
+	for i, u := range authors {
+		ids[i] = u.ID
+	}
+	var stored []User
+	if err := db.WithContext(ctx).Select("id", "article_count").Where("id IN ?", ids).Find(&stored).Error; err != nil {
+		return fmt.Errorf("查询作者文章数失败: %w", err)
+	}
+	counts := make(map[uint]int, len(stored))
+	for _, u := range stored {
+		counts[u.ID] = u.ArticleCount
+	}
+	for i, u := range authors {
+		if want := articles[i].Load(); int64(counts[u.ID]) != want {
+			result.Mismatches = append(result.Mismatches, CounterMismatch{
+				Table: "users", ID: u.ID, Column: "article_count", Stored: fmt.Sprint(counts[u.ID]), Expected: fmt.Sprint(want),
+			})
+		}
+	}
+
+	ids = make([]uint, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	var rows []struct {
+		ID            uint
+		CommentStatus string
+		CommentCount  *int64 // 统计行不存在时为 NULL
+		Actual        int64
+	}
+	err := db.WithContext(ctx).Raw(`
+		SELECT p.id, p.comment_status, s.comment_count,
+			(SELECT COUNT(*) FROM `+commentTable.From("c")+` WHERE c.post_id = p.id AND c.status = ?) AS actual
+		FROM posts p
+		LEFT JOIN post_stats s ON s.post_id = p.id
+		WHERE p.id IN ?
+	`, CommentApproved, ids).Scan(&rows).Error
+	if err != nil {
+		return fmt.Errorf("查询文章评论统计失败: %w", err)
+	}
+	index := make(map[uint]int, len(posts))
+	for i, p := range posts {
+		index[p.ID] = i
+	}
+	for _, r := range rows {
+		want := comments[index[r.ID]].Load()
+		var stat int64
+		if r.CommentCount != nil {
+			stat = *r.CommentCount
+		}
+		status := postCommentStatus(want)
+		for _, m := range []CounterMismatch{
+			{Table: "posts", ID: r.ID, Column: "comments", Stored: fmt.Sprint(r.Actual), Expected: fmt.Sprint(want)},
+			{Table: "post_stats", ID: r.ID, Column: "comment_count", Stored: fmt.Sprint(stat), Expected: fmt.Sprint(want)},
+			{Table: "posts", ID: r.ID, Column: "comment_status", Stored: r.CommentStatus, Expected: string(status)},
+		} {
+			if m.Stored != m.Expected {
+				result.Mismatches = append(result.Mismatches, m)
+			}
+		}
+	}
+	return nil
+}
+
+// Err 压测中有失败的操作或计数不一致时返回错误
+func (r *StressResult) Err() error {
+	var errs []error
+	if n := len(r.Mismatches); n > 0 {
+		errs = append(errs, fmt.Errorf("%d 条记录的计数字段不一致", n))
+	}
+	if n := r.failed(); n > 0 {
+		errs = append(errs, fmt.Errorf("%d 次操作失败", n))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *StressResult) failed() int {
+	n := 0
+	for _, c := range r.Errors {
+		n += c
+	}
+	return n
+}
+
+// WriteText 输出压测结果
+func (r *StressResult) WriteText(w io.Writer) {
+	ops := r.Posts + r.Comments
+	fmt.Fprintf(w, "文章 %d 篇, 评论 %d 条, 失败 %d 次, 耗时 %s (%.0f 次/秒)\n",
+		r.Posts, r.Comments, r.failed(), r.Elapsed.Round(time.Millisecond), float64(ops)/r.Elapsed.Seconds())
+	messages := make([]string, 0, len(r.Errors))
+	for msg := range r.Errors {
+		messages = append(messages, msg)
+	}
+	sort.Slice(messages, func(i, j int) bool { return r.Errors[messages[i]] > r.Errors[messages[j]] })
+	for _, msg := range messages {
+		fmt.Fprintf(w, "  %d × %s\n", r.Errors[msg], msg)
+	}
+	WriteCounterReport(w, r.Mismatches)
+}
@@ -0,0 +1,133 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/filterdsl"
+	"github.com/alexwang789/Base1_golang_task3/repository"
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"gorm.io/gorm"
+)
+
+// PostSummary 列表中展示的文章, 不含正文. 只读取这些列, 正文较长时查询和响应都小得多
+type PostSummary struct {
+	ID            uint
+	UUID          *string
+	Title         string
+	CommentStatus PostCommentStatus
+	ViewCount     uint64
+	Status        PostStatus
+	PublishAt     *time.Time
+	Version       uint
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uint
+	User          User `gorm:"-"` // 作者, 由 LoadSummaryAuthors 加载
+}
+
+// Summary 返回文章的摘要, 已加载的作者一并带上
+func (p *Post) Summary() PostSummary {
+	return PostSummary{
+		ID:            p.ID,
+		UUID:          p.UUID,
+		Title:         p.Title,
+		CommentStatus: p.CommentStatus,
+		ViewCount:     p.ViewCount,
+		Status:        p.Status,
+		PublishAt:     p.PublishAt,
+		Version:       p.Version,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+		UserID:        p.UserID,
+		User:          p.User,
+	}
+}
+
+// UserSummary 列表中展示的用户, 不含邮箱和密码, 读取时无需解密邮箱
+type UserSummary struct {
+	ID           uint
+	Name         string
+	ArticleCount int
+	CreatedAt    time.Time
+}
+
+// SearchSummaries 同 Search, 返回不含正文的文章摘要
+func (r *PostRepository) SearchSummaries(ctx context.Context, q *filterdsl.Query, page, size int) ([]PostSummary, error) {
+	return repository.PageAs[PostSummary](ctx, r.Repository, page, size, PublicOnly(), q.Scope(), newestFirst)
+}
+
+// PageSummariesByUser 同 PageByUser, 返回不含正文的文章摘要
+func (r *PostRepository) PageSummariesByUser(ctx context.Context, userID uint, page, size int) ([]PostSummary, error) {
+	return repository.PageAs[PostSummary](ctx, r.Repository, page, size, PublicOnly(), scopes.ByUser(userID), newestFirst)
+}
+
+// ListSummariesByUser 同 ListByUser (n 为 0), 返回用户全部对外可见的文章的摘要
+func (r *PostRepository) ListSummariesByUser(ctx context.Context, userID uint) ([]PostSummary, error) {
+	return repository.ListAs[PostSummary](ctx, r.Repository, PublicOnly(), scopes.ByUser(userID), newestFirst)
+}
+
+// LoadSummaryAuthors 同 LoadAuthors, 为文章摘要批量加载作者及其资料
+func LoadSummaryAuthors(ctx context.Context, db *gorm.DB, posts []PostSummary) error {
+	return loadAuthors(ctx, db, posts, func(p *PostSummary) (uint, *User) { return p.UserID, &p.User })
+}
+
+// loadAuthors 按 author 返回的作者 ID 批量查询作者及其资料, 写入 author 返回的位置. 同一作者只查询一次, 启用了用户缓存时命中的作者不查询
+func loadAuthors[P any](ctx context.Context, db *gorm.DB, posts []P, author func(*P) (uint, *User)) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(posts))
+	seen := make(map[uint]bool, len(posts))
+	for i := range posts {
+		if id, _ := author(&posts[i]); !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	byID, err := cachedUsers(ctx, db, ids)
+	if err != nil {
+		return fmt.Errorf("查询文章作者失败: %w", err)
+	}
+	for i := range posts {
+		id, dst := author(&posts[i])
+		if u, ok := byID[id]; ok {
+			*dst = *u
+		} else {
+			*dst = User{}
+		}
+	}
+	return nil
+}
+
+// LoadLatestComments 为每篇文章加载最新的至多 limit 条已通过审核的评论 (post.Comments, 新评论在前).
+// 与 Preload 的 LIMIT 作用于全部文章不同, 这里用窗口函数在一条查询中按文章分别取前 limit 条
+func LoadLatestComments(ctx context.Context, db *gorm.DB, posts []Post, limit int) error {
+	if len(posts) == 0 || limit <= 0 {
+		return nil
+	}
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+
+	db = db.WithContext(ctx)
+	ranked := db.Model(&Comment{}).
+		Select("comments.*, ROW_NUMBER() OVER (PARTITION BY comments.post_id ORDER BY comments.id DESC) AS rn").
+		Scopes(approvedComments).Where("comments.post_id IN ?", ids)
+	var comments []Comment
+	err := db.Table("(?) AS comments", ranked).Where("rn <= ?", limit).Order("post_id, id DESC").Find(&comments).Error
+	if err != nil {
+		return fmt.Errorf("查询文章评论失败: %w", err)
+	}
+	byPost := make(map[uint][]Comment, len(posts))
+	for _, c := range comments {
+		byPost[c.PostID] = append(byPost[c.PostID], c)
+	}
+	for i := range posts {
+		posts[i].Comments = byPost[posts[i].ID]
+	}
+	return nil
+}
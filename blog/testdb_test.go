@@ -0,0 +1,79 @@
+package blog
+
+import (
+	"testing"
+
+	"github.com/alexwang789/Base1_golang_task3/outbox"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 打开内存 SQLite 库并建好用户、文章、评论及其钩子写入的表, 用于不依赖 MySQL 语法的单元测试
+func newTestDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开 SQLite 失败: %v", err)
+	}
+	// 内存库每个连接各自独立, 只用一个连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	err = db.AutoMigrate(&User{}, &Profile{}, &Follow{}, &Post{}, &Comment{}, &PostStat{}, &PostLike{}, &Notification{}, &outbox.Event{},
+		&ReadingProgress{}, &PostDiscoverWeight{}, &PostViewBucket{}, &TrendingScore{}, &Attachment{})
+	if err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	return db
+}
+
+// newTestUser 创建一个已验证邮箱的用户
+func newTestUser(t testing.TB, db *gorm.DB, name string) *User {
+	t.Helper()
+	user := &User{Name: name, Email: name + "@example.com", Password: "Passw0rd!", EmailVerified: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
+
+func assertArticleCount(t *testing.T, db *gorm.DB, userID uint, want int) {
+	t.Helper()
+	var user User
+	if err := db.Select("article_count").Take(&user, userID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.ArticleCount != want {
+		t.Errorf("用户 %d 的 article_count = %d, 期望 %d", userID, user.ArticleCount, want)
+	}
+}
+
+func assertCommentStatus(t *testing.T, db *gorm.DB, postID uint, want PostCommentStatus) {
+	t.Helper()
+	var post Post
+	if err := db.Select("comment_status").Take(&post, postID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if post.CommentStatus != want {
+		t.Errorf("文章 %d 的 comment_status = %s, 期望 %s", postID, post.CommentStatus, want)
+	}
+}
+
+func assertPostStats(t *testing.T, db *gorm.DB, postID uint, want int64) {
+	t.Helper()
+	var stat PostStat
+	if err := db.Take(&stat, postID).Error; err != nil {
+		t.Fatalf("查询文章 %d 的统计失败: %v", postID, err)
+	}
+	if stat.CommentCount != want {
+		t.Errorf("文章 %d 的 post_stats.comment_count = %d, 期望 %d", postID, stat.CommentCount, want)
+	}
+	if want == 0 && stat.LastCommentedAt != nil || want > 0 && stat.LastCommentedAt == nil {
+		t.Errorf("文章 %d 的 last_commented_at = %v, 与评论数 %d 不一致", postID, stat.LastCommentedAt, want)
+	}
+}
@@ -0,0 +1,119 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"gorm.io/gorm"
+)
+
+// TrendingScore 文章的热度分, 由 RefreshTrendingScores 定期整表重算, 首页按它排序
+type TrendingScore struct {
+	PostID    uint    `gorm:"primaryKey"`
+	Score     float64 `gorm:"not null;index"`
+	UpdatedAt time.Time
+}
+
+// 热度参数: 每次浏览、点赞、评论 (已通过审核) 各计一次分, 按发生时间以半衰期指数衰减.
+// 只统计 MaxViewWindow 内的事件, 更早的浏览桶已被清理; 只保留分数最高的 trendingKept 篇
+const (
+	trendingViewWeight    = 1.0
+	trendingLikeWeight    = 5.0
+	trendingCommentWeight = 10.0
+	trendingHalfLife      = 24 * time.Hour
+	trendingKept          = 1000
+)
+
+// sqlTrendingScores 汇总窗口内的浏览桶、点赞和评论. 三类事件分别沿
+// idx_post_view_buckets_hour、idx_post_likes_created_at、idx_comments_status_created 按时间范围读取.
+// 浏览桶以所在小时的起点计算衰减. 评论的表由 trendingScoresSQL 代入
+const sqlTrendingScores = `
+	SELECT post_id, SUM(score) AS score
+	FROM (
+		SELECT post_id, views * @view_weight * POW(2, -TIMESTAMPDIFF(SECOND, hour, @now) / @half_life) AS score
+		FROM post_view_buckets
+		WHERE hour >= @since
+		UNION ALL
+		SELECT post_id, @like_weight * POW(2, -TIMESTAMPDIFF(SECOND, created_at, @now) / @half_life)
+		FROM post_likes
+		WHERE created_at >= @since
+		UNION ALL
+		SELECT post_id, @comment_weight * POW(2, -TIMESTAMPDIFF(SECOND, created_at, @now) / @half_life)
+		FROM %s
+		WHERE status = @approved AND created_at >= @since
+	) AS events
+	GROUP BY post_id
+	ORDER BY score DESC, post_id DESC
+	LIMIT @kept
+`
+
+// trendingScoresSQL 代入评论表的 sqlTrendingScores, 分表后为全部分表
+func trendingScoresSQL() string {
+	return fmt.Sprintf(sqlTrendingScores, commentTable.From(""))
+}
+
+func trendingScoresArgs(now time.Time) map[string]any {
+	return map[string]any{
+		"now":            now,
+		"since":          now.Add(-MaxViewWindow),
+		"half_life":      trendingHalfLife.Seconds(),
+		"view_weight":    trendingViewWeight,
+		"like_weight":    trendingLikeWeight,
+		"comment_weight": trendingCommentWeight,
+		"approved":       CommentApproved,
+		"kept":           trendingKept,
+	}
+}
+
+// RefreshTrendingScores 重新计算热度分, 整表在一个事务中替换
+func RefreshTrendingScores(ctx context.Context, db *gorm.DB) error {
+	now := time.Now()
+	var scores []TrendingScore
+	if err := db.WithContext(ctx).Raw(trendingScoresSQL(), trendingScoresArgs(now)).Scan(&scores).Error; err != nil {
+		return fmt.Errorf("计算文章热度失败: %w", err)
+	}
+	for i := range scores {
+		scores[i].UpdatedAt = now
+	}
+
+	err := transaction(ctx, db, func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&TrendingScore{}).Error; err != nil {
+			return err
+		}
+		if len(scores) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&scores, DefaultBatchSize).Error
+	})
+	if err != nil {
+		return fmt.Errorf("写入文章热度失败: %w", err)
+	}
+	return nil
+}
+
+// TrendingPost 文章及其热度分
+type TrendingPost struct {
+	Post
+	Score float64
+}
+
+// 按热度分索引倒序读取, 执行计划检查 (queryplans.go) 也引用该语句
+const sqlTrending = `
+	SELECT posts.*, s.score
+	FROM trending_scores AS s
+	JOIN posts ON posts.id = s.post_id
+	WHERE posts.tenant_id = ? AND posts.status = ?
+	ORDER BY s.score DESC, s.post_id DESC
+	LIMIT ?
+`
+
+// GetTrending 按热度返回 ctx 所属租户的前 limit 篇文章, 用于首页. 结果反映最近一次 RefreshTrendingScores
+func GetTrending(ctx context.Context, db *gorm.DB, limit int) ([]TrendingPost, error) {
+	var posts []TrendingPost
+	if err := db.WithContext(ctx).Raw(sqlTrending, tenant.ID(ctx), PostPublished, limit).Scan(&posts).Error; err != nil {
+		return nil, fmt.Errorf("查询热门文章失败: %w", err)
+	}
+	return posts, nil
+}
@@ -0,0 +1,50 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertUserByEmail 按邮箱插入或更新用户: 邮箱已存在时更新姓名和密码, 保留文章数等统计字段.
+// 完成后 user 会被重新加载为库中的最新状态 (MySQL 更新时不会回填自增 ID).
+//
+// 注意 MySQL 的 ON DUPLICATE KEY 对任意唯一索引生效, 姓名与其他用户冲突时同样会走更新分支.
+func UpsertUserByEmail(ctx context.Context, db *gorm.DB, user *User) error {
+	if err := user.Validate(); err != nil {
+		return err
+	}
+	return transaction(ctx, db, func(tx *gorm.DB) error {
+		// 更新分支同样经过 Create, 跳过钩子, 只在真正插入时写入注册事件. 冲突按邮箱的盲索引判断,
+		// 跳过钩子时需要自己填写盲索引、主键和 UUID 键
+		if err := user.BeforeSave(tx); err != nil {
+			return err
+		}
+		if err := user.BeforeCreate(tx); err != nil {
+			return err
+		}
+		result := tx.Session(&gorm.Session{SkipHooks: true}).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "email_hash"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "password", "updated_at"}),
+		}).Create(user)
+		if result.Error != nil {
+			return fmt.Errorf("写入用户失败: %w", translateUserDuplicate(result.Error))
+		}
+
+		// 更新分支中 user.ID 可能是 BeforeCreate 生成但未写入的 ID, 不能作为查询条件
+		var stored User
+		if err := tx.Scopes(ByEmail(user.Email)).First(&stored).Error; err != nil {
+			return fmt.Errorf("重新加载用户失败: %w", err)
+		}
+		*user = stored
+		// MySQL 的 ON DUPLICATE KEY: 插入时影响 1 行, 更新时 2 行, 未变化时 0 行
+		if result.RowsAffected == 1 {
+			if err := addUserRegistered(tx, user); err != nil {
+				return err
+			}
+		}
+		return invalidateUserCacheAfterCommit(tx, user.ID)
+	})
+}
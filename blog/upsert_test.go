@@ -0,0 +1,91 @@
+package blog
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newMockDB 在 sqlmock 上打开 MySQL 方言的 GORM 连接, 用于依赖 MySQL 语义 (如 ON DUPLICATE KEY 的影响行数) 的测试
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		sqlDB.Close()
+	})
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func TestUpsertUserByEmail(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64 // ON DUPLICATE KEY 的影响行数: 插入 1, 更新 2, 未变化 0
+		registered   bool  // 是否写入注册事件
+	}{
+		{"新邮箱插入", 1, true},
+		{"已有邮箱更新", 2, false},
+		{"已有邮箱且未变化", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			user := &User{Name: "alice", Email: "Alice@Example.com", Password: "Passw0rd!"}
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`") + ".*" +
+				regexp.QuoteMeta("ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`password`=VALUES(`password`),`updated_at`=VALUES(`updated_at`)")).
+				WillReturnResult(sqlmock.NewResult(5, tt.rowsAffected))
+			// 重新加载按邮箱的盲索引查询, 更新分支保留库中的文章数等字段
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE email_hash = ? OR (email_hash IS NULL AND email = ?) ORDER BY `users`.`id` LIMIT ?")).
+				WithArgs(emailHash("alice@example.com"), "Alice@Example.com", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "article_count"}).
+					AddRow(5, "alice", "Alice@Example.com", 3))
+			if tt.registered {
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `outbox_events`")).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+			mock.ExpectCommit()
+
+			if err := UpsertUserByEmail(context.Background(), db, user); err != nil {
+				t.Fatal(err)
+			}
+			if user.ID != 5 || user.ArticleCount != 3 {
+				t.Errorf("user = {ID: %d, ArticleCount: %d}, 期望重新加载为库中的 {5, 3}", user.ID, user.ArticleCount)
+			}
+		})
+	}
+}
+
+func TestUpsertUserByEmailRollsBackOnError(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`")).WillReturnError(gorm.ErrInvalidDB)
+	mock.ExpectRollback()
+
+	err := UpsertUserByEmail(context.Background(), db, &User{Name: "alice", Email: "alice@example.com", Password: "Passw0rd!"})
+	if err == nil {
+		t.Fatal("写入失败时应返回错误")
+	}
+}
+
+func TestUpsertUserByEmailValidates(t *testing.T) {
+	db, _ := newMockDB(t) // 数据不合法时不执行任何语句
+	if err := UpsertUserByEmail(context.Background(), db, &User{Name: "alice"}); err == nil {
+		t.Fatal("邮箱为空时应返回校验错误")
+	}
+}
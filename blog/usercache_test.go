@@ -0,0 +1,125 @@
+package blog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"gorm.io/gorm"
+)
+
+// withUserCache 在测试期间启用用户缓存
+func withUserCache(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	EnableUserCache(db)
+	t.Cleanup(func() { userCache = nil })
+}
+
+// renameSkippingHooks 绕过钩子改名, 缓存不会失效, 用来判断读取是否走了缓存
+func renameSkippingHooks(t *testing.T, db *gorm.DB, id uint, name string) {
+	t.Helper()
+	if err := db.Session(&gorm.Session{SkipHooks: true}).Model(&User{}).Where("id = ?", id).Update("name", name).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUserCacheKeyedByTenant(t *testing.T) {
+	db := newTestDB(t)
+	withUserCache(t, db)
+	user := newTestUser(t, db, "alice")
+	users := NewUserRepository(db)
+	ctx := tenant.With(context.Background(), 1)
+
+	if _, err := users.GetByIDCached(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	renameSkippingHooks(t, db, user.ID, "bob")
+
+	got, err := users.GetByIDCached(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "alice" {
+		t.Errorf("租户 1 读到 %q, 期望缓存的 alice", got.Name)
+	}
+	// 另一个租户的同一 ID 不能命中租户 1 的缓存
+	got, err = users.GetByIDCached(tenant.With(context.Background(), 2), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "bob" {
+		t.Errorf("租户 2 读到 %q, 期望从数据库加载的 bob", got.Name)
+	}
+}
+
+func TestLoadAuthorsUsesUserCache(t *testing.T) {
+	db := newTestDB(t)
+	withUserCache(t, db)
+	user := newTestUser(t, db, "alice")
+	ctx := context.Background()
+
+	posts := []Post{{UserID: user.ID}, {UserID: user.ID}}
+	if err := LoadAuthors(ctx, db, posts); err != nil {
+		t.Fatal(err)
+	}
+	renameSkippingHooks(t, db, user.ID, "bob")
+
+	if err := LoadAuthors(ctx, db, posts); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range posts {
+		if p.User.Name != "alice" {
+			t.Errorf("作者 = %q, 期望缓存的 alice", p.User.Name)
+		}
+	}
+	got, err := NewUserRepository(db).GetByIDCached(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "alice" {
+		t.Errorf("GetByIDCached 读到 %q, 期望 LoadAuthors 写入缓存的 alice", got.Name)
+	}
+
+	// 不存在的作者留空
+	posts = []Post{{UserID: user.ID + 100}}
+	if err := LoadAuthors(ctx, db, posts); err != nil {
+		t.Fatal(err)
+	}
+	if posts[0].User.ID != 0 {
+		t.Errorf("不存在的作者 = %+v, 期望空", posts[0].User)
+	}
+}
+
+func TestUserCacheInvalidatedByHooks(t *testing.T) {
+	db := newTestDB(t)
+	withUserCache(t, db)
+	user := newTestUser(t, db, "alice")
+	users := NewUserRepository(db)
+	ctx := context.Background()
+
+	if _, err := users.GetByIDCached(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(user).Update("name", "bob").Error; err != nil {
+		t.Fatal(err)
+	}
+	got, err := users.GetByIDCached(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "bob" {
+		t.Errorf("更新用户后读到 %q, 期望 bob", got.Name)
+	}
+
+	// 缓存的用户带有资料, 保存资料也要失效
+	if err := SaveProfile(ctx, db, &Profile{UserID: user.ID, Bio: "hello"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err = users.GetByIDCached(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Profile == nil || got.Profile.Bio != "hello" {
+		t.Errorf("保存资料后读到资料 %+v, 期望 Bio 为 hello", got.Profile)
+	}
+}
@@ -0,0 +1,290 @@
+package blog
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/apikey"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 用户数据的导出和注销 (GDPR 的访问权和删除权).
+//
+// 注销先登记请求, 宽限期 (见 config.LoadErasureGracePeriod) 内用户可以撤销, 到期后由任务 EraseDueUsers 在一个事务中执行:
+//   - ErasureAnonymize: 保留文章和评论, 作者变为匿名的占位用户 (用户名、邮箱、密码替换, 主键和 UUID 不变, 链接仍然有效)
+//   - ErasureDelete: 删除其文章 (连同其他人在这些文章下的评论) 和评论, 然后删除用户
+//
+// 两种方式都删除资料、点赞、关注、通知、阅读进度、验证令牌和 API key. 审计日志按保留期清理 (见 retention 包)
+
+// ErasureMode 注销的方式
+type ErasureMode string
+
+const (
+	ErasureAnonymize ErasureMode = "anonymize" // 匿名化, 保留内容
+	ErasureDelete    ErasureMode = "delete"    // 删除内容
+)
+
+// Valid 是否为支持的注销方式
+func (m ErasureMode) Valid() bool {
+	return m == ErasureAnonymize || m == ErasureDelete
+}
+
+// ErrNoErasure 用户没有待执行的注销请求
+var ErrNoErasure = errors.New("没有待执行的注销请求")
+
+// erasureGrace 注销请求的宽限期, 由 Open 按 ERASURE_GRACE_PERIOD 设置
+var erasureGrace = 30 * 24 * time.Hour
+
+// UserErasure 待执行的注销请求, 每个用户最多一个, 执行或撤销后删除
+type UserErasure struct {
+	UserID      uint        `gorm:"primaryKey;autoIncrement:false"`
+	Mode        ErasureMode `gorm:"size:20;not null"`
+	RequestedAt time.Time   `gorm:"not null"`
+	EraseAt     time.Time   `gorm:"not null;index"` // 宽限期结束的时间, 之后由 EraseDueUsers 执行
+}
+
+// UserData 用户数据导出的内容, 只含用户自己产生的数据
+type UserData struct {
+	ExportedAt time.Time        `json:"exported_at"`
+	User       userRecord       `json:"user"`
+	Profile    *userDataProfile `json:"profile"` // 没有保存过资料时为 null
+	Posts      []userDataPost   `json:"posts"`
+	Comments   []commentRecord  `json:"comments"`
+	Likes      []userDataLike   `json:"likes"`
+	Erasure    *userDataErasure `json:"erasure"` // 待执行的注销请求, 没有时为 null
+}
+
+type userDataProfile struct {
+	Bio       string    `json:"bio"`
+	AvatarURL string    `json:"avatar_url"`
+	Website   string    `json:"website"`
+	Location  string    `json:"location"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type userDataPost struct {
+	ID         uint       `json:"id"`
+	Title      string     `json:"title"`
+	Content    string     `json:"content"`
+	Status     string     `json:"status"`
+	PublishAt  *time.Time `json:"publish_at"`
+	ArchivedAt *time.Time `json:"archived_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type userDataLike struct {
+	PostID    uint      `json:"post_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type userDataErasure struct {
+	Mode    ErasureMode `json:"mode"`
+	EraseAt time.Time   `json:"erase_at"`
+}
+
+// ExportUserData 导出用户的资料、文章、评论 (含未通过审核的) 和点赞, 用户不存在时返回 ErrUserNotFound.
+// 不含密码, 也不含其他人在其文章下的评论
+func ExportUserData(ctx context.Context, db *gorm.DB, userID uint) (*UserData, error) {
+	db = db.WithContext(ctx)
+	var user User
+	if err := db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	data := &UserData{
+		ExportedAt: time.Now(),
+		User:       userRecord{ID: user.ID, Name: user.Name, Email: user.Email, ArticleCount: user.ArticleCount, CreatedAt: user.CreatedAt},
+		Posts:      []userDataPost{},
+		Comments:   []commentRecord{},
+		Likes:      []userDataLike{},
+	}
+
+	var profiles []Profile
+	if err := db.Where("user_id = ?", userID).Limit(1).Find(&profiles).Error; err != nil {
+		return nil, fmt.Errorf("查询用户资料失败: %w", err)
+	}
+	if len(profiles) > 0 {
+		p := profiles[0]
+		data.Profile = &userDataProfile{Bio: p.Bio, AvatarURL: p.AvatarURL, Website: p.Website, Location: p.Location, UpdatedAt: p.UpdatedAt}
+	}
+
+	var posts []Post
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("查询用户的文章失败: %w", err)
+	}
+	for _, p := range posts {
+		data.Posts = append(data.Posts, userDataPost{
+			ID: p.ID, Title: p.Title, Content: p.Content, Status: string(p.Status),
+			PublishAt: p.PublishAt, ArchivedAt: p.ArchivedAt, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt,
+		})
+	}
+
+	var comments []Comment
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("查询用户的评论失败: %w", err)
+	}
+	for i := range comments {
+		data.Comments = append(data.Comments, toCommentRecord(&comments[i]))
+	}
+
+	var likes []PostLike
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&likes).Error; err != nil {
+		return nil, fmt.Errorf("查询用户的点赞失败: %w", err)
+	}
+	for _, l := range likes {
+		data.Likes = append(data.Likes, userDataLike{PostID: l.PostID, CreatedAt: l.CreatedAt})
+	}
+
+	var erasures []UserErasure
+	if err := db.Where("user_id = ?", userID).Limit(1).Find(&erasures).Error; err != nil {
+		return nil, fmt.Errorf("查询注销请求失败: %w", err)
+	}
+	if len(erasures) > 0 {
+		data.Erasure = &userDataErasure{Mode: erasures[0].Mode, EraseAt: erasures[0].EraseAt}
+	}
+	return data, nil
+}
+
+// RequestErasure 登记用户的注销请求, 宽限期后执行; 已有请求时改为新的方式并重新计算宽限期
+func RequestErasure(ctx context.Context, db *gorm.DB, userID uint, mode ErasureMode) (*UserErasure, error) {
+	if !mode.Valid() {
+		return nil, fmt.Errorf("不支持的注销方式 %q", mode)
+	}
+	now := time.Now()
+	e := &UserErasure{UserID: userID, Mode: mode, RequestedAt: now, EraseAt: now.Add(erasureGrace)}
+	err := db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(e).Error
+	if err != nil {
+		return nil, fmt.Errorf("登记注销请求失败: %w", err)
+	}
+	return e, nil
+}
+
+// CancelErasure 撤销用户待执行的注销请求, 没有时返回 ErrNoErasure
+func CancelErasure(ctx context.Context, db *gorm.DB, userID uint) error {
+	result := db.WithContext(ctx).Delete(&UserErasure{UserID: userID})
+	if result.Error != nil {
+		return fmt.Errorf("撤销注销请求失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoErasure
+	}
+	return nil
+}
+
+// EraseDueUsers 执行宽限期已过的注销请求, 每个用户在各自的事务中执行, 返回注销的用户数
+func EraseDueUsers(ctx context.Context, db *gorm.DB) (int, error) {
+	var due []UserErasure
+	if err := db.WithContext(ctx).Where("erase_at <= ?", time.Now()).Order("erase_at").Find(&due).Error; err != nil {
+		return 0, fmt.Errorf("查询到期的注销请求失败: %w", err)
+	}
+	erased := 0
+	var errs []error
+	for _, e := range due {
+		if err := EraseUser(ctx, db, e.UserID, e.Mode); err != nil {
+			errs = append(errs, fmt.Errorf("注销用户 %d 失败: %w", e.UserID, err))
+			continue
+		}
+		erased++
+	}
+	return erased, errors.Join(errs...)
+}
+
+// EraseUser 立即按 mode 注销用户, 全部改动在一个事务中, 同时删除其注销请求. 用户不存在时返回 ErrUserNotFound
+func EraseUser(ctx context.Context, db *gorm.DB, userID uint, mode ErasureMode) error {
+	if !mode.Valid() {
+		return fmt.Errorf("不支持的注销方式 %q", mode)
+	}
+	return transaction(ctx, db, func(tx *gorm.DB) error {
+		var user User
+		err := WithRowLock(tx, &user, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if mode == ErasureDelete {
+			if err := deleteUserContent(tx, userID); err != nil {
+				return err
+			}
+		}
+
+		// 取消点赞以同步文章统计并撤回通知
+		var likes []PostLike
+		if err := tx.Where("user_id = ?", userID).Find(&likes).Error; err != nil {
+			return fmt.Errorf("查询用户的点赞失败: %w", err)
+		}
+		for _, l := range likes {
+			if err := UnlikePost(ctx, tx, userID, l.PostID); err != nil {
+				return err
+			}
+		}
+		for _, del := range []struct {
+			model any
+			where string
+		}{
+			{&Profile{}, "user_id = ?"},
+			{&Follow{}, "follower_id = ? OR followee_id = ?"},
+			{&Notification{}, "user_id = ? OR actor_id = ?"},
+			{&ReadingProgress{}, "user_id = ?"},
+			{&EmailVerification{}, "user_id = ?"},
+			{&apikey.Key{}, "user_id = ?"},
+			{&UserErasure{}, "user_id = ?"},
+		} {
+			args := []any{userID}
+			if del.where != "user_id = ?" {
+				args = append(args, userID)
+			}
+			if err := tx.Where(del.where, args...).Delete(del.model).Error; err != nil {
+				return fmt.Errorf("删除用户 %d 的数据失败: %w", userID, err)
+			}
+		}
+
+		if mode == ErasureDelete {
+			if err := tx.Delete(&user).Error; err != nil {
+				return fmt.Errorf("删除用户失败: %w", err)
+			}
+			return nil
+		}
+		user.Name = fmt.Sprintf("已注销用户%d", user.ID)
+		user.Email = fmt.Sprintf("erased-%d@invalid", user.ID)
+		user.Password = rand.Text() // 随机的密码, 保存时哈希且不保留明文, 无法再登录
+		user.EmailVerified = false
+		user.IsAdmin = false
+		if err := tx.Save(&user).Error; err != nil {
+			return fmt.Errorf("匿名化用户失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// deleteUserContent 逐条删除用户的评论和文章, 经过钩子维护文章的评论状态、统计和作者的文章数
+func deleteUserContent(tx *gorm.DB, userID uint) error {
+	var comments []Comment
+	if err := tx.Where("user_id = ?", userID).Order("id DESC").Find(&comments).Error; err != nil {
+		return fmt.Errorf("查询用户的评论失败: %w", err)
+	}
+	for i := range comments {
+		if err := tx.Delete(&comments[i]).Error; err != nil {
+			return fmt.Errorf("删除评论 %d 失败: %w", comments[i].ID, err)
+		}
+	}
+	var posts []Post
+	if err := tx.Where("user_id = ?", userID).Find(&posts).Error; err != nil {
+		return fmt.Errorf("查询用户的文章失败: %w", err)
+	}
+	for i := range posts {
+		if err := tx.Delete(&posts[i]).Error; err != nil {
+			return fmt.Errorf("删除文章 %d 失败: %w", posts[i].ID, err)
+		}
+	}
+	return nil
+}
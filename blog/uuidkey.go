@@ -0,0 +1,87 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"uuid"
+
+	"gorm.io/gorm"
+)
+
+// UUID 键: 开启后 (UUID_KEYS, 见 config.LoadUUIDKeys) 用户、文章和评论在创建时生成随机的 UUID,
+// 对外接口可以用它代替由自增主键编码的 ID, 无法从一个 ID 推出其他记录的 ID.
+//
+// UUID 是唯一的备用键, 自增主键和外键保持不变: 关联、连接和游标分页仍走整数键, 索引也不会因随机主键而分裂.
+// 使用 v7 (时间有序), 写入唯一索引时基本是追加. 开启前创建的记录由迁移补全 (见 backfillUUIDs)
+var uuidKeys bool
+
+// UUIDKeysEnabled 是否开启了 UUID 键
+func UUIDKeysEnabled() bool {
+	return uuidKeys
+}
+
+// 补全 UUID 时每批更新的记录数
+const uuidBackfillBatch = 500
+
+// assignUUID 开启 UUID 键时为尚未设置的 key 生成 UUID
+func assignUUID(key **string) {
+	if uuidKeys && *key == nil {
+		s := uuid.NewV7().String()
+		*key = &s
+	}
+}
+
+// FindIDByUUID 按 UUID 查找 model (&User{}、&Post{} 或 &Comment{}) 的自增 ID, 不存在时返回 gorm.ErrRecordNotFound
+func FindIDByUUID(ctx context.Context, db *gorm.DB, model any, key string) (uint, error) {
+	u, err := uuid.Parse(key)
+	if err != nil {
+		return 0, gorm.ErrRecordNotFound
+	}
+	var ids []uint
+	if err := db.WithContext(ctx).Model(model).Where("uuid = ?", u.String()).Limit(1).Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("按 UUID 查询失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return ids[0], nil
+}
+
+// IsUUID s 是否为 UUID 格式, 用于区分 UUID 和编码后的 ID
+func IsUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil
+}
+
+// backfillUUIDs 开启 UUID 键时为之前创建的记录补全 UUID, 分批更新, 不改变 updated_at
+func backfillUUIDs(db *gorm.DB) error {
+	if !uuidKeys {
+		return nil
+	}
+	// 按表名逐张更新, 评论分表后逐张分表更新
+	for _, table := range append([]string{"users", "posts"}, commentTable.Names()...) {
+		for {
+			var ids []uint
+			if err := db.Table(table).Where("uuid IS NULL").Order("id").Limit(uuidBackfillBatch).Pluck("id", &ids).Error; err != nil {
+				return fmt.Errorf("查询缺少 UUID 的记录失败: %w", err)
+			}
+			if len(ids) == 0 {
+				break
+			}
+			var sql strings.Builder
+			args := make([]any, 0, 2*len(ids))
+			sql.WriteString("CASE id")
+			for _, id := range ids {
+				sql.WriteString(" WHEN ? THEN ?")
+				args = append(args, id, uuid.NewV7().String())
+			}
+			sql.WriteString(" END")
+			err := db.Table(table).Where("id IN ? AND uuid IS NULL", ids).UpdateColumn("uuid", gorm.Expr(sql.String(), args...)).Error
+			if err != nil {
+				return fmt.Errorf("补全 UUID 失败: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package blog
+
+import "testing"
+
+// withUUIDKeys 在测试期间开启 UUID 键
+func withUUIDKeys(t *testing.T) {
+	t.Helper()
+	uuidKeys = true
+	t.Cleanup(func() { uuidKeys = false })
+}
+
+func TestUserCreateAssignsUUID(t *testing.T) {
+	withUUIDKeys(t)
+	db := newTestDB(t)
+
+	user := newTestUser(t, db, "alice")
+	if user.UUID == nil || !IsUUID(*user.UUID) {
+		t.Fatalf("UUID = %v, want 生成的 UUID", user.UUID)
+	}
+	id, err := FindIDByUUID(t.Context(), db, &User{}, *user.UUID)
+	if err != nil || id != user.ID {
+		t.Errorf("FindIDByUUID = %d, %v, want %d", id, err, user.ID)
+	}
+}
+
+// 对空模型的 Update 只执行 BeforeSave, 不能为它生成 UUID
+func TestUpdateOnEmptyUserModelKeepsUUID(t *testing.T) {
+	withUUIDKeys(t)
+	db := newTestDB(t)
+	user := newTestUser(t, db, "alice")
+
+	model := &User{}
+	if err := db.Model(model).Where("id = ?", user.ID).Update("name", "bob").Error; err != nil {
+		t.Fatal(err)
+	}
+	if model.UUID != nil {
+		t.Errorf("空模型被生成了 UUID %s", *model.UUID)
+	}
+
+	var got User
+	if err := db.First(&got, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.UUID == nil || *got.UUID != *user.UUID {
+		t.Errorf("UUID 变为 %v, want %s", got.UUID, *user.UUID)
+	}
+}
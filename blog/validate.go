@@ -0,0 +1,42 @@
+package blog
+
+import (
+	"github.com/alexwang789/Base1_golang_task3/validate"
+)
+
+// 密码的最小长度
+const minPasswordLen = 6
+
+// Validate 校验用户的姓名、邮箱和密码强度, 失败时返回 validate.Errors
+func (u *User) Validate() error {
+	errs := validate.Errors{}
+	errs.Check(validate.NotBlank(u.Name), "name", "姓名不能为空")
+	errs.Check(validate.MaxLen(u.Name, 100), "name", "姓名不能超过 100 个字符")
+	errs.Check(validate.Email(u.Email), "email", "邮箱格式不正确")
+	errs.Check(validate.MaxLen(u.Email, 100), "email", "邮箱不能超过 100 个字符")
+	errs.Check(validate.StrongPassword(u.Password, minPasswordLen), "password", "密码至少 6 位且需同时包含字母和数字")
+	errs.Check(len(u.Password) <= maxPasswordBytes, "password", "密码不能超过 72 个字节")
+	return errs.Err()
+}
+
+// Validate 校验文章的标题、内容、作者和状态, 失败时返回 validate.Errors. 状态为空时由数据库默认值或钩子确定
+func (p *Post) Validate() error {
+	errs := validate.Errors{}
+	errs.Check(validate.NotBlank(p.Title), "title", "标题不能为空")
+	errs.Check(validate.MaxLen(p.Title, 200), "title", "标题不能超过 200 个字符")
+	errs.Check(validate.NotBlank(p.Content), "content", "内容不能为空")
+	errs.Check(p.UserID != 0, "user_id", "缺少作者")
+	errs.Check(p.Status == "" || p.Status.Valid(), "status", "发布状态不正确")
+	errs.Check(p.CommentStatus == "" || p.CommentStatus.Valid(), "comment_status", "评论状态不正确")
+	return errs.Err()
+}
+
+// Validate 校验评论的内容、所属文章、作者和审核状态, 失败时返回 validate.Errors
+func (c *Comment) Validate() error {
+	errs := validate.Errors{}
+	errs.Check(validate.NotBlank(c.Content), "content", "内容不能为空")
+	errs.Check(c.PostID != 0, "post_id", "缺少所属文章")
+	errs.Check(c.UserID != 0, "user_id", "缺少作者")
+	errs.Check(c.Status == "" || c.Status.Valid(), "status", "审核状态不正确")
+	return errs.Err()
+}
@@ -0,0 +1,148 @@
+package blog
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/emailqueue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EmailVerification 注册邮箱的验证令牌, 每个用户只保留最近发出的一个. 库中只存令牌的 SHA-256, 令牌本身只出现在邮件中
+type EmailVerification struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;uniqueIndex"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null;index"` // 过期的令牌由 PruneEmailVerifications 清理
+	CreatedAt time.Time
+}
+
+var (
+	// ErrEmailNotVerified 用户的邮箱尚未验证, 只能浏览, 不能发表文章和评论
+	ErrEmailNotVerified = errors.New("邮箱尚未验证")
+	// ErrEmailAlreadyVerified 邮箱已验证, 不需要重新发送验证邮件
+	ErrEmailAlreadyVerified = errors.New("邮箱已验证")
+	// ErrVerificationToken 验证令牌不存在、已被使用或已过期
+	ErrVerificationToken = errors.New("验证链接无效或已过期")
+)
+
+// VerificationOptions 验证邮件的选项
+type VerificationOptions struct {
+	TTL time.Duration // 令牌的有效期
+	URL string        // 验证页面的地址, 邮件中的链接为 URL?token=<令牌>; 为空时邮件中只给出令牌
+}
+
+// 验证邮件的选项, 由 ConfigureEmailVerification 设置
+var verificationOptions = VerificationOptions{TTL: 24 * time.Hour}
+
+// ConfigureEmailVerification 设置验证邮件的选项, TTL 不大于 0 时保留默认的 24 小时
+func ConfigureEmailVerification(opts VerificationOptions) {
+	if opts.TTL <= 0 {
+		opts.TTL = verificationOptions.TTL
+	}
+	verificationOptions = opts
+}
+
+// sendVerification 为 user 生成新的验证令牌 (之前发出的随之失效), 并在 tx 中写入验证邮件
+func sendVerification(tx *gorm.DB, user *User, subject, greeting string) error {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("生成验证令牌失败: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	v := EmailVerification{UserID: user.ID, TokenHash: hashVerificationToken(token), ExpiresAt: time.Now().Add(verificationOptions.TTL)}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token_hash", "expires_at", "created_at"}),
+	}).Create(&v).Error
+	if err != nil {
+		return fmt.Errorf("保存验证令牌失败: %w", err)
+	}
+
+	link := "验证令牌: " + token
+	if verificationOptions.URL != "" {
+		link = "验证链接: " + verificationOptions.URL + "?token=" + url.QueryEscape(token)
+	}
+	return emailqueue.Enqueue(tx, emailqueue.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf("%s, 你好! %s请在 %s 内完成邮箱验证, 验证前只能浏览, 不能发表文章和评论.\n%s", user.Name, greeting, verificationOptions.TTL, link),
+	})
+}
+
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ResendVerification 重新发送验证邮件, 之前的令牌失效. 邮箱已验证时返回 ErrEmailAlreadyVerified
+func ResendVerification(ctx context.Context, db *gorm.DB, user *User) error {
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return sendVerification(tx, user, "验证你的邮箱", "")
+	})
+}
+
+// VerifyEmail 用邮件中的令牌验证邮箱, 令牌只能使用一次. 令牌无效或已过期时返回 ErrVerificationToken
+func VerifyEmail(ctx context.Context, db *gorm.DB, token string) error {
+	return transaction(ctx, db, func(tx *gorm.DB) error {
+		var v EmailVerification
+		err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+			Where("token_hash = ? AND expires_at > ?", hashVerificationToken(token), time.Now()).
+			Take(&v).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVerificationToken
+		}
+		if err != nil {
+			return fmt.Errorf("查询验证令牌失败: %w", err)
+		}
+		result := tx.Model(&User{}).Where("id = ?", v.UserID).Update("email_verified", true)
+		if result.Error != nil {
+			return fmt.Errorf("更新邮箱验证状态失败: %w", result.Error)
+		}
+		// 用户在其他租户 (令牌从其他站点的链接打开) 或已被删除
+		if result.RowsAffected == 0 {
+			return ErrVerificationToken
+		}
+		if err := tx.Delete(&v).Error; err != nil {
+			return fmt.Errorf("删除验证令牌失败: %w", err)
+		}
+		return invalidateUserCacheAfterCommit(tx, v.UserID)
+	})
+}
+
+// requireVerified 用户的邮箱已验证时返回 nil, 否则返回 ErrEmailNotVerified; 用户不存在时返回 ErrUserNotFound
+func requireVerified(ctx context.Context, db *gorm.DB, userID uint) error {
+	var verified []bool
+	err := db.WithContext(ctx).Model(&User{}).Where("id = ?", userID).Limit(1).Pluck("email_verified", &verified).Error
+	if err != nil {
+		return fmt.Errorf("查询邮箱验证状态失败: %w", err)
+	}
+	if len(verified) == 0 {
+		return ErrUserNotFound
+	}
+	if !verified[0] {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// PruneEmailVerifications 删除过期的验证令牌, 返回删除的行数. 用户需要重新发送验证邮件
+func PruneEmailVerifications(ctx context.Context, db *gorm.DB) (int64, error) {
+	result := db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&EmailVerification{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("清理过期的验证令牌失败: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
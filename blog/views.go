@@ -0,0 +1,213 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"github.com/alexwang789/Base1_golang_task3/tenantdb"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostViewBucket 文章每小时的浏览数, 用于按滑动窗口统计浏览最多的文章 (见 MostViewedPosts) 和计算热度 (见 RefreshTrendingScores).
+// 早于 MaxViewWindow 的桶由定期任务清理 (见 PrunePostViews)
+type PostViewBucket struct {
+	PostID uint      `gorm:"primaryKey;index:idx_post_view_buckets_hour,priority:2"`
+	Hour   time.Time `gorm:"primaryKey;index:idx_post_view_buckets_hour,priority:1"` // 所在小时的起点
+	Views  uint64    `gorm:"not null;index:idx_post_view_buckets_hour,priority:3"`
+}
+
+type viewKey struct {
+	db     uint // 写入的库, 见 tenantdb.Target
+	postID uint
+	hour   time.Time
+}
+
+// ViewCounter 在内存中累计文章浏览数, 每隔 interval 或累计 threshold 次浏览后批量写入:
+// posts.view_count 累加总数, post_view_buckets 累加每小时的数量. 进程退出前需要调用 Flush,
+// 异常退出时最多丢失一个刷新周期的计数
+type ViewCounter struct {
+	db        *gorm.DB
+	interval  time.Duration
+	threshold int
+
+	mu      sync.Mutex
+	pending map[viewKey]uint64
+	total   int           // pending 中的浏览次数
+	full    chan struct{} // 达到 threshold 时通知 Run 提前刷新
+}
+
+// NewViewCounter 创建浏览计数器, 需要调用 Run 定期写入. threshold <= 0 时只按时间刷新
+func NewViewCounter(db *gorm.DB, interval time.Duration, threshold int) *ViewCounter {
+	return &ViewCounter{
+		db:        db,
+		interval:  interval,
+		threshold: threshold,
+		pending:   make(map[viewKey]uint64),
+		full:      make(chan struct{}, 1),
+	}
+}
+
+// Record 记一次浏览, 刷新时写入 ctx 对应的库
+func (c *ViewCounter) Record(ctx context.Context, postID uint) {
+	key := viewKey{tenantdb.Target(ctx), postID, time.Now().Truncate(time.Hour)}
+	c.mu.Lock()
+	c.pending[key]++
+	c.total++
+	full := c.threshold > 0 && c.total >= c.threshold
+	c.mu.Unlock()
+
+	if full {
+		select {
+		case c.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush 把累计的浏览数按库分别在一个事务中写入, 失败的放回等待下次重试
+func (c *ViewCounter) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	if len(c.pending) == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	batches := make(map[uint]map[viewKey]uint64)
+	for key, n := range c.pending {
+		if batches[key.db] == nil {
+			batches[key.db] = make(map[viewKey]uint64)
+		}
+		batches[key.db][key] = n
+	}
+	c.pending, c.total = make(map[viewKey]uint64), 0
+	c.mu.Unlock()
+
+	var errs []error
+	for target, batch := range batches {
+		err := transaction(tenantdb.Route(ctx, target), c.db, func(tx *gorm.DB) error {
+			return writeViews(tx, batch)
+		})
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		c.mu.Lock()
+		for key, n := range batch {
+			c.pending[key] += n
+			c.total += int(n)
+		}
+		c.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// writeViews 累加每小时的浏览数和文章的总浏览数.
+// view_count 用原生 SQL 更新, 不经过 GORM 回调, 浏览计数不产生审计日志
+func writeViews(tx *gorm.DB, batch map[viewKey]uint64) error {
+	buckets := make([]PostViewBucket, 0, len(batch))
+	perPost := make(map[uint]uint64)
+	for key, n := range batch {
+		buckets = append(buckets, PostViewBucket{PostID: key.postID, Hour: key.hour, Views: n})
+		perPost[key.postID] += n
+	}
+
+	err := tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{"views": gorm.Expr("views + VALUES(views)")}),
+	}).CreateInBatches(&buckets, DefaultBatchSize).Error
+	if err != nil {
+		return fmt.Errorf("写入文章浏览数失败: %w", err)
+	}
+
+	var (
+		cases strings.Builder
+		args  []any
+		ids   []uint
+	)
+	for id, n := range perPost {
+		cases.WriteString(" WHEN ? THEN ?")
+		args = append(args, id, n)
+		ids = append(ids, id)
+	}
+	err = tx.Exec("UPDATE posts SET view_count = view_count + CASE id"+cases.String()+" END WHERE id IN ?", append(args, ids)...).Error
+	if err != nil {
+		return fmt.Errorf("更新文章浏览数失败: %w", err)
+	}
+	return nil
+}
+
+// Run 每隔 interval 或累计到 threshold 次浏览时刷新, 直到 ctx 取消, 退出前做最后一次刷新
+func (c *ViewCounter) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := c.Flush(context.WithoutCancel(ctx)); err != nil {
+				log.Print(err)
+			}
+			return
+		case <-ticker.C:
+		case <-c.full:
+		}
+		if err := c.Flush(ctx); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// MaxViewWindow 浏览统计窗口的上限, 也是浏览桶的保留时间
+const MaxViewWindow = 7 * 24 * time.Hour
+
+// MostViewedPost 文章及其在窗口内的浏览数
+type MostViewedPost struct {
+	Post
+	WindowViews uint64
+}
+
+// sqlMostViewedPosts 沿 idx_post_view_buckets_hour (hour, post_id, views) 只读窗口内的桶, 不回表;
+// 先在桶上聚合出前 n 篇再关联文章, 已删除的文章被 JOIN 过滤, 已归档的文章被 WHERE 过滤, 因此结果可能不足 n 篇
+const sqlMostViewedPosts = `
+	SELECT posts.*, t.window_views
+	FROM (
+		SELECT b.post_id, SUM(b.views) AS window_views
+		FROM post_view_buckets AS b
+		JOIN posts ON posts.id = b.post_id AND posts.tenant_id = ?
+		WHERE b.hour >= ?
+		GROUP BY b.post_id
+		ORDER BY window_views DESC, post_id DESC
+		LIMIT ?
+	) AS t
+	JOIN posts ON posts.id = t.post_id
+	WHERE posts.status = ?
+	ORDER BY t.window_views DESC, t.post_id DESC
+`
+
+// MostViewedPosts 按最近 window 内的浏览数倒序返回 ctx 所属租户的前 n 篇文章. 窗口以小时为粒度向前取整,
+// 即包含 now-window 所在的整个小时; window 超过 MaxViewWindow 时按上限计算.
+// 只统计已经刷新写入的浏览
+func MostViewedPosts(ctx context.Context, db *gorm.DB, window time.Duration, n int) ([]MostViewedPost, error) {
+	window = min(window, MaxViewWindow)
+	since := time.Now().Add(-window).Truncate(time.Hour)
+
+	var posts []MostViewedPost
+	if err := db.WithContext(ctx).Raw(sqlMostViewedPosts, tenant.ID(ctx), since, n, PostPublished).Scan(&posts).Error; err != nil {
+		return nil, fmt.Errorf("查询浏览最多的文章失败: %w", err)
+	}
+	return posts, nil
+}
+
+// PrunePostViews 删除早于 MaxViewWindow 的浏览桶, 返回删除的行数
+func PrunePostViews(ctx context.Context, db *gorm.DB) (int64, error) {
+	before := time.Now().Add(-MaxViewWindow).Truncate(time.Hour)
+	result := db.WithContext(ctx).Where("hour < ?", before).Delete(&PostViewBucket{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("清理文章浏览桶失败: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,62 @@
+// Package cdc 变更数据捕获 (change data capture): 把指定表上每一行的创建、更新和删除作为变更事件发布给下游
+// (搜索索引、数据分析等), 下游不必轮询或直接读业务库.
+//
+// Plugin 在写入所在的事务中把变更写入发件箱 (见 outbox 包), 事件类型为 cdc.<表名>, 与业务数据一起提交或回滚;
+// outbox.Relay 投递时由 Sink 交给 Producer 发布到 Kafka. 每张表一个 topic, 消息键为 <表名>:<主键>,
+// 同一行的变更进入同一分区并保持写入顺序. 投递至少一次, 下游按 event_id 去重.
+//
+// 消息体为 Change, 行的内容只含 Table.Columns 中的列; 列有不兼容的变化时增加 Table.Version, 下游按 schema 和 version 解析.
+// 与审计插件相同, 原生 SQL (db.Exec) 不经过 GORM 回调, 其写入不会被捕获.
+package cdc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Op 变更的类型
+type Op string
+
+const (
+	OpCreate Op = "c"
+	OpUpdate Op = "u"
+	OpDelete Op = "d"
+)
+
+// Change 一行的一次变更, 即发布到 Kafka 的消息体
+type Change struct {
+	EventID uint64         `json:"event_id"` // 发件箱事件 ID, 投递时填入, 下游据此去重
+	Schema  string         `json:"schema"`   // 表名
+	Version int            `json:"version"`  // 行结构的版本, 见 Table.Version
+	Op      Op             `json:"op"`
+	ID      uint64         `json:"id"`     // 行的主键
+	Before  map[string]any `json:"before"` // 变更前的行, 创建时为 null
+	After   map[string]any `json:"after"`  // 变更后的行, 删除时为 null
+	TS      time.Time      `json:"ts"`     // 写入的时间
+}
+
+// Table 一张捕获变更的表
+type Table struct {
+	Name    string
+	Version int      // 行结构的版本, 删除、改名或改变类型等不兼容的变化时加一; 只增加列时不必
+	Columns []string // 发布的列, 须包含主键 id. 其他列 (如密码、频繁变化的计数) 不发布, 只有它们变化时也不产生事件
+}
+
+// 变更事件类型的前缀
+const eventPrefix = "cdc."
+
+// EventType 表 table 的变更在发件箱中的事件类型
+func EventType(table string) string {
+	return eventPrefix + table
+}
+
+// IsChange 发件箱中的事件类型是否为行变更
+func IsChange(eventType string) bool {
+	return strings.HasPrefix(eventType, eventPrefix)
+}
+
+// key 行在发件箱和 Kafka 中的键
+func key(table string, id uint64) string {
+	return fmt.Sprintf("%s:%d", table, id)
+}
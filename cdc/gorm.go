@@ -0,0 +1,238 @@
+package cdc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/outbox"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Plugin 把指定表上经 GORM Create/Update/Delete 的写入作为变更写入发件箱. 更新和删除前先按同样的条件读出受影响的行,
+// 写入后再按主键读出新值, 发布的列都没有变化的更新不产生事件
+type Plugin struct {
+	tables map[string]Table
+}
+
+// NewPlugin 创建插件, 通过 db.Use 注册. 表须有单列整数主键
+func NewPlugin(tables ...Table) *Plugin {
+	p := &Plugin{tables: map[string]Table{}}
+	for _, t := range tables {
+		p.tables[t.Name] = t
+	}
+	return p
+}
+
+// Name 实现 gorm.Plugin
+func (p *Plugin) Name() string {
+	return "cdc"
+}
+
+const beforeSetting = "cdc:before"
+
+// Initialize 实现 gorm.Plugin
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	errs := []error{
+		cb.Create().After("gorm:create").Register("cdc:create", p.afterCreate),
+		cb.Update().Before("gorm:update").Register("cdc:before_update", p.snapshot),
+		cb.Update().After("gorm:update").Register("cdc:update", p.afterUpdate),
+		cb.Delete().Before("gorm:delete").Register("cdc:before_delete", p.snapshot),
+		cb.Delete().After("gorm:delete").Register("cdc:delete", p.afterDelete),
+	}
+	return errors.Join(errs...)
+}
+
+// table 语句写入的表的配置, 不捕获变更时返回 false
+func (p *Plugin) table(db *gorm.DB) (Table, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.Schema.PrioritizedPrimaryField == nil {
+		return Table{}, false
+	}
+	t, ok := p.tables[stmt.Table]
+	return t, ok
+}
+
+func (p *Plugin) afterCreate(db *gorm.DB) {
+	t, ok := p.table(db)
+	if !ok || db.RowsAffected == 0 {
+		return
+	}
+	after, err := load(db, t, primaryKeys(db))
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	for _, id := range slices.Sorted(maps.Keys(after)) {
+		if err := add(db, t, OpCreate, id, nil, after[id]); err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+}
+
+// snapshot 在更新或删除前读出将受影响的行
+func (p *Plugin) snapshot(db *gorm.DB) {
+	t, ok := p.table(db)
+	if !ok {
+		return
+	}
+	stmt := db.Statement
+	q := db.Session(&gorm.Session{NewDB: true}).Table(t.Name).Select(t.Columns)
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			q = q.Clauses(where)
+		}
+	}
+	// 主键非零的模型 (如 Model(&post).Update) 在 gorm:update/gorm:delete 中才追加主键条件
+	if ids := primaryKeys(db); len(ids) > 0 {
+		q = q.Where(clause.IN{Column: clause.Column{Name: stmt.Schema.PrioritizedPrimaryField.DBName}, Values: ids})
+	}
+
+	var rows []map[string]any
+	if err := q.Find(&rows).Error; err != nil {
+		db.AddError(fmt.Errorf("读取变更前的数据失败: %w", err))
+		return
+	}
+	before, err := byID(db, rows)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(beforeSetting, before)
+}
+
+func (p *Plugin) afterUpdate(db *gorm.DB) {
+	t, ok := p.table(db)
+	before := snapshotOf(db)
+	if !ok || len(before) == 0 {
+		return
+	}
+	ids := slices.Sorted(maps.Keys(before))
+	after, err := load(db, t, toAny(ids))
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	for _, id := range ids {
+		if after[id] == nil {
+			continue
+		}
+		changed, err := differ(before[id], after[id])
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		if !changed {
+			continue
+		}
+		if err := add(db, t, OpUpdate, id, before[id], after[id]); err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+}
+
+func (p *Plugin) afterDelete(db *gorm.DB) {
+	t, ok := p.table(db)
+	before := snapshotOf(db)
+	if !ok || db.RowsAffected == 0 {
+		return
+	}
+	for _, id := range slices.Sorted(maps.Keys(before)) {
+		if err := add(db, t, OpDelete, id, before[id], nil); err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+}
+
+// add 在当前事务中把一行的变更写入发件箱
+func add(db *gorm.DB, t Table, op Op, id uint64, before, after map[string]any) error {
+	change := Change{Schema: t.Name, Version: t.Version, Op: op, ID: id, Before: before, After: after, TS: time.Now()}
+	return outbox.Add(db.Session(&gorm.Session{NewDB: true, SkipHooks: true}), EventType(t.Name), key(t.Name, id), change)
+}
+
+func snapshotOf(db *gorm.DB) map[uint64]map[string]any {
+	v, _ := db.InstanceGet(beforeSetting)
+	before, _ := v.(map[uint64]map[string]any)
+	return before
+}
+
+// primaryKeys 返回语句模型 (单个或切片) 中非零的主键值
+func primaryKeys(db *gorm.DB) []any {
+	stmt := db.Statement
+	field := stmt.Schema.PrioritizedPrimaryField
+	rv := reflect.Indirect(stmt.ReflectValue)
+
+	var ids []any
+	switch rv.Kind() {
+	case reflect.Struct:
+		if v, zero := field.ValueOf(stmt.Context, rv); !zero {
+			ids = append(ids, v)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range rv.Len() {
+			if v, zero := field.ValueOf(stmt.Context, reflect.Indirect(rv.Index(i))); !zero {
+				ids = append(ids, v)
+			}
+		}
+	}
+	return ids
+}
+
+// load 在当前事务中按主键读出行的发布列
+func load(db *gorm.DB, t Table, ids []any) (map[uint64]map[string]any, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var rows []map[string]any
+	err := db.Session(&gorm.Session{NewDB: true}).Table(t.Name).Select(t.Columns).
+		Where(clause.IN{Column: clause.Column{Name: db.Statement.Schema.PrioritizedPrimaryField.DBName}, Values: ids}).
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("读取变更后的数据失败: %w", err)
+	}
+	return byID(db, rows)
+}
+
+func byID(db *gorm.DB, rows []map[string]any) (map[uint64]map[string]any, error) {
+	pk := db.Statement.Schema.PrioritizedPrimaryField.DBName
+	m := make(map[uint64]map[string]any, len(rows))
+	for _, row := range rows {
+		id, err := strconv.ParseUint(fmt.Sprint(row[pk]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s 的主键 %v 不是整数", db.Statement.Table, row[pk])
+		}
+		m[id] = row
+	}
+	return m, nil
+}
+
+func toAny(ids []uint64) []any {
+	values := make([]any, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+	return values
+}
+
+// differ 按 JSON 表示比较, 时间等类型的内部表示 (时区、单调时钟) 不同但取值相同时视为相等
+func differ(before, after map[string]any) (bool, error) {
+	b, err := json.Marshal(before)
+	if err != nil {
+		return false, fmt.Errorf("序列化变更前的数据失败: %w", err)
+	}
+	a, err := json.Marshal(after)
+	if err != nil {
+		return false, fmt.Errorf("序列化变更后的数据失败: %w", err)
+	}
+	return string(a) != string(b), nil
+}
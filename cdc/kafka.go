@@ -0,0 +1,91 @@
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Message 发布到 Kafka 的一条消息
+type Message struct {
+	Key   string          // 分区键, 同一键的消息进入同一分区
+	Value json.RawMessage // JSON 消息体
+}
+
+// Producer 把消息发布到 Kafka topic, 全部消息写入成功才返回 nil. 换用 Kafka 客户端库时实现此接口即可
+type Producer interface {
+	Produce(ctx context.Context, topic string, messages ...Message) error
+}
+
+// RESTProducer 经 Kafka REST Proxy (v2 API, JSON 格式) 发布消息, 不需要 Kafka 客户端库
+type RESTProducer struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewRESTProducer 创建 producer, baseURL 为 REST Proxy 的地址, 如 http://kafka-rest:8082
+func NewRESTProducer(baseURL string, timeout time.Duration) *RESTProducer {
+	return &RESTProducer{baseURL: strings.TrimRight(baseURL, "/"), client: &http.Client{Timeout: timeout}}
+}
+
+type restRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type restResponse struct {
+	Offsets []struct {
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Produce 实现 Producer
+func (p *RESTProducer) Produce(ctx context.Context, topic string, messages ...Message) error {
+	records := make([]restRecord, len(messages))
+	for i, m := range messages {
+		records[i] = restRecord{Key: m.Key, Value: m.Value}
+	}
+	body, err := json.Marshal(map[string]any{"records": records})
+	if err != nil {
+		return fmt.Errorf("序列化 Kafka 消息失败: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/topics/"+url.PathEscape(topic), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建 Kafka REST 请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发布到 Kafka topic %s 失败: %w", topic, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("发布到 Kafka topic %s 失败: HTTP %d %s", topic, resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	// REST Proxy 对每条消息分别返回结果, 部分失败时整体重试 (至少一次)
+	var result restResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("解析 Kafka REST 响应失败: %w", err)
+	}
+	for _, o := range result.Offsets {
+		if o.ErrorCode != nil || o.Error != nil {
+			msg := ""
+			if o.Error != nil {
+				msg = *o.Error
+			}
+			return fmt.Errorf("发布到 Kafka topic %s 失败: %s", topic, msg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/outbox"
+)
+
+// Sink 作为 outbox.Relay 的投递目标, 把行变更发布到 Producer, 其他事件忽略
+type Sink struct {
+	producer Producer
+	prefix   string
+}
+
+// NewSink 创建投递目标, 表 t 的变更发布到 topic <prefix>.<t>
+func NewSink(producer Producer, prefix string) *Sink {
+	return &Sink{producer: producer, prefix: prefix}
+}
+
+// Topic 表 table 的变更发布到的 topic
+func (s *Sink) Topic(table string) string {
+	return s.prefix + "." + table
+}
+
+// Publish 实现 outbox.Sink
+func (s *Sink) Publish(ctx context.Context, event outbox.Event) error {
+	if !IsChange(event.Type) {
+		return nil
+	}
+	var change Change
+	if err := json.Unmarshal([]byte(event.Payload), &change); err != nil {
+		return fmt.Errorf("解析变更事件 %d 失败: %w", event.ID, err)
+	}
+	change.EventID = event.ID
+	value, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("序列化变更事件 %d 失败: %w", event.ID, err)
+	}
+	return s.producer.Produce(ctx, s.Topic(change.Schema), Message{Key: event.Key, Value: value})
+}
+
+// Exclude 返回不投递行变更的 sink, 用于包装日志、webhook 等只关心领域事件的投递目标
+func Exclude(sink outbox.Sink) outbox.Sink {
+	return excluded{sink}
+}
+
+type excluded struct {
+	sink outbox.Sink
+}
+
+// Publish 实现 outbox.Sink
+func (e excluded) Publish(ctx context.Context, event outbox.Event) error {
+	if IsChange(event.Type) {
+		return nil
+	}
+	return e.sink.Publish(ctx, event)
+}
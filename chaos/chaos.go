@@ -0,0 +1,92 @@
+// Package chaos 在数据库访问路径上按比例注入延迟和瞬时错误, 仅用于测试和预发环境,
+// 让重试, 熔断和超时逻辑能够真正被触发.
+//
+// GORM 通过 Plugin 接入, sqlx 通过 OpenSqlx 在驱动层包装连接, 两者共享同一个 Injector.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErrInjected 默认注入的瞬时错误
+var ErrInjected = errors.New("chaos: 注入的瞬时数据库错误")
+
+// Config 注入配置, 比例取值 0 ~ 1
+type Config struct {
+	Enabled     bool
+	LatencyRate float64       // 注入延迟的查询比例
+	Latency     time.Duration // 固定延迟
+	Jitter      time.Duration // 在固定延迟上随机增加 [0, Jitter)
+	ErrorRate   float64       // 返回错误的查询比例
+	Err         error         // 注入的错误, 为 nil 时使用 ErrInjected
+}
+
+// ConfigFromEnv 从环境变量读取配置:
+//
+//	CHAOS_ENABLED=1  CHAOS_LATENCY=200ms  CHAOS_JITTER=50ms
+//	CHAOS_LATENCY_RATE=0.1  CHAOS_ERROR_RATE=0.05
+//
+// APP_ENV=production 时始终不启用.
+func ConfigFromEnv() Config {
+	return Config{
+		Enabled:     os.Getenv("CHAOS_ENABLED") == "1" && os.Getenv("APP_ENV") != "production",
+		LatencyRate: envFloat("CHAOS_LATENCY_RATE"),
+		Latency:     envDuration("CHAOS_LATENCY"),
+		Jitter:      envDuration("CHAOS_JITTER"),
+		ErrorRate:   envFloat("CHAOS_ERROR_RATE"),
+	}
+}
+
+// Injector 按配置决定每次查询是否延迟或失败, 可并发使用
+type Injector struct {
+	cfg Config
+}
+
+// New 创建注入器
+func New(cfg Config) *Injector {
+	if cfg.Err == nil {
+		cfg.Err = ErrInjected
+	}
+	return &Injector{cfg: cfg}
+}
+
+// Inject 在一次查询前调用: 可能先等待一段延迟 (ctx 取消时提前返回), 再可能返回注入的错误
+func (i *Injector) Inject(ctx context.Context) error {
+	if !i.cfg.Enabled {
+		return nil
+	}
+
+	if i.cfg.Latency > 0 && rand.Float64() < i.cfg.LatencyRate {
+		delay := i.cfg.Latency
+		if i.cfg.Jitter > 0 {
+			delay += rand.N(i.cfg.Jitter)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if rand.Float64() < i.cfg.ErrorRate {
+		return i.cfg.Err
+	}
+	return nil
+}
+
+func envFloat(key string) float64 {
+	v, _ := strconv.ParseFloat(os.Getenv(key), 64)
+	return v
+}
+
+func envDuration(key string) time.Duration {
+	v, _ := time.ParseDuration(os.Getenv(key))
+	return v
+}
@@ -0,0 +1,46 @@
+package chaos
+
+import (
+	"gorm.io/gorm"
+)
+
+// Plugin 在 GORM 的每类操作执行前调用 Injector
+type Plugin struct {
+	injector *Injector
+}
+
+// NewPlugin 创建 GORM 插件, 通过 db.Use 注册
+func NewPlugin(injector *Injector) *Plugin {
+	return &Plugin{injector: injector}
+}
+
+// Name 实现 gorm.Plugin
+func (p *Plugin) Name() string {
+	return "chaos"
+}
+
+// Initialize 实现 gorm.Plugin
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []error{
+		cb.Create().Before("gorm:create").Register("chaos:create", p.inject),
+		cb.Query().Before("gorm:query").Register("chaos:query", p.inject),
+		cb.Update().Before("gorm:update").Register("chaos:update", p.inject),
+		cb.Delete().Before("gorm:delete").Register("chaos:delete", p.inject),
+		cb.Row().Before("gorm:row").Register("chaos:row", p.inject),
+		cb.Raw().Before("gorm:raw").Register("chaos:raw", p.inject),
+	}
+	for _, err := range hooks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 注入的错误写入 db.Error 后, 后续的 gorm 回调会跳过真正的 SQL 执行
+func (p *Plugin) inject(db *gorm.DB) {
+	if err := p.injector.Inject(db.Statement.Context); err != nil {
+		db.AddError(err)
+	}
+}
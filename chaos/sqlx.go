@@ -0,0 +1,134 @@
+package chaos
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OpenSqlx 与 sqlx.Connect 相同, 但返回的连接会在每次查询, 执行和预编译前调用 Injector.
+// 包装发生在驱动层, 因此返回值仍是普通的 *sqlx.DB, 事务和 Named 系列方法同样受影响.
+func OpenSqlx(driverName, dsn string, injector *Injector) (*sqlx.DB, error) {
+	base, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := base.Driver()
+	base.Close()
+
+	var inner driver.Connector
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if inner, err = dc.OpenConnector(dsn); err != nil {
+			return nil, fmt.Errorf("创建连接器失败: %w", err)
+		}
+	} else {
+		inner = dsnConnector{dsn: dsn, drv: drv}
+	}
+
+	db := sqlx.NewDb(sql.OpenDB(WrapConnector(inner, injector)), driverName)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// dsnConnector 适配未实现 driver.DriverContext 的驱动
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+// WrapConnector 包装驱动连接器, 其连接在每次查询, 执行和预编译前调用 Injector, 可与其他连接器包装叠加
+func WrapConnector(inner driver.Connector, injector *Injector) driver.Connector {
+	return &connector{inner: inner, injector: injector}
+}
+
+type connector struct {
+	inner    driver.Connector
+	injector *Injector
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.inner.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: cn, injector: c.injector}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.inner.Driver()
+}
+
+// conn 包装驱动连接; 底层连接不支持的可选接口返回 driver.ErrSkip, 由 database/sql 回退处理
+type conn struct {
+	driver.Conn
+	injector *Injector
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.injector.Inject(ctx); err != nil {
+		return nil, err
+	}
+	return q.QueryContext(ctx, query, args)
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.injector.Inject(ctx); err != nil {
+		return nil, err
+	}
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.injector.Inject(ctx); err != nil {
+		return nil, err
+	}
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
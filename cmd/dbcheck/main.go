@@ -0,0 +1,121 @@
+// dbcheck 记录并校验数据库快照.
+//
+//	dbcheck snapshot [-tables users,posts] -o before.json
+//	dbcheck verify before.json
+//
+// 连接配置读取 DB_* 环境变量 (见 config.LoadDatabase), 校验其他库时设置 DB_NAME.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/snapshot"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	db, err := sql.Open("mysql", config.LoadDatabase("blog_db").DSN("parseTime=true"))
+	if err != nil {
+		log.Fatalf("数据库连接失败: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "snapshot":
+		err = runSnapshot(ctx, db, os.Args[2:])
+	case "verify":
+		err = runVerify(ctx, db, os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: dbcheck snapshot [-tables t1,t2] -o 文件 | dbcheck verify [-tables t1,t2] 快照文件")
+	os.Exit(2)
+}
+
+// 记录快照
+func runSnapshot(ctx context.Context, db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	tables := fs.String("tables", "", "逗号分隔的表名, 默认全部表")
+	out := fs.String("o", "snapshot.json", "输出文件")
+	fs.Parse(args)
+
+	snap, err := snapshot.Take(ctx, db, splitTables(*tables))
+	if err != nil {
+		return err
+	}
+	if err := snap.Save(*out); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ 已记录 %s 的 %d 张表到 %s\n", snap.Database, len(snap.Tables), *out)
+	return nil
+}
+
+// 与快照比较, 有差异时以非零状态退出
+func runVerify(ctx context.Context, db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	tables := fs.String("tables", "", "逗号分隔的表名, 默认全部表")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	baseline, err := snapshot.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// 指定了表时只比较这些表, 否则比较全部表, 这样也能发现被删除或新增的表
+	names := splitTables(*tables)
+	if len(names) > 0 {
+		for name := range baseline.Tables {
+			if !slices.Contains(names, name) {
+				delete(baseline.Tables, name)
+			}
+		}
+	}
+	current, err := snapshot.Take(ctx, db, names)
+	if err != nil {
+		return err
+	}
+
+	diffs := snapshot.Compare(baseline, current)
+	if len(diffs) == 0 {
+		fmt.Printf("✅ %d 张表与 %s 时的快照一致\n", len(current.Tables), baseline.TakenAt.Format("2006-01-02 15:04:05"))
+		return nil
+	}
+	for _, d := range diffs {
+		fmt.Printf("❌ %s\n", d)
+	}
+	os.Exit(1)
+	return nil
+}
+
+func splitTables(s string) []string {
+	var tables []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
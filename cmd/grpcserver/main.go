@@ -0,0 +1,47 @@
+// grpcserver 提供博客和员工的 gRPC 服务 (见 proto/), 供内部服务调用.
+//
+//	grpcserver -addr :9090
+//
+// 连接配置读取 DB_* 环境变量 (见 config.LoadDatabase), 人事库连接失败时只提供 BlogService.
+// DB_SHARED_POOL=true 时两个库共用一个连接池 (见 shareddb).
+package main
+
+import (
+	"cmp"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/grpcapi"
+	"github.com/alexwang789/Base1_golang_task3/idcodec"
+	"github.com/alexwang789/Base1_golang_task3/shareddb"
+)
+
+func main() {
+	addr := flag.String("addr", cmp.Or(os.Getenv("GRPC_ADDR"), ":9090"), "gRPC 监听地址")
+	flag.Parse()
+
+	dbs, err := shareddb.Open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dbs.Close()
+	db := dbs.Blog
+	blog.EnableUserCache(db)
+
+	hid := config.LoadHashID()
+	ids, err := idcodec.New(hid.Salt, hid.MinLength)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if dbs.HRErr != nil {
+		log.Printf("人事库连接失败, EmployeeService 不可用: %v", dbs.HRErr)
+	}
+
+	if err := grpcapi.Serve(*addr, db, dbs.HR, ids); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/anonymize"
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	"github.com/alexwang789/Base1_golang_task3/tenantdb"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// 脱敏时清空的表: 其中保存着个人信息的副本 (审计日志、待发邮件、事件) 或生产环境的凭据和推送地址, 无法逐条改写
+var (
+	blogPurgeTables = []string{"audit_logs", "email_queue", "email_verifications", "api_keys", "outbox_events", "webhooks", "webhook_deliveries", "webhook_delivery_attempts"}
+	hrPurgeTables   = []string{"audit_logs"}
+)
+
+func newAnonymizeCmd() *cobra.Command {
+	var (
+		seed       int64
+		password   string
+		copySuffix string
+		yes        bool
+	)
+	cmd := &cobra.Command{
+		Use:   "anonymize",
+		Short: "把博客库和人事库中的用户、员工个人信息改写为假数据, 用于在本地调试时使用生产数据",
+		Long: `把用户名、邮箱、密码和员工姓名改写为逼真的假数据, 主键和关联关系不变; 清空审计日志、待发邮件、事件、API Key 和 webhook 订阅.
+默认直接改写 DB_* 配置的库, 需要 --yes 确认; 指定 --copy-suffix 时先在同一台服务器上把库复制为 <库名><后缀> 再改写副本, 原库不变.
+复制模式只处理默认库, 不处理独立库的租户.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			if copySuffix == "" && !yes {
+				return errors.New("将直接改写当前配置的库, 确认请加 --yes, 或用 --copy-suffix 改写副本")
+			}
+			faker := anonymize.New(seed)
+
+			db, hr, closeAll, err := openAnonymizeTargets(ctx, copySuffix)
+			if err != nil {
+				return err
+			}
+			defer closeAll()
+
+			err = tenantdb.Of(db).ForEach(ctx, func(ctx context.Context) error {
+				if target := tenantdb.Target(ctx); target != 0 {
+					fmt.Printf("租户 %d 的数据库: ", target)
+				}
+				n, err := blog.AnonymizeUsers(ctx, db, faker, password)
+				if err != nil {
+					return err
+				}
+				if err := purgeBlogTables(ctx, db); err != nil {
+					return err
+				}
+				fmt.Printf("已改写 %d 个用户\n", n)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			n, err := employee.AnonymizeNames(ctx, hr, faker)
+			if err != nil {
+				return err
+			}
+			if err := purgeHRTables(ctx, hr); err != nil {
+				return err
+			}
+			fmt.Printf("已改写 %d 名员工\n", n)
+			fmt.Println("✅ 脱敏完成")
+			return nil
+		},
+	}
+	cmd.Flags().Int64Var(&seed, "seed", 1, "假数据的随机种子, 同一种子下结果固定")
+	cmd.Flags().StringVar(&password, "password", "anon1234", "全部用户的新密码")
+	cmd.Flags().StringVar(&copySuffix, "copy-suffix", "", "先把库复制为 <库名><后缀> 再改写副本, 如 _anon")
+	cmd.Flags().BoolVar(&yes, "yes", false, "确认直接改写当前配置的库")
+	return cmd
+}
+
+// openAnonymizeTargets 打开要改写的博客库和人事库, copySuffix 不为空时先复制两个库再打开副本
+func openAnonymizeTargets(ctx context.Context, copySuffix string) (*gorm.DB, *sqlx.DB, func(), error) {
+	if copySuffix == "" {
+		db, err := blog.Open()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		hr, err := employee.Open()
+		if err != nil {
+			blog.Close(db)
+			return nil, nil, nil, err
+		}
+		return db, hr.Primary(), func() { hr.Close(); blog.Close(db) }, nil
+	}
+
+	blogCfg, err := copyDatabase(ctx, config.LoadDatabase("blog_db"), copySuffix)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	hrCfg, err := copyDatabase(ctx, config.LoadDatabase("company_db"), copySuffix)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	conn, err := sql.Open("mysql", blogCfg.DSN(blog.DSNParams))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("数据库连接失败: %w", err)
+	}
+	db, err := blog.OpenConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	hr, err := employee.OpenSqlx(hrCfg.DSN(employee.DSNParams))
+	if err != nil {
+		blog.Close(db)
+		return nil, nil, nil, err
+	}
+	return db, hr, func() { hr.Close(); blog.Close(db) }, nil
+}
+
+// copyDatabase 把 cfg 的库复制为 <库名><suffix>, 返回连接副本的配置
+func copyDatabase(ctx context.Context, cfg config.Database, suffix string) (config.Database, error) {
+	conn, err := sql.Open("mysql", cfg.DSN(""))
+	if err != nil {
+		return cfg, fmt.Errorf("数据库连接失败: %w", err)
+	}
+	defer conn.Close()
+	dst := cfg.Name + suffix
+	if err := anonymize.CopyDatabase(ctx, conn, cfg.Name, dst); err != nil {
+		return cfg, err
+	}
+	fmt.Printf("✅ 已把 %s 复制为 %s\n", cfg.Name, dst)
+	cfg.Name = dst
+	return cfg, nil
+}
+
+func purgeBlogTables(ctx context.Context, db *gorm.DB) error {
+	for _, t := range blogPurgeTables {
+		if !db.WithContext(ctx).Migrator().HasTable(t) {
+			continue
+		}
+		if err := db.WithContext(ctx).Exec("DELETE FROM " + t).Error; err != nil {
+			return fmt.Errorf("清空 %s 失败: %w", t, err)
+		}
+	}
+	return nil
+}
+
+func purgeHRTables(ctx context.Context, db *sqlx.DB) error {
+	for _, t := range hrPurgeTables {
+		var n int
+		err := db.GetContext(ctx, &n, `
+			SELECT COUNT(*) FROM information_schema.TABLES
+			WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		`, t)
+		if err != nil {
+			return fmt.Errorf("查询表结构失败: %w", err)
+		}
+		if n == 0 {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, "DELETE FROM "+t); err != nil {
+			return fmt.Errorf("清空 %s 失败: %w", t, err)
+		}
+	}
+	return nil
+}
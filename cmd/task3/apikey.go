@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/apikey"
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"github.com/spf13/cobra"
+)
+
+func newAPIKeyCmd() *cobra.Command {
+	var tenantID uint
+	cmd := &cobra.Command{
+		Use:   "apikey",
+		Short: "管理机器客户端调用 REST API 用的 API Key",
+	}
+	cmd.PersistentFlags().UintVar(&tenantID, "tenant", tenant.Default, "key 所属的租户")
+	ctx := func(cmd *cobra.Command) context.Context { return tenant.With(cmd.Context(), tenantID) }
+	cmd.AddCommand(newAPIKeyCreateCmd(ctx), newAPIKeyListCmd(ctx), newAPIKeyRevokeCmd(ctx))
+	return cmd
+}
+
+func newAPIKeyCreateCmd(tenantCtx func(*cobra.Command) context.Context) *cobra.Command {
+	var (
+		name   string
+		scopes []string
+		ttl    time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "create <用户邮箱>",
+		Short: "为用户创建 key, 请求以该用户的身份执行; key 只显示这一次",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			ctx := tenantCtx(cmd)
+			var user blog.User
+			if err := db.WithContext(ctx).Scopes(blog.ByEmail(args[0])).First(&user).Error; err != nil {
+				return fmt.Errorf("查找用户 %s 失败: %w", args[0], err)
+			}
+			plain, key, err := apikey.Issue(ctx, db, user.ID, name, scopes, ttl)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("✅ 已创建 API Key %d (用户: %s, 权限: %s)\n%s\n", key.ID, user.Name, key.Scopes, plain)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "用途说明")
+	cmd.Flags().StringSliceVar(&scopes, "scopes", []string{apikey.ScopeRead}, "权限范围: read 只读, write 可写")
+	cmd.Flags().DurationVar(&ttl, "ttl", 0, "有效期, 如 720h, 默认不过期")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func newAPIKeyListCmd(tenantCtx func(*cobra.Command) context.Context) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "列出租户的全部 key",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			keys, err := apikey.List(tenantCtx(cmd), db)
+			if err != nil {
+				return err
+			}
+			for _, k := range keys {
+				fmt.Printf("%d\t%s\t用户=%d\t权限=%s\t过期=%s\t最近使用=%s\n", k.ID, k.Name, k.UserID, k.Scopes, formatOptionalTime(k.ExpiresAt), formatOptionalTime(k.LastUsedAt))
+			}
+			return nil
+		},
+	}
+}
+
+func newAPIKeyRevokeCmd(tenantCtx func(*cobra.Command) context.Context) *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "吊销 key, 之后使用它的请求返回 401",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("无效的 API Key ID %q", args[0])
+			}
+
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			if err := apikey.Revoke(tenantCtx(cmd), db, uint(id)); err != nil {
+				return err
+			}
+			fmt.Printf("✅ 已吊销 API Key %d\n", id)
+			return nil
+		},
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.DateTime)
+}
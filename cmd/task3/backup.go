@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/backup"
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/exportsink"
+	"github.com/spf13/cobra"
+)
+
+// backupDatabases backup/restore 的 --db 可选的库, 值为 config.LoadDatabase 的默认库名
+var backupDatabases = map[string]string{"blog": "blog_db", "hr": "company_db"}
+
+// backupDSNParams 备份和恢复使用的连接参数: 不设置 parseTime, 时间列按文本原样读写
+const backupDSNParams = "charset=utf8mb4"
+
+func newBackupCmd() *cobra.Command {
+	var (
+		database, format, out string
+		filter                backup.Filter
+	)
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "把博客库或人事库的表导出为 gzip 压缩的 SQL 或 JSONL 文件",
+		Long: `逐表流式导出表结构和数据 (不依赖 mysqldump), 全部表在同一个一致性快照中读取.
+--include 和 --exclude 按表名筛选, 支持通配符, 如 --exclude 'webhook_*'. 只导出 DB_* 配置的库, 不包括独立库的租户.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			if err := filter.Validate(); err != nil {
+				return err
+			}
+			cfg, err := backupDatabase(database)
+			if err != nil {
+				return err
+			}
+			if out == "" {
+				out = fmt.Sprintf("%s-%s.%s", cfg.Name, time.Now().Format("20060102-150405"), format)
+			}
+			if !strings.HasSuffix(out, ".gz") {
+				out += ".gz"
+			}
+
+			db, err := sql.Open("mysql", cfg.DSN(backupDSNParams))
+			if err != nil {
+				return fmt.Errorf("数据库连接失败: %w", err)
+			}
+			defer db.Close()
+
+			sink, err := exportsink.CreateFile(out)
+			if err != nil {
+				return err
+			}
+			stats, err := backup.Dump(ctx, db, sink, format, filter)
+			if err != nil {
+				sink.Abort()
+				return err
+			}
+			if err := sink.Close(); err != nil {
+				return err
+			}
+			printTableStats(stats)
+			fmt.Printf("✅ 已把 %s 的 %d 张表备份到 %s\n", cfg.Name, len(stats), out)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&database, "db", "blog", "要备份的库: blog 或 hr")
+	cmd.Flags().StringVar(&format, "format", "sql", "备份格式: "+strings.Join(backup.Formats, ", "))
+	cmd.Flags().StringVar(&out, "out", "", "备份文件, 默认为 <库名>-<时间>.<格式>.gz, 总是 gzip 压缩")
+	cmd.Flags().StringSliceVar(&filter.Include, "include", nil, "只备份这些表, 可重复或以逗号分隔")
+	cmd.Flags().StringSliceVar(&filter.Exclude, "exclude", nil, "不备份这些表, 优先于 --include")
+	return cmd
+}
+
+func newRestoreCmd() *cobra.Command {
+	var (
+		database string
+		filter   backup.Filter
+		yes      bool
+	)
+	cmd := &cobra.Command{
+		Use:   "restore <备份文件>",
+		Short: "把 backup 导出的文件导回博客库或人事库",
+		Long: `按文件名判断格式 (含 .jsonl 的为 JSONL, 否则为 SQL), 自动识别 gzip 压缩.
+备份中的每张表先被删除再重建, 需要 --yes 确认. --include 和 --exclude 按表名筛选要恢复的表.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			if !yes {
+				return errors.New("恢复会删除并重建备份中的表, 确认请加 --yes")
+			}
+			if err := filter.Validate(); err != nil {
+				return err
+			}
+			cfg, err := backupDatabase(database)
+			if err != nil {
+				return err
+			}
+			format := "sql"
+			if strings.Contains(args[0], ".jsonl") {
+				format = "jsonl"
+			}
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("打开备份文件失败: %w", err)
+			}
+			defer f.Close()
+			r, err := decompress(f)
+			if err != nil {
+				return err
+			}
+
+			db, err := sql.Open("mysql", cfg.DSN(backupDSNParams))
+			if err != nil {
+				return fmt.Errorf("数据库连接失败: %w", err)
+			}
+			defer db.Close()
+
+			stats, err := backup.Restore(ctx, db, r, format, filter)
+			printTableStats(stats)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("✅ 已把 %d 张表恢复到 %s\n", len(stats), cfg.Name)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&database, "db", "blog", "要恢复的库: blog 或 hr")
+	cmd.Flags().StringSliceVar(&filter.Include, "include", nil, "只恢复这些表, 可重复或以逗号分隔")
+	cmd.Flags().StringSliceVar(&filter.Exclude, "exclude", nil, "不恢复这些表, 优先于 --include")
+	cmd.Flags().BoolVar(&yes, "yes", false, "确认删除并重建备份中的表")
+	return cmd
+}
+
+// backupDatabase 返回 --db 对应的库配置
+func backupDatabase(name string) (config.Database, error) {
+	defaultName, ok := backupDatabases[name]
+	if !ok {
+		return config.Database{}, fmt.Errorf("未知的库 %q, 可选 blog, hr", name)
+	}
+	return config.LoadDatabase(defaultName), nil
+}
+
+// decompress 文件以 gzip 魔数开头时返回解压后的内容, 否则原样返回
+func decompress(f io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("读取备份文件失败: %w", err)
+	}
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return br, nil
+	}
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("解压备份文件失败: %w", err)
+	}
+	return gz, nil
+}
+
+func printTableStats(stats []backup.TableStats) {
+	for _, s := range stats {
+		fmt.Printf("  %-32s %8d 行\n", s.Table, s.Rows)
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/api"
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	"github.com/alexwang789/Base1_golang_task3/idcodec"
+	"github.com/alexwang789/Base1_golang_task3/queryplan"
+	"github.com/alexwang789/Base1_golang_task3/search"
+	"github.com/alexwang789/Base1_golang_task3/shareddb"
+	"github.com/alexwang789/Base1_golang_task3/storage"
+	"github.com/alexwang789/Base1_golang_task3/tenantdb"
+	"github.com/spf13/cobra"
+)
+
+func newBlogCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "blog",
+		Short: "博客模块 (GORM)",
+	}
+	cmd.AddCommand(newBlogDemoCmd(), newBlogServeCmd(), newBlogCheckCmd(), newBlogRekeyCmd(), newBlogReshardCmd(), newBlogPartitionCmd(), newBlogPurgeCmd(), newBlogSearchReindexCmd(), newBlogBenchCmd())
+	return cmd
+}
+
+func newBlogDemoCmd() *cobra.Command {
+	var (
+		topQueries int
+		debugAddr  string
+	)
+	cmd := &cobra.Command{
+		Use:   "demo",
+		Short: "建表、写入测试数据并演示关联查询和钩子函数",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			if err := blog.Migrate(db); err != nil {
+				return err
+			}
+			fmt.Println("✅ 数据表已创建")
+
+			// 连接池监控和调试接口
+			startDebugServer(debugAddr)
+			monitorPool(ctx, "blog_db")
+
+			blog.EnableUserCache(db)
+			if err := blog.Seed(db); err != nil {
+				return fmt.Errorf("创建测试数据失败: %w", err)
+			}
+
+			// 检查查询依赖的索引和命名查询的执行计划
+			if sqlDB, err := db.DB(); err == nil {
+				checkIndexes(ctx, sqlDB, blog.Indexes)
+				if err := queryplan.CheckEnv(sqlDB, blog.QueryPlans(db)); err != nil {
+					return err
+				}
+			}
+
+			blog.RunDemo(ctx, db)
+
+			// 输出本次运行中最频繁和最慢的 N 种语句
+			if topQueries > 0 {
+				fmt.Println("\ntop-queries:")
+				blog.QueryStats.BuildReport(topQueries).WriteText(os.Stdout)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&topQueries, "top-queries", envInt("TOP_QUERIES"), "结束时输出最频繁和最慢的 N 种语句")
+	cmd.Flags().StringVar(&debugAddr, "debug-addr", os.Getenv("DEBUG_ADDR"), "调试接口监听地址, 为空时不启动")
+	return cmd
+}
+
+func newBlogServeCmd() *cobra.Command {
+	var addr string
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "提供博客 REST API",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbs, err := shareddb.Open()
+			if err != nil {
+				return err
+			}
+			defer dbs.Close()
+			db := dbs.Blog
+			monitorPool(cmd.Context(), "blog_db")
+			if sqlDB, err := db.DB(); err == nil {
+				checkIndexes(cmd.Context(), sqlDB, blog.Indexes)
+			}
+			if dbs.HR != nil {
+				checkIndexes(cmd.Context(), dbs.HR.DB, employee.Indexes)
+			}
+			blog.EnableUserCache(db)
+			blog.EnableSpamCheck(blog.NewHeuristicSpamChecker(config.LoadSpamBannedWords()))
+			verification := config.LoadEmailVerification()
+			blog.ConfigureEmailVerification(blog.VerificationOptions{TTL: verification.TTL, URL: verification.URL})
+
+			att := config.LoadAttachments()
+			store, err := storage.NewLocal(att.Dir)
+			if err != nil {
+				return err
+			}
+			blog.EnableAttachments(store, blog.AttachmentLimits{MaxSize: att.MaxSize, AllowedTypes: att.AllowedTypes})
+
+			hid := config.LoadHashID()
+			ids, err := idcodec.New(hid.Salt, hid.MinLength)
+			if err != nil {
+				return err
+			}
+			// 员工自助接口需要人事库, 连接失败时其余接口照常提供
+			if dbs.HRErr != nil {
+				log.Printf("人事库连接失败, 员工自助接口不可用: %v", dbs.HRErr)
+			}
+			return api.Serve(addr, db, dbs.HR, ids)
+		},
+	}
+	cmd.Flags().StringVar(&addr, "addr", cmp.Or(os.Getenv("API_ADDR"), ":8080"), "API 监听地址")
+	return cmd
+}
+
+func newBlogCheckCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "校验钩子维护的文章数和评论状态与实际数据一致, 不一致时以非 0 状态退出",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			mismatches, err := blog.CheckCounters(cmd.Context(), db)
+			if err != nil {
+				return err
+			}
+			blog.WriteCounterReport(os.Stdout, mismatches)
+			if len(mismatches) > 0 {
+				return fmt.Errorf("%d 条记录的计数字段不一致", len(mismatches))
+			}
+			return nil
+		},
+	}
+}
+
+func newBlogRekeyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rekey",
+		Short: "用当前密钥重新加密用户邮箱并补全盲索引, 用于启用加密和轮换密钥",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			return tenantdb.Of(db).ForEach(cmd.Context(), func(ctx context.Context) error {
+				n, err := blog.RekeyUsers(ctx, db)
+				if target := tenantdb.Target(ctx); target != 0 {
+					fmt.Printf("租户 %d 的数据库: ", target)
+				}
+				fmt.Printf("已重新加密 %d 个用户的邮箱\n", n)
+				return err
+			})
+		},
+	}
+}
+
+func newBlogReshardCmd() *cobra.Command {
+	var from, to int
+	cmd := &cobra.Command{
+		Use:   "reshard-comments",
+		Short: "把评论从 --from 张分表搬迁到 --to 张分表 (1 表示不分表), 搬迁期间应停止写入, 完成后修改 COMMENT_SHARDS 并重启",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if from < 1 || to < 1 {
+				return errors.New("分表数量应不小于 1")
+			}
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			return tenantdb.Of(db).ForEach(cmd.Context(), func(ctx context.Context) error {
+				n, err := blog.ReshardComments(ctx, db, from, to)
+				if target := tenantdb.Target(ctx); target != 0 {
+					fmt.Printf("租户 %d 的数据库: ", target)
+				}
+				fmt.Printf("已搬迁 %d 条评论\n", n)
+				return err
+			})
+		},
+	}
+	cmd.Flags().IntVar(&from, "from", config.LoadCommentShards(), "当前的分表数量, 默认为 COMMENT_SHARDS")
+	cmd.Flags().IntVar(&to, "to", 1, "目标分表数量")
+	return cmd
+}
+
+func newBlogPartitionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "partition [table...]",
+		Short: fmt.Sprintf("把表 (%s) 改为按 created_at 每月一个分区; 不指定表时列出已分区的表", strings.Join(blog.PartitionedTables, ", ")),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			return tenantdb.Of(db).ForEach(cmd.Context(), func(ctx context.Context) error {
+				if target := tenantdb.Target(ctx); target != 0 {
+					fmt.Printf("租户 %d 的数据库:\n", target)
+				}
+				for _, table := range args {
+					if err := blog.PartitionTable(ctx, db, table, config.LoadPartitioning().Ahead); err != nil {
+						return err
+					}
+					fmt.Printf("✅ %s 已按月分区\n", table)
+				}
+				partitioned, err := blog.ListPartitions(ctx, db)
+				if err != nil {
+					return err
+				}
+				if len(partitioned) == 0 {
+					fmt.Println("没有已分区的表")
+				}
+				for _, table := range slices.Sorted(maps.Keys(partitioned)) {
+					fmt.Println(table)
+					for _, p := range partitioned[table] {
+						fmt.Printf("  %-8s < %-24s 约 %d 行\n", p.Name, p.Bound, p.Rows)
+					}
+				}
+				return nil
+			})
+		},
+	}
+}
+
+func newBlogPurgeCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "purge",
+		Short: "按 RETENTION_* 配置的保留期分批删除过期的软删除记录、审计日志和通知, 并报告删除的行数",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			verb := "已删除"
+			if dryRun {
+				verb = "将删除"
+			}
+			return tenantdb.Of(db).ForEach(cmd.Context(), func(ctx context.Context) error {
+				if target := tenantdb.Target(ctx); target != 0 {
+					fmt.Printf("租户 %d 的数据库:\n", target)
+				}
+				results, err := blog.PurgeExpired(ctx, db, config.LoadRetention(), dryRun)
+				if len(results) == 0 && err == nil {
+					fmt.Println("没有设置保留期")
+				}
+				for _, r := range results {
+					fmt.Printf("%s %s\n", verb, r)
+				}
+				return err
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "只统计将要删除的行数")
+	return cmd
+}
+
+func newBlogSearchReindexCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "search-reindex",
+		Short: "把全部对外可见的文章写入 SEARCH_URL 配置的搜索引擎, 首次启用或索引丢失时执行",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.LoadSearch()
+			if !cfg.Enabled() {
+				return errors.New("没有配置 SEARCH_URL")
+			}
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			client := search.NewClient(cfg.URL, cfg.Index, cfg.Timeout)
+			return tenantdb.Of(db).ForEach(cmd.Context(), func(ctx context.Context) error {
+				if target := tenantdb.Target(ctx); target != 0 {
+					fmt.Printf("租户 %d 的数据库:\n", target)
+				}
+				n, err := blog.ReindexPosts(ctx, db, client)
+				fmt.Printf("已写入 %d 篇文章到索引 %s\n", n, cfg.Index)
+				return err
+			})
+		},
+	}
+}
+
+func newBlogBenchCmd() *cobra.Command {
+	var b blog.LoadBenchmark
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "对比 Preload、JOIN、IN 查询和 N+1 加载用户文章及评论的耗时, 数据在事务中生成并回滚",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			results, err := blog.RunLoadBenchmark(cmd.Context(), db, b)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%d 个用户 × %d 篇文章 × %d 条评论, %d 轮:\n", b.Users, b.PostsPerUser, b.CommentsPerPost, b.Rounds)
+			for _, r := range results {
+				fmt.Printf("  %-8s 总计 %-12v 每个用户 %v\n", r.Strategy, r.Total.Round(time.Millisecond), r.PerLoad)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&b.Users, "users", 20, "生成的用户数")
+	cmd.Flags().IntVar(&b.PostsPerUser, "posts", 20, "每个用户的文章数")
+	cmd.Flags().IntVar(&b.CommentsPerPost, "comments", 10, "每篇文章的评论数")
+	cmd.Flags().IntVar(&b.Rounds, "rounds", 5, "每种方式加载全部用户的轮数")
+	return cmd
+}
+
+// 读取整数环境变量, 未设置或无效时返回 0
+func envInt(name string) int {
+	n, _ := strconv.Atoi(os.Getenv(name))
+	return n
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/dbpool"
+	"github.com/alexwang789/Base1_golang_task3/querystats"
+)
+
+// 在 addr 上启动调试接口, addr 为空时不启动:
+//
+//	/debug/vars         expvar 指标 (含连接池和数据库熔断器状态)
+//	/debug/dbpool       查看 / 调整连接池
+//	/debug/top-queries  最频繁和最慢的语句
+func startDebugServer(addr string) {
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/debug/dbpool", dbpool.Default.Handler())
+	mux.Handle("/debug/top-queries", querystats.Handler(blog.QueryStats))
+
+	go func() {
+		log.Printf("调试接口监听 %s", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("调试接口退出: %v", err)
+		}
+	}()
+}
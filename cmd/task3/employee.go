@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	"github.com/alexwang789/Base1_golang_task3/queryplan"
+	"github.com/spf13/cobra"
+)
+
+func newEmployeeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "employee",
+		Short: "员工模块 (sqlx)",
+	}
+	cmd.AddCommand(newEmployeeQueryCmd(), newEmployeeImportCmd(), newEmployeeManagerCmd(), newEmployeeOrgCmd(), newEmployeeRaiseCmd())
+	return cmd
+}
+
+func newEmployeeQueryCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "query",
+		Short: "演示按部门、薪资等条件查询员工和部门统计",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			hr, err := employee.Open()
+			if err != nil {
+				return err
+			}
+			defer hr.Close()
+			monitorPool(ctx, "company_db")
+
+			if err := employee.Migrate(ctx, hr.Primary()); err != nil {
+				return err
+			}
+			// 检查查询依赖的索引和命名查询的执行计划
+			checkIndexes(ctx, hr.Primary().DB, employee.Indexes)
+			if err := queryplan.CheckEnv(hr.Primary().DB, employee.QueryPlans); err != nil {
+				return err
+			}
+
+			employee.RunQueryDemo(ctx, hr)
+			return nil
+		},
+	}
+}
+
+func newEmployeeImportCmd() *cobra.Command {
+	var batchSize int
+	cmd := &cobra.Command{
+		Use:   "import <csv 文件>",
+		Short: "从 CSV 导入员工, 表头需包含 name, department, salary",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("打开导入文件失败: %w", err)
+			}
+			defer f.Close()
+
+			hr, err := employee.Open()
+			if err != nil {
+				return err
+			}
+			defer hr.Close()
+
+			report, err := employee.ImportCSV(hr.Primary(), f, batchSize)
+			if err != nil {
+				return fmt.Errorf("导入员工失败: %w", err)
+			}
+			report.WriteText(os.Stdout)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&batchSize, "batch-size", employee.DefaultBatchSize, "每条 INSERT 语句包含的行数")
+	return cmd
+}
+
+func newEmployeeManagerCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "manager <员工 ID> <上级 ID|none>",
+		Short: "设置员工的直属上级, none 取消上级; 不能设为本人或其下属",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("无效的员工 ID %q", args[0])
+			}
+			var managerID *int
+			if args[1] != "none" {
+				m, err := strconv.Atoi(args[1])
+				if err != nil {
+					return fmt.Errorf("无效的上级 ID %q", args[1])
+				}
+				managerID = &m
+			}
+
+			hr, err := employee.Open()
+			if err != nil {
+				return err
+			}
+			defer hr.Close()
+
+			if err := employee.SetManager(cmd.Context(), hr.Primary(), id, managerID); err != nil {
+				return err
+			}
+			fmt.Printf("✅ 已更新员工 %d 的上级\n", id)
+			return nil
+		},
+	}
+}
+
+func newEmployeeOrgCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "org <员工 ID>",
+		Short: "显示员工的汇报链和其下的组织架构",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("无效的员工 ID %q", args[0])
+			}
+
+			hr, err := employee.Open()
+			if err != nil {
+				return err
+			}
+			defer hr.Close()
+
+			chain, err := employee.ReportingChain(ctx, hr.Reader(ctx), id)
+			if err != nil {
+				return err
+			}
+			names := make([]string, len(chain))
+			for i, n := range chain {
+				names[i] = n.Name
+			}
+			fmt.Println("汇报链:", strings.Join(names, " → "))
+
+			tree, err := employee.Subordinates(ctx, hr.Reader(ctx), id)
+			if err != nil {
+				return err
+			}
+			fmt.Println("组织架构:")
+			for _, n := range tree {
+				fmt.Printf("%s- %s (%s)\n", strings.Repeat("  ", n.Depth), n.Name, n.Department)
+			}
+			return nil
+		},
+	}
+}
+
+func newEmployeeRaiseCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "raise <部门> <百分比>",
+		Short: "按百分比调整部门全部员工的薪资, 如 raise 技术部 5; 降薪用负数, 放在 -- 之后: raise 技术部 -- -3",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			percent, err := strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				return fmt.Errorf("无效的百分比 %q", args[1])
+			}
+
+			hr, err := employee.Open()
+			if err != nil {
+				return err
+			}
+			defer hr.Close()
+
+			report, err := employee.ApplyRaise(cmd.Context(), hr.Primary(), args[0], percent, employee.RaiseOptions{DryRun: dryRun})
+			if err != nil {
+				return err
+			}
+			report.WriteText(os.Stdout)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "只显示调薪结果, 不写入")
+	return cmd
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	"github.com/alexwang789/Base1_golang_task3/queryplan"
+	"github.com/spf13/cobra"
+)
+
+func newExplainCmd() *cobra.Command {
+	var (
+		database string
+		asJSON   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "explain [查询名...]",
+		Short: "对命名查询执行 EXPLAIN, 指出全表扫描和额外排序并建议索引",
+		Long: `用示例参数对博客库 (blog.QueryPlans) 或人事库 (employee.QueryPlans) 的命名查询执行 EXPLAIN, 不指定查询名时分析全部.
+出现全表扫描、filesort 或临时表时, 按 WHERE 和 ORDER BY 中的列给出 CREATE INDEX 建议. 建议是启发式的, 采用前请先核对.
+--json 时输出 EXPLAIN FORMAT=JSON 的结果 (MySQL 5.6 及以上).`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			var (
+				conn    *sql.DB
+				queries []queryplan.Query
+			)
+			switch database {
+			case "blog":
+				db, err := blog.Open()
+				if err != nil {
+					return err
+				}
+				defer blog.Close(db)
+				if conn, err = db.DB(); err != nil {
+					return fmt.Errorf("获取数据库连接失败: %w", err)
+				}
+				queries = blog.QueryPlans(db)
+			case "hr":
+				hr, err := employee.Open()
+				if err != nil {
+					return err
+				}
+				defer hr.Close()
+				conn, queries = hr.Primary().DB, employee.QueryPlans
+			default:
+				return fmt.Errorf("未知的库 %q, 可选 blog, hr", database)
+			}
+
+			if len(args) > 0 {
+				names := make([]string, len(queries))
+				for i, q := range queries {
+					names[i] = q.Name
+				}
+				for _, name := range args {
+					if !slices.Contains(names, name) {
+						return fmt.Errorf("未知的查询 %q, 可选 %s", name, strings.Join(names, ", "))
+					}
+				}
+				queries = slices.DeleteFunc(queries, func(q queryplan.Query) bool { return !slices.Contains(args, q.Name) })
+			}
+
+			for _, q := range queries {
+				report, err := queryplan.Advise(ctx, conn, q)
+				if err != nil {
+					return err
+				}
+				if asJSON {
+					if report.JSON == nil {
+						return fmt.Errorf("%s: 数据库不支持 EXPLAIN FORMAT=JSON", q.Name)
+					}
+					fmt.Printf("== %s\n%s\n", q.Name, report.JSON)
+					continue
+				}
+				report.WriteText(os.Stdout)
+				fmt.Println()
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&database, "db", "blog", "要分析的库: blog 或 hr")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "输出 EXPLAIN FORMAT=JSON 的结果")
+	return cmd
+}
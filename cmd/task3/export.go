@@ -0,0 +1,68 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	"github.com/alexwang789/Base1_golang_task3/exportsink"
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd() *cobra.Command {
+	var format, dest string
+	tables := append(slices.Clone(blog.Tables), "employees")
+	cmd := &cobra.Command{
+		Use:   "export <" + strings.Join(tables, "|") + ">",
+		Short: "导出数据到本地文件或 s3://bucket/key",
+		Long: `导出数据到本地文件或 s3://bucket/key, 文件名以 .gz 结尾时压缩.
+设置 EXPORT_CHECKPOINT 时 S3 上传支持断点续传: 失败后保留已上传的分片, 重新运行同一命令从中断处继续.`,
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: tables,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			table := args[0]
+			if dest == "" {
+				dest = table + "." + format
+			}
+			checkpoint := os.Getenv("EXPORT_CHECKPOINT")
+
+			if table == "employees" {
+				hr, err := employee.Open()
+				if err != nil {
+					return err
+				}
+				defer hr.Close()
+
+				err = exportsink.Export(ctx, dest, format, checkpoint, func(out *exportsink.Output) error {
+					return employee.Export(ctx, hr.Reader(ctx), out)
+				})
+				if err != nil {
+					return err
+				}
+			} else {
+				db, err := blog.Open()
+				if err != nil {
+					return err
+				}
+				defer blog.Close(db)
+
+				err = exportsink.Export(ctx, dest, format, checkpoint, func(out *exportsink.Output) error {
+					return blog.Export(ctx, db, table, out)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			fmt.Printf("✅ %s 导出完成\n", table)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", cmp.Or(os.Getenv("EXPORT_FORMAT"), "jsonl"), "导出格式: "+strings.Join(exportsink.Formats, ", "))
+	cmd.Flags().StringVar(&dest, "out", os.Getenv("EXPORT_DEST"), "导出目标: 本地文件或 s3://bucket/key, 默认为 <数据>.<格式>")
+	return cmd
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/jobs"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+func newJobsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "定期任务, 平时由 blog serve 按调度执行",
+	}
+	cmd.AddCommand(newJobsListCmd(), newJobsRunCmd())
+	return cmd
+}
+
+// openScheduler 打开博客库并注册已启用的任务
+func openScheduler() (*jobs.Scheduler, *gorm.DB, error) {
+	db, err := blog.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		blog.Close(db)
+		return nil, nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	s := jobs.New(jobs.NewMySQLLocker(sqlDB))
+	if err := jobs.RegisterBlogJobs(s, db); err != nil {
+		blog.Close(db)
+		return nil, nil, err
+	}
+	return s, db, nil
+}
+
+func newJobsListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "列出已启用的任务",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, db, err := openScheduler()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			for _, name := range s.Names() {
+				fmt.Println(name)
+			}
+			return nil
+		},
+	}
+}
+
+func newJobsRunCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "run <name>",
+		Short: "立即执行一次任务, 其他进程正在执行同一任务时跳过",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, db, err := openScheduler()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			if err := s.RunOnce(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("✅ 任务 %s 已执行\n", args[0])
+			return nil
+		},
+	}
+}
@@ -0,0 +1,289 @@
+// task3 博客 (GORM)、员工 (sqlx) 和学生三个模块的命令行入口.
+//
+//	task3 migrate                     创建/升级全部表
+//	task3 seed [--reload]             写入博客测试数据 (设置 FIXTURES 时从 YAML 加载)
+//	task3 blog demo                   博客关联查询和钩子演示
+//	task3 blog serve --addr :8080     提供博客 REST API
+//	task3 blog check                  校验文章数和评论状态等冗余字段
+//	task3 blog bench                  对比 Preload / JOIN / IN / N+1 加载方式的耗时
+//	task3 employee query              员工查询演示
+//	task3 employee import <csv>       从 CSV 导入员工
+//	task3 student demo                分班、转班和花名册演示
+//	task3 student classes             各班人数统计
+//	task3 student transcript <id>     学生成绩单 (另有 subjects <班级 ID>)
+//	task3 export <数据> --format csv  导出数据到本地文件或 S3
+//	task3 anonymize --copy-suffix _anon  复制博客库和人事库并把个人信息改写为假数据
+//	task3 backup --db blog --format sql  备份库中的表到 gzip 文件 (另有 restore <文件>)
+//	task3 explain --db blog [查询名]    分析命名查询的执行计划并建议索引
+//	task3 genload --posts 100000      向博客库写入压测数据
+//	task3 stress --workers 20         并发发表文章和评论, 核对计数是否一致
+//	task3 webhook add <url>           添加事件推送订阅 (另有 list / remove)
+//	task3 jobs run <任务>              立即执行一次定期任务 (另有 list)
+//
+// 连接配置读取 DB_* 环境变量 (见 config.LoadDatabase). DB_SHARED_POOL=true 时 blog serve 的博客库和人事库
+// 共用一个连接池 (见 shareddb).
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/accounts"
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/dbpool"
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	"github.com/alexwang789/Base1_golang_task3/fixtures"
+	"github.com/alexwang789/Base1_golang_task3/queryplan"
+	"github.com/alexwang789/Base1_golang_task3/student"
+	"github.com/alexwang789/Base1_golang_task3/webhook"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	root := &cobra.Command{
+		Use:   "task3",
+		Short: "博客、员工和学生模块的演示与运维命令",
+		// 参数错误之外的失败不打印用法
+		SilenceUsage: true,
+	}
+	root.AddCommand(
+		newMigrateCmd(),
+		newSeedCmd(),
+		newBlogCmd(),
+		newEmployeeCmd(),
+		newStudentCmd(),
+		newExportCmd(),
+		newReportCmd(),
+		newAnonymizeCmd(),
+		newBackupCmd(),
+		newRestoreCmd(),
+		newExplainCmd(),
+		newGenloadCmd(),
+		newStressCmd(),
+		newWebhookCmd(),
+		newAPIKeyCmd(),
+		newJobsCmd(),
+	)
+	if err := root.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newMigrateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "创建或升级博客库和员工库的表, 可重复执行",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			if err := blog.Migrate(db); err != nil {
+				return err
+			}
+			if err := accounts.Migrate(db); err != nil {
+				return err
+			}
+			if err := webhook.Migrate(db); err != nil {
+				return err
+			}
+			if err := student.Migrate(db); err != nil {
+				return fmt.Errorf("创建学生表失败: %w", err)
+			}
+			fmt.Println("✅ 博客库数据表已创建")
+
+			hr, err := employee.Open()
+			if err != nil {
+				return err
+			}
+			defer hr.Close()
+
+			if err := employee.Migrate(cmd.Context(), hr.Primary()); err != nil {
+				return err
+			}
+			fmt.Println("✅ 员工库数据表已创建")
+			return nil
+		},
+	}
+}
+
+func newSeedCmd() *cobra.Command {
+	var reload bool
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "写入博客测试数据, 设置 FIXTURES (逗号分隔的 YAML 文件) 时从 fixture 加载",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			if !reload {
+				return blog.Seed(db)
+			}
+
+			paths := os.Getenv("FIXTURES")
+			if paths == "" {
+				return errors.New("--reload 需要设置 FIXTURES")
+			}
+			set, err := fixtures.Load(strings.Split(paths, ",")...)
+			if err != nil {
+				return err
+			}
+			sqlDB, err := db.DB()
+			if err != nil {
+				return fmt.Errorf("获取数据库连接失败: %w", err)
+			}
+			if _, err := set.Reload(cmd.Context(), sqlDB, time.Now()); err != nil {
+				return err
+			}
+			if err := blog.RebuildPostStats(cmd.Context(), db); err != nil {
+				return err
+			}
+			fmt.Printf("✅ 已清空 %s 并重新加载 %s\n", strings.Join(set.Tables(), ", "), paths)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&reload, "reload", false, "先清空 fixture 涉及的表再加载, 自增主键从 1 开始")
+	return cmd
+}
+
+func newStudentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "student",
+		Short: "学生模块",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "demo",
+		Short: "演示分班、转班和花名册查询",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			if err := student.Migrate(db); err != nil {
+				return fmt.Errorf("创建学生表失败: %w", err)
+			}
+			return student.Run(cmd.Context(), student.NewGormStore(db))
+		},
+	}, &cobra.Command{
+		Use:   "classes",
+		Short: "显示各班人数和汇总统计",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			stats, err := student.NewGormStore(db).ClassStats(cmd.Context())
+			if err != nil {
+				return err
+			}
+			student.PrintClassStats(stats)
+			return nil
+		},
+	}, newStudentTranscriptCmd(), newStudentSubjectsCmd())
+	return cmd
+}
+
+func newStudentTranscriptCmd() *cobra.Command {
+	var term string
+	cmd := &cobra.Command{
+		Use:   "transcript <学生 ID>",
+		Short: "显示学生的成绩单、平均分和绩点",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("无效的学生 ID %q", args[0])
+			}
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			t, err := student.NewGormStore(db).Transcript(cmd.Context(), uint(id), term)
+			if err != nil {
+				return err
+			}
+			student.PrintTranscript(t)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&term, "term", "", "学期, 如 2024-秋; 默认全部学期")
+	return cmd
+}
+
+func newStudentSubjectsCmd() *cobra.Command {
+	var term string
+	cmd := &cobra.Command{
+		Use:   "subjects <班级 ID>",
+		Short: "显示班级各科成绩分布和学生平均分排名",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("无效的班级 ID %q", args[0])
+			}
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			store := student.NewGormStore(db)
+			subjects, err := store.SubjectDistribution(ctx, uint(id), term)
+			if err != nil {
+				return err
+			}
+			fmt.Println("各科成绩:")
+			student.PrintSubjectStats(subjects)
+
+			averages, err := store.ClassAverages(ctx, uint(id), term)
+			if err != nil {
+				return err
+			}
+			fmt.Println("学生平均分:")
+			for i, a := range averages {
+				fmt.Printf("%d. %s: %.1f 分, 绩点 %.2f (%d 科)\n", i+1, a.Name, a.Average, a.GPA, a.Subjects)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&term, "term", "", "学期, 如 2024-秋; 默认全部学期")
+	return cmd
+}
+
+// 按配置的间隔输出连接池使用率日志, 直到 ctx 取消; 未配置间隔时不启动
+func monitorPool(ctx context.Context, dbName string) {
+	if pool := config.LoadDatabase(dbName).Pool; pool.MonitorInterval > 0 {
+		go dbpool.Default.Monitor(ctx, pool.MonitorInterval, 0.8)
+	}
+}
+
+// 检查查询依赖的索引, 缺少时只输出警告; 检查本身失败也不影响启动
+func checkIndexes(ctx context.Context, db *sql.DB, indexes []queryplan.Index) {
+	if err := queryplan.WarnMissingIndexes(ctx, db, indexes); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	"github.com/alexwang789/Base1_golang_task3/reports"
+	"github.com/spf13/cobra"
+)
+
+func newReportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "运行预定义的 SQL 报表, 输出 CSV 或 JSON",
+	}
+	cmd.AddCommand(newReportListCmd(), newReportRunCmd())
+	return cmd
+}
+
+func newReportListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "列出全部报表及其参数",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, r := range reports.All() {
+				fmt.Printf("%s [%s] %s\n", r.Name, r.Source, r.Title)
+				for _, p := range r.Params {
+					def := "必填"
+					if p.Default != "" {
+						def = "默认 " + p.Default
+					}
+					fmt.Printf("    --param %s=...  %s (%s)\n", p.Name, p.Help, def)
+				}
+			}
+		},
+	}
+}
+
+func newReportRunCmd() *cobra.Command {
+	var (
+		params map[string]string
+		format string
+		dest   string
+	)
+	names := make([]string, 0, len(reports.All()))
+	for _, r := range reports.All() {
+		names = append(names, r.Name)
+	}
+	cmd := &cobra.Command{
+		Use:       "run <" + strings.Join(names, "|") + ">",
+		Short:     "运行报表, 结果写到标准输出或 --out 指定的文件",
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: names,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			report, _ := reports.Get(args[0])
+
+			var result *reports.Result
+			switch report.Source {
+			case reports.HR:
+				hr, err := employee.Open()
+				if err != nil {
+					return err
+				}
+				defer hr.Close()
+				if result, err = report.Run(ctx, hr.Reader(ctx), params); err != nil {
+					return err
+				}
+			case reports.Blog:
+				db, err := blog.Open()
+				if err != nil {
+					return err
+				}
+				defer blog.Close(db)
+				// 直接使用 GORM 的连接池, 租户独立部署时由 tenantdb.Router 按 tenant 参数路由
+				if result, err = report.Run(ctx, db.ConnPool, params); err != nil {
+					return err
+				}
+			}
+
+			var w io.Writer = os.Stdout
+			if dest != "" {
+				f, err := os.Create(dest)
+				if err != nil {
+					return fmt.Errorf("创建报表文件失败: %w", err)
+				}
+				defer f.Close()
+				w = f
+			}
+			if err := result.Write(w, format); err != nil {
+				return err
+			}
+			if dest != "" {
+				fmt.Printf("✅ 报表 %s 共 %d 行, 已写入 %s\n", report.Name, result.Len(), dest)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringToStringVarP(&params, "param", "p", nil, "报表参数, 如 --param from=2024-01-01 (可重复), 见 report list")
+	cmd.Flags().StringVar(&format, "format", "csv", "输出格式: "+strings.Join(reports.Formats, ", "))
+	cmd.Flags().StringVar(&dest, "out", "", "输出文件, 默认为标准输出")
+	return cmd
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/spf13/cobra"
+)
+
+func newStressCmd() *cobra.Command {
+	var opts blog.StressOptions
+	cmd := &cobra.Command{
+		Use:   "stress",
+		Short: "并发发表文章和评论, 核对文章数和评论数是否完全一致",
+		Long: `用 --workers 个 goroutine 并发发表文章和评论 (评论随即由管理员审核通过), 结束后核对本次创建的作者的文章数、
+目标文章的评论状态和 post_stats 中的评论数是否与成功的操作数完全一致, 用于验证钩子和行锁的设计.
+作者和文章越少, 同一行上的竞争越激烈. 数据写入 DB_* 配置的博客库且不清理, 请在测试库上执行; APP_ENV=production 时拒绝执行.
+有操作失败 (如死锁) 或计数不一致时以非 0 状态退出.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getenv("APP_ENV") == "production" {
+				return errors.New("生产环境不能执行压测")
+			}
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+			if err := blog.Migrate(db); err != nil {
+				return err
+			}
+
+			result, err := blog.Stress(cmd.Context(), db, opts)
+			if err != nil {
+				return err
+			}
+			result.WriteText(os.Stdout)
+			return result.Err()
+		},
+	}
+	cmd.Flags().IntVar(&opts.Workers, "workers", 20, "并发的 goroutine 数")
+	cmd.Flags().IntVar(&opts.Iterations, "iterations", 50, "每个 goroutine 的操作次数")
+	cmd.Flags().IntVar(&opts.Users, "users", 5, "参与的作者数")
+	cmd.Flags().IntVar(&opts.Posts, "posts", 3, "评论的目标文章数")
+	return cmd
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/webhook"
+	"github.com/spf13/cobra"
+)
+
+func newWebhookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "管理博客事件的 webhook 订阅, 推送由 blog serve 执行",
+	}
+	cmd.AddCommand(newWebhookAddCmd(), newWebhookListCmd(), newWebhookRemoveCmd())
+	return cmd
+}
+
+func newWebhookAddCmd() *cobra.Command {
+	var (
+		secret string
+		events []string
+	)
+	cmd := &cobra.Command{
+		Use:   "add <url>",
+		Short: "添加订阅, 未指定 --secret 时随机生成签名密钥",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if secret == "" {
+				buf := make([]byte, 24)
+				rand.Read(buf)
+				secret = hex.EncodeToString(buf)
+			}
+
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			hook, err := webhook.Register(cmd.Context(), db, args[0], secret, events)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("✅ 已添加 webhook %d: %s (事件: %s)\n签名密钥: %s\n", hook.ID, hook.URL, hook.Events, hook.Secret)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&secret, "secret", "", "签名密钥")
+	cmd.Flags().StringSliceVar(&events, "events", nil, "订阅的事件类型, 如 "+blog.EventPostCreated+", 默认全部")
+	return cmd
+}
+
+func newWebhookListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "列出全部订阅",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			hooks, err := webhook.List(cmd.Context(), db)
+			if err != nil {
+				return err
+			}
+			for _, h := range hooks {
+				fmt.Printf("%d\t%s\t%s\t启用=%t\n", h.ID, h.URL, h.Events, h.Active)
+			}
+			return nil
+		},
+	}
+}
+
+func newWebhookRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <id>",
+		Short: "删除订阅, 未完成的推送不再进行",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("无效的 webhook ID %q", args[0])
+			}
+
+			db, err := blog.Open()
+			if err != nil {
+				return err
+			}
+			defer blog.Close(db)
+
+			if err := webhook.Remove(cmd.Context(), db, uint(id)); err != nil {
+				return err
+			}
+			fmt.Printf("✅ 已删除 webhook %d\n", id)
+			return nil
+		},
+	}
+}
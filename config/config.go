@@ -0,0 +1,673 @@
+// Package config 从环境变量读取运行配置, 未设置的项使用本地开发的默认值.
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Database 一个 MySQL 库的连接配置
+type Database struct {
+	User     string
+	Password string
+	Host     string
+	Port     string
+	Name     string
+
+	// 只读副本地址 (host:port), 账号和库名与主库相同; 为空时读写都走主库
+	Replicas []string
+	// 副本选择策略: random (默认) 或 round_robin
+	ReplicaPolicy string
+
+	// 连接池配置, 未设置的项为零值, 由调用方通过 Pool.WithDefaults 补齐
+	Pool Pool
+
+	// SharedPool 同一进程中的 GORM 和 sqlx 是否共用一个连接池, 只在两者连接同一个库时生效 (见 SameDatabase)
+	SharedPool bool
+}
+
+// Pool 连接池配置
+type Pool struct {
+	MaxOpen     int           `json:"max_open"`
+	MaxIdle     int           `json:"max_idle"`
+	MaxLifetime time.Duration `json:"max_lifetime"`
+	MaxIdleTime time.Duration `json:"max_idle_time"`
+
+	// 连接池使用率日志的输出间隔, 0 表示不输出
+	MonitorInterval time.Duration `json:"monitor_interval"`
+}
+
+// WithDefaults 用 d 补齐未设置 (零值) 的项
+func (p Pool) WithDefaults(d Pool) Pool {
+	if p.MaxOpen == 0 {
+		p.MaxOpen = d.MaxOpen
+	}
+	if p.MaxIdle == 0 {
+		p.MaxIdle = d.MaxIdle
+	}
+	if p.MaxLifetime == 0 {
+		p.MaxLifetime = d.MaxLifetime
+	}
+	if p.MaxIdleTime == 0 {
+		p.MaxIdleTime = d.MaxIdleTime
+	}
+	if p.MonitorInterval == 0 {
+		p.MonitorInterval = d.MonitorInterval
+	}
+	return p
+}
+
+// LoadDatabase 读取 DB_* 环境变量, DB_NAME 未设置时使用 defaultName:
+//
+//	DB_USER DB_PASS DB_HOST DB_PORT DB_NAME
+//	DB_REPLICAS=10.0.0.2:3306,10.0.0.3:3306  DB_REPLICA_POLICY=round_robin
+//	DB_MAX_OPEN_CONNS=100  DB_MAX_IDLE_CONNS=10  DB_CONN_MAX_LIFETIME=1h  DB_CONN_MAX_IDLE_TIME=10m
+//	DB_POOL_MONITOR_INTERVAL=1m
+//	DB_SHARED_POOL=true
+func LoadDatabase(defaultName string) Database {
+	cfg := Database{
+		User:          os.Getenv("DB_USER"),
+		Password:      os.Getenv("DB_PASS"),
+		Host:          getenv("DB_HOST", "localhost"),
+		Port:          getenv("DB_PORT", "3306"),
+		Name:          getenv("DB_NAME", defaultName),
+		ReplicaPolicy: getenv("DB_REPLICA_POLICY", "random"),
+		Pool: Pool{
+			MaxOpen:         getenvInt("DB_MAX_OPEN_CONNS"),
+			MaxIdle:         getenvInt("DB_MAX_IDLE_CONNS"),
+			MaxLifetime:     getenvDuration("DB_CONN_MAX_LIFETIME"),
+			MaxIdleTime:     getenvDuration("DB_CONN_MAX_IDLE_TIME"),
+			MonitorInterval: getenvDuration("DB_POOL_MONITOR_INTERVAL"),
+		},
+	}
+
+	cfg.SharedPool, _ = strconv.ParseBool(os.Getenv("DB_SHARED_POOL"))
+
+	// 账号和密码需同时设置, 否则一起使用默认值
+	if cfg.User == "" || cfg.Password == "" {
+		cfg.User = "root"
+		cfg.Password = "password"
+	}
+
+	for _, addr := range strings.Split(os.Getenv("DB_REPLICAS"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			cfg.Replicas = append(cfg.Replicas, addr)
+		}
+	}
+	return cfg
+}
+
+// SameDatabase 两份配置是否连接同一台服务器上的同一个库, 是则可以共用一个连接池.
+// 连接池的每个连接都绑定 DSN 中的库名, 库名不同时不能共用
+func (d Database) SameDatabase(o Database) bool {
+	return d.User == o.User && d.Password == o.Password && d.Host == o.Host && d.Port == o.Port && d.Name == o.Name
+}
+
+// DSN 返回主库的 go-sql-driver/mysql 连接串, params 为 ? 之后的参数
+func (d Database) DSN(params string) string {
+	return d.dsn(d.Host+":"+d.Port, params)
+}
+
+// ReplicaDSNs 返回所有只读副本的连接串
+func (d Database) ReplicaDSNs(params string) []string {
+	dsns := make([]string, len(d.Replicas))
+	for i, addr := range d.Replicas {
+		dsns[i] = d.dsn(addr, params)
+	}
+	return dsns
+}
+
+func (d Database) dsn(addr, params string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?%s", d.User, d.Password, addr, d.Name, params)
+}
+
+// HashID 对外 ID 编码配置
+type HashID struct {
+	Salt      string
+	MinLength int
+}
+
+// LoadHashID 读取 HASHID_SALT 和 HASHID_MIN_LENGTH, 默认值只适用于本地开发
+func LoadHashID() HashID {
+	cfg := HashID{
+		Salt:      getenv("HASHID_SALT", "dev-salt"),
+		MinLength: getenvInt("HASHID_MIN_LENGTH"),
+	}
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 8
+	}
+	return cfg
+}
+
+// LoadCommentRateLimit 读取 COMMENT_RATE_LIMIT: 每个用户每分钟最多发表的评论数,
+// 未设置时为 5, 设为负数时不限流
+func LoadCommentRateLimit() int {
+	if n := getenvInt("COMMENT_RATE_LIMIT"); n != 0 {
+		return n
+	}
+	return 5
+}
+
+// LoadSpamBannedWords 读取 SPAM_BANNED_WORDS: 逗号分隔的评论屏蔽词
+func LoadSpamBannedWords() []string {
+	return splitList(os.Getenv("SPAM_BANNED_WORDS"))
+}
+
+// LoadViewFlush 读取文章浏览数的刷新策略: 每隔 POST_VIEW_FLUSH_INTERVAL (默认 10s)
+// 或累计 POST_VIEW_FLUSH_THRESHOLD 次浏览 (默认 1000) 写入一次
+func LoadViewFlush() (interval time.Duration, threshold int) {
+	interval = getenvDuration("POST_VIEW_FLUSH_INTERVAL")
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	threshold = getenvInt("POST_VIEW_FLUSH_THRESHOLD")
+	if threshold <= 0 {
+		threshold = 1000
+	}
+	return interval, threshold
+}
+
+// Attachments 文章附件的存储和校验配置
+type Attachments struct {
+	Dir          string   // 本地存储目录
+	MaxSize      int64    // 单个附件的最大字节数
+	AllowedTypes []string // 允许的 MIME 类型, 按文件内容识别
+}
+
+// LoadAttachments 读取附件配置:
+//
+//	ATTACHMENT_DIR="data/attachments"  本地存储目录
+//	ATTACHMENT_MAX_SIZE=10485760       单个附件的最大字节数, 默认 10 MiB
+//	ATTACHMENT_ALLOWED_TYPES="image/png,image/jpeg,image/gif,image/webp,application/pdf"
+func LoadAttachments() Attachments {
+	cfg := Attachments{
+		Dir:          getenv("ATTACHMENT_DIR", "data/attachments"),
+		MaxSize:      int64(getenvInt("ATTACHMENT_MAX_SIZE")),
+		AllowedTypes: splitList(os.Getenv("ATTACHMENT_ALLOWED_TYPES")),
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 10 << 20
+	}
+	if len(cfg.AllowedTypes) == 0 {
+		cfg.AllowedTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf"}
+	}
+	return cfg
+}
+
+// LoadValidateResponses 读取 API_VALIDATE_RESPONSES: 为 true 时按 OpenAPI 文档校验 API 的响应,
+// 不符合时只记录日志. 校验需要缓存整个响应体, 默认关闭, 用于开发和测试环境
+func LoadValidateResponses() bool {
+	v, _ := strconv.ParseBool(os.Getenv("API_VALIDATE_RESPONSES"))
+	return v
+}
+
+// RateLimit 限流策略: 每 Per 时间最多 N 次, N <= 0 时不限流
+type RateLimit struct {
+	N   int
+	Per time.Duration
+}
+
+// ParseRateLimit 解析 "次数/时长" 格式的限流策略, 如 "600/1m"
+func ParseRateLimit(v string) (RateLimit, error) {
+	n, per, ok := strings.Cut(v, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("限流策略 %q 应为 次数/时长, 如 600/1m", v)
+	}
+	var (
+		rl  RateLimit
+		err error
+	)
+	if rl.N, err = strconv.Atoi(strings.TrimSpace(n)); err != nil {
+		return RateLimit{}, fmt.Errorf("限流策略 %q 的次数无效: %w", v, err)
+	}
+	if rl.Per, err = time.ParseDuration(strings.TrimSpace(per)); err != nil || rl.Per <= 0 {
+		return RateLimit{}, fmt.Errorf("限流策略 %q 的时长无效", v)
+	}
+	return rl, nil
+}
+
+// LoadRateLimit 读取名为 name 的限流策略 RATE_LIMIT_<NAME>, 格式见 ParseRateLimit, 如
+// RATE_LIMIT_GLOBAL=600/1m, 次数设为 0 时关闭该策略. 未设置或格式错误时返回 fallback
+func LoadRateLimit(name string, fallback RateLimit) RateLimit {
+	v := os.Getenv("RATE_LIMIT_" + strings.ToUpper(name))
+	if v == "" {
+		return fallback
+	}
+	rl, err := ParseRateLimit(v)
+	if err != nil {
+		return fallback
+	}
+	return rl
+}
+
+// Redis Redis 连接配置, Addr 为空表示未配置
+type Redis struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+// LoadRedis 读取 REDIS_ADDR (host:port)、REDIS_PASSWORD 和 REDIS_DB.
+// 配置后限流计数保存在 Redis 中, 多个 API 实例共享
+func LoadRedis() Redis {
+	return Redis{
+		Addr:     os.Getenv("REDIS_ADDR"),
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       getenvInt("REDIS_DB"),
+	}
+}
+
+// LoadTrustProxy 读取 TRUST_PROXY: 为 true 时服务部署在反向代理之后,
+// 客户端 IP 取 X-Forwarded-For 中代理追加的地址, 否则取 TCP 连接的对端地址
+func LoadTrustProxy() bool {
+	v, _ := strconv.ParseBool(os.Getenv("TRUST_PROXY"))
+	return v
+}
+
+// CORS 跨域访问配置
+type CORS struct {
+	AllowedOrigins   []string // "*" 表示任意来源, 为空时不允许跨域
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration // 预检结果的缓存时间
+}
+
+// LoadCORS 读取跨域配置:
+//
+//	CORS_ALLOWED_ORIGINS     逗号分隔的来源, 如 "https://blog.example.com", 默认不允许跨域
+//	CORS_ALLOWED_METHODS     默认 GET, POST, PUT, DELETE
+//	CORS_ALLOWED_HEADERS     默认 Authorization, Content-Type, X-Request-ID
+//	CORS_ALLOW_CREDENTIALS   为 true 时允许携带 Cookie 和认证信息
+//	CORS_MAX_AGE             如 10m, 默认 10 分钟
+func LoadCORS() CORS {
+	cfg := CORS{
+		AllowedOrigins: splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		AllowedMethods: splitList(getenv("CORS_ALLOWED_METHODS", "GET, POST, PUT, DELETE")),
+		AllowedHeaders: splitList(getenv("CORS_ALLOWED_HEADERS", "Authorization, Content-Type, X-Request-ID")),
+		MaxAge:         getenvDuration("CORS_MAX_AGE"),
+	}
+	cfg.AllowCredentials, _ = strconv.ParseBool(os.Getenv("CORS_ALLOW_CREDENTIALS"))
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 10 * time.Minute
+	}
+	return cfg
+}
+
+// Job 一个定期任务的配置
+type Job struct {
+	Enabled  bool
+	Schedule string // 格式见 jobs.Parse
+}
+
+// LoadJob 读取名为 name 的任务的配置, 变量名为任务名转大写:
+//
+//	JOB_<NAME>_ENABLED=false          关闭任务, 默认开启
+//	JOB_<NAME>_SCHEDULE="*/10 * * * *"  覆盖默认调度 defaultSchedule
+func LoadJob(name, defaultSchedule string) Job {
+	prefix := "JOB_" + strings.ToUpper(name) + "_"
+	enabled, err := strconv.ParseBool(os.Getenv(prefix + "ENABLED"))
+	return Job{
+		Enabled:  err != nil || enabled,
+		Schedule: getenv(prefix+"SCHEDULE", defaultSchedule),
+	}
+}
+
+// QueryTimeouts 数据库语句的默认超时, 0 表示不限制
+type QueryTimeouts struct {
+	Query  time.Duration // 查询, 同时作为 MySQL 的 MAX_EXECUTION_TIME
+	Create time.Duration
+	Update time.Duration
+	Delete time.Duration
+	Raw    time.Duration // db.Exec 执行的原生语句
+	Report time.Duration // 报表、导出和定期任务中标记为报表的语句, 代替上面的各项
+}
+
+// LoadQueryTimeouts 读取语句超时, 格式同 time.ParseDuration, 设置为 0 时不限制:
+//
+//	DB_QUERY_TIMEOUT=10s   DB_CREATE_TIMEOUT=30s  DB_UPDATE_TIMEOUT=30s
+//	DB_DELETE_TIMEOUT=30s  DB_RAW_TIMEOUT=30s     DB_REPORT_TIMEOUT=5m
+func LoadQueryTimeouts() QueryTimeouts {
+	return QueryTimeouts{
+		Query:  getenvDurationOr("DB_QUERY_TIMEOUT", 10*time.Second),
+		Create: getenvDurationOr("DB_CREATE_TIMEOUT", 30*time.Second),
+		Update: getenvDurationOr("DB_UPDATE_TIMEOUT", 30*time.Second),
+		Delete: getenvDurationOr("DB_DELETE_TIMEOUT", 30*time.Second),
+		Raw:    getenvDurationOr("DB_RAW_TIMEOUT", 30*time.Second),
+		Report: getenvDurationOr("DB_REPORT_TIMEOUT", 5*time.Minute),
+	}
+}
+
+// Breaker 数据库熔断配置
+type Breaker struct {
+	MaxFailures      int           // 连续失败多少次后熔断, 0 表示不熔断
+	OpenTimeout      time.Duration // 熔断多久后放行试探请求
+	HalfOpenRequests int           // 半开状态下放行的试探请求数, 全部成功后恢复
+}
+
+// LoadBreaker 读取数据库熔断配置:
+//
+//	DB_BREAKER_MAX_FAILURES=5  DB_BREAKER_OPEN_TIMEOUT=10s  DB_BREAKER_HALF_OPEN_REQUESTS=1
+func LoadBreaker() Breaker {
+	cfg := Breaker{
+		MaxFailures:      5,
+		OpenTimeout:      getenvDurationOr("DB_BREAKER_OPEN_TIMEOUT", 10*time.Second),
+		HalfOpenRequests: max(getenvInt("DB_BREAKER_HALF_OPEN_REQUESTS"), 1),
+	}
+	if v, err := strconv.Atoi(os.Getenv("DB_BREAKER_MAX_FAILURES")); err == nil {
+		cfg.MaxFailures = v
+	}
+	return cfg
+}
+
+// LoadTenantHosts 读取 TENANT_HOSTS: 域名到租户 ID 的映射, 如 "a.example.com=1, b.example.com=2".
+// 为空时是单租户部署; 格式错误的项忽略
+func LoadTenantHosts() map[string]uint {
+	hosts := make(map[string]uint)
+	for _, item := range splitList(os.Getenv("TENANT_HOSTS")) {
+		host, id, ok := strings.Cut(item, "=")
+		n, err := strconv.ParseUint(strings.TrimSpace(id), 10, 64)
+		if !ok || err != nil || n == 0 {
+			continue
+		}
+		hosts[strings.ToLower(strings.TrimSpace(host))] = uint(n)
+	}
+	return hosts
+}
+
+// TenantDatabases 独立库的租户的配置
+type TenantDatabases struct {
+	DSNs        map[uint]string // 租户 ID -> 连接串
+	IdleTimeout time.Duration   // 连接池空闲多久后关闭, 0 表示不关闭
+}
+
+// LoadTenantDatabases 读取独立库的租户:
+//
+//	TENANT_<ID>_DSN="user:pass@tcp(host:3306)/blog_t2"  租户 ID 的库, 未带参数时使用博客库的参数
+//	TENANT_DB_IDLE_TIMEOUT=10m                           租户连接池的空闲关闭时间, 默认 10 分钟
+func LoadTenantDatabases() TenantDatabases {
+	cfg := TenantDatabases{
+		DSNs:        make(map[uint]string),
+		IdleTimeout: getenvDurationOr("TENANT_DB_IDLE_TIMEOUT", 10*time.Minute),
+	}
+	for _, kv := range os.Environ() {
+		key, dsn, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, "TENANT_") || !strings.HasSuffix(key, "_DSN") || dsn == "" {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(key, "TENANT_"), "_DSN")
+		if n, err := strconv.ParseUint(id, 10, 64); err == nil && n > 0 {
+			cfg.DSNs[uint(n)] = dsn
+		}
+	}
+	return cfg
+}
+
+// FieldEncryption 敏感字段加密的密钥, 见 fieldcrypt
+type FieldEncryption struct {
+	Keys    []string // "<ID>:<base64 编码的 32 字节密钥>", 第一个用于加密, 其余只用于解密
+	HashKey string   // base64 编码的盲索引 HMAC 密钥, 设置后不能更换
+}
+
+// LoadFieldEncryption 读取敏感字段加密的密钥, 未设置时按明文存储:
+//
+//	FIELD_ENCRYPTION_KEYS="k2:<base64>, k1:<base64>"  FIELD_HASH_KEY="<base64>"
+func LoadFieldEncryption() FieldEncryption {
+	return FieldEncryption{
+		Keys:    splitList(os.Getenv("FIELD_ENCRYPTION_KEYS")),
+		HashKey: os.Getenv("FIELD_HASH_KEY"),
+	}
+}
+
+// EmailVerification 注册邮箱验证的配置
+type EmailVerification struct {
+	TTL time.Duration // 验证令牌的有效期
+	URL string        // 验证页面的地址, 邮件中的链接为 URL?token=<令牌>
+}
+
+// LoadEmailVerification 读取 EMAIL_VERIFICATION_TTL (默认 24h) 和 EMAIL_VERIFICATION_URL (未设置时邮件中只给出令牌)
+func LoadEmailVerification() EmailVerification {
+	return EmailVerification{
+		TTL: getenvDurationOr("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		URL: os.Getenv("EMAIL_VERIFICATION_URL"),
+	}
+}
+
+// LoadRedactFields 读取 LOG_REDACT_FIELDS: 逗号分隔的字段名, SQL 日志和结构化日志中这些列和键的值被遮盖,
+// 默认 password,email. 设置为 "-" 时不遮盖
+func LoadRedactFields() []string {
+	v := os.Getenv("LOG_REDACT_FIELDS")
+	switch v {
+	case "":
+		return []string{"password", "email"}
+	case "-":
+		return nil
+	}
+	return splitList(v)
+}
+
+// NPlusOne 开发环境的 N+1 查询检测, 见 nplusone
+type NPlusOne struct {
+	Enabled   bool
+	Threshold int // 同一请求中同一形状的单行查询执行多少次时告警
+}
+
+// LoadNPlusOne 读取 N+1 查询检测的配置: APP_ENV=development 时默认开启, NPLUSONE_DETECT=true/false 可强制开关;
+// NPLUSONE_THRESHOLD 为告警阈值, 默认 5
+func LoadNPlusOne() NPlusOne {
+	cfg := NPlusOne{
+		Enabled:   os.Getenv("APP_ENV") == "development",
+		Threshold: getenvInt("NPLUSONE_THRESHOLD"),
+	}
+	if v, err := strconv.ParseBool(os.Getenv("NPLUSONE_DETECT")); err == nil {
+		cfg.Enabled = v
+	}
+	if cfg.Threshold < 2 {
+		cfg.Threshold = 5
+	}
+	return cfg
+}
+
+// LoadIdempotencyTTL 读取 IDEMPOTENCY_TTL: 幂等键的保留时间, 期间同一键的重试返回第一次的结果, 默认 24h
+func LoadIdempotencyTTL() time.Duration {
+	if ttl := getenvDuration("IDEMPOTENCY_TTL"); ttl > 0 {
+		return ttl
+	}
+	return 24 * time.Hour
+}
+
+// LoadDefaultLocale 读取 DEFAULT_LOCALE: 请求没有 Accept-Language 或其中的语言都不支持时使用的语言, 默认 zh-CN
+func LoadDefaultLocale() string {
+	if v := os.Getenv("DEFAULT_LOCALE"); v != "" {
+		return v
+	}
+	return "zh-CN"
+}
+
+// LoadUUIDKeys 读取 UUID_KEYS: 为 true 时用户、文章和评论创建时生成 UUID 键, 对外接口可以用它代替编码后的 ID.
+// 开启后执行 migrate 为已有记录补全
+func LoadUUIDKeys() bool {
+	v, _ := strconv.ParseBool(os.Getenv("UUID_KEYS"))
+	return v
+}
+
+// IDGenerator 用户、文章和评论的主键生成方式
+type IDGenerator struct {
+	Snowflake bool // 为 true 时由应用按 snowflake 生成, 否则使用数据库自增
+	NodeID    int  // 本实例的 snowflake 节点 ID, 各实例必须不同
+}
+
+// LoadIDGenerator 读取 ID_GENERATOR (auto 或 snowflake, 默认 auto) 和 NODE_ID (默认 0).
+// 部署多个实例或分库时使用 snowflake, 并为每个实例设置不同的 NODE_ID
+func LoadIDGenerator() IDGenerator {
+	return IDGenerator{
+		Snowflake: os.Getenv("ID_GENERATOR") == "snowflake",
+		NodeID:    getenvInt("NODE_ID"),
+	}
+}
+
+// LoadCommentShards 读取 COMMENT_SHARDS, 评论按文章拆分到的分表数量, 默认 1 (不分表).
+// 大于 1 时须同时使用 ID_GENERATOR=snowflake; 修改已有数据的分表数量前先用 task3 blog reshard-comments 搬迁
+func LoadCommentShards() int {
+	return max(getenvInt("COMMENT_SHARDS"), 1)
+}
+
+// Partitioning 按月分区的表的维护配置
+type Partitioning struct {
+	Ahead     int            // 提前建好分区的月份数
+	Retention map[string]int // 表名 -> 保留的月份数, 未列出的表不删除分区
+}
+
+// LoadPartitioning 读取按月分区的维护配置:
+//
+//	PARTITION_AHEAD=3                       提前建好当月之后几个月的分区, 默认 3
+//	PARTITION_RETENTION="audit_logs=12"     各表保留的月份数, 更早的分区连同数据一起删除; 格式错误的项忽略
+//
+// 文章的分区删除后其评论、点赞等从属数据不会随之删除, 一般只为审计日志和评论设置保留期
+func LoadPartitioning() Partitioning {
+	cfg := Partitioning{Ahead: 3, Retention: make(map[string]int)}
+	if v, err := strconv.Atoi(os.Getenv("PARTITION_AHEAD")); err == nil && v >= 0 {
+		cfg.Ahead = v
+	}
+	for _, item := range splitList(os.Getenv("PARTITION_RETENTION")) {
+		table, months, ok := strings.Cut(item, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(months))
+		if !ok || err != nil || n <= 0 {
+			continue
+		}
+		cfg.Retention[strings.TrimSpace(table)] = n
+	}
+	return cfg
+}
+
+// LoadErasureGracePeriod 读取 ERASURE_GRACE_PERIOD, 用户申请注销后到执行前可以撤销的时间, 默认 720h (30 天)
+func LoadErasureGracePeriod() time.Duration {
+	return getenvDurationOr("ERASURE_GRACE_PERIOD", 30*24*time.Hour)
+}
+
+// Retention 过期数据的保留期, 0 表示不删除
+type Retention struct {
+	SoftDeleted   time.Duration // 软删除的记录在删除后保留多久
+	AuditLogs     time.Duration
+	Notifications time.Duration // 已读和未读的通知都按创建时间计算
+	StaleDrafts   time.Duration // 定时发布的文章过了计划时间且超过多久没有修改仍未发布时删除
+	BatchSize     int           // 每批删除的行数
+	Pause         time.Duration // 批次之间的暂停
+}
+
+// LoadRetention 读取过期数据的保留期, 默认都不删除:
+//
+//	RETENTION_SOFT_DELETED_DAYS=30    软删除的记录在删除多少天后物理删除
+//	RETENTION_AUDIT_LOG_DAYS=365      审计日志保留的天数
+//	RETENTION_NOTIFICATION_DAYS=90    通知保留的天数
+//	RETENTION_STALE_DRAFT_DAYS=180    过了计划时间仍未发布的定时文章在计划时间和最后一次修改后保留的天数
+//	RETENTION_BATCH_SIZE=1000         每批删除的行数, 默认 1000
+//	RETENTION_BATCH_PAUSE=100ms       批次之间的暂停, 默认 100ms
+func LoadRetention() Retention {
+	days := func(key string) time.Duration {
+		return time.Duration(max(getenvInt(key), 0)) * 24 * time.Hour
+	}
+	cfg := Retention{
+		SoftDeleted:   days("RETENTION_SOFT_DELETED_DAYS"),
+		AuditLogs:     days("RETENTION_AUDIT_LOG_DAYS"),
+		Notifications: days("RETENTION_NOTIFICATION_DAYS"),
+		StaleDrafts:   days("RETENTION_STALE_DRAFT_DAYS"),
+		BatchSize:     1000,
+		Pause:         getenvDurationOr("RETENTION_BATCH_PAUSE", 100*time.Millisecond),
+	}
+	if v, err := strconv.Atoi(os.Getenv("RETENTION_BATCH_SIZE")); err == nil && v > 0 {
+		cfg.BatchSize = v
+	}
+	return cfg
+}
+
+// CDC 行变更发布到 Kafka 的配置, 见 cdc 包
+type CDC struct {
+	KafkaRESTURL string        // Kafka REST Proxy 的地址, 为空时不捕获变更
+	TopicPrefix  string        // topic 的前缀, 表 t 的变更发布到 <TopicPrefix>.<t>
+	Timeout      time.Duration // 每次发布请求的超时
+}
+
+// Enabled 是否捕获并发布行变更
+func (c CDC) Enabled() bool {
+	return c.KafkaRESTURL != ""
+}
+
+// LoadCDC 读取 CDC 配置:
+//
+//	CDC_KAFKA_REST_URL=http://kafka-rest:8082  Kafka REST Proxy 的地址, 默认为空 (不捕获)
+//	CDC_TOPIC_PREFIX=task3.cdc                 topic 的前缀, 默认 task3.cdc
+//	CDC_KAFKA_TIMEOUT=10s                      发布请求的超时, 默认 10s
+func LoadCDC() CDC {
+	return CDC{
+		KafkaRESTURL: os.Getenv("CDC_KAFKA_REST_URL"),
+		TopicPrefix:  getenv("CDC_TOPIC_PREFIX", "task3.cdc"),
+		Timeout:      getenvDurationOr("CDC_KAFKA_TIMEOUT", 10*time.Second),
+	}
+}
+
+// Search 文章检索使用的搜索引擎 (Elasticsearch 或 OpenSearch), 见 search 包
+type Search struct {
+	URL     string        // 集群地址, 为空时只用数据库检索
+	Index   string        // 文章的索引名
+	Timeout time.Duration // 每次请求的超时
+}
+
+// Enabled 是否使用搜索引擎
+func (s Search) Enabled() bool {
+	return s.URL != ""
+}
+
+// LoadSearch 读取搜索引擎配置:
+//
+//	SEARCH_URL=http://user:pass@es:9200  集群地址, 默认为空 (只用数据库的 FULLTEXT 索引检索)
+//	SEARCH_INDEX=posts                   文章的索引名, 默认 posts
+//	SEARCH_TIMEOUT=2s                    请求的超时, 默认 2s, 超时后改用数据库检索
+func LoadSearch() Search {
+	return Search{
+		URL:     os.Getenv("SEARCH_URL"),
+		Index:   getenv("SEARCH_INDEX", "posts"),
+		Timeout: getenvDurationOr("SEARCH_TIMEOUT", 2*time.Second),
+	}
+}
+
+// splitList 拆分逗号分隔的列表, 忽略空项
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// 未设置或格式错误时返回 0
+func getenvInt(key string) int {
+	v, _ := strconv.Atoi(os.Getenv(key))
+	return v
+}
+
+func getenvDuration(key string) time.Duration {
+	v, _ := time.ParseDuration(os.Getenv(key))
+	return v
+}
+
+// 未设置或格式错误时返回 fallback, 与 getenvDuration 不同, 显式设置的 0 保留
+func getenvDurationOr(key string, fallback time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
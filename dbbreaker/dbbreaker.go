@@ -0,0 +1,223 @@
+// Package dbbreaker 数据库访问的熔断器: MySQL 不可用时连续失败达到阈值后熔断, 之后的请求立即返回
+// ErrDBUnavailable 而不是各自等待连接超时; 熔断 OpenTimeout 后进入半开状态放行少量试探请求,
+// 全部成功则恢复, 任一失败则再次熔断.
+//
+// GORM 通过 Plugin 接入, sqlx 通过 WrapConnector 在驱动层接入. 只有表明数据库不可用的错误 (见 IsUnavailable)
+// 计为失败, 记录不存在、唯一键冲突等业务错误不影响熔断.
+//
+// 各熔断器的状态和状态转换次数通过 expvar 以 "dbbreaker" 发布, 可从 /debug/vars 采集.
+package dbbreaker
+
+import (
+	"cmp"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/chaos"
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrDBUnavailable 熔断期间拒绝执行的数据库操作返回的错误
+var ErrDBUnavailable = errors.New("数据库暂时不可用")
+
+// State 熔断器状态
+type State int
+
+const (
+	Closed   State = iota // 正常放行
+	Open                  // 熔断, 全部拒绝
+	HalfOpen              // 放行少量试探请求
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	}
+	return "closed"
+}
+
+// Breaker 一个数据库的熔断器, 可并发使用
+type Breaker struct {
+	name string
+	cfg  config.Breaker
+	now  func() time.Time
+
+	mu          sync.Mutex
+	state       State
+	generation  uint64 // 每次状态转换递增, 之前放行的请求的结果不再计入
+	failures    int    // Closed 下的连续失败数
+	probes      int    // HalfOpen 下已放行的试探请求数
+	successes   int    // HalfOpen 下成功的试探请求数
+	openedAt    time.Time
+	rejected    int64
+	transitions map[string]int64 // "closed->open" -> 次数
+}
+
+// New 创建熔断器并登记到 expvar, name 为数据库名, 如 "blog"; cfg.MaxFailures <= 0 时始终放行
+func New(name string, cfg config.Breaker) *Breaker {
+	b := &Breaker{name: name, cfg: cfg, now: time.Now, transitions: make(map[string]int64)}
+	registry.Lock()
+	registry.breakers[name] = b
+	registry.Unlock()
+	return b
+}
+
+// Allow 判断是否放行一次操作. 放行时返回的 done 必须以操作的错误调用一次; 拒绝时返回 ErrDBUnavailable
+func (b *Breaker) Allow() (done func(err error), err error) {
+	if b.cfg.MaxFailures <= 0 {
+		return func(error) {}, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.setState(HalfOpen)
+	}
+	switch {
+	case b.state == Open, b.state == HalfOpen && b.probes >= b.cfg.HalfOpenRequests:
+		b.rejected++
+		return nil, fmt.Errorf("%s: %w", b.name, ErrDBUnavailable)
+	case b.state == HalfOpen:
+		b.probes++
+	}
+	gen := b.generation
+	return func(err error) { b.done(gen, err) }, nil
+}
+
+func (b *Breaker) done(gen uint64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen != b.generation {
+		return
+	}
+	failed := IsUnavailable(err)
+	switch b.state {
+	case Closed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.MaxFailures {
+			b.setState(Open)
+		}
+	case HalfOpen:
+		if failed {
+			b.setState(Open)
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.setState(Closed)
+		}
+	}
+}
+
+// setState 转换状态并清零计数, 调用方持有 mu
+func (b *Breaker) setState(to State) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.failures, b.probes, b.successes = 0, 0, 0
+	if to == Open {
+		b.openedAt = b.now()
+	}
+	b.transitions[from.String()+"->"+to.String()]++
+	log.Printf("数据库熔断器 %s: %s -> %s", b.name, from, to)
+}
+
+// State 返回当前状态; 熔断已到期但还没有请求触发转换时仍为 Open
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Status 一个熔断器的状态和累计指标
+type Status struct {
+	Name                string           `json:"name"`
+	State               string           `json:"state"`
+	ConsecutiveFailures int              `json:"consecutive_failures"`
+	Rejected            int64            `json:"rejected"`    // 熔断期间拒绝的操作数
+	Transitions         map[string]int64 `json:"transitions"` // 各状态转换的次数
+}
+
+// Status 返回当前状态和累计指标
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := Status{
+		Name:                b.name,
+		State:               b.state.String(),
+		ConsecutiveFailures: b.failures,
+		Rejected:            b.rejected,
+		Transitions:         make(map[string]int64, len(b.transitions)),
+	}
+	for k, v := range b.transitions {
+		st.Transitions[k] = v
+	}
+	return st
+}
+
+// IsUnavailable 错误是否表明数据库不可用: 连接失败或中断、连接数已满、服务器关闭、语句超时,
+// 以及 chaos 注入的瞬时错误. 调用方取消 (context.Canceled) 不计入
+func IsUnavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrDBUnavailable) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, chaos.ErrInjected) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1040, // Too many connections
+			1053, // Server shutdown in progress
+			3024: // MAX_EXECUTION_TIME exceeded
+			return true
+		}
+	}
+	return false
+}
+
+var registry = struct {
+	sync.Mutex
+	breakers map[string]*Breaker
+}{breakers: make(map[string]*Breaker)}
+
+// Statuses 返回全部熔断器的状态, 按名称排序
+func Statuses() []Status {
+	registry.Lock()
+	defer registry.Unlock()
+	list := make([]Status, 0, len(registry.breakers))
+	for _, b := range registry.breakers {
+		list = append(list, b.Status())
+	}
+	slices.SortFunc(list, func(a, b Status) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return list
+}
+
+func init() {
+	expvar.Publish("dbbreaker", expvar.Func(func() any { return Statuses() }))
+}
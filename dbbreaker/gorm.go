@@ -0,0 +1,68 @@
+package dbbreaker
+
+import (
+	"gorm.io/gorm"
+)
+
+const doneKey = "dbbreaker:done"
+
+// Plugin 在 GORM 的每类操作执行前询问熔断器, 执行后报告结果
+type Plugin struct {
+	breaker *Breaker
+}
+
+// NewPlugin 创建 GORM 插件, 通过 db.Use 注册
+func NewPlugin(b *Breaker) *Plugin {
+	return &Plugin{breaker: b}
+}
+
+// Name 实现 gorm.Plugin
+func (p *Plugin) Name() string {
+	return "dbbreaker"
+}
+
+// Initialize 实现 gorm.Plugin
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []error{
+		cb.Create().Before("gorm:create").Register("dbbreaker:before_create", p.before),
+		cb.Create().After("gorm:create").Register("dbbreaker:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("dbbreaker:before_query", p.before),
+		cb.Query().After("gorm:query").Register("dbbreaker:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("dbbreaker:before_update", p.before),
+		cb.Update().After("gorm:update").Register("dbbreaker:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("dbbreaker:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("dbbreaker:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("dbbreaker:before_row", p.before),
+		cb.Row().After("gorm:row").Register("dbbreaker:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("dbbreaker:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("dbbreaker:after_raw", p.after),
+	}
+	for _, err := range hooks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 拒绝时的错误写入 db.Error 后, 后续的 gorm 回调会跳过真正的 SQL 执行
+func (p *Plugin) before(db *gorm.DB) {
+	if db.Error != nil || db.DryRun {
+		return
+	}
+	done, err := p.breaker.Allow()
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(doneKey, done)
+}
+
+func (p *Plugin) after(db *gorm.DB) {
+	v, _ := db.InstanceGet(doneKey)
+	if done, ok := v.(func(error)); ok {
+		done(db.Error)
+		db.InstanceSet(doneKey, nil)
+	}
+}
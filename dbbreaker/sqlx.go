@@ -0,0 +1,119 @@
+package dbbreaker
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// WrapConnector 包装驱动连接器: 建立连接以及每次查询, 执行, 预编译和开启事务前询问熔断器, 之后报告结果.
+// 包装发生在驱动层, 用它打开的 *sql.DB (及 sqlx.NewDb) 的全部操作都受熔断保护
+func WrapConnector(inner driver.Connector, b *Breaker) driver.Connector {
+	return &connector{inner: inner, breaker: b}
+}
+
+type connector struct {
+	inner   driver.Connector
+	breaker *Breaker
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var cn driver.Conn
+	err := c.guard(func() (err error) {
+		cn, err = c.inner.Connect(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: cn, guard: c.guard}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.inner.Driver()
+}
+
+// guard 熔断器放行时执行 fn 并报告结果
+func (c *connector) guard(fn func() error) error {
+	done, err := c.breaker.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err)
+	return err
+}
+
+// conn 包装驱动连接; 底层连接不支持的可选接口返回 driver.ErrSkip, 由 database/sql 回退处理
+type conn struct {
+	driver.Conn
+	guard func(fn func() error) error
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	err = c.guard(func() error {
+		rows, err = q.QueryContext(ctx, query, args)
+		return err
+	})
+	return rows, err
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (res driver.Result, err error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	err = c.guard(func() error {
+		res, err = e.ExecContext(ctx, query, args)
+		return err
+	})
+	return res, err
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
+	err = c.guard(func() error {
+		if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+			stmt, err = p.PrepareContext(ctx, query)
+		} else {
+			stmt, err = c.Conn.Prepare(query)
+		}
+		return err
+	})
+	return stmt, err
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
+	err = c.guard(func() error {
+		if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+			tx, err = b.BeginTx(ctx, opts)
+		} else {
+			tx, err = c.Conn.Begin()
+		}
+		return err
+	})
+	return tx, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return c.guard(func() error { return p.Ping(ctx) })
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
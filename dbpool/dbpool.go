@@ -0,0 +1,137 @@
+// Package dbpool 管理 database/sql 连接池: 按配置初始化, 运行时调整参数而无需重启,
+// 并定期输出使用率, 在连接耗尽导致延迟飙升之前发现问题.
+//
+// 连接池状态同时通过 expvar 以 "dbpool" 发布, 可从 /debug/vars 采集.
+package dbpool
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/config"
+)
+
+// Apply 把配置应用到连接池, 可在运行时重复调用
+func Apply(db *sql.DB, p config.Pool) {
+	db.SetMaxOpenConns(p.MaxOpen)
+	db.SetMaxIdleConns(p.MaxIdle)
+	db.SetConnMaxLifetime(p.MaxLifetime)
+	db.SetConnMaxIdleTime(p.MaxIdleTime)
+}
+
+// Status 一个连接池的当前配置和使用情况
+type Status struct {
+	Name   string      `json:"name"`
+	Config config.Pool `json:"config"`
+	sql.DBStats
+	Utilization float64 `json:"utilization"` // InUse / MaxOpen, 未限制 MaxOpen 时为 0
+}
+
+type pool struct {
+	db  *sql.DB
+	cfg config.Pool
+}
+
+// Manager 按名称管理多个连接池, 可并发使用
+type Manager struct {
+	mu    sync.Mutex
+	pools map[string]*pool
+}
+
+// NewManager 创建管理器
+func NewManager() *Manager {
+	return &Manager{pools: make(map[string]*pool)}
+}
+
+// Default 进程内共享的管理器, 已发布到 expvar
+var Default = NewManager()
+
+func init() {
+	expvar.Publish("dbpool", expvar.Func(func() any { return Default.Status() }))
+}
+
+// Register 应用配置并登记连接池, 同名连接池会被替换
+func (m *Manager) Register(name string, db *sql.DB, cfg config.Pool) {
+	Apply(db, cfg)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pools[name] = &pool{db: db, cfg: cfg}
+}
+
+// Unregister 移除登记的连接池, 连接池本身由调用方关闭
+func (m *Manager) Unregister(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pools, name)
+}
+
+// Update 运行时调整连接池, 只修改 patch 中的非零项
+func (m *Manager) Update(name string, patch config.Pool) (config.Pool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pools[name]
+	if !ok {
+		return config.Pool{}, fmt.Errorf("连接池 %s 不存在", name)
+	}
+
+	p.cfg = patch.WithDefaults(p.cfg)
+	Apply(p.db, p.cfg)
+	log.Printf("连接池 %s 已调整: max_open=%d max_idle=%d max_lifetime=%s max_idle_time=%s",
+		name, p.cfg.MaxOpen, p.cfg.MaxIdle, p.cfg.MaxLifetime, p.cfg.MaxIdleTime)
+	return p.cfg, nil
+}
+
+// Status 返回全部连接池的状态, 按名称排序
+func (m *Manager) Status() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Status, 0, len(m.pools))
+	for name, p := range m.pools {
+		st := Status{Name: name, Config: p.cfg, DBStats: p.db.Stats()}
+		if st.MaxOpenConnections > 0 {
+			st.Utilization = float64(st.InUse) / float64(st.MaxOpenConnections)
+		}
+		list = append(list, st)
+	}
+	slices.SortFunc(list, func(a, b Status) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return list
+}
+
+// Monitor 每隔 interval 输出一次各连接池的使用情况, 直到 ctx 取消.
+// 使用率达到 warnAt (如 0.8) 或出现等待连接时输出告警.
+func (m *Manager) Monitor(ctx context.Context, interval time.Duration, warnAt float64) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastWait := make(map[string]int64)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, st := range m.Status() {
+			waited := st.WaitCount - lastWait[st.Name]
+			lastWait[st.Name] = st.WaitCount
+
+			level := "📊"
+			if (warnAt > 0 && st.Utilization >= warnAt) || waited > 0 {
+				level = "⚠️"
+			}
+			log.Printf("%s 连接池 %s: 使用中 %d/%d (%.0f%%), 空闲 %d, 本周期等待 %d 次, 累计等待 %s",
+				level, st.Name, st.InUse, st.MaxOpenConnections, st.Utilization*100,
+				st.Idle, waited, st.WaitDuration.Round(time.Millisecond))
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package dbpool
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alexwang789/Base1_golang_task3/config"
+)
+
+// Handler 连接池运行时接口:
+//
+//	GET  返回全部连接池的状态
+//	POST ?name=blog  请求体为 config.Pool 的 JSON, 只修改给出的非零项
+func (m *Manager) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, m.Status())
+
+		case http.MethodPost:
+			var patch config.Pool
+			if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+				http.Error(w, "请求体格式错误: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if patch.MaxOpen < 0 || patch.MaxIdle < 0 || patch.MaxLifetime < 0 || patch.MaxIdleTime < 0 {
+				http.Error(w, "连接池参数不能为负数", http.StatusBadRequest)
+				return
+			}
+			cfg, err := m.Update(r.URL.Query().Get("name"), patch)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			writeJSON(w, http.StatusOK, cfg)
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "不支持的请求方法", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
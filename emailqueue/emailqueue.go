@@ -0,0 +1,107 @@
+// Package emailqueue 以数据库表作为邮件发送队列.
+//
+// 业务代码在自己的事务中调用 Enqueue 写入 email_queue, 事务回滚时邮件也不会发出;
+// Worker 轮询到期的邮件并发发送, 失败按指数退避重试, 超过最大次数后转为 dead 状态 (死信),
+// 各状态的数量可通过 Stats 观察.
+package emailqueue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Status 邮件状态
+type Status string
+
+const (
+	StatusPending Status = "pending" // 等待发送或等待重试
+	StatusSending Status = "sending" // 已被 worker 领取
+	StatusSent    Status = "sent"    // 发送成功
+	StatusDead    Status = "dead"    // 超过最大重试次数, 不再发送
+)
+
+// Email 队列中的一封邮件
+type Email struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	To            string    `gorm:"size:255;not null"`
+	Subject       string    `gorm:"size:255;not null"`
+	Body          string    `gorm:"type:text;not null"`
+	Status        Status    `gorm:"size:20;not null;default:'pending';index:idx_email_queue_due,priority:1"`
+	Attempts      int       `gorm:"not null;default:0"`
+	MaxAttempts   int       `gorm:"not null;default:5"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_email_queue_due,priority:2"`
+	LastError     string    `gorm:"size:1000"`
+	SentAt        *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// TableName 指定表名
+func (Email) TableName() string {
+	return "email_queue"
+}
+
+// Message 待发送的邮件内容
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Enqueue 把邮件写入队列. 传入业务事务的 tx, 使邮件与业务数据一起提交或回滚
+func Enqueue(tx *gorm.DB, msg Message) error {
+	email := Email{
+		To:            msg.To,
+		Subject:       msg.Subject,
+		Body:          msg.Body,
+		Status:        StatusPending,
+		MaxAttempts:   5,
+		NextAttemptAt: time.Now(),
+	}
+	if err := tx.Create(&email).Error; err != nil {
+		return fmt.Errorf("写入邮件队列失败: %w", err)
+	}
+	return nil
+}
+
+// Stats 返回各状态的邮件数量
+func Stats(ctx context.Context, db *gorm.DB) (map[Status]int64, error) {
+	var rows []struct {
+		Status Status
+		Count  int64
+	}
+	err := db.WithContext(ctx).Model(&Email{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计邮件队列失败: %w", err)
+	}
+
+	stats := make(map[Status]int64, len(rows))
+	for _, row := range rows {
+		stats[row.Status] = row.Count
+	}
+	return stats, nil
+}
+
+// Requeue 把死信重新放回队列, 重置重试次数
+func Requeue(ctx context.Context, db *gorm.DB, id uint) error {
+	result := db.WithContext(ctx).Model(&Email{}).
+		Where("id = ? AND status = ?", id, StatusDead).
+		Updates(map[string]any{
+			"status":          StatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("重新入队失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("邮件 %d 不是死信", id)
+	}
+	return nil
+}
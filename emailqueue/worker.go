@@ -0,0 +1,178 @@
+package emailqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sender 实际发送邮件的后端 (SMTP, 第三方 API 等)
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender 只打印日志的发送器, 用于开发环境
+type LogSender struct{}
+
+// Send 实现 Sender
+func (LogSender) Send(_ context.Context, msg Message) error {
+	log.Printf("📧 发送邮件 to=%s subject=%s", msg.To, msg.Subject)
+	return nil
+}
+
+// Options worker 配置, 零值字段使用默认值
+type Options struct {
+	Concurrency  int           // 并发发送数, 默认 4
+	BatchSize    int           // 每次领取的邮件数, 默认 20
+	PollInterval time.Duration // 队列为空时的轮询间隔, 默认 2s
+	BaseBackoff  time.Duration // 首次重试等待, 之后每次翻倍, 默认 30s
+	MaxBackoff   time.Duration // 重试等待上限, 默认 1h
+	Lease        time.Duration // 领取后超过该时间仍未完成视为 worker 崩溃, 重新投递, 默认 5m
+}
+
+func (o *Options) setDefaults() {
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 20
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = 2 * time.Second
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = 30 * time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = time.Hour
+	}
+	if o.Lease <= 0 {
+		o.Lease = 5 * time.Minute
+	}
+}
+
+// Worker 从队列领取邮件并发送
+type Worker struct {
+	db     *gorm.DB
+	sender Sender
+	opts   Options
+}
+
+// NewWorker 创建 worker
+func NewWorker(db *gorm.DB, sender Sender, opts Options) *Worker {
+	opts.setDefaults()
+	return &Worker{db: db, sender: sender, opts: opts}
+}
+
+// Run 持续处理队列直到 ctx 取消, 返回前等待已领取的邮件处理完成
+func (w *Worker) Run(ctx context.Context) error {
+	sem := make(chan struct{}, w.opts.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		emails, err := w.claim(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("领取邮件失败: %v", err)
+		}
+
+		for _, email := range emails {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(email Email) {
+				defer func() { <-sem; wg.Done() }()
+				w.deliver(context.WithoutCancel(ctx), email)
+			}(email)
+		}
+
+		// 领满一批说明可能还有积压, 立即继续; 否则等待下一轮
+		if len(emails) == w.opts.BatchSize {
+			if ctx.Err() != nil {
+				return nil
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.opts.PollInterval):
+		}
+	}
+}
+
+// claim 在事务中锁定一批到期邮件并标记为 sending, SKIP LOCKED 让多个 worker 互不阻塞
+func (w *Worker) claim(ctx context.Context) ([]Email, error) {
+	var emails []Email
+	err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND updated_at < ?)",
+				StatusPending, now, StatusSending, now.Add(-w.opts.Lease)).
+			Order("next_attempt_at").
+			Limit(w.opts.BatchSize).
+			Find(&emails).Error
+		if err != nil || len(emails) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(emails))
+		for i, email := range emails {
+			ids[i] = email.ID
+		}
+		return tx.Model(&Email{}).Where("id IN ?", ids).
+			Updates(map[string]any{"status": StatusSending, "updated_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+// deliver 发送一封邮件并记录结果
+func (w *Worker) deliver(ctx context.Context, email Email) {
+	msg := Message{To: email.To, Subject: email.Subject, Body: email.Body}
+	sendErr := w.sender.Send(ctx, msg)
+
+	updates := map[string]any{"attempts": email.Attempts + 1}
+	switch {
+	case sendErr == nil:
+		now := time.Now()
+		updates["status"] = StatusSent
+		updates["sent_at"] = &now
+		updates["last_error"] = ""
+	case email.Attempts+1 >= email.MaxAttempts:
+		updates["status"] = StatusDead
+		updates["last_error"] = truncate(sendErr.Error(), 1000)
+		log.Printf("邮件 %d 已重试 %d 次, 转入死信: %v", email.ID, email.Attempts+1, sendErr)
+	default:
+		updates["status"] = StatusPending
+		updates["next_attempt_at"] = time.Now().Add(w.backoff(email.Attempts))
+		updates["last_error"] = truncate(sendErr.Error(), 1000)
+	}
+
+	if err := w.db.WithContext(ctx).Model(&Email{}).Where("id = ?", email.ID).Updates(updates).Error; err != nil {
+		log.Printf("更新邮件 %d 状态失败: %v", email.ID, err)
+	}
+}
+
+// backoff 第 attempts 次失败后的等待时间: BaseBackoff * 2^attempts, 不超过 MaxBackoff
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.opts.BaseBackoff
+	for i := 0; i < attempts && d < w.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, w.opts.MaxBackoff)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return fmt.Sprintf("%.*s...", n-3, s)
+}
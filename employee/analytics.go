@@ -0,0 +1,181 @@
+package employee
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 员工薪资分析. MySQL 8.0 / MariaDB 10.2 起用窗口函数, 更早的版本用等价的关联子查询 (每行扫描一次员工表, 只适合小表).
+// 是否支持窗口函数按 SELECT VERSION() 判断, 每个连接池只查一次
+
+// SalaryRank 员工在部门内的薪资名次, 薪资相同的名次相同 (与 RANK() 一致, 之后的名次跳过)
+type SalaryRank struct {
+	Employee
+	Rank int `db:"rank_no" json:"rank"`
+}
+
+// SalaryPercentile 员工薪资在全公司的百分位: 薪资低于该员工的人数占其他员工的百分比 (与 PERCENT_RANK() 一致),
+// 最低薪资为 0, 最高薪资为 100, 只有一名员工时为 0
+type SalaryPercentile struct {
+	Employee
+	Percentile float64 `db:"percentile" json:"percentile"`
+}
+
+// PayrollRunningTotal 按员工 ID (入职顺序) 累计到该员工为止的工资总额
+type PayrollRunningTotal struct {
+	Employee
+	RunningTotal int64 `db:"running_total" json:"running_total"`
+}
+
+// 分析查询, 每个查询有窗口函数版和兼容版两种写法, 结果相同
+var (
+	// 部门内的薪资名次, :department 为空时返回全部部门
+	sqlSalaryRanks = analyticsQuery{
+		window: `
+			SELECT id, name, department, salary,
+				RANK() OVER (PARTITION BY department ORDER BY salary DESC) AS rank_no
+			FROM employees
+			WHERE :department = '' OR department = :department
+			ORDER BY department, rank_no, id
+		`,
+		legacy: `
+			SELECT e.id, e.name, e.department, e.salary,
+				1 + (SELECT COUNT(*) FROM employees o WHERE o.department = e.department AND o.salary > e.salary) AS rank_no
+			FROM employees e
+			WHERE :department = '' OR e.department = :department
+			ORDER BY e.department, rank_no, e.id
+		`,
+	}
+	// 全公司的薪资百分位
+	sqlSalaryPercentiles = analyticsQuery{
+		window: `
+			SELECT id, name, department, salary,
+				PERCENT_RANK() OVER (ORDER BY salary) * 100 AS percentile
+			FROM employees
+			ORDER BY salary DESC, id
+		`,
+		legacy: `
+			SELECT e.id, e.name, e.department, e.salary,
+				COALESCE((SELECT COUNT(*) FROM employees o WHERE o.salary < e.salary)
+					/ NULLIF((SELECT COUNT(*) FROM employees) - 1, 0), 0) * 100 AS percentile
+			FROM employees e
+			ORDER BY e.salary DESC, e.id
+		`,
+	}
+	// 按 ID 累计的工资总额, :department 为空时累计全公司, 否则只累计该部门
+	sqlPayrollRunningTotals = analyticsQuery{
+		window: `
+			SELECT id, name, department, salary,
+				SUM(salary) OVER (ORDER BY id) AS running_total
+			FROM employees
+			WHERE :department = '' OR department = :department
+			ORDER BY id
+		`,
+		legacy: `
+			SELECT e.id, e.name, e.department, e.salary,
+				(SELECT SUM(o.salary) FROM employees o
+				 WHERE o.id <= e.id AND (:department = '' OR o.department = :department)) AS running_total
+			FROM employees e
+			WHERE :department = '' OR e.department = :department
+			ORDER BY e.id
+		`,
+	}
+)
+
+// analyticsQuery 同一分析的窗口函数版和兼容版语句, 使用 :name 参数
+type analyticsQuery struct {
+	window string
+	legacy string
+}
+
+// SalaryRanks 返回部门内的薪资名次, 按部门、名次排序. department 为空时返回全部部门
+func SalaryRanks(ctx context.Context, db sqlx.QueryerContext, department string) ([]SalaryRank, error) {
+	var ranks []SalaryRank
+	if err := selectAnalytics(ctx, db, &ranks, sqlSalaryRanks, map[string]any{"department": department}); err != nil {
+		return nil, fmt.Errorf("查询部门薪资名次失败: %w", err)
+	}
+	return ranks, nil
+}
+
+// SalaryPercentiles 返回每名员工的薪资百分位, 薪资高的在前
+func SalaryPercentiles(ctx context.Context, db sqlx.QueryerContext) ([]SalaryPercentile, error) {
+	var percentiles []SalaryPercentile
+	if err := selectAnalytics(ctx, db, &percentiles, sqlSalaryPercentiles, map[string]any{}); err != nil {
+		return nil, fmt.Errorf("查询薪资百分位失败: %w", err)
+	}
+	return percentiles, nil
+}
+
+// PayrollRunningTotals 按员工 ID 顺序返回累计工资总额. department 为空时累计全公司, 否则只累计该部门的员工
+func PayrollRunningTotals(ctx context.Context, db sqlx.QueryerContext, department string) ([]PayrollRunningTotal, error) {
+	var totals []PayrollRunningTotal
+	if err := selectAnalytics(ctx, db, &totals, sqlPayrollRunningTotals, map[string]any{"department": department}); err != nil {
+		return nil, fmt.Errorf("查询累计工资总额失败: %w", err)
+	}
+	return totals, nil
+}
+
+// selectAnalytics 按数据库是否支持窗口函数选择语句执行
+func selectAnalytics(ctx context.Context, db sqlx.QueryerContext, dest any, q analyticsQuery, arg map[string]any) error {
+	window, err := supportsWindowFunctions(ctx, db)
+	if err != nil {
+		return err
+	}
+	query := q.legacy
+	if window {
+		query = q.window
+	}
+	bound, args, err := sqlx.Named(query, arg)
+	if err != nil {
+		return err
+	}
+	return sqlx.SelectContext(ctx, db, dest, bound, args...)
+}
+
+// 各连接池是否支持窗口函数, *sqlx.DB -> bool. 事务等其他 db 每次查询版本
+var windowSupport sync.Map
+
+// supportsWindowFunctions 数据库是否支持窗口函数
+func supportsWindowFunctions(ctx context.Context, db sqlx.QueryerContext) (bool, error) {
+	pool, cacheable := db.(*sqlx.DB)
+	if cacheable {
+		if ok, found := windowSupport.Load(pool); found {
+			return ok.(bool), nil
+		}
+	}
+	var version string
+	if err := sqlx.GetContext(ctx, db, &version, "SELECT VERSION()"); err != nil {
+		return false, fmt.Errorf("查询数据库版本失败: %w", err)
+	}
+	ok := windowFunctionsAvailable(version)
+	if cacheable {
+		windowSupport.Store(pool, ok)
+	}
+	return ok, nil
+}
+
+// windowFunctionsAvailable 按 VERSION() 的结果判断, 如 "8.0.36"、"5.7.44-log"、"10.6.16-MariaDB-1:10.6.16+maria~ubu2204".
+// 无法解析的版本按不支持处理, 兼容版的结果相同, 只是慢
+func windowFunctionsAvailable(version string) bool {
+	mariadb := strings.Contains(version, "MariaDB")
+	// 部分 MariaDB 以 5.5.5- 前缀兼容旧客户端
+	version = strings.TrimPrefix(version, "5.5.5-")
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	if mariadb {
+		return major > 10 || major == 10 && minor >= 2
+	}
+	return major >= 8
+}
@@ -0,0 +1,109 @@
+package employee
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLite 同时支持窗口函数和兼容写法, 用来核对两种写法的结果相同
+func TestDepartmentHeadcountRankingQueriesAgree(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	db.MustExec(`CREATE TABLE departments (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`)
+	db.MustExec(`CREATE TABLE employees (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		name          TEXT NOT NULL,
+		department    TEXT NOT NULL,
+		salary        INTEGER NOT NULL,
+		department_id INTEGER
+	)`)
+	// 人数 3, 2, 2, 1, 0: 名次 1, 2, 2, 4, 没有员工的部门不列出
+	for id, d := range []struct {
+		name      string
+		headcount int
+	}{{"技术部", 3}, {"市场部", 2}, {"财务部", 2}, {"行政部", 1}, {"法务部", 0}} {
+		db.MustExec(`INSERT INTO departments (id, name) VALUES (?, ?)`, id+1, d.name)
+		for i := range d.headcount {
+			db.MustExec(`INSERT INTO employees (name, department, salary, department_id) VALUES (?, ?, ?, ?)`,
+				d.name+string(rune('A'+i)), d.name, 5000+1000*i, id+1)
+		}
+	}
+
+	want := []struct {
+		department string
+		headcount  int
+		rank       int
+	}{{"技术部", 3, 1}, {"市场部", 2, 2}, {"财务部", 2, 2}, {"行政部", 1, 4}}
+
+	for name, query := range map[string]string{"窗口函数": sqlDepartmentHeadcountRanking.window, "兼容写法": sqlDepartmentHeadcountRanking.legacy} {
+		t.Run(name, func(t *testing.T) {
+			var stats []DepartmentStats
+			if err := db.Select(&stats, query); err != nil {
+				t.Fatal(err)
+			}
+			if len(stats) != len(want) {
+				t.Fatalf("人数排名 = %+v", stats)
+			}
+			// 同名次的部门按名称排序, 比较时不依赖排序规则
+			for i, w := range want {
+				found := false
+				for _, s := range stats {
+					if s.Department == w.department {
+						found = true
+						if s.Headcount != w.headcount || s.Rank != w.rank {
+							t.Errorf("%s: 人数 %d 名次 %d, 期望 %d 和 %d", w.department, s.Headcount, s.Rank, w.headcount, w.rank)
+						}
+					}
+				}
+				if !found {
+					t.Errorf("缺少部门 %s", w.department)
+				}
+				if stats[i].Rank != w.rank {
+					t.Errorf("第 %d 行的名次 = %d, 期望 %d", i+1, stats[i].Rank, w.rank)
+				}
+			}
+			if stats[0].Payroll != 5000+6000+7000 {
+				t.Errorf("技术部工资总额 = %d", stats[0].Payroll)
+			}
+		})
+	}
+}
+
+// 按数据库版本选择写法, 不支持窗口函数的版本不发送 RANK() OVER
+func TestDepartmentHeadcountRankingFallback(t *testing.T) {
+	tests := []struct {
+		version string
+		query   string
+	}{
+		{"8.0.36", "RANK() OVER (ORDER BY COUNT(e.id) DESC)"},
+		{"5.7.44-log", "WHERE o.headcount > s.headcount"},
+		{"10.1.48-MariaDB", "WHERE o.headcount > s.headcount"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			db, mock := newMock(t)
+			mock.ExpectQuery(quote("SELECT VERSION()")).
+				WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(tt.version))
+			mock.ExpectQuery(quote(tt.query)).
+				WillReturnRows(sqlmock.NewRows([]string{"department_id", "department", "headcount", "rank_no"}).
+					AddRow(1, "技术部", 3, 1))
+
+			stats, err := DepartmentHeadcountRanking(context.Background(), db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stats) != 1 || stats[0].Rank != 1 {
+				t.Errorf("人数排名 = %+v", stats)
+			}
+		})
+	}
+}
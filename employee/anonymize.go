@@ -0,0 +1,29 @@
+package employee
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/anonymize"
+	"github.com/jmoiron/sqlx"
+)
+
+// AnonymizeNames 把全部员工的姓名改写为假姓名, 部门、薪资和主键不变. 用于在本地调试时使用生产数据的副本.
+// 直接改写列值, 不写审计日志. 返回改写的员工数
+func AnonymizeNames(ctx context.Context, db *sqlx.DB, faker *anonymize.Faker) (int, error) {
+	var ids []int
+	if err := db.SelectContext(ctx, &ids, "SELECT id FROM employees ORDER BY id"); err != nil {
+		return 0, fmt.Errorf("查询员工失败: %w", err)
+	}
+	stmt, err := db.PreparexContext(ctx, "UPDATE employees SET name = ? WHERE id = ?")
+	if err != nil {
+		return 0, fmt.Errorf("改写员工姓名失败: %w", err)
+	}
+	defer stmt.Close()
+	for i, id := range ids {
+		if _, err := stmt.ExecContext(ctx, faker.Person(uint64(id)).Name, id); err != nil {
+			return i, fmt.Errorf("改写员工 %d 的姓名失败: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,89 @@
+package employee
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Department 部门, employees.department_id 引用 departments.id.
+// employees.department 中的部门名保留不变, 已有的按部门名查询无需修改
+type Department struct {
+	ID   int    `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+}
+
+const (
+	// 把员工表中出现的部门名补充到部门表
+	sqlInsertMissingDepartments = `
+		INSERT IGNORE INTO departments (name)
+		SELECT DISTINCT department FROM employees WHERE department <> ''
+	`
+	// 按部门名回填 department_id
+	sqlSyncEmployeeDepartments = `
+		UPDATE employees e
+		JOIN departments d ON d.name = e.department
+		SET e.department_id = d.id
+		WHERE e.department_id IS NULL OR e.department_id <> d.id
+	`
+)
+
+// 创建部门表并为员工表添加外键, 可重复执行
+func migrateDepartments(ctx context.Context, db *sqlx.DB) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS departments (
+			id   INT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			UNIQUE KEY uk_departments_name (name)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建部门表失败: %w", err)
+	}
+
+	// MySQL 不支持 ADD COLUMN IF NOT EXISTS, 先查询列是否存在
+	var n int
+	err = db.GetContext(ctx, &n, `
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'employees' AND COLUMN_NAME = 'department_id'
+	`)
+	if err != nil {
+		return fmt.Errorf("查询表结构失败: %w", err)
+	}
+	if n == 0 {
+		_, err := db.ExecContext(ctx, `
+			ALTER TABLE employees
+			ADD COLUMN department_id INT NULL,
+			ADD CONSTRAINT fk_employees_department FOREIGN KEY (department_id) REFERENCES departments (id)
+		`)
+		if err != nil {
+			return fmt.Errorf("添加部门外键失败: %w", err)
+		}
+	}
+
+	return syncEmployeeDepartments(ctx, db)
+}
+
+// 按员工的部门名补齐部门表并回填 department_id
+func syncEmployeeDepartments(ctx context.Context, db sqlx.ExecerContext) error {
+	if _, err := db.ExecContext(ctx, sqlInsertMissingDepartments); err != nil {
+		return fmt.Errorf("补充部门失败: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, sqlSyncEmployeeDepartments); err != nil {
+		return fmt.Errorf("回填员工部门失败: %w", err)
+	}
+	return nil
+}
+
+// 确保部门存在, 返回部门 ID
+func ensureDepartment(ctx context.Context, db sqlx.ExtContext, name string) (int, error) {
+	if _, err := db.ExecContext(ctx, "INSERT IGNORE INTO departments (name) VALUES (?)", name); err != nil {
+		return 0, fmt.Errorf("创建部门失败: %w", err)
+	}
+	var id int
+	if err := sqlx.GetContext(ctx, db, &id, "SELECT id FROM departments WHERE name = ?", name); err != nil {
+		return 0, fmt.Errorf("查询部门失败: %w", err)
+	}
+	return id, nil
+}
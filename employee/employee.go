@@ -0,0 +1,376 @@
+// Package employee 员工模块 (sqlx): 员工的查询、增删改、部门统计、薪资历史、CSV 导入和导出.
+package employee
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/audit"
+	"github.com/alexwang789/Base1_golang_task3/chaos"
+	"github.com/alexwang789/Base1_golang_task3/collate"
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/dbbreaker"
+	"github.com/alexwang789/Base1_golang_task3/dbpool"
+	"github.com/alexwang789/Base1_golang_task3/replica"
+	"github.com/alexwang789/Base1_golang_task3/stmtcache"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// Employee 结构体映射 employees 表, 上级 (manager_id) 见 OrgNode 和 SetManager
+type Employee struct {
+	ID         int    `db:"id" json:"id"`
+	Name       string `db:"name" json:"name"`
+	Department string `db:"department" json:"department"`
+	Salary     int    `db:"salary" json:"salary"`
+}
+
+// 员工模块的命名查询, 执行计划检查 (queryplans.go) 也引用这些语句
+const (
+	// 按部门查询员工
+	sqlEmployeesByDepartment = `
+		SELECT id, name, department, salary
+		FROM employees
+		WHERE department = :department
+	`
+	// 工资最高的一名员工
+	sqlHighestPaidEmployee = `
+		SELECT id, name, department, salary
+		FROM employees
+		ORDER BY salary DESC
+		LIMIT 1
+	`
+	// 所有并列最高工资的员工
+	sqlAllHighestPaidEmployees = `
+		SELECT id, name, department, salary
+		FROM employees
+		WHERE salary = (SELECT MAX(salary) FROM employees)
+	`
+)
+
+// DefaultBatchSize 批量插入时每条 INSERT 语句包含的默认行数
+const DefaultBatchSize = 500
+
+// DSNParams 人事库连接串的参数
+const DSNParams = "parseTime=true"
+
+// Open 按 company_db 配置连接主库和只读副本, 并注册连接池监控
+func Open() (*replica.Cluster, error) {
+	// 从环境变量获取数据库配置
+	cfg := config.LoadDatabase("company_db")
+	params := DSNParams
+
+	policy, err := replica.ParsePolicy(cfg.ReplicaPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	// 环境变量未设置的连接池参数使用默认值
+	pool := cfg.Pool.WithDefaults(config.Pool{
+		MaxOpen:     25,
+		MaxIdle:     10,
+		MaxLifetime: 5 * time.Minute,
+	})
+
+	primary, err := OpenSqlx(cfg.DSN(params))
+	if err != nil {
+		return nil, fmt.Errorf("数据库连接失败: %w", err)
+	}
+
+	dbpool.Default.Register("company", primary.DB, pool)
+
+	var replicas []*sqlx.DB
+	for i, dsn := range cfg.ReplicaDSNs(params) {
+		db, err := OpenSqlx(dsn)
+		if err != nil {
+			replica.NewCluster(primary, replicas, policy).Close()
+			return nil, fmt.Errorf("只读副本连接失败: %w", err)
+		}
+		dbpool.Default.Register(fmt.Sprintf("company_replica_%d", i+1), db.DB, pool)
+		replicas = append(replicas, db)
+	}
+
+	fmt.Println("✅ 数据库连接成功")
+	return replica.NewCluster(primary, replicas, policy), nil
+}
+
+// OpenSqlx 打开单个连接, 测试/预发环境按配置注入延迟和错误.
+// 每个连接池有自己的熔断器, 以 "库名@地址" 登记 (见 dbbreaker), 数据库不可用时快速返回 dbbreaker.ErrDBUnavailable
+func OpenSqlx(dsn string) (*sqlx.DB, error) {
+	mc, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("解析连接串失败: %w", err)
+	}
+	base, err := mysql.NewConnector(mc)
+	if err != nil {
+		return nil, fmt.Errorf("创建连接器失败: %w", err)
+	}
+	var conn driver.Connector = base
+	if cfg := chaos.ConfigFromEnv(); cfg.Enabled {
+		conn = chaos.WrapConnector(conn, chaos.New(cfg))
+		fmt.Println("⚠️ 已启用数据库故障注入")
+	}
+	conn = dbbreaker.WrapConnector(conn, dbbreaker.New(mc.DBName+"@"+mc.Addr, config.LoadBreaker()))
+
+	db := sqlx.NewDb(sql.OpenDB(conn), "mysql")
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate 创建部门表、员工的部门外键和上级外键、薪资历史表、审计日志表和查询依赖的索引 (见 Indexes), 可重复执行
+func Migrate(ctx context.Context, db *sqlx.DB) error {
+	if err := migrateDepartments(ctx, db); err != nil {
+		return err
+	}
+	if err := migrateIndexes(ctx, db); err != nil {
+		return err
+	}
+	if err := migrateOrgChart(ctx, db); err != nil {
+		return err
+	}
+	if err := migrateSalaryHistory(ctx, db); err != nil {
+		return err
+	}
+	return audit.Migrate(ctx, db)
+}
+
+// RunQueryDemo 演示员工查询: 只读查询走副本, 固定的查询语句预编译后复用.
+// 各查询的错误只记录日志, 不中断后续查询
+func RunQueryDemo(ctx context.Context, hr *replica.Cluster) {
+	stmts := stmtcache.New(hr.Reader(ctx))
+	defer stmts.Close()
+
+	// 1. 查询技术部所有员工
+	fmt.Println("技术部员工列表:")
+	techEmployees, err := getEmployeesByDepartment(stmts, "技术部")
+	if err != nil {
+		log.Printf("查询失败: %v", err)
+	} else {
+		for _, emp := range techEmployees {
+			fmt.Printf("- ID: %d, 姓名: %s, 部门: %s, 薪资: %d\n",
+				emp.ID, emp.Name, emp.Department, emp.Salary)
+		}
+	}
+
+	// 2. 查询工资最高的员工
+	fmt.Println("\n工资最高的员工:")
+	topEarner, err := getHighestPaidEmployee(stmts)
+	if err != nil {
+		log.Printf("查询失败: %v", err)
+	} else {
+		fmt.Printf("- ID: %d, 姓名: %s, 部门: %s, 薪资: %d\n",
+			topEarner.ID, topEarner.Name, topEarner.Department, topEarner.Salary)
+	}
+
+	// 3. 按条件筛选员工
+	fmt.Println("\n技术部薪资 20000 以上的员工:")
+	minSalary := 20000
+	rich, err := Find(hr.Reader(ctx), Filter{Department: "技术部", MinSalary: &minSalary})
+	if err != nil {
+		log.Printf("查询失败: %v", err)
+	} else {
+		for _, emp := range rich {
+			fmt.Printf("- %s: %d\n", emp.Name, emp.Salary)
+		}
+	}
+
+	// 4. 按姓名排序列出全部员工
+	fmt.Println("\n全部员工 (按姓名排序):")
+	employees, err := listEmployeesByName(stmts, collate.LocaleFromEnv())
+	if err != nil {
+		log.Printf("查询失败: %v", err)
+	} else {
+		for _, emp := range employees {
+			fmt.Printf("- %s (%s)\n", emp.Name, emp.Department)
+		}
+	}
+
+	// 5. 部门薪资统计
+	fmt.Println("\n部门人数排名:")
+	ranking, err := DepartmentHeadcountRanking(ctx, hr.Reader(ctx))
+	if err != nil {
+		log.Printf("查询失败: %v", err)
+	} else {
+		for _, d := range ranking {
+			fmt.Printf("%d. %s: %d 人, 平均薪资 %.0f (%d ~ %d), 工资总额 %d\n",
+				d.Rank, d.Department, d.Headcount, d.AvgSalary, d.MinSalary, d.MaxSalary, d.Payroll)
+		}
+	}
+
+	// 6. 薪资分析 (窗口函数, 旧版本 MySQL 自动改用子查询)
+	fmt.Println("\n技术部薪资名次:")
+	ranks, err := SalaryRanks(ctx, hr.Reader(ctx), "技术部")
+	if err != nil {
+		log.Printf("查询失败: %v", err)
+	} else {
+		for _, r := range ranks {
+			fmt.Printf("%d. %s: %d\n", r.Rank, r.Name, r.Salary)
+		}
+	}
+
+	fmt.Println("\n薪资百分位:")
+	percentiles, err := SalaryPercentiles(ctx, hr.Reader(ctx))
+	if err != nil {
+		log.Printf("查询失败: %v", err)
+	} else {
+		for _, p := range percentiles {
+			fmt.Printf("- %s (%s): %d, 第 %.1f 百分位\n", p.Name, p.Department, p.Salary, p.Percentile)
+		}
+	}
+
+	fmt.Println("\n累计工资总额 (按入职顺序):")
+	totals, err := PayrollRunningTotals(ctx, hr.Reader(ctx), "")
+	if err != nil {
+		log.Printf("查询失败: %v", err)
+	} else {
+		for _, t := range totals {
+			fmt.Printf("- %s: %d, 累计 %d\n", t.Name, t.Salary, t.RunningTotal)
+		}
+	}
+}
+
+// 1. 查询指定部门的所有员工
+func getEmployeesByDepartment(stmts *stmtcache.Cache, department string) ([]Employee, error) {
+	stmt, err := stmts.PrepareNamed(sqlEmployeesByDepartment)
+	if err != nil {
+		return nil, err
+	}
+
+	var employees []Employee
+	err = stmt.Select(&employees, map[string]any{"department": department})
+	if err != nil {
+		return nil, fmt.Errorf("查询部门员工失败: %w", err)
+	}
+
+	if len(employees) == 0 {
+		return nil, fmt.Errorf("部门 '%s' 没有员工", department)
+	}
+
+	return employees, nil
+}
+
+// 2. 查询工资最高的员工
+func getHighestPaidEmployee(stmts *stmtcache.Cache) (Employee, error) {
+	stmt, err := stmts.Preparex(sqlHighestPaidEmployee)
+	if err != nil {
+		return Employee{}, err
+	}
+
+	var employee Employee
+	err = stmt.Get(&employee)
+	if err != nil {
+		return Employee{}, fmt.Errorf("查询最高薪资员工失败: %w", err)
+	}
+
+	return employee, nil
+}
+
+// 可选：获取所有最高薪资员工（处理并列情况）
+func getAllHighestPaidEmployees(stmts *stmtcache.Cache) ([]Employee, error) {
+	stmt, err := stmts.Preparex(sqlAllHighestPaidEmployees)
+	if err != nil {
+		return nil, err
+	}
+
+	var employees []Employee
+	err = stmt.Select(&employees)
+	if err != nil {
+		return nil, fmt.Errorf("查询所有最高薪资员工失败: %w", err)
+	}
+
+	return employees, nil
+}
+
+// 按姓名排序查询全部员工, locale 决定中文姓名的排序方式 (语句只有几种取值, 可以缓存)
+func listEmployeesByName(stmts *stmtcache.Cache, locale collate.Locale) ([]Employee, error) {
+	stmt, err := stmts.Preparex(`
+		SELECT id, name, department, salary
+		FROM employees
+		ORDER BY ` + collate.OrderBy("name", locale, false))
+	if err != nil {
+		return nil, err
+	}
+
+	var employees []Employee
+	if err := stmt.Select(&employees); err != nil {
+		return nil, fmt.Errorf("查询员工列表失败: %w", err)
+	}
+
+	return employees, nil
+}
+
+// 3. 批量插入员工: 每批生成一条多行 INSERT, 所有批次在同一事务中执行.
+// batchSize <= 0 时使用 DefaultBatchSize; 与 GORM 不同, 插入后不会回填 ID
+func insertEmployeesInBatches(db *sqlx.DB, employees []Employee, batchSize int) error {
+	if len(employees) == 0 {
+		return nil
+	}
+	for i := range employees {
+		if err := employees[i].Validate(); err != nil {
+			return fmt.Errorf("第 %d 条: %w", i+1, err)
+		}
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	query := `
+		INSERT INTO employees (name, department, salary)
+		VALUES (:name, :department, :salary)
+	`
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	for start := 0; start < len(employees); start += batchSize {
+		end := min(start+batchSize, len(employees))
+		result, err := tx.NamedExec(query, employees[start:end])
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("批量插入员工失败 (第 %d-%d 行): %w", start+1, end, err)
+		}
+		// 多行 INSERT 分配连续的自增 ID, LastInsertId 为第一行的 ID
+		firstID, err := result.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("获取员工 ID 失败: %w", err)
+		}
+		logs := make([]audit.Log, 0, end-start)
+		for i := start; i < end; i++ {
+			emp := employees[i]
+			emp.ID = int(firstID) + i - start
+			entry, _, err := newEmployeeAuditLog(context.Background(), emp.ID, nil, &emp)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			logs = append(logs, entry)
+		}
+		if err := audit.Write(context.Background(), tx, logs...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	// 多行 INSERT 不方便逐行查部门, 插入后统一回填 department_id
+	if err := syncEmployeeDepartments(context.Background(), tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := recordAllSalaryChanges(context.Background(), tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package employee
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alexwang789/Base1_golang_task3/stmtcache"
+)
+
+func TestGetEmployeesByDepartment(t *testing.T) {
+	db, mock := newMock(t)
+	mock.ExpectPrepare(quote("SELECT id, name, department, salary FROM employees WHERE department = ?")).
+		ExpectQuery().
+		WithArgs("技术部").
+		WillReturnRows(sqlmock.NewRows(employeeColumns).
+			AddRow(1, "张三", "技术部", 12000).
+			AddRow(2, "李四", "技术部", 9000))
+
+	got, err := getEmployeesByDepartment(stmtcache.New(db), "技术部")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Employee{
+		{ID: 1, Name: "张三", Department: "技术部", Salary: 12000},
+		{ID: 2, Name: "李四", Department: "技术部", Salary: 9000},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("结果 = %+v, 期望 %+v", got, want)
+	}
+}
+
+func TestGetEmployeesByDepartmentErrors(t *testing.T) {
+	dbErr := errors.New("连接断开")
+	tests := []struct {
+		name    string
+		setup   func(sqlmock.Sqlmock)
+		wantErr string
+		wrapped error
+	}{
+		{
+			name: "部门没有员工",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare(quote("WHERE department = ?")).ExpectQuery().WithArgs("市场部").
+					WillReturnRows(sqlmock.NewRows(employeeColumns))
+			},
+			wantErr: "部门 '市场部' 没有员工",
+		},
+		{
+			name: "查询失败",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare(quote("WHERE department = ?")).ExpectQuery().WithArgs("市场部").WillReturnError(dbErr)
+			},
+			wantErr: "查询部门员工失败",
+			wrapped: dbErr,
+		},
+		{
+			name: "预编译失败",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare(quote("WHERE department = ?")).WillReturnError(dbErr)
+			},
+			wantErr: "预编译语句失败",
+			wrapped: dbErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMock(t)
+			tt.setup(mock)
+
+			_, err := getEmployeesByDepartment(stmtcache.New(db), "市场部")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("错误 = %v, 期望包含 %q", err, tt.wantErr)
+			}
+			if tt.wrapped != nil && !errors.Is(err, tt.wrapped) {
+				t.Errorf("错误 = %v, 期望包装 %v", err, tt.wrapped)
+			}
+		})
+	}
+}
+
+func TestGetHighestPaidEmployee(t *testing.T) {
+	db, mock := newMock(t)
+	mock.ExpectPrepare(quote("SELECT id, name, department, salary FROM employees ORDER BY salary DESC LIMIT 1")).
+		ExpectQuery().
+		WithoutArgs().
+		WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(3, "王五", "管理部", 30000))
+
+	got, err := getHighestPaidEmployee(stmtcache.New(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Employee{ID: 3, Name: "王五", Department: "管理部", Salary: 30000}
+	if got != want {
+		t.Errorf("结果 = %+v, 期望 %+v", got, want)
+	}
+}
+
+func TestGetHighestPaidEmployeeNoRows(t *testing.T) {
+	db, mock := newMock(t)
+	mock.ExpectPrepare(quote("ORDER BY salary DESC LIMIT 1")).ExpectQuery().
+		WillReturnRows(sqlmock.NewRows(employeeColumns))
+
+	_, err := getHighestPaidEmployee(stmtcache.New(db))
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("错误 = %v, 期望包装 sql.ErrNoRows", err)
+	}
+}
+
+// 同一语句只预编译一次, 第二次查询复用缓存的语句
+func TestGetHighestPaidEmployeeReusesStatement(t *testing.T) {
+	db, mock := newMock(t)
+	prep := mock.ExpectPrepare(quote("ORDER BY salary DESC LIMIT 1"))
+	for range 2 {
+		prep.ExpectQuery().WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(3, "王五", "管理部", 30000))
+	}
+
+	stmts := stmtcache.New(db)
+	for range 2 {
+		if _, err := getHighestPaidEmployee(stmts); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
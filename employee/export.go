@@ -0,0 +1,28 @@
+package employee
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/alexwang789/Base1_golang_task3/exportsink"
+	"github.com/alexwang789/Base1_golang_task3/filterdsl"
+	"github.com/jmoiron/sqlx"
+)
+
+type employeeRecord Employee
+
+func (employeeRecord) CSVHeader() []string {
+	return []string{"id", "name", "department", "salary"}
+}
+
+func (r employeeRecord) CSVRow() []string {
+	return []string{strconv.Itoa(r.ID), r.Name, r.Department, strconv.Itoa(r.Salary)}
+}
+
+// Export 按主键顺序把全部员工写入 out, 续传时从 out.After() 之后开始
+func Export(ctx context.Context, db *sqlx.DB, out *exportsink.Output) error {
+	filter := Filter{Conds: []filterdsl.Cond{{Column: "id", Op: ">", Value: out.After()}}}
+	return IterateEmployees(ctx, db, filter, func(emp *Employee) error {
+		return out.Write(employeeRecord(*emp), uint64(emp.ID))
+	})
+}
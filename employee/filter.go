@@ -0,0 +1,227 @@
+package employee
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alexwang789/Base1_golang_task3/filterdsl"
+	"github.com/jmoiron/sqlx"
+)
+
+// Filter 员工查询条件, 零值字段不参与过滤
+type Filter struct {
+	Department string
+	MinSalary  *int
+	MaxSalary  *int
+	NamePrefix string
+	NameLike   string // 姓名包含该子串
+
+	// Conds 由 filterdsl.Parse 按 FilterFields 解析出的条件, 与上面的字段同时生效
+	Conds []filterdsl.Cond
+}
+
+// FilterFields 可以通过 filterdsl 过滤和排序的字段, 如 ?filter=salary>5000,department=技术部&sort=-salary
+var FilterFields = filterdsl.Fields{
+	"id":         {Column: "id", Kind: filterdsl.Int},
+	"name":       {Column: "name", Kind: filterdsl.String},
+	"department": {Column: "department", Kind: filterdsl.String},
+	"salary":     {Column: "salary", Kind: filterdsl.Int},
+}
+
+// namedWhere 组合使用 :name 参数的 WHERE 条件.
+// 条件文本只来自代码, 用户输入一律作为参数绑定, 不会拼进 SQL
+type namedWhere struct {
+	conds []string
+	args  map[string]any
+}
+
+func newNamedWhere() *namedWhere {
+	return &namedWhere{args: make(map[string]any)}
+}
+
+// add 追加一个条件, cond 中以 :name 引用 value
+func (w *namedWhere) add(cond, name string, value any) {
+	w.conds = append(w.conds, cond)
+	w.args[name] = value
+}
+
+// String 返回 "WHERE a AND b", 没有条件时返回空串
+func (w *namedWhere) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// where 把过滤条件转换为 WHERE 子句和参数
+func (f Filter) where() *namedWhere {
+	w := newNamedWhere()
+	if f.Department != "" {
+		w.add("department = :department", "department", f.Department)
+	}
+	if f.MinSalary != nil {
+		w.add("salary >= :min_salary", "min_salary", *f.MinSalary)
+	}
+	if f.MaxSalary != nil {
+		w.add("salary <= :max_salary", "max_salary", *f.MaxSalary)
+	}
+	if f.NamePrefix != "" {
+		w.add("name LIKE :name_prefix", "name_prefix", escapeLike(f.NamePrefix)+"%")
+	}
+	if f.NameLike != "" {
+		w.add("name LIKE :name_like", "name_like", "%"+escapeLike(f.NameLike)+"%")
+	}
+	// 列名和运算符来自 FilterFields 和 filterdsl, 值按序号命名绑定
+	for i, c := range f.Conds {
+		name := fmt.Sprintf("cond_%d", i)
+		w.add(c.Column+" "+c.Op+" :"+name, name, c.Value)
+	}
+	return w
+}
+
+// Find 按条件查询员工, 按 id 排序. 结果集可能很大时用 IterateEmployees
+func Find(db *sqlx.DB, filter Filter) ([]Employee, error) {
+	var employees []Employee
+	err := IterateEmployees(context.Background(), db, filter, func(emp *Employee) error {
+		employees = append(employees, *emp)
+		return nil
+	})
+	return employees, err
+}
+
+// IterateEmployees 按 id 顺序对符合条件的每名员工调用 fn. 结果集由一条查询流式读取, 内存中只有当前一行,
+// 适合导出和批处理任务; 遍历期间占用一个连接, fn 中的读写使用连接池中的其他连接.
+// fn 返回错误时中止并返回该错误, *Employee 在下一次调用时被复用
+func IterateEmployees(ctx context.Context, db *sqlx.DB, filter Filter, fn func(*Employee) error) error {
+	where := filter.where()
+	query := `
+		SELECT id, name, department, salary
+		FROM employees
+		` + where.String() + `
+		ORDER BY id
+	`
+
+	rows, err := db.NamedQueryContext(ctx, query, where.args)
+	if err != nil {
+		return fmt.Errorf("查询员工失败: %w", err)
+	}
+	defer rows.Close()
+
+	var emp Employee
+	for rows.Next() {
+		if err := rows.StructScan(&emp); err != nil {
+			return fmt.Errorf("读取员工失败: %w", err)
+		}
+		if err := fn(&emp); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Page 分页和排序参数
+type Page struct {
+	Number int    // 页码, 从 1 开始
+	Size   int    // 每页条数, <= 0 时使用 20, 最大 100
+	SortBy string // 排序列, 必须在 employeeSortColumns 中, 为空时按 id
+	Desc   bool
+
+	// Sort 由 filterdsl.Parse 解析出的多列排序, 不为空时代替 SortBy 和 Desc
+	Sort []filterdsl.Order
+}
+
+// SearchResult 一页查询结果
+type SearchResult struct {
+	Employees []Employee `json:"employees"`
+	Total     int        `json:"total"` // 符合条件的总条数
+	Number    int        `json:"page"`
+	Size      int        `json:"page_size"`
+}
+
+// 允许排序的列: 对外的排序名 -> 列名. 排序列会拼进 SQL, 只能取这里的值
+var employeeSortColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"department": "department",
+	"salary":     "salary",
+}
+
+// Search 按条件分页查询员工, 同时返回符合条件的总条数
+func Search(ctx context.Context, db *sqlx.DB, filter Filter, page Page) (*SearchResult, error) {
+	column := "id"
+	if page.SortBy != "" {
+		var ok bool
+		if column, ok = employeeSortColumns[page.SortBy]; !ok {
+			return nil, fmt.Errorf("不支持按 %q 排序", page.SortBy)
+		}
+	}
+	direction := "ASC"
+	if page.Desc {
+		direction = "DESC"
+	}
+	orderBy := column + " " + direction + ", id " + direction
+	if len(page.Sort) > 0 {
+		orderBy = dslOrderBy(page.Sort)
+	}
+
+	page.Number = max(page.Number, 1)
+	if page.Size <= 0 {
+		page.Size = 20
+	}
+	page.Size = min(page.Size, 100)
+
+	where := filter.where()
+
+	var total int
+	countQuery, countArgs, err := sqlx.Named("SELECT COUNT(*) FROM employees "+where.String(), where.args)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.GetContext(ctx, &total, db.Rebind(countQuery), countArgs...); err != nil {
+		return nil, fmt.Errorf("统计员工数失败: %w", err)
+	}
+
+	result := &SearchResult{Employees: []Employee{}, Total: total, Number: page.Number, Size: page.Size}
+	offset := (page.Number - 1) * page.Size
+	if offset >= total {
+		return result, nil
+	}
+
+	// 排序值相同时按 id 排, 保证翻页结果稳定
+	where.args["limit"] = page.Size
+	where.args["offset"] = offset
+	query, args, err := sqlx.Named(`
+		SELECT id, name, department, salary
+		FROM employees
+		`+where.String()+`
+		ORDER BY `+orderBy+`
+		LIMIT :limit OFFSET :offset
+	`, where.args)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SelectContext(ctx, &result.Employees, db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("查询员工失败: %w", err)
+	}
+	return result, nil
+}
+
+// dslOrderBy 把 filterdsl 的排序转换为 ORDER BY 子句, 最后按 id 排保证翻页稳定.
+// 列名来自 FilterFields
+func dslOrderBy(sort []filterdsl.Order) string {
+	parts := make([]string, 0, len(sort)+1)
+	for _, o := range sort {
+		if o.Desc {
+			parts = append(parts, o.Column+" DESC")
+		} else {
+			parts = append(parts, o.Column+" ASC")
+		}
+	}
+	return strings.Join(append(parts, "id ASC"), ", ")
+}
+
+// 转义 LIKE 中的通配符, 使用户输入按字面匹配
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,114 @@
+package employee
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ImportRowError CSV 中一行的错误, Line 为文件中的行号 (表头为第 1 行)
+type ImportRowError struct {
+	Line int
+	Err  error
+}
+
+func (e ImportRowError) Error() string {
+	return fmt.Sprintf("第 %d 行: %v", e.Line, e.Err)
+}
+
+// ImportReport 导入结果
+type ImportReport struct {
+	Imported int
+	Errors   []ImportRowError
+}
+
+// WriteText 以文本形式输出导入结果
+func (r *ImportReport) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "成功导入 %d 行, 跳过 %d 行\n", r.Imported, len(r.Errors))
+	for _, e := range r.Errors {
+		fmt.Fprintf(w, "  %v\n", e)
+	}
+}
+
+// ImportCSV 从 CSV 导入员工. 文件第一行为表头, 需包含 name, department, salary 三列 (顺序不限).
+// 校验失败的行记入报告并跳过, 其余行在同一事务中分批插入; 插入失败时整体回滚并返回错误
+func ImportCSV(db *sqlx.DB, r io.Reader, batchSize int) (*ImportReport, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取表头失败: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"name", "department", "salary"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("表头缺少 %s 列", name)
+		}
+	}
+	cr.FieldsPerRecord = len(header)
+
+	report := &ImportReport{}
+	var employees []Employee
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// 列数不对等格式错误只影响当前行, 其他读取错误无法继续
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				report.Errors = append(report.Errors, ImportRowError{Line: parseErr.StartLine, Err: parseErr.Err})
+				continue
+			}
+			return nil, fmt.Errorf("读取 CSV 失败: %w", err)
+		}
+
+		emp, err := parseEmployeeRecord(record, columns)
+		if err != nil {
+			line, _ := cr.FieldPos(0)
+			report.Errors = append(report.Errors, ImportRowError{Line: line, Err: err})
+			continue
+		}
+		employees = append(employees, emp)
+	}
+
+	if err := insertEmployeesInBatches(db, employees, batchSize); err != nil {
+		return nil, err
+	}
+	report.Imported = len(employees)
+	return report, nil
+}
+
+// 解析并校验一行数据
+func parseEmployeeRecord(record []string, columns map[string]int) (Employee, error) {
+	emp := Employee{
+		Name:       strings.TrimSpace(record[columns["name"]]),
+		Department: strings.TrimSpace(record[columns["department"]]),
+	}
+	if emp.Name == "" {
+		return emp, errors.New("姓名不能为空")
+	}
+	if emp.Department == "" {
+		return emp, errors.New("部门不能为空")
+	}
+
+	salary, err := strconv.Atoi(strings.TrimSpace(record[columns["salary"]]))
+	if err != nil {
+		return emp, fmt.Errorf("薪资 %q 不是整数", record[columns["salary"]])
+	}
+	if salary <= 0 {
+		return emp, fmt.Errorf("薪资必须大于 0, 实际为 %d", salary)
+	}
+	emp.Salary = salary
+	return emp, nil
+}
@@ -0,0 +1,128 @@
+//go:build integration
+
+package employee
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alexwang789/Base1_golang_task3/stmtcache"
+	"github.com/alexwang789/Base1_golang_task3/testmysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// 员工表由外部脚本创建, Migrate 在此基础上添加部门、上级等
+const sqlCreateEmployees = `
+	CREATE TABLE employees (
+		id         INT AUTO_INCREMENT PRIMARY KEY,
+		name       VARCHAR(100) NOT NULL,
+		department VARCHAR(100) NOT NULL,
+		salary     INT NOT NULL
+	)
+`
+
+// 在真实的 MySQL 上执行迁移、员工增删改和查询. 运行: go test -tags=integration ./employee
+func TestEmployeesOnMySQL(t *testing.T) {
+	db, err := OpenSqlx(testmysql.Start(t, "hr_db", DSNParams))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, sqlCreateEmployees); err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	// 迁移可重复执行
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("再次迁移失败: %v", err)
+	}
+
+	staff := []*Employee{
+		{Name: "张三", Department: "技术部", Salary: 12000},
+		{Name: "李四", Department: "技术部", Salary: 9000},
+		{Name: "王五", Department: "管理部", Salary: 30000},
+	}
+	for _, e := range staff {
+		if err := InTx(ctx, db, func(tx *sqlx.Tx) error { return Insert(ctx, tx, e) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stmts := stmtcache.New(db)
+	t.Cleanup(func() { stmts.Close() })
+
+	t.Run("按部门查询", func(t *testing.T) {
+		got, err := getEmployeesByDepartment(stmts, "技术部")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 {
+			t.Errorf("技术部有 %d 名员工, 期望 2", len(got))
+		}
+	})
+
+	t.Run("最高薪资", func(t *testing.T) {
+		got, err := getHighestPaidEmployee(stmts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != *staff[2] {
+			t.Errorf("最高薪资员工 = %+v, 期望 %+v", got, *staff[2])
+		}
+	})
+
+	t.Run("部门统计", func(t *testing.T) {
+		stats, err := DepartmentHeadcountRanking(ctx, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != 2 || stats[0].Department != "技术部" || stats[0].Headcount != 2 || stats[0].Rank != 1 || stats[1].Rank != 2 {
+			t.Errorf("人数排名 = %+v", stats)
+		}
+		stats, err = DepartmentSalaryStats(ctx, db, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != 1 || stats[0].Payroll != 21000 || stats[0].MinSalary != 9000 {
+			t.Errorf("人数不少于 2 的部门 = %+v", stats)
+		}
+	})
+
+	t.Run("更新记录薪资历史和审计", func(t *testing.T) {
+		e := *staff[1]
+		e.Salary = 10000
+		if err := InTx(ctx, db, func(tx *sqlx.Tx) error { return Update(ctx, tx, &e) }); err != nil {
+			t.Fatal(err)
+		}
+		history, err := SalaryGrowth(ctx, db, e.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 || history[len(history)-1].Salary != 10000 {
+			t.Errorf("薪资历史 = %+v, 期望两条, 最新为 10000", history)
+		}
+		var audits int
+		if err := db.GetContext(ctx, &audits, "SELECT COUNT(*) FROM audit_logs WHERE entity = 'employees' AND entity_id = ?", e.ID); err != nil {
+			t.Fatal(err)
+		}
+		if audits != 2 {
+			t.Errorf("审计记录 %d 条, 期望创建和更新各一条", audits)
+		}
+	})
+
+	t.Run("删除", func(t *testing.T) {
+		id := staff[0].ID
+		if err := InTx(ctx, db, func(tx *sqlx.Tx) error { return Delete(ctx, tx, id) }); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := GetByID(ctx, db, id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("删除后查询的错误 = %v, 期望 ErrNotFound", err)
+		}
+		if err := Delete(ctx, db, id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("重复删除的错误 = %v, 期望 ErrNotFound", err)
+		}
+	})
+}
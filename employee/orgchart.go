@@ -0,0 +1,199 @@
+package employee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/audit"
+	"github.com/jmoiron/sqlx"
+)
+
+// 组织架构: employees.manager_id 引用上级员工, 最上层的员工为 NULL. 上级被删除时下属的 manager_id 置为 NULL.
+// 汇报链和下属树用递归 CTE 查询, 需要 MySQL 8.0 / MariaDB 10.2 及以上
+
+// ErrManagerCycle 设置的上级是员工本人或其下属, 会使汇报关系形成环
+var ErrManagerCycle = errors.New("上级不能是本人或其下属")
+
+// 递归查询的最大层数. SetManager 不允许形成环, 这里只防止手工修改数据造成的环使查询不终止
+const maxOrgDepth = 64
+
+// OrgNode 组织架构中的一名员工, Depth 为与查询起点相隔的层数, 起点为 0
+type OrgNode struct {
+	Employee
+	ManagerID *int `db:"manager_id" json:"manager_id"` // 最上层的员工为 nil
+	Depth     int  `db:"depth" json:"depth"`
+}
+
+const (
+	// 从 :id 向上到最上层的汇报链
+	sqlReportingChain = `
+		WITH RECURSIVE chain AS (
+			SELECT id, name, department, salary, manager_id, 0 AS depth
+			FROM employees
+			WHERE id = :id
+			UNION ALL
+			SELECT m.id, m.name, m.department, m.salary, m.manager_id, c.depth + 1
+			FROM employees m
+			JOIN chain c ON m.id = c.manager_id
+			WHERE c.depth < :max_depth
+		)
+		SELECT id, name, department, salary, manager_id, depth
+		FROM chain
+		ORDER BY depth
+	`
+	// :id 及其下的全部下属, path 按 ID 拼接, 排序后为深度优先的顺序 (每名上级紧跟着自己的下属)
+	sqlOrgSubtree = `
+		WITH RECURSIVE tree AS (
+			SELECT id, name, department, salary, manager_id, 0 AS depth,
+				CAST(LPAD(id, 10, '0') AS CHAR(1000)) AS path
+			FROM employees
+			WHERE id = :id
+			UNION ALL
+			SELECT e.id, e.name, e.department, e.salary, e.manager_id, t.depth + 1,
+				CONCAT(t.path, '/', LPAD(e.id, 10, '0'))
+			FROM employees e
+			JOIN tree t ON e.manager_id = t.id
+			WHERE t.depth < :max_depth
+		)
+		SELECT id, name, department, salary, manager_id, depth
+		FROM tree
+		ORDER BY path
+	`
+)
+
+// 添加上级外键, 可重复执行
+func migrateOrgChart(ctx context.Context, db *sqlx.DB) error {
+	var n int
+	err := db.GetContext(ctx, &n, `
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'employees' AND COLUMN_NAME = 'manager_id'
+	`)
+	if err != nil {
+		return fmt.Errorf("查询表结构失败: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err = db.ExecContext(ctx, `
+		ALTER TABLE employees
+		ADD COLUMN manager_id INT NULL,
+		ADD CONSTRAINT fk_employees_manager FOREIGN KEY (manager_id) REFERENCES employees (id) ON DELETE SET NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("添加上级外键失败: %w", err)
+	}
+	return nil
+}
+
+// ReportingChain 返回员工向上的汇报链: 第一项为员工本人, 之后依次为直属上级、上级的上级, 直到最上层
+func ReportingChain(ctx context.Context, db sqlx.QueryerContext, id int) ([]OrgNode, error) {
+	chain, err := selectOrg(ctx, db, sqlReportingChain, id)
+	if err != nil {
+		return nil, fmt.Errorf("查询汇报链失败: %w", err)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("员工 %d: %w", id, ErrNotFound)
+	}
+	return chain, nil
+}
+
+// Subordinates 返回上级本人 (第一项) 及其下的全部直接和间接下属, 按深度优先的顺序, 适合缩进输出组织架构
+func Subordinates(ctx context.Context, db sqlx.QueryerContext, managerID int) ([]OrgNode, error) {
+	tree, err := selectOrg(ctx, db, sqlOrgSubtree, managerID)
+	if err != nil {
+		return nil, fmt.Errorf("查询下属失败: %w", err)
+	}
+	if len(tree) == 0 {
+		return nil, fmt.Errorf("员工 %d: %w", managerID, ErrNotFound)
+	}
+	return tree, nil
+}
+
+func selectOrg(ctx context.Context, db sqlx.QueryerContext, query string, id int) ([]OrgNode, error) {
+	bound, args, err := sqlx.Named(query, map[string]any{"id": id, "max_depth": maxOrgDepth})
+	if err != nil {
+		return nil, err
+	}
+	var nodes []OrgNode
+	if err := sqlx.SelectContext(ctx, db, &nodes, bound, args...); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// SetManager 设置员工的直属上级, managerID 为 nil 时取消上级. 新上级是员工本人或其下属时返回 ErrManagerCycle.
+// 事务按读已提交隔离, 先按 ID 顺序锁定员工和新上级的行再检查环, 同时修改这两名员工上级的请求依次执行
+func SetManager(ctx context.Context, db *sqlx.DB, employeeID int, managerID *int) error {
+	if managerID != nil && *managerID == employeeID {
+		return ErrManagerCycle
+	}
+
+	tx, err := db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := []int{employeeID}
+	if managerID != nil {
+		ids = append(ids, *managerID)
+	}
+	query, args, err := sqlx.In("SELECT id, manager_id FROM employees WHERE id IN (?) ORDER BY id FOR UPDATE", ids)
+	if err != nil {
+		return err
+	}
+	var locked []struct {
+		ID        int  `db:"id"`
+		ManagerID *int `db:"manager_id"`
+	}
+	if err := tx.SelectContext(ctx, &locked, query, args...); err != nil {
+		return fmt.Errorf("锁定员工失败: %w", err)
+	}
+	var before *int
+	found := map[int]bool{}
+	for _, e := range locked {
+		found[e.ID] = true
+		if e.ID == employeeID {
+			before = e.ManagerID
+		}
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return fmt.Errorf("员工 %d: %w", id, ErrNotFound)
+		}
+	}
+
+	if managerID != nil {
+		// 新上级的汇报链中出现员工本人, 说明新上级是其下属
+		chain, err := selectOrg(ctx, tx, sqlReportingChain, *managerID)
+		if err != nil {
+			return fmt.Errorf("查询汇报链失败: %w", err)
+		}
+		for _, n := range chain {
+			if n.ID == employeeID {
+				return ErrManagerCycle
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE employees SET manager_id = ? WHERE id = ?", managerID, employeeID); err != nil {
+		return fmt.Errorf("更新上级失败: %w", err)
+	}
+	log, changed, err := audit.NewLog(ctx, "employees", uint64(employeeID),
+		map[string]any{"manager_id": before}, map[string]any{"manager_id": managerID})
+	if err != nil {
+		return err
+	}
+	if changed {
+		if err := audit.Write(ctx, tx, log); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
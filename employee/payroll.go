@@ -0,0 +1,149 @@
+package employee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Adjustment 一项调薪: 按百分比调整一个部门或一名员工的薪资, Department 和 EmployeeID 二选一
+type Adjustment struct {
+	Department string
+	EmployeeID int
+	Percent    float64 // 如 5 表示涨 5%, -3 表示降 3%, 不能低于 -100
+}
+
+// SalaryUpdate 一名员工的薪资变化, 新薪资四舍五入到整数
+type SalaryUpdate struct {
+	EmployeeID int    `json:"employee_id"`
+	Name       string `json:"name"`
+	Department string `json:"department"`
+	OldSalary  int    `json:"old_salary"`
+	NewSalary  int    `json:"new_salary"`
+}
+
+// RaiseReport 调薪结果. DryRun 为 true 时只是预演, 数据没有修改
+type RaiseReport struct {
+	DryRun  bool
+	Updates []SalaryUpdate // 按 ID 排序, 薪资没有变化的员工不列出
+	Delta   int64          // 月工资总额的变化
+}
+
+// WriteText 以文本形式输出调薪结果
+func (r *RaiseReport) WriteText(w io.Writer) {
+	if r.DryRun {
+		fmt.Fprint(w, "[预演] ")
+	}
+	fmt.Fprintf(w, "调整 %d 名员工的薪资, 工资总额变化 %+d\n", len(r.Updates), r.Delta)
+	for _, u := range r.Updates {
+		fmt.Fprintf(w, "  %d %s (%s): %d -> %d\n", u.EmployeeID, u.Name, u.Department, u.OldSalary, u.NewSalary)
+	}
+}
+
+// RaiseOptions 调薪选项
+type RaiseOptions struct {
+	DryRun bool // 只计算调薪结果, 不写入
+}
+
+// ApplyRaise 按百分比调整部门全部员工的薪资, 见 ApplyRaiseBatch
+func ApplyRaise(ctx context.Context, db *sqlx.DB, department string, percent float64, opts RaiseOptions) (*RaiseReport, error) {
+	return ApplyRaiseBatch(ctx, db, []Adjustment{{Department: department, Percent: percent}}, opts)
+}
+
+// ApplyRaiseBatch 在同一事务中依次执行各项调薪并记录薪资历史和审计日志, 任何一项失败时全部回滚.
+// 同一员工命中多项调薪时按顺序叠加. 部门没有员工或员工不存在时返回错误
+func ApplyRaiseBatch(ctx context.Context, db *sqlx.DB, adjustments []Adjustment, opts RaiseOptions) (*RaiseReport, error) {
+	if len(adjustments) == 0 {
+		return nil, errors.New("没有调薪项")
+	}
+	for i, a := range adjustments {
+		if (a.Department == "") == (a.EmployeeID == 0) {
+			return nil, fmt.Errorf("第 %d 项调薪应指定部门或员工之一", i+1)
+		}
+		if a.Percent < -100 || math.IsNaN(a.Percent) || math.IsInf(a.Percent, 0) {
+			return nil, fmt.Errorf("第 %d 项调薪的百分比 %v 无效", i+1, a.Percent)
+		}
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 预演不锁定行
+	lock := " FOR UPDATE"
+	if opts.DryRun {
+		lock = ""
+	}
+	before := map[int]Employee{}
+	salaries := map[int]int{}
+	for i, a := range adjustments {
+		var matched []Employee
+		if a.Department != "" {
+			err = tx.SelectContext(ctx, &matched, "SELECT id, name, department, salary FROM employees WHERE department = ? ORDER BY id"+lock, a.Department)
+		} else {
+			err = tx.SelectContext(ctx, &matched, "SELECT id, name, department, salary FROM employees WHERE id = ?"+lock, a.EmployeeID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("查询第 %d 项调薪的员工失败: %w", i+1, err)
+		}
+		if len(matched) == 0 {
+			if a.Department != "" {
+				return nil, fmt.Errorf("第 %d 项调薪: 部门 %s 没有员工", i+1, a.Department)
+			}
+			return nil, fmt.Errorf("第 %d 项调薪: 员工 %d: %w", i+1, a.EmployeeID, ErrNotFound)
+		}
+		for _, e := range matched {
+			if _, ok := before[e.ID]; !ok {
+				before[e.ID] = e
+				salaries[e.ID] = e.Salary
+			}
+			salaries[e.ID] = int(math.Round(float64(salaries[e.ID]) * (1 + a.Percent/100)))
+		}
+	}
+
+	report := &RaiseReport{DryRun: opts.DryRun}
+	for id, old := range before {
+		if salaries[id] == old.Salary {
+			continue
+		}
+		report.Updates = append(report.Updates, SalaryUpdate{
+			EmployeeID: id,
+			Name:       old.Name,
+			Department: old.Department,
+			OldSalary:  old.Salary,
+			NewSalary:  salaries[id],
+		})
+		report.Delta += int64(salaries[id] - old.Salary)
+	}
+	slices.SortFunc(report.Updates, func(a, b SalaryUpdate) int { return a.EmployeeID - b.EmployeeID })
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for _, u := range report.Updates {
+		if _, err := tx.ExecContext(ctx, "UPDATE employees SET salary = ? WHERE id = ?", u.NewSalary, u.EmployeeID); err != nil {
+			return nil, fmt.Errorf("更新员工 %d 的薪资失败: %w", u.EmployeeID, err)
+		}
+		old := before[u.EmployeeID]
+		updated := old
+		updated.Salary = u.NewSalary
+		if err := auditEmployee(ctx, tx, u.EmployeeID, &old, &updated); err != nil {
+			return nil, err
+		}
+	}
+	if err := recordAllSalaryChanges(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,47 @@
+package employee
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/queryplan"
+	"github.com/jmoiron/sqlx"
+)
+
+// QueryPlans 员工库中受执行计划检查保护的查询
+var QueryPlans = []queryplan.Query{
+	namedQueryPlan("employees_by_department", sqlEmployeesByDepartment, map[string]any{"department": "技术部"}),
+	{Name: "highest_paid_employee", SQL: sqlHighestPaidEmployee},
+	{Name: "all_highest_paid_employees", SQL: sqlAllHighestPaidEmployees},
+	// 统计需要读取全部员工, 部门表很小
+	namedQueryPlan("department_salary_stats", sqlDepartmentSalaryStats, map[string]any{"min_headcount": 1}, "d", "e"),
+}
+
+// Indexes 员工库的查询依赖的复合索引, 由 Migrate 创建, 启动时检查是否缺失
+var Indexes = []queryplan.Index{
+	// 按部门筛选并按工资排序或统计
+	{Table: "employees", Name: "idx_employees_department_salary", Columns: []string{"department", "salary"}},
+}
+
+// 把使用 :name 参数的查询转换为 EXPLAIN 可执行的 ? 形式
+func namedQueryPlan(name, query string, arg any, allowFullScan ...string) queryplan.Query {
+	bound, args, err := sqlx.Named(query, arg)
+	if err != nil {
+		panic(fmt.Sprintf("查询 %s 的参数绑定失败: %v", name, err))
+	}
+	return queryplan.Query{Name: name, SQL: bound, Args: args, AllowFullScan: allowFullScan}
+}
+
+// 创建 Indexes 中缺少的索引, 可重复执行
+func migrateIndexes(ctx context.Context, db *sqlx.DB) error {
+	missing, err := queryplan.MissingIndexes(ctx, db.DB, Indexes)
+	if err != nil {
+		return err
+	}
+	for _, idx := range missing {
+		if _, err := db.ExecContext(ctx, idx.SQL()); err != nil {
+			return fmt.Errorf("创建索引 %s 失败: %w", idx.Name, err)
+		}
+	}
+	return nil
+}
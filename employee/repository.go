@@ -0,0 +1,277 @@
+package employee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/audit"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNotFound 员工不存在
+var ErrNotFound = errors.New("员工不存在")
+
+// GetByID 按主键查询员工
+func GetByID(ctx context.Context, db sqlx.QueryerContext, id int) (*Employee, error) {
+	var employee Employee
+	err := sqlx.GetContext(ctx, db, &employee, `
+		SELECT id, name, department, salary
+		FROM employees
+		WHERE id = ?
+	`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("员工 %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询员工失败: %w", err)
+	}
+	return &employee, nil
+}
+
+// InTx 在事务中执行 fn, fn 返回错误时回滚. Insert、Update 和 Delete 会执行多条语句 (部门、薪资历史、审计日志),
+// 应放在事务中调用, 避免中途失败留下没有薪资历史或审计记录的员工
+func InTx(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// Insert 插入员工, 成功后回填 employee.ID. 部门不存在时自动创建, 数据不合法时返回 validate.Errors
+func Insert(ctx context.Context, db sqlx.ExtContext, employee *Employee) error {
+	if err := employee.Validate(); err != nil {
+		return err
+	}
+	departmentID, err := ensureDepartment(ctx, db, employee.Department)
+	if err != nil {
+		return err
+	}
+
+	result, err := sqlx.NamedExecContext(ctx, db, `
+		INSERT INTO employees (name, department, department_id, salary)
+		VALUES (:name, :department, :department_id, :salary)
+	`, employeeWithDepartment{employee, departmentID})
+	if err != nil {
+		return fmt.Errorf("插入员工失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取员工 ID 失败: %w", err)
+	}
+	employee.ID = int(id)
+	if err := recordSalaryChange(ctx, db, employee.ID); err != nil {
+		return err
+	}
+	return auditEmployee(ctx, db, employee.ID, nil, employee)
+}
+
+// Update 按 employee.ID 更新姓名、部门和薪资
+func Update(ctx context.Context, db sqlx.ExtContext, employee *Employee) error {
+	if err := employee.Validate(); err != nil {
+		return err
+	}
+	before, err := GetByID(ctx, db, employee.ID)
+	if err != nil {
+		return err
+	}
+	departmentID, err := ensureDepartment(ctx, db, employee.Department)
+	if err != nil {
+		return err
+	}
+
+	result, err := sqlx.NamedExecContext(ctx, db, `
+		UPDATE employees
+		SET name = :name, department = :department, department_id = :department_id, salary = :salary
+		WHERE id = :id
+	`, employeeWithDepartment{employee, departmentID})
+	if err != nil {
+		return fmt.Errorf("更新员工失败: %w", err)
+	}
+	// MySQL 默认返回实际改变的行数, 值没有变化时为 0
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if n == 0 {
+		return nil
+	}
+	if err := recordSalaryChange(ctx, db, employee.ID); err != nil {
+		return err
+	}
+	return auditEmployee(ctx, db, employee.ID, before, employee)
+}
+
+// 写入员工时附带部门外键, Employee 本身只保存部门名
+type employeeWithDepartment struct {
+	*Employee
+	DepartmentID int `db:"department_id"`
+}
+
+// Delete 删除员工
+func Delete(ctx context.Context, db sqlx.ExtContext, id int) error {
+	before, err := GetByID(ctx, db, id)
+	if err != nil {
+		return err
+	}
+	result, err := db.ExecContext(ctx, "DELETE FROM employees WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("删除员工失败: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("员工 %d: %w", id, ErrNotFound)
+	}
+	return auditEmployee(ctx, db, id, before, nil)
+}
+
+// auditEmployee 记录员工的写入, before 为 nil 表示创建, after 为 nil 表示删除
+func auditEmployee(ctx context.Context, db sqlx.ExtContext, id int, before, after *Employee) error {
+	log, changed, err := newEmployeeAuditLog(ctx, id, before, after)
+	if err != nil || !changed {
+		return err
+	}
+	return audit.Write(ctx, db, log)
+}
+
+func newEmployeeAuditLog(ctx context.Context, id int, before, after *Employee) (audit.Log, bool, error) {
+	var fields [2]map[string]any
+	for i, e := range []*Employee{before, after} {
+		if e == nil {
+			continue
+		}
+		f, err := audit.Fields(e)
+		if err != nil {
+			return audit.Log{}, false, err
+		}
+		fields[i] = f
+	}
+	return audit.NewLog(ctx, "employees", uint64(id), fields[0], fields[1])
+}
+
+// DepartmentStats 部门薪资统计
+type DepartmentStats struct {
+	DepartmentID int     `db:"department_id" json:"department_id"`
+	Department   string  `db:"department" json:"department"`
+	Headcount    int     `db:"headcount" json:"headcount"`
+	AvgSalary    float64 `db:"avg_salary" json:"avg_salary"`
+	MinSalary    int     `db:"min_salary" json:"min_salary"`
+	MaxSalary    int     `db:"max_salary" json:"max_salary"`
+	Payroll      int64   `db:"payroll" json:"payroll"`
+	Rank         int     `db:"rank_no" json:"rank"` // 只有 DepartmentHeadcountRanking 填充
+}
+
+// 部门统计语句, 执行计划检查 (queryplans.go) 也引用这些语句
+const (
+	// 各部门平均/最低/最高薪资, 只返回人数不少于 :min_headcount 的部门
+	sqlDepartmentSalaryStats = `
+		SELECT d.id AS department_id, d.name AS department,
+			COUNT(e.id) AS headcount,
+			AVG(e.salary) AS avg_salary,
+			MIN(e.salary) AS min_salary,
+			MAX(e.salary) AS max_salary,
+			SUM(e.salary) AS payroll
+		FROM departments d
+		JOIN employees e ON e.department_id = d.id
+		GROUP BY d.id, d.name
+		HAVING COUNT(e.id) >= :min_headcount
+		ORDER BY d.name
+	`
+	// 工资总额不低于 :min_payroll 的部门, 按总额降序
+	sqlDepartmentPayrollTotals = `
+		SELECT d.id AS department_id, d.name AS department,
+			COUNT(e.id) AS headcount,
+			AVG(e.salary) AS avg_salary,
+			MIN(e.salary) AS min_salary,
+			MAX(e.salary) AS max_salary,
+			SUM(e.salary) AS payroll
+		FROM departments d
+		JOIN employees e ON e.department_id = d.id
+		GROUP BY d.id, d.name
+		HAVING SUM(e.salary) >= :min_payroll
+		ORDER BY payroll DESC
+	`
+)
+
+// 按人数排名, 人数相同的部门名次相同. 与 analytics.go 的分析查询一样, 不支持窗口函数的版本用兼容写法
+var sqlDepartmentHeadcountRanking = analyticsQuery{
+	window: `
+		SELECT d.id AS department_id, d.name AS department,
+			COUNT(e.id) AS headcount,
+			AVG(e.salary) AS avg_salary,
+			MIN(e.salary) AS min_salary,
+			MAX(e.salary) AS max_salary,
+			SUM(e.salary) AS payroll,
+			RANK() OVER (ORDER BY COUNT(e.id) DESC) AS rank_no
+		FROM departments d
+		JOIN employees e ON e.department_id = d.id
+		GROUP BY d.id, d.name
+		ORDER BY rank_no, d.name
+	`,
+	legacy: `
+		SELECT s.*,
+			1 + (SELECT COUNT(*) FROM (
+				SELECT COUNT(*) AS headcount
+				FROM departments d
+				JOIN employees e ON e.department_id = d.id
+				GROUP BY d.id
+			) o WHERE o.headcount > s.headcount) AS rank_no
+		FROM (
+			SELECT d.id AS department_id, d.name AS department,
+				COUNT(e.id) AS headcount,
+				AVG(e.salary) AS avg_salary,
+				MIN(e.salary) AS min_salary,
+				MAX(e.salary) AS max_salary,
+				SUM(e.salary) AS payroll
+			FROM departments d
+			JOIN employees e ON e.department_id = d.id
+			GROUP BY d.id, d.name
+		) s
+		ORDER BY rank_no, s.department
+	`,
+}
+
+// DepartmentSalaryStats 返回人数不少于 minHeadcount 的部门的薪资统计
+func DepartmentSalaryStats(ctx context.Context, db sqlx.ExtContext, minHeadcount int) ([]DepartmentStats, error) {
+	return departmentStats(ctx, db, sqlDepartmentSalaryStats, map[string]any{"min_headcount": minHeadcount})
+}
+
+// DepartmentHeadcountRanking 按人数从多到少返回各部门及名次
+func DepartmentHeadcountRanking(ctx context.Context, db sqlx.ExtContext) ([]DepartmentStats, error) {
+	var stats []DepartmentStats
+	if err := selectAnalytics(ctx, db, &stats, sqlDepartmentHeadcountRanking, map[string]any{}); err != nil {
+		return nil, fmt.Errorf("查询部门统计失败: %w", err)
+	}
+	return stats, nil
+}
+
+// DepartmentPayrollTotals 返回工资总额不低于 minPayroll 的部门, 总额高的在前
+func DepartmentPayrollTotals(ctx context.Context, db sqlx.ExtContext, minPayroll int64) ([]DepartmentStats, error) {
+	return departmentStats(ctx, db, sqlDepartmentPayrollTotals, map[string]any{"min_payroll": minPayroll})
+}
+
+func departmentStats(ctx context.Context, db sqlx.ExtContext, query string, arg map[string]any) ([]DepartmentStats, error) {
+	bound, args, err := sqlx.Named(query, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []DepartmentStats
+	if err := sqlx.SelectContext(ctx, db, &stats, db.Rebind(bound), args...); err != nil {
+		return nil, fmt.Errorf("查询部门统计失败: %w", err)
+	}
+	return stats, nil
+}
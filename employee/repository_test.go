@@ -0,0 +1,230 @@
+package employee
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alexwang789/Base1_golang_task3/audit"
+	"github.com/alexwang789/Base1_golang_task3/validate"
+	"github.com/jmoiron/sqlx"
+)
+
+var employeeColumns = []string{"id", "name", "department", "salary"}
+
+// newMock 创建 sqlmock 连接, 测试结束时检查所有预期的语句都已执行
+func newMock(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return sqlx.NewDb(db, "mysql"), mock
+}
+
+// quote 把语句片段转为匹配原文的正则
+func quote(fragment string) string {
+	return regexp.QuoteMeta(fragment)
+}
+
+func expectGetByID(mock sqlmock.Sqlmock, e Employee) {
+	mock.ExpectQuery(quote("SELECT id, name, department, salary FROM employees WHERE id = ?")).
+		WithArgs(e.ID).
+		WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(e.ID, e.Name, e.Department, e.Salary))
+}
+
+func expectEnsureDepartment(mock sqlmock.Sqlmock, name string, id int) {
+	mock.ExpectExec(quote("INSERT IGNORE INTO departments (name) VALUES (?)")).
+		WithArgs(name).
+		WillReturnResult(sqlmock.NewResult(int64(id), 1))
+	mock.ExpectQuery(quote("SELECT id FROM departments WHERE name = ?")).
+		WithArgs(name).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+}
+
+func expectSalaryChange(mock sqlmock.Sqlmock, employeeID int) {
+	mock.ExpectExec(quote("INSERT INTO salary_history (employee_id, salary, effective_from)")).
+		WithArgs(sqlmock.AnyArg(), employeeID, employeeID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func expectAudit(mock sqlmock.Sqlmock, employeeID int, action string) {
+	mock.ExpectExec(quote("INSERT INTO audit_logs (actor_id, entity, entity_id, action, old_values, new_values, created_at)")).
+		WithArgs(0, "employees", employeeID, action, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func TestGetByID(t *testing.T) {
+	db, mock := newMock(t)
+	want := Employee{ID: 7, Name: "张三", Department: "技术部", Salary: 12000}
+	expectGetByID(mock, want)
+
+	got, err := GetByID(context.Background(), db, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != want {
+		t.Errorf("GetByID = %+v, 期望 %+v", *got, want)
+	}
+}
+
+func TestGetByIDErrors(t *testing.T) {
+	dbErr := errors.New("连接断开")
+	tests := []struct {
+		name  string
+		setup func(*sqlmock.ExpectedQuery)
+		want  error
+	}{
+		{"不存在", func(q *sqlmock.ExpectedQuery) { q.WillReturnRows(sqlmock.NewRows(employeeColumns)) }, ErrNotFound},
+		{"查询失败", func(q *sqlmock.ExpectedQuery) { q.WillReturnError(dbErr) }, dbErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMock(t)
+			tt.setup(mock.ExpectQuery(quote("FROM employees WHERE id = ?")).WithArgs(7))
+
+			_, err := GetByID(context.Background(), db, 7)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("错误 = %v, 期望 %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsert(t *testing.T) {
+	db, mock := newMock(t)
+	expectEnsureDepartment(mock, "技术部", 3)
+	mock.ExpectExec(quote("INSERT INTO employees (name, department, department_id, salary) VALUES (?, ?, ?, ?)")).
+		WithArgs("张三", "技术部", 3, 12000).
+		WillReturnResult(sqlmock.NewResult(42, 1))
+	expectSalaryChange(mock, 42)
+	expectAudit(mock, 42, audit.ActionCreate)
+
+	e := &Employee{Name: "张三", Department: "技术部", Salary: 12000}
+	if err := Insert(context.Background(), db, e); err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != 42 {
+		t.Errorf("回填的 ID = %d, 期望 42", e.ID)
+	}
+}
+
+func TestInsertErrors(t *testing.T) {
+	dbErr := errors.New("连接断开")
+
+	t.Run("数据不合法时不执行语句", func(t *testing.T) {
+		db, _ := newMock(t)
+		err := Insert(context.Background(), db, &Employee{Department: "技术部", Salary: -1})
+		var errs validate.Errors
+		if !errors.As(err, &errs) || errs["name"] == "" || errs["salary"] == "" {
+			t.Errorf("错误 = %v, 期望 name 和 salary 的校验错误", err)
+		}
+	})
+
+	t.Run("插入失败", func(t *testing.T) {
+		db, mock := newMock(t)
+		expectEnsureDepartment(mock, "技术部", 3)
+		mock.ExpectExec(quote("INSERT INTO employees")).WillReturnError(dbErr)
+
+		err := Insert(context.Background(), db, &Employee{Name: "张三", Department: "技术部"})
+		if !errors.Is(err, dbErr) {
+			t.Errorf("错误 = %v, 期望包装 %v", err, dbErr)
+		}
+	})
+
+	t.Run("创建部门失败", func(t *testing.T) {
+		db, mock := newMock(t)
+		mock.ExpectExec(quote("INSERT IGNORE INTO departments")).WillReturnError(dbErr)
+
+		err := Insert(context.Background(), db, &Employee{Name: "张三", Department: "技术部"})
+		if !errors.Is(err, dbErr) {
+			t.Errorf("错误 = %v, 期望包装 %v", err, dbErr)
+		}
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	before := Employee{ID: 7, Name: "张三", Department: "技术部", Salary: 12000}
+	after := Employee{ID: 7, Name: "张三", Department: "产品部", Salary: 15000}
+
+	tests := []struct {
+		name    string
+		changed int64 // UPDATE 实际改变的行数
+	}{
+		{"有变化时记录薪资和审计", 1},
+		{"没有变化时跳过薪资和审计", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMock(t)
+			expectGetByID(mock, before)
+			expectEnsureDepartment(mock, "产品部", 5)
+			mock.ExpectExec(quote("UPDATE employees SET name = ?, department = ?, department_id = ?, salary = ? WHERE id = ?")).
+				WithArgs("张三", "产品部", 5, 15000, 7).
+				WillReturnResult(sqlmock.NewResult(0, tt.changed))
+			if tt.changed > 0 {
+				expectSalaryChange(mock, 7)
+				expectAudit(mock, 7, audit.ActionUpdate)
+			}
+
+			e := after
+			if err := Update(context.Background(), db, &e); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestUpdateNotFound(t *testing.T) {
+	db, mock := newMock(t)
+	mock.ExpectQuery(quote("FROM employees WHERE id = ?")).WithArgs(7).WillReturnRows(sqlmock.NewRows(employeeColumns))
+
+	err := Update(context.Background(), db, &Employee{ID: 7, Name: "张三", Department: "技术部"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("错误 = %v, 期望 ErrNotFound", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db, mock := newMock(t)
+	expectGetByID(mock, Employee{ID: 7, Name: "张三", Department: "技术部", Salary: 12000})
+	mock.ExpectExec(quote("DELETE FROM employees WHERE id = ?")).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAudit(mock, 7, audit.ActionDelete)
+
+	if err := Delete(context.Background(), db, 7); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteErrors(t *testing.T) {
+	dbErr := errors.New("连接断开")
+	tests := []struct {
+		name   string
+		result func(*sqlmock.ExpectedExec)
+		want   error
+	}{
+		// 查询和删除之间被并发删除
+		{"没有删除任何行", func(e *sqlmock.ExpectedExec) { e.WillReturnResult(sqlmock.NewResult(0, 0)) }, ErrNotFound},
+		{"删除失败", func(e *sqlmock.ExpectedExec) { e.WillReturnError(dbErr) }, dbErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMock(t)
+			expectGetByID(mock, Employee{ID: 7, Name: "张三", Department: "技术部"})
+			tt.result(mock.ExpectExec(quote("DELETE FROM employees WHERE id = ?")).WithArgs(7))
+
+			err := Delete(context.Background(), db, 7)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("错误 = %v, 期望 %v", err, tt.want)
+			}
+		})
+	}
+}
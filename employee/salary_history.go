@@ -0,0 +1,165 @@
+package employee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNoSalaryRecord 指定日期之前没有薪资记录 (员工尚未入职或不存在)
+var ErrNoSalaryRecord = errors.New("没有薪资记录")
+
+// SalaryChange 一次薪资变动
+type SalaryChange struct {
+	EffectiveFrom  time.Time `db:"effective_from" json:"effective_from"`
+	Salary         int       `db:"salary" json:"salary"`
+	PreviousSalary *int      `db:"previous_salary" json:"previous_salary,omitempty"` // 第一条记录为 nil
+}
+
+// GrowthPercent 相对上一次的涨幅百分比, 第一条记录返回 0
+func (c SalaryChange) GrowthPercent() float64 {
+	if c.PreviousSalary == nil || *c.PreviousSalary == 0 {
+		return 0
+	}
+	return float64(c.Salary-*c.PreviousSalary) / float64(*c.PreviousSalary) * 100
+}
+
+// 当前薪资与最近一条历史记录不同时追加一条记录. 参数依次为生效时间和两次员工 ID,
+// 员工 ID 为 NULL 时处理全部员工
+const sqlRecordSalaryChanges = `
+	INSERT INTO salary_history (employee_id, salary, effective_from)
+	SELECT e.id, e.salary, ?
+	FROM employees e
+	WHERE (? IS NULL OR e.id = ?)
+	AND NOT (e.salary <=> (
+		SELECT h.salary FROM salary_history h
+		WHERE h.employee_id = e.id
+		ORDER BY h.effective_from DESC, h.id DESC
+		LIMIT 1
+	))
+`
+
+// 创建薪资历史表并为已有员工写入初始记录, 可重复执行
+func migrateSalaryHistory(ctx context.Context, db *sqlx.DB) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS salary_history (
+			id             BIGINT AUTO_INCREMENT PRIMARY KEY,
+			employee_id    INT NOT NULL,
+			salary         INT NOT NULL,
+			effective_from DATETIME NOT NULL,
+			created_at     DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			KEY idx_salary_history_employee (employee_id, effective_from)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建薪资历史表失败: %w", err)
+	}
+	return recordAllSalaryChanges(ctx, db)
+}
+
+// 员工薪资写入后调用, 薪资有变化时以当前时间为生效日期记录
+func recordSalaryChange(ctx context.Context, db sqlx.ExecerContext, employeeID int) error {
+	if _, err := db.ExecContext(ctx, sqlRecordSalaryChanges, time.Now(), employeeID, employeeID); err != nil {
+		return fmt.Errorf("记录薪资变动失败: %w", err)
+	}
+	return nil
+}
+
+// 批量写入员工后调用, 为所有薪资有变化的员工记录
+func recordAllSalaryChanges(ctx context.Context, db sqlx.ExecerContext) error {
+	if _, err := db.ExecContext(ctx, sqlRecordSalaryChanges, time.Now(), nil, nil); err != nil {
+		return fmt.Errorf("记录薪资变动失败: %w", err)
+	}
+	return nil
+}
+
+// ChangeSalary 调整员工薪资, effectiveFrom 可以是过去的日期 (补录调薪), 不能晚于当前时间.
+// 员工的当前薪资总是取生效时间最晚的一条记录, 补录的调薪早于已有记录时不影响当前薪资
+func ChangeSalary(ctx context.Context, db *sqlx.DB, employeeID, salary int, effectiveFrom time.Time) error {
+	if effectiveFrom.After(time.Now()) {
+		return fmt.Errorf("生效日期 %s 晚于当前时间", effectiveFrom.Format(time.DateOnly))
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := GetByID(ctx, tx, employeeID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO salary_history (employee_id, salary, effective_from)
+		VALUES (?, ?, ?)
+	`, employeeID, salary, effectiveFrom)
+	if err != nil {
+		return fmt.Errorf("记录薪资变动失败: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE employees SET salary = (
+			SELECT h.salary FROM salary_history h
+			WHERE h.employee_id = ?
+			ORDER BY h.effective_from DESC, h.id DESC
+			LIMIT 1
+		)
+		WHERE id = ?
+	`, employeeID, employeeID)
+	if err != nil {
+		return fmt.Errorf("更新薪资失败: %w", err)
+	}
+
+	after, err := GetByID(ctx, tx, employeeID)
+	if err != nil {
+		return err
+	}
+	if err := auditEmployee(ctx, tx, employeeID, before, after); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// SalaryAt 返回员工在指定时间的薪资
+func SalaryAt(ctx context.Context, db sqlx.QueryerContext, employeeID int, at time.Time) (int, error) {
+	var salary int
+	err := sqlx.GetContext(ctx, db, &salary, `
+		SELECT salary FROM salary_history
+		WHERE employee_id = ? AND effective_from <= ?
+		ORDER BY effective_from DESC, id DESC
+		LIMIT 1
+	`, employeeID, at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("员工 %d 在 %s: %w", employeeID, at.Format(time.DateOnly), ErrNoSalaryRecord)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("查询历史薪资失败: %w", err)
+	}
+	return salary, nil
+}
+
+// SalaryGrowth 按生效时间顺序返回员工的全部薪资变动及每次的上一档薪资
+func SalaryGrowth(ctx context.Context, db sqlx.QueryerContext, employeeID int) ([]SalaryChange, error) {
+	var changes []SalaryChange
+	err := sqlx.SelectContext(ctx, db, &changes, `
+		SELECT effective_from, salary,
+			LAG(salary) OVER (ORDER BY effective_from, id) AS previous_salary
+		FROM salary_history
+		WHERE employee_id = ?
+		ORDER BY effective_from, id
+	`, employeeID)
+	if err != nil {
+		return nil, fmt.Errorf("查询薪资变动失败: %w", err)
+	}
+	return changes, nil
+}
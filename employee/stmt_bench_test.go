@@ -0,0 +1,93 @@
+package employee
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alexwang789/Base1_golang_task3/stmtcache"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newBenchDB 创建内存 SQLite 上的员工表并插入 rows 行, 部门轮流取三个值.
+// 内存库每个连接各自独立, 连接池限制为一个连接
+func newBenchDB(b *testing.B, rows int) *sqlx.DB {
+	b.Helper()
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	b.Cleanup(func() { db.Close() })
+
+	db.MustExec(`CREATE TABLE employees (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		name       TEXT NOT NULL,
+		department TEXT NOT NULL,
+		salary     INTEGER NOT NULL
+	)`)
+	db.MustExec(`CREATE INDEX idx_employees_department ON employees (department)`)
+
+	departments := []string{"技术部", "市场部", "财务部"}
+	tx := db.MustBegin()
+	for i := range rows {
+		tx.MustExec(`INSERT INTO employees (name, department, salary) VALUES (?, ?, ?)`,
+			fmt.Sprintf("员工%d", i), departments[i%len(departments)], 5000+i)
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+	return db
+}
+
+// 缓存的预编译语句与每次即时执行同一查询的对比. 运行: go test -run=^$ -bench=Statement ./employee
+func BenchmarkStatementEmployeesByDepartment(b *testing.B) {
+	db := newBenchDB(b, 300)
+
+	b.Run("预编译", func(b *testing.B) {
+		stmts := stmtcache.New(db)
+		b.Cleanup(func() { stmts.Close() })
+		for b.Loop() {
+			if _, err := getEmployeesByDepartment(stmts, "技术部"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("即时", func(b *testing.B) {
+		query, args, err := sqlx.Named(sqlEmployeesByDepartment, map[string]any{"department": "技术部"})
+		if err != nil {
+			b.Fatal(err)
+		}
+		query = db.Rebind(query)
+		for b.Loop() {
+			var employees []Employee
+			if err := db.Select(&employees, query, args...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkStatementHighestPaidEmployee(b *testing.B) {
+	db := newBenchDB(b, 300)
+
+	b.Run("预编译", func(b *testing.B) {
+		stmts := stmtcache.New(db)
+		b.Cleanup(func() { stmts.Close() })
+		for b.Loop() {
+			if _, err := getHighestPaidEmployee(stmts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("即时", func(b *testing.B) {
+		for b.Loop() {
+			var employee Employee
+			if err := db.Get(&employee, sqlHighestPaidEmployee); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
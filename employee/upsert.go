@@ -0,0 +1,66 @@
+package employee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Upsert 按主键插入或更新员工, ID 为 0 时总是插入新员工
+func Upsert(ctx context.Context, db *gorm.DB, employee *Employee) error {
+	if err := employee.Validate(); err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 写入前的值用于审计, 不存在时为插入
+		var before *Employee
+		if employee.ID != 0 {
+			var current Employee
+			err := tx.Table("employees").Clauses(clause.Locking{Strength: "UPDATE"}).Take(&current, employee.ID).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("查询员工失败: %w", err)
+			}
+			if err == nil {
+				before = &current
+			}
+		}
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "department", "salary"}),
+		}).Create(employee).Error
+		if err != nil {
+			return fmt.Errorf("写入员工失败: %w", err)
+		}
+
+		// 同步部门外键, 薪资有变化时记录薪资历史
+		if err := tx.Exec("INSERT IGNORE INTO departments (name) VALUES (?)", employee.Department).Error; err != nil {
+			return fmt.Errorf("同步员工部门失败: %w", err)
+		}
+		err = tx.Exec(`
+			UPDATE employees e
+			JOIN departments d ON d.name = e.department
+			SET e.department_id = d.id
+			WHERE e.id = ?
+		`, employee.ID).Error
+		if err != nil {
+			return fmt.Errorf("同步员工部门失败: %w", err)
+		}
+		if err := tx.Exec(sqlRecordSalaryChanges, time.Now(), employee.ID, employee.ID).Error; err != nil {
+			return fmt.Errorf("记录薪资变动失败: %w", err)
+		}
+
+		entry, changed, err := newEmployeeAuditLog(ctx, employee.ID, before, employee)
+		if err != nil || !changed {
+			return err
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return fmt.Errorf("写入审计日志失败: %w", err)
+		}
+		return nil
+	})
+}
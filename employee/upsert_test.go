@@ -0,0 +1,88 @@
+package employee
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newGormMock 在 newMock 的连接上打开 MySQL 方言的 GORM
+func newGormMock(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock := newMock(t)
+	gdb, err := gorm.Open(mysql.New(mysql.Config{Conn: db.DB, SkipInitializeWithVersion: true}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gdb, mock
+}
+
+func TestUpsert(t *testing.T) {
+	current := Employee{ID: 7, Name: "张三", Department: "技术部", Salary: 12000}
+	tests := []struct {
+		name     string
+		employee Employee
+		existing *Employee // 写入前库中的员工, nil 表示不存在
+		wantID   int
+		audited  bool // 是否写入审计日志
+	}{
+		{"ID 为 0 时插入", Employee{Name: "李四", Department: "技术部", Salary: 9000}, nil, 9, true},
+		{"ID 不存在时插入", Employee{ID: 9, Name: "李四", Department: "技术部", Salary: 9000}, nil, 9, true},
+		{"ID 已存在时更新", Employee{ID: 7, Name: "张三", Department: "产品部", Salary: 15000}, &current, 7, true},
+		{"更新但没有变化", current, &current, 7, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newGormMock(t)
+			mock.ExpectBegin()
+			if tt.employee.ID != 0 {
+				rows := sqlmock.NewRows(employeeColumns)
+				if tt.existing != nil {
+					rows.AddRow(tt.existing.ID, tt.existing.Name, tt.existing.Department, tt.existing.Salary)
+				}
+				mock.ExpectQuery(quote("SELECT * FROM `employees` WHERE `employees`.`id` = ? LIMIT ? FOR UPDATE")).
+					WithArgs(tt.employee.ID, 1).
+					WillReturnRows(rows)
+			}
+			mock.ExpectExec(quote("INSERT INTO `employees`") + ".*" +
+				quote("ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`department`=VALUES(`department`),`salary`=VALUES(`salary`)")).
+				WillReturnResult(sqlmock.NewResult(int64(tt.wantID), 1))
+			mock.ExpectExec(quote("INSERT IGNORE INTO departments (name) VALUES (?)")).
+				WithArgs(tt.employee.Department).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(quote("UPDATE employees e JOIN departments d ON d.name = e.department")).
+				WithArgs(tt.wantID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			expectSalaryChange(mock, tt.wantID)
+			if tt.audited {
+				mock.ExpectExec(quote("INSERT INTO `audit_logs`")).WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+			mock.ExpectCommit()
+
+			e := tt.employee
+			if err := Upsert(context.Background(), db, &e); err != nil {
+				t.Fatal(err)
+			}
+			if e.ID != tt.wantID {
+				t.Errorf("ID = %d, 期望 %d", e.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestUpsertRollsBackOnError(t *testing.T) {
+	db, mock := newGormMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(quote("INSERT INTO `employees`")).WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectExec(quote("INSERT IGNORE INTO departments")).WillReturnError(gorm.ErrInvalidDB)
+	mock.ExpectRollback()
+
+	e := Employee{Name: "李四", Department: "技术部", Salary: 9000}
+	if err := Upsert(context.Background(), db, &e); err == nil {
+		t.Fatal("同步部门失败时应返回错误")
+	}
+}
@@ -0,0 +1,13 @@
+package employee
+
+import "github.com/alexwang789/Base1_golang_task3/validate"
+
+// Validate 校验员工的姓名、部门和薪资, 失败时返回 validate.Errors
+func (e *Employee) Validate() error {
+	errs := validate.Errors{}
+	errs.Check(validate.NotBlank(e.Name), "name", "姓名不能为空")
+	errs.Check(validate.NotBlank(e.Department), "department", "部门不能为空")
+	errs.Check(validate.MaxLen(e.Department, 100), "department", "部门不能超过 100 个字符") // departments.name 为 VARCHAR(100)
+	errs.Check(e.Salary >= 0, "salary", "薪资不能为负数")
+	return errs.Err()
+}
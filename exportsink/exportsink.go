@@ -0,0 +1,96 @@
+// Package exportsink 导出数据的输出目标: gzip 文件或对象存储的分片上传.
+//
+// 导出逐行生成数据并直接写入 Sink, 不在内存中缓冲整个导出. 导出方每写完一行调用
+// Checkpoint 报告当前游标 (通常是该行主键), 支持断点续传的 Sink 在分片边界记录游标,
+// 重新运行时由 Resume 返回上次提交的游标, 导出方从该游标之后继续.
+package exportsink
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Sink 导出数据的写入目标
+type Sink interface {
+	io.Writer
+
+	// Checkpoint 在一行完整写入后调用, cursor 标识该行
+	Checkpoint(cursor string) error
+	// Resume 返回上次中断前已提交的游标, 没有可恢复的进度时返回空串
+	Resume() string
+	// Close 完成导出, 之后写入的数据才对读取方可见
+	Close() error
+	// Abort 放弃导出并清理已写出的部分
+	Abort() error
+}
+
+// Open 按目标地址创建 Sink: s3://bucket/key 使用分片上传 (总是 gzip 压缩), 其他视为本地文件路径.
+// checkpointPath 只对 S3 目标生效
+func Open(ctx context.Context, dest, checkpointPath string) (Sink, error) {
+	if !strings.HasPrefix(dest, "s3://") {
+		return CreateFile(dest)
+	}
+
+	u, err := url.Parse(dest)
+	if err != nil || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("无效的 S3 地址 %q, 格式为 s3://bucket/key", dest)
+	}
+	client, err := NewS3Client(ctx, u.Host)
+	if err != nil {
+		return nil, err
+	}
+	return NewMultipart(ctx, client, strings.TrimPrefix(u.Path, "/"), MultipartOptions{
+		CheckpointPath: checkpointPath,
+	})
+}
+
+// fileSink 写入本地文件, 文件名以 .gz 结尾时启用 gzip 压缩; 不支持断点续传
+type fileSink struct {
+	f  *os.File
+	gz *gzip.Writer
+	w  io.Writer
+}
+
+// CreateFile 创建本地文件作为导出目标
+func CreateFile(path string) (Sink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建导出文件失败: %w", err)
+	}
+
+	s := &fileSink{f: f, w: f}
+	if strings.HasSuffix(path, ".gz") {
+		s.gz = gzip.NewWriter(f)
+		s.w = s.gz
+	}
+	return s, nil
+}
+
+func (s *fileSink) Write(p []byte) (int, error) { return s.w.Write(p) }
+
+func (s *fileSink) Checkpoint(string) error { return nil }
+
+func (s *fileSink) Resume() string { return "" }
+
+func (s *fileSink) Close() error {
+	if s.gz != nil {
+		if err := s.gz.Close(); err != nil {
+			s.f.Close()
+			return fmt.Errorf("写入 gzip 尾部失败: %w", err)
+		}
+	}
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("关闭导出文件失败: %w", err)
+	}
+	return nil
+}
+
+func (s *fileSink) Abort() error {
+	s.f.Close()
+	return os.Remove(s.f.Name())
+}
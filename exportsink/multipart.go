@@ -0,0 +1,191 @@
+package exportsink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// MinPartSize S3 要求除最后一片外每片至少 5 MiB
+const MinPartSize = 5 << 20
+
+// MultipartClient 对象存储的分片上传接口, S3 的实现见 NewS3Client
+type MultipartClient interface {
+	Create(ctx context.Context, key string) (uploadID string, err error)
+	UploadPart(ctx context.Context, key, uploadID string, number int, data []byte) (etag string, err error)
+	Complete(ctx context.Context, key, uploadID string, parts []Part) error
+	Abort(ctx context.Context, key, uploadID string) error
+}
+
+// Part 已上传的分片
+type Part struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Cursor string `json:"cursor"` // 分片中最后一行的游标
+}
+
+// checkpoint 断点续传状态, 每上传完一片写入一次
+type checkpoint struct {
+	Key      string `json:"key"`
+	UploadID string `json:"upload_id"`
+	Parts    []Part `json:"parts"`
+}
+
+// MultipartOptions 分片上传配置
+type MultipartOptions struct {
+	PartSize       int    // 压缩后每片的目标大小, 小于 MinPartSize 时使用 MinPartSize
+	CheckpointPath string // 断点续传状态文件, 为空时不支持续传
+}
+
+// multipartSink 把数据压缩后分片上传. 每个分片是一个独立的 gzip member 且只包含完整的行,
+// 多个 member 拼接后仍是合法的 gzip 文件; 续传时从最后一片的游标之后重新生成数据即可,
+// 不要求重新生成的数据与中断前逐字节一致
+type multipartSink struct {
+	ctx    context.Context
+	client MultipartClient
+	opts   MultipartOptions
+	state  checkpoint
+
+	buf     bytes.Buffer
+	gz      *gzip.Writer
+	written int    // 当前分片已写入的未压缩字节数
+	cursor  string // 当前分片中最后一行的游标
+}
+
+// NewMultipart 创建分片上传目标. CheckpointPath 中存在同一 key 的未完成上传时继续该上传
+func NewMultipart(ctx context.Context, client MultipartClient, key string, opts MultipartOptions) (Sink, error) {
+	opts.PartSize = max(opts.PartSize, MinPartSize)
+	s := &multipartSink{ctx: ctx, client: client, opts: opts}
+
+	state, err := readCheckpoint(opts.CheckpointPath)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil && state.Key == key {
+		s.state = *state
+	} else {
+		id, err := client.Create(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("创建分片上传失败: %w", err)
+		}
+		s.state = checkpoint{Key: key, UploadID: id}
+		if err := s.saveCheckpoint(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.gz = gzip.NewWriter(&s.buf)
+	return s, nil
+}
+
+func (s *multipartSink) Write(p []byte) (int, error) {
+	n, err := s.gz.Write(p)
+	s.written += n
+	return n, err
+}
+
+func (s *multipartSink) Checkpoint(cursor string) error {
+	s.cursor = cursor
+	if s.buf.Len() < s.opts.PartSize {
+		return nil
+	}
+	return s.flushPart()
+}
+
+func (s *multipartSink) Resume() string {
+	if n := len(s.state.Parts); n > 0 {
+		return s.state.Parts[n-1].Cursor
+	}
+	return ""
+}
+
+func (s *multipartSink) Close() error {
+	if err := s.flushPart(); err != nil {
+		return err
+	}
+	if err := s.client.Complete(s.ctx, s.state.Key, s.state.UploadID, s.state.Parts); err != nil {
+		return fmt.Errorf("完成分片上传失败: %w", err)
+	}
+	return s.removeCheckpoint()
+}
+
+func (s *multipartSink) Abort() error {
+	err := s.client.Abort(s.ctx, s.state.Key, s.state.UploadID)
+	return errors.Join(err, s.removeCheckpoint())
+}
+
+// flushPart 结束当前 gzip member 并作为一个分片上传
+func (s *multipartSink) flushPart() error {
+	if err := s.gz.Close(); err != nil {
+		return fmt.Errorf("压缩分片失败: %w", err)
+	}
+	// 没有新数据时不上传空分片, 但整个上传至少要有一片
+	if s.written > 0 || len(s.state.Parts) == 0 {
+		number := len(s.state.Parts) + 1
+		etag, err := s.client.UploadPart(s.ctx, s.state.Key, s.state.UploadID, number, s.buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("上传第 %d 片失败: %w", number, err)
+		}
+		s.state.Parts = append(s.state.Parts, Part{Number: number, ETag: etag, Cursor: s.cursor})
+		if err := s.saveCheckpoint(); err != nil {
+			return err
+		}
+	}
+
+	s.buf.Reset()
+	s.gz.Reset(&s.buf)
+	s.written = 0
+	return nil
+}
+
+func readCheckpoint(path string) (*checkpoint, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取续传状态失败: %w", err)
+	}
+
+	var state checkpoint
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析续传状态失败: %w", err)
+	}
+	return &state, nil
+}
+
+func (s *multipartSink) saveCheckpoint() error {
+	if s.opts.CheckpointPath == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再改名, 中断时不会留下写了一半的状态
+	tmp := s.opts.CheckpointPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("写入续传状态失败: %w", err)
+	}
+	if err := os.Rename(tmp, s.opts.CheckpointPath); err != nil {
+		return fmt.Errorf("写入续传状态失败: %w", err)
+	}
+	return nil
+}
+
+func (s *multipartSink) removeCheckpoint() error {
+	if s.opts.CheckpointPath == "" {
+		return nil
+	}
+	if err := os.Remove(s.opts.CheckpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("删除续传状态失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package exportsink
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+)
+
+// Record 一条导出记录, JSON 格式直接序列化, CSV 格式使用 CSVHeader/CSVRow
+type Record interface {
+	CSVHeader() []string
+	CSVRow() []string
+}
+
+// Formats 支持的导出格式
+var Formats = []string{"jsonl", "json", "csv"}
+
+// Output 按格式把记录写入 Sink, 每条记录写完后以主键作为续传游标
+type Output struct {
+	sink   Sink
+	format string
+	after  uint64
+
+	enc   *json.Encoder
+	csv   *csv.Writer
+	wrote bool // 已写出过记录 (含续传前), 决定 JSON 数组是否需要逗号、CSV 是否需要表头
+}
+
+// NewOutput 创建指定格式的输出, format 为 Formats 之一
+func NewOutput(sink Sink, format string) (*Output, error) {
+	out := &Output{sink: sink, format: format}
+	if cursor := sink.Resume(); cursor != "" {
+		id, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的续传游标 %q: %w", cursor, err)
+		}
+		out.after = id
+		out.wrote = true
+	}
+
+	switch format {
+	case "jsonl":
+		out.enc = json.NewEncoder(sink)
+	case "json":
+		out.enc = json.NewEncoder(sink)
+		if !out.wrote {
+			if _, err := io.WriteString(sink, "["); err != nil {
+				return nil, err
+			}
+		}
+	case "csv":
+		out.csv = csv.NewWriter(sink)
+	default:
+		return nil, fmt.Errorf("不支持的导出格式 %q", format)
+	}
+	return out, nil
+}
+
+// After 续传时上次导出到的主键, 从头导出时为 0. 导出方应从该主键之后开始读取
+func (o *Output) After() uint64 {
+	return o.after
+}
+
+// Write 写入一条记录, id 为记录主键, 导出方须按主键递增的顺序写入
+func (o *Output) Write(rec Record, id uint64) error {
+	var err error
+	switch o.format {
+	case "jsonl":
+		err = o.enc.Encode(rec)
+	case "json":
+		if o.wrote {
+			_, err = io.WriteString(o.sink, ",")
+		}
+		if err == nil {
+			err = o.enc.Encode(rec)
+		}
+	case "csv":
+		if !o.wrote {
+			err = o.csv.Write(rec.CSVHeader())
+		}
+		if err == nil {
+			err = o.csv.Write(rec.CSVRow())
+		}
+		if err == nil {
+			// 续传的游标只能落在已写入 Sink 的行上
+			o.csv.Flush()
+			err = o.csv.Error()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("写入导出数据失败: %w", err)
+	}
+	o.wrote = true
+	return o.sink.Checkpoint(strconv.FormatUint(id, 10))
+}
+
+// finish 写入格式的结尾
+func (o *Output) finish() error {
+	if o.format == "json" {
+		if _, err := io.WriteString(o.sink, "]\n"); err != nil {
+			return fmt.Errorf("写入导出数据失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Export 打开 dest (见 Open) 并调用 write 写入全部记录.
+// checkpointPath 不为空时 S3 上传支持断点续传: 失败后保留已上传的分片, 重新运行同一导出从中断处继续
+func Export(ctx context.Context, dest, format, checkpointPath string, write func(*Output) error) error {
+	sink, err := Open(ctx, dest, checkpointPath)
+	if err != nil {
+		return err
+	}
+	if cursor := sink.Resume(); cursor != "" {
+		log.Printf("从游标 %s 之后继续导出", cursor)
+	}
+
+	out, err := NewOutput(sink, format)
+	if err == nil {
+		err = write(out)
+	}
+	if err == nil {
+		err = out.finish()
+	}
+	if err != nil {
+		if checkpointPath == "" {
+			if abortErr := sink.Abort(); abortErr != nil {
+				log.Printf("清理导出失败: %v", abortErr)
+			}
+		}
+		return fmt.Errorf("导出失败: %w", err)
+	}
+	return sink.Close()
+}
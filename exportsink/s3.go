@@ -0,0 +1,79 @@
+package exportsink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3Client 基于 AWS SDK 的 MultipartClient 实现
+type s3Client struct {
+	api    *s3.Client
+	bucket string
+}
+
+// NewS3Client 使用默认凭证链 (环境变量、共享配置文件、实例角色) 创建指定存储桶的分片上传客户端
+func NewS3Client(ctx context.Context, bucket string) (MultipartClient, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("加载 AWS 配置失败: %w", err)
+	}
+	return &s3Client{api: s3.NewFromConfig(cfg), bucket: bucket}, nil
+}
+
+func (c *s3Client) Create(ctx context.Context, key string) (string, error) {
+	out, err := c.api.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(c.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String("application/gzip"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.UploadId), nil
+}
+
+func (c *s3Client) UploadPart(ctx context.Context, key, uploadID string, number int, data []byte) (string, error) {
+	out, err := c.api.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(c.bucket),
+		Key:        aws.String(key),
+		UploadId:   aws.String(uploadID),
+		PartNumber: aws.Int32(int32(number)),
+		Body:       bytes.NewReader(data),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.ETag), nil
+}
+
+func (c *s3Client) Complete(ctx context.Context, key, uploadID string, parts []Part) error {
+	completed := make([]types.CompletedPart, len(parts))
+	for i, p := range parts {
+		completed[i] = types.CompletedPart{
+			ETag:       aws.String(p.ETag),
+			PartNumber: aws.Int32(int32(p.Number)),
+		}
+	}
+	_, err := c.api.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(c.bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	return err
+}
+
+func (c *s3Client) Abort(ctx context.Context, key, uploadID string) error {
+	_, err := c.api.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(c.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	return err
+}
@@ -0,0 +1,201 @@
+// Package fieldcrypt 敏感字段的行级加密: 以 AES-256-GCM 加密后存储, 读取时解密, 对业务代码透明.
+//
+// 模型字段加上 GORM 标签 serializer:encrypted 即可, 如 User.Email. 密文形如 "enc:<密钥 ID>:<base64(nonce || 密文)>",
+// 不带前缀的值视为加密上线前写入的明文, 原样读出, 由 "blog rekey" 命令加密.
+//
+// 密钥环的第一个密钥用于加密, 其余的只用于解密. 轮换密钥时把新密钥放在最前, 执行 rekey 把全部密文改用新密钥后再移除旧密钥.
+// 未配置密钥时 (开发环境) 按明文存储.
+//
+// 加密的值每次都不同, 不能用于等值查询和唯一索引; 需要按值查找的字段另存 Hash (HMAC-SHA256) 作为盲索引.
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"gorm.io/gorm/schema"
+)
+
+// 密文前缀
+const prefix = "enc:"
+
+// ErrUnknownKey 密文使用的密钥不在密钥环中
+var ErrUnknownKey = errors.New("密文使用的密钥未配置")
+
+// Keyring 加密密钥和盲索引密钥
+type Keyring struct {
+	active  string // 用于加密的密钥 ID, 为空时不加密
+	keys    map[string]cipher.AEAD
+	hashKey []byte
+}
+
+// NewKeyring 按配置创建密钥环, 密钥为 base64 编码的 32 字节 (AES-256)
+func NewKeyring(cfg config.FieldEncryption) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	for i, entry := range cfg.Keys {
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("第 %d 个加密密钥格式应为 <ID>:<base64 密钥>", i+1)
+		}
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(secret) != 32 {
+			return nil, fmt.Errorf("加密密钥 %s 应为 base64 编码的 32 字节", id)
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("加密密钥 ID %s 重复", id)
+		}
+		block, err := aes.NewCipher(secret)
+		if err != nil {
+			return nil, fmt.Errorf("创建加密密钥 %s 失败: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("创建加密密钥 %s 失败: %w", id, err)
+		}
+		k.keys[id] = aead
+		if i == 0 {
+			k.active = id
+		}
+	}
+	if cfg.HashKey != "" {
+		secret, err := base64.StdEncoding.DecodeString(cfg.HashKey)
+		if err != nil || len(secret) < 16 {
+			return nil, errors.New("盲索引密钥应为 base64 编码的至少 16 字节")
+		}
+		k.hashKey = secret
+	}
+	return k, nil
+}
+
+// Encrypt 用当前密钥加密; 没有配置密钥时原样返回
+func (k *Keyring) Encrypt(plain string) (string, error) {
+	if k.active == "" {
+		return plain, nil
+	}
+	aead := k.keys[k.active]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), []byte(k.active))
+	return prefix + k.active + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的结果, 不带密文前缀的值原样返回
+func (k *Keyring) Decrypt(v string) (string, error) {
+	id, sealed, ok := parse(v)
+	if !ok {
+		return v, nil
+	}
+	aead, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return "", errors.New("密文格式错误")
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %w", err)
+	}
+	return string(plain), nil
+}
+
+// NeedsRekey 存储的值是否需要用当前密钥重新加密: 明文, 或由其他密钥加密. 没有配置密钥时总是 false
+func (k *Keyring) NeedsRekey(v string) bool {
+	if k.active == "" {
+		return false
+	}
+	id, _, ok := parse(v)
+	return !ok || id != k.active
+}
+
+// Hash 返回 v 的盲索引 (HMAC-SHA256 的十六进制, 64 个字符). 与加密密钥无关, 轮换加密密钥后不变
+func (k *Keyring) Hash(v string) string {
+	mac := hmac.New(sha256.New, k.hashKey)
+	mac.Write([]byte(v))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parse 拆分密文为密钥 ID 和 base64 部分, 不是密文时 ok 为 false
+func parse(v string) (id, sealed string, ok bool) {
+	rest, ok := strings.CutPrefix(v, prefix)
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
+
+// 序列化器使用的进程级密钥环, 由 Configure 设置, 未设置时不加密
+var current atomic.Pointer[Keyring]
+
+func init() {
+	current.Store(&Keyring{})
+	schema.RegisterSerializer("encrypted", Serializer{})
+}
+
+// Configure 按配置设置进程级密钥环, 在打开数据库时调用
+func Configure(cfg config.FieldEncryption) error {
+	k, err := NewKeyring(cfg)
+	if err != nil {
+		return err
+	}
+	current.Store(k)
+	return nil
+}
+
+// Current 返回进程级密钥环
+func Current() *Keyring {
+	return current.Load()
+}
+
+// Hash 用进程级密钥环计算盲索引
+func Hash(v string) string {
+	return Current().Hash(v)
+}
+
+// Serializer GORM 序列化器, 以 "encrypted" 注册, 用于 string 字段
+type Serializer struct{}
+
+// Scan 实现 schema.SerializerInterface
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	var v string
+	switch dbValue := dbValue.(type) {
+	case nil:
+		return nil
+	case []byte:
+		v = string(dbValue)
+	case string:
+		v = dbValue
+	default:
+		return fmt.Errorf("字段 %s 的值类型 %T 无法解密", field.Name, dbValue)
+	}
+	plain, err := Current().Decrypt(v)
+	if err != nil {
+		return fmt.Errorf("字段 %s: %w", field.Name, err)
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plain)
+	return nil
+}
+
+// Value 实现 schema.SerializerValuerInterface
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	plain, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("字段 %s 的类型 %T 不能加密, 只支持 string", field.Name, fieldValue)
+	}
+	return Current().Encrypt(plain)
+}
@@ -0,0 +1,263 @@
+// Package fieldsel 响应裁剪参数, 移动端等客户端只取需要的字段和关联, 减少查询的列和响应的大小:
+//
+//	?fields=id,title,author.name&embed=comments(limit:5)
+//
+// fields 为逗号分隔的字段, 对象字段的子字段用 . 连接 (author.name), 只写对象名时返回整个对象; 省略时返回全部字段.
+// embed 为逗号分隔的关联, 括号中为 参数:值 形式的参数, 目前只有 limit (每条记录内嵌的条数).
+//
+// 字段和关联必须在调用方给出的白名单 (Fields, Embeds) 中. 字段映射到生成它需要的列, 查询只读取选择的字段对应的列;
+// 拼进 SQL 的只有白名单中的列名, 用户输入不会成为 SQL 文本.
+package fieldsel
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalid fields 或 embed 参数不合法, 错误信息可以直接返回给调用方
+var ErrInvalid = errors.New("字段参数不合法")
+
+// Field 可选择的响应字段
+type Field struct {
+	Columns []string // 生成该字段需要查询的列, 只能来自代码
+	Nested  []string // 对象字段可以单独选择的子字段, 为空时不是对象
+}
+
+// Fields 对外的字段名 -> 字段
+type Fields map[string]Field
+
+// Embed 可内嵌的关联
+type Embed struct {
+	DefaultLimit int // 未指定 limit 时每条记录内嵌的条数
+	MaxLimit     int
+}
+
+// Embeds 对外的关联名 -> 关联
+type Embeds map[string]Embed
+
+// 单次请求的字段数和关联数上限
+const (
+	MaxFields = 30
+	MaxEmbeds = 5
+)
+
+// Selection 解析后的字段和关联. 零值表示返回全部字段, 不内嵌关联
+type Selection struct {
+	fields  map[string][]string // 选择的字段 -> 选择的子字段, 为 nil 时返回整个字段
+	columns []string            // 选择的字段需要查询的列
+	embeds  map[string]int      // 内嵌的关联 -> 条数
+}
+
+// Parse 按白名单解析 fields 和 embed 参数, 均可为空
+func Parse(fields, embed string, allowed Fields, embeds Embeds) (*Selection, error) {
+	s := &Selection{}
+	if fields != "" {
+		items := strings.Split(fields, ",")
+		if len(items) > MaxFields {
+			return nil, fmt.Errorf("%w: 最多选择 %d 个字段", ErrInvalid, MaxFields)
+		}
+		s.fields = make(map[string][]string, len(items))
+		for _, item := range items {
+			if err := s.addField(strings.TrimSpace(item), allowed); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if embed != "" {
+		items, err := splitTop(embed)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) > MaxEmbeds {
+			return nil, fmt.Errorf("%w: 最多内嵌 %d 个关联", ErrInvalid, MaxEmbeds)
+		}
+		s.embeds = make(map[string]int, len(items))
+		for _, item := range items {
+			if err := s.addEmbed(strings.TrimSpace(item), embeds); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *Selection) addField(item string, allowed Fields) error {
+	name, sub, nested := strings.Cut(item, ".")
+	f, ok := allowed[name]
+	if !ok {
+		return fmt.Errorf("%w: 不支持字段 %q", ErrInvalid, item)
+	}
+	if nested && !slices.Contains(f.Nested, sub) {
+		return fmt.Errorf("%w: 不支持字段 %q", ErrInvalid, item)
+	}
+
+	current, seen := s.fields[name]
+	switch {
+	case !nested:
+		s.fields[name] = nil // 整个字段, 覆盖之前选择的子字段
+	case !seen:
+		s.fields[name] = []string{sub}
+	case current != nil && !slices.Contains(current, sub):
+		s.fields[name] = append(current, sub)
+	}
+	for _, c := range f.Columns {
+		if !slices.Contains(s.columns, c) {
+			s.columns = append(s.columns, c)
+		}
+	}
+	return nil
+}
+
+// addEmbed 解析 name 或 name(limit:N)
+func (s *Selection) addEmbed(item string, embeds Embeds) error {
+	name, args, hasArgs := strings.Cut(item, "(")
+	e, ok := embeds[name]
+	if !ok {
+		return fmt.Errorf("%w: 不支持内嵌 %q", ErrInvalid, name)
+	}
+	limit := e.DefaultLimit
+	if hasArgs {
+		args, ok = strings.CutSuffix(args, ")")
+		if !ok {
+			return fmt.Errorf("%w: %q 缺少右括号", ErrInvalid, item)
+		}
+		for _, arg := range strings.Split(args, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(arg), ":")
+			if key != "limit" {
+				return fmt.Errorf("%w: 内嵌 %s 不支持参数 %q", ErrInvalid, name, key)
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 1 || n > e.MaxLimit {
+				return fmt.Errorf("%w: 内嵌 %s 的 limit 应为 1~%d", ErrInvalid, name, e.MaxLimit)
+			}
+			limit = n
+		}
+	}
+	s.embeds[name] = limit
+	return nil
+}
+
+// splitTop 按括号外的逗号拆分
+func splitTop(s string) ([]string, error) {
+	var items []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, s[start:i])
+				start = i + 1
+			}
+		}
+		if depth < 0 || depth > 1 {
+			return nil, fmt.Errorf("%w: %q 的括号不匹配", ErrInvalid, s)
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("%w: %q 的括号不匹配", ErrInvalid, s)
+	}
+	return append(items, s[start:]), nil
+}
+
+// All 是否返回全部字段 (没有指定 fields)
+func (s *Selection) All() bool {
+	return s.fields == nil
+}
+
+// Has 是否选择了字段 name (或它的子字段), 没有指定 fields 时总是为 true. 未选择的对象字段可以不加载
+func (s *Selection) Has(name string) bool {
+	if s.All() {
+		return true
+	}
+	_, ok := s.fields[name]
+	return ok
+}
+
+// Embed 是否内嵌关联 name 及每条记录内嵌的条数
+func (s *Selection) Embed(name string) (int, bool) {
+	limit, ok := s.embeds[name]
+	return limit, ok
+}
+
+// Columns 需要查询的列: 选择的字段对应的列加上 always (如主键和处理请求本身需要的列); 返回全部字段时为 nil
+func (s *Selection) Columns(always ...string) []string {
+	if s.All() {
+		return nil
+	}
+	columns := slices.Clone(always)
+	for _, c := range s.columns {
+		if !slices.Contains(columns, c) {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// Scope 只查询 Columns(always...) 中的列, 返回全部字段时不限制
+func (s *Selection) Scope(always ...string) func(*gorm.DB) *gorm.DB {
+	columns := s.Columns(always...)
+	return func(tx *gorm.DB) *gorm.DB {
+		if columns == nil {
+			return tx
+		}
+		return tx.Select(columns)
+	}
+}
+
+// Apply 裁剪响应 v (对象或对象数组): 只保留选择的字段和内嵌的关联, 对象字段只保留选择的子字段.
+// 没有指定 fields 时原样返回
+func (s *Selection) Apply(v any) (any, error) {
+	if s.All() {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("裁剪响应失败: %w", err)
+	}
+	var decoded any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // 保持数字原样输出
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("裁剪响应失败: %w", err)
+	}
+	s.prune(decoded)
+	return decoded, nil
+}
+
+func (s *Selection) prune(v any) {
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			s.prune(item)
+		}
+	case map[string]any:
+		for key, value := range v {
+			if _, ok := s.embeds[key]; ok {
+				continue
+			}
+			nested, ok := s.fields[key]
+			if !ok {
+				delete(v, key)
+				continue
+			}
+			if obj, isObj := value.(map[string]any); isObj && nested != nil {
+				for sub := range obj {
+					if !slices.Contains(nested, sub) {
+						delete(obj, sub)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Package filterdsl 列表接口的过滤和排序参数:
+//
+//	?filter=salary>5000,department=技术部,name~张&sort=-salary,id
+//
+// filter 为逗号分隔的条件, 每个条件为 字段 运算符 值, 运算符为 = != > >= < <= 和 ~ (包含, 仅字符串);
+// sort 为逗号分隔的字段, 前缀 - 表示倒序. 值中不能含逗号.
+//
+// 字段必须在调用方给出的白名单 (Fields) 中, 对外的字段名映射到列名; 值按字段类型解析后作为参数绑定.
+// 拼进 SQL 的只有白名单中的列名和固定的运算符, 用户输入不会成为 SQL 文本.
+package filterdsl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalid 过滤或排序参数不合法, 错误信息可以直接返回给调用方
+var ErrInvalid = errors.New("过滤参数不合法")
+
+// Kind 字段的值类型
+type Kind int
+
+const (
+	String Kind = iota
+	Int
+	Float
+	Time // RFC 3339 时间或 2006-01-02 格式的日期 (UTC 零点)
+)
+
+// Field 可过滤和排序的字段
+type Field struct {
+	Column string // 列名, 只能来自代码
+	Kind   Kind
+}
+
+// Fields 对外的字段名 -> 字段
+type Fields map[string]Field
+
+// 单次查询的条件数和排序字段数上限
+const (
+	MaxConds = 10
+	MaxSort  = 3
+)
+
+// Cond 一个过滤条件. Op 为 SQL 运算符 (= <> > >= < <= LIKE), Value 已按字段类型转换,
+// LIKE 的值已转义通配符并加上 %
+type Cond struct {
+	Column string
+	Op     string
+	Value  any
+}
+
+// Order 一个排序字段
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// Query 解析后的过滤和排序
+type Query struct {
+	Conds []Cond
+	Sort  []Order
+}
+
+// 运算符 -> SQL 运算符, 两个字符的在前, 使 >= 不会被识别为 >
+var operators = []struct{ dsl, sql string }{
+	{">=", ">="},
+	{"<=", "<="},
+	{"!=", "<>"},
+	{">", ">"},
+	{"<", "<"},
+	{"=", "="},
+	{"~", "LIKE"},
+}
+
+// Parse 按白名单解析 filter 和 sort 参数, 均可为空
+func Parse(filter, sort string, fields Fields) (*Query, error) {
+	q := &Query{}
+	if filter != "" {
+		items := strings.Split(filter, ",")
+		if len(items) > MaxConds {
+			return nil, fmt.Errorf("%w: 最多 %d 个条件", ErrInvalid, MaxConds)
+		}
+		for _, item := range items {
+			cond, err := parseCond(strings.TrimSpace(item), fields)
+			if err != nil {
+				return nil, err
+			}
+			q.Conds = append(q.Conds, cond)
+		}
+	}
+	if sort != "" {
+		items := strings.Split(sort, ",")
+		if len(items) > MaxSort {
+			return nil, fmt.Errorf("%w: 最多按 %d 个字段排序", ErrInvalid, MaxSort)
+		}
+		for _, item := range items {
+			item = strings.TrimSpace(item)
+			name, desc := strings.CutPrefix(item, "-")
+			f, ok := fields[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: 不支持按 %q 排序", ErrInvalid, name)
+			}
+			q.Sort = append(q.Sort, Order{Column: f.Column, Desc: desc})
+		}
+	}
+	return q, nil
+}
+
+func parseCond(item string, fields Fields) (Cond, error) {
+	// 取最靠前的运算符, 同一位置优先两个字符的
+	at, op := -1, operators[0]
+	for _, o := range operators {
+		if i := strings.Index(item, o.dsl); i > 0 && (at < 0 || i < at) {
+			at, op = i, o
+		}
+	}
+	if at < 0 {
+		return Cond{}, fmt.Errorf("%w: 条件 %q 缺少运算符", ErrInvalid, item)
+	}
+	name, raw := strings.TrimSpace(item[:at]), strings.TrimSpace(item[at+len(op.dsl):])
+	f, ok := fields[name]
+	if !ok {
+		return Cond{}, fmt.Errorf("%w: 不支持按 %q 过滤", ErrInvalid, name)
+	}
+
+	if op.sql == "LIKE" {
+		if f.Kind != String {
+			return Cond{}, fmt.Errorf("%w: %s 不是文本字段, 不能用 ~", ErrInvalid, name)
+		}
+		return Cond{Column: f.Column, Op: op.sql, Value: "%" + EscapeLike(raw) + "%"}, nil
+	}
+	v, err := parseValue(f.Kind, raw)
+	if err != nil {
+		return Cond{}, fmt.Errorf("%w: %s 的值 %q 无效", ErrInvalid, name, raw)
+	}
+	return Cond{Column: f.Column, Op: op.sql, Value: v}, nil
+}
+
+func parseValue(kind Kind, raw string) (any, error) {
+	switch kind {
+	case Int:
+		return strconv.ParseInt(raw, 10, 64)
+	case Float:
+		return strconv.ParseFloat(raw, 64)
+	case Time:
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.DateOnly, raw)
+	}
+	return raw, nil
+}
+
+// EscapeLike 转义 LIKE 中的通配符, 使用户输入按字面匹配
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Scope 把条件和排序转换为 GORM 查询条件, 列以当前表限定. 排序与 tx.Order 一样追加在已有的排序之后.
+// 调用方的默认排序应作为之后的 scope 传入, 成为用户排序相同时的次要排序; db.Scopes 在执行时才应用,
+// 在它之后直接调用的 Order 反而排在前面
+func (q *Query) Scope() func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		for _, c := range q.Conds {
+			col := clause.Column{Table: clause.CurrentTable, Name: c.Column}
+			var expr clause.Expression
+			switch c.Op {
+			case "=":
+				expr = clause.Eq{Column: col, Value: c.Value}
+			case "<>":
+				expr = clause.Neq{Column: col, Value: c.Value}
+			case ">":
+				expr = clause.Gt{Column: col, Value: c.Value}
+			case ">=":
+				expr = clause.Gte{Column: col, Value: c.Value}
+			case "<":
+				expr = clause.Lt{Column: col, Value: c.Value}
+			case "<=":
+				expr = clause.Lte{Column: col, Value: c.Value}
+			case "LIKE":
+				expr = clause.Like{Column: col, Value: c.Value}
+			}
+			tx = tx.Where(expr)
+		}
+		for _, o := range q.Sort {
+			tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: o.Column}, Desc: o.Desc})
+		}
+		return tx
+	}
+}
@@ -0,0 +1,61 @@
+package filterdsl
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type employee struct {
+	ID     uint
+	Name   string
+	Salary int
+}
+
+var employeeFields = Fields{
+	"name":   {Column: "name", Kind: String},
+	"salary": {Column: "salary", Kind: Int},
+}
+
+func TestScopeOrder(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newestFirst := func(tx *gorm.DB) *gorm.DB { return tx.Order("id DESC") }
+	tests := []struct {
+		name  string
+		sort  string
+		build func(tx *gorm.DB, scope func(*gorm.DB) *gorm.DB) *gorm.DB
+		want  string
+	}{
+		{"默认排序作为之后的 scope", "-salary,name", func(tx *gorm.DB, scope func(*gorm.DB) *gorm.DB) *gorm.DB {
+			return tx.Scopes(scope, newestFirst)
+		}, "ORDER BY `employees`.`salary` DESC,`employees`.`name`,id DESC"},
+		{"追加在已有的排序之后", "salary", func(tx *gorm.DB, scope func(*gorm.DB) *gorm.DB) *gorm.DB {
+			return scope(tx.Order("id"))
+		}, "ORDER BY id,`employees`.`salary`"},
+		{"Scopes 在执行时才应用", "salary", func(tx *gorm.DB, scope func(*gorm.DB) *gorm.DB) *gorm.DB {
+			return tx.Scopes(scope).Order("id DESC")
+		}, "ORDER BY id DESC,`employees`.`salary`"},
+		{"没有排序参数", "", func(tx *gorm.DB, scope func(*gorm.DB) *gorm.DB) *gorm.DB {
+			return tx.Scopes(scope, newestFirst)
+		}, "ORDER BY id DESC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse("", tt.sort, employeeFields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stmt := tt.build(db.Model(&employee{}), q.Scope()).Find(&[]employee{}).Statement
+			if got := stmt.SQL.String(); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("SQL = %s, 期望以 %s 结尾", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+// Package fixtures 从 YAML 文件加载声明式数据, 测试和 seed 流程共用同一份定义.
+//
+// 文件顶层键为表名, 其下每个条目有一个唯一的名字, 条目内容为列名到值的映射.
+// 形如 "$表名.条目名" 的字符串值会被解析为被引用条目插入后的主键,
+// 被引用的条目总是先于引用方插入, 与它们在文件中的顺序无关:
+//
+//	users:
+//	  zhangsan:
+//	    name: 张三
+//	    email: zhangsan@example.com
+//	posts:
+//	  go_intro:
+//	    title: Go语言入门
+//	    user_id: $users.zhangsan
+//
+// 以 "$$" 开头的字符串表示字面量 "$", 不做引用解析.
+//
+// 形如 "@now"、"@now-72h"、"@now+30m" 的字符串值是相对时间, 以插入时给定的基准时间计算
+// (见 InsertAt), 测试传入固定的基准时间即可得到确定的数据. "@@" 开头表示字面量 "@".
+// YAML 不允许未加引号的值以 @ 开头, 需写成 created_at: "@now-72h".
+//
+// 测试之间可以用 Reload 清空 fixture 涉及的表并重新插入, 自增主键随之复位.
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 表名和列名只允许普通标识符, 拼接进 SQL 前先校验
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Execer 是插入数据所需的最小接口, *sql.DB, *sql.Tx 和 *sqlx.DB 都满足
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Entry 一条 fixture 记录
+type Entry struct {
+	Table   string
+	Name    string
+	Columns []string       // 列名, 保持文件中的书写顺序
+	Values  map[string]any // 列名 -> 原始值 (引用尚未解析)
+}
+
+// Key 返回条目的引用键 "表名.条目名"
+func (e *Entry) Key() string {
+	return e.Table + "." + e.Name
+}
+
+// Set 一组待插入的 fixture 条目
+type Set struct {
+	entries []*Entry
+	index   map[string]*Entry
+}
+
+// Refs 记录已插入条目的主键, 键为 "表名.条目名"
+type Refs map[string]int64
+
+// ID 返回指定条目插入后的主键, 条目不存在时返回 0
+func (r Refs) ID(table, name string) int64 {
+	return r[table+"."+name]
+}
+
+// Load 读取并合并多个 fixture 文件
+func Load(paths ...string) (*Set, error) {
+	set := &Set{index: make(map[string]*Entry)}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取 fixture 文件失败: %w", err)
+		}
+		if err := set.parse(data); err != nil {
+			return nil, fmt.Errorf("解析 fixture 文件 %s 失败: %w", path, err)
+		}
+	}
+	return set, nil
+}
+
+// Parse 解析单个 fixture 文档
+func Parse(data []byte) (*Set, error) {
+	set := &Set{index: make(map[string]*Entry)}
+	if err := set.parse(data); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Entries 按文件顺序返回全部条目
+func (s *Set) Entries() []*Entry {
+	return s.entries
+}
+
+func (s *Set) parse(data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil // 空文件
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("第 %d 行: 顶层必须是 表名 -> 条目 的映射", root.Line)
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		table, entries := root.Content[i].Value, root.Content[i+1]
+		if !identRe.MatchString(table) {
+			return fmt.Errorf("第 %d 行: 非法表名 %q", root.Content[i].Line, table)
+		}
+		if entries.Kind != yaml.MappingNode {
+			return fmt.Errorf("第 %d 行: 表 %s 的内容必须是 条目名 -> 列 的映射", entries.Line, table)
+		}
+
+		for j := 0; j < len(entries.Content); j += 2 {
+			entry, err := parseEntry(table, entries.Content[j], entries.Content[j+1])
+			if err != nil {
+				return err
+			}
+			if _, dup := s.index[entry.Key()]; dup {
+				return fmt.Errorf("第 %d 行: 条目 %s 重复定义", entries.Content[j].Line, entry.Key())
+			}
+			s.entries = append(s.entries, entry)
+			s.index[entry.Key()] = entry
+		}
+	}
+	return nil
+}
+
+func parseEntry(table string, nameNode, body *yaml.Node) (*Entry, error) {
+	if body.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("第 %d 行: 条目 %s.%s 必须是 列名 -> 值 的映射", body.Line, table, nameNode.Value)
+	}
+
+	entry := &Entry{
+		Table:  table,
+		Name:   nameNode.Value,
+		Values: make(map[string]any, len(body.Content)/2),
+	}
+	for k := 0; k < len(body.Content); k += 2 {
+		column, valueNode := body.Content[k].Value, body.Content[k+1]
+		if !identRe.MatchString(column) {
+			return nil, fmt.Errorf("第 %d 行: 非法列名 %q", body.Content[k].Line, column)
+		}
+		if valueNode.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("第 %d 行: 列 %s 只能是标量值", valueNode.Line, column)
+		}
+
+		var value any
+		if err := valueNode.Decode(&value); err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", valueNode.Line, err)
+		}
+		entry.Columns = append(entry.Columns, column)
+		entry.Values[column] = value
+	}
+	return entry, nil
+}
+
+// Tables 按首次出现的顺序返回条目涉及的表
+func (s *Set) Tables() []string {
+	var tables []string
+	seen := make(map[string]bool)
+	for _, entry := range s.entries {
+		if !seen[entry.Table] {
+			seen[entry.Table] = true
+			tables = append(tables, entry.Table)
+		}
+	}
+	return tables
+}
+
+// Insert 以当前时间为基准调用 InsertAt
+func (s *Set) Insert(ctx context.Context, db Execer) (Refs, error) {
+	return s.InsertAt(ctx, db, time.Now())
+}
+
+// InsertAt 按引用依赖顺序插入全部条目, 返回各条目的主键. 相对时间以 now 为基准.
+// 条目显式给出 id 列时使用该值, 否则取数据库生成的自增主键.
+func (s *Set) InsertAt(ctx context.Context, db Execer, now time.Time) (Refs, error) {
+	l := &loader{
+		set:      s,
+		db:       db,
+		now:      now,
+		refs:     make(Refs, len(s.entries)),
+		visiting: make(map[string]bool),
+	}
+	for _, entry := range s.entries {
+		if err := l.insert(ctx, entry); err != nil {
+			return nil, err
+		}
+	}
+	return l.refs, nil
+}
+
+// Reload 清空条目涉及的表后以 now 为基准重新插入, 用于测试之间复位数据.
+// TRUNCATE 会复位自增主键, 未显式给出 id 的条目每次得到相同的主键.
+// 清空期间关闭外键检查, 该设置只对当前连接有效, 因此全程使用同一个连接
+func (s *Set) Reload(ctx context.Context, db *sql.DB, now time.Time) (Refs, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return nil, fmt.Errorf("关闭外键检查失败: %w", err)
+	}
+	for _, table := range s.Tables() {
+		if _, err := conn.ExecContext(ctx, "TRUNCATE TABLE `"+table+"`"); err != nil {
+			conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 1")
+			return nil, fmt.Errorf("清空表 %s 失败: %w", table, err)
+		}
+	}
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 1"); err != nil {
+		return nil, fmt.Errorf("恢复外键检查失败: %w", err)
+	}
+
+	return s.InsertAt(ctx, conn, now)
+}
+
+type loader struct {
+	set      *Set
+	db       Execer
+	now      time.Time // 相对时间的基准
+	refs     Refs
+	visiting map[string]bool // 正在解析依赖的条目, 用于发现循环引用
+}
+
+func (l *loader) insert(ctx context.Context, entry *Entry) error {
+	key := entry.Key()
+	if _, done := l.refs[key]; done {
+		return nil
+	}
+	if l.visiting[key] {
+		return fmt.Errorf("fixture %s 存在循环引用", key)
+	}
+	l.visiting[key] = true
+	defer delete(l.visiting, key)
+
+	columns := make([]string, 0, len(entry.Columns))
+	args := make([]any, 0, len(entry.Columns))
+	for _, column := range entry.Columns {
+		value, err := l.resolve(ctx, entry.Values[column])
+		if err != nil {
+			return fmt.Errorf("fixture %s.%s: %w", key, column, err)
+		}
+		columns = append(columns, "`"+column+"`")
+		args = append(args, value)
+	}
+
+	query := fmt.Sprintf("INSERT INTO `%s` (%s) VALUES (%s)",
+		entry.Table,
+		strings.Join(columns, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", "))
+
+	result, err := l.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("插入 fixture %s 失败: %w", key, err)
+	}
+
+	if id, ok := toInt64(entry.Values["id"]); ok {
+		l.refs[key] = id
+		return nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取 fixture %s 主键失败: %w", key, err)
+	}
+	l.refs[key] = id
+	return nil
+}
+
+// resolve 把 "$表名.条目名" 解析为被引用条目的主键, 必要时先插入被引用条目; 把 "@now..." 解析为时间
+func (l *loader) resolve(ctx context.Context, value any) (any, error) {
+	s, ok := value.(string)
+	if ok && strings.HasPrefix(s, "@") {
+		return l.relativeTime(s)
+	}
+	if !ok || !strings.HasPrefix(s, "$") {
+		return value, nil
+	}
+	if strings.HasPrefix(s, "$$") {
+		return s[1:], nil
+	}
+
+	target, ok := l.set.index[s[1:]]
+	if !ok {
+		return nil, fmt.Errorf("引用的条目 %s 不存在", s)
+	}
+	if err := l.insert(ctx, target); err != nil {
+		return nil, err
+	}
+	return l.refs[target.Key()], nil
+}
+
+// 解析 "@now"、"@now-72h" 形式的相对时间, "@@" 开头的字符串去掉一个 "@" 后原样返回
+func (l *loader) relativeTime(s string) (any, error) {
+	if strings.HasPrefix(s, "@@") {
+		return s[1:], nil
+	}
+	offset, ok := strings.CutPrefix(s, "@now")
+	if !ok {
+		return nil, fmt.Errorf("无法识别的相对时间 %q, 应为 @now 或 @now±时长", s)
+	}
+	if offset == "" {
+		return l.now, nil
+	}
+	if offset[0] != '+' && offset[0] != '-' {
+		return nil, fmt.Errorf("无法识别的相对时间 %q, 应为 @now 或 @now±时长", s)
+	}
+	d, err := time.ParseDuration(offset)
+	if err != nil {
+		return nil, fmt.Errorf("相对时间 %q: %w", s, err)
+	}
+	return l.now.Add(d), nil
+}
+
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), true
+	}
+	return 0, false
+}
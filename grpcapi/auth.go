@@ -0,0 +1,120 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/alexwang789/Base1_golang_task3/apikey"
+	"github.com/alexwang789/Base1_golang_task3/audit"
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	blogv1 "github.com/alexwang789/Base1_golang_task3/gen/blog/v1"
+	employeev1 "github.com/alexwang789/Base1_golang_task3/gen/employee/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// methodScopes 各方法要求的 API Key 权限范围, 未列出的方法一律拒绝
+var methodScopes = map[string]string{
+	blogv1.BlogService_GetUser_FullMethodName:    apikey.ScopeRead,
+	blogv1.BlogService_GetPost_FullMethodName:    apikey.ScopeRead,
+	blogv1.BlogService_ListPosts_FullMethodName:  apikey.ScopeRead,
+	blogv1.BlogService_CreatePost_FullMethodName: apikey.ScopeWrite,
+	blogv1.BlogService_UpdatePost_FullMethodName: apikey.ScopeWrite,
+	blogv1.BlogService_DeletePost_FullMethodName: apikey.ScopeWrite,
+
+	employeev1.EmployeeService_GetEmployee_FullMethodName:    apikey.ScopeRead,
+	employeev1.EmployeeService_ListEmployees_FullMethodName:  apikey.ScopeRead,
+	employeev1.EmployeeService_CreateEmployee_FullMethodName: apikey.ScopeWrite,
+	employeev1.EmployeeService_UpdateEmployee_FullMethodName: apikey.ScopeWrite,
+	employeev1.EmployeeService_DeleteEmployee_FullMethodName: apikey.ScopeWrite,
+}
+
+// adminOnly 只允许管理员的 key 调用的服务. 员工数据含薪资, 与 REST 的 /employees 一样仅管理员可用
+var adminOnly = map[string]bool{
+	employeev1.EmployeeService_ServiceDesc.ServiceName: true,
+}
+
+type currentUserKey struct{}
+
+// authenticate 按 authorization 元数据中的 API Key ("Bearer <key>", 见 apikey) 认证调用方, 并按方法检查权限范围.
+// 认证通过后把 key 代表的用户放入 context, 并作为审计日志的操作者
+func authenticate(db *gorm.DB) grpc.UnaryServerInterceptor {
+	users := blog.NewUserRepository(db)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		scope, ok := methodScopes[info.FullMethod]
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, "不允许调用该方法")
+		}
+		token, ok := bearerToken(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "需要 API Key")
+		}
+
+		key, err := apikey.Authenticate(ctx, db, token)
+		if errors.Is(err, apikey.ErrInvalid) || errors.Is(err, apikey.ErrExpired) {
+			return nil, status.Error(codes.Unauthenticated, "API Key 无效或已过期")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !key.Has(scope) {
+			return nil, status.Errorf(codes.PermissionDenied, "API Key 没有 %s 权限", scope)
+		}
+
+		user, err := users.GetByIDCached(ctx, key.UserID)
+		if errors.Is(err, blog.ErrUserNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "API Key 无效或已过期")
+		}
+		if err != nil {
+			return nil, err
+		}
+		service, _, _ := strings.Cut(strings.TrimPrefix(info.FullMethod, "/"), "/")
+		if adminOnly[service] && !user.IsAdmin {
+			return nil, status.Error(codes.PermissionDenied, "仅管理员可用")
+		}
+
+		ctx = audit.WithActor(context.WithValue(ctx, currentUserKey{}, user), user.ID)
+		return handler(ctx, req)
+	}
+}
+
+// bearerToken 返回 authorization 元数据携带的 API Key
+func bearerToken(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", false
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// currentUser 返回 authenticate 认证的用户
+func currentUser(ctx context.Context) *blog.User {
+	user, _ := ctx.Value(currentUserKey{}).(*blog.User)
+	return user
+}
+
+// apiKeyCredentials 在每次调用的 authorization 元数据中携带 API Key
+type apiKeyCredentials string
+
+func (c apiKeyCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(c)}, nil
+}
+
+// RequireTransportSecurity 服务只在内网提供, 不使用 TLS
+func (apiKeyCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// WithAPIKey 让客户端以 key (见 apikey) 的身份调用
+func WithAPIKey(key string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(apiKeyCredentials(key))
+}
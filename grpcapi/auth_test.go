@@ -0,0 +1,71 @@
+package grpcapi
+
+import (
+	"context"
+	"testing"
+
+	blogv1 "github.com/alexwang789/Base1_golang_task3/gen/blog/v1"
+	employeev1 "github.com/alexwang789/Base1_golang_task3/gen/employee/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 新增的方法必须声明权限范围, 否则会被 authenticate 一律拒绝
+func TestMethodScopesCoverServices(t *testing.T) {
+	for _, desc := range []grpc.ServiceDesc{blogv1.BlogService_ServiceDesc, employeev1.EmployeeService_ServiceDesc} {
+		for _, m := range desc.Methods {
+			method := "/" + desc.ServiceName + "/" + m.MethodName
+			if _, ok := methodScopes[method]; !ok {
+				t.Errorf("%s 没有声明权限范围", method)
+			}
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		md     metadata.MD
+		want   string
+		wantOK bool
+	}{
+		{"无元数据", nil, "", false},
+		{"Bearer", metadata.Pairs("authorization", "Bearer bk_abc"), "bk_abc", true},
+		{"大小写不敏感", metadata.Pairs("authorization", "bearer  bk_abc "), "bk_abc", true},
+		{"Basic", metadata.Pairs("authorization", "Basic dTpw"), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			got, ok := bearerToken(ctx)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("bearerToken() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// 未声明的方法和没有 key 的调用在访问数据库之前被拒绝
+func TestAuthenticateRejectsBeforeLookup(t *testing.T) {
+	intercept := authenticate(nil)
+	handler := func(context.Context, any) (any, error) {
+		t.Fatal("handler 不应被调用")
+		return nil, nil
+	}
+	tests := []struct {
+		method string
+		want   codes.Code
+	}{
+		{"/blog.v1.BlogService/Unknown", codes.PermissionDenied},
+		{blogv1.BlogService_GetPost_FullMethodName, codes.Unauthenticated},
+		{employeev1.EmployeeService_DeleteEmployee_FullMethodName, codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		_, err := intercept(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+		if got := status.Code(err); got != tt.want {
+			t.Errorf("%s: code = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
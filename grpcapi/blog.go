@@ -0,0 +1,174 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	blogv1 "github.com/alexwang789/Base1_golang_task3/gen/blog/v1"
+	"github.com/alexwang789/Base1_golang_task3/idcodec"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+// 文章列表的默认和最大每页条数
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type blogServer struct {
+	blogv1.UnimplementedBlogServiceServer
+
+	ids   *idcodec.Codec
+	users *blog.UserRepository
+	posts *blog.PostRepository
+}
+
+func newBlogServer(db *gorm.DB, ids *idcodec.Codec) *blogServer {
+	return &blogServer{
+		ids:   ids,
+		users: blog.NewUserRepository(db),
+		posts: blog.NewPostRepository(db),
+	}
+}
+
+func (s *blogServer) GetUser(ctx context.Context, req *blogv1.GetUserRequest) (*blogv1.User, error) {
+	id, err := s.ids.Decode(req.GetId())
+	if err != nil {
+		return nil, errInvalidID
+	}
+	user, err := s.users.GetByIDCached(ctx, id)
+	if errors.Is(err, blog.ErrUserNotFound) {
+		return nil, status.Error(codes.NotFound, "用户不存在")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &blogv1.User{
+		Id:           s.ids.Encode(user.ID),
+		Name:         user.Name,
+		ArticleCount: int32(user.ArticleCount),
+		CreatedAt:    timestamppb.New(user.CreatedAt),
+	}, nil
+}
+
+func (s *blogServer) CreatePost(ctx context.Context, req *blogv1.CreatePostRequest) (*blogv1.Post, error) {
+	authorID, err := s.ids.Decode(req.GetAuthorId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "无效的 author_id")
+	}
+	// 与 REST 一样只能以 key 代表的用户身份发表文章
+	if authorID != currentUser(ctx).ID {
+		return nil, status.Error(codes.PermissionDenied, "只能以 API Key 代表的用户发表文章")
+	}
+
+	post := &blog.Post{Title: req.GetTitle(), Content: req.GetContent(), UserID: authorID}
+	if req.PublishAt != nil {
+		publishAt := req.GetPublishAt().AsTime()
+		post.PublishAt = &publishAt
+	}
+	if err := s.posts.Create(ctx, post); errors.Is(err, blog.ErrEmailNotVerified) {
+		return nil, status.Error(codes.FailedPrecondition, "作者的邮箱尚未验证")
+	} else if err != nil {
+		return nil, err
+	}
+	return s.toPost(post), nil
+}
+
+func (s *blogServer) GetPost(ctx context.Context, req *blogv1.GetPostRequest) (*blogv1.Post, error) {
+	id, err := s.ids.Decode(req.GetId())
+	if err != nil {
+		return nil, errInvalidID
+	}
+	post, err := s.posts.GetByID(ctx, id)
+	if err != nil {
+		return nil, postError(err)
+	}
+	return s.toPost(post), nil
+}
+
+func (s *blogServer) UpdatePost(ctx context.Context, req *blogv1.UpdatePostRequest) (*blogv1.Post, error) {
+	id, err := s.ids.Decode(req.GetId())
+	if err != nil {
+		return nil, errInvalidID
+	}
+	post := &blog.Post{ID: id, Title: req.GetTitle(), Content: req.GetContent()}
+	if err := s.posts.Update(ctx, post); err != nil {
+		return nil, postError(err)
+	}
+	return s.toPost(post), nil
+}
+
+func (s *blogServer) DeletePost(ctx context.Context, req *blogv1.DeletePostRequest) (*emptypb.Empty, error) {
+	id, err := s.ids.Decode(req.GetId())
+	if err != nil {
+		return nil, errInvalidID
+	}
+	if err := s.posts.Delete(ctx, id); err != nil {
+		return nil, postError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ListPosts 翻页令牌是上一页最后一篇文章的编码 ID
+func (s *blogServer) ListPosts(ctx context.Context, req *blogv1.ListPostsRequest) (*blogv1.ListPostsResponse, error) {
+	authorID, err := s.ids.Decode(req.GetAuthorId())
+	if err != nil {
+		return nil, errInvalidID
+	}
+	var afterID uint
+	if token := req.GetPageToken(); token != "" {
+		if afterID, err = s.ids.Decode(token); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "无效的 page_token")
+		}
+	}
+	size := int(req.GetPageSize())
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	size = min(size, maxPageSize)
+
+	// 多取一条判断是否还有下一页
+	posts, err := s.posts.ListByUser(ctx, authorID, afterID, size+1)
+	if err != nil {
+		return nil, err
+	}
+	resp := &blogv1.ListPostsResponse{}
+	if len(posts) > size {
+		posts = posts[:size]
+		resp.NextPageToken = s.ids.Encode(posts[size-1].ID)
+	}
+	resp.Posts = make([]*blogv1.Post, len(posts))
+	for i := range posts {
+		resp.Posts[i] = s.toPost(&posts[i])
+	}
+	return resp, nil
+}
+
+func (s *blogServer) toPost(p *blog.Post) *blogv1.Post {
+	post := &blogv1.Post{
+		Id:            s.ids.Encode(p.ID),
+		Title:         p.Title,
+		Content:       p.Content,
+		CommentStatus: string(p.CommentStatus),
+		AuthorId:      s.ids.Encode(p.UserID),
+		CreatedAt:     timestamppb.New(p.CreatedAt),
+		UpdatedAt:     timestamppb.New(p.UpdatedAt),
+		Status:        string(p.Status),
+	}
+	if p.PublishAt != nil {
+		post.PublishAt = timestamppb.New(*p.PublishAt)
+	}
+	return post
+}
+
+func postError(err error) error {
+	if errors.Is(err, blog.ErrPostNotFound) {
+		return status.Error(codes.NotFound, "文章不存在")
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+package grpcapi
+
+import (
+	"fmt"
+
+	blogv1 "github.com/alexwang789/Base1_golang_task3/gen/blog/v1"
+	employeev1 "github.com/alexwang789/Base1_golang_task3/gen/employee/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Clients 其他服务调用博客和员工服务的客户端, 共用一个连接
+type Clients struct {
+	Blog     blogv1.BlogServiceClient
+	Employee employeev1.EmployeeServiceClient
+
+	conn *grpc.ClientConn
+}
+
+// Dial 创建连接到 addr 的客户端, 通常需要传入 WithAPIKey. 服务只在内网提供, 不使用 TLS
+func Dial(addr string, opts ...grpc.DialOption) (*Clients, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("连接 gRPC 服务 %s 失败: %w", addr, err)
+	}
+	return &Clients{
+		Blog:     blogv1.NewBlogServiceClient(conn),
+		Employee: employeev1.NewEmployeeServiceClient(conn),
+		conn:     conn,
+	}, nil
+}
+
+// Close 关闭连接
+func (c *Clients) Close() error {
+	return c.conn.Close()
+}
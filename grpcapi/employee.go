@@ -0,0 +1,119 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	employeev1 "github.com/alexwang789/Base1_golang_task3/gen/employee/v1"
+	"github.com/jmoiron/sqlx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type employeeServer struct {
+	employeev1.UnimplementedEmployeeServiceServer
+
+	hr *sqlx.DB
+}
+
+func newEmployeeServer(hr *sqlx.DB) *employeeServer {
+	return &employeeServer{hr: hr}
+}
+
+func (s *employeeServer) GetEmployee(ctx context.Context, req *employeev1.GetEmployeeRequest) (*employeev1.Employee, error) {
+	emp, err := employee.GetByID(ctx, s.hr, int(req.GetId()))
+	if err != nil {
+		return nil, employeeError(err)
+	}
+	return toEmployee(emp), nil
+}
+
+func (s *employeeServer) CreateEmployee(ctx context.Context, req *employeev1.CreateEmployeeRequest) (*employeev1.Employee, error) {
+	emp := &employee.Employee{Name: req.GetName(), Department: req.GetDepartment(), Salary: int(req.GetSalary())}
+	err := employee.InTx(ctx, s.hr, func(tx *sqlx.Tx) error {
+		return employee.Insert(ctx, tx, emp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toEmployee(emp), nil
+}
+
+func (s *employeeServer) UpdateEmployee(ctx context.Context, req *employeev1.UpdateEmployeeRequest) (*employeev1.Employee, error) {
+	in := req.GetEmployee()
+	if in == nil {
+		return nil, status.Error(codes.InvalidArgument, "缺少 employee")
+	}
+	emp := &employee.Employee{ID: int(in.GetId()), Name: in.GetName(), Department: in.GetDepartment(), Salary: int(in.GetSalary())}
+	err := employee.InTx(ctx, s.hr, func(tx *sqlx.Tx) error {
+		return employee.Update(ctx, tx, emp)
+	})
+	if err != nil {
+		return nil, employeeError(err)
+	}
+	return toEmployee(emp), nil
+}
+
+func (s *employeeServer) DeleteEmployee(ctx context.Context, req *employeev1.DeleteEmployeeRequest) (*emptypb.Empty, error) {
+	err := employee.InTx(ctx, s.hr, func(tx *sqlx.Tx) error {
+		return employee.Delete(ctx, tx, int(req.GetId()))
+	})
+	if err != nil {
+		return nil, employeeError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *employeeServer) ListEmployees(ctx context.Context, req *employeev1.ListEmployeesRequest) (*employeev1.ListEmployeesResponse, error) {
+	filter := employee.Filter{Department: req.GetDepartment(), NamePrefix: req.GetNamePrefix()}
+	if req.MinSalary != nil {
+		v := int(req.GetMinSalary())
+		filter.MinSalary = &v
+	}
+	if req.MaxSalary != nil {
+		v := int(req.GetMaxSalary())
+		filter.MaxSalary = &v
+	}
+	switch req.GetSortBy() {
+	case "", "id", "name", "department", "salary":
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "不支持按 %q 排序", req.GetSortBy())
+	}
+	page := employee.Page{
+		Number: int(req.GetPage()),
+		Size:   int(req.GetPageSize()),
+		SortBy: req.GetSortBy(),
+		Desc:   req.GetDesc(),
+	}
+
+	result, err := employee.Search(ctx, s.hr, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	resp := &employeev1.ListEmployeesResponse{
+		Employees: make([]*employeev1.Employee, len(result.Employees)),
+		Total:     int32(result.Total),
+	}
+	for i := range result.Employees {
+		resp.Employees[i] = toEmployee(&result.Employees[i])
+	}
+	return resp, nil
+}
+
+func toEmployee(e *employee.Employee) *employeev1.Employee {
+	return &employeev1.Employee{
+		Id:         int64(e.ID),
+		Name:       e.Name,
+		Department: e.Department,
+		Salary:     int64(e.Salary),
+	}
+}
+
+func employeeError(err error) error {
+	if errors.Is(err, employee.ErrNotFound) {
+		return status.Error(codes.NotFound, "员工不存在")
+	}
+	return err
+}
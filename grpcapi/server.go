@@ -0,0 +1,79 @@
+// Package grpcapi 博客和员工的 gRPC 服务, 供内部服务调用.
+//
+// 服务定义见 proto/, 与 REST API 共用 blog、employee 包的仓储和校验; 对外的博客 ID 同样经 idcodec 编码.
+// 调用方在 authorization 元数据中携带 API Key ("Bearer <key>", 见 apikey), 查询方法需要 read 权限, 写入方法需要 write 权限;
+// EmployeeService 只允许管理员的 key 调用. 服务不使用 TLS, 只应在内网监听.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+
+	blogv1 "github.com/alexwang789/Base1_golang_task3/gen/blog/v1"
+	employeev1 "github.com/alexwang789/Base1_golang_task3/gen/employee/v1"
+	"github.com/alexwang789/Base1_golang_task3/idcodec"
+	"github.com/alexwang789/Base1_golang_task3/validate"
+	"github.com/jmoiron/sqlx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// NewServer 创建注册了 BlogService 的 gRPC 服务; hr 不为 nil 时同时注册 EmployeeService
+func NewServer(db *gorm.DB, hr *sqlx.DB, ids *idcodec.Codec) *grpc.Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(recoverPanic, toStatus, authenticate(db)))
+	blogv1.RegisterBlogServiceServer(srv, newBlogServer(db, ids))
+	if hr != nil {
+		employeev1.RegisterEmployeeServiceServer(srv, newEmployeeServer(hr))
+	}
+	return srv
+}
+
+// Serve 在 addr 上提供 gRPC 服务, 阻塞直到服务退出
+func Serve(addr string, db *gorm.DB, hr *sqlx.DB, ids *idcodec.Codec) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %w", addr, err)
+	}
+	log.Printf("gRPC 监听 %s", addr)
+	return NewServer(db, hr, ids).Serve(lis)
+}
+
+// errInvalidID 无效的编码 ID 与不存在的资源一样返回 NotFound
+var errInvalidID = status.Error(codes.NotFound, "资源不存在")
+
+// toStatus 把处理函数返回的普通错误转换为 gRPC 状态: 校验错误为 InvalidArgument, 其余为 Internal 并记录日志.
+// 已经是 gRPC 状态的错误原样返回
+func toStatus(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return nil, err
+	}
+	var invalid validate.Errors
+	if errors.As(err, &invalid) {
+		return nil, status.Error(codes.InvalidArgument, invalid.Error())
+	}
+	if ctx.Err() != nil {
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	log.Printf("gRPC %s 内部错误: %v", info.FullMethod, err)
+	return nil, status.Error(codes.Internal, "服务器内部错误")
+}
+
+// recoverPanic 把处理函数的 panic 转换为 Internal 错误, 不让单个请求拖垮进程
+func recoverPanic(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("gRPC %s panic: %v", info.FullMethod, r)
+			err = status.Error(codes.Internal, "服务器内部错误")
+		}
+	}()
+	return handler(ctx, req)
+}
@@ -0,0 +1,156 @@
+package i18n
+
+// enUS 英文目录. 含 %d、%s 等的消息按格式串匹配, 译文中的动词与原文一一对应
+var enUS = map[string]string{
+	// 枚举
+	"comment_status.none":         "No comments",
+	"comment_status.has_comments": "Has comments",
+
+	// 通用
+	"服务器内部错误":                    "Internal server error",
+	"数据库暂时不可用":                   "Database temporarily unavailable",
+	"数据校验失败":                     "Validation failed",
+	"请求不符合接口定义":                  "Request does not match the API definition",
+	"资源不存在":                      "Resource not found",
+	"站点不存在":                      "Site not found",
+	"资源已被修改, 请重新获取后再提交":          "Resource has been modified, fetch it again before submitting",
+	"操作过于频繁":                     "Too many requests",
+	"操作过于频繁, 请 %s 后重试":           "Too many requests, retry after %s",
+	"读取请求体失败":                    "Failed to read request body",
+	"请求体超过 %d 字节":                "Request body exceeds %d bytes",
+	"时间范围过大":                     "Time range too large",
+	"from 不能晚于 to":               "from must not be later than to",
+	"before 无效":                  "Invalid before",
+	"%s 应为 RFC 3339 格式的时间":       "%s must be an RFC 3339 timestamp",
+	"无效的 entity_id %q":           "Invalid entity_id %q",
+	"无效的 actor_id %q":            "Invalid actor_id %q",
+	"无效的 limit %q":               "Invalid limit %q",
+	"window 应为不超过 %s 的时长, 如 24h": "window must be a duration of at most %s, such as 24h",
+
+	// 认证和权限
+	"需要登录":             "Login required",
+	"需要管理员权限":          "Administrator permission required",
+	"邮箱或密码错误":          "Incorrect email or password",
+	"API Key 无效或已过期":   "API key is invalid or expired",
+	"API Key 没有 %s 权限": "API key lacks the %s permission",
+	"邮箱尚未验证":           "Email address not verified",
+	"邮箱已验证":            "Email address already verified",
+	"验证链接无效或已过期":       "Verification link is invalid or expired",
+	"账号未关联学生":          "Account is not linked to a student",
+	"账号未关联员工":          "Account is not linked to an employee",
+	"人事系统不可用":          "HR system unavailable",
+
+	// 用户、文章和评论
+	"用户不存在":      "User not found",
+	"文章不存在":      "Post not found",
+	"评论不存在":      "Comment not found",
+	"暂无文章":       "No posts yet",
+	"没有阅读进度":     "No reading progress",
+	"没有可推荐的文章":   "No posts to recommend",
+	"不能关注自己":     "You cannot follow yourself",
+	"只有作者可以修改文章": "Only the author can edit the post",
+	"只有作者和管理员可以归档或恢复文章":      "Only the author or an administrator can archive or restore the post",
+	"文章状态不允许该操作":             "The post's status does not allow this operation",
+	"文章 %d 为 %s: 文章状态不允许该操作": "Post %d is %s: the post's status does not allow this operation",
+	"percent 必须在 0~100 之间":   "percent must be between 0 and 100",
+
+	// 用户数据的导出和注销
+	"没有待执行的注销请求":                             "No pending account erasure request",
+	`请求体应为 {"mode": "anonymize" 或 "delete"}`: `Request body must be {"mode": "anonymize" or "delete"}`,
+
+	// 附件
+	"附件不存在":                                   "Attachment not found",
+	"附件存储未启用":                                 "Attachment storage is not enabled",
+	"附件过大":                                    "Attachment too large",
+	"附件过大: 超过 %d 字节":                          "Attachment too large: exceeds %d bytes",
+	"不支持的附件类型":                                "Unsupported attachment type",
+	"不支持的附件类型: %s":                            "Unsupported attachment type: %s",
+	"只有文章作者可以上传附件":                            "Only the post's author can upload attachments",
+	"只有上传者可以删除附件":                             "Only the uploader can delete the attachment",
+	"请求体应为 multipart/form-data, 文件放在 file 字段": "Request body must be multipart/form-data with the file in the file field",
+
+	// 请求体格式
+	`请求体应为 {"title": "...", "content": "...", "publish_at": "RFC 3339 时间"}`:          `Request body must be {"title": "...", "content": "...", "publish_at": "RFC 3339 timestamp"}`,
+	`请求体应为 {"title": "...", "content": "..."}`:                                       `Request body must be {"title": "...", "content": "..."}`,
+	`请求体应为 {"content": "...", "parent_id": "..."}`:                                   `Request body must be {"content": "...", "parent_id": "..."}`,
+	`请求体应为 {"bio": "...", "avatar_url": "...", "website": "...", "location": "..."}`: `Request body must be {"bio": "...", "avatar_url": "...", "website": "...", "location": "..."}`,
+	`请求体应为 {"token": "..."}`:                                                         `Request body must be {"token": "..."}`,
+	`请求体应为 {"ids": [...]}`:                                                           `Request body must be {"ids": [...]}`,
+	`请求体应为 {"percent": 0~100}`:                                                       `Request body must be {"percent": 0~100}`,
+
+	// 幂等键
+	"Idempotency-Key 不能超过 255 个字符": "Idempotency-Key must not exceed 255 characters",
+	"Idempotency-Key 已用于另一个请求":     "Idempotency-Key has already been used for a different request",
+	"相同 Idempotency-Key 的请求正在处理":   "A request with the same Idempotency-Key is in progress",
+
+	// 字段校验
+	"姓名不能为空":                 "Name is required",
+	"姓名不能超过 100 个字符":         "Name must not exceed 100 characters",
+	"姓名已被使用":                 "Name is already taken",
+	"邮箱格式不正确":                "Invalid email address",
+	"邮箱不能超过 100 个字符":         "Email must not exceed 100 characters",
+	"邮箱已被注册":                 "Email is already registered",
+	"密码至少 6 位且需同时包含字母和数字":    "Password must be at least 6 characters and contain both letters and digits",
+	"密码不能超过 72 个字节":          "Password must be at most 72 bytes",
+	"标题不能为空":                 "Title is required",
+	"标题不能超过 200 个字符":         "Title must not exceed 200 characters",
+	"内容不能为空":                 "Content is required",
+	"缺少作者":                   "Author is missing",
+	"缺少所属文章":                 "Post is missing",
+	"审核状态不正确":                "Invalid moderation status",
+	"发布状态不正确":                "Invalid publication status",
+	"评论状态不正确":                "Invalid comment status",
+	"回复的评论不存在":               "The comment being replied to does not exist",
+	"简介不能超过 500 个字符":         "Bio must not exceed 500 characters",
+	"头像地址应为 http 或 https 链接": "Avatar URL must be an http or https link",
+	"头像地址不能超过 500 个字符":       "Avatar URL must not exceed 500 characters",
+	"个人网站应为 http 或 https 链接": "Website must be an http or https link",
+	"个人网站不能超过 255 个字符":       "Website must not exceed 255 characters",
+	"所在地不能超过 100 个字符":        "Location must not exceed 100 characters",
+	"%s 已被使用":                "%s is already taken",
+
+	// 按接口定义校验请求
+	"缺少参数":                "Missing parameter",
+	"缺少请求体":               "Missing request body",
+	"请求体不是合法的 JSON":       "Request body is not valid JSON",
+	"不能为 null":            "Must not be null",
+	"应为对象":                "Must be an object",
+	"缺少该字段":               "Missing field",
+	"未定义的字段":              "Undefined field",
+	"应为数组":                "Must be an array",
+	"至少 %d 项":             "Must have at least %d items",
+	"最多 %d 项":             "Must have at most %d items",
+	"应为字符串":               "Must be a string",
+	"至少 %d 个字符":           "Must be at least %d characters",
+	"不能超过 %d 个字符":         "Must not exceed %d characters",
+	"应为 RFC 3339 格式的时间":   "Must be an RFC 3339 timestamp",
+	"应为 YYYY-MM-DD 格式的日期": "Must be a YYYY-MM-DD date",
+	"应为整数":                "Must be an integer",
+	"应为数字":                "Must be a number",
+	"不能小于 %v":             "Must not be less than %v",
+	"不能大于 %v":             "Must not be greater than %v",
+	"应为布尔值":               "Must be a boolean",
+	"应为 %v 之一":            "Must be one of %v",
+
+	// filter、sort 参数 (filterdsl)
+	"过滤参数不合法":                   "Invalid filter parameter",
+	"过滤参数不合法: %s":               "Invalid filter parameter: %s",
+	"过滤参数不合法: 不支持按 %q 过滤":       "Invalid filter parameter: cannot filter by %q",
+	"过滤参数不合法: 不支持按 %q 排序":       "Invalid filter parameter: cannot sort by %q",
+	"过滤参数不合法: 最多 %d 个条件":        "Invalid filter parameter: at most %d conditions",
+	"过滤参数不合法: 最多按 %d 个字段排序":     "Invalid filter parameter: at most %d sort fields",
+	"过滤参数不合法: 条件 %q 缺少运算符":      "Invalid filter parameter: condition %q is missing an operator",
+	"过滤参数不合法: %s 不是文本字段, 不能用 ~": "Invalid filter parameter: %s is not a text field and cannot use ~",
+	"过滤参数不合法: %s 的值 %q 无效":      "Invalid filter parameter: invalid value %[2]q for %[1]s",
+
+	// fields、embed 参数 (fieldsel)
+	"字段参数不合法":                        "Invalid fields parameter",
+	"字段参数不合法: 最多选择 %d 个字段":           "Invalid fields parameter: at most %d fields",
+	"字段参数不合法: 最多内嵌 %d 个关联":           "Invalid fields parameter: at most %d embeds",
+	"字段参数不合法: 不支持字段 %q":              "Invalid fields parameter: unsupported field %q",
+	"字段参数不合法: 不支持内嵌 %q":              "Invalid fields parameter: unsupported embed %q",
+	"字段参数不合法: %q 缺少右括号":              "Invalid fields parameter: %q is missing a closing parenthesis",
+	"字段参数不合法: %q 的括号不匹配":             "Invalid fields parameter: unbalanced parentheses in %q",
+	"字段参数不合法: 内嵌 %s 不支持参数 %q":        "Invalid fields parameter: embed %s does not support argument %q",
+	"字段参数不合法: 内嵌 %s 的 limit 应为 1~%d": "Invalid fields parameter: limit of embed %s must be 1~%d",
+}
@@ -0,0 +1,217 @@
+// Package i18n 面向用户的文字 (错误信息、枚举的展示文字) 的多语言支持.
+//
+// 源语言为简体中文: 消息 ID 就是中文原文, 代码中照常书写中文, 展示前按请求的语言查目录翻译,
+// 目录中没有的消息原样返回. 含 %d、%s 等的消息以格式串为 ID, 翻译时按格式串匹配已经格式化的文字,
+// 再把匹配到的参数代入译文, 因此各层返回的、已经格式化好的错误信息也能翻译.
+//
+// 存储为代码的枚举值以 "类型.代码" 为 ID (如 comment_status.none), 每种语言的目录都要给出展示文字.
+//
+// HTTP 接口经 Middleware 按 Accept-Language 确定语言, JSON 错误响应在输出前统一翻译, 处理函数只需照常写中文.
+package i18n
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Locale 语言, 取 BCP 47 标签
+type Locale string
+
+const (
+	ZhCN Locale = "zh-CN"
+	EnUS Locale = "en-US"
+)
+
+// Source 源语言, 消息 ID 使用的语言; 目录中没有的消息按它展示
+const Source = ZhCN
+
+// catalogs 各语言的目录: 消息 ID -> 译文
+var catalogs = map[Locale]map[string]string{
+	ZhCN: zhCN,
+	EnUS: enUS,
+}
+
+// pattern 含格式化动词的消息, 用于翻译已经格式化的文字
+type pattern struct {
+	re     *regexp.Regexp
+	format string // 译文, 动词统一替换为 %s (保留参数序号)
+}
+
+// patterns 各语言目录中含动词的消息, 格式串长的在前, 避免短的格式串先匹配到
+var patterns = compilePatterns()
+
+// 格式化动词, 如 %d、%q、%.2f; 译文中的参数顺序与原文不同时用 %[n]s 指定
+var verbRe = regexp.MustCompile(`%(\[\d+\])?[-+# 0-9.]*[a-zA-Z]`)
+
+func compilePatterns() map[Locale][]pattern {
+	all := make(map[Locale][]pattern, len(catalogs))
+	for loc, catalog := range catalogs {
+		ids := make([]string, 0, len(catalog))
+		for id := range catalog {
+			if verbRe.MatchString(strings.ReplaceAll(id, "%%", "")) {
+				ids = append(ids, id)
+			}
+		}
+		slices.SortFunc(ids, func(a, b string) int { return len(b) - len(a) })
+		for _, id := range ids {
+			literals := verbRe.Split(id, -1)
+			for i, lit := range literals {
+				literals[i] = regexp.QuoteMeta(strings.ReplaceAll(lit, "%%", "%"))
+			}
+			all[loc] = append(all[loc], pattern{
+				re:     regexp.MustCompile("^" + strings.Join(literals, "(.+?)") + "$"),
+				format: verbRe.ReplaceAllString(catalog[id], "%${1}s"),
+			})
+		}
+	}
+	return all
+}
+
+// Translate 把消息 msg 翻译为 loc 的文字: 先按 ID 查找, 再按格式串匹配, 然后在源语言的目录中查找 (枚举的展示文字),
+// 都没有时返回 msg
+func Translate(loc Locale, msg string) string {
+	if s, ok := catalogs[loc][msg]; ok {
+		return s
+	}
+	for _, p := range patterns[loc] {
+		if m := p.re.FindStringSubmatch(msg); m != nil {
+			args := make([]any, len(m)-1)
+			for i := range args {
+				args[i] = m[i+1]
+			}
+			return fmt.Sprintf(p.format, args...)
+		}
+	}
+	if s, ok := catalogs[Source][msg]; ok {
+		return s
+	}
+	return msg
+}
+
+// T 把消息翻译为 ctx 的语言 (见 WithLocale)
+func T(ctx context.Context, msg string) string {
+	return Translate(FromContext(ctx), msg)
+}
+
+// Parse 把语言标签 (如 en、en-GB、zh-Hans-CN, 不区分大小写) 映射到支持的语言, 不支持时返回 false
+func Parse(tag string) (Locale, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case tag == "zh" || strings.HasPrefix(tag, "zh-"):
+		return ZhCN, true
+	case tag == "en" || strings.HasPrefix(tag, "en-"):
+		return EnUS, true
+	}
+	return "", false
+}
+
+// Negotiate 按 Accept-Language 的权重 (q) 选出支持的语言, 都不支持或没有该请求头时返回 fallback
+func Negotiate(acceptLanguage string, fallback Locale) Locale {
+	best, bestQ := fallback, 0.0
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(item, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if loc, ok := Parse(tag); ok && q > bestQ {
+			best, bestQ = loc, q
+		}
+	}
+	return best
+}
+
+type ctxKey struct{}
+
+// WithLocale 返回带有语言 loc 的 ctx
+func WithLocale(ctx context.Context, loc Locale) context.Context {
+	return context.WithValue(ctx, ctxKey{}, loc)
+}
+
+// FromContext 返回 ctx 的语言, 没有时为源语言
+func FromContext(ctx context.Context) Locale {
+	if loc, ok := ctx.Value(ctxKey{}).(Locale); ok {
+		return loc
+	}
+	return Source
+}
+
+// Middleware 按 Accept-Language 确定请求的语言 (不支持时为 fallback) 放入 ctx, 并在响应头中给出 Content-Language.
+// JSON 错误响应 (状态码 >= 400) 先缓存, 处理完成后翻译其中的 error 和 fields 再输出; 其他响应直接输出
+func Middleware(fallback Locale) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			loc := Negotiate(r.Header.Get("Accept-Language"), fallback)
+			w.Header().Set("Content-Language", string(loc))
+			w.Header().Add("Vary", "Accept-Language")
+			tw := &translator{ResponseWriter: w, locale: loc}
+			next.ServeHTTP(tw, r.WithContext(WithLocale(r.Context(), loc)))
+			tw.flush()
+		})
+	}
+}
+
+// translator 缓存需要翻译的错误响应
+type translator struct {
+	http.ResponseWriter
+	locale Locale
+	status int // 缓存的错误响应的状态码, 0 表示没有缓存
+	body   bytes.Buffer
+}
+
+func (t *translator) WriteHeader(status int) {
+	// 源语言的错误信息无需翻译
+	if t.status == 0 && status >= http.StatusBadRequest && t.locale != Source &&
+		strings.HasPrefix(t.Header().Get("Content-Type"), "application/json") {
+		t.status = status
+		return
+	}
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *translator) Write(b []byte) (int, error) {
+	if t.status != 0 {
+		return t.body.Write(b)
+	}
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap 供 http.ResponseController 取得底层的 ResponseWriter
+func (t *translator) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// flush 翻译并输出缓存的错误响应, 响应体不是 JSON 对象时原样输出
+func (t *translator) flush() {
+	if t.status == 0 {
+		return
+	}
+	body := t.body.Bytes()
+	var v map[string]any
+	if err := json.Unmarshal(body, &v); err == nil {
+		if msg, ok := v["error"].(string); ok {
+			v["error"] = Translate(t.locale, msg)
+		}
+		if fields, ok := v["fields"].(map[string]any); ok {
+			for name, msg := range fields {
+				if msg, ok := msg.(string); ok {
+					fields[name] = Translate(t.locale, msg)
+				}
+			}
+		}
+		if data, err := json.Marshal(v); err == nil {
+			body = append(data, '\n')
+		}
+	}
+	t.ResponseWriter.WriteHeader(t.status)
+	t.ResponseWriter.Write(body)
+}
@@ -0,0 +1,7 @@
+package i18n
+
+// zhCN 简体中文目录. 消息 ID 本身就是中文, 这里只需给出枚举的展示文字
+var zhCN = map[string]string{
+	"comment_status.none":         "无评论",
+	"comment_status.has_comments": "有评论",
+}
@@ -0,0 +1,57 @@
+// Package idcodec 在 API 边界把自增主键编码为不可猜测的短字符串 (hashids),
+// 响应中只出现编码后的 ID, 处理请求时再解码, 避免暴露数据量和遍历接口.
+//
+// 编码不是加密: 知道 salt 即可还原, salt 应和其他密钥一样保管.
+package idcodec
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/speps/go-hashids/v2"
+)
+
+// ErrInvalidID 无法解码的 ID
+var ErrInvalidID = errors.New("无效的 ID")
+
+// Codec ID 编解码器, 可并发使用
+type Codec struct {
+	h *hashids.HashID
+}
+
+// New 创建编解码器, minLength 为编码结果的最小长度
+func New(salt string, minLength int) (*Codec, error) {
+	data := hashids.NewData()
+	data.Salt = salt
+	data.MinLength = minLength
+
+	h, err := hashids.NewWithData(data)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 ID 编码失败: %w", err)
+	}
+	return &Codec{h: h}, nil
+}
+
+// Encode 编码主键
+func (c *Codec) Encode(id uint) string {
+	s, err := c.h.EncodeInt64([]int64{int64(id)})
+	if err != nil {
+		// 只有负数会编码失败, uint 不会出现
+		panic(err)
+	}
+	return s
+}
+
+// Decode 解码主键. 除了格式校验, 还会把结果重新编码比对,
+// 拒绝能解码但不是由本编解码器生成的字符串
+func (c *Codec) Decode(s string) (uint, error) {
+	nums, err := c.h.DecodeInt64WithError(s)
+	if err != nil || len(nums) != 1 || nums[0] < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidID, s)
+	}
+	id := uint(nums[0])
+	if c.Encode(id) != s {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidID, s)
+	}
+	return id, nil
+}
@@ -0,0 +1,90 @@
+package idcodec
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func newCodec(t *testing.T, salt string, minLength int) *Codec {
+	t.Helper()
+	c, err := New(salt, minLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRoundTrip(t *testing.T) {
+	c := newCodec(t, "test-salt", 8)
+
+	for _, id := range []uint{0, 1, 2, 42, 1000, 1 << 32, math.MaxInt64} {
+		s := c.Encode(id)
+		if len(s) < 8 {
+			t.Errorf("Encode(%d) = %q, 短于最小长度 8", id, s)
+		}
+		got, err := c.Decode(s)
+		if err != nil {
+			t.Errorf("Decode(Encode(%d)) 出错: %v", id, err)
+			continue
+		}
+		if got != id {
+			t.Errorf("Decode(Encode(%d)) = %d", id, got)
+		}
+	}
+}
+
+func TestEncodeDependsOnSalt(t *testing.T) {
+	a := newCodec(t, "salt-a", 8)
+	b := newCodec(t, "salt-b", 8)
+
+	s := a.Encode(42)
+	if s == b.Encode(42) {
+		t.Fatalf("不同 salt 的编码结果相同: %q", s)
+	}
+	if id, err := b.Decode(s); err == nil && id == 42 {
+		t.Errorf("其他 salt 的编码被解码为 %d", id)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	c := newCodec(t, "test-salt", 8)
+	other := newCodec(t, "test-salt", 0)
+	h := c.h
+
+	pair, err := h.EncodeInt64([]int64{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"空字符串", ""},
+		{"不在字母表中的字符", "!!!!!!!!"},
+		{"纯数字主键", "42"},
+		{"编码了多个数字", pair},
+		{"最小长度不同的编码", other.Encode(42)},
+		{"篡改一个字符", tamper(c.Encode(42))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := c.Decode(tt.in)
+			if !errors.Is(err, ErrInvalidID) {
+				t.Errorf("Decode(%q) = %d, %v, 期望 ErrInvalidID", tt.in, id, err)
+			}
+		})
+	}
+}
+
+// tamper 替换 s 的最后一个字符
+func tamper(s string) string {
+	b := []byte(s)
+	if b[len(b)-1] == 'a' {
+		b[len(b)-1] = 'b'
+	} else {
+		b[len(b)-1] = 'a'
+	}
+	return string(b)
+}
@@ -0,0 +1,235 @@
+// Package idempotency 写接口的幂等键. 客户端在 POST 请求中带上 Idempotency-Key 头, 因网络不稳定重试时
+// 服务端返回第一次请求的结果, 不会重复创建记录.
+//
+// 键按 (租户, 用户, 键) 唯一. 第一次请求时写入 idempotency_keys, 处理完成后保存响应的状态码和响应体; 同一键的重试:
+//   - 第一次请求已完成: 原样返回保存的响应, 并带上 Idempotent-Replayed: true
+//   - 第一次请求仍在处理: 返回 409, 客户端稍后重试
+//   - 方法、路径或请求体与第一次不同: 返回 422, 同一个键不能用于不同的请求
+//
+// 5xx 和 429 响应不保存, 键随即释放, 客户端可以用同一个键重试. 键在 TTL 后过期, 由 Prune 清理.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/requestid"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// Header 请求头名
+const Header = "Idempotency-Key"
+
+// ReplayedHeader 重放保存的响应时带上的响应头
+const ReplayedHeader = "Idempotent-Replayed"
+
+// 键的最大长度, 通常是客户端生成的 UUID
+const maxKeyLen = 255
+
+// MySQL 唯一键冲突错误码
+const errDuplicateEntry = 1062
+
+// Record 一个幂等键及其第一次请求的响应
+type Record struct {
+	ID          uint      `gorm:"primaryKey"`
+	TenantID    uint      `gorm:"not null;default:1;uniqueIndex:idx_idempotency_keys_owner,priority:1"` // 所属租户
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_idempotency_keys_owner,priority:2"`           // 发起请求的用户, 不同用户的键互不影响
+	Key         string    `gorm:"column:idempotency_key;size:255;not null;uniqueIndex:idx_idempotency_keys_owner,priority:3"`
+	Fingerprint string    `gorm:"size:64;not null"`   // 方法、路径和请求体的 SHA-256
+	Status      int       `gorm:"not null;default:0"` // 响应的状态码, 0 表示第一次请求仍在处理
+	ContentType string    `gorm:"size:100;not null;default:''"`
+	Body        []byte    `gorm:"type:mediumblob"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TableName 指定表名
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
+
+// Store 在数据库中保存幂等键
+type Store struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewStore 创建 Store, 键保留 ttl
+func NewStore(db *gorm.DB, ttl time.Duration) *Store {
+	return &Store{db: db, ttl: ttl}
+}
+
+// Middleware 为带 Idempotency-Key 的请求去重, 没有该请求头的请求直接放行. owner 返回发起请求的用户,
+// 中间件须放在认证之后. 请求体读入内存计算指纹, 超过 maxBody 字节时返回 413
+func (s *Store) Middleware(owner func(*http.Request) uint, maxBody int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLen {
+				writeError(w, http.StatusBadRequest, "Idempotency-Key 不能超过 255 个字符")
+				return
+			}
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("请求体超过 %d 字节", maxBody))
+				return
+			}
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "读取请求体失败")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			rec, existing, err := s.begin(ctx, owner(r), key, fingerprint(r, body))
+			if err != nil {
+				s.internalError(w, r, err)
+				return
+			}
+			if existing != nil {
+				replay(w, existing, rec.Fingerprint)
+				return
+			}
+
+			rw := &recorder{ResponseWriter: w, status: http.StatusOK}
+			// 处理函数 panic 时释放键, 否则重试在键过期前一直得到 409
+			completed := false
+			defer func() {
+				if !completed {
+					s.db.WithContext(context.WithoutCancel(ctx)).Delete(rec)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+			completed = true
+			// 第一次请求的结果已经写给客户端, 保存失败只影响之后的重试, 记录日志即可
+			if err := s.finish(context.WithoutCancel(ctx), rec, rw); err != nil {
+				log.Printf("[%s] 保存幂等键 %q 的响应失败: %v", requestid.From(ctx), key, err)
+			}
+		})
+	}
+}
+
+// begin 登记键. 键已存在且未过期时返回已有的记录, 否则返回新登记的记录
+func (s *Store) begin(ctx context.Context, userID uint, key, fp string) (*Record, *Record, error) {
+	db := s.db.WithContext(ctx)
+	rec := &Record{UserID: userID, Key: key, Fingerprint: fp}
+	// 过期的键删除后重新登记; 并发的请求可能抢先删除或登记, 最多重试一次
+	for range 2 {
+		rec.ID, rec.ExpiresAt = 0, time.Now().Add(s.ttl)
+		err := db.Create(rec).Error
+		if err == nil {
+			return rec, nil, nil
+		}
+		if !isDuplicate(err) {
+			return nil, nil, err
+		}
+		var existing Record
+		err = db.Where("user_id = ? AND idempotency_key = ?", userID, key).Take(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if existing.ExpiresAt.After(time.Now()) {
+			return rec, &existing, nil
+		}
+		if err := db.Where("id = ? AND expires_at <= ?", existing.ID, time.Now()).Delete(&Record{}).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, errors.New("登记幂等键失败: 并发冲突")
+}
+
+// finish 保存第一次请求的响应. 5xx 和 429 是暂时性的失败, 删除键让客户端用同一个键重试
+func (s *Store) finish(ctx context.Context, rec *Record, rw *recorder) error {
+	db := s.db.WithContext(ctx)
+	if rw.status >= http.StatusInternalServerError || rw.status == http.StatusTooManyRequests {
+		return db.Delete(rec).Error
+	}
+	return db.Model(rec).Updates(map[string]any{
+		"status":       rw.status,
+		"content_type": rw.Header().Get("Content-Type"),
+		"body":         rw.body.Bytes(),
+	}).Error
+}
+
+// replay 对重试的请求返回第一次请求的响应, 或说明冲突的原因
+func replay(w http.ResponseWriter, existing *Record, fp string) {
+	switch {
+	case existing.Fingerprint != fp:
+		writeError(w, http.StatusUnprocessableEntity, "Idempotency-Key 已用于另一个请求")
+	case existing.Status == 0:
+		writeError(w, http.StatusConflict, "相同 Idempotency-Key 的请求正在处理")
+	default:
+		if existing.ContentType != "" {
+			w.Header().Set("Content-Type", existing.ContentType)
+		}
+		w.Header().Set(ReplayedHeader, "true")
+		w.WriteHeader(existing.Status)
+		w.Write(existing.Body)
+	}
+}
+
+// Prune 删除过期的键, 返回删除的数量
+func Prune(ctx context.Context, db *gorm.DB) (int64, error) {
+	result := db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&Record{})
+	return result.RowsAffected, result.Error
+}
+
+// fingerprint 请求的方法、路径和请求体的 SHA-256
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func isDuplicate(err error) bool {
+	var myErr *mysqldriver.MySQLError
+	return errors.As(err, &myErr) && myErr.Number == errDuplicateEntry
+}
+
+func (s *Store) internalError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("[%s] 幂等键处理失败: %v", requestid.From(r.Context()), err)
+	writeError(w, http.StatusInternalServerError, "服务器内部错误")
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// recorder 在写给客户端的同时记录状态码和响应体
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
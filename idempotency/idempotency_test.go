@@ -0,0 +1,64 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 超过上限的请求体在登记键之前被拒绝, 不会整个读入内存
+func TestMiddlewareLimitsBody(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&Record{}); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(db, time.Hour)
+
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		{"不超过上限", 16, http.StatusCreated},
+		{"超过上限", 17, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			h := store.Middleware(func(*http.Request) uint { return 1 }, 16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusCreated)
+			}))
+			r := httptest.NewRequest("POST", "/posts", strings.NewReader(strings.Repeat("a", tt.size)))
+			r.Header.Set(Header, tt.name)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("状态码 = %d, 期望 %d", w.Code, tt.want)
+			}
+			if called != (tt.want == http.StatusCreated) {
+				t.Errorf("处理函数是否执行 = %v", called)
+			}
+			var n int64
+			db.Model(&Record{}).Where("idempotency_key = ?", tt.name).Count(&n)
+			if (n == 1) != called {
+				t.Errorf("登记的键 %d 个", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/idempotency"
+	"github.com/alexwang789/Base1_golang_task3/querytimeout"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	"github.com/alexwang789/Base1_golang_task3/tenantdb"
+	"gorm.io/gorm"
+)
+
+// 博客模块的任务名, 也用于 config.LoadJob 的环境变量
+const (
+	CounterReconcile  = "counter_reconcile"  // 修正文章数、评论状态等冗余字段
+	PostStatsRebuild  = "post_stats_rebuild" // 从评论和点赞重建文章统计表
+	SlowQueryReport   = "slow_query_report"  // 输出本进程的热点和慢查询报告
+	PostViewPrune     = "post_view_prune"    // 清理超出浏览统计窗口的浏览桶
+	TrendingScores    = "trending_scores"    // 重算首页的文章热度
+	PublishScheduled  = "publish_scheduled"  // 发布到期的定时文章
+	VerificationPrune = "verification_prune" // 清理过期的邮箱验证令牌
+	IdempotencyPrune  = "idempotency_prune"  // 清理过期的幂等键
+	PartitionMaintain = "partition_maintain" // 为按月分区的表新建分区并删除过期分区
+	RetentionPurge    = "retention_purge"    // 按保留期删除软删除的记录、审计日志和通知
+	UserErasure       = "user_erasure"       // 执行宽限期已过的用户注销请求
+	StaleDraftPurge   = "stale_draft_purge"  // 删除早已过了计划时间仍未发布的定时文章
+)
+
+// 慢查询报告包含的语句数
+const slowQueryReportSize = 20
+
+// RegisterBlogJobs 注册博客模块的任务, 通过 JOB_<NAME>_ENABLED 关闭的任务不注册.
+// 任务处理全部租户 (见 tenant.All), 在默认库和每个独立库上各执行一次, 其中的语句按报表查询限时 (见 querytimeout.Report). 慢查询报告写入 SLOW_QUERY_REPORT_DIR 下按时间命名的文件, 未设置时写入日志
+func RegisterBlogJobs(s *Scheduler, db *gorm.DB) error {
+	for _, j := range []struct {
+		name     string
+		schedule string
+		run      func(ctx context.Context) error
+	}{
+		{CounterReconcile, "30 * * * *", func(ctx context.Context) error {
+			fixed, err := blog.ReconcileCounters(ctx, db)
+			for _, m := range fixed {
+				log.Printf("已修正 %s", m)
+			}
+			return err
+		}},
+		{PostStatsRebuild, "@hourly", func(ctx context.Context) error {
+			return blog.RebuildPostStats(ctx, db)
+		}},
+		{SlowQueryReport, "@daily", func(ctx context.Context) error {
+			return writeSlowQueryReport(os.Getenv("SLOW_QUERY_REPORT_DIR"))
+		}},
+		{PostViewPrune, "15 * * * *", func(ctx context.Context) error {
+			_, err := blog.PrunePostViews(ctx, db)
+			return err
+		}},
+		{TrendingScores, "*/10 * * * *", func(ctx context.Context) error {
+			return blog.RefreshTrendingScores(ctx, db)
+		}},
+		{PublishScheduled, "* * * * *", func(ctx context.Context) error {
+			n, err := blog.PublishDuePosts(ctx, db)
+			if n > 0 {
+				log.Printf("已发布 %d 篇定时文章", n)
+			}
+			return err
+		}},
+		{VerificationPrune, "20 * * * *", func(ctx context.Context) error {
+			n, err := blog.PruneEmailVerifications(ctx, db)
+			if n > 0 {
+				log.Printf("已清理 %d 个过期的邮箱验证令牌", n)
+			}
+			return err
+		}},
+		{IdempotencyPrune, "40 * * * *", func(ctx context.Context) error {
+			n, err := idempotency.Prune(ctx, db)
+			if n > 0 {
+				log.Printf("已清理 %d 个过期的幂等键", n)
+			}
+			return err
+		}},
+		{PartitionMaintain, "10 3 * * *", func(ctx context.Context) error {
+			return blog.MaintainPartitions(ctx, db, config.LoadPartitioning())
+		}},
+		{UserErasure, "25 * * * *", func(ctx context.Context) error {
+			n, err := blog.EraseDueUsers(ctx, db)
+			if n > 0 {
+				log.Printf("已注销 %d 个用户", n)
+			}
+			return err
+		}},
+		{StaleDraftPurge, "50 3 * * *", func(ctx context.Context) error {
+			n, err := blog.PurgeStaleScheduledPosts(ctx, db, config.LoadRetention().StaleDrafts)
+			if n > 0 {
+				log.Printf("已删除 %d 篇长期未发布的定时文章", n)
+			}
+			return err
+		}},
+		{RetentionPurge, "40 3 * * *", func(ctx context.Context) error {
+			results, err := blog.PurgeExpired(ctx, db, config.LoadRetention(), false)
+			for _, r := range results {
+				log.Printf("已删除 %s", r)
+			}
+			return err
+		}},
+	} {
+		cfg := config.LoadJob(j.name, j.schedule)
+		if !cfg.Enabled {
+			log.Printf("任务 %s 已关闭", j.name)
+			continue
+		}
+		run := j.run
+		err := s.Add(j.name, cfg.Schedule, func(ctx context.Context) error {
+			return tenantdb.Of(db).ForEach(querytimeout.Report(tenant.All(ctx)), run)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSlowQueryReport 输出自上次报告以来的语句统计, 并清空统计开始下一个周期
+func writeSlowQueryReport(dir string) error {
+	report := blog.QueryStats.BuildReport(slowQueryReportSize)
+
+	var w io.Writer = log.Writer()
+	if dir != "" {
+		name := filepath.Join(dir, "slow-queries-"+time.Now().Format("20060102-150405")+".txt")
+		f, err := os.Create(name)
+		if err != nil {
+			return fmt.Errorf("创建慢查询报告失败: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := report.WriteText(w); err != nil {
+		return fmt.Errorf("写入慢查询报告失败: %w", err)
+	}
+	blog.QueryStats.Reset()
+	return nil
+}
@@ -0,0 +1,161 @@
+// Package jobs 按 cron 表达式定期执行的后台任务.
+//
+// 同一任务不会重叠执行: 上一次还没结束时本次跳过; 配置了 Locker 时还会在执行前获取以任务名命名的
+// 分布式锁, 多个进程 (如多个 blog serve 实例) 中同一时刻只有一个在执行.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrLocked 锁已被其他进程持有
+var ErrLocked = errors.New("jobs: 任务正在其他进程中执行")
+
+// Locker 跨进程的互斥锁
+type Locker interface {
+	// TryLock 立即尝试获取名为 name 的锁, 被占用时返回 ErrLocked; 成功时返回释放函数
+	TryLock(ctx context.Context, name string) (unlock func(), err error)
+}
+
+// Job 一个定期任务
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Run      func(ctx context.Context) error
+
+	running atomic.Bool
+}
+
+// Scheduler 任务调度器
+type Scheduler struct {
+	locker Locker
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// New 创建调度器, locker 为 nil 时只在本进程内防止重叠
+func New(locker Locker) *Scheduler {
+	return &Scheduler{locker: locker, jobs: map[string]*Job{}}
+}
+
+// Add 注册任务, spec 的格式见 Parse
+func (s *Scheduler) Add(name, spec string, run func(ctx context.Context) error) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("任务 %s: %w", name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("任务 %s 已注册", name)
+	}
+	s.jobs[name] = &Job{Name: name, Schedule: schedule, Run: run}
+	return nil
+}
+
+// Names 按名称排序返回已注册的任务
+func (s *Scheduler) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunOnce 立即执行一次名为 name 的任务, 同样受重叠保护
+func (s *Scheduler) RunOnce(ctx context.Context, name string) error {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("未知的任务 %s", name)
+	}
+	return s.execute(ctx, job)
+}
+
+// Run 按各自的调度执行全部任务, 阻塞直到 ctx 取消, 返回前等待执行中的任务结束
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job *Job) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("任务 %s 没有下一次执行时间, 停止调度", job.Name)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		// 执行放在单独的 goroutine 中, 耗时超过间隔时下一次按时触发并因重叠被跳过
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.execute(ctx, job); err != nil && ctx.Err() == nil {
+				log.Printf("任务 %s: %v", job.Name, err)
+			}
+		}()
+	}
+}
+
+// execute 执行一次任务, 上一次仍在执行或锁被占用时跳过
+func (s *Scheduler) execute(ctx context.Context, job *Job) error {
+	if !job.running.CompareAndSwap(false, true) {
+		log.Printf("任务 %s 上一次尚未结束, 跳过", job.Name)
+		return nil
+	}
+	defer job.running.Store(false)
+
+	if s.locker != nil {
+		unlock, err := s.locker.TryLock(ctx, "job:"+job.Name)
+		if errors.Is(err, ErrLocked) {
+			log.Printf("任务 %s 正在其他进程中执行, 跳过", job.Name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	start := time.Now()
+	err := job.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("执行失败 (耗时 %s): %w", time.Since(start).Round(time.Millisecond), err)
+	}
+	log.Printf("任务 %s 完成, 耗时 %s", job.Name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
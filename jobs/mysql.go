@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// MySQLLocker 基于 MySQL GET_LOCK 的 Locker. 命名锁属于连接, 持有期间独占一个连接,
+// 进程异常退出时随连接断开自动释放
+type MySQLLocker struct {
+	db *sql.DB
+}
+
+// NewMySQLLocker 创建 MySQLLocker
+func NewMySQLLocker(db *sql.DB) *MySQLLocker {
+	return &MySQLLocker{db: db}
+}
+
+// TryLock 实现 Locker
+func (l *MySQLLocker) TryLock(ctx context.Context, name string) (func(), error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&got); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("获取锁 %s 失败: %w", name, err)
+	}
+	if got.Int64 != 1 {
+		conn.Close()
+		return nil, ErrLocked
+	}
+	return func() {
+		// 任务的 ctx 可能已取消, 释放锁不受其影响
+		if _, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name); err != nil {
+			log.Printf("释放锁 %s 失败: %v", name, err)
+		}
+		conn.Close()
+	}, nil
+}
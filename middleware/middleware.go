@@ -0,0 +1,144 @@
+// Package middleware API 服务的 HTTP 中间件: 请求 ID、panic 恢复、访问日志、CORS 和租户.
+//
+// 中间件的类型都是 Middleware, 用 Chain 按顺序组合, 排在前面的在外层.
+package middleware
+
+import (
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/requestid"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+)
+
+// Middleware 包装一个 handler
+type Middleware func(http.Handler) http.Handler
+
+// Chain 用 mws 依次包装 h, mws[0] 在最外层, 最先处理请求
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// RequestID 沿用请求头 X-Request-ID 中合法的 ID, 没有时生成新的 UUID.
+// ID 写入响应头并放入请求的 ctx, 之后的日志和 SQL 日志都能取到 (见 requestid.From)
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.With(r.Context(), id)))
+	})
+}
+
+// Tenant 用 resolve 确定请求所属的租户并放入请求的 ctx (见 tenant.With), 之后的查询只能看到该租户的数据.
+// 无法确定租户时返回 404
+func Tenant(resolve func(r *http.Request) (uint, bool)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := resolve(r)
+			if !ok {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":"站点不存在"}` + "\n"))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(tenant.With(r.Context(), id)))
+		})
+	}
+}
+
+// Recover 捕获处理请求时的 panic, 连同调用栈和请求 ID 记录日志并返回 500.
+// http.ErrAbortHandler 是有意中断响应, 原样抛出交给 net/http 处理
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := wrap(w)
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(v)
+			}
+			log.Printf("[%s] 处理 %s %s 时 panic: %v\n%s", requestid.From(r.Context()), r.Method, r.URL.Path, v, debug.Stack())
+			// 已经开始写响应时状态码无法再修改, 只能中断连接
+			if sw.wroteHeader {
+				panic(http.ErrAbortHandler)
+			}
+			sw.Header().Set("Content-Type", "application/json; charset=utf-8")
+			sw.WriteHeader(http.StatusInternalServerError)
+			sw.Write([]byte(`{"error":"服务器内部错误"}` + "\n"))
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// AccessLog 每个请求结束后以结构化日志记录方法、路径、状态码、响应字节数和耗时
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := wrap(w)
+			next.ServeHTTP(sw, r)
+
+			level := slog.LevelInfo
+			if sw.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(r.Context(), level, "http_request",
+				slog.String("request_id", requestid.From(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", sw.status),
+				slog.Int64("bytes", sw.bytes),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			)
+		})
+	}
+}
+
+// statusWriter 记录写出的状态码和字节数
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// wrap 外层已经包装过时直接复用, 各中间件看到的是同一份状态
+func wrap(w http.ResponseWriter) *statusWriter {
+	if sw, ok := w.(*statusWriter); ok {
+		return sw
+	}
+	return &statusWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap 供 http.ResponseController 取得底层的 ResponseWriter
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/ratelimit"
+	"github.com/alexwang789/Base1_golang_task3/requestid"
+)
+
+// RateLimit 按 key(r) 限流, 超出频率时返回 429 和 Retry-After.
+// 限流存储不可用 (如 Redis 故障) 时放行并记录日志, 不因限流本身的故障拒绝服务
+func RateLimit(l ratelimit.Limiter, key func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := l.Allow(r.Context(), key(r))
+			if err != nil && !errors.Is(err, ratelimit.ErrRateLimited) {
+				log.Printf("[%s] 限流失败, 已放行: %v", requestid.From(r.Context()), err)
+				err = nil
+			}
+			if err != nil {
+				WriteRateLimited(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WriteRateLimited 返回 429, Retry-After 为向上取整的等待秒数
+func WriteRateLimited(w http.ResponseWriter, err error) {
+	wait, _ := ratelimit.RetryAfter(err)
+	w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// ClientIP 返回客户端 IP. trustProxy 为 true 时 (服务在反向代理之后) 取 X-Forwarded-For 的最后一个地址,
+// 即代理看到的来源; 更前面的地址由客户端自己填写, 不可信
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+			parts := strings.Split(xff[len(xff)-1], ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,128 @@
+// Package nplusone 开发环境的 N+1 查询检测: 同一请求中同一形状 (见 querystats.Fingerprint) 的单行查询
+// 反复执行, 通常是在循环中逐条加载关联数据, 应改为 Preload 或一次 IN 查询.
+//
+// 检测按 ctx 进行, 只有经 Track (HTTP 服务用 Middleware) 标记的 ctx 才会统计. 某个形状的次数达到阈值时
+// 记录一条带调用栈的警告, 每个请求每个形状只告警一次. 告警的请求中该形状的执行次数按形状累加,
+// 通过 expvar 以 "nplusone" 发布, 可从 /debug/vars 采集.
+package nplusone
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/alexwang789/Base1_golang_task3/querystats"
+	"github.com/alexwang789/Base1_golang_task3/requestid"
+	"gorm.io/gorm"
+)
+
+// 调用栈最多记录的帧数
+const maxFrames = 10
+
+// Counts 各形状在告警的请求中的执行次数, 已发布到 expvar
+var Counts = expvar.NewMap("nplusone")
+
+type key struct{}
+
+// tracker 一个请求中各形状的单行查询次数
+type tracker struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// Track 返回开始统计 N+1 查询的 ctx, 之后用它执行的查询按形状计数
+func Track(ctx context.Context) context.Context {
+	return context.WithValue(ctx, key{}, &tracker{counts: make(map[string]int)})
+}
+
+// Middleware 为每个请求调用 Track
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(Track(r.Context())))
+	})
+}
+
+// add 记录一次查询, 返回该形状在本请求中的次数
+func (t *tracker) add(fingerprint string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counts[fingerprint]++
+	return t.counts[fingerprint]
+}
+
+// Plugin 在查询后按形状计数, 达到阈值时告警
+type Plugin struct {
+	threshold int
+	logger    *slog.Logger
+}
+
+// NewPlugin 创建 GORM 插件, 通过 db.Use 注册. threshold 为告警阈值, logger 为 nil 时使用 slog.Default()
+func NewPlugin(threshold int, logger *slog.Logger) *Plugin {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Plugin{threshold: max(threshold, 2), logger: logger}
+}
+
+// Name 实现 gorm.Plugin
+func (p *Plugin) Name() string {
+	return "nplusone"
+}
+
+// Initialize 实现 gorm.Plugin
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Query().After("gorm:query").Register("nplusone:after_query", p.after)
+}
+
+func (p *Plugin) after(db *gorm.DB) {
+	ctx := db.Statement.Context
+	t, ok := ctx.Value(key{}).(*tracker)
+	// 多行的结果说明已经是批量查询, 预加载的 IN 查询也属于这种
+	if !ok || db.Error != nil || db.RowsAffected > 1 {
+		return
+	}
+	fingerprint := querystats.Fingerprint(db.Statement.SQL.String())
+	n := t.add(fingerprint)
+	if n < p.threshold {
+		return
+	}
+	if n == p.threshold {
+		Counts.Add(fingerprint, int64(n))
+		p.logger.WarnContext(ctx, "疑似 N+1 查询: 同一请求中同一形状的单行查询反复执行",
+			"request_id", requestid.From(ctx),
+			"count", n,
+			"sql", fingerprint,
+			"stack", callers(),
+		)
+		return
+	}
+	Counts.Add(fingerprint, 1)
+}
+
+// callers 返回触发查询的业务代码调用栈, 跳过 GORM 和本包的帧
+func callers() string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	count := 0
+	for {
+		f, more := frames.Next()
+		if !strings.Contains(f.File, "gorm.io/") && !strings.HasPrefix(f.Function, "runtime.") &&
+			!strings.Contains(f.Function, "/nplusone.") {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+			if count++; count == maxFrames {
+				break
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+// Package outbox 事务性发件箱: 领域事件与业务数据在同一事务中写入 outbox_events 表,
+// 由 Relay 异步投递到外部 (日志、消息队列等).
+//
+// 业务代码不直接调用消息队列, 因此不存在 "数据已提交但消息没发出" 或反过来的双写不一致;
+// Relay 至少投递一次 (at-least-once), 下游需按事件 ID 去重.
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Event 发件箱中的一条领域事件
+type Event struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement"`
+	Type        string     `gorm:"size:100;not null"`
+	Key         string     `gorm:"size:100;not null"` // 聚合标识, 如 post:42, 可用作消息分区键
+	Payload     string     `gorm:"type:json;not null"`
+	PublishedAt *time.Time `gorm:"index"` // 未投递时为 NULL
+	Attempts    int        `gorm:"not null;default:0"`
+	LastError   string     `gorm:"size:1000"`
+	CreatedAt   time.Time
+}
+
+// TableName 指定表名
+func (Event) TableName() string {
+	return "outbox_events"
+}
+
+// Add 把事件写入发件箱, payload 序列化为 JSON. 传入业务事务的 tx (或 GORM 钩子的 tx),
+// 使事件与业务数据一起提交或回滚
+func Add(tx *gorm.DB, eventType, key string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化事件 %s 失败: %w", eventType, err)
+	}
+	event := Event{Type: eventType, Key: key, Payload: string(data)}
+	if err := tx.Create(&event).Error; err != nil {
+		return fmt.Errorf("写入发件箱失败: %w", err)
+	}
+	return nil
+}
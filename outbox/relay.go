@@ -0,0 +1,169 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sink 事件的投递目标. Kafka、NATS 等实现此接口后传给 NewRelay
+type Sink interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// LogSink 只打印日志的投递目标, 用于开发环境
+type LogSink struct{}
+
+// Publish 实现 Sink
+func (LogSink) Publish(_ context.Context, event Event) error {
+	log.Printf("📣 事件 #%d %s key=%s %s", event.ID, event.Type, event.Key, event.Payload)
+	return nil
+}
+
+// MultiSink 依次投递到多个目标, 任一失败即返回错误 (整个事件稍后重新投递, 各目标需自行去重)
+type MultiSink []Sink
+
+// Publish 实现 Sink
+func (m MultiSink) Publish(ctx context.Context, event Event) error {
+	for _, sink := range m {
+		if err := sink.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Options relay 配置, 零值字段使用默认值
+type Options struct {
+	BatchSize    int           // 每次投递的事件数, 默认 100
+	PollInterval time.Duration // 没有待投递事件时的轮询间隔, 默认 1s
+	BaseBackoff  time.Duration // 投递失败后首次等待, 之后每次翻倍, 默认 1s
+	MaxBackoff   time.Duration // 失败等待上限, 默认 1m
+}
+
+func (o *Options) setDefaults() {
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = time.Second
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = time.Minute
+	}
+}
+
+// Relay 按写入顺序把未投递的事件发布到 Sink
+type Relay struct {
+	db   *gorm.DB
+	sink Sink
+	opts Options
+}
+
+// NewRelay 创建 relay
+func NewRelay(db *gorm.DB, sink Sink, opts Options) *Relay {
+	opts.setDefaults()
+	return &Relay{db: db, sink: sink, opts: opts}
+}
+
+// Run 持续投递直到 ctx 取消. 某个事件投递失败时整批停止并退避重试, 不会跳过它投递后面的事件,
+// 保证下游按写入顺序收到事件
+func (r *Relay) Run(ctx context.Context) error {
+	failures := 0
+	for {
+		n, err := r.publishBatch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		wait := r.opts.PollInterval
+		switch {
+		case err != nil:
+			log.Printf("投递事件失败: %v", err)
+			wait = r.backoff(failures)
+			failures++
+		case n == r.opts.BatchSize:
+			failures = 0
+			continue // 可能还有积压, 立即继续
+		default:
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// publishBatch 在事务中锁定一批未投递事件并逐个发布, 返回成功投递的数量和 Sink 的错误.
+// 锁保证多个 relay 实例不会同时投递同一批事件; 投递失败时已成功的部分照常提交
+func (r *Relay) publishBatch(ctx context.Context) (int, error) {
+	var (
+		published  int
+		publishErr error
+	)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var events []Event
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("published_at IS NULL").
+			Order("id").
+			Limit(r.opts.BatchSize).
+			Find(&events).Error
+		if err != nil {
+			return fmt.Errorf("查询待投递事件失败: %w", err)
+		}
+
+		ids := make([]uint64, 0, len(events))
+		for _, event := range events {
+			if publishErr = r.sink.Publish(ctx, event); publishErr != nil {
+				err := tx.Model(&event).Updates(map[string]any{
+					"attempts":   gorm.Expr("attempts + 1"),
+					"last_error": truncate(publishErr.Error(), 1000),
+				}).Error
+				if err != nil {
+					return fmt.Errorf("记录事件 %d 投递失败: %w", event.ID, err)
+				}
+				publishErr = fmt.Errorf("事件 %d: %w", event.ID, publishErr)
+				break
+			}
+			ids = append(ids, event.ID)
+		}
+
+		if len(ids) > 0 {
+			if err := tx.Model(&Event{}).Where("id IN ?", ids).Update("published_at", time.Now()).Error; err != nil {
+				return fmt.Errorf("标记事件已投递失败: %w", err)
+			}
+		}
+		published = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, publishErr
+}
+
+// backoff 连续第 failures 次失败后的等待时间: BaseBackoff * 2^failures, 不超过 MaxBackoff
+func (r *Relay) backoff(failures int) time.Duration {
+	d := r.opts.BaseBackoff
+	for i := 0; i < failures && d < r.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.opts.MaxBackoff)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return fmt.Sprintf("%.*s...", n-3, s)
+}
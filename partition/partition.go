@@ -0,0 +1,231 @@
+// Package partition 按月对 MySQL 表做 RANGE COLUMNS 分区 (分区列一般为 created_at), 过期数据按分区整体删除,
+// 不必逐行 DELETE, 也不会留下碎片.
+//
+// 分区名为 pYYYYMM, 存放该月 (按本地时区) 的行; 第一个分区同时存放更早的行, 最后的 pmax 存放尚未建好分区的月份.
+// Maintain 提前建好之后几个月的分区, 并删除超出保留期的分区.
+//
+// MySQL 要求分区列包含在每个唯一键 (含主键) 中, 且分区表不能有外键, 也不能被外键引用. Enable 把主键和唯一键扩展为
+// 包含分区列, 并删除涉及该表的外键: 此后唯一键只保证 (原有列, 分区列) 唯一, 关联的完整性由应用维护.
+// 按时间范围查询时在分区列上加条件 (如 scopes.CreatedBetween), MySQL 只读相关的分区; 只按主键查询时每个分区各查一次.
+package partition
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 存放尚未建好分区的月份的分区
+const maxPartition = "pmax"
+
+// Partition 分区的信息
+type Partition struct {
+	Name  string
+	Bound string // 上界 (不含), pmax 为 MAXVALUE
+	Rows  int64  // 估算的行数
+}
+
+// Month t 所在月份的第一天 0 点
+func Month(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// Name 存放 month 所在月份的分区名
+func Name(month time.Time) string {
+	return "p" + month.Format("200601")
+}
+
+// parseName 分区名对应的月份, 不是按月的分区 (如 pmax) 时返回 false
+func parseName(name string, loc *time.Location) (time.Time, bool) {
+	s, ok := strings.CutPrefix(name, "p")
+	if !ok {
+		return time.Time{}, false
+	}
+	month, err := time.ParseInLocation("200601", s, loc)
+	return month, err == nil
+}
+
+// definition month 所在月份的分区定义
+func definition(month time.Time) string {
+	return fmt.Sprintf("PARTITION %s VALUES LESS THAN ('%s')", Name(month), month.AddDate(0, 1, 0).Format(time.DateTime))
+}
+
+// List 表 table 的分区, 按顺序排列; 没有分区时为空
+func List(ctx context.Context, db *gorm.DB, table string) ([]Partition, error) {
+	var parts []Partition
+	err := db.WithContext(ctx).Raw(`
+		SELECT PARTITION_NAME AS name, PARTITION_DESCRIPTION AS bound, TABLE_ROWS AS `+"`rows`"+`
+		FROM information_schema.PARTITIONS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL
+		ORDER BY PARTITION_ORDINAL_POSITION
+	`, table).Scan(&parts).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询 %s 的分区失败: %w", table, err)
+	}
+	return parts, nil
+}
+
+// Enable 把表 table 改为按 column 每月一个分区, 分区从已有数据最早的月份建到 now 之后 ahead 个月; 已经分区时什么都不做.
+// 会删除涉及该表的外键、把主键和唯一键扩展为包含 column, 然后重建整张表, 大表上耗时较长, 应在低峰期执行
+func Enable(ctx context.Context, db *gorm.DB, table, column string, now time.Time, ahead int) error {
+	db = db.WithContext(ctx)
+	parts, err := List(ctx, db, table)
+	if err != nil || len(parts) > 0 {
+		return err
+	}
+	if err := dropForeignKeys(db, table); err != nil {
+		return err
+	}
+	if err := extendUniqueKeys(db, table, column); err != nil {
+		return err
+	}
+
+	var oldest sql.NullTime
+	if err := db.Table(table).Select("MIN(" + db.Statement.Quote(column) + ")").Row().Scan(&oldest); err != nil {
+		return fmt.Errorf("查询 %s 最早的数据失败: %w", table, err)
+	}
+	first, last := Month(now), Month(now).AddDate(0, ahead, 0)
+	if oldest.Valid && oldest.Time.Before(first) {
+		first = Month(oldest.Time.In(now.Location()))
+	}
+	var defs []string
+	for m := first; !m.After(last); m = m.AddDate(0, 1, 0) {
+		defs = append(defs, definition(m))
+	}
+	defs = append(defs, "PARTITION "+maxPartition+" VALUES LESS THAN (MAXVALUE)")
+	stmt := fmt.Sprintf("ALTER TABLE %s PARTITION BY RANGE COLUMNS(%s) (%s)", db.Statement.Quote(table), db.Statement.Quote(column), strings.Join(defs, ", "))
+	if err := db.Exec(stmt).Error; err != nil {
+		return fmt.Errorf("为 %s 分区失败: %w", table, err)
+	}
+	return nil
+}
+
+// dropForeignKeys 删除 table 上的和引用 table 的外键
+func dropForeignKeys(db *gorm.DB, table string) error {
+	var fks []struct {
+		Table string
+		Name  string
+	}
+	err := db.Raw(`
+		SELECT TABLE_NAME AS `+"`table`"+`, CONSTRAINT_NAME AS name
+		FROM information_schema.REFERENTIAL_CONSTRAINTS
+		WHERE CONSTRAINT_SCHEMA = DATABASE() AND (TABLE_NAME = ? OR REFERENCED_TABLE_NAME = ?)
+	`, table, table).Scan(&fks).Error
+	if err != nil {
+		return fmt.Errorf("查询 %s 的外键失败: %w", table, err)
+	}
+	for _, fk := range fks {
+		stmt := fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s", db.Statement.Quote(fk.Table), db.Statement.Quote(fk.Name))
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("删除外键 %s.%s 失败: %w", fk.Table, fk.Name, err)
+		}
+	}
+	return nil
+}
+
+// extendUniqueKeys 把 table 上不含 column 的主键和唯一键扩展为包含 column
+func extendUniqueKeys(db *gorm.DB, table, column string) error {
+	var rows []struct {
+		Index  string
+		Column string
+	}
+	err := db.Raw(`
+		SELECT INDEX_NAME AS `+"`index`"+`, COLUMN_NAME AS `+"`column`"+`
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND NON_UNIQUE = 0
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX
+	`, table).Scan(&rows).Error
+	if err != nil {
+		return fmt.Errorf("查询 %s 的唯一键失败: %w", table, err)
+	}
+	keys := map[string][]string{}
+	var names []string
+	for _, r := range rows {
+		if _, ok := keys[r.Index]; !ok {
+			names = append(names, r.Index)
+		}
+		keys[r.Index] = append(keys[r.Index], r.Column)
+	}
+
+	var specs []string
+	for _, name := range names {
+		cols := keys[name]
+		if slices.Contains(cols, column) {
+			continue
+		}
+		quoted := make([]string, 0, len(cols)+1)
+		for _, c := range append(cols, column) {
+			quoted = append(quoted, db.Statement.Quote(c))
+		}
+		if name == "PRIMARY" {
+			specs = append(specs, "DROP PRIMARY KEY", "ADD PRIMARY KEY ("+strings.Join(quoted, ", ")+")")
+		} else {
+			specs = append(specs, "DROP INDEX "+db.Statement.Quote(name), "ADD UNIQUE INDEX "+db.Statement.Quote(name)+" ("+strings.Join(quoted, ", ")+")")
+		}
+	}
+	if len(specs) == 0 {
+		return nil
+	}
+	if err := db.Exec("ALTER TABLE " + db.Statement.Quote(table) + " " + strings.Join(specs, ", ")).Error; err != nil {
+		return fmt.Errorf("把 %s 的唯一键扩展为包含 %s 失败: %w", table, column, err)
+	}
+	return nil
+}
+
+// Maintain 为已分区的表 table 建好 now 之后 ahead 个月的分区, retention 大于 0 时删除早于 now 所在月份之前
+// retention 个月的分区, 返回新建和删除的分区名. 表没有分区时什么都不做. 删除分区会直接删除其中的数据
+func Maintain(ctx context.Context, db *gorm.DB, table string, now time.Time, ahead, retention int) (added, dropped []string, err error) {
+	db = db.WithContext(ctx)
+	parts, err := List(ctx, db, table)
+	if err != nil || len(parts) == 0 {
+		return nil, nil, err
+	}
+	var months []time.Time
+	hasMax := false
+	for _, p := range parts {
+		if m, ok := parseName(p.Name, now.Location()); ok {
+			months = append(months, m)
+		} else if p.Name == maxPartition {
+			hasMax = true
+		}
+	}
+	if !hasMax || len(months) == 0 {
+		return nil, nil, fmt.Errorf("%s 不是按月分区的表 (缺少 %s 或 pYYYYMM 分区)", table, maxPartition)
+	}
+
+	// pmax 之前补上缺少的月份; pmax 中一般没有数据, 重组很快
+	var defs []string
+	for m, last := months[len(months)-1].AddDate(0, 1, 0), Month(now).AddDate(0, ahead, 0); !m.After(last); m = m.AddDate(0, 1, 0) {
+		defs = append(defs, definition(m))
+		added = append(added, Name(m))
+	}
+	if len(defs) > 0 {
+		defs = append(defs, "PARTITION "+maxPartition+" VALUES LESS THAN (MAXVALUE)")
+		stmt := fmt.Sprintf("ALTER TABLE %s REORGANIZE PARTITION %s INTO (%s)", db.Statement.Quote(table), maxPartition, strings.Join(defs, ", "))
+		if err := db.Exec(stmt).Error; err != nil {
+			return nil, nil, fmt.Errorf("为 %s 新建分区失败: %w", table, err)
+		}
+	}
+
+	if retention > 0 {
+		cutoff := Month(now).AddDate(0, -retention, 0)
+		for _, m := range months {
+			// 保留至少一个按月的分区
+			if m.Before(cutoff) && len(dropped) < len(months)-1 {
+				dropped = append(dropped, Name(m))
+			}
+		}
+		if len(dropped) > 0 {
+			stmt := fmt.Sprintf("ALTER TABLE %s DROP PARTITION %s", db.Statement.Quote(table), strings.Join(dropped, ", "))
+			if err := db.Exec(stmt).Error; err != nil {
+				return added, nil, fmt.Errorf("删除 %s 的过期分区失败: %w", table, err)
+			}
+		}
+	}
+	return added, dropped, nil
+}
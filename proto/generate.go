@@ -0,0 +1,11 @@
+// Package proto 博客和员工服务的 protobuf 定义, 生成的 Go 代码 (消息类型、服务端接口和客户端) 提交在 gen/ 下,
+// 其他服务直接引用 gen/blog/v1 和 gen/employee/v1 即可.
+//
+// 修改 .proto 后需要安装 protoc、protoc-gen-go 和 protoc-gen-go-grpc 并重新生成:
+//
+//	go generate ./proto
+//
+// go test ./proto 会检查 gen/ 与 .proto 是否一致.
+package proto
+
+//go:generate protoc -I . --go_out=../gen --go_opt=paths=source_relative --go-grpc_out=../gen --go-grpc_opt=paths=source_relative blog/v1/blog.proto employee/v1/employee.proto
@@ -0,0 +1,47 @@
+package proto
+
+import (
+	"context"
+	"testing"
+
+	blogv1 "github.com/alexwang789/Base1_golang_task3/gen/blog/v1"
+	employeev1 "github.com/alexwang789/Base1_golang_task3/gen/employee/v1"
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// TestGeneratedUpToDate 检查 gen/ 下提交的代码与 .proto 一致, 修改 .proto 后忘记重新生成时失败
+func TestGeneratedUpToDate(t *testing.T) {
+	generated := map[string]protoreflect.FileDescriptor{
+		"blog/v1/blog.proto":         blogv1.File_blog_v1_blog_proto,
+		"employee/v1/employee.proto": employeev1.File_employee_v1_employee_proto,
+	}
+	files := make([]string, 0, len(generated))
+	for path := range generated {
+		files = append(files, path)
+	}
+
+	c := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: []string{"."}}),
+	}
+	compiled, err := c.Compile(context.Background(), files...)
+	if err != nil {
+		t.Fatalf("编译 .proto 失败: %v", err)
+	}
+	for _, fd := range compiled {
+		want := normalize(protodesc.ToFileDescriptorProto(fd))
+		got := normalize(protodesc.ToFileDescriptorProto(generated[fd.Path()]))
+		if !proto.Equal(want, got) {
+			t.Errorf("gen/ 下 %s 的生成代码已过期, 请执行 go generate ./proto", fd.Path())
+		}
+	}
+}
+
+// normalize 去掉不影响生成代码的源码位置信息
+func normalize(fd *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+	fd.SourceCodeInfo = nil
+	return fd
+}
@@ -0,0 +1,295 @@
+package queryplan
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Finding 执行计划中值得关注的一处问题
+type Finding struct {
+	Table string // EXPLAIN 中的表名, 可能是别名或 <derived2> 这样的派生表
+	Kind  string // full_scan、filesort 或 temporary
+	Rows  int64  // 预计扫描的行数
+}
+
+// 问题的说明
+var findingText = map[string]string{
+	"full_scan": "全表扫描",
+	"filesort":  "额外排序 (Using filesort)",
+	"temporary": "使用临时表 (Using temporary)",
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("表 %s: %s, 预计 %d 行", f.Table, findingText[f.Kind], f.Rows)
+}
+
+// Suggestion 建议添加的索引
+type Suggestion struct {
+	Table   string
+	Columns []string
+}
+
+// SQL 创建该索引的语句
+func (s Suggestion) SQL() string {
+	return fmt.Sprintf("CREATE INDEX idx_%s_%s ON %s (%s)", s.Table, strings.Join(s.Columns, "_"), s.Table, strings.Join(s.Columns, ", "))
+}
+
+// Report 一个查询的执行计划分析
+type Report struct {
+	Query       Query
+	Plan        *Plan
+	JSON        json.RawMessage // EXPLAIN FORMAT=JSON 的输出, 数据库不支持时为 nil
+	Findings    []Finding
+	Suggestions []Suggestion
+}
+
+// Advise 执行 EXPLAIN 并分析执行计划: 找出全表扫描 (Query.AllowFullScan 中的表除外)、额外排序和临时表,
+// 再按 WHERE 和 ORDER BY 中的列为有问题的表建议索引. 建议只是启发式的: 等值条件的列在前, 然后是第一个范围条件的列,
+// 没有范围条件时接上排序列; 已有索引以这些列开头时不再建议
+func Advise(ctx context.Context, db *sql.DB, q Query) (*Report, error) {
+	plan, err := Explain(ctx, db, q)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{Query: q, Plan: plan}
+
+	var doc string
+	if err := db.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+q.SQL, q.Args...).Scan(&doc); err == nil {
+		r.JSON = json.RawMessage(doc)
+	}
+
+	for _, row := range plan.Rows {
+		if row.Type == "ALL" && !slices.Contains(q.AllowFullScan, row.Table) {
+			r.Findings = append(r.Findings, Finding{Table: row.Table, Kind: "full_scan", Rows: row.Rows})
+		}
+		if strings.Contains(row.Extra, "Using filesort") {
+			r.Findings = append(r.Findings, Finding{Table: row.Table, Kind: "filesort", Rows: row.Rows})
+		}
+		if strings.Contains(row.Extra, "Using temporary") {
+			r.Findings = append(r.Findings, Finding{Table: row.Table, Kind: "temporary", Rows: row.Rows})
+		}
+	}
+	if len(r.Findings) == 0 {
+		return r, nil
+	}
+
+	refs := parseColumns(q.SQL)
+	seen := map[string]bool{}
+	for _, f := range r.Findings {
+		table, ok := refs.tables[f.Table]
+		if !ok || seen[table] {
+			continue
+		}
+		seen[table] = true
+		s, err := suggest(ctx, db, table, f.Table, refs)
+		if err != nil {
+			return nil, err
+		}
+		if s != nil {
+			r.Suggestions = append(r.Suggestions, *s)
+		}
+	}
+	return r, nil
+}
+
+// WriteText 输出可读的分析结果
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "== %s\n", r.Query.Name)
+	fmt.Fprintf(w, "%-4s %-12s %-16s %-8s %-28s %10s  %s\n", "id", "select_type", "table", "type", "key", "rows", "extra")
+	for _, row := range r.Plan.Rows {
+		fmt.Fprintf(w, "%-4d %-12s %-16s %-8s %-28s %10d  %s\n", row.ID, row.SelectType, row.Table, row.Type, row.Key, row.Rows, row.Extra)
+	}
+	if len(r.Findings) == 0 {
+		fmt.Fprintln(w, "✅ 没有发现全表扫描和额外排序")
+		return
+	}
+	for _, f := range r.Findings {
+		fmt.Fprintf(w, "⚠️ %s\n", f)
+	}
+	for _, s := range r.Suggestions {
+		fmt.Fprintf(w, "💡 %s;\n", s.SQL())
+	}
+}
+
+// columnRefs 从 SQL 中解析出的表别名和各表在条件、排序中使用的列
+type columnRefs struct {
+	tables map[string]string // 别名或表名 -> 表名
+	single string            // 只有一张表时的表名, 未限定表名的列归属于它
+	eq     []colRef          // 等值条件 (=、IN、IS NULL) 和连接条件的列
+	rng    []colRef          // 范围条件 (<、>、BETWEEN、LIKE 等) 的列
+	order  []colRef          // ORDER BY 的列
+}
+
+type colRef struct{ qualifier, name string }
+
+var (
+	// FROM / JOIN 后的表名, 子查询 "FROM (" 不匹配; 表名之后可能是别名
+	tableRe = regexp.MustCompile(`(?i)\b(?:from|join)\s+([a-z_][a-z0-9_]*)`)
+	aliasRe = regexp.MustCompile(`(?i)^\s+(?:as\s+)?([a-z_][a-z0-9_]*)`)
+	// 列与运算符, 运算符前的列为条件列
+	condRe = regexp.MustCompile(`(?i)(?:\b([a-z_][a-z0-9_]*)\.)?\b([a-z_][a-z0-9_]*)\s*(<=>|>=|<=|<>|!=|=|>|<|\bnot\s+in\b|\bin\b|\bnot\s+like\b|\blike\b|\bbetween\b|\bis\s+(?:not\s+)?null\b)`)
+	// 等号右侧的限定列, 即连接条件的另一侧
+	joinRe  = regexp.MustCompile(`(?i)=\s*([a-z_][a-z0-9_]*)\.([a-z_][a-z0-9_]*)`)
+	orderRe = regexp.MustCompile(`(?is)\border\s+by\s+(.+?)(?:\blimit\b|\)|;|$)`)
+	itemRe  = regexp.MustCompile(`(?i)^(?:([a-z_][a-z0-9_]*)\.)?([a-z_][a-z0-9_]*)(?:\s+(?:asc|desc))?$`)
+)
+
+// 不会是别名的关键字
+var sqlKeywords = map[string]bool{
+	"where": true, "on": true, "join": true, "left": true, "right": true, "inner": true, "outer": true, "cross": true,
+	"straight_join": true, "group": true, "order": true, "limit": true, "using": true, "union": true, "for": true,
+	"lateral": true, "natural": true, "having": true, "window": true, "force": true, "use": true, "ignore": true,
+}
+
+// 条件列的位置上可能出现的关键字
+var notColumns = map[string]bool{"and": true, "or": true, "not": true, "where": true, "on": true, "when": true, "then": true, "else": true}
+
+// parseColumns 用正则粗略解析 SQL, 不处理子查询的作用域, 只用于给出索引建议
+func parseColumns(query string) *columnRefs {
+	query = strings.ReplaceAll(query, "`", "")
+	refs := &columnRefs{tables: map[string]string{}}
+	names := map[string]bool{}
+	for _, m := range tableRe.FindAllStringSubmatchIndex(query, -1) {
+		table := strings.ToLower(query[m[2]:m[3]])
+		if table == "lateral" || table == "dual" {
+			continue
+		}
+		names[table] = true
+		refs.tables[table] = table
+		if am := aliasRe.FindStringSubmatch(query[m[1]:]); am != nil && !sqlKeywords[strings.ToLower(am[1])] {
+			refs.tables[strings.ToLower(am[1])] = table
+		}
+	}
+	if len(names) == 1 {
+		for t := range names {
+			refs.single = t
+		}
+	}
+
+	for _, m := range condRe.FindAllStringSubmatch(query, -1) {
+		ref := colRef{strings.ToLower(m[1]), strings.ToLower(m[2])}
+		if notColumns[ref.name] {
+			continue
+		}
+		switch op := strings.ToLower(strings.Join(strings.Fields(m[3]), " ")); op {
+		case "=", "<=>", "in", "is null":
+			refs.eq = append(refs.eq, ref)
+		default:
+			refs.rng = append(refs.rng, ref)
+		}
+	}
+	for _, m := range joinRe.FindAllStringSubmatch(query, -1) {
+		refs.eq = append(refs.eq, colRef{strings.ToLower(m[1]), strings.ToLower(m[2])})
+	}
+
+	if m := orderRe.FindStringSubmatch(query); m != nil {
+		for _, item := range strings.Split(m[1], ",") {
+			if im := itemRe.FindStringSubmatch(strings.TrimSpace(item)); im != nil {
+				refs.order = append(refs.order, colRef{strings.ToLower(im[1]), strings.ToLower(im[2])})
+			}
+		}
+	}
+	return refs
+}
+
+// suggest 为表 table (在 SQL 中以 alias 引用) 建议索引, 没有可用的列或已有合适的索引时返回 nil
+func suggest(ctx context.Context, db *sql.DB, table, alias string, refs *columnRefs) (*Suggestion, error) {
+	columns, indexes, err := tableSchema(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	// 列属于该表: 以别名或表名限定, 或未限定且该表有此列 (多表时可能误判, 只影响建议)
+	belongs := func(c colRef) bool {
+		if c.qualifier != "" {
+			return refs.tables[c.qualifier] == table && (c.qualifier == alias || c.qualifier == table)
+		}
+		return (refs.single == table || refs.single == "") && columns[c.name]
+	}
+	pick := func(list []colRef) []string {
+		var out []string
+		for _, c := range list {
+			if belongs(c) && columns[c.name] && !slices.Contains(out, c.name) {
+				out = append(out, c.name)
+			}
+		}
+		return out
+	}
+
+	cols := pick(refs.eq)
+	if rng := pick(refs.rng); len(rng) > 0 {
+		if !slices.Contains(cols, rng[0]) {
+			cols = append(cols, rng[0])
+		}
+	} else {
+		for _, c := range pick(refs.order) {
+			if !slices.Contains(cols, c) {
+				cols = append(cols, c)
+			}
+		}
+	}
+	if len(cols) == 0 {
+		return nil, nil
+	}
+	for _, index := range indexes {
+		if len(index) >= len(cols) && slices.Equal(index[:len(cols)], cols) {
+			return nil, nil
+		}
+	}
+	return &Suggestion{Table: table, Columns: cols}, nil
+}
+
+// tableSchema 返回当前库中表的列和各索引的列 (按索引中的顺序)
+func tableSchema(ctx context.Context, db *sql.DB, table string) (map[string]bool, [][]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT LOWER(COLUMN_NAME) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+	`, table)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询表 %s 的列失败: %w", table, err)
+	}
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("读取表 %s 的列失败: %w", table, err)
+		}
+		columns[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("读取表 %s 的列失败: %w", table, err)
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT INDEX_NAME, LOWER(COLUMN_NAME) FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME IS NOT NULL
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX
+	`, table)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询表 %s 的索引失败: %w", table, err)
+	}
+	defer rows.Close()
+	var (
+		indexes [][]string
+		last    string
+	)
+	for rows.Next() {
+		var index, column string
+		if err := rows.Scan(&index, &column); err != nil {
+			return nil, nil, fmt.Errorf("读取表 %s 的索引失败: %w", table, err)
+		}
+		if index != last || len(indexes) == 0 {
+			indexes = append(indexes, nil)
+			last = index
+		}
+		indexes[len(indexes)-1] = append(indexes[len(indexes)-1], column)
+	}
+	return columns, indexes, rows.Err()
+}
@@ -0,0 +1,56 @@
+package queryplan
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Index 查询依赖的索引, 由各模块的迁移创建
+type Index struct {
+	Table   string
+	Name    string
+	Columns []string // 按索引中的顺序
+}
+
+// SQL 创建该索引的语句
+func (i Index) SQL() string {
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s)", i.Name, i.Table, strings.Join(i.Columns, ", "))
+}
+
+// MissingIndexes 返回 expected 中当前库缺少的索引. 只比较列: 已有索引以这些列开头即视为存在, 不要求同名
+func MissingIndexes(ctx context.Context, db *sql.DB, expected []Index) ([]Index, error) {
+	existing := map[string][][]string{}
+	var missing []Index
+	for _, idx := range expected {
+		indexes, ok := existing[idx.Table]
+		if !ok {
+			var err error
+			if _, indexes, err = tableSchema(ctx, db, idx.Table); err != nil {
+				return nil, err
+			}
+			existing[idx.Table] = indexes
+		}
+		if !slices.ContainsFunc(indexes, func(columns []string) bool {
+			return len(columns) >= len(idx.Columns) && slices.Equal(columns[:len(idx.Columns)], idx.Columns)
+		}) {
+			missing = append(missing, idx)
+		}
+	}
+	return missing, nil
+}
+
+// WarnMissingIndexes 启动时检查 expected 中的索引, 缺少时输出警告和创建语句. 缺少索引不影响启动,
+// 查询会退化为全表扫描或额外排序, 应执行 task3 migrate 补齐
+func WarnMissingIndexes(ctx context.Context, db *sql.DB, expected []Index) error {
+	missing, err := MissingIndexes(ctx, db, expected)
+	if err != nil {
+		return fmt.Errorf("检查索引失败: %w", err)
+	}
+	for _, idx := range missing {
+		fmt.Printf("⚠️ 缺少索引 %s (%s), 请执行 task3 migrate 或: %s;\n", idx.Name, strings.Join(idx.Columns, ", "), idx.SQL())
+	}
+	return nil
+}
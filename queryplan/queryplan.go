@@ -0,0 +1,247 @@
+// Package queryplan 捕获命名查询的 EXPLAIN 输出, 在索引失效时报错, 防止查询性能悄悄退化.
+//
+// 每个 Query 在已填充数据的 MySQL 上执行 EXPLAIN, 出现以下情况视为回归:
+//   - 某张表被全表扫描 (type = ALL), 且该表不在 Query.AllowFullScan 中
+//   - 基线文件中记录使用了索引的表, 本次不再使用该索引
+//
+// 基线以 JSON 保存在 Guard.Dir 下, 每个查询一个文件; Guard.Update 为 true 时用本次结果覆盖基线.
+//
+// 开发时可用 Advise 分析单个查询: 除全表扫描外还指出额外排序和临时表, 并按条件和排序列建议索引 (见 task3 explain).
+package queryplan
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// Query 受保护的命名查询
+type Query struct {
+	Name          string   // 唯一名称, 同时作为基线文件名
+	SQL           string   // 使用 ? 占位符的查询语句
+	Args          []any    // 执行 EXPLAIN 时使用的示例参数
+	AllowFullScan []string // 允许全表扫描的表, 例如数据量很小的字典表
+}
+
+// Row EXPLAIN 输出的一行
+type Row struct {
+	ID           int64  `json:"id"`
+	SelectType   string `json:"select_type"`
+	Table        string `json:"table"`
+	Type         string `json:"type"`
+	PossibleKeys string `json:"possible_keys,omitempty"`
+	Key          string `json:"key,omitempty"`
+	Rows         int64  `json:"rows"`
+	Extra        string `json:"extra,omitempty"`
+}
+
+// Plan 一次 EXPLAIN 的完整结果
+type Plan struct {
+	Query string `json:"query"`
+	Rows  []Row  `json:"rows"`
+}
+
+// Explain 执行 EXPLAIN 并返回执行计划
+func Explain(ctx context.Context, db *sql.DB, q Query) (*Plan, error) {
+	rows, err := db.QueryContext(ctx, "EXPLAIN "+q.SQL, q.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("EXPLAIN %s 失败: %w", q.Name, err)
+	}
+	defer rows.Close()
+
+	// 不同 MySQL 版本的 EXPLAIN 列不完全相同, 按列名取值
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Query: q.SQL}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("读取 EXPLAIN 结果失败: %w", err)
+		}
+
+		field := make(map[string]string, len(columns))
+		for i, column := range columns {
+			field[column] = values[i].String
+		}
+		id, _ := strconv.ParseInt(field["id"], 10, 64)
+		n, _ := strconv.ParseInt(field["rows"], 10, 64)
+		plan.Rows = append(plan.Rows, Row{
+			ID:           id,
+			SelectType:   field["select_type"],
+			Table:        field["table"],
+			Type:         field["type"],
+			PossibleKeys: field["possible_keys"],
+			Key:          field["key"],
+			Rows:         n,
+			Extra:        field["Extra"],
+		})
+	}
+	return plan, rows.Err()
+}
+
+// FullScans 返回被全表扫描的表
+func (p *Plan) FullScans() []string {
+	var tables []string
+	for _, row := range p.Rows {
+		if row.Type == "ALL" {
+			tables = append(tables, row.Table)
+		}
+	}
+	return tables
+}
+
+// Guard 对一组查询做执行计划回归检查
+type Guard struct {
+	DB     *sql.DB
+	Dir    string // 基线文件目录
+	Update bool   // 为 true 时写入本次结果作为新基线
+}
+
+// Check 检查单个查询, 返回发现的全部回归
+func (g *Guard) Check(ctx context.Context, q Query) error {
+	plan, err := Explain(ctx, g.DB, q)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, table := range plan.FullScans() {
+		if !slices.Contains(q.AllowFullScan, table) {
+			errs = append(errs, fmt.Errorf("%s: 表 %s 出现全表扫描", q.Name, table))
+		}
+	}
+
+	baseline, err := g.readBaseline(q.Name)
+	if err != nil {
+		return err
+	}
+	if baseline != nil {
+		errs = append(errs, compare(q.Name, baseline, plan)...)
+	}
+
+	if g.Update {
+		if err := g.writeBaseline(q.Name, plan); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckAll 依次检查全部查询, 汇总所有回归
+func (g *Guard) CheckAll(ctx context.Context, queries []Query) error {
+	var errs []error
+	for _, q := range queries {
+		if err := g.Check(ctx, q); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckEnv 设置 QUERY_PLAN_DIR 时检查执行计划, 基线保存在该目录; QUERY_PLAN_UPDATE=1 时更新基线.
+// 未设置 QUERY_PLAN_DIR 时直接返回 nil
+func CheckEnv(db *sql.DB, queries []Query) error {
+	dir := os.Getenv("QUERY_PLAN_DIR")
+	if dir == "" {
+		return nil
+	}
+
+	guard := &Guard{
+		DB:     db,
+		Dir:    dir,
+		Update: os.Getenv("QUERY_PLAN_UPDATE") == "1",
+	}
+	if err := guard.CheckAll(context.Background(), queries); err != nil {
+		return fmt.Errorf("执行计划检查未通过:\n%w", err)
+	}
+
+	fmt.Println("✅ 执行计划检查通过")
+	return nil
+}
+
+// TB 是 testing.TB 的子集, 便于在测试中直接使用 Guard
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Run 在测试中检查全部查询, 每个回归报告为一条测试错误
+func Run(t TB, g *Guard, queries ...Query) {
+	t.Helper()
+	for _, q := range queries {
+		if err := g.Check(context.Background(), q); err != nil {
+			t.Errorf("执行计划回归: %v", err)
+		}
+	}
+}
+
+// compare 找出基线中使用了索引、本次却不再使用的表
+func compare(name string, baseline, current *Plan) []error {
+	keys := make(map[string]string, len(current.Rows))
+	for _, row := range current.Rows {
+		keys[row.Table] = row.Key
+	}
+
+	var errs []error
+	for _, row := range baseline.Rows {
+		if row.Key == "" {
+			continue
+		}
+		switch key, ok := keys[row.Table]; {
+		case !ok:
+			// 表已不在执行计划中, 查询本身变了, 由全表扫描检查兜底
+		case key == "":
+			errs = append(errs, fmt.Errorf("%s: 表 %s 不再使用索引 %s", name, row.Table, row.Key))
+		case key != row.Key:
+			errs = append(errs, fmt.Errorf("%s: 表 %s 的索引由 %s 变为 %s", name, row.Table, row.Key, key))
+		}
+	}
+	return errs
+}
+
+func (g *Guard) baselinePath(name string) string {
+	return filepath.Join(g.Dir, name+".json")
+}
+
+func (g *Guard) readBaseline(name string) (*Plan, error) {
+	data, err := os.ReadFile(g.baselinePath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取执行计划基线失败: %w", err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("解析执行计划基线 %s 失败: %w", name, err)
+	}
+	return &plan, nil
+}
+
+func (g *Guard) writeBaseline(name string, plan *Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(g.Dir, 0o755); err != nil {
+		return fmt.Errorf("创建基线目录失败: %w", err)
+	}
+	if err := os.WriteFile(g.baselinePath(name), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("写入执行计划基线失败: %w", err)
+	}
+	return nil
+}
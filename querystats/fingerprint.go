@@ -0,0 +1,101 @@
+package querystats
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// IN (?, ?, ?) 折叠为 IN (?+)
+	listRe = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)+\s*\)`)
+	// 多行 VALUES (?+), (?+) 折叠为一组
+	valuesRe = regexp.MustCompile(`(\((?:\?\+|\?)\))(?:\s*,\s*\((?:\?\+|\?)\))+`)
+)
+
+// Fingerprint 把 SQL 归一化为语句形状: 去掉注释, 字符串和数字字面量替换为 ?,
+// 占位符列表折叠为 ?+, 空白合并并转为小写. 只有参数不同的语句会得到相同的指纹.
+func Fingerprint(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	rs := []rune(query)
+	space := false
+	emit := func(s string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteString(s)
+	}
+
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+
+		case r == '-' && i+1 < len(rs) && rs[i+1] == '-', r == '#':
+			// 行注释
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+			space = true
+
+		case r == '/' && i+1 < len(rs) && rs[i+1] == '*':
+			// 块注释
+			i += 2
+			for i+1 < len(rs) && !(rs[i] == '*' && rs[i+1] == '/') {
+				i++
+			}
+			i++
+			space = true
+
+		case r == '\'' || r == '"':
+			// 字符串字面量, 支持反斜杠转义和重复引号转义
+			for i++; i < len(rs); i++ {
+				if rs[i] == '\\' {
+					i++
+				} else if rs[i] == r {
+					if i+1 < len(rs) && rs[i+1] == r {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			emit("?")
+
+		case r == '`':
+			// 反引号标识符原样保留
+			j := i + 1
+			for j < len(rs) && rs[j] != '`' {
+				j++
+			}
+			emit(strings.ToLower(string(rs[i:min(j+1, len(rs))])))
+			i = j
+
+		case unicode.IsDigit(r) && (i == 0 || !isIdent(rs[i-1])):
+			// 数字字面量, 标识符中的数字 (如 t1) 不受影响
+			for i+1 < len(rs) && (unicode.IsDigit(rs[i+1]) || rs[i+1] == '.') {
+				i++
+			}
+			emit("?")
+
+		default:
+			j := i
+			for j+1 < len(rs) && isIdent(rs[j+1]) && isIdent(r) {
+				j++
+			}
+			emit(strings.ToLower(string(rs[i : j+1])))
+			i = j
+		}
+	}
+
+	out := listRe.ReplaceAllString(b.String(), "(?+)")
+	return valuesRe.ReplaceAllString(out, "$1")
+}
+
+func isIdent(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,65 @@
+package querystats
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startKey = "querystats:start"
+
+// Plugin 把 GORM 执行的每条语句记录到 Aggregator
+type Plugin struct {
+	agg *Aggregator
+}
+
+// NewPlugin 创建 GORM 插件, 通过 db.Use 注册
+func NewPlugin(agg *Aggregator) *Plugin {
+	return &Plugin{agg: agg}
+}
+
+// Name 实现 gorm.Plugin
+func (p *Plugin) Name() string {
+	return "querystats"
+}
+
+// Initialize 实现 gorm.Plugin
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []error{
+		cb.Create().Before("gorm:create").Register("querystats:before_create", p.before),
+		cb.Create().After("gorm:create").Register("querystats:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("querystats:before_query", p.before),
+		cb.Query().After("gorm:query").Register("querystats:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("querystats:before_update", p.before),
+		cb.Update().After("gorm:update").Register("querystats:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("querystats:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("querystats:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("querystats:before_row", p.before),
+		cb.Row().After("gorm:row").Register("querystats:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("querystats:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("querystats:after_raw", p.after),
+	}
+	for _, err := range hooks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Plugin) before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (p *Plugin) after(db *gorm.DB) {
+	query := db.Statement.SQL.String()
+	if query == "" {
+		return // 语句未生成, 例如在执行前就已出错
+	}
+	v, ok := db.InstanceGet(startKey)
+	if !ok {
+		return
+	}
+	p.agg.Record(query, time.Since(v.(time.Time)), db.RowsAffected, db.Error)
+}
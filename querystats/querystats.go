@@ -0,0 +1,118 @@
+// Package querystats 按语句形状 (指纹) 聚合进程启动以来执行过的 SQL,
+// 输出执行次数最多和耗时最长的语句, 用于定位热点和慢查询.
+package querystats
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Stat 一种语句形状的累计统计
+type Stat struct {
+	Fingerprint string        `json:"fingerprint"`
+	Example     string        `json:"example"` // 最近一次执行的原始语句
+	Count       int64         `json:"count"`
+	Errors      int64         `json:"errors"`
+	Rows        int64         `json:"rows"`
+	Total       time.Duration `json:"total"`
+	Max         time.Duration `json:"max"`
+}
+
+// Avg 平均耗时
+func (s Stat) Avg() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// Aggregator 内存中的统计聚合器, 可并发使用
+type Aggregator struct {
+	mu      sync.Mutex
+	stats   map[string]*Stat
+	started time.Time
+}
+
+// NewAggregator 创建聚合器
+func NewAggregator() *Aggregator {
+	return &Aggregator{stats: make(map[string]*Stat), started: time.Now()}
+}
+
+// Record 记录一次语句执行
+func (a *Aggregator) Record(query string, elapsed time.Duration, rows int64, err error) {
+	fp := Fingerprint(query)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	st, ok := a.stats[fp]
+	if !ok {
+		st = &Stat{Fingerprint: fp}
+		a.stats[fp] = st
+	}
+	st.Example = query
+	st.Count++
+	st.Rows += rows
+	st.Total += elapsed
+	st.Max = max(st.Max, elapsed)
+	if err != nil {
+		st.Errors++
+	}
+}
+
+// Since 返回统计的起始时间
+func (a *Aggregator) Since() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.started
+}
+
+// Reset 清空统计
+func (a *Aggregator) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stats = make(map[string]*Stat)
+	a.started = time.Now()
+}
+
+// SortBy 排序方式
+type SortBy string
+
+const (
+	ByCount SortBy = "count" // 执行次数
+	ByTotal SortBy = "total" // 累计耗时
+	ByMax   SortBy = "max"   // 单次最大耗时
+)
+
+// Top 返回按指定方式排序的前 n 条统计, n <= 0 时返回全部
+func (a *Aggregator) Top(n int, by SortBy) []Stat {
+	a.mu.Lock()
+	list := make([]Stat, 0, len(a.stats))
+	for _, st := range a.stats {
+		list = append(list, *st)
+	}
+	a.mu.Unlock()
+
+	key := func(s Stat) int64 {
+		switch by {
+		case ByTotal:
+			return int64(s.Total)
+		case ByMax:
+			return int64(s.Max)
+		default:
+			return s.Count
+		}
+	}
+	slices.SortFunc(list, func(x, y Stat) int {
+		if c := cmp.Compare(key(y), key(x)); c != 0 {
+			return c
+		}
+		return cmp.Compare(x.Fingerprint, y.Fingerprint)
+	})
+
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
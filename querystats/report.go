@@ -0,0 +1,67 @@
+package querystats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Report top-queries 报告
+type Report struct {
+	Since    time.Time `json:"since"`
+	Frequent []Stat    `json:"frequent"` // 执行次数最多
+	Slowest  []Stat    `json:"slowest"`  // 单次耗时最长
+}
+
+// BuildReport 生成前 n 条的报告
+func (a *Aggregator) BuildReport(n int) Report {
+	return Report{
+		Since:    a.Since(),
+		Frequent: a.Top(n, ByCount),
+		Slowest:  a.Top(n, ByMax),
+	}
+}
+
+// WriteText 以表格形式输出报告
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "统计起始: %s\n", r.Since.Format(time.DateTime))
+	for _, section := range []struct {
+		title string
+		stats []Stat
+	}{
+		{"执行次数最多的语句", r.Frequent},
+		{"耗时最长的语句", r.Slowest},
+	} {
+		fmt.Fprintf(tw, "\n%s:\n", section.title)
+		fmt.Fprintln(tw, "次数\t错误\t平均\t最大\t语句")
+		for _, st := range section.stats {
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n",
+				st.Count, st.Errors, st.Avg().Round(time.Microsecond), st.Max.Round(time.Microsecond), st.Fingerprint)
+		}
+	}
+	return tw.Flush()
+}
+
+// Handler 返回 top-queries 的 HTTP 接口, 支持 ?limit=N 和 ?format=text
+func Handler(agg *Aggregator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := 10
+		if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+			limit = v
+		}
+		report := agg.BuildReport(limit)
+
+		if r.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			report.WriteText(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	})
+}
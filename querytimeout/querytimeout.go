@@ -0,0 +1,162 @@
+// Package querytimeout 为 GORM 执行的语句加上默认超时, 防止失控的查询长时间占用连接池.
+//
+// 每条语句按操作类型 (查询、插入、更新、删除、原生语句) 取超时, 在 context 上设置截止时间,
+// 查询还会加上 MySQL 的 /*+ MAX_EXECUTION_TIME(ms) */ 提示, 客户端放弃等待后服务端也会终止执行.
+// 调用方的 context 已有更早的截止时间时保留调用方的.
+//
+// 单次调用可以用 With 指定超时, 或用 Report 标记为报表查询, 改用 config.QueryTimeouts.Report:
+//
+//	db.WithContext(querytimeout.Report(ctx)).Raw(sqlMonthlyReport).Scan(&rows)
+//
+// db.Row / Rows / Scan 的结果在回调返回之后才读取, 不能在回调结束时取消 context, 只加 MAX_EXECUTION_TIME 提示.
+// 原生语句以 SELECT 开头时同样加上提示.
+package querytimeout
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ctxKey int
+
+const (
+	overrideKey ctxKey = iota
+	reportKey
+)
+
+// With 本次调用的语句使用超时 d 代替默认值, d <= 0 表示不限制
+func With(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, overrideKey, d)
+}
+
+// Report 把本次调用的语句标记为报表查询, 使用 config.QueryTimeouts.Report
+func Report(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reportKey, true)
+}
+
+// pendingKey before 设置截止时间后留给 after 的 *pending
+const pendingKey = "querytimeout:pending"
+
+type pending struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+// Plugin 按操作类型为语句设置超时
+type Plugin struct {
+	timeouts config.QueryTimeouts
+}
+
+// NewPlugin 创建 GORM 插件, 通过 db.Use 注册
+func NewPlugin(timeouts config.QueryTimeouts) *Plugin {
+	return &Plugin{timeouts: timeouts}
+}
+
+// Name 实现 gorm.Plugin
+func (p *Plugin) Name() string {
+	return "querytimeout"
+}
+
+// Initialize 实现 gorm.Plugin
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []error{
+		cb.Create().Before("gorm:create").Register("querytimeout:before_create", p.before(p.timeouts.Create, false)),
+		cb.Create().After("gorm:create").Register("querytimeout:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("querytimeout:before_query", p.before(p.timeouts.Query, true)),
+		cb.Query().After("gorm:query").Register("querytimeout:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("querytimeout:before_update", p.before(p.timeouts.Update, false)),
+		cb.Update().After("gorm:update").Register("querytimeout:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("querytimeout:before_delete", p.before(p.timeouts.Delete, false)),
+		cb.Delete().After("gorm:delete").Register("querytimeout:after_delete", p.after),
+		cb.Raw().Before("gorm:raw").Register("querytimeout:before_raw", p.before(p.timeouts.Raw, false)),
+		cb.Raw().After("gorm:raw").Register("querytimeout:after_raw", p.after),
+		cb.Row().Before("gorm:row").Register("querytimeout:before_row", p.hintOnly),
+	}
+	for _, err := range hooks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timeout 语句的超时: With 指定的优先, 其次是报表标记, 最后是操作类型的默认值
+func (p *Plugin) timeout(ctx context.Context, d time.Duration) time.Duration {
+	if v, ok := ctx.Value(overrideKey).(time.Duration); ok {
+		return v
+	}
+	if ctx.Value(reportKey) != nil {
+		return p.timeouts.Report
+	}
+	return d
+}
+
+// before 在 context 上设置截止时间, 查询还加上 MAX_EXECUTION_TIME 提示. 原 context 保存下来,
+// 语句结束后由 after 恢复, 同一个 *gorm.DB 继续执行的语句 (如先 Count 再 Find) 不会拿到已取消的 context
+func (p *Plugin) before(d time.Duration, hint bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		d := p.timeout(ctx, d)
+		if d <= 0 {
+			return
+		}
+		if hint {
+			addExecutionTimeHint(db.Statement, d)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+			return
+		}
+		timed, cancel := context.WithTimeout(ctx, d)
+		db.InstanceSet(pendingKey, &pending{parent: ctx, cancel: cancel})
+		db.Statement.Context = timed
+	}
+}
+
+func (p *Plugin) after(db *gorm.DB) {
+	v, _ := db.InstanceGet(pendingKey)
+	if pd, ok := v.(*pending); ok {
+		pd.cancel()
+		db.Statement.Context = pd.parent
+		db.InstanceSet(pendingKey, nil)
+	}
+}
+
+// hintOnly Row / Rows 只加提示, 见包文档
+func (p *Plugin) hintOnly(db *gorm.DB) {
+	if d := p.timeout(db.Statement.Context, p.timeouts.Query); d > 0 {
+		addExecutionTimeHint(db.Statement, d)
+	}
+}
+
+// addExecutionTimeHint 在 SELECT 之后加上 MAX_EXECUTION_TIME 提示 (毫秒), 已有优化器提示时不加.
+// Raw 的语句只在以 SELECT 开头时加上
+func addExecutionTimeHint(stmt *gorm.Statement, d time.Duration) {
+	hint := fmt.Sprintf("/*+ MAX_EXECUTION_TIME(%d) */", max(d.Milliseconds(), 1))
+	if stmt.SQL.Len() == 0 {
+		c := stmt.Clauses["SELECT"]
+		if c.AfterNameExpression != nil {
+			return
+		}
+		c.Name = "SELECT"
+		c.AfterNameExpression = clause.Expr{SQL: hint}
+		stmt.Clauses["SELECT"] = c
+		return
+	}
+
+	sql := stmt.SQL.String()
+	body := strings.TrimLeft(sql, " \t\r\n")
+	if len(body) <= len("SELECT") || !strings.EqualFold(body[:len("SELECT")], "SELECT") ||
+		!unicode.IsSpace(rune(body[len("SELECT")])) || strings.Contains(sql, "/*+") {
+		return
+	}
+	stmt.SQL.Reset()
+	stmt.SQL.WriteString(body[:len("SELECT")] + " " + hint + body[len("SELECT"):])
+}
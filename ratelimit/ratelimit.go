@@ -0,0 +1,127 @@
+// Package ratelimit 按 key (通常是用户) 限制操作频率.
+//
+// Limiter 是限流的抽象, 业务代码只依赖它. TokenBucket 是进程内的令牌桶实现, 适用于单实例部署;
+// 多实例部署时用 RedisStore 创建的限流器, 各实例共享同一份计数.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited 操作过于频繁. 限流返回的 *LimitError 满足 errors.Is(err, ErrRateLimited)
+var ErrRateLimited = errors.New("操作过于频繁")
+
+// LimitError 被限流时返回的错误, RetryAfter 为下一次操作可被允许前需要等待的时间
+type LimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("操作过于频繁, 请 %s 后重试", e.RetryAfter.Round(time.Second))
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RetryAfter 返回 err 链中限流错误的等待时间, err 不是限流错误时返回 false
+func RetryAfter(err error) (time.Duration, bool) {
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return limitErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// Limiter 限流器, 必须可并发使用
+type Limiter interface {
+	// Allow 为 key 记一次操作. 允许时返回 nil, 超出频率时返回 *LimitError
+	Allow(ctx context.Context, key string) error
+	// Check 只检查 key 现在能否操作, 不记次数. 用于只对失败计数的场景 (如登录失败)
+	Check(ctx context.Context, key string) error
+}
+
+// TokenBucket 进程内令牌桶: 每个 key 一个容量为 n 的桶, 每 per 时间补满 n 个令牌,
+// 即允许瞬间连续操作 n 次, 长期平均不超过每 per 时间 n 次
+type TokenBucket struct {
+	burst float64
+	rate  float64 // 每秒补充的令牌数
+	per   time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time // tokens 的计算时间
+}
+
+// NewTokenBucket 创建每 per 时间最多 n 次操作的限流器, n <= 0 时不限流
+func NewTokenBucket(n int, per time.Duration) *TokenBucket {
+	return &TokenBucket{
+		burst:     float64(n),
+		rate:      float64(n) / per.Seconds(),
+		per:       per,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow 实现 Limiter
+func (l *TokenBucket) Allow(_ context.Context, key string) error {
+	return l.take(key, 1)
+}
+
+// Check 实现 Limiter
+func (l *TokenBucket) Check(_ context.Context, key string) error {
+	return l.take(key, 0)
+}
+
+// take 补充 key 的令牌后取走 cost 个, 不足一个时返回需要等待的时间
+func (l *TokenBucket) take(key string, cost float64) error {
+	if l.burst <= 0 {
+		return nil
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if cost == 0 {
+			return nil
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens -= cost
+		return nil
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return &LimitError{RetryAfter: wait}
+}
+
+// sweep 每隔 per 清理一次已经补满的桶, 它们与不存在的桶等价, 避免 key 无限增长
+func (l *TokenBucket) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.per {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisOptions Redis 连接配置
+type RedisOptions struct {
+	Addr     string // host:port
+	Password string
+	DB       int
+	PoolSize int // 空闲连接数上限, 默认 1
+}
+
+// RedisStore 把令牌桶保存在 Redis 中, 多个实例共享计数. 桶的补充和扣减在一个 Lua 脚本中完成,
+// 时间取 Redis 服务器的时钟, 不受各实例时钟偏差影响
+type RedisStore struct {
+	pool *redisPool
+}
+
+// NewRedisStore 创建 Redis 存储, 连接在第一次使用时建立
+func NewRedisStore(opts RedisOptions) *RedisStore {
+	return &RedisStore{pool: newRedisPool(opts)}
+}
+
+// Limiter 创建每 per 时间最多 n 次操作的限流器, 语义与 NewTokenBucket 相同. name 用作 key 的前缀,
+// 不同用途的限流器须使用不同的 name
+func (s *RedisStore) Limiter(name string, n int, per time.Duration) Limiter {
+	return &redisLimiter{
+		store:  s,
+		prefix: "ratelimit:" + name + ":",
+		burst:  n,
+		per:    per,
+	}
+}
+
+type redisLimiter struct {
+	store  *RedisStore
+	prefix string
+	burst  int
+	per    time.Duration
+}
+
+// 令牌桶脚本. KEYS[1] 桶; ARGV: 容量, 补满时间 (毫秒), 取走的令牌数 (0 表示只检查).
+// 允许时返回 0, 否则返回需要等待的毫秒数. 桶在补满所需的时间后过期, 与不存在等价
+const tokenBucketScript = `
+local burst = tonumber(ARGV[1])
+local per = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local rate = burst / per
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1]) or burst
+local ts = tonumber(b[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+if tokens < 1 then
+	return math.ceil((1 - tokens) / rate)
+end
+if cost == 0 then
+	return 0
+end
+tokens = tokens - cost
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], per)
+return 0
+`
+
+var tokenBucketSHA = func() string {
+	sum := sha1.Sum([]byte(tokenBucketScript))
+	return hex.EncodeToString(sum[:])
+}()
+
+// Allow 实现 Limiter
+func (l *redisLimiter) Allow(ctx context.Context, key string) error {
+	return l.take(ctx, key, 1)
+}
+
+// Check 实现 Limiter
+func (l *redisLimiter) Check(ctx context.Context, key string) error {
+	return l.take(ctx, key, 0)
+}
+
+func (l *redisLimiter) take(ctx context.Context, key string, cost int) error {
+	if l.burst <= 0 {
+		return nil
+	}
+	args := []string{"1", l.prefix + key, strconv.Itoa(l.burst), strconv.FormatInt(l.per.Milliseconds(), 10), strconv.Itoa(cost)}
+
+	// 先按 SHA 执行, 脚本未缓存 (如 Redis 重启后) 时再发送脚本全文
+	reply, err := l.store.pool.do(ctx, append([]string{"EVALSHA", tokenBucketSHA}, args...)...)
+	var rerr redisError
+	if errors.As(err, &rerr) && strings.HasPrefix(string(rerr), "NOSCRIPT") {
+		reply, err = l.store.pool.do(ctx, append([]string{"EVAL", tokenBucketScript}, args...)...)
+	}
+	if err != nil {
+		return fmt.Errorf("Redis 限流失败: %w", err)
+	}
+
+	waitMS, ok := reply.(int64)
+	if !ok {
+		return fmt.Errorf("Redis 限流失败: 意外的回复 %v", reply)
+	}
+	if waitMS > 0 {
+		return &LimitError{RetryAfter: time.Duration(waitMS) * time.Millisecond}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisError Redis 返回的错误回复, 如 "NOSCRIPT No matching script"
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisConn 一条 Redis 连接, 按 RESP2 协议收发命令. 限流只需要 EVALSHA/EVAL, 不引入完整的客户端
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// 没有截止时间的 ctx 使用的默认超时
+const redisTimeout = time.Second
+
+// redisPool 空闲连接池, 连接出错后丢弃, 下次使用时重新建立
+type redisPool struct {
+	opts RedisOptions
+	idle chan *redisConn
+}
+
+func newRedisPool(opts RedisOptions) *redisPool {
+	return &redisPool{opts: opts, idle: make(chan *redisConn, max(opts.PoolSize, 1))}
+}
+
+// do 执行一条命令并返回回复: string、int64、nil、[]any 或 redisError
+func (p *redisPool) do(ctx context.Context, args ...string) (any, error) {
+	c, err := p.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.do(ctx, args...)
+	var rerr redisError
+	if err != nil && !errors.As(err, &rerr) {
+		c.conn.Close()
+		return nil, err
+	}
+	p.put(c)
+	return reply, err
+}
+
+func (p *redisPool) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+	}
+
+	d := net.Dialer{Timeout: redisTimeout}
+	conn, err := d.DialContext(ctx, "tcp", p.opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接 Redis 失败: %w", err)
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	if p.opts.Password != "" {
+		if _, err := c.do(ctx, "AUTH", p.opts.Password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("Redis 认证失败: %w", err)
+		}
+	}
+	if p.opts.DB != 0 {
+		if _, err := c.do(ctx, "SELECT", strconv.Itoa(p.opts.DB)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("选择 Redis 库失败: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (p *redisPool) put(c *redisConn) {
+	select {
+	case p.idle <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+func (c *redisConn) do(ctx context.Context, args ...string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	c.conn.SetDeadline(deadline)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		return nil, fmt.Errorf("发送 Redis 命令失败: %w", err)
+	}
+	return c.read()
+}
+
+// read 读取一条回复
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("读取 Redis 回复失败: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("读取 Redis 回复失败: 空行")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, fmt.Errorf("读取 Redis 回复失败: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			// 数组元素中的错误回复作为值返回, 不中断读取
+			item, err := c.read()
+			var rerr redisError
+			if err != nil && !errors.As(err, &rerr) {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("无法识别的 Redis 回复 %q", line)
+}
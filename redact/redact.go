@@ -0,0 +1,149 @@
+// Package redact 日志脱敏: 按字段名遮盖密码、邮箱等敏感值, 用于 GORM 的 SQL 日志和 slog 结构化日志.
+//
+// 字段清单见 config.LoadRedactFields. SQL 日志按参数对应的列名判断, 结构化日志按属性的键判断.
+package redact
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Mask 替换敏感值的文本
+const Mask = "***"
+
+// Redactor 按字段名判断敏感值, 不区分大小写. nil 不遮盖任何字段
+type Redactor struct {
+	fields map[string]bool
+}
+
+// New 创建 Redactor, fields 为敏感的字段名或列名, 如 password、email
+func New(fields []string) *Redactor {
+	r := &Redactor{fields: make(map[string]bool, len(fields))}
+	for _, f := range fields {
+		r.fields[strings.ToLower(f)] = true
+	}
+	return r
+}
+
+// Sensitive 字段 name 是否敏感. 忽略表名前缀和引号, `users`.`email` 与 email 相同
+func (r *Redactor) Sensitive(name string) bool {
+	if r == nil || len(r.fields) == 0 {
+		return false
+	}
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return r.fields[strings.ToLower(strings.Trim(name, "`\""))]
+}
+
+// ReplaceAttr 用作 slog.HandlerOptions.ReplaceAttr, 遮盖键为敏感字段的属性
+func (r *Redactor) ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindGroup && r.Sensitive(a.Key) {
+		return slog.String(a.Key, Mask)
+	}
+	return a
+}
+
+// Params 返回遮盖了敏感列的参数的副本, 不修改 params. 参数对应的列按占位符前的条件判断,
+// 如 email = ?、`users`.`email` IN (?,?)、SET `password`=?; INSERT 按列清单判断.
+// 占位符与参数的数量对不上时无法对应, 全部遮盖
+func (r *Redactor) Params(sql string, params []any) []any {
+	if r == nil || len(r.fields) == 0 || len(params) == 0 {
+		return params
+	}
+	cols := columns(sql)
+	out := make([]any, len(params))
+	for i, p := range params {
+		if len(cols) != len(params) || r.Sensitive(cols[i]) {
+			out[i] = Mask
+		} else {
+			out[i] = p
+		}
+	}
+	return out
+}
+
+var (
+	// 占位符之前的 "列 运算符", 如 `email` = 、users.email IN (
+	reCondition = regexp.MustCompile("(?i)`?(\\w+)`?\\s*(?:<=>|<>|<=|>=|!=|=|<|>|\\b(?:NOT\\s+)?(?:LIKE|IN|REGEXP|BETWEEN)\\b)\\s*\\(?\\s*$")
+	// INSERT 的列清单
+	reInsert = regexp.MustCompile("(?is)^\\s*(?:INSERT|REPLACE)\\s+(?:IGNORE\\s+)?INTO\\s+\\S+\\s*\\(([^)]*)\\)\\s*VALUES\\s*")
+)
+
+// 向前查找条件时最多看的字符数
+const lookback = 128
+
+// columns 返回 sql 中每个占位符对应的列名, 判断不出的为空串. 跳过字符串和标识符中的 ?
+func columns(sql string) []string {
+	var (
+		cols   []string
+		insert []string // INSERT 的列清单
+		values = -1     // INSERT 的 VALUES 部分的起始位置, 不是 INSERT 时为 -1
+		depth  int      // VALUES 中的括号深度
+		index  int      // VALUES 中当前值在行中的序号
+		last   = -1     // 上一个占位符的位置
+		quote  byte
+	)
+	if m := reInsert.FindStringSubmatchIndex(sql); m != nil {
+		for _, c := range strings.Split(sql[m[2]:m[3]], ",") {
+			insert = append(insert, strings.TrimSpace(c))
+		}
+		values = m[1]
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		if quote != 0 {
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if values >= 0 && i >= values {
+			switch {
+			case c == '(':
+				depth++
+				if depth == 1 {
+					index = 0
+				}
+			case c == ')':
+				depth--
+			case c == ',' && depth == 1:
+				index++
+			case c == '?' && depth >= 1:
+				col := ""
+				if index < len(insert) {
+					col = insert[index]
+				}
+				cols = append(cols, col)
+				last = i
+				continue
+			case depth == 0 && c != ',' && c != ' ' && c != '\n' && c != '\t':
+				// 行之后的部分 (如 ON DUPLICATE KEY UPDATE) 按普通条件判断
+				values = -1
+			}
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case '?':
+			cols = append(cols, column(sql, last, i, cols))
+			last = i
+		}
+	}
+	return cols
+}
+
+// column 判断位置 i 的占位符对应的列. 与上一个占位符之间只有逗号和空白时 (IN 列表) 沿用上一个的列
+func column(sql string, last, i int, cols []string) string {
+	if last >= 0 && len(cols) > 0 && strings.Trim(sql[last+1:i], ", \t\n") == "" {
+		return cols[len(cols)-1]
+	}
+	prefix := sql[max(0, i-lookback):i]
+	if m := reCondition.FindStringSubmatch(prefix); m != nil {
+		return m[1]
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package replica
+
+import (
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Register 为 db 注册只读副本, replicaDSNs 为空时不做任何事
+func Register(db *gorm.DB, replicaDSNs []string, policy Policy) error {
+	if len(replicaDSNs) == 0 {
+		return nil
+	}
+
+	replicas := make([]gorm.Dialector, len(replicaDSNs))
+	for i, dsn := range replicaDSNs {
+		replicas[i] = mysql.Open(dsn)
+	}
+	return db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   gormPolicy{policy},
+	}))
+}
+
+// Primary 让本次查询强制走主库
+func Primary(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Write)
+}
+
+// Replica 让本次查询强制走副本 (例如 Raw 执行的只读语句默认会被当作写操作)
+func Replica(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Read)
+}
+
+// gormPolicy 把 Policy 适配为 dbresolver.Policy
+type gormPolicy struct {
+	policy Policy
+}
+
+func (p gormPolicy) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	return pools[p.policy.Pick(len(pools))]
+}
@@ -0,0 +1,59 @@
+// Package replica 实现读写分离: 写操作和事务走主库, 读操作按策略分发到只读副本.
+//
+// GORM 基于 dbresolver 插件; sqlx 没有对应机制, 由 Cluster 按同样的策略挑选连接.
+// 需要读到刚写入的数据时, 可按查询强制走主库 (GORM 用 Primary, sqlx 用 WithPrimary).
+package replica
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// Policy 副本选择策略
+type Policy interface {
+	// Pick 从 n 个副本中选出一个的下标, n > 0
+	Pick(n int) int
+}
+
+// RandomPolicy 随机选择
+type RandomPolicy struct{}
+
+// Pick 实现 Policy
+func (RandomPolicy) Pick(n int) int {
+	return rand.IntN(n)
+}
+
+// RoundRobinPolicy 依次轮询, 可并发使用
+type RoundRobinPolicy struct {
+	next atomic.Uint64
+}
+
+// Pick 实现 Policy
+func (p *RoundRobinPolicy) Pick(n int) int {
+	return int((p.next.Add(1) - 1) % uint64(n))
+}
+
+// ParsePolicy 按配置名创建策略: random 或 round_robin
+func ParsePolicy(name string) (Policy, error) {
+	switch name {
+	case "", "random":
+		return RandomPolicy{}, nil
+	case "round_robin":
+		return &RoundRobinPolicy{}, nil
+	}
+	return nil, fmt.Errorf("未知的副本选择策略: %s", name)
+}
+
+type primaryKey struct{}
+
+// WithPrimary 标记 ctx 中的读操作强制走主库, 用于写后立即读取的场景
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
@@ -0,0 +1,45 @@
+package replica
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Cluster 一个主库及其只读副本的 sqlx 连接
+type Cluster struct {
+	primary  *sqlx.DB
+	replicas []*sqlx.DB
+	policy   Policy
+}
+
+// NewCluster 创建集群, replicas 为空时读写都走主库
+func NewCluster(primary *sqlx.DB, replicas []*sqlx.DB, policy Policy) *Cluster {
+	if policy == nil {
+		policy = RandomPolicy{}
+	}
+	return &Cluster{primary: primary, replicas: replicas, policy: policy}
+}
+
+// Primary 返回主库连接, 用于写操作和事务
+func (c *Cluster) Primary() *sqlx.DB {
+	return c.primary
+}
+
+// Reader 返回执行只读查询的连接; ctx 经 WithPrimary 标记或没有副本时返回主库
+func (c *Cluster) Reader(ctx context.Context) *sqlx.DB {
+	if len(c.replicas) == 0 || usePrimary(ctx) {
+		return c.primary
+	}
+	return c.replicas[c.policy.Pick(len(c.replicas))]
+}
+
+// Close 关闭主库和全部副本连接
+func (c *Cluster) Close() error {
+	errs := []error{c.primary.Close()}
+	for _, r := range c.replicas {
+		errs = append(errs, r.Close())
+	}
+	return errors.Join(errs...)
+}
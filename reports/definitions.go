@@ -0,0 +1,81 @@
+package reports
+
+// DepartmentPayrollRow 部门薪资汇总的一行
+type DepartmentPayrollRow struct {
+	Department  string  `db:"department" json:"department"`
+	Headcount   int     `db:"headcount" json:"headcount"`
+	TotalSalary int64   `db:"total_salary" json:"total_salary"`
+	AvgSalary   float64 `db:"avg_salary" json:"avg_salary"`
+	MinSalary   int     `db:"min_salary" json:"min_salary"`
+	MaxSalary   int     `db:"max_salary" json:"max_salary"`
+}
+
+// DepartmentPayroll 各部门的人数和薪资汇总, 薪资总额高的部门在前
+var DepartmentPayroll = define[DepartmentPayrollRow](Report{
+	Name:   "department_payroll",
+	Title:  "各部门的人数和薪资汇总",
+	Source: HR,
+	Params: []Param{
+		{Name: "min_headcount", Kind: Int, Default: "1", Help: "只列出人数不少于该值的部门"},
+	},
+	SQL: `
+		SELECT department, COUNT(*) AS headcount, SUM(salary) AS total_salary,
+		       AVG(salary) AS avg_salary, MIN(salary) AS min_salary, MAX(salary) AS max_salary
+		FROM employees
+		GROUP BY department
+		HAVING COUNT(*) >= :min_headcount
+		ORDER BY total_salary DESC, department`,
+})
+
+// AuthorLeaderboardRow 作者排行的一行
+type AuthorLeaderboardRow struct {
+	UserID   uint   `db:"user_id" json:"user_id"`
+	Name     string `db:"name" json:"name"`
+	Posts    int64  `db:"post_count" json:"post_count"`
+	Comments int64  `db:"comment_count" json:"comment_count"` // 这些文章收到的已通过审核的评论数, 读自 post_stats
+	Views    uint64 `db:"views" json:"views"`
+}
+
+// AuthorLeaderboard 时间范围内发表已发布文章最多的作者, 与 blog.TopAuthors 口径相同.
+// 原生 SQL 不经过 tenant 插件, 按 tenant 参数显式过滤
+var AuthorLeaderboard = define[AuthorLeaderboardRow](Report{
+	Name:   "author_leaderboard",
+	Title:  "作者排行: 发表的文章数、收到的评论数和浏览数",
+	Source: Blog,
+	Params: []Param{
+		{Name: "tenant", Kind: Int, Default: "1", Help: "租户 ID"},
+		{Name: "from", Kind: Date, Default: "1970-01-01", Help: "发表日期的起点 (含), 默认不限"},
+		{Name: "to", Kind: Date, Default: "9999-12-31", Help: "发表日期的终点 (不含), 默认不限"},
+		{Name: "limit", Kind: Int, Default: "20", Help: "列出的作者数"},
+	},
+	SQL: `
+		SELECT posts.user_id, users.name, COUNT(*) AS post_count,
+		       COALESCE(SUM(post_stats.comment_count), 0) AS comment_count, COALESCE(SUM(posts.view_count), 0) AS views
+		FROM posts
+		JOIN users ON users.id = posts.user_id
+		LEFT JOIN post_stats ON post_stats.post_id = posts.id
+		WHERE posts.tenant_id = :tenant AND posts.status = 'published'
+		  AND COALESCE(posts.publish_at, posts.created_at) >= :from
+		  AND COALESCE(posts.publish_at, posts.created_at) < :to
+		GROUP BY posts.user_id, users.name
+		ORDER BY post_count DESC, comment_count DESC, posts.user_id
+		LIMIT :limit`,
+})
+
+// all 全部报表, 按 report list 列出的顺序
+var all = []*Report{DepartmentPayroll, AuthorLeaderboard}
+
+// All 返回全部报表
+func All() []*Report {
+	return all
+}
+
+// Get 按名称查找报表
+func Get(name string) (*Report, bool) {
+	for _, r := range all {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
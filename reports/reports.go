@@ -0,0 +1,222 @@
+// Package reports 报表: 每个报表是一条带命名参数 (sqlx 的 :name) 的 SQL 和对应的结果结构体,
+// 通过 sqlx 扫描结果, 以 CSV 或 JSON 输出. 报表定义见 definitions.go, 由 "report run <name>" 命令执行.
+//
+// 结果结构体的字段以 db 标签对应查询的列, CSV 的表头同样取 db 标签, JSON 按 json 标签序列化.
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/tenantdb"
+	"github.com/jmoiron/sqlx"
+)
+
+// Source 报表读取的库
+type Source string
+
+const (
+	HR   Source = "hr"   // 人事库
+	Blog Source = "blog" // 博客库
+)
+
+// ParamKind 参数类型, 决定命令行传入的字符串如何转换
+type ParamKind int
+
+const (
+	String ParamKind = iota
+	Int
+	Date // YYYY-MM-DD, 转换为当天 0 点的本地时间
+)
+
+// Param 报表参数
+type Param struct {
+	Name    string // SQL 中的 :name
+	Kind    ParamKind
+	Default string // 未传入时的值, 为空表示必须传入
+	Help    string
+}
+
+// Formats 支持的输出格式
+var Formats = []string{"csv", "json"}
+
+// Queryer 执行查询, *sql.DB、*sqlx.DB 和 GORM 的连接池 (包括 tenantdb.Router) 都满足
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Report 报表定义, 由 define 创建
+type Report struct {
+	Name   string
+	Title  string
+	Source Source
+	Params []Param
+	SQL    string
+
+	scan func(*sql.Rows) (*Result, error)
+}
+
+// define 定义结果的每一行为 T 的报表
+func define[T any](r Report) *Report {
+	r.scan = func(rows *sql.Rows) (*Result, error) {
+		out := []T{}
+		if err := sqlx.StructScan(rows, &out); err != nil {
+			return nil, err
+		}
+		return newResult(out), nil
+	}
+	return &r
+}
+
+// Run 按 args (参数名 -> 字符串值) 执行报表, 未传入的参数取默认值. 博客库的报表有 tenant 参数时在该租户的库中执行 (见 tenantdb.Route)
+func (r *Report) Run(ctx context.Context, db Queryer, args map[string]string) (*Result, error) {
+	named, err := r.bind(args)
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := named["tenant"].(int64); ok && r.Source == Blog {
+		ctx = tenantdb.Route(ctx, uint(id))
+	}
+	query, values, err := sqlx.Named(r.SQL, named)
+	if err != nil {
+		return nil, fmt.Errorf("报表 %s 的 SQL 无效: %w", r.Name, err)
+	}
+	rows, err := db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return nil, fmt.Errorf("执行报表 %s 失败: %w", r.Name, err)
+	}
+	defer rows.Close()
+	result, err := r.scan(rows)
+	if err != nil {
+		return nil, fmt.Errorf("读取报表 %s 的结果失败: %w", r.Name, err)
+	}
+	return result, nil
+}
+
+// bind 把字符串参数按类型转换为 SQL 的命名参数
+func (r *Report) bind(args map[string]string) (map[string]any, error) {
+	named := make(map[string]any, len(r.Params))
+	for name := range args {
+		if !r.hasParam(name) {
+			return nil, fmt.Errorf("报表 %s 没有参数 %s", r.Name, name)
+		}
+	}
+	for _, p := range r.Params {
+		v, ok := args[p.Name]
+		if !ok {
+			v = p.Default
+		}
+		if v == "" {
+			return nil, fmt.Errorf("报表 %s 缺少参数 %s (%s)", r.Name, p.Name, p.Help)
+		}
+		switch p.Kind {
+		case Int:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("参数 %s 应为整数", p.Name)
+			}
+			named[p.Name] = n
+		case Date:
+			t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("参数 %s 应为 YYYY-MM-DD 格式的日期", p.Name)
+			}
+			named[p.Name] = t
+		default:
+			named[p.Name] = v
+		}
+	}
+	return named, nil
+}
+
+func (r *Report) hasParam(name string) bool {
+	for _, p := range r.Params {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Result 报表的结果
+type Result struct {
+	rows    any // []T
+	columns []string
+	values  [][]string
+}
+
+// newResult 按 T 的 db 标签整理 CSV 的表头和各行的值
+func newResult[T any](rows []T) *Result {
+	res := &Result{rows: rows}
+	t := reflect.TypeFor[T]()
+	var fields []int
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("db"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		res.columns = append(res.columns, name)
+		fields = append(fields, i)
+	}
+	for _, row := range rows {
+		v := reflect.ValueOf(row)
+		values := make([]string, len(fields))
+		for j, i := range fields {
+			values[j] = formatValue(v.Field(i))
+		}
+		res.values = append(res.values, values)
+	}
+	return res
+}
+
+// formatValue CSV 中的值: 时间为 RFC 3339, NULL (nil 指针) 为空
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch x := v.Interface().(type) {
+	case time.Time:
+		return x.Format(time.RFC3339)
+	case float32, float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// Len 结果的行数
+func (r *Result) Len() int {
+	return len(r.values)
+}
+
+// Write 按 format (Formats 之一) 把结果写入 w
+func (r *Result) Write(w io.Writer, format string) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(r.columns)
+		cw.WriteAll(r.values)
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("写入报表失败: %w", err)
+		}
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r.rows); err != nil {
+			return fmt.Errorf("写入报表失败: %w", err)
+		}
+	default:
+		return fmt.Errorf("不支持的报表格式 %q, 可选 %v", format, Formats)
+	}
+	return nil
+}
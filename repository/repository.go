@@ -0,0 +1,195 @@
+// Package repository 基于 GORM 泛型接口 (gorm.G) 的通用仓储, 提供按主键查询、列表、分页、计数和增删改,
+// 各模块的仓储嵌入 Repository[T] 后只需编写特有的查询:
+//
+//	type PostRepository struct {
+//		*repository.Repository[Post]
+//	}
+//
+// 查询条件使用 scopes 包中的 Scope, 如 repo.Page(ctx, 1, 20, scopes.ByUser(id)).
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/scopes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Options 仓储配置
+type Options struct {
+	// Name 实体名, 用于错误信息, 如 "文章"
+	Name string
+	// NotFound 记录不存在时包装返回的错误, 为 nil 时返回 gorm.ErrRecordNotFound
+	NotFound error
+	// Transaction 执行 Delete 的事务, 为 nil 时使用 db.Transaction. 删除钩子需要在提交后执行副作用时替换
+	Transaction func(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error
+}
+
+// Repository 实体 T 的通用数据访问, T 为以自增主键 id 标识的 GORM 模型.
+// *T 实现 Validate() error 时, Create 和 Update 先校验, 失败时原样返回校验错误
+type Repository[T any] struct {
+	db   *gorm.DB
+	opts Options
+}
+
+// New 创建实体 T 的仓储
+func New[T any](db *gorm.DB, opts Options) *Repository[T] {
+	if opts.NotFound == nil {
+		opts.NotFound = gorm.ErrRecordNotFound
+	}
+	if opts.Transaction == nil {
+		opts.Transaction = func(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+			return db.WithContext(ctx).Transaction(fn)
+		}
+	}
+	return &Repository[T]{db: db, opts: opts}
+}
+
+// DB 返回仓储使用的连接, 供嵌入方编写特有的查询
+func (r *Repository[T]) DB() *gorm.DB {
+	return r.db
+}
+
+// query 应用 scopes 后的泛型查询. gorm.G 的 Scopes 作用于 Statement, 这里把 scopes.Scope 转换过去,
+// Scope 需要在传入的 *gorm.DB 上追加条件 (scopes 包中的 Scope 均如此)
+func (r *Repository[T]) query(ss []scopes.Scope) gorm.ChainInterface[T] {
+	fns := make([]func(*gorm.Statement), len(ss))
+	for i, s := range ss {
+		fns[i] = func(stmt *gorm.Statement) { s(stmt.DB) }
+	}
+	return gorm.G[T](r.db).Scopes(fns...)
+}
+
+// byID 主键等于 id
+func byID(id uint) clause.Expression {
+	return clause.Eq{Column: clause.PrimaryColumn, Value: id}
+}
+
+// GetByID 按主键查询, 不存在时返回 Options.NotFound
+func (r *Repository[T]) GetByID(ctx context.Context, id uint) (*T, error) {
+	return r.Get(ctx, id)
+}
+
+// Get 同 GetByID, 先应用 ss, 如只查询部分列
+func (r *Repository[T]) Get(ctx context.Context, id uint, ss ...scopes.Scope) (*T, error) {
+	v, err := r.query(ss).Where(byID(id)).First(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%s %d: %w", r.opts.Name, id, r.opts.NotFound)
+		}
+		return nil, fmt.Errorf("查询%s失败: %w", r.opts.Name, err)
+	}
+	return &v, nil
+}
+
+// List 按主键顺序返回符合 ss 的全部记录, ss 中含 Order 时先按其排序
+func (r *Repository[T]) List(ctx context.Context, ss ...scopes.Scope) ([]T, error) {
+	list, err := r.query(ss).Order(clause.OrderByColumn{Column: clause.PrimaryColumn}).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("查询%s列表失败: %w", r.opts.Name, err)
+	}
+	return list, nil
+}
+
+// Page 返回符合 ss 的第 page 页 (从 1 开始), 页大小见 scopes.Paginate
+func (r *Repository[T]) Page(ctx context.Context, page, size int, ss ...scopes.Scope) ([]T, error) {
+	return r.List(ctx, append(ss, scopes.Paginate(page, size))...)
+}
+
+// ListAs 同 Repository.List, 但只查询 S 的字段对应的列, 结果映射到 S. S 通常是 T 的精简版本 (如不含正文的文章摘要),
+// 列表接口用它减少读取和传输的数据量. S 的字段按 GORM 的命名规则对应 T 的列, 关联字段需标记 gorm:"-"
+func ListAs[S, T any](ctx context.Context, r *Repository[T], ss ...scopes.Scope) ([]S, error) {
+	// db.Scopes 在执行时才应用, 排序会落在主键之后, 这里先逐个应用
+	tx := r.db.WithContext(ctx).Model(new(T))
+	for _, s := range ss {
+		tx = s(tx)
+	}
+	var list []S
+	err := tx.Order(clause.OrderByColumn{Column: clause.PrimaryColumn}).Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询%s列表失败: %w", r.opts.Name, err)
+	}
+	return list, nil
+}
+
+// PageAs 同 Repository.Page, 结果的列和映射同 ListAs
+func PageAs[S, T any](ctx context.Context, r *Repository[T], page, size int, ss ...scopes.Scope) ([]S, error) {
+	return ListAs[S](ctx, r, append(ss, scopes.Paginate(page, size))...)
+}
+
+// Count 返回符合 ss 的记录数
+func (r *Repository[T]) Count(ctx context.Context, ss ...scopes.Scope) (int64, error) {
+	n, err := r.query(ss).Count(ctx, "*")
+	if err != nil {
+		return 0, fmt.Errorf("统计%s数失败: %w", r.opts.Name, err)
+	}
+	return n, nil
+}
+
+// Create 校验并创建记录, 成功后回填主键
+func (r *Repository[T]) Create(ctx context.Context, v *T) error {
+	if err := validate(v); err != nil {
+		return err
+	}
+	if err := gorm.G[T](r.db).Create(ctx, v); err != nil {
+		return fmt.Errorf("创建%s失败: %w", r.opts.Name, err)
+	}
+	return nil
+}
+
+// Update 校验 v 后按主键更新指定的列, columns 不能为空. 记录不存在时返回 Options.NotFound.
+// 通过 v 更新以触发其 BeforeUpdate / AfterUpdate 钩子, 泛型接口的 Updates 不传递指针, 钩子看不到主键
+func (r *Repository[T]) Update(ctx context.Context, v *T, columns ...string) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("更新%s失败: 没有指定列", r.opts.Name)
+	}
+	if err := validate(v); err != nil {
+		return err
+	}
+	result := r.db.WithContext(ctx).Model(v).Select(columns).Updates(v)
+	if result.Error != nil {
+		return fmt.Errorf("更新%s失败: %w", r.opts.Name, result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	// MySQL 默认返回实际改变的行数, 值没有变化时也是 0, 需要再确认记录是否存在.
+	// 目标记录带有主键时 GORM 按主键查询
+	current := *v
+	if err := r.db.WithContext(ctx).Take(&current).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%s: %w", r.opts.Name, r.opts.NotFound)
+		}
+		return fmt.Errorf("查询%s失败: %w", r.opts.Name, err)
+	}
+	return nil
+}
+
+// Delete 在事务中加锁读出记录后删除, 删除钩子可以读到完整的记录. 记录不存在时返回 Options.NotFound
+func (r *Repository[T]) Delete(ctx context.Context, id uint) error {
+	err := r.opts.Transaction(ctx, r.db, func(tx *gorm.DB) error {
+		var v T
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(byID(id)).First(&v).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&v).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s %d: %w", r.opts.Name, id, r.opts.NotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("删除%s失败: %w", r.opts.Name, err)
+	}
+	return nil
+}
+
+// validate *T 实现 Validate() error 时校验 v
+func validate[T any](v *T) error {
+	if c, ok := any(v).(interface{ Validate() error }); ok {
+		return c.Validate()
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Package requestid 为每个请求生成唯一 ID 并通过 ctx 传递, HTTP 访问日志、panic 日志和 SQL 日志都带上它,
+// 同一请求的日志可以按 ID 串起来.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+// Header 请求和响应中携带请求 ID 的头
+const Header = "X-Request-ID"
+
+type key struct{}
+
+// New 生成随机的 UUID (版本 4)
+func New() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Valid 上游传入的 ID 是否可以沿用: 1-64 个字母、数字、'-'、'_' 或 '.', 其余的重新生成, 避免日志注入
+func Valid(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// With 返回携带请求 ID 的 ctx
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// From 返回 ctx 中的请求 ID, 没有时为空串
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
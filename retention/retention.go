@@ -0,0 +1,100 @@
+// Package retention 按保留期物理删除过期数据: 软删除超过保留期的记录、过期的审计日志和通知等.
+//
+// 每条 Policy 描述一张表中哪些行过期. 删除分批执行 (DELETE ... ORDER BY id LIMIT n), 每批是一个独立的短语句,
+// 批次之间可以暂停, 不会长时间持有大量行锁, 也不会产生巨大的 undo 和复制延迟. 删除经原生 SQL 执行, 不触发模型钩子,
+// 也不经租户过滤, 处理库中全部租户的数据.
+//
+// Run 返回每条策略删除的行数; DryRun 时只统计将要删除的行数.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Policy 一张表的保留策略
+type Policy struct {
+	Name  string        // 策略名, 用于报告
+	Table string        // 表名, 须有整数主键 id
+	Where string        // 过期的条件, 以唯一的 ? 代表截止时间, 如 "created_at < ?"
+	Keep  time.Duration // 保留期, 截止时间为 now - Keep; 不大于 0 时不删除
+}
+
+// Options 执行选项
+type Options struct {
+	BatchSize int           // 每批删除的行数, 不大于 0 时为 DefaultBatchSize
+	Pause     time.Duration // 批次之间的暂停, 让出锁和 IO 给在线请求
+	DryRun    bool          // 只统计, 不删除
+}
+
+// DefaultBatchSize 默认每批删除的行数
+const DefaultBatchSize = 1000
+
+// Result 一条策略的执行结果
+type Result struct {
+	Policy  string
+	Table   string
+	Cutoff  time.Time // 早于它的数据已过期
+	Deleted int64     // 删除 (DryRun 时为将要删除) 的行数
+	Batches int
+	Elapsed time.Duration
+}
+
+// String 一行报告
+func (r Result) String() string {
+	return fmt.Sprintf("%s: %s 中早于 %s 的 %d 行 (%d 批, 耗时 %s)", r.Policy, r.Table, r.Cutoff.Format(time.DateTime), r.Deleted, r.Batches, r.Elapsed.Round(time.Millisecond))
+}
+
+// Run 按 policies 删除 now 时已过期的数据, 保留期不大于 0 的策略跳过. 出错时返回已完成的策略的结果
+// (包括出错的策略已删除的部分)
+func Run(ctx context.Context, db *gorm.DB, policies []Policy, now time.Time, opts Options) ([]Result, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	db = db.WithContext(ctx)
+	var results []Result
+	for _, p := range policies {
+		if p.Keep <= 0 {
+			continue
+		}
+		start := time.Now()
+		r := Result{Policy: p.Name, Table: p.Table, Cutoff: now.Add(-p.Keep)}
+		err := run(ctx, db, p, r.Cutoff, opts, &r)
+		r.Elapsed = time.Since(start)
+		results = append(results, r)
+		if err != nil {
+			return results, fmt.Errorf("执行保留策略 %s 失败: %w", p.Name, err)
+		}
+	}
+	return results, nil
+}
+
+func run(ctx context.Context, db *gorm.DB, p Policy, cutoff time.Time, opts Options, r *Result) error {
+	table := db.Statement.Quote(p.Table)
+	if opts.DryRun {
+		return db.Raw(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table, p.Where), cutoff).Row().Scan(&r.Deleted)
+	}
+	// 按主键顺序删除, 每批锁定的是一段连续的主键
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE %s ORDER BY id LIMIT ?", table, p.Where)
+	for {
+		result := db.Exec(stmt, cutoff, opts.BatchSize)
+		if result.Error != nil {
+			return result.Error
+		}
+		r.Batches++
+		r.Deleted += result.RowsAffected
+		if result.RowsAffected < int64(opts.BatchSize) {
+			return nil
+		}
+		if opts.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(opts.Pause):
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Package saga 以 "步骤 + 补偿动作" 的方式执行跨数据库操作.
+//
+// 博客库和人事库是两个独立的 MySQL 库, 无法放进同一个事务. Saga 依次执行各步骤,
+// 某一步失败时按相反顺序执行已完成步骤的补偿动作, 把两边恢复到操作前的状态.
+// 补偿本身也可能失败, 这种情况会在 Error 中如实报告, 需要人工介入.
+package saga
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Step 一个步骤及其补偿动作, Compensate 可以为 nil (例如只读步骤)
+type Step struct {
+	Name       string
+	Action     func(ctx context.Context) error
+	Compensate func(ctx context.Context) error
+}
+
+// Saga 一组按顺序执行的步骤
+type Saga struct {
+	name      string
+	steps     []Step
+	completed []string
+}
+
+// New 创建 saga
+func New(name string) *Saga {
+	return &Saga{name: name}
+}
+
+// Step 追加一个步骤
+func (s *Saga) Step(name string, action, compensate func(ctx context.Context) error) *Saga {
+	s.steps = append(s.steps, Step{Name: name, Action: action, Compensate: compensate})
+	return s
+}
+
+// Completed 返回已成功执行且未被补偿的步骤名
+func (s *Saga) Completed() []string {
+	return s.completed
+}
+
+// Error saga 执行失败的详细信息
+type Error struct {
+	Saga             string
+	Step             string           // 失败的步骤
+	Err              error            // 步骤返回的错误
+	CompensationErrs map[string]error // 补偿失败的步骤 -> 错误
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("saga %s 在步骤 %s 失败: %v", e.Saga, e.Step, e.Err)
+	if len(e.CompensationErrs) == 0 {
+		return msg + " (已全部补偿)"
+	}
+	var failed []string
+	for step, err := range e.CompensationErrs {
+		failed = append(failed, fmt.Sprintf("%s: %v", step, err))
+	}
+	return msg + "; 以下步骤补偿失败, 需要人工处理: " + strings.Join(failed, "; ")
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Compensated 是否所有已完成步骤都补偿成功
+func (e *Error) Compensated() bool {
+	return len(e.CompensationErrs) == 0
+}
+
+// Run 依次执行全部步骤. 任一步失败时逆序补偿已完成的步骤并返回 *Error.
+// 补偿使用不随 ctx 取消的上下文, 调用方超时也不会中断补偿.
+func (s *Saga) Run(ctx context.Context) error {
+	s.completed = s.completed[:0]
+	for i, step := range s.steps {
+		if err := step.Action(ctx); err != nil {
+			return s.compensate(context.WithoutCancel(ctx), i, err)
+		}
+		s.completed = append(s.completed, step.Name)
+	}
+	return nil
+}
+
+func (s *Saga) compensate(ctx context.Context, failed int, cause error) error {
+	sagaErr := &Error{Saga: s.name, Step: s.steps[failed].Name, Err: cause}
+	var remaining []string // 补偿失败, 仍处于已完成状态的步骤
+	for i := failed - 1; i >= 0; i-- {
+		step := s.steps[i]
+		if step.Compensate == nil {
+			continue
+		}
+		if err := step.Compensate(ctx); err != nil {
+			if sagaErr.CompensationErrs == nil {
+				sagaErr.CompensationErrs = make(map[string]error)
+			}
+			sagaErr.CompensationErrs[step.Name] = err
+			remaining = append(remaining, step.Name)
+		}
+	}
+	slices.Reverse(remaining)
+	s.completed = remaining
+	return sagaErr
+}
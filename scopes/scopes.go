@@ -0,0 +1,86 @@
+// Package scopes 可复用的 GORM 查询条件, 通过 db.Scopes(...) 组合使用:
+//
+//	db.Scopes(scopes.ByUser(id), scopes.PublishedOnly, scopes.Paginate(page, size)).Find(&posts)
+//
+// 条件中的列以当前查询的表限定 (如 posts.user_id), 与 JOIN 组合时不会产生歧义.
+package scopes
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Scope GORM 查询条件
+type Scope = func(*gorm.DB) *gorm.DB
+
+// 分页的默认和最大页大小
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Published 已发布文章的状态值, 即 blog.PostPublished
+const Published = "published"
+
+// column 以当前表限定的列
+func column(name string) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
+
+// Paginate 按页码取一页, page 从 1 开始. page < 1 时取第一页, size <= 0 时为 DefaultPageSize,
+// 超过 MaxPageSize 时按上限. 需要与 Order 组合才有稳定的结果; 深翻页时 OFFSET 的代价随页码增长,
+// 大表上的连续翻页应使用 keyset (按上一页最后一行的主键继续)
+func Paginate(page, size int) Scope {
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	size = min(size, MaxPageSize)
+	page = max(page, 1)
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Offset((page - 1) * size).Limit(size)
+	}
+}
+
+// CreatedBetween created_at 在 [from, to) 之间, 零值表示该端不限
+func CreatedBetween(from, to time.Time) Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		if !from.IsZero() {
+			tx = tx.Where(clause.Gte{Column: column("created_at"), Value: from})
+		}
+		if !to.IsZero() {
+			tx = tx.Where(clause.Lt{Column: column("created_at"), Value: to})
+		}
+		return tx
+	}
+}
+
+// ByUser user_id 等于 id, 即属于该用户的记录
+func ByUser(id uint) Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(clause.Eq{Column: column("user_id"), Value: id})
+	}
+}
+
+// PublishedOnly 只保留已发布的文章 (status = Published), 定时发布和已归档的文章被排除
+func PublishedOnly(tx *gorm.DB) *gorm.DB {
+	return tx.Where(clause.Eq{Column: column("status"), Value: Published})
+}
+
+// NotDeleted 排除软删除的记录. GORM 对含 gorm.DeletedAt 字段的模型默认已加该条件,
+// 在 Unscoped 查询中仍需排除时使用; 模型没有软删除字段时不加条件
+func NotDeleted(tx *gorm.DB) *gorm.DB {
+	model := tx.Statement.Model
+	if model == nil {
+		model = tx.Statement.Dest
+	}
+	if model == nil || tx.Statement.Parse(model) != nil {
+		return tx
+	}
+	field := tx.Statement.Schema.LookUpField("DeletedAt")
+	if field == nil {
+		return tx
+	}
+	return tx.Where(clause.Eq{Column: column(field.DBName), Value: nil})
+}
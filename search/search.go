@@ -0,0 +1,244 @@
+// Package search 经 REST API 把文章同步到外部搜索引擎 (Elasticsearch 或 OpenSearch, 两者的这部分接口一致) 并在其中检索.
+//
+// 只用到索引、删除、批量写入、按查询更新和检索几个接口, 不依赖客户端库. 地址中可以带 Basic 认证的用户名和密码,
+// 如 https://user:pass@es:9200. 集群不可用 (连接失败、超时或 5xx) 时返回 ErrUnavailable,
+// 并在 Cooldown 内不再请求, 调用方直接改用数据库检索, 不必每个请求都等待超时.
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrUnavailable 搜索集群不可用
+var ErrUnavailable = errors.New("搜索引擎不可用")
+
+// Cooldown 集群不可用后暂停请求的时间
+const Cooldown = 30 * time.Second
+
+// Doc 索引中的一篇文章
+type Doc struct {
+	ID        uint      `json:"-"`
+	TenantID  uint      `json:"tenant_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	AuthorID  uint      `json:"author_id"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 索引的映射, 与 Doc 一致
+const mappings = `{
+	"mappings": {
+		"properties": {
+			"tenant_id":  {"type": "long"},
+			"title":      {"type": "text"},
+			"content":    {"type": "text"},
+			"author_id":  {"type": "long"},
+			"author":     {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+			"created_at": {"type": "date"}
+		}
+	}
+}`
+
+// Client 一个索引的客户端
+type Client struct {
+	baseURL   string
+	index     string
+	client    *http.Client
+	downUntil atomic.Int64 // 集群不可用时暂停请求到此时间 (UnixNano)
+}
+
+// NewClient 创建客户端, baseURL 为集群地址, index 为文章的索引名
+func NewClient(baseURL, index string, timeout time.Duration) *Client {
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), index: index, client: &http.Client{Timeout: timeout}}
+}
+
+// Available 是否未处于不可用后的暂停期
+func (c *Client) Available() bool {
+	return time.Now().UnixNano() >= c.downUntil.Load()
+}
+
+// EnsureIndex 索引不存在时按 Doc 的映射创建
+func (c *Client) EnsureIndex(ctx context.Context) error {
+	status, _, err := c.do(ctx, http.MethodHead, "/"+url.PathEscape(c.index), "", nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		return nil
+	}
+	_, _, err = c.do(ctx, http.MethodPut, "/"+url.PathEscape(c.index), "application/json", strings.NewReader(mappings))
+	return err
+}
+
+// Index 写入或替换一篇文章
+func (c *Client) Index(ctx context.Context, doc Doc) error {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("序列化文章 %d 失败: %w", doc.ID, err)
+	}
+	_, _, err = c.do(ctx, http.MethodPut, c.docPath(doc.ID), "application/json", bytes.NewReader(body))
+	return err
+}
+
+// Delete 从索引中删除文章, 不存在时什么都不做
+func (c *Client) Delete(ctx context.Context, id uint) error {
+	status, data, err := c.send(ctx, http.MethodDelete, c.docPath(id), "", nil)
+	if err != nil || status == http.StatusNotFound || status/100 == 2 {
+		return err
+	}
+	return fmt.Errorf("从索引删除文章 %d 失败: HTTP %d %s", id, status, data)
+}
+
+// Bulk 批量写入或替换文章
+func (c *Client) Bulk(ctx context.Context, docs []Doc) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, doc := range docs {
+		action := map[string]any{"index": map[string]string{"_index": c.index, "_id": strconv.FormatUint(uint64(doc.ID), 10)}}
+		if err := enc.Encode(action); err != nil {
+			return fmt.Errorf("序列化批量请求失败: %w", err)
+		}
+		if err := enc.Encode(doc); err != nil {
+			return fmt.Errorf("序列化文章 %d 失败: %w", doc.ID, err)
+		}
+	}
+	_, data, err := c.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &buf)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("解析批量写入的结果失败: %w", err)
+	}
+	if result.Errors {
+		return fmt.Errorf("批量写入索引时部分文章失败: %.500s", data)
+	}
+	return nil
+}
+
+// UpdateAuthor 把作者 authorID 的全部文章中的作者名改为 name
+func (c *Client) UpdateAuthor(ctx context.Context, authorID uint, name string) error {
+	body, err := json.Marshal(map[string]any{
+		"query":  map[string]any{"term": map[string]any{"author_id": authorID}},
+		"script": map[string]any{"source": "ctx._source.author = params.name", "lang": "painless", "params": map[string]any{"name": name}},
+	})
+	if err != nil {
+		return fmt.Errorf("序列化更新请求失败: %w", err)
+	}
+	_, _, err = c.do(ctx, http.MethodPost, "/"+url.PathEscape(c.index)+"/_update_by_query?conflicts=proceed", "application/json", bytes.NewReader(body))
+	return err
+}
+
+// Search 在租户 tenantID 的文章中按标题、正文和作者检索, 按相关度返回第 from 条起的 size 篇文章的 ID
+func (c *Client) Search(ctx context.Context, tenantID uint, query string, from, size int) ([]uint, error) {
+	body, err := json.Marshal(map[string]any{
+		"from":    from,
+		"size":    size,
+		"_source": false,
+		"query": map[string]any{
+			"bool": map[string]any{
+				"must": map[string]any{"multi_match": map[string]any{
+					"query":  query,
+					"fields": []string{"title^3", "author^2", "content"},
+				}},
+				"filter": []any{map[string]any{"term": map[string]any{"tenant_id": tenantID}}},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化检索请求失败: %w", err)
+	}
+	_, data, err := c.do(ctx, http.MethodPost, "/"+url.PathEscape(c.index)+"/_search", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析检索结果失败: %w", err)
+	}
+	ids := make([]uint, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		id, err := strconv.ParseUint(hit.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("索引中的文章 ID %q 不是整数", hit.ID)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+func (c *Client) docPath(id uint) string {
+	return "/" + url.PathEscape(c.index) + "/_doc/" + strconv.FormatUint(uint64(id), 10)
+}
+
+// do 发送请求, 非 2xx 且不是 HEAD 的 404 时返回错误
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader) (int, []byte, error) {
+	status, data, err := c.send(ctx, method, path, contentType, body)
+	if err != nil {
+		return status, data, err
+	}
+	if status/100 != 2 && !(method == http.MethodHead && status == http.StatusNotFound) {
+		return status, data, fmt.Errorf("搜索引擎请求 %s %s 失败: HTTP %d %.500s", method, path, status, data)
+	}
+	return status, data, nil
+}
+
+// send 发送请求并读出响应体. 连接失败、超时和 5xx 视为集群不可用, 返回 ErrUnavailable 并暂停之后的请求
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader) (int, []byte, error) {
+	if !c.Available() {
+		return 0, nil, ErrUnavailable
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("创建搜索引擎请求失败: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, nil, ctx.Err()
+		}
+		c.markDown()
+		return 0, nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.markDown()
+		return 0, nil, fmt.Errorf("%w: 读取响应失败: %v", ErrUnavailable, err)
+	}
+	if resp.StatusCode >= 500 {
+		c.markDown()
+		return resp.StatusCode, data, fmt.Errorf("%w: HTTP %d %.500s", ErrUnavailable, resp.StatusCode, data)
+	}
+	return resp.StatusCode, data, nil
+}
+
+func (c *Client) markDown() {
+	c.downUntil.Store(time.Now().Add(Cooldown).UnixNano())
+}
@@ -0,0 +1,292 @@
+package sharding
+
+import (
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Plugin 把逻辑表上由 GORM 生成的语句路由到分表, 见包文档
+type Plugin struct {
+	tables map[string]Table
+	conds  map[string]*regexp.Regexp // 各表在 Where("post_id = ?") 这类字符串条件中匹配分片键
+}
+
+// NewPlugin 创建 GORM 插件, 通过 db.Use 注册; 不分表的 Table 被忽略
+func NewPlugin(tables ...Table) *Plugin {
+	p := &Plugin{tables: map[string]Table{}, conds: map[string]*regexp.Regexp{}}
+	for _, t := range tables {
+		if !t.Sharded() {
+			continue
+		}
+		p.tables[t.Name] = t
+		name, key := regexp.QuoteMeta(t.Name), regexp.QuoteMeta(t.Key)
+		p.conds[t.Name] = regexp.MustCompile("(?i)(?:^|[^\\w.`])(?:`?" + name + "`?\\.)?`?" + key + "`?\\s*(=|IN)\\s*\\(?\\?")
+	}
+	return p
+}
+
+// Name 实现 gorm.Plugin
+func (p *Plugin) Name() string {
+	return "sharding"
+}
+
+// Initialize 实现 gorm.Plugin
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []error{
+		cb.Create().Before("gorm:create").Register("sharding:create", p.routeCreate),
+		cb.Query().Before("gorm:query").Register("sharding:query", p.route(false)),
+		cb.Update().Before("gorm:update").Register("sharding:update", p.route(true)),
+		cb.Delete().Before("gorm:delete").Register("sharding:delete", p.route(true)),
+		cb.Row().Before("gorm:row").Register("sharding:row", p.route(false)),
+	}
+	for _, err := range hooks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 记录插件设置的 TableExpr, 以区分调用方通过 Table("(?) AS comments", ...) 设置的表达式
+const settingExpr = "sharding:table_expr"
+
+// table 语句访问的分表的配置; 不是分表的逻辑表、原生 SQL 或调用方自行指定了表达式时返回 false
+func (p *Plugin) table(db *gorm.DB) (Table, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.SQL.Len() > 0 {
+		return Table{}, false
+	}
+	t, ok := p.tables[stmt.Table]
+	if !ok {
+		return Table{}, false
+	}
+	if stmt.TableExpr != nil {
+		if own, _ := stmt.Settings.Load(settingExpr); own != stmt.TableExpr {
+			return Table{}, false
+		}
+	}
+	return t, true
+}
+
+// use 让语句访问 expr
+func use(db *gorm.DB, expr string) {
+	e := &clause.Expr{SQL: expr}
+	db.Statement.TableExpr = e
+	db.Statement.Settings.Store(settingExpr, e)
+}
+
+// routeCreate 按记录的分片键选择分表, 全部记录必须在同一张分表
+func (p *Plugin) routeCreate(db *gorm.DB) {
+	t, ok := p.table(db)
+	if !ok {
+		return
+	}
+	keys, complete := p.valueKeys(db, t)
+	if !complete || len(keys) == 0 {
+		db.AddError(ErrMissingKey)
+		return
+	}
+	shards := shardsOf(t, keys)
+	if len(shards) > 1 {
+		db.AddError(ErrCrossShard)
+		return
+	}
+	use(db, db.Statement.Quote(t.ShardName(shards[0])))
+}
+
+// route 按条件中的分片键选择分表. 写操作还可以从 Model 取得分片键 (如 Model(&comment).Update)
+func (p *Plugin) route(write bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		t, ok := p.table(db)
+		if !ok {
+			return
+		}
+		keys := p.whereKeys(db, t)
+		if len(keys) == 0 && write {
+			if vk, complete := p.valueKeys(db, t); complete {
+				keys = vk
+			}
+		}
+		_, locking := db.Statement.Clauses["FOR"]
+		shards := shardsOf(t, keys)
+		if len(shards) == 1 {
+			use(db, db.Statement.Quote(t.ShardName(shards[0]))+" AS "+db.Statement.Quote(t.Name))
+			return
+		}
+		switch {
+		case write && len(shards) > 1:
+			db.AddError(ErrCrossShard)
+		case write || locking:
+			db.AddError(ErrMissingKey)
+		default:
+			if len(shards) == 0 {
+				shards = allShards(t)
+			}
+			names := make([]string, len(shards))
+			for i, s := range shards {
+				names[i] = t.ShardName(s)
+			}
+			use(db, union(names, func(s string) string { return db.Statement.Quote(s) })+" AS "+db.Statement.Quote(t.Name))
+		}
+	}
+}
+
+// valueKeys 要写入的记录 (或 Model) 的分片键, 有记录的分片键为零值时 complete 为 false
+func (p *Plugin) valueKeys(db *gorm.DB, t Table) (keys []uint64, complete bool) {
+	stmt := db.Statement
+	if stmt.Schema == nil {
+		return nil, false
+	}
+	f := stmt.Schema.LookUpField(t.Key)
+	if f == nil {
+		return nil, false
+	}
+	complete = true
+	one := func(rv reflect.Value) {
+		v, zero := f.ValueOf(stmt.Context, rv)
+		key, ok := toKey(v)
+		if zero || !ok {
+			complete = false
+			return
+		}
+		keys = append(keys, key)
+	}
+	rv := stmt.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+				one(elem)
+			}
+		}
+	case reflect.Struct:
+		one(rv)
+	default:
+		complete = false
+	}
+	return keys, complete
+}
+
+// whereKeys 顶层 AND 条件中分片键的取值. 在 OR 中的条件不能限定分表, 不予采用
+func (p *Plugin) whereKeys(db *gorm.DB, t Table) []uint64 {
+	c, ok := db.Statement.Clauses["WHERE"]
+	if !ok {
+		return nil
+	}
+	where, ok := c.Expression.(clause.Where)
+	if !ok {
+		return nil
+	}
+	return p.exprKeys(t, where.Exprs)
+}
+
+func (p *Plugin) exprKeys(t Table, exprs []clause.Expression) []uint64 {
+	for _, e := range exprs {
+		var values []any
+		switch e := e.(type) {
+		case clause.Eq:
+			if isKey(t, e.Column) {
+				values = []any{e.Value}
+			}
+		case clause.IN:
+			if isKey(t, e.Column) {
+				values = e.Values
+			}
+		case clause.Expr:
+			values = p.sqlKeys(t, e)
+		case clause.AndConditions:
+			if keys := p.exprKeys(t, e.Exprs); len(keys) > 0 {
+				return keys
+			}
+		}
+		if keys, ok := toKeys(values); ok && len(keys) > 0 {
+			return keys
+		}
+	}
+	return nil
+}
+
+// sqlKeys 字符串条件中分片键对应的参数
+func (p *Plugin) sqlKeys(t Table, e clause.Expr) []any {
+	if strings.Contains(strings.ToUpper(e.SQL), " OR ") {
+		return nil
+	}
+	loc := p.conds[t.Name].FindStringIndex(e.SQL)
+	if loc == nil {
+		return nil
+	}
+	i := strings.Count(e.SQL[:loc[1]], "?") - 1
+	if i >= len(e.Vars) {
+		return nil
+	}
+	rv := reflect.ValueOf(e.Vars[i])
+	if rv.Kind() != reflect.Slice {
+		return []any{e.Vars[i]}
+	}
+	values := make([]any, rv.Len())
+	for j := range values {
+		values[j] = rv.Index(j).Interface()
+	}
+	return values
+}
+
+// isKey column 是否为 t 的分片键列
+func isKey(t Table, column any) bool {
+	switch c := column.(type) {
+	case string:
+		return c == t.Key || c == t.Name+"."+t.Key
+	case clause.Column:
+		return c.Name == t.Key && (c.Table == "" || c.Table == clause.CurrentTable || c.Table == t.Name)
+	}
+	return false
+}
+
+// toKeys 把条件中的取值转换为分片键, 有不是整数的取值时返回 false
+func toKeys(values []any) ([]uint64, bool) {
+	keys := make([]uint64, 0, len(values))
+	for _, v := range values {
+		key, ok := toKey(v)
+		if !ok {
+			return nil, false
+		}
+		keys = append(keys, key)
+	}
+	return keys, true
+}
+
+func toKey(v any) (uint64, bool) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(rv.Int()), rv.Int() >= 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), true
+	}
+	return 0, false
+}
+
+// shardsOf keys 所在的分表序号, 升序去重
+func shardsOf(t Table, keys []uint64) []int {
+	var shards []int
+	for _, key := range keys {
+		if s := t.Shard(key); !slices.Contains(shards, s) {
+			shards = append(shards, s)
+		}
+	}
+	slices.Sort(shards)
+	return shards
+}
+
+func allShards(t Table) []int {
+	shards := make([]int, t.Shards)
+	for i := range shards {
+		shards[i] = i
+	}
+	return shards
+}
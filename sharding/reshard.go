@@ -0,0 +1,64 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Reshard 把 from 布局的数据搬迁到 to 布局 (表名和分片键相同, 分表数量不同), 返回搬迁的行数.
+// 不分表时数据在 Name 中, 因此也用于首次分表 (from.Shards 为 1) 和取消分表 (to.Shards 为 1).
+//
+// 逐张源表按主键 id 分批读取, 每批中分表发生变化的行在一个事务中复制到目标分表并从源表删除, 中断后可以重新执行.
+// 搬迁期间应停止写入; 完成后按 to 修改分表数量并重启. 缩减分表数量后多出的分表已经为空, 不会自动删除
+func Reshard(ctx context.Context, db *gorm.DB, from, to Table, batch int) (int64, error) {
+	if from.Name != to.Name || from.Key != to.Key {
+		return 0, errors.New("只能在同一张表的分表布局之间搬迁")
+	}
+	db = db.WithContext(ctx)
+	if err := to.Create(db); err != nil {
+		return 0, err
+	}
+	q := db.Statement.Quote
+	var moved int64
+	for _, source := range from.Names() {
+		var last uint64
+		for {
+			var rows []struct {
+				ID  uint64
+				Key uint64
+			}
+			sql := fmt.Sprintf("SELECT id, %s AS `key` FROM %s WHERE id > ? ORDER BY id LIMIT ?", q(from.Key), q(source))
+			if err := db.Raw(sql, last, batch).Scan(&rows).Error; err != nil {
+				return moved, fmt.Errorf("读取 %s 失败: %w", source, err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+			last = rows[len(rows)-1].ID
+
+			targets := map[string][]uint64{}
+			for _, r := range rows {
+				if target := to.TableFor(r.Key); target != source {
+					targets[target] = append(targets[target], r.ID)
+				}
+			}
+			for target, ids := range targets {
+				err := db.Transaction(func(tx *gorm.DB) error {
+					insert := fmt.Sprintf("INSERT INTO %s SELECT * FROM %s WHERE id IN ?", q(target), q(source))
+					if err := tx.Exec(insert, ids).Error; err != nil {
+						return err
+					}
+					return tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id IN ?", q(source)), ids).Error
+				})
+				if err != nil {
+					return moved, fmt.Errorf("从 %s 搬迁到 %s 失败: %w", source, target, err)
+				}
+				moved += int64(len(ids))
+			}
+		}
+	}
+	return moved, nil
+}
@@ -0,0 +1,119 @@
+// Package sharding 按分片键的哈希把一张大表水平拆分到 N 张结构相同的分表 (如 comments 拆为 comments_0 ~ comments_7).
+//
+// Plugin 改写 GORM 生成的语句访问的表: 能从写入的记录、Model 或条件 (key = ?、key IN ?) 确定分片键时只访问对应的分表;
+// 确定不了时读操作查询相关分表的 UNION ALL, 别名仍为逻辑表名, 条件、排序和列的限定照常生效.
+// 写操作和加锁读取必须确定到一张分表, 否则返回 ErrMissingKey; 一次创建跨多张分表的记录返回 ErrCrossShard,
+// 调用方按 Table.Shard 分组后分别创建. 原生 SQL 不经改写, 用 Table.From 取得表.
+//
+// 分表以逻辑表 (模板表, 仍由 AutoMigrate 维护, 分表后不再存放数据) 为模板用 CREATE TABLE ... LIKE 创建 (见 Table.Create),
+// 因此不带外键; 模板表之后的结构变更不会同步到已有的分表. 改变分表数量时用 Reshard 搬迁数据.
+//
+// 分表各自的自增计数器会产生重复的主键, 分表后主键须由应用生成 (如 snowflake).
+package sharding
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrMissingKey 写操作或加锁读取无法确定分片键
+var ErrMissingKey = errors.New("分表的写操作和加锁读取必须指定分片键")
+
+// ErrCrossShard 一条语句写入多张分表
+var ErrCrossShard = errors.New("一条语句不能写入多张分表")
+
+// Table 分表的配置
+type Table struct {
+	Name   string // 逻辑表名, 也是创建分表的模板
+	Key    string // 分片键列, 取值为整数
+	Shards int    // 分表数量, 不超过 1 时不分表, 直接使用 Name
+}
+
+// Sharded 是否分表
+func (t Table) Sharded() bool {
+	return t.Shards > 1
+}
+
+// Shard 分片键 key 所在分表的序号. 按哈希取模, 连续或按时间生成的键 (如 snowflake) 也能均匀分布
+func (t Table) Shard(key uint64) int {
+	if !t.Sharded() {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write(binary.BigEndian.AppendUint64(nil, key))
+	return int(h.Sum64() % uint64(t.Shards))
+}
+
+// ShardName 第 i 张分表的表名, 不分表时为 Name
+func (t Table) ShardName(i int) string {
+	if !t.Sharded() {
+		return t.Name
+	}
+	return fmt.Sprintf("%s_%d", t.Name, i)
+}
+
+// TableFor 分片键 key 所在分表的表名
+func (t Table) TableFor(key uint64) string {
+	return t.ShardName(t.Shard(key))
+}
+
+// Names 全部分表的表名
+func (t Table) Names() []string {
+	if !t.Sharded() {
+		return []string{t.Name}
+	}
+	names := make([]string, t.Shards)
+	for i := range names {
+		names[i] = t.ShardName(i)
+	}
+	return names
+}
+
+// From 原生 SQL 中代替表名的表引用, 别名为 alias (为空时取 Name). 分表时为全部分表的 UNION ALL,
+// 能确定分片键时应改用 TableFor
+func (t Table) From(alias string) string {
+	if alias == "" {
+		alias = t.Name
+	}
+	if !t.Sharded() {
+		if alias == t.Name {
+			return t.Name
+		}
+		return t.Name + " AS " + alias
+	}
+	return union(t.Names(), func(s string) string { return s }) + " AS " + alias
+}
+
+// union 分表的 UNION ALL 子查询
+func union(names []string, quote func(string) string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(" UNION ALL ")
+		}
+		b.WriteString("SELECT * FROM ")
+		b.WriteString(quote(name))
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// Create 以 Name 为模板创建尚不存在的分表, 可重复执行. 不分表时什么都不做
+func (t Table) Create(db *gorm.DB) error {
+	if !t.Sharded() {
+		return nil
+	}
+	for _, name := range t.Names() {
+		sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s LIKE %s", db.Statement.Quote(name), db.Statement.Quote(t.Name))
+		if err := db.Exec(sql).Error; err != nil {
+			return fmt.Errorf("创建分表 %s 失败: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Package shareddb 为同时使用博客库 (GORM) 和人事库 (sqlx) 的服务打开连接.
+//
+// 设置 DB_SHARED_POOL=true 且两者的配置连接同一个库 (config.Database.SameDatabase, 通常是设置了 DB_NAME) 时,
+// 先由 sqlx 打开一个连接池, GORM 通过 blog.OpenConn 在其上打开, 进程对 MySQL 只保持一个连接池.
+// 共用的连接池使用博客库的连接参数 (blog.DSNParams) 和连接池配置, 以 "blog" 登记到 dbpool.
+// 未开启或库不同时各自打开连接池, 与之前相同.
+package shareddb
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/alexwang789/Base1_golang_task3/blog"
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/employee"
+	"github.com/jmoiron/sqlx"
+	"gorm.io/gorm"
+)
+
+// Databases 博客库和人事库的连接
+type Databases struct {
+	Blog *gorm.DB
+	// HR 人事库, 单独连接失败时为 nil, 原因见 HRErr; 人事库不是必需的, 调用方可以降级提供服务
+	HR    *sqlx.DB
+	HRErr error
+	// Shared 两者是否共用一个连接池
+	Shared bool
+}
+
+// Open 打开博客库和人事库. 博客库连接失败时返回错误; 人事库单独连接失败时记录在 HRErr 中
+func Open() (*Databases, error) {
+	blogCfg, hrCfg := config.LoadDatabase("blog_db"), config.LoadDatabase("company_db")
+	if blogCfg.SharedPool {
+		if blogCfg.SameDatabase(hrCfg) {
+			return openShared(blogCfg)
+		}
+		log.Printf("DB_SHARED_POOL 已开启, 但博客库 %s 和人事库 %s 不是同一个库, 仍使用各自的连接池", blogCfg.Name, hrCfg.Name)
+	}
+
+	db, err := blog.Open()
+	if err != nil {
+		return nil, err
+	}
+	d := &Databases{Blog: db}
+	d.HR, d.HRErr = employee.OpenSqlx(hrCfg.DSN(employee.DSNParams))
+	return d, nil
+}
+
+func openShared(cfg config.Database) (*Databases, error) {
+	hr, err := employee.OpenSqlx(cfg.DSN(blog.DSNParams))
+	if err != nil {
+		return nil, fmt.Errorf("数据库连接失败: %w", err)
+	}
+	db, err := blog.OpenConn(hr.DB)
+	if err != nil {
+		hr.Close()
+		return nil, err
+	}
+	fmt.Println("🔗 博客库和人事库共用一个连接池")
+	return &Databases{Blog: db, HR: hr, Shared: true}, nil
+}
+
+// Close 关闭连接, 共用时连接池只关闭一次
+func (d *Databases) Close() {
+	blog.Close(d.Blog)
+	if d.HR != nil && !d.Shared {
+		if err := d.HR.Close(); err != nil {
+			log.Printf("关闭人事库连接失败: %v", err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package stmtcache 按查询文本缓存 sqlx 预编译语句, 重复执行同一查询时跳过服务端的解析和计划.
+//
+// 预编译语句由 database/sql 在连接池的各个连接上按需重新准备, 缓存本身与连接无关,
+// 可在多个 goroutine 间共享. 缓存只应用于固定的查询文本, 动态拼接的 SQL 会让缓存无限增长.
+package stmtcache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Cache 一个数据库连接上的预编译语句缓存
+type Cache struct {
+	db *sqlx.DB
+
+	mu    sync.RWMutex
+	stmts map[string]*sqlx.Stmt
+	named map[string]*sqlx.NamedStmt
+}
+
+// New 创建缓存
+func New(db *sqlx.DB) *Cache {
+	return &Cache{
+		db:    db,
+		stmts: make(map[string]*sqlx.Stmt),
+		named: make(map[string]*sqlx.NamedStmt),
+	}
+}
+
+// DB 返回底层连接
+func (c *Cache) DB() *sqlx.DB {
+	return c.db
+}
+
+// Preparex 返回 query 的预编译语句, 首次调用时准备并缓存
+func (c *Cache) Preparex(query string) (*sqlx.Stmt, error) {
+	c.mu.RLock()
+	stmt, ok := c.stmts[query]
+	c.mu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stmt, ok := c.stmts[query]; ok { // 等锁期间可能已被其他 goroutine 准备好
+		return stmt, nil
+	}
+	stmt, err := c.db.Preparex(query)
+	if err != nil {
+		return nil, fmt.Errorf("预编译语句失败: %w", err)
+	}
+	c.stmts[query] = stmt
+	return stmt, nil
+}
+
+// PrepareNamed 返回使用 :name 参数的预编译语句, 首次调用时准备并缓存
+func (c *Cache) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	c.mu.RLock()
+	stmt, ok := c.named[query]
+	c.mu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stmt, ok := c.named[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := c.db.PrepareNamed(query)
+	if err != nil {
+		return nil, fmt.Errorf("预编译语句失败: %w", err)
+	}
+	c.named[query] = stmt
+	return stmt, nil
+}
+
+// Len 返回已缓存的语句数
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.stmts) + len(c.named)
+}
+
+// Close 关闭全部缓存的语句, 不关闭底层连接
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for q, stmt := range c.stmts {
+		errs = append(errs, stmt.Close())
+		delete(c.stmts, q)
+	}
+	for q, stmt := range c.named {
+		errs = append(errs, stmt.Close())
+		delete(c.named, q)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,127 @@
+// Package storage 上传文件的存储, 目前实现本地磁盘, 接口按对象存储的语义设计,
+// 之后接入 S3 兼容的存储时只需新增实现.
+//
+// 对象以 key 寻址, key 由调用方生成, 使用 "/" 分隔的相对路径, 不允许包含 ".." 等路径成分.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// Store 对象存储
+type Store interface {
+	// Put 写入对象, 同名对象被覆盖. 写入失败时不留下部分内容
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open 读取对象, 对象不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象, 对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey key 为规范的相对路径: 各段不为空、"." 或 ".."
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.ContainsRune(key, '\\') {
+		return false
+	}
+	// Clean 消去中间的 "." 和 "..", 规范的路径只可能以 ".." 开头
+	return path.Clean(key) == key && key != "." && key != ".." && !strings.HasPrefix(key, "../")
+}
+
+// Local 把对象保存为 dir 下的文件, key 中的 "/" 对应子目录
+type Local struct {
+	dir string
+}
+
+// NewLocal 创建本地磁盘存储, dir 不存在时创建
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建存储目录失败: %w", err)
+	}
+	return &Local{dir: dir}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", fmt.Errorf("无效的对象 key %q", key)
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put 先写入同目录下的临时文件再改名, 读取方不会看到写了一半的对象
+func (l *Local) Put(ctx context.Context, key string, r io.Reader) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("创建存储目录失败: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(f.Name()) // 改名成功后删除不存在的文件, 忽略错误
+
+	if _, err := io.Copy(f, contextReader{ctx, r}); err != nil {
+		f.Close()
+		return fmt.Errorf("写入对象 %s 失败: %w", key, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("写入对象 %s 失败: %w", key, err)
+	}
+	if err := os.Rename(f.Name(), p); err != nil {
+		return fmt.Errorf("保存对象 %s 失败: %w", key, err)
+	}
+	return nil
+}
+
+// Open 打开对象对应的文件
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取对象 %s 失败: %w", key, err)
+	}
+	return f, nil
+}
+
+// Delete 删除对象对应的文件, 不清理留空的子目录
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("删除对象 %s 失败: %w", key, err)
+	}
+	return nil
+}
+
+// contextReader 在 ctx 取消后停止读取, 客户端断开时中止上传
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
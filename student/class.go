@@ -0,0 +1,56 @@
+package student
+
+import "errors"
+
+// Class 班级, 一个班级有多名学生. 同一年级内班级名唯一
+type Class struct {
+	ID       uint      `gorm:"primaryKey"`
+	Grade    string    `gorm:"size:20;not null;uniqueIndex:idx_classes_grade_name,priority:1"` // 年级, 如 "三年级"
+	Name     string    `gorm:"size:50;not null;uniqueIndex:idx_classes_grade_name,priority:2"` // 班级名, 如 "2 班"
+	Capacity int       `gorm:"not null;default:0"`                                             // 人数上限, 0 表示不限
+	Students []Student // 一对多关系: 班级 -> 学生
+}
+
+var (
+	// ErrClassNotFound 班级不存在
+	ErrClassNotFound = errors.New("班级不存在")
+	// ErrClassFull 班级人数已达上限
+	ErrClassFull = errors.New("班级人数已满")
+	// ErrAlreadyEnrolled 学生已分班, 换班应使用 Transfer
+	ErrAlreadyEnrolled = errors.New("学生已分班")
+	// ErrNotEnrolled 学生尚未分班
+	ErrNotEnrolled = errors.New("学生尚未分班")
+)
+
+// ClassSize 一个班级的人数
+type ClassSize struct {
+	ClassID  uint
+	Grade    string
+	Name     string
+	Capacity int
+	Students int64
+}
+
+// ClassStats 班级人数统计
+type ClassStats struct {
+	Classes    []ClassSize // 按年级、班级名排序
+	Enrolled   int64       // 已分班的学生数
+	Unassigned int64       // 尚未分班的学生数
+	MinSize    int64       // 人数最少的班级的人数, 没有班级时为 0
+	MaxSize    int64
+	AvgSize    float64
+}
+
+// summarize 按各班人数计算最少、最多和平均人数
+func (s *ClassStats) summarize() {
+	for i, c := range s.Classes {
+		s.Enrolled += c.Students
+		if i == 0 || c.Students < s.MinSize {
+			s.MinSize = c.Students
+		}
+		s.MaxSize = max(s.MaxSize, c.Students)
+	}
+	if len(s.Classes) > 0 {
+		s.AvgSize = float64(s.Enrolled) / float64(len(s.Classes))
+	}
+}
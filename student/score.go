@@ -0,0 +1,187 @@
+package student
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Score 学生一个学期一门科目的成绩, 同一学生同一学期同一科目只有一条, 重复录入时覆盖
+type Score struct {
+	ID        uint     `gorm:"primaryKey"`
+	StudentID uint     `gorm:"not null;uniqueIndex:idx_scores_student_term_subject,priority:1"`
+	Student   *Student `gorm:"constraint:OnDelete:CASCADE"`                                                   // 学生删除时成绩随之删除
+	Term      string   `gorm:"size:20;not null;uniqueIndex:idx_scores_student_term_subject,priority:2;index"` // 学期, 如 "2024-秋"
+	Subject   string   `gorm:"size:50;not null;uniqueIndex:idx_scores_student_term_subject,priority:3"`       // 科目, 如 "数学"
+	Score     float64  `gorm:"type:decimal(5,2);not null"`                                                    // 百分制, 0 ~ 100
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// 成绩的范围
+const (
+	MinScore = 0
+	MaxScore = 100
+)
+
+// gradePointSQL 百分制成绩换算为 4 分制绩点的 SQL 表达式 (常用的 4.0 标准), 成绩列为 score
+const gradePointSQL = `CASE
+	WHEN score >= 90 THEN 4.0 WHEN score >= 85 THEN 3.7 WHEN score >= 82 THEN 3.3
+	WHEN score >= 78 THEN 3.0 WHEN score >= 75 THEN 2.7 WHEN score >= 72 THEN 2.3
+	WHEN score >= 68 THEN 2.0 WHEN score >= 64 THEN 1.5 WHEN score >= 60 THEN 1.0
+	ELSE 0 END`
+
+// Transcript 学生的成绩单. 平均分和绩点各科等权, 不计学分
+type Transcript struct {
+	Student *Student
+	Term    string  // 为空表示全部学期
+	Scores  []Score // 按学期、科目排序
+	Average float64 // 平均分, 没有成绩时为 0
+	GPA     float64 // 平均绩点, 见 gradePointSQL
+}
+
+// StudentAverage 班级中一名学生的平均成绩
+type StudentAverage struct {
+	StudentID uint
+	Name      string
+	Subjects  int64 // 有成绩的科目数 (跨学期时按科次计)
+	Average   float64
+	GPA       float64
+}
+
+// SubjectStats 班级一门科目的成绩分布: 优秀 [90, 100], 良好 [80, 90), 中等 [70, 80), 及格 [60, 70), 不及格 [0, 60)
+type SubjectStats struct {
+	Subject   string
+	Count     int64
+	Average   float64
+	Min       float64
+	Max       float64
+	Excellent int64
+	Good      int64
+	Fair      int64
+	Pass      int64
+	Fail      int64
+}
+
+// PassRate 及格率, 0 ~ 1
+func (s SubjectStats) PassRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Count-s.Fail) / float64(s.Count)
+}
+
+// validateScores 校验批量录入的成绩, 返回第一条不合法的成绩的错误
+func validateScores(scores []Score) error {
+	for i := range scores {
+		if err := scores[i].Validate(); err != nil {
+			return fmt.Errorf("第 %d 条成绩: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// 批量录入时每条 INSERT 的行数
+const scoreBatchSize = 200
+
+func (s *GormStore) RecordScores(ctx context.Context, scores []Score) error {
+	if len(scores) == 0 {
+		return nil
+	}
+	if err := validateScores(scores); err != nil {
+		return err
+	}
+	return s.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "student_id"}, {Name: "term"}, {Name: "subject"}},
+			DoUpdates: clause.AssignmentColumns([]string{"score", "updated_at"}),
+		}).CreateInBatches(scores, scoreBatchSize).Error
+		if err != nil {
+			return fmt.Errorf("录入成绩失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// termFilter term 不为空时只保留该学期的成绩
+func termFilter(term string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if term == "" {
+			return tx
+		}
+		return tx.Where("scores.term = ?", term)
+	}
+}
+
+func (s *GormStore) Transcript(ctx context.Context, studentID uint, term string) (*Transcript, error) {
+	st, err := s.GetByID(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	t := &Transcript{Student: st, Term: term}
+	db := s.DB().WithContext(ctx)
+	err = db.Scopes(termFilter(term)).Where("student_id = ?", studentID).Order("term, subject").Find(&t.Scores).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询成绩失败: %w", err)
+	}
+	var avg struct {
+		Average float64
+		GPA     float64
+	}
+	err = db.Model(&Score{}).
+		Select("COALESCE(AVG(score), 0) AS average, COALESCE(AVG("+gradePointSQL+"), 0) AS gpa").
+		Scopes(termFilter(term)).
+		Where("student_id = ?", studentID).
+		Scan(&avg).Error
+	if err != nil {
+		return nil, fmt.Errorf("计算平均成绩失败: %w", err)
+	}
+	t.Average, t.GPA = avg.Average, avg.GPA
+	return t, nil
+}
+
+func (s *GormStore) ClassAverages(ctx context.Context, classID uint, term string) ([]StudentAverage, error) {
+	if _, err := s.GetClass(ctx, classID); err != nil {
+		return nil, err
+	}
+	var list []StudentAverage
+	err := s.DB().WithContext(ctx).Model(&Score{}).
+		Select("students.id AS student_id, students.name, COUNT(*) AS subjects, AVG(scores.score) AS average, AVG("+gradePointSQL+") AS gpa").
+		Joins("JOIN students ON students.id = scores.student_id").
+		Where("students.class_id = ?", classID).
+		Scopes(termFilter(term)).
+		Group("students.id, students.name").
+		Order("average DESC, students.id").
+		Scan(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("计算班级平均成绩失败: %w", err)
+	}
+	return list, nil
+}
+
+func (s *GormStore) SubjectDistribution(ctx context.Context, classID uint, term string) ([]SubjectStats, error) {
+	if _, err := s.GetClass(ctx, classID); err != nil {
+		return nil, err
+	}
+	var list []SubjectStats
+	err := s.DB().WithContext(ctx).Model(&Score{}).
+		Select(`scores.subject, COUNT(*) AS count, AVG(scores.score) AS average, MIN(scores.score) AS min, MAX(scores.score) AS max,
+			SUM(scores.score >= 90) AS excellent,
+			SUM(scores.score >= 80 AND scores.score < 90) AS good,
+			SUM(scores.score >= 70 AND scores.score < 80) AS fair,
+			SUM(scores.score >= 60 AND scores.score < 70) AS pass,
+			SUM(scores.score < 60) AS fail`).
+		Joins("JOIN students ON students.id = scores.student_id").
+		Where("students.class_id = ?", classID).
+		Scopes(termFilter(term)).
+		Group("scores.subject").
+		Order("scores.subject").
+		Scan(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计科目成绩分布失败: %w", err)
+	}
+	return list, nil
+}
@@ -0,0 +1,180 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotFound 学生不存在
+var ErrNotFound = errors.New("学生不存在")
+
+// Store 学生、班级和成绩的数据访问
+type Store interface {
+	// Create 创建学生, 数据不合法时返回 validate.Errors, 成功后回填 s.ID
+	Create(ctx context.Context, s *Student) error
+	// GetByID 按主键查询学生, 不存在时返回 ErrNotFound
+	GetByID(ctx context.Context, id uint) (*Student, error)
+	// CreateClass 创建班级, 数据不合法时返回 validate.Errors, 成功后回填 c.ID
+	CreateClass(ctx context.Context, c *Class) error
+	// GetClass 按主键查询班级, 不存在时返回 ErrClassNotFound
+	GetClass(ctx context.Context, id uint) (*Class, error)
+	// Enroll 把尚未分班的学生分到班级, 学生的年级随班级. 已分班时返回 ErrAlreadyEnrolled, 班级已满时返回 ErrClassFull
+	Enroll(ctx context.Context, studentID, classID uint) error
+	// Transfer 把已分班的学生转到另一个班级, 学生的年级随新班级. 未分班时返回 ErrNotEnrolled, 新班级已满时返回 ErrClassFull
+	Transfer(ctx context.Context, studentID, classID uint) error
+	// Withdraw 让学生退出所在班级, 未分班时返回 ErrNotEnrolled
+	Withdraw(ctx context.Context, studentID uint) error
+	// Roster 返回班级的花名册, 按姓名排序. 班级不存在时返回 ErrClassNotFound
+	Roster(ctx context.Context, classID uint) ([]Student, error)
+	// ClassStats 返回各班人数和汇总统计
+	ClassStats(ctx context.Context) (*ClassStats, error)
+	// RecordScores 批量录入成绩, 已有的同一学生、学期、科目的成绩被覆盖. 全部成功或全部失败, 数据不合法时返回 validate.Errors
+	RecordScores(ctx context.Context, scores []Score) error
+	// Transcript 返回学生的成绩单, term 为空时包含全部学期. 学生不存在时返回 ErrNotFound
+	Transcript(ctx context.Context, studentID uint, term string) (*Transcript, error)
+	// ClassAverages 返回班级每名学生的平均分和绩点, 平均分高的在前, 没有成绩的学生不列出. term 为空时包含全部学期
+	ClassAverages(ctx context.Context, classID uint, term string) ([]StudentAverage, error)
+	// SubjectDistribution 返回班级各科目的成绩分布, 按科目排序. term 为空时包含全部学期
+	SubjectDistribution(ctx context.Context, classID uint, term string) ([]SubjectStats, error)
+}
+
+// GormStore 基于 GORM 的 Store 实现, 学生的 Create 和 GetByID 来自 repository.Repository
+type GormStore struct {
+	*repository.Repository[Student]
+	classes *repository.Repository[Class]
+}
+
+var _ Store = (*GormStore)(nil)
+
+// NewGormStore 创建学生仓储
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{
+		Repository: repository.New[Student](db, repository.Options{Name: "学生", NotFound: ErrNotFound}),
+		classes:    repository.New[Class](db, repository.Options{Name: "班级", NotFound: ErrClassNotFound}),
+	}
+}
+
+func (s *GormStore) CreateClass(ctx context.Context, c *Class) error {
+	return s.classes.Create(ctx, c)
+}
+
+func (s *GormStore) GetClass(ctx context.Context, id uint) (*Class, error) {
+	return s.classes.GetByID(ctx, id)
+}
+
+func (s *GormStore) Enroll(ctx context.Context, studentID, classID uint) error {
+	return s.assign(ctx, studentID, classID, false)
+}
+
+func (s *GormStore) Transfer(ctx context.Context, studentID, classID uint) error {
+	return s.assign(ctx, studentID, classID, true)
+}
+
+// assign 把学生分到班级, transfer 为 true 时要求学生已分班 (转班), 否则要求尚未分班 (入学).
+// 先锁定班级再检查人数, 同一班级的并发分班依次执行, 不会超过上限
+func (s *GormStore) assign(ctx context.Context, studentID, classID uint, transfer bool) error {
+	return s.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var class Class
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&class, classID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("班级 %d: %w", classID, ErrClassNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("查询班级失败: %w", err)
+		}
+		st, err := lockStudent(tx, studentID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case transfer && st.ClassID == nil:
+			return fmt.Errorf("学生 %s: %w", st.Name, ErrNotEnrolled)
+		case !transfer && st.ClassID != nil:
+			return fmt.Errorf("学生 %s: %w", st.Name, ErrAlreadyEnrolled)
+		case st.ClassID != nil && *st.ClassID == classID:
+			return nil
+		}
+
+		if class.Capacity > 0 {
+			var n int64
+			if err := tx.Model(&Student{}).Where("class_id = ?", classID).Count(&n).Error; err != nil {
+				return fmt.Errorf("统计班级人数失败: %w", err)
+			}
+			if n >= int64(class.Capacity) {
+				return fmt.Errorf("%s%s (%d 人): %w", class.Grade, class.Name, class.Capacity, ErrClassFull)
+			}
+		}
+
+		err = tx.Model(st).Updates(map[string]any{"class_id": classID, "grade": class.Grade}).Error
+		if err != nil {
+			return fmt.Errorf("分班失败: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *GormStore) Withdraw(ctx context.Context, studentID uint) error {
+	return s.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		st, err := lockStudent(tx, studentID)
+		if err != nil {
+			return err
+		}
+		if st.ClassID == nil {
+			return fmt.Errorf("学生 %s: %w", st.Name, ErrNotEnrolled)
+		}
+		if err := tx.Model(st).Update("class_id", nil).Error; err != nil {
+			return fmt.Errorf("退班失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// lockStudent 锁定并返回学生, 不存在时返回 ErrNotFound
+func lockStudent(tx *gorm.DB, id uint) (*Student, error) {
+	var st Student
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&st, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("学生 %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询学生失败: %w", err)
+	}
+	return &st, nil
+}
+
+func (s *GormStore) Roster(ctx context.Context, classID uint) ([]Student, error) {
+	if _, err := s.GetClass(ctx, classID); err != nil {
+		return nil, err
+	}
+	var list []Student
+	err := s.DB().WithContext(ctx).Where("class_id = ?", classID).Order("name, id").Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询花名册失败: %w", err)
+	}
+	return list, nil
+}
+
+func (s *GormStore) ClassStats(ctx context.Context) (*ClassStats, error) {
+	db := s.DB().WithContext(ctx)
+	stats := &ClassStats{}
+	err := db.Model(&Class{}).
+		Select("classes.id AS class_id, classes.grade, classes.name, classes.capacity, COUNT(students.id) AS students").
+		Joins("LEFT JOIN students ON students.class_id = classes.id").
+		Group("classes.id, classes.grade, classes.name, classes.capacity").
+		Order("classes.grade, classes.name").
+		Scan(&stats.Classes).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计班级人数失败: %w", err)
+	}
+	if err := db.Model(&Student{}).Where("class_id IS NULL").Count(&stats.Unassigned).Error; err != nil {
+		return nil, fmt.Errorf("统计未分班的学生失败: %w", err)
+	}
+	stats.summarize()
+	return stats, nil
+}
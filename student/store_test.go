@@ -0,0 +1,461 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/alexwang789/Base1_golang_task3/validate"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestStore 打开内存 SQLite 库并建好班级、学生和成绩表
+func newTestStore(t *testing.T) *GormStore {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开 SQLite 失败: %v", err)
+	}
+	// 内存库每个连接各自独立, 只用一个连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	return NewGormStore(db)
+}
+
+func mustCreateClass(t *testing.T, store Store, grade, name string, capacity int) *Class {
+	t.Helper()
+	c := &Class{Grade: grade, Name: name, Capacity: capacity}
+	if err := store.CreateClass(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func mustCreateStudent(t *testing.T, store Store, name string) *Student {
+	t.Helper()
+	s := &Student{Name: name, Age: 9}
+	if err := store.Create(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCreateValidates(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		student Student
+		fields  []string // 期望校验失败的字段, 为空表示创建成功
+	}{
+		{"合法", Student{Name: "张三", Age: 9}, nil},
+		{"年龄下限", Student{Name: "李四", Age: MinAge}, nil},
+		{"年龄上限", Student{Name: "王五", Age: MaxAge}, nil},
+		{"姓名为空", Student{Name: "  ", Age: 9}, []string{"name"}},
+		{"年龄过小", Student{Name: "赵六", Age: MinAge - 1}, []string{"age"}},
+		{"年龄过大", Student{Name: "钱七", Age: MaxAge + 1}, []string{"age"}},
+		{"多个字段", Student{Age: 0}, []string{"name", "age"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.Create(ctx, &tt.student)
+			if len(tt.fields) == 0 {
+				if err != nil {
+					t.Fatalf("创建失败: %v", err)
+				}
+				got, err := store.GetByID(ctx, tt.student.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Name != tt.student.Name || got.Age != tt.student.Age {
+					t.Errorf("查询结果 = %+v, 期望 %+v", got, tt.student)
+				}
+				return
+			}
+
+			var errs validate.Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("err = %v, 期望 validate.Errors", err)
+			}
+			if len(errs) != len(tt.fields) {
+				t.Errorf("校验错误 = %v, 期望字段 %v", errs, tt.fields)
+			}
+			for _, f := range tt.fields {
+				if _, ok := errs[f]; !ok {
+					t.Errorf("缺少字段 %s 的错误: %v", f, errs)
+				}
+			}
+			if tt.student.ID != 0 {
+				t.Errorf("校验失败仍然写入了学生 %d", tt.student.ID)
+			}
+		})
+	}
+}
+
+func TestCreateClassValidates(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	mustCreateClass(t, store, "三年级", "1 班", 0)
+
+	tests := []struct {
+		name    string
+		class   Class
+		wantErr bool
+	}{
+		{"合法", Class{Grade: "三年级", Name: "2 班", Capacity: 40}, false},
+		{"不同年级同名", Class{Grade: "四年级", Name: "1 班"}, false},
+		{"年级为空", Class{Name: "3 班"}, true},
+		{"班级名为空", Class{Grade: "三年级"}, true},
+		{"人数上限为负数", Class{Grade: "三年级", Name: "4 班", Capacity: -1}, true},
+		{"同年级重名", Class{Grade: "三年级", Name: "1 班"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.CreateClass(ctx, &tt.class)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateClass() err = %v, 期望出错 %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	if _, err := store.GetByID(ctx, 404); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID() err = %v, 期望 ErrNotFound", err)
+	}
+	if _, err := store.GetClass(ctx, 404); !errors.Is(err, ErrClassNotFound) {
+		t.Errorf("GetClass() err = %v, 期望 ErrClassNotFound", err)
+	}
+	if _, err := store.Roster(ctx, 404); !errors.Is(err, ErrClassNotFound) {
+		t.Errorf("Roster() err = %v, 期望 ErrClassNotFound", err)
+	}
+	if _, err := store.Transcript(ctx, 404, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Transcript() err = %v, 期望 ErrNotFound", err)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	tests := []struct {
+		name string
+		// run 在一班 (上限 2 人)、二班 (不限) 和两名已分到一班的学生 a、b 上执行操作
+		run     func(store Store, one, two *Class, a, b, c *Student) error
+		wantErr error
+		moved   map[string]int // 班级发生变化的学生: 姓名 -> 1 或 2 表示一班或二班, 0 表示未分班
+	}{
+		{
+			name: "班级已满",
+			run: func(store Store, one, two *Class, a, b, c *Student) error {
+				return store.Enroll(context.Background(), c.ID, one.ID)
+			},
+			wantErr: ErrClassFull,
+		},
+		{
+			name: "入学",
+			run: func(store Store, one, two *Class, a, b, c *Student) error {
+				return store.Enroll(context.Background(), c.ID, two.ID)
+			},
+			moved: map[string]int{"王五": 2},
+		},
+		{
+			name: "重复入学",
+			run: func(store Store, one, two *Class, a, b, c *Student) error {
+				return store.Enroll(context.Background(), a.ID, two.ID)
+			},
+			wantErr: ErrAlreadyEnrolled,
+		},
+		{
+			name: "转班",
+			run: func(store Store, one, two *Class, a, b, c *Student) error {
+				return store.Transfer(context.Background(), a.ID, two.ID)
+			},
+			moved: map[string]int{"张三": 2},
+		},
+		{
+			name: "转到所在班级",
+			run: func(store Store, one, two *Class, a, b, c *Student) error {
+				return store.Transfer(context.Background(), a.ID, one.ID)
+			},
+		},
+		{
+			name: "未分班时转班",
+			run: func(store Store, one, two *Class, a, b, c *Student) error {
+				return store.Transfer(context.Background(), c.ID, two.ID)
+			},
+			wantErr: ErrNotEnrolled,
+		},
+		{
+			name: "退班后名额空出",
+			run: func(store Store, one, two *Class, a, b, c *Student) error {
+				if err := store.Withdraw(context.Background(), a.ID); err != nil {
+					return err
+				}
+				return store.Enroll(context.Background(), c.ID, one.ID)
+			},
+			moved: map[string]int{"张三": 0, "王五": 1},
+		},
+		{
+			name: "未分班时退班",
+			run: func(store Store, one, two *Class, a, b, c *Student) error {
+				return store.Withdraw(context.Background(), c.ID)
+			},
+			wantErr: ErrNotEnrolled,
+		},
+		{
+			name: "班级不存在",
+			run: func(store Store, one, two *Class, a, b, c *Student) error {
+				return store.Enroll(context.Background(), c.ID, 404)
+			},
+			wantErr: ErrClassNotFound,
+		},
+		{
+			name: "学生不存在",
+			run: func(store Store, one, two *Class, a, b, c *Student) error {
+				return store.Enroll(context.Background(), 404, two.ID)
+			},
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			ctx := context.Background()
+			one := mustCreateClass(t, store, "三年级", "1 班", 2)
+			two := mustCreateClass(t, store, "四年级", "2 班", 0)
+			a, b, c := mustCreateStudent(t, store, "张三"), mustCreateStudent(t, store, "李四"), mustCreateStudent(t, store, "王五")
+			for _, s := range []*Student{a, b} {
+				if err := store.Enroll(ctx, s.ID, one.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := tt.run(store, one, two, a, b, c)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, 期望 %v", err, tt.wantErr)
+			}
+
+			// 检查各学生所在的班级和年级, 失败时不应有任何变化
+			classes := []*Class{nil, one, two}
+			want := map[uint]*Class{a.ID: one, b.ID: one}
+			for _, s := range []*Student{a, b, c} {
+				if i, ok := tt.moved[s.Name]; ok {
+					want[s.ID] = classes[i]
+				}
+			}
+			for _, s := range []*Student{a, b, c} {
+				got, err := store.GetByID(ctx, s.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				class := want[s.ID]
+				switch {
+				case class == nil && got.ClassID != nil:
+					t.Errorf("%s 在班级 %d, 期望未分班", s.Name, *got.ClassID)
+				case class != nil && (got.ClassID == nil || *got.ClassID != class.ID):
+					t.Errorf("%s 的班级 = %v, 期望 %d", s.Name, got.ClassID, class.ID)
+				case class != nil && got.Grade != class.Grade:
+					t.Errorf("%s 的年级 = %q, 期望随班级为 %q", s.Name, got.Grade, class.Grade)
+				}
+			}
+		})
+	}
+}
+
+func TestRosterAndClassStats(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	one := mustCreateClass(t, store, "三年级", "1 班", 40)
+	two := mustCreateClass(t, store, "三年级", "2 班", 0)
+	mustCreateClass(t, store, "三年级", "3 班", 0)
+
+	for _, name := range []string{"王五", "张三", "李四"} {
+		s := mustCreateStudent(t, store, name)
+		if err := store.Enroll(ctx, s.ID, one.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := mustCreateStudent(t, store, "赵六")
+	if err := store.Enroll(ctx, s.ID, two.ID); err != nil {
+		t.Fatal(err)
+	}
+	mustCreateStudent(t, store, "钱七")
+
+	roster, err := store.Roster(ctx, one.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roster) != 3 {
+		t.Fatalf("花名册 %d 人, 期望 3 人", len(roster))
+	}
+	for i := 1; i < len(roster); i++ {
+		if roster[i-1].Name > roster[i].Name {
+			t.Errorf("花名册没有按姓名排序: %q 在 %q 之前", roster[i-1].Name, roster[i].Name)
+		}
+	}
+
+	stats, err := store.ClassStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := make([]int64, len(stats.Classes))
+	for i, c := range stats.Classes {
+		sizes[i] = c.Students
+	}
+	if len(sizes) != 3 || sizes[0] != 3 || sizes[1] != 1 || sizes[2] != 0 {
+		t.Errorf("各班人数 = %v, 期望 [3 1 0]", sizes)
+	}
+	if stats.Enrolled != 4 || stats.Unassigned != 1 || stats.MinSize != 0 || stats.MaxSize != 3 {
+		t.Errorf("统计 = %+v", stats)
+	}
+	if math.Abs(stats.AvgSize-4.0/3) > 1e-9 {
+		t.Errorf("平均人数 = %v, 期望 %v", stats.AvgSize, 4.0/3)
+	}
+}
+
+func TestRecordScores(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	s := mustCreateStudent(t, store, "张三")
+
+	tests := []struct {
+		name    string
+		scores  []Score
+		wantErr bool
+	}{
+		{"空列表", nil, false},
+		{"合法", []Score{{StudentID: s.ID, Term: "2024-秋", Subject: "数学", Score: 95}}, false},
+		{"覆盖已有成绩", []Score{{StudentID: s.ID, Term: "2024-秋", Subject: "数学", Score: 88}}, false},
+		{"成绩超出范围", []Score{{StudentID: s.ID, Term: "2024-秋", Subject: "语文", Score: 101}}, true},
+		{"缺少学期", []Score{{StudentID: s.ID, Subject: "语文", Score: 80}}, true},
+		{"缺少学生", []Score{{Term: "2024-秋", Subject: "语文", Score: 80}}, true},
+		{"一条不合法时全部不录入", []Score{
+			{StudentID: s.ID, Term: "2024-秋", Subject: "英语", Score: 80},
+			{StudentID: s.ID, Term: "2024-秋", Subject: "语文", Score: -1},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.RecordScores(ctx, tt.scores)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordScores() err = %v, 期望出错 %v", err, tt.wantErr)
+			}
+			var errs validate.Errors
+			if tt.wantErr && !errors.As(err, &errs) {
+				t.Errorf("err = %v, 期望 validate.Errors", err)
+			}
+		})
+	}
+
+	transcript, err := store.Transcript(ctx, s.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcript.Scores) != 1 || transcript.Scores[0].Score != 88 {
+		t.Errorf("成绩单 = %+v, 期望只有被覆盖为 88 分的数学", transcript.Scores)
+	}
+}
+
+func TestScoreReports(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	class := mustCreateClass(t, store, "三年级", "1 班", 0)
+	a, b := mustCreateStudent(t, store, "张三"), mustCreateStudent(t, store, "李四")
+	c := mustCreateStudent(t, store, "王五") // 在班级中但没有成绩
+	for _, s := range []*Student{a, b, c} {
+		if err := store.Enroll(ctx, s.ID, class.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := store.RecordScores(ctx, []Score{
+		{StudentID: a.ID, Term: "2024-秋", Subject: "数学", Score: 92},
+		{StudentID: a.ID, Term: "2024-秋", Subject: "语文", Score: 78},
+		{StudentID: a.ID, Term: "2025-春", Subject: "数学", Score: 55},
+		{StudentID: b.ID, Term: "2024-秋", Subject: "数学", Score: 85},
+		{StudentID: b.ID, Term: "2024-秋", Subject: "语文", Score: 64},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("成绩单", func(t *testing.T) {
+		tests := []struct {
+			term    string
+			scores  int
+			average float64
+			gpa     float64
+		}{
+			{"2024-秋", 2, 85, 3.5}, // 92 -> 4.0, 78 -> 3.0
+			{"", 3, 75, 7.0 / 3},   // 另有 55 -> 0
+			{"2023-秋", 0, 0, 0},    // 没有成绩的学期
+		}
+		for _, tt := range tests {
+			got, err := store.Transcript(ctx, a.ID, tt.term)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Scores) != tt.scores || !near(got.Average, tt.average) || !near(got.GPA, tt.gpa) {
+				t.Errorf("学期 %q: %d 条成绩, 平均分 %v, 绩点 %v; 期望 %d, %v, %v",
+					tt.term, len(got.Scores), got.Average, got.GPA, tt.scores, tt.average, tt.gpa)
+			}
+		}
+	})
+
+	t.Run("班级平均成绩", func(t *testing.T) {
+		list, err := store.ClassAverages(ctx, class.ID, "2024-秋")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 {
+			t.Fatalf("%d 名学生, 期望没有成绩的学生不列出: %+v", len(list), list)
+		}
+		// 张三 85 分在前, 李四 74.5 分
+		if list[0].StudentID != a.ID || !near(list[0].Average, 85) || list[1].StudentID != b.ID || !near(list[1].Average, 74.5) {
+			t.Errorf("班级平均成绩 = %+v", list)
+		}
+		if _, err := store.ClassAverages(ctx, 404, ""); !errors.Is(err, ErrClassNotFound) {
+			t.Errorf("err = %v, 期望 ErrClassNotFound", err)
+		}
+	})
+
+	t.Run("科目成绩分布", func(t *testing.T) {
+		list, err := store.SubjectDistribution(ctx, class.ID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []SubjectStats{
+			{Subject: "数学", Count: 3, Average: 232.0 / 3, Min: 55, Max: 92, Excellent: 1, Good: 1, Fail: 1},
+			{Subject: "语文", Count: 2, Average: 71, Min: 64, Max: 78, Fair: 1, Pass: 1},
+		}
+		if len(list) != len(want) {
+			t.Fatalf("科目成绩分布 = %+v", list)
+		}
+		for i, w := range want {
+			g := list[i]
+			g.Average, w.Average = math.Round(g.Average*100), math.Round(w.Average*100)
+			if g != w {
+				t.Errorf("%s: %+v, 期望 %+v", w.Subject, list[i], want[i])
+			}
+		}
+		if rate := list[0].PassRate(); !near(rate, 2.0/3) {
+			t.Errorf("数学及格率 = %v, 期望 %v", rate, 2.0/3)
+		}
+	})
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,147 @@
+// Package student 学生模块: 学生和班级模型, 基于 Store 接口的入学、转班和花名册查询.
+package student
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexwang789/Base1_golang_task3/collate"
+	"gorm.io/gorm"
+)
+
+// Student 学生, 对应 students 表
+type Student struct {
+	ID      uint   // Standard field for the primary key
+	Name    string // A regular string field
+	Age     uint8  // An unsigned 8-bit integer
+	Grade   string // 分班后与班级的年级相同
+	ClassID *uint  `gorm:"index"`                        // 所在班级, 尚未分班时为 NULL
+	Class   *Class `gorm:"constraint:OnDelete:SET NULL"` // 多对一关系: 学生 -> 班级
+}
+
+// Migrate 创建班级表、学生表和成绩表
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Class{}, &Student{}, &Score{})
+}
+
+// Run 演示分班、转班、花名册、成绩录入和成绩统计, 任一步失败即返回错误. 演示班级已存在时复用
+func Run(ctx context.Context, store Store) error {
+	existing, err := store.ClassStats(ctx)
+	if err != nil {
+		return err
+	}
+	var classes [2]Class
+	for i, name := range []string{"1 班", "2 班"} {
+		classes[i] = Class{Grade: "三年级", Name: name, Capacity: 40}
+		for _, c := range existing.Classes {
+			if c.Grade == classes[i].Grade && c.Name == name {
+				classes[i].ID = c.ClassID
+			}
+		}
+		if classes[i].ID == 0 {
+			if err := store.CreateClass(ctx, &classes[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	students := []Student{{Name: "张三", Age: 9}, {Name: "李四", Age: 9}, {Name: "王五", Age: 10}}
+	for i := range students {
+		if err := store.Create(ctx, &students[i]); err != nil {
+			return err
+		}
+		if err := store.Enroll(ctx, students[i].ID, classes[0].ID); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("✅ %d 名学生已分到%s%s\n", len(students), classes[0].Grade, classes[0].Name)
+
+	if err := store.Transfer(ctx, students[2].ID, classes[1].ID); err != nil {
+		return err
+	}
+	fmt.Printf("✅ %s 已转到%s\n", students[2].Name, classes[1].Name)
+
+	for _, c := range classes {
+		roster, err := store.Roster(ctx, c.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s%s 花名册:\n", c.Grade, c.Name)
+		for _, s := range roster {
+			fmt.Printf("- %s, %d 岁\n", s.Name, s.Age)
+		}
+	}
+
+	stats, err := store.ClassStats(ctx)
+	if err != nil {
+		return err
+	}
+	PrintClassStats(stats)
+
+	var scores []Score
+	for i, st := range students {
+		for j, subject := range []string{"语文", "数学", "英语"} {
+			scores = append(scores, Score{StudentID: st.ID, Term: "2024-秋", Subject: subject, Score: float64(70 + 7*i + 5*j)})
+		}
+	}
+	if err := store.RecordScores(ctx, scores); err != nil {
+		return err
+	}
+	fmt.Printf("✅ 已录入 %d 条成绩\n", len(scores))
+
+	transcript, err := store.Transcript(ctx, students[0].ID, "2024-秋")
+	if err != nil {
+		return err
+	}
+	PrintTranscript(transcript)
+
+	subjects, err := store.SubjectDistribution(ctx, classes[0].ID, "2024-秋")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s%s 各科成绩:\n", classes[0].Grade, classes[0].Name)
+	PrintSubjectStats(subjects)
+	return nil
+}
+
+// PrintClassStats 输出各班人数和汇总统计
+func PrintClassStats(stats *ClassStats) {
+	fmt.Println("班级人数:")
+	for _, c := range stats.Classes {
+		limit := "不限"
+		if c.Capacity > 0 {
+			limit = fmt.Sprint(c.Capacity)
+		}
+		fmt.Printf("- %s%s: %d 人 (上限 %s)\n", c.Grade, c.Name, c.Students, limit)
+	}
+	fmt.Printf("已分班 %d 人, 未分班 %d 人; 每班 %d ~ %d 人, 平均 %.1f 人\n",
+		stats.Enrolled, stats.Unassigned, stats.MinSize, stats.MaxSize, stats.AvgSize)
+}
+
+// PrintTranscript 输出成绩单
+func PrintTranscript(t *Transcript) {
+	term := t.Term
+	if term == "" {
+		term = "全部学期"
+	}
+	fmt.Printf("%s 的成绩单 (%s):\n", t.Student.Name, term)
+	for _, s := range t.Scores {
+		fmt.Printf("- %s %s: %.1f\n", s.Term, s.Subject, s.Score)
+	}
+	fmt.Printf("平均分 %.1f, 绩点 %.2f\n", t.Average, t.GPA)
+}
+
+// PrintSubjectStats 输出各科成绩分布
+func PrintSubjectStats(list []SubjectStats) {
+	for _, s := range list {
+		fmt.Printf("- %s: %d 人, 平均 %.1f (%.1f ~ %.1f), 优秀 %d / 良好 %d / 中等 %d / 及格 %d / 不及格 %d, 及格率 %.0f%%\n",
+			s.Subject, s.Count, s.Average, s.Min, s.Max, s.Excellent, s.Good, s.Fair, s.Pass, s.Fail, s.PassRate()*100)
+	}
+}
+
+// ListByName 按姓名排序查询学生, locale 决定中文姓名的排序方式
+func ListByName(db *gorm.DB, locale collate.Locale) ([]Student, error) {
+	var list []Student
+	err := db.Order(collate.OrderBy("name", locale, false)).Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,40 @@
+package student
+
+import "github.com/alexwang789/Base1_golang_task3/validate"
+
+// 学生的年龄范围
+const (
+	MinAge = 3
+	MaxAge = 100
+)
+
+// Validate 校验学生的姓名和年龄, 失败时返回 validate.Errors
+func (s *Student) Validate() error {
+	errs := validate.Errors{}
+	errs.Check(validate.NotBlank(s.Name), "name", "姓名不能为空")
+	errs.Check(s.Age >= MinAge && s.Age <= MaxAge, "age", "年龄必须在 3 ~ 100 之间")
+	return errs.Err()
+}
+
+// Validate 校验班级的年级、班级名和人数上限, 失败时返回 validate.Errors
+func (c *Class) Validate() error {
+	errs := validate.Errors{}
+	errs.Check(validate.NotBlank(c.Grade), "grade", "年级不能为空")
+	errs.Check(validate.MaxLen(c.Grade, 20), "grade", "年级不能超过 20 个字符")
+	errs.Check(validate.NotBlank(c.Name), "name", "班级名不能为空")
+	errs.Check(validate.MaxLen(c.Name, 50), "name", "班级名不能超过 50 个字符")
+	errs.Check(c.Capacity >= 0, "capacity", "人数上限不能为负数")
+	return errs.Err()
+}
+
+// Validate 校验成绩的学生、学期、科目和分数, 失败时返回 validate.Errors
+func (s *Score) Validate() error {
+	errs := validate.Errors{}
+	errs.Check(s.StudentID > 0, "student_id", "学生不能为空")
+	errs.Check(validate.NotBlank(s.Term), "term", "学期不能为空")
+	errs.Check(validate.MaxLen(s.Term, 20), "term", "学期不能超过 20 个字符")
+	errs.Check(validate.NotBlank(s.Subject), "subject", "科目不能为空")
+	errs.Check(validate.MaxLen(s.Subject, 50), "subject", "科目不能超过 50 个字符")
+	errs.Check(s.Score >= MinScore && s.Score <= MaxScore, "score", "成绩必须在 0 ~ 100 之间")
+	return errs.Err()
+}
@@ -0,0 +1,105 @@
+package tenant
+
+import (
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrCrossTenant 写入的记录属于 ctx 之外的租户
+var ErrCrossTenant = errors.New("不能写入其他租户的数据")
+
+// ErrNoTenant 跨租户的 ctx 中创建记录时没有填写 TenantID
+var ErrNoTenant = errors.New("跨租户创建记录时必须指定 TenantID")
+
+// Plugin 按 ctx 的租户过滤带 TenantID 字段的模型的语句, 见包文档
+type Plugin struct{}
+
+// NewPlugin 创建 GORM 插件, 通过 db.Use 注册
+func NewPlugin() *Plugin {
+	return &Plugin{}
+}
+
+// Name 实现 gorm.Plugin
+func (p *Plugin) Name() string {
+	return "tenant"
+}
+
+// Initialize 实现 gorm.Plugin
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []error{
+		cb.Create().Before("gorm:create").Register("tenant:create", p.fill(true)),
+		cb.Query().Before("gorm:query").Register("tenant:query", p.filter),
+		cb.Update().Before("gorm:update").Register("tenant:fill_update", p.fill(false)),
+		cb.Update().Before("gorm:update").Register("tenant:update", p.filter),
+		cb.Delete().Before("gorm:delete").Register("tenant:delete", p.filter),
+		cb.Row().Before("gorm:row").Register("tenant:row", p.filter),
+	}
+	for _, err := range hooks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// field 语句的模型的租户字段; 模型不按租户隔离或是原生 SQL 时返回 nil
+func field(db *gorm.DB) *schema.Field {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.SQL.Len() > 0 {
+		return nil
+	}
+	return db.Statement.Schema.LookUpField(Field)
+}
+
+// filter 加上 tenant_id = ? 条件
+func (p *Plugin) filter(db *gorm.DB) {
+	f := field(db)
+	if f == nil || IsAll(db.Statement.Context) {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: ID(db.Statement.Context)},
+	}})
+}
+
+// fill 为要写入的记录填写 TenantID, 已经填写的必须是 ctx 的租户. 跨租户的 ctx 中创建的记录必须已经填写,
+// 更新则不要求 (如 Model(&User{}).Update). 更新也要填写, 避免 Save 一个没有 TenantID 的结构体把记录移到租户 0
+func (p *Plugin) fill(create bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		f := field(db)
+		if f == nil {
+			return
+		}
+		ctx := db.Statement.Context
+		id, all := ID(ctx), IsAll(ctx)
+		fillOne := func(rv reflect.Value) {
+			v, zero := f.ValueOf(ctx, rv)
+			switch {
+			case zero && all:
+				if create {
+					db.AddError(ErrNoTenant)
+				}
+			case zero:
+				db.AddError(f.Set(ctx, rv, id))
+			case !all && v != id:
+				db.AddError(ErrCrossTenant)
+			}
+		}
+
+		rv := db.Statement.ReflectValue
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+					fillOne(elem)
+				}
+			}
+		case reflect.Struct:
+			fillOne(rv)
+		}
+	}
+}
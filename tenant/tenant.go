@@ -0,0 +1,77 @@
+// Package tenant 多租户: 一个部署同时服务多个博客, 每个博客是一个租户, 数据以 tenant_id 列区分.
+//
+// 请求所属的租户由 middleware.Tenant 按域名确定 (见 HostResolver) 并放入 ctx. GORM 插件 Plugin
+// 为带 TenantID 字段的模型的查询、更新和删除自动加上 tenant_id = ? 条件, 创建时自动填写 TenantID,
+// 业务代码不需要逐处传递租户. ctx 中没有租户时 (命令行、单租户部署) 使用 Default.
+//
+// 定期任务等需要处理全部租户的代码用 All 标记 ctx, 此时不加条件, 创建的记录必须自己填写 TenantID.
+// 原生 SQL (Raw / Exec) 不会被改写, 涉及租户数据的语句需要自己加上条件 (见 ID).
+package tenant
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Default 未指定租户时使用的租户, 启用多租户之前的数据都属于它
+const Default uint = 1
+
+// Field 租户字段名, 模型有该字段即按租户隔离
+const Field = "TenantID"
+
+type ctxKey int
+
+const (
+	idKey ctxKey = iota
+	allKey
+)
+
+// With 返回属于租户 id 的 ctx
+func With(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, idKey, id)
+}
+
+// ID 返回 ctx 所属的租户, 没有时为 Default
+func ID(ctx context.Context) uint {
+	if id, ok := ctx.Value(idKey).(uint); ok {
+		return id
+	}
+	return Default
+}
+
+// All 返回跨租户的 ctx, 其中的查询不按租户过滤
+func All(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allKey, true)
+}
+
+// IsAll ctx 是否跨租户
+func IsAll(ctx context.Context) bool {
+	all, _ := ctx.Value(allKey).(bool)
+	return all
+}
+
+// Inherit 把 from 的租户 (或跨租户标记) 带到 ctx 上, 用于在新的 ctx 中继续执行请求的后续工作
+func Inherit(ctx, from context.Context) context.Context {
+	if IsAll(from) {
+		return All(ctx)
+	}
+	return With(ctx, ID(from))
+}
+
+// HostResolver 按请求的域名 (不含端口, 不区分大小写) 查找租户. hosts 为空时是单租户部署,
+// 全部请求属于 Default; 否则不在 hosts 中的域名无法确定租户
+func HostResolver(hosts map[string]uint) func(r *http.Request) (uint, bool) {
+	return func(r *http.Request) (uint, bool) {
+		if len(hosts) == 0 {
+			return Default, true
+		}
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		id, ok := hosts[strings.ToLower(host)]
+		return id, ok
+	}
+}
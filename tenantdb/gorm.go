@@ -0,0 +1,49 @@
+package tenantdb
+
+import (
+	"gorm.io/gorm"
+)
+
+// Plugin 在只读副本插件 (dbresolver) 选择连接池之后, 把独立库的租户的语句改回 Router
+type Plugin struct {
+	router *Router
+}
+
+// NewPlugin 创建 GORM 插件, 通过 db.Use 注册
+func NewPlugin(r *Router) *Plugin {
+	return &Plugin{router: r}
+}
+
+// Name 实现 gorm.Plugin
+func (p *Plugin) Name() string {
+	return "tenantdb"
+}
+
+// Initialize 实现 gorm.Plugin
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []error{
+		cb.Create().Before("gorm:create").After("gorm:db_resolver").Register("tenantdb:create", p.route),
+		cb.Query().Before("gorm:query").After("gorm:db_resolver").Register("tenantdb:query", p.route),
+		cb.Update().Before("gorm:update").After("gorm:db_resolver").Register("tenantdb:update", p.route),
+		cb.Delete().Before("gorm:delete").After("gorm:db_resolver").Register("tenantdb:delete", p.route),
+		cb.Row().Before("gorm:row").After("gorm:db_resolver").Register("tenantdb:row", p.route),
+		cb.Raw().Before("gorm:raw").After("gorm:db_resolver").Register("tenantdb:raw", p.route),
+	}
+	for _, err := range hooks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// route 事务中的语句已经在租户的库中, 不做改动
+func (p *Plugin) route(db *gorm.DB) {
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return
+	}
+	if p.router.Dedicated(db.Statement.Context) {
+		db.Statement.ConnPool = p.router
+	}
+}
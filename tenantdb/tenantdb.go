@@ -0,0 +1,288 @@
+// Package tenantdb 为需要隔离的租户提供独立的数据库: 配置了连接串的租户 (见 config.LoadTenantDatabases)
+// 的语句走自己的库, 其余租户共用默认库, 仍按 tenant_id 隔离.
+//
+// Router 实现 gorm.ConnPool, 作为 GORM 的连接池按 ctx 的租户 (见 Target) 选择连接池, 事务同样在租户的库中开启,
+// 仓储和服务不需要任何改动. 租户的连接池在第一次使用时打开, 空闲超过 IdleTimeout 且没有使用中的连接时关闭.
+// 只读副本 (replica) 只属于默认库, 独立库的租户的读操作由 Plugin 改回 Router.
+//
+// 定期任务和迁移需要对每个独立库分别执行, 用 Route 指定 ctx 中的语句走哪个库.
+package tenantdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/config"
+	"github.com/alexwang789/Base1_golang_task3/dbpool"
+	"github.com/alexwang789/Base1_golang_task3/tenant"
+	_ "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+type routeKey struct{}
+
+// Route 返回语句走租户 id 的库的 ctx, 跨租户的 ctx 同样适用; id 为 0 或没有独立库时走默认库
+func Route(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, routeKey{}, id)
+}
+
+// Target 返回 ctx 中的语句走的库: Route 指定的优先, 跨租户的 ctx 为 0 (默认库), 否则为 ctx 的租户.
+// 没有独立库的租户同样返回租户 ID, 由 Router 落到默认库. 需要在之后按原来的库执行的工作用它记下目标
+func Target(ctx context.Context) uint {
+	if id, ok := ctx.Value(routeKey{}).(uint); ok {
+		return id
+	}
+	if tenant.IsAll(ctx) {
+		return 0
+	}
+	return tenant.ID(ctx)
+}
+
+// Options 租户连接池的选项
+type Options struct {
+	Name        string        // 登记到 dbpool 的名称前缀, 如 "blog", 租户的连接池登记为 "blog/tenant_2"
+	Pool        config.Pool   // 租户连接池的配置
+	IdleTimeout time.Duration // 连接池空闲多久后关闭, 0 表示不关闭
+}
+
+// Router 按租户选择连接池, 可并发使用
+type Router struct {
+	def  *sql.DB
+	dsns map[uint]string
+	opts Options
+
+	mu     sync.Mutex
+	pools  map[uint]*pool
+	swept  time.Time
+	closed bool
+}
+
+// pool 一个租户的连接池; ready 关闭后 db 和 err 可读
+type pool struct {
+	ready    chan struct{}
+	db       *sql.DB
+	err      error
+	lastUsed time.Time
+}
+
+// New 创建路由, def 是默认库的连接池, dsns 为租户到独立库连接串的映射
+func New(def *sql.DB, dsns map[uint]string, opts Options) *Router {
+	return &Router{def: def, dsns: dsns, opts: opts, pools: make(map[uint]*pool), swept: time.Now()}
+}
+
+// Of 返回 db 使用的 Router, 没有配置独立库时为 nil
+func Of(db *gorm.DB) *Router {
+	r, _ := db.Config.ConnPool.(*Router)
+	return r
+}
+
+// Tenants 返回有独立库的租户, 按 ID 排序; r 为 nil 时返回 nil
+func (r *Router) Tenants() []uint {
+	if r == nil {
+		return nil
+	}
+	ids := make([]uint, 0, len(r.dsns))
+	for id := range r.dsns {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// ForEach 依次在默认库和每个独立库上执行 fn, 传给 fn 的 ctx 已用 Route 指定库; r 为 nil 时只执行默认库.
+// 某个库失败不影响其余的库, 返回全部错误
+func (r *Router) ForEach(ctx context.Context, fn func(ctx context.Context) error) error {
+	var errs []error
+	if err := fn(Route(ctx, 0)); err != nil {
+		errs = append(errs, err)
+	}
+	for _, id := range r.Tenants() {
+		if err := fn(Route(ctx, id)); err != nil {
+			errs = append(errs, fmt.Errorf("租户 %d 的数据库: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Dedicated ctx 中的语句是否走独立库
+func (r *Router) Dedicated(ctx context.Context) bool {
+	_, ok := r.dsns[Target(ctx)]
+	return ok
+}
+
+// db 返回 ctx 应使用的连接池, 租户的连接池不存在时打开
+func (r *Router) db(ctx context.Context) (*sql.DB, error) {
+	id := Target(ctx)
+	dsn, ok := r.dsns[id]
+	if !ok {
+		return r.def, nil
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, sql.ErrConnDone
+	}
+	evicted := r.sweep(now)
+	p, exists := r.pools[id]
+	if !exists {
+		p = &pool{ready: make(chan struct{})}
+		r.pools[id] = p
+	}
+	p.lastUsed = now
+	r.mu.Unlock()
+	r.closeAll(evicted)
+
+	if !exists {
+		p.db, p.err = r.open(id, dsn)
+		if p.err != nil {
+			// 打开失败的不保留, 下次使用时重试
+			r.mu.Lock()
+			delete(r.pools, id)
+			r.mu.Unlock()
+		}
+		close(p.ready)
+	}
+	select {
+	case <-p.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return p.db, p.err
+}
+
+func (r *Router) open(id uint, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开租户 %d 的数据库失败: %w", id, err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("连接租户 %d 的数据库失败: %w", id, err)
+	}
+	dbpool.Default.Register(r.poolName(id), db, r.opts.Pool)
+	log.Printf("已打开租户 %d 的数据库连接池", id)
+	return db, nil
+}
+
+func (r *Router) poolName(id uint) string {
+	return fmt.Sprintf("%s/tenant_%d", r.opts.Name, id)
+}
+
+// sweep 每隔半个 IdleTimeout 从 pools 中取出空闲超时且没有使用中的连接的连接池, 由调用方在释放 mu 后关闭.
+// 调用方持有 mu
+func (r *Router) sweep(now time.Time) map[uint]*sql.DB {
+	if r.opts.IdleTimeout <= 0 || now.Sub(r.swept) < r.opts.IdleTimeout/2 {
+		return nil
+	}
+	r.swept = now
+	evicted := make(map[uint]*sql.DB)
+	for id, p := range r.pools {
+		select {
+		case <-p.ready:
+		default:
+			continue
+		}
+		if now.Sub(p.lastUsed) >= r.opts.IdleTimeout && p.db.Stats().InUse == 0 {
+			evicted[id] = p.db
+			delete(r.pools, id)
+		}
+	}
+	return evicted
+}
+
+func (r *Router) closeAll(pools map[uint]*sql.DB) {
+	for id, db := range pools {
+		dbpool.Default.Unregister(r.poolName(id))
+		if err := db.Close(); err != nil {
+			log.Printf("关闭租户 %d 的数据库连接失败: %v", id, err)
+		}
+	}
+}
+
+// Close 关闭全部租户的连接池, 默认库的连接池由调用方关闭
+func (r *Router) Close() {
+	r.mu.Lock()
+	r.closed = true
+	pools := make(map[uint]*sql.DB)
+	for id, p := range r.pools {
+		select {
+		case <-p.ready:
+			if p.db != nil {
+				pools[id] = p.db
+			}
+		default:
+		}
+	}
+	r.pools = make(map[uint]*pool)
+	r.mu.Unlock()
+	r.closeAll(pools)
+}
+
+// PrepareContext 实现 gorm.ConnPool
+func (r *Router) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	db, err := r.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.PrepareContext(ctx, query)
+}
+
+// ExecContext 实现 gorm.ConnPool
+func (r *Router) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	db, err := r.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext 实现 gorm.ConnPool
+func (r *Router) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	db, err := r.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext 实现 gorm.ConnPool. 租户的连接池打不开时返回的 *sql.Row 在 Scan 时返回该错误
+func (r *Router) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	db, err := r.db(ctx)
+	if err != nil {
+		failed := sql.OpenDB(failedConnector{err})
+		defer failed.Close()
+		return failed.QueryRowContext(ctx, query, args...)
+	}
+	return db.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx 实现 gorm.TxBeginner, 事务在 ctx 的租户的库中开启
+func (r *Router) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	db, err := r.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.BeginTx(ctx, opts)
+}
+
+// GetDBConn 实现 gorm.GetDBConnector, db.DB() 返回默认库的连接池
+func (r *Router) GetDBConn() (*sql.DB, error) {
+	return r.def, nil
+}
+
+// failedConnector 连接总是失败, 用于构造携带错误的 *sql.Row
+type failedConnector struct {
+	err error
+}
+
+func (c failedConnector) Connect(context.Context) (driver.Conn, error) { return nil, c.err }
+func (c failedConnector) Driver() driver.Driver                        { return nil }
@@ -0,0 +1,60 @@
+//go:build integration
+
+// Package testmysql 为集成测试 (go test -tags=integration) 在容器中启动 MySQL, 需要本机可以访问 Docker.
+//
+// 每次调用 Start 启动一个新容器, 测试结束时销毁; 连接参数写入 DB_HOST 等环境变量,
+// 被测代码按正常流程读取配置连接 (见 config.LoadDatabase).
+package testmysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+)
+
+// Image 使用的 MySQL 镜像
+const Image = "mysql:8.0"
+
+const password = "password"
+
+// Start 启动 MySQL 容器并创建 database 库, 返回以 params 为参数连接该库的 DSN.
+// 同时设置 DB_HOST、DB_PORT、DB_USER、DB_PASS 和 DB_NAME, 因此调用方不能使用 t.Parallel
+func Start(t testing.TB, database, params string) string {
+	t.Helper()
+	ctx := context.Background()
+
+	container, err := mysql.Run(ctx, Image,
+		mysql.WithDatabase(database),
+		mysql.WithUsername("root"),
+		mysql.WithPassword(password),
+	)
+	if err != nil {
+		t.Fatalf("启动 MySQL 容器失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(context.Background()); err != nil {
+			t.Logf("销毁 MySQL 容器失败: %v", err)
+		}
+	})
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("获取容器地址失败: %v", err)
+	}
+	port, err := container.MappedPort(ctx, "3306/tcp")
+	if err != nil {
+		t.Fatalf("获取容器端口失败: %v", err)
+	}
+	dsn, err := container.ConnectionString(ctx, params)
+	if err != nil {
+		t.Fatalf("获取连接串失败: %v", err)
+	}
+
+	t.Setenv("DB_HOST", host)
+	t.Setenv("DB_PORT", port.Port())
+	t.Setenv("DB_USER", "root")
+	t.Setenv("DB_PASS", password)
+	t.Setenv("DB_NAME", database)
+	return dsn
+}
@@ -0,0 +1,161 @@
+// Package txmanager 统一管理 GORM 事务.
+//
+// RunInTx 把事务放进 context, 嵌套调用不会开启新事务, 而是在当前事务上创建保存点,
+// 内层失败只回滚到保存点. fn 中的 panic 会先回滚再继续向上抛出.
+// 最外层事务遇到 MySQL 死锁 (1213) 时整体重试, 死锁发生时 MySQL 已回滚了整个事务,
+// 因此只有最外层才能重试.
+package txmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// MySQL 死锁错误码
+const errDeadlock = 1213
+
+type ctxKey struct{}
+
+// txState 保存在 context 中的事务状态
+type txState struct {
+	tx    *gorm.DB
+	depth int // 嵌套层数, 用于生成保存点名称
+}
+
+// Manager 事务管理器
+type Manager struct {
+	db         *gorm.DB
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Option 配置项
+type Option func(*Manager)
+
+// WithMaxRetries 设置死锁后的最大重试次数, 默认 3
+func WithMaxRetries(n int) Option {
+	return func(m *Manager) { m.maxRetries = n }
+}
+
+// WithBackoff 设置重试前的基础等待时间, 第 n 次重试等待 n 倍基础时间再加随机抖动, 默认 20ms
+func WithBackoff(d time.Duration) Option {
+	return func(m *Manager) { m.backoff = d }
+}
+
+// New 创建事务管理器
+func New(db *gorm.DB, opts ...Option) *Manager {
+	m := &Manager{db: db, maxRetries: 3, backoff: 20 * time.Millisecond}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// DB 返回 ctx 中的当前事务, 不在事务中时返回普通连接. 仓储代码用它访问数据库即可自动加入事务
+func (m *Manager) DB(ctx context.Context) *gorm.DB {
+	if st, ok := ctx.Value(ctxKey{}).(*txState); ok {
+		return st.tx.WithContext(ctx)
+	}
+	return m.db.WithContext(ctx)
+}
+
+// InTx 判断 ctx 是否处于事务中
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(ctxKey{}).(*txState)
+	return ok
+}
+
+// RunInTx 在事务中执行 fn, fn 应通过 m.DB(ctx) 访问数据库.
+// fn 返回错误或 panic 时回滚; 已在事务中时改用保存点.
+func (m *Manager) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if st, ok := ctx.Value(ctxKey{}).(*txState); ok {
+		return m.runNested(ctx, st, fn)
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = m.runOuter(ctx, fn)
+		if err == nil || !IsDeadlock(err) || attempt >= m.maxRetries {
+			break
+		}
+
+		wait := m.backoff * time.Duration(attempt+1)
+		wait += rand.N(m.backoff + 1)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+	return err
+}
+
+func (m *Manager) runOuter(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx := m.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("开启事务失败: %w", tx.Error)
+	}
+
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+		if rbErr := tx.Rollback().Error; rbErr != nil && !errors.Is(rbErr, gorm.ErrInvalidTransaction) {
+			err = errors.Join(err, fmt.Errorf("回滚事务失败: %w", rbErr))
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, ctxKey{}, &txState{tx: tx})); err != nil {
+		return err
+	}
+	if err = tx.Commit().Error; err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+func (m *Manager) runNested(ctx context.Context, parent *txState, fn func(ctx context.Context) error) (err error) {
+	st := &txState{tx: parent.tx, depth: parent.depth + 1}
+	name := fmt.Sprintf("sp_%d", st.depth)
+	if err := st.tx.SavePoint(name).Error; err != nil {
+		return fmt.Errorf("创建保存点失败: %w", err)
+	}
+
+	done := false
+	defer func() {
+		if done {
+			return
+		}
+		if r := recover(); r != nil {
+			st.tx.RollbackTo(name)
+			panic(r)
+		}
+		if rbErr := st.tx.RollbackTo(name).Error; rbErr != nil {
+			err = errors.Join(err, fmt.Errorf("回滚到保存点失败: %w", rbErr))
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, ctxKey{}, st)); err != nil {
+		return err
+	}
+	done = true
+	return nil
+}
+
+// IsDeadlock 判断错误是否为 MySQL 死锁
+func IsDeadlock(err error) bool {
+	var myErr *mysql.MySQLError
+	return errors.As(err, &myErr) && myErr.Number == errDeadlock
+}
@@ -0,0 +1,196 @@
+// Package usercache 为按主键查询用户提供进程内 LRU 缓存.
+//
+// 评论和文章的渲染路径会反复按 ID 查询作者, 缓存包装 UserRepository.GetByID,
+// 按容量淘汰最久未使用的条目, 并在 TTL 到期后重新加载. 用户被修改时由 GORM 钩子调用 Invalidate.
+//
+// 缓存键由调用方决定: 多租户部署中不同租户的用户 ID 可能相同 (独立库的租户各自自增), 键应包含租户.
+package usercache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Loader 缓存未命中时调用的加载函数, 通常包装 UserRepository.GetByID
+type Loader[K comparable, U any] func(ctx context.Context, key K) (U, error)
+
+// Options 缓存配置
+type Options struct {
+	Size int           // 最多缓存的用户数, <= 0 时使用 1000
+	TTL  time.Duration // 条目有效期, <= 0 表示不过期
+}
+
+// Cache 按键缓存加载结果, 可并发使用
+type Cache[K comparable, U any] struct {
+	load Loader[K, U]
+	size int
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	ll    *list.List          // 表头为最近使用
+	items map[K]*list.Element // 键 -> ll 中的节点
+	gen   uint64              // 每次失效递增, 防止并发加载把旧数据写回
+}
+
+type entry[K comparable, U any] struct {
+	key       K
+	user      U
+	expiresAt time.Time
+}
+
+// New 创建缓存
+func New[K comparable, U any](load Loader[K, U], opts Options) *Cache[K, U] {
+	if opts.Size <= 0 {
+		opts.Size = 1000
+	}
+	return &Cache[K, U]{
+		load:  load,
+		size:  opts.Size,
+		ttl:   opts.TTL,
+		ll:    list.New(),
+		items: make(map[K]*list.Element),
+	}
+}
+
+// Get 优先从缓存返回用户, 未命中或已过期时调用 Loader 加载并写入缓存.
+// 加载失败的结果不会被缓存.
+func (c *Cache[K, U]) Get(ctx context.Context, key K) (U, error) {
+	c.mu.Lock()
+	if user, ok := c.lookup(key); ok {
+		c.mu.Unlock()
+		return user, nil
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	user, err := c.load(ctx, key)
+	if err != nil {
+		return user, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen == c.gen { // 加载期间没有发生失效才写入
+		c.add(key, user)
+	}
+	return user, nil
+}
+
+// GetMany 批量返回 keys 对应的用户, 未命中的键交给 loadMany 一次加载并写入缓存.
+// loadMany 没有返回的键 (如用户不存在) 不出现在结果中, 也不会被缓存
+func (c *Cache[K, U]) GetMany(ctx context.Context, keys []K, loadMany func(ctx context.Context, missing []K) (map[K]U, error)) (map[K]U, error) {
+	found := make(map[K]U, len(keys))
+	var missing []K
+	seen := make(map[K]bool, len(keys))
+
+	c.mu.Lock()
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if user, ok := c.lookup(key); ok {
+			found[key] = user
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	if len(missing) == 0 {
+		return found, nil
+	}
+	loaded, err := loadMany(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, user := range loaded {
+		found[key] = user
+		if gen == c.gen {
+			c.add(key, user)
+		}
+	}
+	return found, nil
+}
+
+// Invalidate 移除指定键的缓存
+func (c *Cache[K, U]) Invalidate(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// InvalidateFunc 移除 match 返回 true 的全部键, 用于不知道完整的键时 (如不知道用户属于哪个租户) 失效
+func (c *Cache[K, U]) InvalidateFunc(match func(K) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for key, el := range c.items {
+		if match(key) {
+			c.removeElement(el)
+		}
+	}
+}
+
+// Purge 清空缓存
+func (c *Cache[K, U]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.ll.Init()
+	c.items = make(map[K]*list.Element)
+}
+
+// Len 返回当前缓存的条目数
+func (c *Cache[K, U]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// lookup 返回未过期的缓存条目并标记为最近使用, 已过期的条目被移除. 调用方持有 mu
+func (c *Cache[K, U]) lookup(key K) (U, bool) {
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, U])
+		if c.ttl <= 0 || time.Now().Before(e.expiresAt) {
+			c.ll.MoveToFront(el)
+			return e.user, true
+		}
+		c.removeElement(el)
+	}
+	var zero U
+	return zero, false
+}
+
+func (c *Cache[K, U]) add(key K, user U) {
+	e := &entry[K, U]{key: key, user: user}
+	if c.ttl > 0 {
+		e.expiresAt = time.Now().Add(c.ttl)
+	}
+
+	if el, ok := c.items[key]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(e)
+
+	// 超出容量时淘汰最久未使用的条目
+	for c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *Cache[K, U]) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*entry[K, U]).key)
+}
@@ -0,0 +1,108 @@
+package usercache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type key struct {
+	tenant, id uint
+}
+
+// counter 记录每个键被加载的次数
+type counter map[key]int
+
+func (c counter) load(_ context.Context, k key) (string, error) {
+	c[k]++
+	if k.id == 0 {
+		return "", errors.New("用户不存在")
+	}
+	return "user", nil
+}
+
+func TestGetCachesByKey(t *testing.T) {
+	loads := counter{}
+	c := New(loads.load, Options{Size: 10})
+	ctx := context.Background()
+
+	for _, k := range []key{{1, 7}, {1, 7}, {2, 7}, {2, 7}} {
+		if _, err := c.Get(ctx, k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 不同租户的同一 ID 是不同的条目
+	if loads[key{1, 7}] != 1 || loads[key{2, 7}] != 1 {
+		t.Errorf("加载次数 = %v, 期望每个键各 1 次", loads)
+	}
+
+	// 加载失败不缓存
+	c.Get(ctx, key{1, 0})
+	c.Get(ctx, key{1, 0})
+	if loads[key{1, 0}] != 2 {
+		t.Errorf("失败的加载被缓存了: %d", loads[key{1, 0}])
+	}
+}
+
+func TestGetManyLoadsOnlyMissing(t *testing.T) {
+	loads := counter{}
+	c := New(loads.load, Options{Size: 10})
+	ctx := context.Background()
+	c.Get(ctx, key{1, 1})
+
+	var missing []key
+	got, err := c.GetMany(ctx, []key{{1, 1}, {1, 2}, {1, 2}, {1, 3}}, func(_ context.Context, keys []key) (map[key]string, error) {
+		missing = keys
+		return map[key]string{{1, 2}: "two"}, nil // {1, 3} 不存在
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 2 || missing[0] != (key{1, 2}) || missing[1] != (key{1, 3}) {
+		t.Errorf("未命中的键 = %v, 期望 [{1 2} {1 3}]", missing)
+	}
+	if len(got) != 2 || got[key{1, 1}] != "user" || got[key{1, 2}] != "two" {
+		t.Errorf("结果 = %v", got)
+	}
+	if c.Len() != 2 {
+		t.Errorf("缓存条目数 = %d, 期望 2", c.Len())
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	loads := counter{}
+	c := New(loads.load, Options{Size: 10})
+	ctx := context.Background()
+	for _, k := range []key{{1, 7}, {2, 7}, {1, 8}} {
+		c.Get(ctx, k)
+	}
+
+	c.Invalidate(key{1, 8})
+	if c.Len() != 2 {
+		t.Fatalf("Invalidate 后条目数 = %d, 期望 2", c.Len())
+	}
+	c.InvalidateFunc(func(k key) bool { return k.id == 7 })
+	if c.Len() != 0 {
+		t.Fatalf("InvalidateFunc 后条目数 = %d, 期望 0", c.Len())
+	}
+	c.Get(ctx, key{2, 7})
+	if loads[key{2, 7}] != 2 {
+		t.Errorf("失效后没有重新加载")
+	}
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	loads := counter{}
+	c := New(loads.load, Options{Size: 2})
+	ctx := context.Background()
+	c.Get(ctx, key{1, 1})
+	c.Get(ctx, key{1, 2})
+	c.Get(ctx, key{1, 1}) // {1, 2} 成为最久未使用
+	c.Get(ctx, key{1, 3})
+
+	c.Get(ctx, key{1, 1})
+	c.Get(ctx, key{1, 2})
+	if loads[key{1, 1}] != 1 || loads[key{1, 2}] != 2 {
+		t.Errorf("加载次数 = %v, 期望淘汰 {1 2}", loads)
+	}
+}
@@ -0,0 +1,84 @@
+// Package validate 提供字段级的数据校验错误.
+//
+// 各模型的 Validate 方法逐个检查字段, 把不合法的字段记入 Errors 并返回;
+// Errors 是 "字段名 -> 错误信息" 的 map, 可以直接序列化为 API 响应, 调用方用 errors.As 取出.
+package validate
+
+import (
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Errors 字段名 -> 错误信息
+type Errors map[string]string
+
+// Check ok 为 false 时记录 field 的错误, 同一字段只保留第一条
+func (e Errors) Check(ok bool, field, msg string) {
+	if ok {
+		return
+	}
+	if _, exists := e[field]; !exists {
+		e[field] = msg
+	}
+}
+
+// Err 没有错误时返回 nil, 否则返回 e 本身
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error 按字段名排序输出 "字段: 错误; 字段: 错误"
+func (e Errors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	var b strings.Builder
+	b.WriteString("数据校验失败: ")
+	for i, field := range fields {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(field + ": " + e[field])
+	}
+	return b.String()
+}
+
+// NotBlank 去掉首尾空白后不为空
+func NotBlank(s string) bool {
+	return strings.TrimSpace(s) != ""
+}
+
+// MaxLen 字符数 (而不是字节数) 不超过 n
+func MaxLen(s string, n int) bool {
+	return utf8.RuneCountInString(s) <= n
+}
+
+// Email 是不带显示名的邮箱地址, 如 user@example.com
+func Email(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// HTTPURL 是 http 或 https 的绝对地址, 如 https://example.com/a.png
+func HTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// StrongPassword 至少 minLen 个字符, 同时包含字母和数字
+func StrongPassword(s string, minLen int) bool {
+	if utf8.RuneCountInString(s) < minLen {
+		return false
+	}
+	return strings.IndexFunc(s, unicode.IsLetter) >= 0 && strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
@@ -0,0 +1,189 @@
+// Package webhook 把领域事件推送给外部服务.
+//
+// Sink 作为 outbox.Relay 的投递目标, 把每个事件按订阅过滤后为每个 webhook 写入一条 Delivery;
+// Worker 轮询到期的 Delivery, 以 HMAC-SHA256 签名后 POST 到订阅地址, 失败按指数退避重试,
+// 超过最大次数后转为 dead. 每次请求的结果记入 DeliveryAttempt 便于排查.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alexwang789/Base1_golang_task3/outbox"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotFound webhook 不存在
+var ErrNotFound = errors.New("webhook 不存在")
+
+// Webhook 一个订阅: 事件类型匹配 Events 时推送到 URL
+type Webhook struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	URL       string `gorm:"size:500;not null"`
+	Secret    string `gorm:"size:100;not null"`  // 签名密钥, 推送时需要原文, 因此不做哈希
+	Events    string `gorm:"size:1000;not null"` // 逗号分隔的事件类型, * 表示全部
+	Active    bool   `gorm:"not null;default:true"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Matches 报告 webhook 是否订阅了 eventType
+func (w *Webhook) Matches(eventType string) bool {
+	for _, e := range strings.Split(w.Events, ",") {
+		if e = strings.TrimSpace(e); e == "*" || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Status 推送状态
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // 等待推送或等待重试
+	StatusSending   Status = "sending"   // 已被 worker 领取
+	StatusSucceeded Status = "succeeded" // 对方返回 2xx
+	StatusDead      Status = "dead"      // 超过最大重试次数, 不再推送
+)
+
+// Delivery 一个事件对一个 webhook 的推送
+type Delivery struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	WebhookID     uint      `gorm:"not null;uniqueIndex:idx_webhook_deliveries_event,priority:1"`
+	EventID       uint64    `gorm:"not null;uniqueIndex:idx_webhook_deliveries_event,priority:2"` // outbox 事件 ID, relay 重复投递时不会重复推送
+	EventType     string    `gorm:"size:100;not null"`
+	Payload       string    `gorm:"type:json;not null"`
+	Status        Status    `gorm:"size:20;not null;default:'pending';index:idx_webhook_deliveries_due,priority:1"`
+	Attempts      int       `gorm:"not null;default:0"`
+	MaxAttempts   int       `gorm:"not null;default:8"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_webhook_deliveries_due,priority:2"`
+	LastError     string    `gorm:"size:1000"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// TableName 指定表名
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// DeliveryAttempt 一次推送请求的结果
+type DeliveryAttempt struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	DeliveryID uint   `gorm:"not null;index"`
+	StatusCode int    // 没有收到响应时为 0
+	Error      string `gorm:"size:1000"`
+	DurationMS int64  `gorm:"not null"`
+	CreatedAt  time.Time
+}
+
+// TableName 指定表名
+func (DeliveryAttempt) TableName() string {
+	return "webhook_delivery_attempts"
+}
+
+// Migrate 创建 webhook 相关的表
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Webhook{}, &Delivery{}, &DeliveryAttempt{}); err != nil {
+		return fmt.Errorf("创建 webhook 表失败: %w", err)
+	}
+	return nil
+}
+
+// Register 添加订阅, events 为空时订阅全部事件
+func Register(ctx context.Context, db *gorm.DB, rawURL, secret string, events []string) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("webhook 地址 %q 不是有效的 http(s) URL", rawURL)
+	}
+	if secret == "" {
+		return nil, errors.New("webhook 密钥不能为空")
+	}
+	events = slices.DeleteFunc(slices.Clone(events), func(e string) bool { return strings.TrimSpace(e) == "" })
+	if len(events) == 0 {
+		events = []string{"*"}
+	}
+
+	hook := Webhook{URL: rawURL, Secret: secret, Events: strings.Join(events, ","), Active: true}
+	if err := db.WithContext(ctx).Create(&hook).Error; err != nil {
+		return nil, fmt.Errorf("添加 webhook 失败: %w", err)
+	}
+	return &hook, nil
+}
+
+// List 按 ID 返回全部订阅
+func List(ctx context.Context, db *gorm.DB) ([]Webhook, error) {
+	var hooks []Webhook
+	if err := db.WithContext(ctx).Order("id").Find(&hooks).Error; err != nil {
+		return nil, fmt.Errorf("查询 webhook 失败: %w", err)
+	}
+	return hooks, nil
+}
+
+// Remove 删除订阅, 尚未完成的推送不再进行
+func Remove(ctx context.Context, db *gorm.DB, id uint) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Webhook{}, id)
+		if result.Error != nil {
+			return fmt.Errorf("删除 webhook 失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		err := tx.Model(&Delivery{}).Where("webhook_id = ? AND status IN ?", id, []Status{StatusPending, StatusSending}).
+			Updates(map[string]any{"status": StatusDead, "last_error": "webhook 已删除"}).Error
+		if err != nil {
+			return fmt.Errorf("取消推送失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// Sink 实现 outbox.Sink: 为订阅了事件的每个启用中的 webhook 写入一条待推送的 Delivery
+type Sink struct {
+	db *gorm.DB
+}
+
+// NewSink 创建 Sink
+func NewSink(db *gorm.DB) *Sink {
+	return &Sink{db: db}
+}
+
+// Publish 实现 outbox.Sink
+func (s *Sink) Publish(ctx context.Context, event outbox.Event) error {
+	var hooks []Webhook
+	if err := s.db.WithContext(ctx).Where("active = ?", true).Find(&hooks).Error; err != nil {
+		return fmt.Errorf("查询 webhook 失败: %w", err)
+	}
+
+	var deliveries []Delivery
+	for _, hook := range hooks {
+		if !hook.Matches(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, Delivery{
+			WebhookID:     hook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       event.Payload,
+			Status:        StatusPending,
+			MaxAttempts:   8,
+			NextAttemptAt: time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+	if err != nil {
+		return fmt.Errorf("写入 webhook 推送失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 推送请求头
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign 计算签名: hex(HMAC-SHA256(secret, timestamp + "." + body)), 请求头中带 "sha256=" 前缀.
+// 接收方用同样的方式计算并比较, 同时检查时间戳防止重放
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Options worker 配置, 零值字段使用默认值
+type Options struct {
+	Concurrency  int           // 并发推送数, 默认 4
+	BatchSize    int           // 每次领取的推送数, 默认 20
+	PollInterval time.Duration // 没有到期推送时的轮询间隔, 默认 2s
+	Timeout      time.Duration // 单次请求超时, 默认 10s
+	BaseBackoff  time.Duration // 首次重试等待, 之后每次翻倍, 默认 30s
+	MaxBackoff   time.Duration // 重试等待上限, 默认 6h
+	Lease        time.Duration // 领取后超过该时间仍未完成视为 worker 崩溃, 重新推送, 默认 5m
+}
+
+func (o *Options) setDefaults() {
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 20
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = 2 * time.Second
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = 30 * time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 6 * time.Hour
+	}
+	if o.Lease <= 0 {
+		o.Lease = 5 * time.Minute
+	}
+}
+
+// Worker 领取到期的推送并发送
+type Worker struct {
+	db     *gorm.DB
+	client *http.Client
+	opts   Options
+}
+
+// NewWorker 创建 worker
+func NewWorker(db *gorm.DB, opts Options) *Worker {
+	opts.setDefaults()
+	return &Worker{db: db, client: &http.Client{Timeout: opts.Timeout}, opts: opts}
+}
+
+// Run 持续推送直到 ctx 取消, 返回前等待进行中的请求完成
+func (w *Worker) Run(ctx context.Context) error {
+	sem := make(chan struct{}, w.opts.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		deliveries, err := w.claim(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("领取 webhook 推送失败: %v", err)
+		}
+
+		for _, d := range deliveries {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(d Delivery) {
+				defer func() { <-sem; wg.Done() }()
+				w.deliver(context.WithoutCancel(ctx), d)
+			}(d)
+		}
+
+		// 领满一批说明可能还有积压, 立即继续; 否则等待下一轮
+		if len(deliveries) == w.opts.BatchSize {
+			if ctx.Err() != nil {
+				return nil
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.opts.PollInterval):
+		}
+	}
+}
+
+// claim 在事务中锁定一批到期推送并标记为 sending, SKIP LOCKED 让多个 worker 互不阻塞
+func (w *Worker) claim(ctx context.Context) ([]Delivery, error) {
+	var deliveries []Delivery
+	err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND updated_at < ?)",
+				StatusPending, now, StatusSending, now.Add(-w.opts.Lease)).
+			Order("next_attempt_at").
+			Limit(w.opts.BatchSize).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i, d := range deliveries {
+			ids[i] = d.ID
+		}
+		return tx.Model(&Delivery{}).Where("id IN ?", ids).
+			Updates(map[string]any{"status": StatusSending, "updated_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// deliver 推送一次并记录结果
+func (w *Worker) deliver(ctx context.Context, d Delivery) {
+	var hook Webhook
+	err := w.db.WithContext(ctx).First(&hook, d.WebhookID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = w.db.WithContext(ctx).Model(&Delivery{}).Where("id = ?", d.ID).
+			Updates(map[string]any{"status": StatusDead, "last_error": "webhook 已删除"}).Error
+	}
+	if err != nil {
+		log.Printf("查询 webhook %d 失败: %v", d.WebhookID, err) // 租约到期后重新领取
+	}
+	if hook.ID == 0 {
+		return
+	}
+
+	start := time.Now()
+	statusCode, sendErr := w.send(ctx, &hook, &d)
+	attempt := DeliveryAttempt{
+		DeliveryID: d.ID,
+		StatusCode: statusCode,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if sendErr != nil {
+		attempt.Error = truncate(sendErr.Error(), 1000)
+	}
+
+	updates := map[string]any{"attempts": d.Attempts + 1, "last_error": attempt.Error}
+	switch {
+	case sendErr == nil:
+		updates["status"] = StatusSucceeded
+	case d.Attempts+1 >= d.MaxAttempts:
+		updates["status"] = StatusDead
+		log.Printf("webhook 推送 %d 已重试 %d 次, 不再推送: %v", d.ID, d.Attempts+1, sendErr)
+	default:
+		updates["status"] = StatusPending
+		updates["next_attempt_at"] = time.Now().Add(w.backoff(d.Attempts))
+	}
+
+	err = w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&attempt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Delivery{}).Where("id = ?", d.ID).Updates(updates).Error
+	})
+	if err != nil {
+		log.Printf("更新 webhook 推送 %d 状态失败: %v", d.ID, err)
+	}
+}
+
+// send 发出签名的 POST 请求, 2xx 视为成功. 返回响应状态码 (没有响应时为 0)
+func (w *Worker) send(ctx context.Context, hook *Webhook, d *Delivery) (int, error) {
+	body, err := json.Marshal(map[string]any{
+		"id":   d.EventID,
+		"type": d.EventType,
+		"data": json.RawMessage(d.Payload),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("序列化推送内容失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // 读完响应以复用连接
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("对方返回 %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff 第 attempts 次失败后的等待时间: BaseBackoff * 2^attempts, 不超过 MaxBackoff
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.opts.BaseBackoff
+	for i := 0; i < attempts && d < w.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, w.opts.MaxBackoff)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return fmt.Sprintf("%.*s...", n-3, s)
+}